@@ -0,0 +1,89 @@
+#!/usr/bin/env python3
+"""
+Chunk and embed scraped news articles for an incremental index rebuild.
+
+Called by `strunzctl kb rebuild` with the articles a scrape delta marked as
+added or changed; writes their chunks and a flat FAISS index of their
+vectors, in the order of the chunks, for strunzctl to merge.
+"""
+
+import argparse
+import hashlib
+import json
+import logging
+import sys
+from datetime import datetime
+from pathlib import Path
+
+import faiss
+
+sys.path.insert(0, str(Path(__file__).parent.parent.parent))
+
+from src.rag.news_processor import NewsProcessor
+
+logging.basicConfig(
+    level=logging.INFO,
+    format='%(asctime)s - %(levelname)s - %(message)s'
+)
+
+MODEL_NAME = 'sentence-transformers/paraphrase-multilingual-MiniLM-L12-v2'
+
+
+def chunk_articles(processor: NewsProcessor, articles: list) -> list:
+    """Chunk articles the way news_processor.py chunks news pages."""
+    documents = []
+    for article in articles:
+        url = article['source_url']
+        metadata = {
+            'source': 'news',
+            'filename': url.rstrip('/').rsplit('/', 1)[-1],
+            'url': url,
+            'processed_date': datetime.now().isoformat(),
+            'title': article.get('title') or '',
+            'author': article.get('author') or 'Dr. Ulrich Strunz',
+            'content_type': 'news',
+        }
+        if article.get('date'):
+            metadata['date'] = article['date']
+
+        for chunk in processor.create_chunks(article.get('content') or '', metadata):
+            chunk['metadata']['chunk_id'] = hashlib.md5(chunk['text'].encode()).hexdigest()
+            chunk['title'] = metadata['title']
+            documents.append(chunk)
+    return documents
+
+
+def main():
+    parser = argparse.ArgumentParser(description=__doc__.strip().splitlines()[0])
+    parser.add_argument('--input', required=True, help='JSON file with the articles to embed under "news"')
+    parser.add_argument('--output-dir', required=True, help='directory for documents.json and index.faiss')
+    args = parser.parse_args()
+
+    with open(args.input, 'r', encoding='utf-8') as f:
+        articles = json.load(f)['news']
+
+    processor = NewsProcessor()
+    documents = chunk_articles(processor, articles)
+    logging.info(f"Embedding {len(documents)} chunks of {len(articles)} articles...")
+
+    index = faiss.IndexFlatL2(processor.embedding_dim)
+    if documents:
+        embeddings = processor.model.encode([doc['text'] for doc in documents], show_progress_bar=False)
+        index.add(embeddings.astype('float32'))
+
+    output_dir = Path(args.output_dir)
+    output_dir.mkdir(parents=True, exist_ok=True)
+    faiss.write_index(index, str(output_dir / "index.faiss"))
+    with open(output_dir / "documents.json", 'w', encoding='utf-8') as f:
+        json.dump({
+            'documents': documents,
+            'total_documents': len(documents),
+            'embedding_model': MODEL_NAME,
+            'embedding_dim': processor.embedding_dim,
+        }, f, ensure_ascii=False, indent=2)
+
+    logging.info(f"✅ Wrote {index.ntotal} vectors to {output_dir}")
+
+
+if __name__ == "__main__":
+    main()
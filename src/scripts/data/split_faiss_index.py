@@ -5,6 +5,7 @@ Split large FAISS index and metadata files into smaller chunks for GitHub storag
 
 import os
 import json
+import hashlib
 import numpy as np
 from pathlib import Path
 
@@ -52,7 +53,9 @@ def split_file(filepath, chunk_size_mb=40):
         "original_size": file_size,
         "num_chunks": num_chunks,
         "chunk_size": chunk_size,
-        "chunks": [f"{base_name}.part{i:03d}" for i in range(num_chunks)]
+        "chunks": [f"{base_name}.part{i:03d}" for i in range(num_chunks)],
+        # Checked by `strunzctl kb verify-index` after reassembly
+        "sha256": hashlib.sha256(data).hexdigest()
     }
     
     metadata_file = output_dir / f"{base_name}.metadata.json"
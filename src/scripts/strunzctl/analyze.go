@@ -0,0 +1,218 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var (
+	// historyShell is how the classic builder records instructions:
+	// "/bin/sh -c #(nop)  CMD [...]" for metadata, "/bin/sh -c pip ..." for RUN
+	historyShell = regexp.MustCompile(`^/bin/(?:ba)?sh -c (#\(nop\)\s*)?`)
+	// dataInstruction names the layers that carry the knowledge base
+	dataInstruction = regexp.MustCompile(`(?i)faiss|data/|\.json\b|index`)
+	// historySource is the content hash the classic builder writes for
+	// COPY dir:<hash> in /app
+	historySource = regexp.MustCompile(`\b(?:dir|file|multi):[0-9a-f]{64}`)
+)
+
+// imageLayer is a layer with the instruction that created it
+type imageLayer struct {
+	Digest      string
+	Size        int64
+	Instruction string
+	// occurrence tells identical instructions apart for comparisons
+	occurrence int
+}
+
+// instructionFromHistory turns a history entry into the Dockerfile
+// instruction it came from
+func instructionFromHistory(createdBy string) string {
+	instruction := strings.TrimSuffix(strings.TrimSpace(createdBy), "# buildkit")
+	if m := historyShell.FindStringSubmatch(instruction); m != nil {
+		instruction = instruction[len(m[0]):]
+		if m[1] == "" {
+			instruction = "RUN " + instruction
+		}
+	}
+	instruction = strings.Join(strings.Fields(instruction), " ")
+	instruction = historySource.ReplaceAllString(instruction, "…")
+	if instruction == "" {
+		return "(unknown)"
+	}
+	return instruction
+}
+
+// attributeLayers pairs the layers of an image with the history entries
+// that created them. Entries flagged empty_layer have no layer; when the
+// history does not add up, the unmatched layers stay unattributed.
+func attributeLayers(layers []Descriptor, config *ImageConfig) ([]imageLayer, bool) {
+	result := make([]imageLayer, len(layers))
+	for i, layer := range layers {
+		result[i] = imageLayer{Digest: layer.Digest, Size: layer.Size, Instruction: "(no history)"}
+	}
+	var instructions []string
+	for _, entry := range config.History {
+		if !entry.EmptyLayer {
+			instructions = append(instructions, instructionFromHistory(entry.CreatedBy))
+		}
+	}
+	complete := len(instructions) == len(layers)
+	seen := make(map[string]int)
+	for i := range result {
+		if i < len(instructions) {
+			result[i].Instruction = instructions[i]
+		}
+		result[i].occurrence = seen[result[i].Instruction]
+		seen[result[i].Instruction]++
+	}
+	return result, complete
+}
+
+// analyzedImage is an image broken down into its layers
+type analyzedImage struct {
+	Tag      string
+	Digest   string
+	Layers   []imageLayer
+	Total    int64
+	Complete bool
+}
+
+func analyzeImage(registry *RegistryClient, tag, platform string) (*analyzedImage, error) {
+	manifest, err := resolvePlatformManifest(registry, tag, platform)
+	if err != nil {
+		return nil, err
+	}
+	imageConfig, err := registry.GetImageConfig(manifest)
+	if err != nil {
+		return nil, err
+	}
+	layers, complete := attributeLayers(manifest.Layers, imageConfig)
+	image := &analyzedImage{Tag: tag, Digest: manifest.Digest, Layers: layers, Complete: complete}
+	for _, layer := range layers {
+		image.Total += layer.Size
+	}
+	return image, nil
+}
+
+// sizeBar draws a share of the total as a bar of up to width blocks
+func sizeBar(size, total int64, width int) string {
+	if total == 0 {
+		return ""
+	}
+	n := int(float64(size) / float64(total) * float64(width))
+	if n == 0 && size > 0 {
+		return "▏"
+	}
+	return strings.Repeat("█", n)
+}
+
+func printImageAnalysis(image *analyzedImage, top int) {
+	fmt.Printf("Digest: %s\n", shortDigest(image.Digest))
+	fmt.Printf("Layers: %d, %s compressed\n", len(image.Layers), formatBytes(image.Total))
+	if !image.Complete {
+		fmt.Println("⚠️  The image history does not match its layers; instructions may be attributed wrongly")
+	}
+
+	fmt.Printf("\n%-3s %10s %6s  %-20s %s\n", "#", "SIZE", "SHARE", "", "INSTRUCTION")
+	for i, layer := range image.Layers {
+		fmt.Printf("%-3d %10s %5.1f%%  %-20s %s\n", i+1, formatBytes(layer.Size), share(layer.Size, image.Total),
+			sizeBar(layer.Size, image.Total, 20), truncate(layer.Instruction, 90))
+	}
+
+	biggest := slices.Clone(image.Layers)
+	slices.SortStableFunc(biggest, func(a, b imageLayer) int { return cmp.Compare(b.Size, a.Size) })
+	biggest = biggest[:min(top, len(biggest))]
+	fmt.Printf("\n🏋️  Biggest contributors:\n")
+	var covered int64
+	for _, layer := range biggest {
+		covered += layer.Size
+		marker := ""
+		if dataInstruction.MatchString(layer.Instruction) {
+			marker = "  📚 knowledge base"
+		}
+		fmt.Printf("  %10s %5.1f%%  %s%s\n", formatBytes(layer.Size), share(layer.Size, image.Total), truncate(layer.Instruction, 80), marker)
+	}
+	fmt.Printf("  The top %d layer(s) are %.1f%% of the image\n", len(biggest), share(covered, image.Total))
+}
+
+func share(size, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(size) / float64(total) * 100
+}
+
+// printImageAnalysisComparison matches the layers of two images by the
+// instruction that created them and shows how each changed
+func printImageAnalysisComparison(base, target *analyzedImage) {
+	fmt.Printf("\n🔀 Compared with %s (%s): %s → %s (%s)\n", base.Tag, shortDigest(base.Digest),
+		formatBytes(base.Total), formatBytes(target.Total), formatSizeChange(base.Total, target.Total))
+
+	type key struct {
+		instruction string
+		occurrence  int
+	}
+	before := make(map[key]imageLayer)
+	for _, layer := range base.Layers {
+		before[key{layer.Instruction, layer.occurrence}] = layer
+	}
+	matched := make(map[key]bool)
+	changed := 0
+	for _, layer := range target.Layers {
+		k := key{layer.Instruction, layer.occurrence}
+		old, ok := before[k]
+		matched[k] = ok
+		switch {
+		case !ok:
+			fmt.Printf("  + %10s  %s\n", formatBytes(layer.Size), truncate(layer.Instruction, 90))
+			changed++
+		case old.Digest != layer.Digest:
+			fmt.Printf("  ~ %10s → %s (%s)  %s\n", formatBytes(old.Size), formatBytes(layer.Size), formatSizeChange(old.Size, layer.Size), truncate(layer.Instruction, 70))
+			changed++
+		}
+	}
+	for _, layer := range base.Layers {
+		if !matched[key{layer.Instruction, layer.occurrence}] {
+			fmt.Printf("  - %10s  %s\n", formatBytes(layer.Size), truncate(layer.Instruction, 90))
+			changed++
+		}
+	}
+	if changed == 0 {
+		fmt.Println("  (no layer changed)")
+	}
+}
+
+func newImageAnalyzeCommand() *Command {
+	cmd := newCommand("analyze", "<tag>", "Break an image down into its layers, attributed to Dockerfile instructions via the image history, and show the biggest ones.")
+	platform := cmd.Flags.String("platform", defaultPlatform, "platform image to analyze from manifest lists")
+	compare := cmd.Flags.String("compare", "", "also show how each layer changed since this tag")
+	top := cmd.Flags.Int("top", 5, "biggest layers to highlight")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 1); err != nil {
+			return err
+		}
+		registry := newRegistryClient(config.Registry, imageRepository())
+		image, err := analyzeImage(registry, args[0], *platform)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("\n📦 Layers of %s (%s)\n", registry.Reference(args[0]), *platform)
+		printImageAnalysis(image, max(*top, 1))
+
+		if *compare == "" {
+			return nil
+		}
+		base, err := analyzeImage(registry, *compare, *platform)
+		if err != nil {
+			return err
+		}
+		printImageAnalysisComparison(base, image)
+		return nil
+	}
+	return cmd
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Artifact is a workflow artifact stored by GitHub Actions
+type Artifact struct {
+	ID          int64     `json:"id"`
+	Name        string    `json:"name"`
+	SizeInBytes int64     `json:"size_in_bytes"`
+	Expired     bool      `json:"expired"`
+	CreatedAt   time.Time `json:"created_at"`
+	WorkflowRun struct {
+		HeadBranch string `json:"head_branch"`
+	} `json:"workflow_run"`
+}
+
+// ActionsCache is a dependency cache entry of GitHub Actions
+type ActionsCache struct {
+	ID             int64     `json:"id"`
+	Key            string    `json:"key"`
+	Ref            string    `json:"ref"`
+	SizeInBytes    int64     `json:"size_in_bytes"`
+	LastAccessedAt time.Time `json:"last_accessed_at"`
+}
+
+func newCICleanArtifactsCommand() *Command {
+	cmd := newCommand("clean-artifacts", "", "Delete workflow artifacts older than a given age.")
+	olderThan := cmd.Flags.String("older-than", "30d", "minimum age of deleted artifacts (e.g. 30d, 2w, 12h)")
+	name := cmd.Flags.String("name", "*", "only artifacts whose name matches this glob")
+	dryRun := cmd.Flags.Bool("dry-run", false, "list what would be deleted without deleting")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 0); err != nil {
+			return err
+		}
+		age, err := parseAge(*olderThan)
+		if err != nil {
+			return fmt.Errorf("%w: --older-than: %v", errUsage, err)
+		}
+		if _, err := path.Match(*name, ""); err != nil {
+			return fmt.Errorf("%w: --name: %v", errUsage, err)
+		}
+		github, err := newGitHubClient()
+		if err != nil {
+			return err
+		}
+
+		cutoff := time.Now().Add(-age)
+		var stale []Artifact
+		err = github.GetPages(fmt.Sprintf("/repos/%s/actions/artifacts", config.Repo), func(body []byte) error {
+			var page struct {
+				Artifacts []Artifact `json:"artifacts"`
+			}
+			if err := json.Unmarshal(body, &page); err != nil {
+				return fmt.Errorf("failed to parse artifacts: %w", err)
+			}
+			for _, artifact := range page.Artifacts {
+				// Expired artifacts no longer count against storage
+				if matched, _ := path.Match(*name, artifact.Name); matched && !artifact.Expired && artifact.CreatedAt.Before(cutoff) {
+					stale = append(stale, artifact)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("\n🧹 Artifacts older than %s matching %q: %d\n", *olderThan, *name, len(stale))
+		var total int64
+		for _, artifact := range stale {
+			total += artifact.SizeInBytes
+			fmt.Printf("  %-40s %10s  %s  %s\n", artifact.Name, formatBytes(artifact.SizeInBytes),
+				artifact.CreatedAt.Format(time.DateOnly), orNone(artifact.WorkflowRun.HeadBranch))
+		}
+		if len(stale) == 0 {
+			fmt.Println("\n✅ Nothing to delete")
+			return nil
+		}
+		if *dryRun {
+			fmt.Printf("\nWould free %s (dry run)\n", formatBytes(total))
+			return nil
+		}
+
+		var freed atomic.Int64
+		err = forEachConcurrent(stale, globalOptions.concurrency, func(_ int, artifact Artifact) error {
+			if err := github.Delete(fmt.Sprintf("/repos/%s/actions/artifacts/%d", config.Repo, artifact.ID)); err != nil {
+				return fmt.Errorf("failed to delete artifact %s (%d): %w", artifact.Name, artifact.ID, err)
+			}
+			freed.Add(artifact.SizeInBytes)
+			return nil
+		})
+		fmt.Printf("\n✅ Freed %s\n", formatBytes(freed.Load()))
+		return err
+	}
+	return cmd
+}
+
+func newCICleanCachesCommand() *Command {
+	cmd := newCommand("clean-caches", "", "Evict Actions caches that have not been used for a given age.")
+	olderThan := cmd.Flags.String("older-than", "7d", "minimum time since the cache was last accessed")
+	key := cmd.Flags.String("key", "", "only caches whose key starts with this prefix")
+	ref := cmd.Flags.String("ref", "", "only caches of this ref, e.g. refs/pull/42/merge")
+	dryRun := cmd.Flags.Bool("dry-run", false, "list what would be evicted without deleting")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 0); err != nil {
+			return err
+		}
+		age, err := parseAge(*olderThan)
+		if err != nil {
+			return fmt.Errorf("%w: --older-than: %v", errUsage, err)
+		}
+		github, err := newGitHubClient()
+		if err != nil {
+			return err
+		}
+
+		query := url.Values{"sort": {"last_accessed_at"}, "direction": {"asc"}}
+		if *key != "" {
+			query.Set("key", *key)
+		}
+		if *ref != "" {
+			query.Set("ref", *ref)
+		}
+		cutoff := time.Now().Add(-age)
+		var stale []ActionsCache
+		err = github.GetPages(fmt.Sprintf("/repos/%s/actions/caches?%s", config.Repo, query.Encode()), func(body []byte) error {
+			var page struct {
+				Caches []ActionsCache `json:"actions_caches"`
+			}
+			if err := json.Unmarshal(body, &page); err != nil {
+				return fmt.Errorf("failed to parse caches: %w", err)
+			}
+			for _, cache := range page.Caches {
+				if cache.LastAccessedAt.Before(cutoff) && strings.HasPrefix(cache.Key, *key) {
+					stale = append(stale, cache)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		var usage struct {
+			Size  int64 `json:"active_caches_size_in_bytes"`
+			Count int   `json:"active_caches_count"`
+		}
+		if err := github.Get(fmt.Sprintf("/repos/%s/actions/cache/usage", config.Repo), &usage); err != nil {
+			return err
+		}
+
+		fmt.Printf("\n🧹 Cache usage: %d caches, %s\n", usage.Count, formatBytes(usage.Size))
+		fmt.Printf("Caches unused for %s: %d\n", *olderThan, len(stale))
+		var total int64
+		for _, cache := range stale {
+			total += cache.SizeInBytes
+			fmt.Printf("  %-60s %10s  %s  %s\n", cache.Key, formatBytes(cache.SizeInBytes),
+				cache.LastAccessedAt.Format(time.DateOnly), cache.Ref)
+		}
+		if len(stale) == 0 {
+			fmt.Println("\n✅ Nothing to delete")
+			return nil
+		}
+		if *dryRun {
+			fmt.Printf("\nWould free %s (dry run)\n", formatBytes(total))
+			return nil
+		}
+
+		var freed atomic.Int64
+		err = forEachConcurrent(stale, globalOptions.concurrency, func(_ int, cache ActionsCache) error {
+			if err := github.Delete(fmt.Sprintf("/repos/%s/actions/caches/%d", config.Repo, cache.ID)); err != nil {
+				return fmt.Errorf("failed to evict cache %s (%d): %w", cache.Key, cache.ID, err)
+			}
+			freed.Add(cache.SizeInBytes)
+			return nil
+		})
+		fmt.Printf("\n✅ Freed %s\n", formatBytes(freed.Load()))
+		return err
+	}
+	return cmd
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// askTools are tried in order when --tool is not given; servers name the
+// search tool differently across versions
+var askTools = []string{"search_knowledge", "knowledge_search"}
+
+func newAskCommand() *Command {
+	cmd := newCommand("ask", "<question...>", "Search the knowledge base over MCP and print the ranked results with sources and scores.")
+	serverURL := cmd.Flags.String("url", defaultServerURL, "base URL of the server")
+	tool := cmd.Flags.String("tool", "", "search tool to call (default: search_knowledge or knowledge_search, whichever is advertised)")
+	limit := cmd.Flags.Int("limit", 5, "number of results")
+	format := cmd.Flags.String("format", "text", "output format: text or json")
+	width := cmd.Flags.Int("width", 100, "wrap result content at this many columns")
+	token := cmd.Flags.String("token", "", "bearer token to send")
+	timeout := cmd.Flags.Duration("timeout", 60*time.Second, "timeout for connecting and for the search")
+
+	cmd.Run = func(args []string) error {
+		if len(args) == 0 {
+			return fmt.Errorf("%w: ask needs a question", errUsage)
+		}
+		if *format != "text" && *format != "json" {
+			return fmt.Errorf("%w: unknown format %q (want text or json)", errUsage, *format)
+		}
+		question := strings.Join(args, " ")
+		var header http.Header
+		if *token != "" {
+			header = http.Header{"Authorization": {"Bearer " + *token}}
+		}
+
+		mcp, err := connectMCPWithHeader(*serverURL, *timeout, header)
+		if err != nil {
+			return err
+		}
+		defer mcp.Close()
+		if _, err := mcp.Initialize(commandContext); err != nil {
+			return fmt.Errorf("initialize failed: %s", describeMCPError(err))
+		}
+		name := *tool
+		if name == "" {
+			if name, err = pickSearchTool(mcp); err != nil {
+				return err
+			}
+		}
+
+		start := time.Now()
+		result, err := mcp.CallTool(commandContext, name, map[string]any{"query": question, "limit": *limit})
+		if err != nil {
+			return fmt.Errorf("%s failed: %s", name, describeMCPError(err))
+		}
+		elapsed := time.Since(start)
+		text := result.Text()
+		if result.IsError {
+			return fmt.Errorf("%s returned an error: %s", name, truncate(strings.TrimSpace(text), 200))
+		}
+		hits, ok := parseSearchHits(text)
+
+		if *format == "json" {
+			output := map[string]any{"question": question, "tool": name, "server": *serverURL, "duration_ms": elapsed.Milliseconds()}
+			if ok {
+				output["results"] = append([]searchHit{}, hits...)
+			} else {
+				output["text"] = text
+			}
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(output)
+		}
+
+		fmt.Printf("\n🔎 %q\n", question)
+		switch {
+		case !ok:
+			// Not search hits, e.g. a tool that answers in prose
+			printf("%s on %s in %s\n\n%s\n", name, *serverURL, elapsed.Round(time.Millisecond), strings.TrimSpace(text))
+		case len(hits) == 0:
+			printf("No results from %s on %s (%s)\n", name, *serverURL, elapsed.Round(time.Millisecond))
+		default:
+			printf("%d result(s) from %s on %s in %s\n", len(hits), name, *serverURL, elapsed.Round(time.Millisecond))
+			for _, hit := range hits {
+				fmt.Printf("\n%2d. %s %s  %s\n", hit.Rank, scoreBar(hit.Score, 10), decimal(hit.Score, 3), hit.Source)
+				for _, line := range wrapText(hit.Content, max(*width-4, 20)) {
+					fmt.Printf("    %s\n", line)
+				}
+			}
+		}
+		return nil
+	}
+	return cmd
+}
+
+// pickSearchTool returns the first of askTools the server advertises
+func pickSearchTool(mcp *MCPClient) (string, error) {
+	tools, err := mcp.ListTools(commandContext)
+	if err != nil {
+		return "", fmt.Errorf("tools/list failed: %s", describeMCPError(err))
+	}
+	var names []string
+	for _, tool := range tools {
+		names = append(names, tool.Name)
+	}
+	for _, name := range askTools {
+		if slices.Contains(names, name) {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("%w: the server has no %s tool; pass --tool (advertised: %s)",
+		errUsage, strings.Join(askTools, " or "), orNone(strings.Join(names, ", ")))
+}
+
+// scoreBar draws a relevance score between 0 and 1 as a bar
+func scoreBar(score float64, width int) string {
+	filled := min(max(int(score*float64(width)+0.5), 0), width)
+	return strings.Repeat("█", filled) + strings.Repeat("░", width-filled)
+}
+
+// wrapText breaks text into lines of at most width characters at spaces
+func wrapText(text string, width int) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(text, "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			if line != "" && len([]rune(line))+1+len([]rune(word)) > width {
+				lines = append(lines, line)
+				line = ""
+			}
+			if line != "" {
+				line += " "
+			}
+			line += word
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
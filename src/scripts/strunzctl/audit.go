@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Audit actions recorded by the commands that change deployments
+const (
+	auditPromote  = "promote"
+	auditRollback = "rollback"
+	auditDeploy   = "deploy"
+	auditDelete   = "delete"
+	auditSwitch   = "switch"
+	auditRotate   = "rotate"
+)
+
+// AuditEntry is one line of the append-only audit log
+type AuditEntry struct {
+	At     time.Time `json:"at"`
+	By     string    `json:"by"`
+	Action string    `json:"action"`
+	// Tag is the image tag, or the variables a rotation replaced
+	Tag    string `json:"tag,omitempty"`
+	Digest string `json:"digest,omitempty"`
+	// Target is the tag that was moved, the Railway service deployed to,
+	// the domain switched or the package version deleted
+	Target string `json:"target,omitempty"`
+	// Replaced is the digest a rollback moved away from
+	Replaced string `json:"replaced,omitempty"`
+}
+
+// auditLogPath returns audit.path, or a JSONL file per package in the
+// strunzctl state directory. A log kept in the cache directory by earlier
+// versions is moved there.
+func auditLogPath() (string, error) {
+	if config.Audit.Path != "" {
+		return config.Audit.Path, nil
+	}
+	dir, err := stateDir("audit")
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, config.Package+".jsonl")
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		if base, err := os.UserCacheDir(); err == nil {
+			os.Rename(filepath.Join(base, "strunzctl", "audit", config.Package+".jsonl"), path)
+		}
+	}
+	return path, nil
+}
+
+// recordAudit appends an entry to the audit log and, if audit.gist is set,
+// mirrors the log to that gist. Only the local write can fail the command.
+func recordAudit(entry AuditEntry) error {
+	entry.At = time.Now().UTC()
+	entry.By = operator()
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+
+	path, err := auditLogPath()
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	_, err = file.Write(append(line, '\n'))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	slog.Info("Recorded audit entry", "action", entry.Action, "tag", entry.Tag, "target", entry.Target, "by", entry.By)
+
+	if config.Audit.Gist != "" {
+		if err := pushAuditLog(path); err != nil {
+			slog.Warn("Failed to push audit log to gist", "gist", config.Audit.Gist, "error", err)
+		}
+	}
+	return nil
+}
+
+// loadAudit reads all audit entries, oldest first
+func loadAudit() ([]AuditEntry, error) {
+	path, err := auditLogPath()
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	defer file.Close()
+
+	var entries []AuditEntry
+	scanner := bufio.NewScanner(file)
+	for n := 1; scanner.Scan(); n++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var entry AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("failed to parse audit log %s:%d: %w", path, n, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return entries, nil
+}
+
+// pushAuditLog replaces the gist file with the local log
+func pushAuditLog(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	github, err := newGitHubClient()
+	if err != nil {
+		return err
+	}
+	files := map[string]any{filepath.Base(path): map[string]string{"content": string(data)}}
+	return github.Send(http.MethodPatch, "/gists/"+config.Audit.Gist, map[string]any{"files": files}, nil)
+}
+
+// lastKnownGood returns the newest promotion to productionTag whose image
+// differs from the current one and was never rolled back
+func lastKnownGood(entries []AuditEntry, productionTag, currentDigest string) *AuditEntry {
+	bad := map[string]bool{currentDigest: true}
+	for _, entry := range entries {
+		if entry.Action == auditRollback && entry.Target == productionTag {
+			bad[entry.Replaced] = true
+		}
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		if (entry.Action == auditPromote || entry.Action == auditRollback) && entry.Target == productionTag && !bad[entry.Digest] {
+			return &entries[i]
+		}
+	}
+	return nil
+}
+
+func newAuditCommand() *Command {
+	show := newCommand("show", "", "Show who promoted, deployed, switched, rolled back, deleted or rotated what and when.")
+	action := show.Flags.String("action", "", "only entries with this action (promote, rollback, deploy, switch, delete, rotate)")
+	tag := show.Flags.String("tag", "", "only entries for this tag")
+	by := show.Flags.String("by", "", "only entries by this operator")
+	since := show.Flags.String("since", "", "only entries newer than this age (e.g. 7d, 12h)")
+	limit := show.Flags.Int("limit", 20, "maximum number of entries, newest first (0 for all)")
+	format := show.Flags.String("format", "table", "output format: table or jsonl")
+
+	show.Run = func(args []string) error {
+		if err := show.ExactArgs(args, 0); err != nil {
+			return err
+		}
+		if *format != "table" && *format != "jsonl" {
+			return fmt.Errorf("%w: unknown format %q (want table or jsonl)", errUsage, *format)
+		}
+		var cutoff time.Time
+		if *since != "" {
+			age, err := parseAge(*since)
+			if err != nil {
+				return fmt.Errorf("%w: --since: %v", errUsage, err)
+			}
+			cutoff = time.Now().Add(-age)
+		}
+		entries, err := loadAudit()
+		if err != nil {
+			return err
+		}
+
+		var matched []AuditEntry
+		for i := len(entries) - 1; i >= 0 && (*limit == 0 || len(matched) < *limit); i-- {
+			entry := entries[i]
+			if (*action == "" || entry.Action == *action) && (*tag == "" || entry.Tag == *tag) &&
+				(*by == "" || entry.By == *by) && !entry.At.Before(cutoff) {
+				matched = append(matched, entry)
+			}
+		}
+
+		if *format == "jsonl" {
+			encoder := json.NewEncoder(os.Stdout)
+			for _, entry := range matched {
+				if err := encoder.Encode(entry); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		path, _ := auditLogPath()
+		fmt.Printf("\n📜 Audit log %s\n", path)
+		if len(matched) == 0 {
+			fmt.Println("  (no entries)")
+			return nil
+		}
+		for _, entry := range matched {
+			fmt.Printf("  %s  %-8s %-24s %-20s → %-20s %s\n", entry.At.Local().Format("2006-01-02 15:04"),
+				entry.Action, entry.By, orNone(entry.Tag), orNone(entry.Target), shortDigest(entry.Digest))
+		}
+		return nil
+	}
+	return newGroup("audit", "Query the log of deployment changes made with strunzctl and audit package settings.", show, newAuditPackagesCommand())
+}
+
+// operatorEnv names the operator instead of the git identity, e.g. the user
+// a `strunzctl serve` job was requested by
+const operatorEnv = "STRUNZCTL_OPERATOR"
+
+// operator identifies who runs the tool: STRUNZCTL_OPERATOR, the git
+// identity, else the OS user
+func operator() string {
+	if name := os.Getenv(operatorEnv); name != "" {
+		return name
+	}
+	if email, err := runGit("config", "user.email"); err == nil && email != "" {
+		return email
+	}
+	if current, err := user.Current(); err == nil {
+		return current.Username
+	}
+	return "unknown"
+}
+
+// confirm asks a yes/no question on the terminal. Without a terminal the
+// caller has to pass --yes.
+func confirm(question string, yes bool) (bool, error) {
+	if yes {
+		return true, nil
+	}
+	if !isTerminal(os.Stdin) {
+		return false, fmt.Errorf("%w: stdin is not a terminal, pass --yes to confirm", errUsage)
+	}
+	fmt.Print(question + " [y/N] ")
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	return strings.EqualFold(strings.TrimSpace(answer), "y"), nil
+}
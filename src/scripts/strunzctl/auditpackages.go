@@ -0,0 +1,366 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// defaultPackagePolicy is read from the repository root
+const defaultPackagePolicy = ".github/packages-policy.yml"
+
+// packagePolicy is the declared state of one package. Nil Teams or Users
+// are not checked; an empty map allows nobody.
+type packagePolicy struct {
+	Visibility string
+	Repository string
+	Teams      map[string]string
+	Users      map[string]string
+}
+
+// packageRoles are the roles a package grants, weakest first
+var packageRoles = []string{"read", "write", "admin"}
+
+// packageRole maps a repository permission to the package role it grants
+// when the package inherits access from its repository
+func packageRole(permission string) string {
+	switch permission {
+	case "pull", "read", "triage":
+		return "read"
+	case "push", "write", "maintain":
+		return "write"
+	case "admin":
+		return "admin"
+	}
+	return permission
+}
+
+// parsePackagePolicy reads a file of the form
+//
+//	packages:
+//	  strunzknowledge:
+//	    visibility: public
+//	    repository: longevitycoach/StrunzKnowledge
+//	    teams:
+//	      maintainers: admin
+//	    users:
+//	      release-bot: write
+func parsePackagePolicy(path string) (map[string]*packagePolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	values, err := parseYAML(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", errUsage, path, err)
+	}
+	packages, ok := values["packages"].(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s: expected a packages: section", errUsage, path)
+	}
+
+	policies := make(map[string]*packagePolicy)
+	for name, value := range packages {
+		settings, ok := value.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s: packages.%s must be a section", errUsage, path, name)
+		}
+		policy := &packagePolicy{}
+		for key, value := range settings {
+			switch key {
+			case "visibility", "repository":
+				text, ok := value.(string)
+				if !ok {
+					return nil, fmt.Errorf("%w: %s: packages.%s.%s must be a value", errUsage, path, name, key)
+				}
+				if key == "repository" {
+					policy.Repository = text
+					continue
+				}
+				if text != "public" && text != "private" && text != "internal" {
+					return nil, fmt.Errorf("%w: %s: packages.%s.visibility must be public, private or internal", errUsage, path, name)
+				}
+				policy.Visibility = text
+			case "teams", "users":
+				access, ok := value.(map[string]any)
+				if !ok {
+					return nil, fmt.Errorf("%w: %s: packages.%s.%s must be a section of name: role", errUsage, path, name, key)
+				}
+				roles := make(map[string]string)
+				for who, raw := range access {
+					role, _ := raw.(string)
+					if !slices.Contains(packageRoles, role) {
+						return nil, fmt.Errorf("%w: %s: packages.%s.%s.%s must be one of %s", errUsage, path, name, key, who, strings.Join(packageRoles, ", "))
+					}
+					roles[who] = role
+				}
+				if key == "teams" {
+					policy.Teams = roles
+				} else {
+					policy.Users = roles
+				}
+			default:
+				return nil, fmt.Errorf("%w: %s: unknown setting packages.%s.%s", errUsage, path, name, key)
+			}
+		}
+		policies[name] = policy
+	}
+	return policies, nil
+}
+
+// orgPackage is an organization package with its linked repository
+type orgPackage struct {
+	Name       string `json:"name"`
+	Visibility string `json:"visibility"`
+	Repository *struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+}
+
+func (p orgPackage) linkedRepository() string {
+	if p.Repository == nil {
+		return ""
+	}
+	return p.Repository.FullName
+}
+
+func listOrgPackages(github *GitHubClient) ([]orgPackage, error) {
+	var packages []orgPackage
+	err := github.GetPagesResumable(fmt.Sprintf("/orgs/%s/packages?package_type=container", config.Org), globalOptions.resume, func(body io.Reader) error {
+		return decodeArray(body, "packages", func(pkg orgPackage) error {
+			packages = append(packages, pkg)
+			return nil
+		})
+	})
+	return packages, err
+}
+
+// repositoryAccess are the package roles a repository's teams and direct
+// collaborators get through inherited access
+type repositoryAccess struct {
+	Teams, Users map[string]string
+}
+
+func fetchRepositoryAccess(github *GitHubClient, repository string) (*repositoryAccess, error) {
+	access := &repositoryAccess{Teams: make(map[string]string), Users: make(map[string]string)}
+	err := github.GetPages(fmt.Sprintf("/repos/%s/teams", repository), func(body []byte) error {
+		var page []struct {
+			Slug       string `json:"slug"`
+			Permission string `json:"permission"`
+		}
+		if err := json.Unmarshal(body, &page); err != nil {
+			return fmt.Errorf("failed to parse teams: %w", err)
+		}
+		for _, team := range page {
+			access.Teams[team.Slug] = packageRole(team.Permission)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = github.GetPages(fmt.Sprintf("/repos/%s/collaborators?affiliation=direct", repository), func(body []byte) error {
+		var page []struct {
+			Login    string `json:"login"`
+			RoleName string `json:"role_name"`
+		}
+		if err := json.Unmarshal(body, &page); err != nil {
+			return fmt.Errorf("failed to parse collaborators: %w", err)
+		}
+		for _, user := range page {
+			access.Users[user.Login] = packageRole(user.RoleName)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return access, nil
+}
+
+// compareAccess reports the differences between declared and granted
+// roles of one kind, "team" or "user"
+func compareAccess(kind string, declared, granted map[string]string) (drift []string) {
+	names := make([]string, 0, len(declared)+len(granted))
+	for name := range declared {
+		names = append(names, name)
+	}
+	for name := range granted {
+		if _, ok := declared[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		want, declaredOK := declared[name]
+		have, grantedOK := granted[name]
+		switch {
+		case !grantedOK:
+			drift = append(drift, fmt.Sprintf("%s %s has no access, policy wants %s", kind, name, want))
+		case !declaredOK:
+			drift = append(drift, fmt.Sprintf("%s %s has %s, not declared", kind, name, have))
+		case want != have:
+			drift = append(drift, fmt.Sprintf("%s %s has %s, policy wants %s", kind, name, have, want))
+		}
+	}
+	return drift
+}
+
+// loadPackagePolicy parses the policy at path, by default the one in the
+// repository, and returns the path it read
+func loadPackagePolicy(path string) (string, map[string]*packagePolicy, error) {
+	if path == "" {
+		root, err := repoPath("")
+		if err != nil {
+			return "", nil, fmt.Errorf("%w: not in a git repository, pass --policy", errUsage)
+		}
+		path = filepath.Join(root, defaultPackagePolicy)
+	}
+	policies, err := parsePackagePolicy(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", nil, fmt.Errorf("%w: %v", errUsage, err)
+		}
+		return "", nil, err
+	}
+	return path, policies, nil
+}
+
+// checkPackagePolicy compares a package with its policy. Team and user
+// access is read from the linked repository through access, which callers
+// may cache.
+func checkPackagePolicy(pkg orgPackage, policy *packagePolicy, access func(repository string) (*repositoryAccess, error)) (drift, notes []string, err error) {
+	if policy.Visibility != "" && pkg.Visibility != policy.Visibility {
+		drift = append(drift, fmt.Sprintf("visibility is %s, policy wants %s", pkg.Visibility, policy.Visibility))
+	}
+	repository := pkg.linkedRepository()
+	if policy.Repository != "" && !strings.EqualFold(repository, policy.Repository) {
+		drift = append(drift, fmt.Sprintf("linked to %s, policy wants %s", orNone(repository), policy.Repository))
+	}
+	if policy.Teams == nil && policy.Users == nil {
+		return drift, notes, nil
+	}
+	if repository == "" {
+		// The API exposes access granted on the package itself nowhere
+		return drift, append(notes, "no linked repository, team and user access cannot be read through the API"), nil
+	}
+	granted, err := access(repository)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot read the access of %s (needs the repo scope and admin access): %w", repository, err)
+	}
+	if policy.Teams != nil {
+		drift = append(drift, compareAccess("team", policy.Teams, granted.Teams)...)
+	}
+	if policy.Users != nil {
+		drift = append(drift, compareAccess("user", policy.Users, granted.Users)...)
+	}
+	return drift, notes, nil
+}
+
+// printPackagePolicyResult prints the outcome of checkPackagePolicy for
+// the package shown as name
+func printPackagePolicyResult(name string, pkg orgPackage, drift, notes []string) {
+	status := "✅"
+	switch {
+	case len(drift) > 0:
+		status = "❌"
+	case len(notes) > 0:
+		status = "⚠️ "
+	}
+	fmt.Printf("\n%s %s (%s, %s)\n", status, name, pkg.Visibility, orNone(pkg.linkedRepository()))
+	for _, line := range drift {
+		fmt.Printf("   - %s\n", line)
+	}
+	for _, line := range notes {
+		fmt.Printf("   ⚠️  %s\n", line)
+	}
+}
+
+func newAuditPackagesCommand() *Command {
+	cmd := newCommand("packages", "", "Check the visibility, linked repository and team and user access of every GHCR package against a policy file.")
+	policyPath := cmd.Flags.String("policy", "", "package policy (default: "+defaultPackagePolicy+" in the repository)")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 0); err != nil {
+			return err
+		}
+		path, policies, err := loadPackagePolicy(*policyPath)
+		if err != nil {
+			return err
+		}
+
+		github, err := newGitHubClient()
+		if err != nil {
+			return err
+		}
+		packages, err := listOrgPackages(github)
+		if err != nil {
+			return err
+		}
+		slices.SortFunc(packages, func(a, b orgPackage) int { return strings.Compare(a.Name, b.Name) })
+
+		fmt.Printf("\n🔏 Package policy audit of %s (%s)\n", config.Org, path)
+		drifts, undeclared := 0, 0
+		accessByRepository := make(map[string]*repositoryAccess)
+		seen := make(map[string]bool)
+		for _, pkg := range packages {
+			seen[pkg.Name] = true
+			policy, ok := policies[pkg.Name]
+			if !ok {
+				undeclared++
+				continue
+			}
+			drift, notes, err := checkPackagePolicy(pkg, policy, func(repository string) (*repositoryAccess, error) {
+				if access, ok := accessByRepository[repository]; ok {
+					return access, nil
+				}
+				access, err := fetchRepositoryAccess(github, repository)
+				if err == nil {
+					accessByRepository[repository] = access
+				}
+				return access, err
+			})
+			if err != nil {
+				return err
+			}
+			printPackagePolicyResult(pkg.Name, pkg, drift, notes)
+			drifts += len(drift)
+		}
+
+		var missing []string
+		for name := range policies {
+			if !seen[name] {
+				missing = append(missing, name)
+			}
+		}
+		slices.Sort(missing)
+		for _, name := range missing {
+			fmt.Printf("\n❌ %s is declared but does not exist in %s\n", name, config.Org)
+			drifts++
+		}
+		if undeclared > 0 {
+			fmt.Printf("\n⚠️  %d package(s) are not declared in the policy:", undeclared)
+			for _, pkg := range packages {
+				if policies[pkg.Name] == nil {
+					fmt.Printf(" %s", pkg.Name)
+				}
+			}
+			fmt.Println()
+		}
+		if len(accessByRepository) > 0 {
+			fmt.Println("ℹ️  Team and user access is read from the linked repository, which packages inherit with \"Inherit access from source repository\"")
+		}
+
+		if drifts > 0 {
+			return fmt.Errorf("%w: %d setting(s) drifted from %s", errPolicy, drifts, path)
+		}
+		fmt.Printf("\n✅ %d package(s) match the policy\n", len(packages)-undeclared)
+		return nil
+	}
+	return cmd
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// buildCacheTag holds the buildx layer cache next to the images
+const buildCacheTag = "buildcache"
+
+// branchTagCharacters are not allowed in image tags; the metadata action
+// replaces them with dashes
+var branchTagCharacters = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// buildSource is the commit an image is built from
+type buildSource struct {
+	Revision string
+	Created  time.Time
+	// Tag is the v* tag at the commit, Branch the checked out branch
+	Tag, Branch string
+	Dirty       bool
+}
+
+func readBuildSource(root string) (*buildSource, error) {
+	revision, err := runGit("-C", root, "rev-parse", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	source := &buildSource{Revision: revision}
+	timestamp, err := runGit("-C", root, "log", "-1", "--format=%ct")
+	if err != nil {
+		return nil, err
+	}
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("unexpected commit time %q", timestamp)
+	}
+	source.Created = time.Unix(seconds, 0).UTC()
+	// Several tags may point at the commit; the highest version wins
+	tags, _ := runGit("-C", root, "tag", "--points-at", "HEAD", "--list", "v*")
+	var best Semver
+	for _, tag := range strings.Fields(tags) {
+		if version, ok := parseSemver(tag); ok && (source.Tag == "" || version.Compare(best) > 0) {
+			best, source.Tag = version, tag
+		}
+	}
+	source.Branch, _ = runGit("-C", root, "symbolic-ref", "--quiet", "--short", "HEAD")
+	status, err := runGit("-C", root, "status", "--porcelain", "--untracked-files=no")
+	if err != nil {
+		return nil, err
+	}
+	source.Dirty = status != ""
+	return source, nil
+}
+
+// imageTags follows the tag scheme of the publish workflow: a release tag
+// gives the version, major.minor, major and latest; prereleases only their
+// version. Branches are tagged with their name, main also latest.
+func imageTags(source *buildSource) []string {
+	if version, ok := parseSemver(source.Tag); ok {
+		if version.Prerelease != "" {
+			return []string{version.String()}
+		}
+		return []string{version.String(), fmt.Sprintf("%d.%d", version.Major, version.Minor), strconv.Itoa(version.Major), "latest"}
+	}
+	if source.Branch == "" {
+		return []string{"sha-" + source.Revision[:7]}
+	}
+	tags := []string{strings.Trim(branchTagCharacters.ReplaceAllString(source.Branch, "-"), "-.")}
+	if source.Branch == "main" {
+		tags = append(tags, "latest")
+	}
+	return tags
+}
+
+// imageBuildLabels are the OCI labels the publish workflow sets; the
+// description and license stay as the Dockerfile declares them
+func imageBuildLabels(source *buildSource, version string) map[string]string {
+	repository := "https://github.com/" + config.Repo
+	return map[string]string{
+		ociLabelPrefix + "title":    filepath.Base(config.Repo),
+		ociLabelPrefix + "url":      repository,
+		ociLabelPrefix + "source":   repository,
+		ociLabelPrefix + "version":  version,
+		ociLabelPrefix + "created":  source.Created.Format(time.RFC3339),
+		ociLabelPrefix + "revision": source.Revision,
+	}
+}
+
+// buildxArguments assembles the docker buildx build command line
+func buildxArguments(root, dockerfile string, platforms, references []string, labels map[string]string, source *buildSource, cacheRef string, push bool) []string {
+	args := []string{"buildx", "build", "--file", dockerfile, "--platform", strings.Join(platforms, ",")}
+	for _, reference := range references {
+		args = append(args, "--tag", reference)
+	}
+	for _, name := range slices.Sorted(maps.Keys(labels)) {
+		args = append(args, "--label", name+"="+labels[name])
+	}
+	if cacheRef != "" {
+		args = append(args, "--cache-from", "type=registry,ref="+cacheRef)
+		if push {
+			args = append(args, "--cache-to", "type=registry,ref="+cacheRef+",mode=max")
+		}
+	}
+	// The commit time instead of the build time keeps rebuilds of a commit
+	// identical
+	args = append(args, "--build-arg", "SOURCE_DATE_EPOCH="+strconv.FormatInt(source.Created.Unix(), 10))
+	if push {
+		args = append(args, "--push")
+	} else {
+		args = append(args, "--load")
+	}
+	return append(args, root)
+}
+
+func newImageBuildCommand() *Command {
+	cmd := newCommand("build", "", "Build the image with docker buildx using the labels, tags and layer cache of the publish workflow.")
+	platforms := cmd.Flags.String("platforms", defaultPlatform, "comma-separated platforms to build, e.g. "+defaultPlatforms)
+	push := cmd.Flags.Bool("push", false, "push the image and the layer cache to the registry (required for several platforms)")
+	tags := cmd.Flags.String("tags", "", "comma-separated tags instead of those derived from git")
+	dockerfile := cmd.Flags.String("dockerfile", "Dockerfile", "Dockerfile relative to the repository root")
+	noCache := cmd.Flags.Bool("no-cache", false, "neither read nor write the registry layer cache")
+	allowDirty := cmd.Flags.Bool("allow-dirty", false, "push even when tracked files have uncommitted changes")
+	dryRun := cmd.Flags.Bool("dry-run", false, "print the buildx command instead of running it")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 0); err != nil {
+			return err
+		}
+		selected := splitList(*platforms)
+		if len(selected) == 0 {
+			return fmt.Errorf("%w: --platforms is empty", errUsage)
+		}
+		// The local image store cannot hold a manifest list
+		if len(selected) > 1 && !*push {
+			return fmt.Errorf("%w: building %d platforms needs --push", errUsage, len(selected))
+		}
+		root, err := repoPath("")
+		if err != nil {
+			return err
+		}
+		source, err := readBuildSource(root)
+		if err != nil {
+			return err
+		}
+		if source.Dirty {
+			if *push && !*allowDirty && !*dryRun {
+				return fmt.Errorf("%w: the working tree has uncommitted changes, the image would not match revision %s (--allow-dirty)", errPolicy, source.Revision[:12])
+			}
+			fmt.Printf("⚠️  The working tree has uncommitted changes; the image is labeled %s but contains them\n", source.Revision[:12])
+		}
+
+		imageTagList := splitList(*tags)
+		if len(imageTagList) == 0 {
+			imageTagList = imageTags(source)
+		}
+		version := imageTagList[0]
+		registry := newRegistryClient(config.Registry, imageRepository())
+		var references []string
+		for _, tag := range imageTagList {
+			references = append(references, registry.Reference(tag))
+		}
+		cacheRef := ""
+		if !*noCache {
+			cacheRef = registry.Reference(buildCacheTag)
+		}
+		buildArgs := buildxArguments(root, filepath.Join(root, *dockerfile), selected, references,
+			imageBuildLabels(source, version), source, cacheRef, *push)
+
+		fmt.Printf("\n🏗️  Building %s for %s from %s\n", strings.Join(imageTagList, ", "), strings.Join(selected, ", "), source.Revision[:12])
+		if *dryRun {
+			quoted := make([]string, len(buildArgs))
+			for i, arg := range buildArgs {
+				quoted[i] = shellQuote(arg)
+			}
+			fmt.Printf("docker %s\n", strings.Join(quoted, " "))
+			return nil
+		}
+		if newProcess("docker", "buildx", "version").Run() != nil {
+			return fmt.Errorf("docker buildx is not installed; see https://docs.docker.com/build/install-buildx/")
+		}
+		build := newProcess("docker", buildArgs...)
+		build.Stdout, build.Stderr = os.Stdout, os.Stderr
+		if err := build.Run(); err != nil {
+			return fmt.Errorf("docker buildx build failed: %w", err)
+		}
+		if *push {
+			fmt.Printf("\n✅ Pushed %s\n", strings.Join(references, ", "))
+		} else {
+			fmt.Printf("\n✅ Built %s into the local image store\n", strings.Join(references, ", "))
+		}
+		return nil
+	}
+	return cmd
+}
+
+// shellQuote quotes an argument for pasting into a POSIX shell
+func shellQuote(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t\n'\"$`\\*?[]{}()<>|&;#~!") {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// versionLocation is a version string that has to match the release; the
+// first submatch of pattern is the version
+type versionLocation struct {
+	path    string
+	pattern *regexp.Regexp
+}
+
+// versionLocations lists every file that embeds the release version,
+// relative to the repository root
+var versionLocations = []versionLocation{
+	{"Dockerfile", regexp.MustCompile(`(?m)^\s+version="([^"]+)"`)},
+	{"Dockerfile", regexp.MustCompile(`org\.opencontainers\.image\.version="([^"]+)"`)},
+	{"src/mcp/sse_server_v8.py", regexp.MustCompile(`(?m)^\s+version="([^"]+)",`)},
+	{"src/mcp/sse_server_v8.py", regexp.MustCompile(`"version": "([^"]+)"`)},
+	{"src/mcp/mcp_server_clean.py", regexp.MustCompile(`server_version="([^"]+)"`)},
+}
+
+// versionEdit replaces one version string in a file
+type versionEdit struct {
+	path       string
+	line       int
+	start, end int
+	old        string
+}
+
+func newReleaseBumpCommand() *Command {
+	cmd := newCommand("bump", "<major|minor|patch|X.Y.Z>", "Compute the next version and rewrite every known version string.")
+	dryRun := cmd.Flags.Bool("dry-run", false, "show the diff without writing any file")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 1); err != nil {
+			return err
+		}
+		root, err := runGit("rev-parse", "--show-toplevel")
+		if err != nil {
+			return err
+		}
+		contents, edits, err := findVersionStrings(root)
+		if err != nil {
+			return err
+		}
+
+		current, err := currentVersion(edits)
+		if err != nil {
+			return err
+		}
+		next, err := nextVersion(current, args[0])
+		if err != nil {
+			return err
+		}
+		if next.Compare(current) <= 0 {
+			return fmt.Errorf("%w: %s is not newer than the current version %s", errUsage, next, current)
+		}
+
+		fmt.Printf("\n🔢 Bumping %s → %s\n\n", current, next)
+		updated := applyVersionEdits(contents, edits, next.String())
+		printVersionDiff(contents, updated, edits)
+
+		if *dryRun {
+			fmt.Println("\nDry run, nothing written")
+			return nil
+		}
+		if err := writeFilesAtomically(root, updated); err != nil {
+			return err
+		}
+		fmt.Printf("\n✅ Updated %d version string(s) in %d file(s)\n", len(edits), len(updated))
+		fmt.Printf("Next: commit the change, then run `strunzctl release create v%s`\n", next)
+		return nil
+	}
+	return cmd
+}
+
+// findVersionStrings reads every version location and fails if one of
+// them no longer matches, so a moved string is not silently skipped
+func findVersionStrings(root string) (map[string]string, []versionEdit, error) {
+	contents := make(map[string]string)
+	var edits []versionEdit
+	for _, location := range versionLocations {
+		content, ok := contents[location.path]
+		if !ok {
+			data, err := os.ReadFile(filepath.Join(root, location.path))
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to read version file: %w", err)
+			}
+			content = string(data)
+			contents[location.path] = content
+		}
+
+		matches := location.pattern.FindAllStringSubmatchIndex(content, -1)
+		if len(matches) == 0 {
+			return nil, nil, fmt.Errorf("no version string matching %s in %s", location.pattern, location.path)
+		}
+		for _, match := range matches {
+			edits = append(edits, versionEdit{
+				path:  location.path,
+				line:  strings.Count(content[:match[2]], "\n") + 1,
+				start: match[2],
+				end:   match[3],
+				old:   content[match[2]:match[3]],
+			})
+		}
+	}
+	return contents, edits, nil
+}
+
+// currentVersion returns the highest version found in the files and the
+// latest release tag, warning when they have drifted apart
+func currentVersion(edits []versionEdit) (Semver, error) {
+	var current Semver
+	found := false
+	consider := func(version Semver) {
+		if !found || version.Compare(current) > 0 {
+			current, found = version, true
+		}
+	}
+
+	seen := make(map[string][]string)
+	for _, edit := range edits {
+		version, ok := parseSemver(edit.old)
+		if !ok {
+			return Semver{}, fmt.Errorf("%s:%d: %q is not a semantic version", edit.path, edit.line, edit.old)
+		}
+		seen[version.String()] = append(seen[version.String()], fmt.Sprintf("%s:%d", edit.path, edit.line))
+		consider(version)
+	}
+
+	tags, err := runGit("tag", "--list", "v*")
+	if err != nil {
+		return Semver{}, err
+	}
+	for _, tag := range strings.Fields(tags) {
+		if version, ok := parseSemver(tag); ok {
+			consider(version)
+		}
+	}
+
+	if len(seen) > 1 {
+		fmt.Println("⚠️  Version strings have drifted:")
+		versions := make([]string, 0, len(seen))
+		for version := range seen {
+			versions = append(versions, version)
+		}
+		slices.Sort(versions)
+		for _, version := range versions {
+			fmt.Printf("  %-10s %s\n", version, strings.Join(seen[version], ", "))
+		}
+	}
+	return current, nil
+}
+
+// nextVersion applies a bump part or parses an explicit version
+func nextVersion(current Semver, part string) (Semver, error) {
+	next := Semver{Major: current.Major, Minor: current.Minor, Patch: current.Patch}
+	switch part {
+	case "major":
+		next.Major, next.Minor, next.Patch = next.Major+1, 0, 0
+	case "minor":
+		next.Minor, next.Patch = next.Minor+1, 0
+	case "patch":
+		// Bumping a prerelease releases it rather than skipping a patch
+		if current.Prerelease == "" {
+			next.Patch++
+		}
+	default:
+		version, ok := parseSemver(part)
+		if !ok {
+			return Semver{}, fmt.Errorf("%w: expected major, minor, patch or a version, got %q", errUsage, part)
+		}
+		next = version
+	}
+	return next, nil
+}
+
+// applyVersionEdits returns the rewritten content of each file
+func applyVersionEdits(contents map[string]string, edits []versionEdit, version string) map[string]string {
+	updated := make(map[string]string)
+	byPath := make(map[string][]versionEdit)
+	for _, edit := range edits {
+		byPath[edit.path] = append(byPath[edit.path], edit)
+	}
+	for path, fileEdits := range byPath {
+		// Replace from the end so earlier offsets stay valid
+		slices.SortFunc(fileEdits, func(a, b versionEdit) int { return b.start - a.start })
+		content := contents[path]
+		for _, edit := range fileEdits {
+			content = content[:edit.start] + version + content[edit.end:]
+		}
+		updated[path] = content
+	}
+	return updated
+}
+
+func printVersionDiff(contents, updated map[string]string, edits []versionEdit) {
+	for _, edit := range edits {
+		before := strings.Split(contents[edit.path], "\n")[edit.line-1]
+		after := strings.Split(updated[edit.path], "\n")[edit.line-1]
+		fmt.Printf("%s:%d\n", edit.path, edit.line)
+		fmt.Printf("  - %s\n", strings.TrimSpace(before))
+		fmt.Printf("  + %s\n", strings.TrimSpace(after))
+	}
+}
+
+// writeFilesAtomically writes every file to a temporary sibling first and
+// only renames them into place once all writes succeeded
+func writeFilesAtomically(root string, files map[string]string) error {
+	temps := make(map[string]string)
+	cleanup := func() {
+		for _, temp := range temps {
+			os.Remove(temp)
+		}
+	}
+
+	for path, content := range files {
+		target := filepath.Join(root, path)
+		info, err := os.Stat(target)
+		if err != nil {
+			cleanup()
+			return fmt.Errorf("failed to write version file: %w", err)
+		}
+		temp := target + ".strunzctl-tmp"
+		temps[target] = temp
+		if err := os.WriteFile(temp, []byte(content), info.Mode().Perm()); err != nil {
+			cleanup()
+			return fmt.Errorf("failed to write version file: %w", err)
+		}
+	}
+	for target, temp := range temps {
+		if err := os.Rename(temp, target); err != nil {
+			cleanup()
+			return fmt.Errorf("failed to replace %s: %w", target, err)
+		}
+		delete(temps, target)
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// cachedHeaders are the response headers replayed from the cache
+var cachedHeaders = []string{"Content-Type", "Docker-Content-Digest", "ETag", "Link"}
+
+// cacheDir returns (and creates) a subdirectory of ~/.cache/strunzctl.
+// Cached responses of private packages are for the current user only, so
+// the directories are private, including ones made by earlier versions.
+func cacheDir(sub string) (string, error) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate cache directory: %w", err)
+	}
+	dir := filepath.Join(base, "strunzctl", sub)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	for _, path := range []string{filepath.Join(base, "strunzctl"), dir} {
+		if err := os.Chmod(path, 0o700); err != nil {
+			return "", fmt.Errorf("failed to create cache directory: %w", err)
+		}
+	}
+	return dir, nil
+}
+
+// stateDir returns the strunzctl state directory sub, creating it. State
+// is kept unlike the cache, which cleaners and CI cache eviction may
+// delete: $XDG_STATE_HOME/strunzctl, else ~/.local/state/strunzctl.
+func stateDir(sub string) (string, error) {
+	base := os.Getenv("XDG_STATE_HOME")
+	if !filepath.IsAbs(base) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to locate state directory: %w", err)
+		}
+		base = filepath.Join(home, ".local", "state")
+	}
+	dir := filepath.Join(base, "strunzctl", sub)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("failed to create state directory: %w", err)
+	}
+	return dir, nil
+}
+
+// CachedResponse is a stored HTTP response body with its validators
+type CachedResponse struct {
+	URL      string            `json:"url"`
+	ETag     string            `json:"etag,omitempty"`
+	Header   map[string]string `json:"header"`
+	Body     []byte            `json:"body"`
+	StoredAt time.Time         `json:"stored_at"`
+}
+
+// HTTPHeader rebuilds the replayed response headers
+func (c *CachedResponse) HTTPHeader() http.Header {
+	header := make(http.Header)
+	for key, value := range c.Header {
+		header.Set(key, value)
+	}
+	return header
+}
+
+// responseCachePath keys entries by URL and credential, so responses seen
+// with one token are never served to another
+func responseCachePath(url, credential string) (string, error) {
+	dir, err := cacheDir("http")
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(credential + "\n" + url))
+	return filepath.Join(dir, hex.EncodeToString(sum[:])+".json"), nil
+}
+
+// loadCachedResponse returns the cached response for url, or nil
+func loadCachedResponse(url, credential string) *CachedResponse {
+	if globalOptions.noCache {
+		return nil
+	}
+	path, err := responseCachePath(url, credential)
+	if err != nil {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	var cached CachedResponse
+	if json.Unmarshal(data, &cached) != nil || cached.URL != url {
+		return nil
+	}
+	if globalOptions.offline {
+		// Offline every cached response is served as it is
+		recordOfflineSnapshot(url, cached.StoredAt)
+	}
+	return &cached
+}
+
+// storeCachedResponse saves a response; failures only cost the next run a
+// full request, so they are ignored
+func storeCachedResponse(url, credential string, header http.Header, body []byte) {
+	if globalOptions.noCache {
+		return
+	}
+	path, err := responseCachePath(url, credential)
+	if err != nil {
+		return
+	}
+
+	cached := CachedResponse{
+		URL:      url,
+		ETag:     header.Get("ETag"),
+		Header:   make(map[string]string),
+		Body:     body,
+		StoredAt: time.Now().UTC(),
+	}
+	for _, key := range cachedHeaders {
+		if value := header.Get(key); value != "" {
+			cached.Header[key] = value
+		}
+	}
+
+	data, err := json.Marshal(cached)
+	if err != nil {
+		return
+	}
+	tmp := path + ".tmp"
+	if os.WriteFile(tmp, data, 0o600) == nil {
+		os.Rename(tmp, path)
+	}
+}
+
+// clearResponseCache removes all cached HTTP responses and the
+// checkpoints of interrupted crawls
+func clearResponseCache() error {
+	for _, sub := range []string{"http", "crawls"} {
+		dir, err := cacheDir(sub)
+		if err != nil {
+			return err
+		}
+		if err := os.RemoveAll(dir); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to clear cache: %w", err)
+		}
+	}
+	return nil
+}
+
+func newCacheCommand() *Command {
+	cmd := newCommand("clear", "", "Remove all cached API responses and interrupted crawl checkpoints.")
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 0); err != nil {
+			return err
+		}
+		if err := clearResponseCache(); err != nil {
+			return err
+		}
+		fmt.Println("✅ Response cache cleared")
+		return nil
+	}
+	return newGroup("cache", "Manage the local HTTP response cache.", cmd)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+func newDeployCanaryCommand() *Command {
+	cmd := newCommand("canary", "<tag>", "Deploy a tag to the canary service, smoke test it and promote it to production on success.")
+	canaryService := cmd.Flags.String("canary-service", "", "Railway canary service name or ID (default: railway.canary)")
+	canaryURL := cmd.Flags.String("canary-url", "", "base URL of the canary (default: the deployment's Railway domain)")
+	productionTag := cmd.Flags.String("production-tag", "latest", "GHCR tag that marks the production image")
+	promote := cmd.Flags.Bool("promote", true, "retag and deploy to production when the smoke tests pass")
+	timeout := cmd.Flags.Duration("timeout", 15*time.Minute, "how long to wait for each deployment")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 1); err != nil {
+			return err
+		}
+		tag := args[0]
+		if *canaryService == "" {
+			*canaryService = config.Railway.Canary
+		}
+		if *canaryService == "" {
+			return fmt.Errorf("%w: set railway.canary or --canary-service", errUsage)
+		}
+
+		registry := newRegistryClient(config.Registry, imageRepository())
+		image := registry.Reference(tag)
+		if _, err := registry.GetRawManifest(tag); err != nil {
+			return err
+		}
+		railway, err := newRailwayClient()
+		if err != nil {
+			return err
+		}
+		canary, err := resolveRailwayTarget(railway, *canaryService)
+		if err != nil {
+			return err
+		}
+		production, err := resolveRailwayTarget(railway, "")
+		if err != nil {
+			return err
+		}
+		if canary.Service == production.Service {
+			return fmt.Errorf("%w: the canary service must differ from the production service %s", errUsage, production.ServiceName)
+		}
+
+		fmt.Printf("\n🐤 Deploying %s to canary %s\n", image, canary.ServiceName)
+		id, err := deployRailwayImage(railway, canary, image)
+		if err != nil {
+			return err
+		}
+		deployment, err := waitForDeployment(railway, id, *timeout, 10*time.Second)
+		if err != nil {
+			return err
+		}
+
+		url := *canaryURL
+		if url == "" {
+			if deployment.StaticURL == "" {
+				return fmt.Errorf("%w: canary %s has no public domain, pass --canary-url", errUsage, canary.ServiceName)
+			}
+			url = "https://" + deployment.StaticURL
+		}
+
+		// Only release tags carry a version /health can be compared with
+		version := ""
+		if _, ok := parseSemver(tag); ok {
+			version = tag
+		}
+		fmt.Printf("\n🧪 Smoke testing %s\n", url)
+		if failed := printSmokeChecks(runSmokeTests(url, version, smokeToolCalls)); failed > 0 {
+			return fmt.Errorf("%w: canary failed %d smoke test(s), production is unchanged", errPolicy, failed)
+		}
+		if !*promote {
+			fmt.Println("\n✅ Canary passed; not promoted (--promote=false)")
+			return nil
+		}
+
+		fmt.Printf("\n⬆️  Promoting %s → %s\n", tag, registry.Reference(*productionTag))
+		previous, digest, err := promoteTag(registry, tag, *productionTag, false)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("✅ %s moved from %s to %s\n", *productionTag, orNone(previous), digest)
+		if err := recordAudit(AuditEntry{Action: auditPromote, Tag: tag, Digest: digest, Target: *productionTag}); err != nil {
+			return err
+		}
+
+		fmt.Printf("\n🚂 Deploying %s to %s\n", image, production.ServiceName)
+		if id, err = deployRailwayImage(railway, production, image); err != nil {
+			return err
+		}
+		if _, err := waitForDeployment(railway, id, *timeout, 10*time.Second); err != nil {
+			return err
+		}
+		fmt.Printf("\n🎉 %s is live in production\n", tag)
+		return nil
+	}
+	return cmd
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// conventionalSubject matches "type(scope)!: description"
+var conventionalSubject = regexp.MustCompile(`^(\w+)(?:\(([^)]*)\))?(!)?:\s*(.+)$`)
+
+// changelogSections orders the Markdown sections; commit types not listed
+// end up in "Other Changes"
+var changelogSections = []struct {
+	title string
+	types []string
+}{
+	{"Features", []string{"feat"}},
+	{"Bug Fixes", []string{"fix"}},
+	{"Performance", []string{"perf"}},
+	{"Refactoring", []string{"refactor"}},
+	{"Documentation", []string{"docs"}},
+	{"Tests", []string{"test"}},
+	{"Build and CI", []string{"build", "ci"}},
+	{"Maintenance", []string{"chore", "style", "revert"}},
+}
+
+// Commit is a parsed git commit
+type Commit struct {
+	Hash        string
+	Type        string
+	Scope       string
+	Description string
+	Breaking    bool
+}
+
+func newReleaseChangelogCommand() *Command {
+	cmd := newCommand("changelog", "", "Generate Markdown release notes from conventional commits.")
+	from := cmd.Flags.String("from", "", "start revision, exclusive (default: the latest tag)")
+	to := cmd.Flags.String("to", "HEAD", "end revision, inclusive")
+	title := cmd.Flags.String("title", "", "release tag used as heading and compare link target (default: the --to revision)")
+	output := cmd.Flags.String("output", "", "write the notes to a file instead of stdout")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 0); err != nil {
+			return err
+		}
+		notes, err := generateChangelog(*from, *to, *title)
+		if err != nil {
+			return err
+		}
+		if *output == "" {
+			fmt.Print(notes)
+			return nil
+		}
+		if err := os.WriteFile(*output, []byte(notes), 0o644); err != nil {
+			return fmt.Errorf("failed to write changelog: %w", err)
+		}
+		fmt.Printf("✅ Changelog written to %s\n", *output)
+		return nil
+	}
+	return cmd
+}
+
+// generateChangelog renders the commits in (from, to] as Markdown
+func generateChangelog(from, to, title string) (string, error) {
+	if from == "" {
+		latest, err := runGit("describe", "--tags", "--abbrev=0", to+"^")
+		if err != nil {
+			return "", fmt.Errorf("no previous tag found, pass --from: %w", err)
+		}
+		from = latest
+	}
+	if title == "" {
+		title = to
+	}
+
+	commits, err := gitCommits(from, to)
+	if err != nil {
+		return "", err
+	}
+	return renderChangelog(title, from, commits), nil
+}
+
+// gitCommits lists non-merge commits in (from, to], newest first
+func gitCommits(from, to string) ([]Commit, error) {
+	// Unit and record separators cannot appear in commit messages
+	output, err := runGit("log", "--no-merges", "--format=%H%x1f%s%x1f%b%x1e", from+".."+to)
+	if err != nil {
+		return nil, err
+	}
+
+	var commits []Commit
+	for _, record := range strings.Split(output, "\x1e") {
+		fields := strings.Split(strings.TrimSpace(record), "\x1f")
+		if len(fields) < 2 {
+			continue
+		}
+		body := ""
+		if len(fields) > 2 {
+			body = fields[2]
+		}
+		commits = append(commits, parseCommit(fields[0], fields[1], body))
+	}
+	return commits, nil
+}
+
+func parseCommit(hash, subject, body string) Commit {
+	commit := Commit{Hash: hash, Type: "other", Description: subject}
+	if match := conventionalSubject.FindStringSubmatch(subject); match != nil {
+		commit.Type = strings.ToLower(match[1])
+		commit.Scope = match[2]
+		commit.Breaking = match[3] == "!"
+		commit.Description = match[4]
+	}
+	if strings.Contains(body, "BREAKING CHANGE:") || strings.Contains(body, "BREAKING-CHANGE:") {
+		commit.Breaking = true
+	}
+	return commit
+}
+
+// renderChangelog formats grouped commits; title is also the compare target
+// since it names the tag being released
+func renderChangelog(title, from string, commits []Commit) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## %s (%s)\n", title, time.Now().Format(time.DateOnly))
+
+	section := func(heading string, include func(Commit) bool) {
+		var lines []string
+		for _, commit := range commits {
+			if include(commit) {
+				lines = append(lines, changelogLine(commit))
+			}
+		}
+		if len(lines) > 0 {
+			fmt.Fprintf(&b, "\n### %s\n\n%s\n", heading, strings.Join(lines, "\n"))
+		}
+	}
+
+	section("⚠️ Breaking Changes", func(c Commit) bool { return c.Breaking })
+	known := make(map[string]bool)
+	for _, s := range changelogSections {
+		types := s.types
+		for _, t := range types {
+			known[t] = true
+		}
+		section(s.title, func(c Commit) bool { return slices.Contains(types, c.Type) })
+	}
+	section("Other Changes", func(c Commit) bool { return !known[c.Type] })
+
+	if len(commits) == 0 {
+		b.WriteString("\nNo changes.\n")
+	}
+	fmt.Fprintf(&b, "\n**Full changelog**: https://github.com/%s/compare/%s...%s\n", config.Repo, from, title)
+	return b.String()
+}
+
+func changelogLine(commit Commit) string {
+	line := "- "
+	if commit.Scope != "" {
+		line += "**" + commit.Scope + ":** "
+	}
+	return line + commit.Description + " (" + commit.Hash[:min(7, len(commit.Hash))] + ")"
+}
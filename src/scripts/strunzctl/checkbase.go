@@ -0,0 +1,298 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// baseImage is an image a Dockerfile builds FROM
+type baseImage struct {
+	// Reference is the image as written, build arguments expanded
+	Reference                  string
+	Host, Repository, Tag, Pin string
+	Locations                  []string
+	// Published is set for the base of the image CI publishes
+	Published bool
+}
+
+// parseBaseReference splits an image reference the way docker does: names
+// without a registry host are on Docker Hub, official images under library/
+func parseBaseReference(ref string) (host, repository, tag, pin string) {
+	ref, pin, _ = strings.Cut(ref, "@")
+	repository = ref
+	if i := strings.LastIndexByte(ref, ':'); i > strings.LastIndexByte(ref, '/') {
+		repository, tag = ref[:i], ref[i+1:]
+	}
+	host = "docker.io"
+	if first, rest, ok := strings.Cut(repository, "/"); ok && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		host, repository = first, rest
+	}
+	if host == "docker.io" && !strings.Contains(repository, "/") {
+		repository = "library/" + repository
+	}
+	if tag == "" && pin == "" {
+		tag = "latest"
+	}
+	return host, repository, tag, pin
+}
+
+// dockerfileBases returns the external images a Dockerfile builds on and
+// which of them the final stage ends up on. FROM lines naming an earlier
+// stage or scratch are no base images.
+func dockerfileBases(content string) (bases []*baseImage, final *baseImage, lines []int) {
+	global := make(map[string]string)
+	stages := make(map[string]*baseImage)
+	seenFrom := false
+	for _, instruction := range parseDockerfile(content) {
+		switch instruction.Keyword {
+		case "ARG":
+			// Only ARGs before the first FROM are in scope of FROM lines
+			if !seenFrom {
+				for _, word := range shellWords(instruction.Args) {
+					name, value, _ := strings.Cut(word, "=")
+					global[name] = value
+				}
+			}
+		case "FROM":
+			seenFrom = true
+			words := strings.Fields(instruction.Args)
+			for len(words) > 0 && strings.HasPrefix(words[0], "--") {
+				words = words[1:]
+			}
+			if len(words) == 0 {
+				continue
+			}
+			reference := os.Expand(words[0], func(name string) string { return global[name] })
+			var base *baseImage
+			if stage, ok := stages[strings.ToLower(reference)]; ok {
+				base = stage
+			} else if reference != "scratch" {
+				base = &baseImage{Reference: reference}
+				base.Host, base.Repository, base.Tag, base.Pin = parseBaseReference(reference)
+				bases = append(bases, base)
+				lines = append(lines, instruction.Line)
+			}
+			if len(words) >= 3 && strings.EqualFold(words[1], "AS") {
+				stages[strings.ToLower(words[2])] = base
+			}
+			final = base
+		}
+	}
+	return bases, final, lines
+}
+
+// hasLayerPrefix reports whether an image is built on a base image, which
+// is so when it starts with the base's layers
+func hasLayerPrefix(image, base []Descriptor) bool {
+	if len(base) == 0 || len(base) > len(image) {
+		return false
+	}
+	for i, layer := range base {
+		if image[i].Digest != layer.Digest {
+			return false
+		}
+	}
+	return true
+}
+
+// vulnerabilityDelta compares the findings of two images by ID and package
+func vulnerabilityDelta(before, after []Vulnerability) (fixed, introduced []Vulnerability) {
+	key := func(v Vulnerability) string { return v.ID + "\x00" + v.Package }
+	seen := func(vulns []Vulnerability) map[string]bool {
+		keys := make(map[string]bool, len(vulns))
+		for _, v := range vulns {
+			keys[key(v)] = true
+		}
+		return keys
+	}
+	beforeKeys, afterKeys := seen(before), seen(after)
+	for _, v := range before {
+		if !afterKeys[key(v)] {
+			fixed = append(fixed, v)
+			afterKeys[key(v)] = true
+		}
+	}
+	for _, v := range after {
+		if !beforeKeys[key(v)] {
+			introduced = append(introduced, v)
+			beforeKeys[key(v)] = true
+		}
+	}
+	return fixed, introduced
+}
+
+// severitySummary renders severity counts like ScanResult.Summary
+func severitySummary(vulns []Vulnerability) string {
+	result := &ScanResult{Counts: make(map[string]int)}
+	for _, v := range vulns {
+		result.Counts[v.Severity]++
+	}
+	return result.Summary()
+}
+
+// osVulnerabilities keeps the findings in distribution packages, which are
+// what a base image contributes
+func osVulnerabilities(vulns []Vulnerability) []Vulnerability {
+	return slices.DeleteFunc(slices.Clone(vulns), func(v Vulnerability) bool { return !v.OS })
+}
+
+func printVulnerabilityDelta(scanner, beforeRef, beforeDigest, afterRef, afterDigest string, osOnly bool) error {
+	before, err := scanImage(scanner, beforeRef, beforeDigest)
+	if err != nil {
+		return err
+	}
+	after, err := scanImage(scanner, afterRef, afterDigest)
+	if err != nil {
+		return err
+	}
+	beforeVulns, afterVulns := before.Vulnerabilities, after.Vulnerabilities
+	if osOnly {
+		beforeVulns, afterVulns = osVulnerabilities(beforeVulns), osVulnerabilities(afterVulns)
+	}
+	scope := scanner
+	if osOnly {
+		scope += ", distribution packages"
+	}
+	fixed, introduced := vulnerabilityDelta(beforeVulns, afterVulns)
+	fmt.Printf("     🛡️  CVE delta (%s): fixes %s, introduces %s\n", scope, severitySummary(fixed), severitySummary(introduced))
+	for _, v := range fixed {
+		if v.Severity == "CRITICAL" || v.Severity == "HIGH" {
+			fmt.Printf("        - fixes %-8s %-20s %s %s\n", v.Severity, v.ID, v.Package, v.InstalledVersion)
+		}
+	}
+	for _, v := range introduced {
+		if v.Severity == "CRITICAL" || v.Severity == "HIGH" {
+			fmt.Printf("        + adds  %-8s %-20s %s %s\n", v.Severity, v.ID, v.Package, v.InstalledVersion)
+		}
+	}
+	return nil
+}
+
+func newImageCheckBaseCommand() *Command {
+	cmd := newCommand("check-base", "[dockerfile...]", "Check the FROM images of the Dockerfiles for newer upstream digests and report the CVE delta of updating.")
+	tag := cmd.Flags.String("tag", "latest", "published tag whose base the root Dockerfile's final stage is compared with")
+	platform := cmd.Flags.String("platform", defaultPlatform, "platform to compare")
+	scanner := cmd.Flags.String("scanner", "trivy", "vulnerability scanner for the CVE delta (trivy or grype)")
+	noScan := cmd.Flags.Bool("no-scan", false, "skip the CVE delta")
+
+	cmd.Run = func(args []string) error {
+		root, err := repoPath("")
+		if err != nil {
+			return err
+		}
+		paths := args
+		if len(paths) == 0 {
+			if paths, err = repositoryDockerfiles(root); err != nil {
+				return err
+			}
+		}
+
+		// The same base in several Dockerfiles is checked once
+		var bases []*baseImage
+		byReference := make(map[string]*baseImage)
+		for _, path := range paths {
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("%w: %v", errUsage, err)
+			}
+			name := path
+			if rel, err := filepath.Rel(root, path); err == nil && !strings.HasPrefix(rel, "..") {
+				name = rel
+			}
+			found, final, lines := dockerfileBases(string(content))
+			for i, base := range found {
+				key := base.Host + "/" + base.Repository + ":" + base.Tag + "@" + base.Pin
+				existing, ok := byReference[key]
+				if !ok {
+					existing = base
+					byReference[key] = base
+					bases = append(bases, base)
+				}
+				existing.Locations = append(existing.Locations, fmt.Sprintf("%s:%d", name, lines[i]))
+				// The root Dockerfile builds the image CI publishes
+				if base == final && name == "Dockerfile" {
+					existing.Published = true
+				}
+			}
+		}
+		if len(bases) == 0 {
+			fmt.Println("No base images found")
+			return nil
+		}
+
+		published := newRegistryClient(config.Registry, imageRepository())
+		fmt.Printf("\n🧱 Checking %d base image(s) for %s\n", len(bases), *platform)
+		outdated := 0
+		for _, base := range bases {
+			where := strings.Join(base.Locations, ", ")
+			if base.Tag == "" {
+				fmt.Printf("\n📌 %s (%s)\n     pinned to a digest without a tag; nothing to compare with\n", base.Reference, where)
+				continue
+			}
+			upstream := newRegistryClient(base.Host, base.Repository)
+			current, err := upstream.GetManifest(base.Tag)
+			if err != nil {
+				return fmt.Errorf("%s: %w", base.Reference, err)
+			}
+			image, err := selectPlatform(upstream, current, *platform)
+			if err != nil {
+				return fmt.Errorf("%s: %w", base.Reference, err)
+			}
+			built := ""
+			if imageConfig, err := upstream.GetImageConfig(image); err == nil && !imageConfig.Created.IsZero() {
+				built = ", built " + imageConfig.Created.Format("2006-01-02")
+			}
+			pinLine := fmt.Sprintf("FROM %s/%s:%s@%s", base.Host, base.Repository, base.Tag, current.Digest)
+			if base.Host == "docker.io" {
+				pinLine = fmt.Sprintf("FROM %s:%s@%s", strings.TrimPrefix(base.Repository, "library/"), base.Tag, current.Digest)
+			}
+
+			var beforeRef, beforeDigest string
+			osOnly := false
+			switch {
+			case base.Pin != "":
+				if base.Pin == current.Digest || base.Pin == image.Digest {
+					fmt.Printf("\n✅ %s (%s)\n     the pinned digest is current%s\n", base.Reference, where, built)
+					continue
+				}
+				fmt.Printf("\n⬆️  %s (%s)\n     %s moved to %s%s; update the pin:\n     %s\n", base.Reference, where, base.Tag, shortDigest(current.Digest), built, pinLine)
+				beforeRef, beforeDigest = upstream.Reference(base.Pin), base.Pin
+			case base.Published:
+				ours, err := resolvePlatformManifest(published, *tag, *platform)
+				if err != nil {
+					return err
+				}
+				if hasLayerPrefix(ours.Layers, image.Layers) {
+					fmt.Printf("\n✅ %s (%s)\n     %s is built on the current %s%s\n", base.Reference, where, published.Reference(*tag), shortDigest(image.Digest), built)
+					continue
+				}
+				fmt.Printf("\n⬆️  %s (%s)\n     %s was built on an older %s; the current is %s%s, rebuild to pick it up\n",
+					base.Reference, where, published.Reference(*tag), base.Reference, shortDigest(image.Digest), built)
+				// The old base has no tag any more; the distribution packages
+				// of the published image are what it contributed
+				beforeRef, beforeDigest, osOnly = published.Reference(ours.Digest), ours.Digest, true
+			default:
+				fmt.Printf("\nℹ️  %s (%s)\n     not pinned, so builds take whatever %s is: now %s%s; pin it with:\n     %s\n",
+					base.Reference, where, base.Tag, shortDigest(image.Digest), built, pinLine)
+				continue
+			}
+
+			outdated++
+			if !*noScan {
+				if err := printVulnerabilityDelta(*scanner, beforeRef, beforeDigest, upstream.Reference(image.Digest), image.Digest, osOnly); err != nil {
+					return err
+				}
+			}
+		}
+
+		if outdated > 0 {
+			return fmt.Errorf("%w: %d base image(s) have newer digests", errPolicy, outdated)
+		}
+		fmt.Println("\n✅ No base image updates")
+		return nil
+	}
+	return cmd
+}
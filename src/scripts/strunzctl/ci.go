@@ -0,0 +1,204 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultWorkflow builds and publishes the image for main and release tags
+const defaultWorkflow = "docker-publish.yml"
+
+// Workflow is a GitHub Actions workflow definition
+type Workflow struct {
+	ID    int64  `json:"id"`
+	Name  string `json:"name"`
+	Path  string `json:"path"`
+	State string `json:"state"`
+}
+
+// WorkflowRun is one run of a workflow
+type WorkflowRun struct {
+	ID         int64     `json:"id"`
+	Name       string    `json:"name"`
+	RunNumber  int       `json:"run_number"`
+	HeadBranch string    `json:"head_branch"`
+	HeadSHA    string    `json:"head_sha"`
+	Path       string    `json:"path"`
+	Event      string    `json:"event"`
+	Status     string    `json:"status"`
+	Conclusion string    `json:"conclusion"`
+	HTMLURL    string    `json:"html_url"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// WorkflowJob is a job of a workflow run
+type WorkflowJob struct {
+	ID         int64  `json:"id"`
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Conclusion string `json:"conclusion"`
+	HTMLURL    string `json:"html_url"`
+	Steps      []struct {
+		Name       string `json:"name"`
+		Number     int    `json:"number"`
+		Conclusion string `json:"conclusion"`
+	} `json:"steps"`
+}
+
+// failedConclusions end a run or job unsuccessfully
+var failedConclusions = map[string]bool{
+	"failure": true, "cancelled": true, "timed_out": true, "action_required": true, "startup_failure": true,
+}
+
+func newCICommand() *Command {
+	return newGroup("ci", "Follow GitHub Actions workflows, triage their failures and clean up their storage.",
+		newCIWaitCommand(),
+		newCICleanArtifactsCommand(),
+		newCICleanCachesCommand(),
+		newCITriageCommand(),
+	)
+}
+
+func newCIWaitCommand() *Command {
+	cmd := newCommand("wait", "", "Wait for the workflow run of a ref to finish, streaming job status.")
+	workflow := cmd.Flags.String("workflow", defaultWorkflow, "workflow file name, name or ID")
+	ref := cmd.Flags.String("ref", "", "branch, tag or commit SHA the run was triggered for (required)")
+	timeout := cmd.Flags.Duration("timeout", 45*time.Minute, "how long to wait for the run")
+	interval := cmd.Flags.Duration("interval", 15*time.Second, "time between API checks")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 0); err != nil {
+			return err
+		}
+		if *ref == "" {
+			return fmt.Errorf("%w: --ref is required", errUsage)
+		}
+		github, err := newGitHubClient()
+		if err != nil {
+			return err
+		}
+		run, err := waitForWorkflow(github, *workflow, *ref, *timeout, *interval)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("\n✅ %s succeeded for %s: %s\n", run.Name, *ref, run.HTMLURL)
+		return nil
+	}
+	return cmd
+}
+
+// findWorkflow resolves a workflow by ID, file name or display name
+func findWorkflow(github *GitHubClient, name string) (*Workflow, error) {
+	var result struct {
+		Workflows []Workflow `json:"workflows"`
+	}
+	if err := github.Get(fmt.Sprintf("/repos/%s/actions/workflows", config.Repo), &result); err != nil {
+		return nil, err
+	}
+
+	var available []string
+	for i, workflow := range result.Workflows {
+		file := path.Base(workflow.Path)
+		if strconv.FormatInt(workflow.ID, 10) == name || file == name ||
+			strings.TrimSuffix(file, path.Ext(file)) == name || strings.EqualFold(workflow.Name, name) {
+			return &result.Workflows[i], nil
+		}
+		available = append(available, file)
+	}
+	return nil, fmt.Errorf("%w: workflow %q not found (available: %s)", errUsage, name, strings.Join(available, ", "))
+}
+
+// latestWorkflowRun returns the newest run of a workflow for a ref, or nil
+// if it has not started yet
+func latestWorkflowRun(github *GitHubClient, workflow *Workflow, ref string) (*WorkflowRun, error) {
+	query := url.Values{"per_page": {"1"}}
+	if isCommitSHA(ref) {
+		query.Set("head_sha", ref)
+	} else {
+		// Tag pushes and release events report the tag as head_branch
+		query.Set("branch", ref)
+	}
+	var result struct {
+		WorkflowRuns []WorkflowRun `json:"workflow_runs"`
+	}
+	err := github.Get(fmt.Sprintf("/repos/%s/actions/workflows/%d/runs?%s", config.Repo, workflow.ID, query.Encode()), &result)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.WorkflowRuns) == 0 {
+		return nil, nil
+	}
+	return &result.WorkflowRuns[0], nil
+}
+
+// waitForWorkflow polls until the latest run for a ref completes, printing
+// job status changes and failing as soon as a job fails
+func waitForWorkflow(github *GitHubClient, name, ref string, timeout, interval time.Duration) (*WorkflowRun, error) {
+	workflow, err := findWorkflow(github, name)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("\n⏳ Waiting for %s on %s\n", workflow.Name, ref)
+
+	deadline := time.Now().Add(timeout)
+	seen := make(map[int64]string)
+	announced := false
+	for {
+		run, err := latestWorkflowRun(github, workflow, ref)
+		if err != nil {
+			return nil, err
+		}
+
+		if run != nil {
+			if !announced {
+				fmt.Printf("Run: %s\n", run.HTMLURL)
+				announced = true
+			}
+			var jobs struct {
+				Jobs []WorkflowJob `json:"jobs"`
+			}
+			if err := github.Get(fmt.Sprintf("/repos/%s/actions/runs/%d/jobs", config.Repo, run.ID), &jobs); err != nil {
+				return nil, err
+			}
+			for _, job := range jobs.Jobs {
+				state := job.Status
+				if job.Conclusion != "" {
+					state = job.Conclusion
+				}
+				if seen[job.ID] != state {
+					seen[job.ID] = state
+					fmt.Printf("  %s  %-40s %s\n", time.Now().Format(time.TimeOnly), job.Name, state)
+				}
+				if failedConclusions[job.Conclusion] {
+					return run, fmt.Errorf("%w: job %q of %s finished with %s: %s", errPolicy, job.Name, workflow.Name, job.Conclusion, job.HTMLURL)
+				}
+			}
+
+			if run.Status == "completed" {
+				if run.Conclusion != "success" {
+					return run, fmt.Errorf("%w: %s finished with %s: %s", errPolicy, workflow.Name, run.Conclusion, run.HTMLURL)
+				}
+				return run, nil
+			}
+		}
+
+		if time.Now().Add(interval).After(deadline) {
+			if run == nil {
+				return nil, fmt.Errorf("no %s run for %s started within %s", workflow.Name, ref, timeout)
+			}
+			return run, fmt.Errorf("%s did not finish within %s (status %s)", workflow.Name, timeout, run.Status)
+		}
+		if err := sleepContext(interval); err != nil {
+			return run, err
+		}
+	}
+}
+
+// isCommitSHA reports whether ref is a full hexadecimal commit SHA
+func isCommitSHA(ref string) bool {
+	return len(ref) == 40 && strings.Trim(ref, "0123456789abcdef") == ""
+}
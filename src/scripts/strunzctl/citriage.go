@@ -0,0 +1,330 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// triageMarker identifies the tracking issue's body as generated
+const triageMarker = "<!-- strunzctl:ci-triage -->"
+
+// triageConclusions are the failures worth triaging; cancelled runs were
+// usually superseded by a newer push
+var triageConclusions = map[string]bool{"failure": true, "timed_out": true, "startup_failure": true}
+
+// actionsLogTimestamp prefixes every line of a job log
+var actionsLogTimestamp = regexp.MustCompile(`^\d{4}-\d\d-\d\dT\d\d:\d\d:\d\d(\.\d+)?Z ?`)
+
+// jobFailure is one failed job of a run
+type jobFailure struct {
+	Run  WorkflowRun
+	Job  WorkflowJob
+	Step string
+	// Error is the first error annotation, Tail the log lines up to the last
+	Error string
+	Tail  []string
+}
+
+// signature is what identical failures share
+func (f jobFailure) signature() string {
+	return strings.Join([]string{f.Run.Name, f.Job.Name, f.Step, logSignature(f.Error)}, "\x00")
+}
+
+// failedStep names the first step that failed
+func failedStep(job WorkflowJob) string {
+	for _, step := range job.Steps {
+		if triageConclusions[step.Conclusion] {
+			return step.Name
+		}
+	}
+	return ""
+}
+
+// parseJobLog returns the first meaningful error of a job log and the
+// lines leading up to its last error
+func parseJobLog(log string, lines int) (string, []string) {
+	var cleaned []string
+	for _, line := range strings.Split(strings.ReplaceAll(log, "\r\n", "\n"), "\n") {
+		line = actionsLogTimestamp.ReplaceAllString(line, "")
+		if strings.HasPrefix(line, "##[endgroup]") {
+			continue
+		}
+		cleaned = append(cleaned, strings.TrimPrefix(line, "##[group]"))
+	}
+	first, last, generic := "", -1, ""
+	for i, line := range cleaned {
+		message, ok := strings.CutPrefix(line, "##[error]")
+		if !ok {
+			continue
+		}
+		last = i
+		// Every failed step ends with this; the cause is printed before it
+		if strings.HasPrefix(message, "Process completed with exit code") {
+			generic = message
+			continue
+		}
+		if first == "" {
+			first = message
+		}
+	}
+	if last < 0 {
+		last = len(cleaned) - 1
+	}
+	for last > 0 && strings.TrimSpace(cleaned[last]) == "" {
+		last--
+	}
+	tail := cleaned[max(0, last+1-lines) : last+1]
+	if first == "" {
+		// The last output before the generic error usually says why
+		for i := len(tail) - 1; i >= 0; i-- {
+			if text := strings.TrimSpace(tail[i]); text != "" && !strings.HasPrefix(text, "##[error]") {
+				first = text
+				break
+			}
+		}
+	}
+	return cmp.Or(first, generic), tail
+}
+
+// failureGroup is a set of identical failures, newest first
+type failureGroup struct {
+	Failures []jobFailure
+}
+
+func (g failureGroup) latest() jobFailure {
+	return g.Failures[0]
+}
+
+// groupFailures merges identical failures, most frequent first
+func groupFailures(failures []jobFailure) []*failureGroup {
+	slices.SortFunc(failures, func(a, b jobFailure) int { return b.Run.CreatedAt.Compare(a.Run.CreatedAt) })
+	bySignature := make(map[string]*failureGroup)
+	var groups []*failureGroup
+	for _, failure := range failures {
+		group, ok := bySignature[failure.signature()]
+		if !ok {
+			group = &failureGroup{}
+			bySignature[failure.signature()] = group
+			groups = append(groups, group)
+		}
+		group.Failures = append(group.Failures, failure)
+	}
+	slices.SortStableFunc(groups, func(a, b *failureGroup) int { return len(b.Failures) - len(a.Failures) })
+	return groups
+}
+
+// maxIssueBody stays below GitHub's 65536 character limit
+const maxIssueBody = 60000
+
+// renderTriage writes the tracking issue's body
+func renderTriage(groups []*failureGroup, runs int, since time.Duration) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n_Updated %s by `strunzctl ci triage`: %d failed run(s) in the last %s, %d distinct failure(s)._\n",
+		triageMarker, time.Now().UTC().Format("2006-01-02 15:04 UTC"), runs, formatAge(since), len(groups))
+	for i, group := range groups {
+		latest := group.latest()
+		var section strings.Builder
+		title := latest.Run.Name + " / " + latest.Job.Name
+		if latest.Step != "" {
+			title += " / " + latest.Step
+		}
+		fmt.Fprintf(&section, "\n## %d. %s (%d×)\n\n", i+1, title, len(group.Failures))
+		if latest.Error != "" {
+			fmt.Fprintf(&section, "> %s\n\n", truncate(latest.Error, 300))
+		}
+		var branches, links []string
+		for _, failure := range group.Failures {
+			if !slices.Contains(branches, "`"+failure.Run.HeadBranch+"`") && failure.Run.HeadBranch != "" {
+				branches = append(branches, "`"+failure.Run.HeadBranch+"`")
+			}
+			if len(links) < 10 {
+				links = append(links, fmt.Sprintf("[#%d](%s)", failure.Run.RunNumber, failure.Job.HTMLURL))
+			}
+		}
+		oldest := group.Failures[len(group.Failures)-1]
+		fmt.Fprintf(&section, "- **Seen**: %s to %s on %s\n- **Runs**: %s\n",
+			oldest.Run.CreatedAt.UTC().Format(time.DateTime), latest.Run.CreatedAt.UTC().Format(time.DateTime),
+			orNone(strings.Join(branches, ", ")), strings.Join(links, " "))
+		if len(latest.Tail) > 0 {
+			fmt.Fprintf(&section, "\n<details><summary>Log tail of the latest run</summary>\n\n```\n%s\n```\n\n</details>\n",
+				strings.ReplaceAll(strings.Join(latest.Tail, "\n"), "```", "'''"))
+		}
+		if b.Len()+section.Len() > maxIssueBody {
+			fmt.Fprintf(&b, "\n_%d more failure(s) omitted, see `strunzctl ci triage --dry-run`._\n", len(groups)-i)
+			break
+		}
+		b.WriteString(section.String())
+	}
+	return b.String()
+}
+
+// GitHubTrackingIssue is an open issue carrying the triage label
+type GitHubTrackingIssue struct {
+	Number  int    `json:"number"`
+	HTMLURL string `json:"html_url"`
+}
+
+func findTrackingIssue(github *GitHubClient, label string) (*GitHubTrackingIssue, error) {
+	query := url.Values{"labels": {label}, "state": {"open"}, "per_page": {"1"}}
+	var issues []GitHubTrackingIssue
+	if err := github.Get(fmt.Sprintf("/repos/%s/issues?%s", config.Repo, query.Encode()), &issues); err != nil {
+		return nil, err
+	}
+	if len(issues) == 0 {
+		return nil, nil
+	}
+	return &issues[0], nil
+}
+
+func newCITriageCommand() *Command {
+	cmd := newCommand("triage", "", "Summarize recent failed workflow runs by failing step and log tail, grouping identical failures, in one tracking issue.")
+	since := cmd.Flags.String("since", "24h", "how far back to look for failed runs (e.g. 24h, 7d)")
+	workflow := cmd.Flags.String("workflow", "", "only runs of this workflow (file name, name or ID)")
+	branch := cmd.Flags.String("branch", "", "only runs on this branch (default: all)")
+	lines := cmd.Flags.Int("lines", 30, "log lines kept per failure")
+	label := cmd.Flags.String("label", "ci-triage", "label identifying the tracking issue")
+	title := cmd.Flags.String("title", "CI failure triage", "title of a new tracking issue")
+	closeGreen := cmd.Flags.Bool("close", true, "close the tracking issue when no run failed")
+	dryRun := cmd.Flags.Bool("dry-run", false, "print the summary instead of updating the issue")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 0); err != nil {
+			return err
+		}
+		window, err := parseAge(*since)
+		if err != nil {
+			return fmt.Errorf("%w: --since: %v", errUsage, err)
+		}
+		if *lines < 1 {
+			return fmt.Errorf("%w: --lines must be at least 1", errUsage)
+		}
+		github, err := newGitHubClient()
+		if err != nil {
+			return err
+		}
+
+		runsPath := fmt.Sprintf("/repos/%s/actions/runs", config.Repo)
+		if *workflow != "" {
+			found, err := findWorkflow(github, *workflow)
+			if err != nil {
+				return err
+			}
+			runsPath = fmt.Sprintf("/repos/%s/actions/workflows/%d/runs", config.Repo, found.ID)
+		}
+		query := url.Values{"status": {"completed"}, "created": {">=" + time.Now().Add(-window).UTC().Format(time.RFC3339)}}
+		if *branch != "" {
+			query.Set("branch", *branch)
+		}
+		var runs []WorkflowRun
+		err = github.GetPages(runsPath+"?"+query.Encode(), func(body []byte) error {
+			var page struct {
+				WorkflowRuns []WorkflowRun `json:"workflow_runs"`
+			}
+			if err := json.Unmarshal(body, &page); err != nil {
+				return fmt.Errorf("failed to parse workflow runs: %w", err)
+			}
+			for _, run := range page.WorkflowRuns {
+				if triageConclusions[run.Conclusion] {
+					runs = append(runs, run)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		perRun := make([][]jobFailure, len(runs))
+		err = forEachConcurrent(runs, globalOptions.concurrency, func(i int, run WorkflowRun) error {
+			var jobs struct {
+				Jobs []WorkflowJob `json:"jobs"`
+			}
+			if err := github.Get(fmt.Sprintf("/repos/%s/actions/runs/%d/jobs?filter=latest", config.Repo, run.ID), &jobs); err != nil {
+				return err
+			}
+			for _, job := range jobs.Jobs {
+				if !triageConclusions[job.Conclusion] {
+					continue
+				}
+				failure := jobFailure{Run: run, Job: job, Step: failedStep(job)}
+				log, err := github.GetText(fmt.Sprintf("/repos/%s/actions/jobs/%d/logs", config.Repo, job.ID))
+				if err != nil {
+					// Logs expire with the retention period
+					failure.Error = "log unavailable: " + errorClass(err)
+				} else {
+					failure.Error, failure.Tail = parseJobLog(string(log), *lines)
+				}
+				perRun[i] = append(perRun[i], failure)
+			}
+			if len(perRun[i]) == 0 {
+				// A run can fail before any job starts, e.g. on an invalid workflow file
+				perRun[i] = []jobFailure{{Run: run, Job: WorkflowJob{Name: "(no job)", HTMLURL: run.HTMLURL}, Error: run.Conclusion}}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		var failures []jobFailure
+		for _, run := range perRun {
+			failures = append(failures, run...)
+		}
+		groups := groupFailures(failures)
+
+		fmt.Printf("\n🩹 %d failed run(s) in the last %s, %d distinct failure(s)\n", len(runs), *since, len(groups))
+		for _, group := range groups {
+			latest := group.latest()
+			fmt.Printf("  %3d× %s / %s / %s\n       %s\n", len(group.Failures), latest.Run.Name, latest.Job.Name,
+				orNone(latest.Step), truncate(latest.Error, 100))
+		}
+		body := renderTriage(groups, len(runs), window)
+		if *dryRun {
+			fmt.Printf("\n%s", body)
+			return nil
+		}
+
+		issue, err := findTrackingIssue(github, *label)
+		if err != nil {
+			return err
+		}
+		switch {
+		case len(groups) == 0 && issue == nil:
+			fmt.Println("\n✅ No failures and no tracking issue open")
+		case len(groups) == 0:
+			if !*closeGreen {
+				fmt.Printf("\n✅ No failures; %s stays open (--close=false)\n", issue.HTMLURL)
+				return nil
+			}
+			path := fmt.Sprintf("/repos/%s/issues/%d", config.Repo, issue.Number)
+			comment := fmt.Sprintf("No failed workflow runs in the last %s, closing.", *since)
+			if err := github.Send(http.MethodPost, path+"/comments", map[string]string{"body": comment}, nil); err != nil {
+				return err
+			}
+			if err := github.Send(http.MethodPatch, path, map[string]string{"state": "closed", "state_reason": "completed"}, nil); err != nil {
+				return err
+			}
+			fmt.Printf("\n✅ No failures, closed %s\n", issue.HTMLURL)
+		case issue == nil:
+			var created GitHubTrackingIssue
+			request := map[string]any{"title": *title, "body": body, "labels": []string{*label}}
+			if err := github.Send(http.MethodPost, fmt.Sprintf("/repos/%s/issues", config.Repo), request, &created); err != nil {
+				return fmt.Errorf("failed to open the tracking issue: %w", err)
+			}
+			fmt.Printf("\n📌 Opened %s\n", created.HTMLURL)
+		default:
+			if err := github.Send(http.MethodPatch, fmt.Sprintf("/repos/%s/issues/%d", config.Repo, issue.Number), map[string]string{"body": body}, nil); err != nil {
+				return fmt.Errorf("failed to update the tracking issue: %w", err)
+			}
+			fmt.Printf("\n📌 Updated %s\n", issue.HTMLURL)
+		}
+		return nil
+	}
+	return cmd
+}
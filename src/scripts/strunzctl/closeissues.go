@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// issueReference matches GitHub's closing keywords, "fixes #12" or
+// "closes owner/repo#12"
+var issueReference = regexp.MustCompile(`(?i)\b(?:close[sd]?|fix(?:e[sd])?|resolve[sd]?):?\s+(?:([\w.-]+/[\w.-]+))?#(\d+)\b`)
+
+// releasedMarker tags the comment so running again does not repeat it
+const releasedMarker = "<!-- strunzctl:released %s -->"
+
+// fixedIssues returns the issue numbers of this repository the text
+// references with a closing keyword, in order of appearance
+func fixedIssues(text string) []int {
+	var issues []int
+	for _, match := range issueReference.FindAllStringSubmatch(text, -1) {
+		if match[1] != "" && !strings.EqualFold(match[1], config.Repo) {
+			continue
+		}
+		if n, err := strconv.Atoi(match[2]); err == nil && !slices.Contains(issues, n) {
+			issues = append(issues, n)
+		}
+	}
+	return issues
+}
+
+// releaseChangelog is the text of a release: the messages of the commits
+// since the previous tag and the published notes
+func releaseChangelog(from, tag string, release *GitHubRelease) (string, error) {
+	if from == "" {
+		previous, err := runGit("describe", "--tags", "--abbrev=0", tag+"^")
+		if err != nil {
+			return "", fmt.Errorf("no previous tag found, pass --from: %w", err)
+		}
+		from = previous
+	}
+	log, err := runGit("log", "--format=%B", from+".."+tag)
+	if err != nil {
+		return "", err
+	}
+	return log + "\n" + release.Body, nil
+}
+
+// GitHubIssue is the part of an issue close-issues needs; pull requests
+// are issues too and carry pull_request
+type GitHubIssue struct {
+	Number      int    `json:"number"`
+	Title       string `json:"title"`
+	State       string `json:"state"`
+	HTMLURL     string `json:"html_url"`
+	PullRequest *struct {
+		URL string `json:"url"`
+	} `json:"pull_request"`
+}
+
+// hasReleasedComment reports whether the issue was already linked to tag
+func hasReleasedComment(github *GitHubClient, number int, tag string) (bool, error) {
+	marker := fmt.Sprintf(releasedMarker, tag)
+	found := false
+	err := github.GetPages(fmt.Sprintf("/repos/%s/issues/%d/comments", config.Repo, number), func(body []byte) error {
+		var comments []struct {
+			Body string `json:"body"`
+		}
+		if err := json.Unmarshal(body, &comments); err != nil {
+			return fmt.Errorf("failed to parse comments: %w", err)
+		}
+		for _, comment := range comments {
+			found = found || strings.Contains(comment.Body, marker)
+		}
+		return nil
+	})
+	return found, err
+}
+
+func newReleaseCloseIssuesCommand() *Command {
+	cmd := newCommand("close-issues", "<vX.Y.Z>", "Comment on and close the issues the release's commits and notes reference with \"fixes #N\", linking the release and the GHCR tag.")
+	from := cmd.Flags.String("from", "", "previous release, exclusive (default: the tag before)")
+	dryRun := cmd.Flags.Bool("dry-run", false, "list the issues without commenting or closing")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 1); err != nil {
+			return err
+		}
+		version, ok := parseSemver(args[0])
+		if !ok {
+			return fmt.Errorf("%w: %q is not a semantic version", errUsage, args[0])
+		}
+		tag := "v" + version.String()
+		imageTag := version.String()
+
+		github, err := newGitHubClient()
+		if err != nil {
+			return err
+		}
+		release, err := findRelease(github, tag)
+		if err != nil {
+			return err
+		}
+		if release == nil {
+			return fmt.Errorf("no GitHub release for %s, run `strunzctl release create %s` first", tag, tag)
+		}
+		registry := newRegistryClient(config.Registry, imageRepository())
+		manifest, err := registry.GetRawManifest(imageTag)
+		if err != nil {
+			return fmt.Errorf("%s is not published yet, close the issues once the image is: %w", registry.Reference(imageTag), err)
+		}
+		changelog, err := releaseChangelog(*from, tag, release)
+		if err != nil {
+			return err
+		}
+
+		issues := fixedIssues(changelog)
+		fmt.Printf("\n🔗 %s fixes %d issue(s)\n", tag, len(issues))
+		if len(issues) == 0 {
+			return nil
+		}
+		comment := fmt.Sprintf("Released in [%s](%s).\n\n```bash\ndocker pull %s\n```\n\nImage: `%s`\n\n"+releasedMarker+"\n",
+			tag, release.HTMLURL, registry.Reference(imageTag), registry.Reference(manifest.Digest), tag)
+
+		closed, commented := 0, 0
+		for _, number := range issues {
+			var issue GitHubIssue
+			if err := github.Get(fmt.Sprintf("/repos/%s/issues/%d", config.Repo, number), &issue); err != nil {
+				if isNotFound(err) {
+					fmt.Printf("⚠️  #%d does not exist\n", number)
+					continue
+				}
+				return err
+			}
+			if issue.PullRequest != nil {
+				fmt.Printf("ℹ️  #%d is a pull request, skipped\n", number)
+				continue
+			}
+			done, err := hasReleasedComment(github, number, tag)
+			if err != nil {
+				return err
+			}
+			if done && issue.State == "closed" {
+				fmt.Printf("✅ #%d %s already closed with %s\n", number, truncate(issue.Title, 60), tag)
+				continue
+			}
+			if *dryRun {
+				fmt.Printf("  #%d %s (%s): would comment and close\n", number, truncate(issue.Title, 60), issue.State)
+				continue
+			}
+
+			path := fmt.Sprintf("/repos/%s/issues/%d", config.Repo, number)
+			if !done {
+				if err := github.Send(http.MethodPost, path+"/comments", map[string]string{"body": comment}, nil); err != nil {
+					return fmt.Errorf("failed to comment on #%d: %w", number, err)
+				}
+				commented++
+			}
+			if issue.State != "closed" {
+				if err := github.Send(http.MethodPatch, path, map[string]string{"state": "closed", "state_reason": "completed"}, nil); err != nil {
+					return fmt.Errorf("failed to close #%d: %w", number, err)
+				}
+				closed++
+			}
+			fmt.Printf("✅ #%d %s\n", number, truncate(issue.Title, 60))
+		}
+		if *dryRun {
+			fmt.Println("\nDry run: no issues changed")
+			return nil
+		}
+		fmt.Printf("\n✅ Commented on %d and closed %d issue(s)\n", commented, closed)
+		return nil
+	}
+	return cmd
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// errUsage marks errors caused by invalid command-line usage
+var errUsage = errors.New("invalid usage")
+
+// Command is a node in the CLI command tree. Leaf commands set Run,
+// group commands hold Subcommands. A group that also sets Run runs it
+// unless its first argument names a subcommand.
+type Command struct {
+	Name        string
+	Args        string
+	Summary     string
+	Flags       *flag.FlagSet
+	Run         func(args []string) error
+	Subcommands []*Command
+	// Before runs after a group's flags are parsed, before dispatch
+	Before func() error
+	// Hidden commands are omitted from usage output
+	Hidden bool
+
+	parent *Command
+}
+
+func newCommand(name, args, summary string) *Command {
+	cmd := &Command{
+		Name:    name,
+		Args:    args,
+		Summary: summary,
+		Flags:   flag.NewFlagSet(name, flag.ContinueOnError),
+	}
+	cmd.Flags.Usage = cmd.printUsage
+	return cmd
+}
+
+func newGroup(name, summary string, subcommands ...*Command) *Command {
+	cmd := newCommand(name, "<command>", summary)
+	for _, sub := range subcommands {
+		cmd.AddCommand(sub)
+	}
+	return cmd
+}
+
+// AddCommand attaches a subcommand
+func (c *Command) AddCommand(sub *Command) {
+	sub.parent = c
+	c.Subcommands = append(c.Subcommands, sub)
+}
+
+// Path returns the full invocation path, e.g. "strunzctl image manifest"
+func (c *Command) Path() string {
+	if c.parent == nil {
+		return c.Name
+	}
+	return c.parent.Path() + " " + c.Name
+}
+
+// Execute parses flags and dispatches to the matching subcommand or Run
+func (c *Command) Execute(args []string) error {
+	if len(c.Subcommands) > 0 && (c.Run == nil || len(args) > 0 && c.find(args[0]) != nil) {
+		// Group flags must precede the subcommand name
+		if err := c.Flags.Parse(args); err != nil {
+			return flagError(err)
+		}
+		args = c.Flags.Args()
+		if c.Before != nil {
+			if err := c.Before(); err != nil {
+				return err
+			}
+		}
+		if len(args) == 0 {
+			c.printUsage()
+			return errUsage
+		}
+
+		sub := c.find(args[0])
+		if sub == nil {
+			c.printUsage()
+			return fmt.Errorf("%w: unknown command %q for %s", errUsage, args[0], c.Path())
+		}
+		return sub.Execute(args[1:])
+	}
+
+	positional, err := parseInterspersed(c.Flags, args)
+	if err != nil {
+		return flagError(err)
+	}
+	return c.Run(positional)
+}
+
+func (c *Command) find(name string) *Command {
+	for _, sub := range c.Subcommands {
+		if sub.Name == name {
+			return sub
+		}
+	}
+	return nil
+}
+
+// ExactArgs validates the number of positional arguments
+func (c *Command) ExactArgs(args []string, n int) error {
+	if len(args) != n {
+		return fmt.Errorf("%w: %s expects %d argument(s): %s", errUsage, c.Path(), n, c.Args)
+	}
+	return nil
+}
+
+func (c *Command) printUsage() {
+	out := os.Stderr
+	fmt.Fprintf(out, "Usage: %s", c.Path())
+	if c.Args != "" {
+		fmt.Fprintf(out, " %s", c.Args)
+	}
+	fmt.Fprintln(out, " [flags]")
+	if c.Summary != "" {
+		fmt.Fprintf(out, "\n%s\n", c.Summary)
+	}
+
+	if len(c.Subcommands) > 0 {
+		fmt.Fprintln(out, "\nCommands:")
+		for _, sub := range c.Subcommands {
+			if !sub.Hidden {
+				fmt.Fprintf(out, "  %-16s %s\n", sub.Name, sub.Summary)
+			}
+		}
+	}
+
+	hasFlags := false
+	c.Flags.VisitAll(func(*flag.Flag) { hasFlags = true })
+	if hasFlags {
+		fmt.Fprintln(out, "\nFlags:")
+		c.Flags.SetOutput(out)
+		c.Flags.PrintDefaults()
+	}
+
+	// Group flags precede the subcommand name, so a flag of this command
+	// named like a global one is this command's own
+	root := c
+	for root.parent != nil {
+		root = root.parent
+	}
+	if root == c {
+		return
+	}
+	path := strings.TrimPrefix(c.Path(), root.Name+" ")
+	c.Flags.VisitAll(func(f *flag.Flag) {
+		if root.Flags.Lookup(f.Name) != nil {
+			fmt.Fprintf(out, "\n--%s is this command's own flag; the global --%s goes before the command name: %s --%s <value> %s\n", f.Name, f.Name, root.Name, f.Name, path)
+		}
+	})
+}
+
+// parseInterspersed parses flags that may appear before, between or after
+// positional arguments. Everything after a literal "--" is positional.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		rest := fs.Args()
+		if consumed := len(args) - len(rest); consumed > 0 && args[consumed-1] == "--" {
+			return append(positional, rest...), nil
+		}
+		if len(rest) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+}
+
+func flagError(err error) error {
+	if errors.Is(err, flag.ErrHelp) {
+		return err
+	}
+	return fmt.Errorf("%w: %v", errUsage, err)
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
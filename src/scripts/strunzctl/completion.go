@@ -0,0 +1,173 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Completion scripts delegate to the hidden __complete command, so command,
+// flag and tag candidates always match the installed binary
+const bashCompletion = `# bash completion for strunzctl
+_strunzctl() {
+    local IFS=$'\n'
+    mapfile -t COMPREPLY < <(strunzctl __complete -- "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null)
+}
+complete -o default -F _strunzctl strunzctl
+`
+
+const zshCompletion = `#compdef strunzctl
+_strunzctl() {
+    local -a candidates
+    candidates=(${(f)"$(strunzctl __complete -- "${(@)words[2,CURRENT]}" 2>/dev/null)"})
+    compadd -a candidates
+}
+compdef _strunzctl strunzctl
+`
+
+const fishCompletion = `# fish completion for strunzctl
+complete -c strunzctl -f -a '(strunzctl __complete -- (commandline -opc)[2..-1] (commandline -ct) 2>/dev/null)'
+`
+
+func newCompletionCommand() *Command {
+	cmd := newCommand("completion", "<bash|zsh|fish>", "Print a shell completion script.")
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 1); err != nil {
+			return err
+		}
+		scripts := map[string]string{"bash": bashCompletion, "zsh": zshCompletion, "fish": fishCompletion}
+		script, ok := scripts[args[0]]
+		if !ok {
+			return fmt.Errorf("%w: unsupported shell %q", errUsage, args[0])
+		}
+		fmt.Print(script)
+		return nil
+	}
+	return cmd
+}
+
+// newCompleteCommand prints candidates for the last of the given words,
+// one per line. The words exclude the program name and follow "--" so they
+// are not parsed as flags; the last may be empty.
+func newCompleteCommand(root *Command) *Command {
+	cmd := newCommand("__complete", "<words>...", "Print completion candidates.")
+	cmd.Hidden = true
+	cmd.Run = func(args []string) error {
+		if len(args) == 0 {
+			args = []string{""}
+		}
+		for _, candidate := range completeWords(root, args) {
+			fmt.Println(candidate)
+		}
+		return nil
+	}
+	return cmd
+}
+
+// completeWords walks the command tree along words and returns the
+// candidates for the final word
+func completeWords(root *Command, words []string) []string {
+	cmd, positional := root, 0
+	previous := words[:len(words)-1]
+	current := words[len(words)-1]
+
+	for i := 0; i < len(previous); i++ {
+		word := previous[i]
+		switch {
+		case word == "--":
+			positional += len(previous) - i - 1
+			i = len(previous)
+		case strings.HasPrefix(word, "-"):
+			if flagTakesValue(cmd.Flags, word) {
+				i++
+			}
+		case len(cmd.Subcommands) > 0 && positional == 0:
+			sub := cmd.find(word)
+			if sub == nil && cmd.Run == nil {
+				return nil
+			}
+			if sub == nil {
+				positional++
+				continue
+			}
+			cmd = sub
+		default:
+			positional++
+		}
+	}
+
+	// Values of flags are not completed, let the shell fall back to files
+	if n := len(previous); n > 0 && flagTakesValue(cmd.Flags, previous[n-1]) {
+		return nil
+	}
+
+	var candidates []string
+	switch {
+	case strings.HasPrefix(current, "-"):
+		cmd.Flags.VisitAll(func(f *flag.Flag) {
+			candidates = append(candidates, "--"+f.Name)
+		})
+	case len(cmd.Subcommands) > 0 && positional == 0:
+		for _, sub := range cmd.Subcommands {
+			if !sub.Hidden {
+				candidates = append(candidates, sub.Name)
+			}
+		}
+		if strings.Contains(cmd.Args, "tag") {
+			candidates = append(candidates, completeTags()...)
+		}
+	default:
+		args := strings.Fields(cmd.Args)
+		if positional < len(args) && strings.Contains(args[positional], "tag") {
+			candidates = completeTags()
+		}
+	}
+	return filterPrefix(candidates, current)
+}
+
+// flagTakesValue reports whether a flag word consumes the next word
+func flagTakesValue(fs *flag.FlagSet, word string) bool {
+	name := strings.TrimLeft(word, "-")
+	if name == "" || strings.Contains(name, "=") {
+		return false
+	}
+	f := fs.Lookup(name)
+	if f == nil {
+		return false
+	}
+	if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+		return false
+	}
+	return true
+}
+
+// completeTags lists the package tags; completion stays silent on errors
+func completeTags() []string {
+	globalOptions.maxRetries = 0
+	github, err := newGitHubClient()
+	if err != nil {
+		return nil
+	}
+	versions, err := listPackageVersions(github)
+	if err != nil {
+		return nil
+	}
+
+	var tags []string
+	for _, version := range versions {
+		tags = append(tags, version.Metadata.Container.Tags...)
+	}
+	slices.Sort(tags)
+	return slices.Compact(tags)
+}
+
+func filterPrefix(candidates []string, prefix string) []string {
+	var matches []string
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, prefix) {
+			matches = append(matches, candidate)
+		}
+	}
+	return matches
+}
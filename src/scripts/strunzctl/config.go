@@ -0,0 +1,591 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Config holds the settings shared by all commands. Values come from the
+// built-in defaults, then the config file, then STRUNZCTL_* variables.
+type Config struct {
+	Org      string `json:"org"`
+	Repo     string `json:"repo"`
+	Package  string `json:"package"`
+	Registry string `json:"registry"`
+	Mirror   string `json:"mirror"`
+	// Targets lists comma-separated org/package entries the fleet commands
+	// cover; a bare package name is one of org
+	Targets string `json:"targets,omitempty"`
+	// Lang is the output language, en or de
+	Lang  string `json:"lang,omitempty"`
+	Token string `json:"token,omitempty"`
+	// GitHubAPI is the REST API base URL, for GitHub Enterprise Server or
+	// the fake API of `test fake-api`
+	GitHubAPI string `json:"github_api,omitempty"`
+	// Proxy is used when HTTPS_PROXY and HTTP_PROXY are not set
+	Proxy string `json:"proxy,omitempty"`
+	// CABundle is a PEM file of CA certificates trusted besides the system's
+	CABundle string `json:"ca_bundle,omitempty"`
+	// PluginDir is searched for strunzctl-format-* and strunzctl-notify-*
+	// plugins before PATH
+	PluginDir   string            `json:"plugin_dir,omitempty"`
+	GitHubApp   GitHubAppConfig   `json:"github_app"`
+	OIDC        OIDCConfig        `json:"oidc"`
+	Credentials CredentialsConfig `json:"credentials"`
+	Webhooks    Webhooks          `json:"webhooks"`
+	Alerts      AlertsConfig      `json:"alerts"`
+	Retention   RetentionPolicy   `json:"retention"`
+	Railway     RailwayConfig     `json:"railway"`
+	Audit       AuditConfig       `json:"audit"`
+	Secrets     SecretsConfig     `json:"secrets"`
+	Licenses    LicensePolicy     `json:"licenses"`
+}
+
+// GitHubAppConfig authenticates as a GitHub App installation instead of
+// a personal token, so scheduled jobs do not depend on one that expires
+type GitHubAppConfig struct {
+	ID string `json:"id,omitempty"`
+	// Installation is the installation ID, looked up on the repository
+	// when empty
+	Installation string `json:"installation,omitempty"`
+	// PrivateKey is the path of the app's PEM private key, or the key itself
+	PrivateKey string `json:"private_key,omitempty"`
+}
+
+// OIDCConfig trades the OIDC token of a GitHub Actions job for registry
+// credentials through an RFC 8693 token exchange endpoint, so CI needs no
+// long-lived registry secret
+type OIDCConfig struct {
+	Exchange string `json:"exchange,omitempty"`
+	Audience string `json:"audience,omitempty"`
+	Scope    string `json:"scope,omitempty"`
+	// Registries lists comma-separated registry hosts the exchanged token
+	// is the password for
+	Registries string `json:"registries,omitempty"`
+	Username   string `json:"username"`
+}
+
+// CredentialsConfig selects where `login` stores tokens
+type CredentialsConfig struct {
+	// Helper is a docker-credential-<helper> program such as osxkeychain,
+	// secretservice, wincred or pass; empty picks one for the OS and
+	// "none" disables stored tokens
+	Helper string `json:"helper,omitempty"`
+}
+
+// Webhooks are incoming webhook URLs for notifications
+type Webhooks struct {
+	Notify string `json:"notify,omitempty"`
+}
+
+// AlertsConfig routes --notify-webhook alerts to sinks besides the chat
+// webhook in webhooks.notify. Each sink takes alerts of at least its
+// severity (info, warning or critical).
+type AlertsConfig struct {
+	NotifySeverity string `json:"notify_severity"`
+	// Webhook receives alerts as JSON
+	Webhook         string `json:"webhook,omitempty"`
+	WebhookSeverity string `json:"webhook_severity"`
+	// PagerDuty is an Events API v2 routing key
+	PagerDuty         string `json:"pagerduty,omitempty"`
+	PagerDutySeverity string `json:"pagerduty_severity"`
+	// Email lists comma-separated recipients, mailed through SMTP
+	Email         string `json:"email,omitempty"`
+	EmailSeverity string `json:"email_severity"`
+	SMTP          string `json:"smtp,omitempty"`
+	SMTPUser      string `json:"smtp_user,omitempty"`
+	SMTPPassword  string `json:"smtp_password,omitempty"`
+	From          string `json:"from,omitempty"`
+	// Dedup drops repeats of an alert within this duration
+	Dedup string `json:"dedup"`
+	// Silence lists comma-separated windows without alerts, either
+	// <start>/<end> in RFC 3339 or a daily HH:MM-HH:MM in UTC
+	Silence string `json:"silence,omitempty"`
+	// Plugins lists comma-separated strunzctl-notify-<name> plugins that
+	// receive alerts
+	Plugins         string `json:"plugins,omitempty"`
+	PluginsSeverity string `json:"plugins_severity"`
+}
+
+// RailwayConfig selects the Railway service deploy commands operate on.
+// Project, environment and service accept names or IDs.
+type RailwayConfig struct {
+	Token       string `json:"token,omitempty"`
+	Project     string `json:"project"`
+	Environment string `json:"environment"`
+	Service     string `json:"service"`
+	Canary      string `json:"canary"`
+	// Blue and Green are the service pair of `deploy switch`, which moves
+	// Domain to whichever of them runs the new image
+	Blue   string `json:"blue"`
+	Green  string `json:"green"`
+	Domain string `json:"domain"`
+}
+
+// AuditConfig locates the audit log of deployment changes
+type AuditConfig struct {
+	Path string `json:"path,omitempty"`
+	// Gist is the ID of a gist that receives a copy after every change
+	Gist string `json:"gist,omitempty"`
+}
+
+// SecretsConfig tunes the secret scan of image layers and names the
+// server secrets `secrets rotate` replaces
+type SecretsConfig struct {
+	// Allow lists comma-separated paths or globs whose findings are known
+	// false positives
+	Allow string `json:"allow,omitempty"`
+	// Rotate lists comma-separated variables of the Railway service
+	Rotate string `json:"rotate"`
+}
+
+// LicensePolicy decides which dependency licenses an image may ship.
+// Denied licenses always fail; copyleft and unknown ones fail, warn or
+// are ok as configured.
+type LicensePolicy struct {
+	// Deny lists comma-separated SPDX identifiers or globs like AGPL-*
+	Deny     string `json:"deny"`
+	Copyleft string `json:"copyleft"`
+	Unknown  string `json:"unknown"`
+	// Exceptions are package names accepted whatever their license
+	Exceptions string `json:"exceptions,omitempty"`
+}
+
+// RetentionPolicy describes which versions cleanup must keep
+type RetentionPolicy struct {
+	KeepLast               int  `json:"keep_last"`
+	MaxAgeDays             int  `json:"max_age_days"`
+	KeepSemver             bool `json:"keep_semver"`
+	KeepTagged             bool `json:"keep_tagged"`
+	DeleteStalePrereleases bool `json:"delete_stale_prereleases"`
+}
+
+// config is loaded by the root command before any subcommand runs
+var config = defaultConfig()
+
+func defaultConfig() Config {
+	return Config{
+		Org:      "longevitycoach",
+		Repo:     "longevitycoach/StrunzKnowledge",
+		Package:  "strunzknowledge",
+		Registry: "ghcr.io",
+		Mirror:   "docker.io/longevitycoach/strunzknowledge",
+		Retention: RetentionPolicy{
+			KeepLast:   10,
+			MaxAgeDays: 90,
+			KeepSemver: true,
+			KeepTagged: true,
+		},
+		Alerts: AlertsConfig{NotifySeverity: severityInfo, WebhookSeverity: severityWarning,
+			PagerDutySeverity: severityCritical, EmailSeverity: severityWarning, PluginsSeverity: severityWarning, Dedup: "15m"},
+		OIDC:    OIDCConfig{Username: "oauth2accesstoken"},
+		Railway: RailwayConfig{Environment: "production"},
+		Secrets: SecretsConfig{Rotate: "JWT_SECRET,OAUTH_CLIENT_SECRET"},
+		// The server is offered over the network, where AGPL and SSPL
+		// require publishing the sources of the whole service
+		Licenses: LicensePolicy{Deny: "AGPL-*,SSPL-*", Copyleft: "warn", Unknown: "warn"},
+	}
+}
+
+// configEnv maps environment variables to the config keys they override
+var configEnv = []struct {
+	name string
+	key  []string
+}{
+	{"STRUNZCTL_ORG", []string{"org"}},
+	{"STRUNZCTL_REPO", []string{"repo"}},
+	{"STRUNZCTL_PACKAGE", []string{"package"}},
+	{"STRUNZCTL_REGISTRY", []string{"registry"}},
+	{"STRUNZCTL_MIRROR", []string{"mirror"}},
+	{"STRUNZCTL_TARGETS", []string{"targets"}},
+	{"STRUNZCTL_LANG", []string{"lang"}},
+	{"STRUNZCTL_TOKEN", []string{"token"}},
+	{"STRUNZCTL_GITHUB_API", []string{"github_api"}},
+	{"STRUNZCTL_PROXY", []string{"proxy"}},
+	{"STRUNZCTL_CA_BUNDLE", []string{"ca_bundle"}},
+	{"STRUNZCTL_PLUGIN_DIR", []string{"plugin_dir"}},
+	{"STRUNZCTL_GITHUB_APP_ID", []string{"github_app", "id"}},
+	{"STRUNZCTL_GITHUB_APP_INSTALLATION", []string{"github_app", "installation"}},
+	{"STRUNZCTL_GITHUB_APP_PRIVATE_KEY", []string{"github_app", "private_key"}},
+	{"STRUNZCTL_CREDENTIALS_HELPER", []string{"credentials", "helper"}},
+	{"STRUNZCTL_OIDC_EXCHANGE", []string{"oidc", "exchange"}},
+	{"STRUNZCTL_OIDC_AUDIENCE", []string{"oidc", "audience"}},
+	{"STRUNZCTL_OIDC_SCOPE", []string{"oidc", "scope"}},
+	{"STRUNZCTL_OIDC_REGISTRIES", []string{"oidc", "registries"}},
+	{"STRUNZCTL_OIDC_USERNAME", []string{"oidc", "username"}},
+	{webhookEnv, []string{"webhooks", "notify"}},
+	{"STRUNZCTL_ALERTS_NOTIFY_SEVERITY", []string{"alerts", "notify_severity"}},
+	{"STRUNZCTL_ALERTS_WEBHOOK", []string{"alerts", "webhook"}},
+	{"STRUNZCTL_ALERTS_WEBHOOK_SEVERITY", []string{"alerts", "webhook_severity"}},
+	{"STRUNZCTL_ALERTS_PAGERDUTY", []string{"alerts", "pagerduty"}},
+	{"STRUNZCTL_ALERTS_PAGERDUTY_SEVERITY", []string{"alerts", "pagerduty_severity"}},
+	{"STRUNZCTL_ALERTS_EMAIL", []string{"alerts", "email"}},
+	{"STRUNZCTL_ALERTS_EMAIL_SEVERITY", []string{"alerts", "email_severity"}},
+	{"STRUNZCTL_ALERTS_SMTP", []string{"alerts", "smtp"}},
+	{"STRUNZCTL_ALERTS_SMTP_USER", []string{"alerts", "smtp_user"}},
+	{"STRUNZCTL_ALERTS_SMTP_PASSWORD", []string{"alerts", "smtp_password"}},
+	{"STRUNZCTL_ALERTS_FROM", []string{"alerts", "from"}},
+	{"STRUNZCTL_ALERTS_DEDUP", []string{"alerts", "dedup"}},
+	{"STRUNZCTL_ALERTS_SILENCE", []string{"alerts", "silence"}},
+	{"STRUNZCTL_ALERTS_PLUGINS", []string{"alerts", "plugins"}},
+	{"STRUNZCTL_ALERTS_PLUGINS_SEVERITY", []string{"alerts", "plugins_severity"}},
+	{"STRUNZCTL_RAILWAY_TOKEN", []string{"railway", "token"}},
+	{"STRUNZCTL_RAILWAY_PROJECT", []string{"railway", "project"}},
+	{"STRUNZCTL_RAILWAY_ENVIRONMENT", []string{"railway", "environment"}},
+	{"STRUNZCTL_RAILWAY_SERVICE", []string{"railway", "service"}},
+	{"STRUNZCTL_RAILWAY_CANARY", []string{"railway", "canary"}},
+	{"STRUNZCTL_RAILWAY_BLUE", []string{"railway", "blue"}},
+	{"STRUNZCTL_RAILWAY_GREEN", []string{"railway", "green"}},
+	{"STRUNZCTL_RAILWAY_DOMAIN", []string{"railway", "domain"}},
+	{"STRUNZCTL_AUDIT_PATH", []string{"audit", "path"}},
+	{"STRUNZCTL_AUDIT_GIST", []string{"audit", "gist"}},
+	{"STRUNZCTL_SECRETS_ALLOW", []string{"secrets", "allow"}},
+	{"STRUNZCTL_SECRETS_ROTATE", []string{"secrets", "rotate"}},
+	{"STRUNZCTL_LICENSES_DENY", []string{"licenses", "deny"}},
+	{"STRUNZCTL_LICENSES_COPYLEFT", []string{"licenses", "copyleft"}},
+	{"STRUNZCTL_LICENSES_UNKNOWN", []string{"licenses", "unknown"}},
+	{"STRUNZCTL_LICENSES_EXCEPTIONS", []string{"licenses", "exceptions"}},
+	{"STRUNZCTL_RETENTION_KEEP_LAST", []string{"retention", "keep_last"}},
+	{"STRUNZCTL_RETENTION_MAX_AGE_DAYS", []string{"retention", "max_age_days"}},
+	{"STRUNZCTL_RETENTION_KEEP_SEMVER", []string{"retention", "keep_semver"}},
+	{"STRUNZCTL_RETENTION_KEEP_TAGGED", []string{"retention", "keep_tagged"}},
+	{"STRUNZCTL_RETENTION_DELETE_STALE_PRERELEASES", []string{"retention", "delete_stale_prereleases"}},
+}
+
+// defaultConfigPath returns ~/.config/strunzctl/config.yaml, or the path in
+// STRUNZCTL_CONFIG
+func defaultConfigPath() string {
+	if path := os.Getenv("STRUNZCTL_CONFIG"); path != "" {
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "strunzctl", "config.yaml")
+}
+
+// loadConfig applies the config file and environment to the defaults. A
+// missing file is only an error when the path was given explicitly.
+func loadConfig(path string, explicit bool) (Config, error) {
+	values := make(map[string]any)
+	if path != "" {
+		data, err := os.ReadFile(path)
+		switch {
+		case err == nil:
+			if values, err = parseYAML(data); err != nil {
+				return Config{}, fmt.Errorf("failed to parse %s: %w", path, err)
+			}
+		case errors.Is(err, os.ErrNotExist) && !explicit:
+		default:
+			return Config{}, fmt.Errorf("failed to read config: %w", err)
+		}
+	}
+
+	for _, env := range configEnv {
+		if value, ok := os.LookupEnv(env.name); ok {
+			setNested(values, env.key, value)
+		}
+	}
+
+	cfg := defaultConfig()
+	if err := applyConfig(reflect.ValueOf(&cfg).Elem(), values, ""); err != nil {
+		return Config{}, fmt.Errorf("invalid config: %w", err)
+	}
+	if cfg.Org == "" || cfg.Package == "" || cfg.Registry == "" {
+		return Config{}, errors.New("invalid config: org, package and registry must not be empty")
+	}
+	return cfg, nil
+}
+
+// applyConfig sets the fields of a struct from parsed values, matching keys
+// to json tags so the file, environment and `config show` use one naming
+func applyConfig(target reflect.Value, values map[string]any, prefix string) error {
+	fields := make(map[string]reflect.Value)
+	for i := 0; i < target.NumField(); i++ {
+		name, _, _ := strings.Cut(target.Type().Field(i).Tag.Get("json"), ",")
+		fields[name] = target.Field(i)
+	}
+
+	for key, value := range values {
+		field, ok := fields[key]
+		if !ok {
+			return fmt.Errorf("unknown key %q", prefix+key)
+		}
+		if nested, ok := value.(map[string]any); ok {
+			if field.Kind() != reflect.Struct {
+				return fmt.Errorf("%s%s is not a section", prefix, key)
+			}
+			if err := applyConfig(field, nested, prefix+key+"."); err != nil {
+				return err
+			}
+			continue
+		}
+
+		text := value.(string)
+		switch field.Kind() {
+		case reflect.String:
+			field.SetString(text)
+		case reflect.Int:
+			n, err := strconv.Atoi(text)
+			if err != nil {
+				return fmt.Errorf("%s%s must be an integer, got %q", prefix, key, text)
+			}
+			field.SetInt(int64(n))
+		case reflect.Bool:
+			b, err := strconv.ParseBool(text)
+			if err != nil {
+				return fmt.Errorf("%s%s must be true or false, got %q", prefix, key, text)
+			}
+			field.SetBool(b)
+		default:
+			return fmt.Errorf("%s%s is a section, not a value", prefix, key)
+		}
+	}
+	return nil
+}
+
+func setNested(values map[string]any, key []string, value any) {
+	for _, part := range key[:len(key)-1] {
+		child, ok := values[part].(map[string]any)
+		if !ok {
+			child = make(map[string]any)
+			values[part] = child
+		}
+		values = child
+	}
+	values[key[len(key)-1]] = value
+}
+
+// parseYAML reads the subset of YAML used by the config file: nested
+// mappings of scalars, indented with spaces, with # comments. Scalars are
+// plain, or quoted to hold a leading indicator, a ": " or a " #"; a value
+// in [] or {} must be JSON and is kept as its text. Anything else, such as
+// lists, block scalars, anchors or several documents, is an error naming
+// the line, rather than a value the callers silently misread.
+func parseYAML(data []byte) (map[string]any, error) {
+	root := make(map[string]any)
+	type frame struct {
+		// indent is that of the key owning the mapping, children that of
+		// its entries once the first one is read
+		indent, children int
+		values           map[string]any
+	}
+	stack := []frame{{indent: -1, children: -1, values: root}}
+	// scalarIndent is the indent of the previous line when it held a
+	// value, which nothing may be nested under
+	scalarIndent := -1
+	seen := false
+
+	for n, line := range strings.Split(string(data), "\n") {
+		fail := func(format string, args ...any) error {
+			return fmt.Errorf("line %d: %s", n+1, fmt.Sprintf(format, args...))
+		}
+		content := strings.TrimRight(stripComment(line), " \t\r")
+		if strings.TrimSpace(content) == "" {
+			continue
+		}
+		if content == "---" && !seen {
+			continue
+		}
+		seen = true
+		if strings.Contains(content, "\t") {
+			return nil, fail("tabs are not allowed for indentation")
+		}
+		indent := len(content) - len(strings.TrimLeft(content, " "))
+		text := strings.TrimSpace(content)
+		switch {
+		case text == "---" || text == "...":
+			return nil, fail("only one document is supported")
+		case text == "-" || strings.HasPrefix(text, "- "):
+			return nil, fail("lists are not supported, use a comma-separated value")
+		case strings.HasPrefix(text, "? "):
+			return nil, fail("complex keys are not supported")
+		}
+		key, value, err := splitYAMLEntry(text)
+		if err != nil {
+			return nil, fail("%v", err)
+		}
+
+		if scalarIndent >= 0 && indent > scalarIndent {
+			return nil, fail("unexpected indentation: the key above has a value, so it cannot have entries")
+		}
+		for indent <= stack[len(stack)-1].indent {
+			stack = stack[:len(stack)-1]
+		}
+		top := &stack[len(stack)-1]
+		if top.children < 0 {
+			top.children = indent
+		} else if indent != top.children {
+			return nil, fail("indentation does not match the entries above")
+		}
+		if _, ok := top.values[key]; ok {
+			return nil, fail("duplicate key %q", key)
+		}
+		if value == "" {
+			child := make(map[string]any)
+			top.values[key] = child
+			stack = append(stack, frame{indent: indent, children: -1, values: child})
+			scalarIndent = -1
+			continue
+		}
+		scalar, err := parseYAMLScalar(value)
+		if err != nil {
+			return nil, fail("%s: %v", key, err)
+		}
+		top.values[key] = scalar
+		scalarIndent = indent
+	}
+	return root, nil
+}
+
+// splitYAMLEntry splits "key: value" at the colon that ends the key,
+// which may be quoted
+func splitYAMLEntry(text string) (key, value string, err error) {
+	end := -1
+	if text[0] == '"' || text[0] == '\'' {
+		closing := yamlQuoteEnd(text)
+		if closing < 0 {
+			return "", "", fmt.Errorf("unterminated quoted key")
+		}
+		if !strings.HasPrefix(text[closing+1:], ":") {
+			return "", "", fmt.Errorf("expected \"key: value\"")
+		}
+		end = closing + 1
+	} else if i := strings.Index(text, ": "); i >= 0 {
+		end = i
+	} else if strings.HasSuffix(text, ":") {
+		end = len(text) - 1
+	}
+	if end <= 0 {
+		return "", "", fmt.Errorf("expected \"key: value\"")
+	}
+	if key, err = parseYAMLScalar(strings.TrimSpace(text[:end])); err != nil {
+		return "", "", fmt.Errorf("key: %v", err)
+	}
+	if key == "" {
+		return "", "", fmt.Errorf("empty key")
+	}
+	return key, strings.TrimSpace(text[end+1:]), nil
+}
+
+// yamlQuoteEnd returns the index of the quote closing the quoted string s
+// starts with, -1 when it is not closed
+func yamlQuoteEnd(s string) int {
+	quote := s[0]
+	for i := 1; i < len(s); i++ {
+		switch {
+		case quote == '"' && s[i] == '\\':
+			i++
+		case s[i] == quote && quote == '\'' && i+1 < len(s) && s[i+1] == '\'':
+			// '' is a quote inside single quotes
+			i++
+		case s[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
+
+// parseYAMLScalar returns the value of a plain or quoted scalar
+func parseYAMLScalar(s string) (string, error) {
+	switch s[0] {
+	case '"', '\'':
+		end := yamlQuoteEnd(s)
+		if end < 0 {
+			return "", fmt.Errorf("unterminated quoted value")
+		}
+		if end != len(s)-1 {
+			return "", fmt.Errorf("unexpected text after the quoted value")
+		}
+		if s[0] == '\'' {
+			return strings.ReplaceAll(s[1:end], "''", "'"), nil
+		}
+		unquoted, err := strconv.Unquote(s)
+		if err != nil {
+			return "", fmt.Errorf("invalid escape in double-quoted value")
+		}
+		return unquoted, nil
+	case '[', '{':
+		if !json.Valid([]byte(s)) {
+			return "", fmt.Errorf("flow collections are not supported, use a comma-separated value or JSON")
+		}
+		return s, nil
+	case '|', '>':
+		return "", fmt.Errorf("block scalars are not supported, put the text on one line in quotes")
+	case '&', '*', '!', '@', '`', '%':
+		return "", fmt.Errorf("values starting with %q must be quoted", s[0])
+	}
+	return s, nil
+}
+
+// stripComment removes a trailing # comment outside of quotes. A quote
+// opens a quoted scalar only where one can start, so an apostrophe in a
+// plain value does not hide the comment after it.
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote == '"' && c == '\\':
+			i++
+		case quote == '\'' && c == '\'' && i+1 < len(line) && line[i+1] == '\'':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case (c == '"' || c == '\'') && (i == 0 || line[i-1] == ' '):
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+func newConfigCommand() *Command {
+	show := newCommand("show", "", "Print the effective configuration with secrets redacted.")
+	show.Run = func(args []string) error {
+		if err := show.ExactArgs(args, 0); err != nil {
+			return err
+		}
+		cfg := config
+		if cfg.Token != "" {
+			cfg.Token = "<redacted>"
+		}
+		if cfg.Webhooks.Notify != "" {
+			cfg.Webhooks.Notify = "<redacted>"
+		}
+		if cfg.Railway.Token != "" {
+			cfg.Railway.Token = "<redacted>"
+		}
+		for _, secret := range []*string{&cfg.Alerts.Webhook, &cfg.Alerts.PagerDuty, &cfg.Alerts.SMTPPassword, &cfg.GitHubApp.PrivateKey} {
+			if *secret != "" {
+				*secret = "<redacted>"
+			}
+		}
+		data, err := json.MarshalIndent(cfg, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	path := newCommand("path", "", "Print the default config file location.")
+	path.Run = func(args []string) error {
+		if err := path.ExactArgs(args, 0); err != nil {
+			return err
+		}
+		fmt.Println(defaultConfigPath())
+		return nil
+	}
+	return newGroup("config", "Inspect the strunzctl configuration.", show, path)
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// crawlMaxAge is how old an interrupted crawl may be to be resumed; older
+// pages are likely stale
+const crawlMaxAge = 24 * time.Hour
+
+// crawlCursor is the checkpoint of an interrupted paginated crawl. The
+// pages fetched so far are kept next to it, one JSON page per line.
+type crawlCursor struct {
+	Path    string    `json:"path"`
+	Next    string    `json:"next"`
+	Pages   int       `json:"pages"`
+	Updated time.Time `json:"updated"`
+}
+
+// crawlDir is where the checkpoint of a crawl of path is kept
+func crawlDir(path string) (string, error) {
+	dir, err := cacheDir("crawls")
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(path))
+	return filepath.Join(dir, hex.EncodeToString(sum[:8])), nil
+}
+
+// GetPagesResumable is GetPages that checkpoints every page, so a crawl
+// that fails after retries can continue where it stopped when resume is
+// set, instead of starting from page one. The checkpoint is removed once
+// the crawl completes. Unlike GetPages, page reads each body as it
+// arrives.
+func (g *GitHubClient) GetPagesResumable(path string, resume bool, page func(body io.Reader) error) error {
+	dir, err := crawlDir(path)
+	if err != nil {
+		return err
+	}
+	cursorPath, pagesPath := filepath.Join(dir, "cursor.json"), filepath.Join(dir, "pages.jsonl")
+
+	next := g.firstPage(path)
+	cursor := crawlCursor{Path: path}
+	replayed := false
+	if resume {
+		var saved crawlCursor
+		data, err := os.ReadFile(cursorPath)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			slog.Info("No interrupted crawl to resume, starting from the first page", "path", path)
+		case err != nil:
+			return fmt.Errorf("failed to read crawl checkpoint: %w", err)
+		case json.Unmarshal(data, &saved) != nil || saved.Path != path:
+			slog.Warn("Ignoring an unreadable crawl checkpoint", "path", path)
+		case time.Since(saved.Updated) > crawlMaxAge:
+			slog.Warn("Crawl checkpoint is too old to resume, starting over", "path", path, "age", formatAge(time.Since(saved.Updated)))
+		default:
+			stop, size, err := replayPages(pagesPath, saved.Pages, page)
+			if err != nil {
+				return err
+			}
+			if stop {
+				os.RemoveAll(dir)
+				return nil
+			}
+			// An interruption between checkpointing a page and the cursor
+			// leaves the page in the file; it is fetched again
+			if err := os.Truncate(pagesPath, size); err != nil {
+				return fmt.Errorf("failed to read crawl checkpoint: %w", err)
+			}
+			slog.Info("Resuming crawl", "path", path, "pages", saved.Pages)
+			cursor, next, replayed = saved, saved.Next, true
+		}
+	}
+	if !replayed {
+		os.RemoveAll(dir)
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create crawl checkpoint: %w", err)
+	}
+
+	err = g.paginate(next, func(body io.Reader, next string) error {
+		// The page is kept as it is read, to checkpoint it once complete
+		var raw bytes.Buffer
+		body = io.TeeReader(body, &raw)
+		if err := page(body); err != nil {
+			return err
+		}
+		if _, err := io.Copy(io.Discard, body); err != nil {
+			return fmt.Errorf("failed to read GitHub API response: %w", err)
+		}
+		if err := appendPage(pagesPath, raw.Bytes()); err != nil {
+			return err
+		}
+		cursor.Next, cursor.Pages, cursor.Updated = next, cursor.Pages+1, time.Now().UTC()
+		return writeCursor(cursorPath, cursor)
+	})
+	if err != nil {
+		if cursor.Pages > 0 {
+			slog.Warn("Crawl interrupted, rerun with --resume to continue", "path", path, "pages", cursor.Pages)
+		}
+		return err
+	}
+	os.RemoveAll(dir)
+	return nil
+}
+
+// replayPages feeds the first pages checkpointed pages to page, reporting
+// whether it asked to stop and the size of those pages in the file
+func replayPages(pagesPath string, pages int, page func(body io.Reader) error) (bool, int64, error) {
+	file, err := os.Open(pagesPath)
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to read crawl checkpoint: %w", err)
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 64<<20)
+	var size int64
+	for replayed := 0; replayed < pages; replayed++ {
+		if !scanner.Scan() {
+			if err := scanner.Err(); err != nil {
+				return false, 0, fmt.Errorf("failed to read crawl checkpoint: %w", err)
+			}
+			return false, 0, fmt.Errorf("crawl checkpoint has %d of %d pages, rerun without --resume", replayed, pages)
+		}
+		if err := page(bytes.NewReader(scanner.Bytes())); errors.Is(err, errStopPages) {
+			return true, 0, nil
+		} else if err != nil {
+			return false, 0, err
+		}
+		size += int64(len(scanner.Bytes())) + 1
+	}
+	return false, size, nil
+}
+
+// appendPage adds a page to the checkpoint on a single line
+func appendPage(pagesPath string, body []byte) error {
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, body); err != nil {
+		return fmt.Errorf("failed to checkpoint page: %w", err)
+	}
+	compact.WriteByte('\n')
+	file, err := os.OpenFile(pagesPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to checkpoint page: %w", err)
+	}
+	defer file.Close()
+	_, err = file.Write(compact.Bytes())
+	return err
+}
+
+// writeCursor replaces the cursor atomically, so an interruption leaves
+// the previous one
+func writeCursor(cursorPath string, cursor crawlCursor) error {
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return err
+	}
+	tmp := cursorPath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to checkpoint crawl: %w", err)
+	}
+	return os.Rename(tmp, cursorPath)
+}
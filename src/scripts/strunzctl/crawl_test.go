@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+func TestResumeAfterInterruptedCheckpoint(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	globalOptions.noCache = true
+	t.Cleanup(func() { globalOptions.noCache = false })
+
+	// The crawl has five pages of two items; failing stops it before a page
+	failing := 3
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		page = max(page, 1)
+		if page == failing {
+			http.Error(w, `{"message":"Not Found"}`, http.StatusNotFound)
+			return
+		}
+		if page < 5 {
+			w.Header().Set("Link", fmt.Sprintf(`<%s/items?page=%d&per_page=100>; rel="next"`, server.URL, page+1))
+		}
+		fmt.Fprintf(w, `[{"id":%d},{"id":%d}]`, page*10, page*10+1)
+	}))
+	defer server.Close()
+	github := &GitHubClient{baseURL: server.URL, httpClient: server.Client()}
+
+	crawl := func(resume bool) ([]int, error) {
+		var ids []int
+		err := github.GetPagesResumable("/items", resume, func(body io.Reader) error {
+			return decodeArray(body, "items", func(item struct{ ID int }) error {
+				ids = append(ids, item.ID)
+				return nil
+			})
+		})
+		return ids, err
+	}
+
+	if _, err := crawl(false); err == nil {
+		t.Fatal("want the crawl to fail on page 3")
+	}
+	// Interrupted after checkpointing page 3 but before its cursor
+	dir, err := crawlDir("/items")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := appendPage(filepath.Join(dir, "pages.jsonl"), []byte(`[{"id":30},{"id":31}]`)); err != nil {
+		t.Fatal(err)
+	}
+
+	// The stale copy must not shift the pages replayed by later resumes
+	for failing = 4; failing <= 5; failing++ {
+		if _, err := crawl(true); err == nil {
+			t.Fatalf("want the resumed crawl to fail on page %d", failing)
+		}
+	}
+	failing = 0
+	ids, err := crawl(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{10, 11, 20, 21, 30, 31, 40, 41, 50, 51}; !slices.Equal(ids, want) {
+		t.Errorf("resumed crawl saw %v, want %v", ids, want)
+	}
+}
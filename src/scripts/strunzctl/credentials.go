@@ -0,0 +1,264 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Credentials are stored under these server URLs, the way docker stores
+// registry logins by host
+const (
+	githubCredentialServer  = "https://api.github.com"
+	railwayCredentialServer = "https://backboard.railway.com"
+	credentialUsername      = "strunzctl"
+)
+
+// credentialHelpers are the docker credential helpers backed by the OS
+// keychain, in order of preference
+var credentialHelpers = map[string][]string{
+	"darwin":  {"osxkeychain", "desktop"},
+	"windows": {"wincred", "desktop"},
+	"linux":   {"secretservice", "pass", "desktop"},
+}
+
+// errNoCredentialHelper is returned when no helper can store credentials
+var errNoCredentialHelper = errors.New("no docker credential helper found")
+
+// credentialHelper returns the docker-credential-<name> program to use:
+// credentials.helper, else the first installed one for this OS. "none"
+// disables stored credentials.
+func credentialHelper() (string, error) {
+	switch helper := config.Credentials.Helper; helper {
+	case "none":
+		return "", errNoCredentialHelper
+	case "":
+	default:
+		return exec.LookPath("docker-credential-" + helper)
+	}
+	for _, name := range credentialHelpers[runtime.GOOS] {
+		if path, err := exec.LookPath("docker-credential-" + name); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("%w for %s, install docker-credential-%s or set credentials.helper",
+		errNoCredentialHelper, runtime.GOOS, strings.Join(credentialHelpers[runtime.GOOS], ", docker-credential-"))
+}
+
+// runCredentialHelper talks the docker credential helper protocol: the
+// action is the argument, the request goes to stdin
+func runCredentialHelper(action, input string) (string, error) {
+	helper, err := credentialHelper()
+	if err != nil {
+		return "", err
+	}
+	cmd := newProcess(helper, action)
+	cmd.Stdin = strings.NewReader(input)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("%s %s failed: %w: %s", helper, action, err, strings.TrimSpace(string(output)))
+	}
+	return string(output), nil
+}
+
+// storedCredentials caches lookups; helpers may prompt to unlock the
+// keychain, which should happen once per run
+var storedCredentials = struct {
+	sync.Mutex
+	secrets map[string]string
+}{secrets: make(map[string]string)}
+
+// loadCredential returns the secret stored for server, or "" when there
+// is none or no helper is available
+func loadCredential(server string) string {
+	storedCredentials.Lock()
+	defer storedCredentials.Unlock()
+	if secret, ok := storedCredentials.secrets[server]; ok {
+		return secret
+	}
+	secret := ""
+	output, err := runCredentialHelper("get", server)
+	if err == nil {
+		var credential struct {
+			Secret string `json:"Secret"`
+		}
+		if err := json.Unmarshal([]byte(output), &credential); err == nil {
+			secret = credential.Secret
+		}
+	} else if !errors.Is(err, errNoCredentialHelper) {
+		// Helpers fail with "credentials not found" for unknown servers
+		slog.Debug("No stored credentials", "server", server, "error", err)
+	}
+	storedCredentials.secrets[server] = secret
+	return secret
+}
+
+// storeCredential saves a secret for server in the credential helper
+func storeCredential(server, secret string) error {
+	payload, _ := json.Marshal(map[string]string{"ServerURL": server, "Username": credentialUsername, "Secret": secret})
+	if _, err := runCredentialHelper("store", string(payload)); err != nil {
+		return err
+	}
+	storedCredentials.Lock()
+	storedCredentials.secrets[server] = secret
+	storedCredentials.Unlock()
+	return nil
+}
+
+// eraseCredential removes the secret stored for server
+func eraseCredential(server string) error {
+	if _, err := runCredentialHelper("erase", server); err != nil {
+		return err
+	}
+	storedCredentials.Lock()
+	delete(storedCredentials.secrets, server)
+	storedCredentials.Unlock()
+	return nil
+}
+
+// readSecret reads a token from stdin, without echo on a terminal
+func readSecret(prompt string) (string, error) {
+	terminal := isTerminal(os.Stdin)
+	if terminal {
+		fmt.Fprint(os.Stderr, prompt)
+		stty := exec.Command("stty", "-echo")
+		stty.Stdin = os.Stdin
+		if stty.Run() == nil {
+			defer func() {
+				restore := exec.Command("stty", "echo")
+				restore.Stdin = os.Stdin
+				restore.Run()
+				fmt.Fprintln(os.Stderr)
+			}()
+		}
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("failed to read token: %w", err)
+	}
+	secret := strings.TrimSpace(line)
+	if secret == "" {
+		return "", fmt.Errorf("%w: no token given", errUsage)
+	}
+	return secret, nil
+}
+
+// validateGitHubToken returns the login the token belongs to. Tokens that
+// are not user tokens are refused by /user but still authenticate.
+func validateGitHubToken(token string) (string, error) {
+	github := &GitHubClient{baseURL: githubAPIURL(), token: token, httpClient: &http.Client{Timeout: 30 * time.Second}}
+	var user struct {
+		Login string `json:"login"`
+	}
+	err := github.Get("/user", &user)
+	var apiErr *GitHubError
+	switch {
+	case errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnauthorized:
+		return "", fmt.Errorf("%w: GitHub rejects the token; it is invalid, expired or revoked", errAuth)
+	case errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusForbidden:
+		return "", nil
+	case err != nil:
+		return "", err
+	}
+	return user.Login, nil
+}
+
+// validateRailwayToken returns the number of projects an account or team
+// token can see
+func validateRailwayToken(token string) (int, error) {
+	railway := &RailwayClient{Token: token, URL: railwayAPI, HTTPClient: &http.Client{Timeout: 30 * time.Second}, Retry: doWithRetry}
+	var data struct {
+		Projects struct {
+			Edges []json.RawMessage `json:"edges"`
+		} `json:"projects"`
+	}
+	if err := railway.Query(`query { projects { edges { node { id } } } }`, nil, &data); err != nil {
+		return 0, err
+	}
+	return len(data.Projects.Edges), nil
+}
+
+func newLoginCommand() *Command {
+	cmd := newCommand("login", "<github|railway>", "Validate a token and store it in the OS keychain through a docker credential helper, so it need not be kept in plaintext variables.")
+	withToken := cmd.Flags.Bool("with-token", false, "read the token from stdin instead of prompting")
+	logout := cmd.Flags.Bool("logout", false, "remove the stored token")
+	status := cmd.Flags.Bool("status", false, "show whether a token is stored, without changing it")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 1); err != nil {
+			return err
+		}
+		var server, service string
+		switch args[0] {
+		case "github":
+			server, service = githubCredentialServer, "GitHub"
+		case "railway":
+			server, service = railwayCredentialServer, "Railway"
+		default:
+			return fmt.Errorf("%w: unknown service %q, use github or railway", errUsage, args[0])
+		}
+		helper, err := credentialHelper()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case *status:
+			if loadCredential(server) == "" {
+				fmt.Printf("⚠️  No %s token stored in %s\n", service, helper)
+				return nil
+			}
+			fmt.Printf("✅ %s token stored in %s\n", service, helper)
+			return nil
+		case *logout:
+			if err := eraseCredential(server); err != nil {
+				return err
+			}
+			fmt.Printf("✅ Removed the %s token from %s\n", service, helper)
+			return nil
+		}
+
+		if !*withToken && !isTerminal(os.Stdin) {
+			return fmt.Errorf("%w: stdin is not a terminal, pass --with-token to read the token from it", errUsage)
+		}
+		token, err := readSecret(service + " token: ")
+		if err != nil {
+			return err
+		}
+
+		var identity string
+		switch args[0] {
+		case "github":
+			login, err := validateGitHubToken(token)
+			if err != nil {
+				return err
+			}
+			identity = githubTokenKind(token)
+			if login != "" {
+				identity = login + ", " + identity
+			}
+		case "railway":
+			projects, err := validateRailwayToken(token)
+			if err != nil {
+				return fmt.Errorf("%w (login stores account or team tokens; project tokens belong in RAILWAY_TOKEN)", err)
+			}
+			identity = fmt.Sprintf("%d project(s)", projects)
+		}
+		if err := storeCredential(server, token); err != nil {
+			return err
+		}
+		fmt.Printf("✅ Logged in to %s (%s); token stored in %s\n", service, identity, helper)
+		return nil
+	}
+	return cmd
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSchedule is a parsed cron expression. Fields are bit sets of the
+// allowed minutes, hours, days of the month, months and weekdays, with
+// Sunday as 0.
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	// domAny and dowAny record a day field starting with *, such as */2:
+	// as in cron, a day matches either restricted day field when both are
+	// restricted
+	domAny, dowAny bool
+	// every is the interval of @every, which replaces the fields
+	every time.Duration
+	// reboot runs once when the scheduler starts
+	reboot bool
+}
+
+// cronMacros are the @ shorthands cron accepts
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var (
+	cronMonths   = []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+	cronWeekdays = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+)
+
+// parseCron reads a five-field expression (minute hour day-of-month month
+// day-of-week) with lists, ranges, steps and month or weekday names, one of
+// the @yearly ... @hourly macros, @every DURATION or @reboot
+func parseCron(spec string) (cronSchedule, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "@reboot" {
+		return cronSchedule{reboot: true}, nil
+	}
+	if interval, ok := strings.CutPrefix(spec, "@every "); ok {
+		every, err := time.ParseDuration(strings.TrimSpace(interval))
+		if err != nil || every < time.Minute {
+			return cronSchedule{}, fmt.Errorf("@every needs a duration of at least 1m, got %q", interval)
+		}
+		return cronSchedule{every: every}, nil
+	}
+	if macro, ok := cronMacros[spec]; ok {
+		spec = macro
+	} else if strings.HasPrefix(spec, "@") {
+		return cronSchedule{}, fmt.Errorf("unknown macro %s", spec)
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return cronSchedule{}, fmt.Errorf("%q has %d fields, want 5 (minute hour day-of-month month day-of-week)", spec, len(fields))
+	}
+	var s cronSchedule
+	var err error
+	if s.minute, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return s, fmt.Errorf("minute: %w", err)
+	}
+	if s.hour, err = parseCronField(fields[1], 0, 23, nil); err != nil {
+		return s, fmt.Errorf("hour: %w", err)
+	}
+	if s.dom, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return s, fmt.Errorf("day of month: %w", err)
+	}
+	if s.month, err = parseCronField(fields[3], 1, 12, cronMonths); err != nil {
+		return s, fmt.Errorf("month: %w", err)
+	}
+	// 7 is Sunday too
+	if s.dow, err = parseCronField(fields[4], 0, 7, cronWeekdays); err != nil {
+		return s, fmt.Errorf("day of week: %w", err)
+	}
+	if s.dow&(1<<7) != 0 {
+		s.dow = s.dow&^(1<<7) | 1
+	}
+	s.domAny, s.dowAny = strings.HasPrefix(fields[2], "*"), strings.HasPrefix(fields[4], "*")
+	return s, nil
+}
+
+// parseCronField parses a comma-separated list of *, N, N-M and either with
+// a /STEP into a bit set. names, if given, are accepted for the values from
+// min upwards.
+func parseCronField(field string, min, max int, names []string) (uint64, error) {
+	value := func(text string) (int, error) {
+		for i, name := range names {
+			if strings.EqualFold(text, name) {
+				return min + i, nil
+			}
+		}
+		n, err := strconv.Atoi(text)
+		if err != nil || n < min || n > max {
+			return 0, fmt.Errorf("%q is not a number from %d to %d", text, min, max)
+		}
+		return n, nil
+	}
+
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		span, stepText, stepped := strings.Cut(part, "/")
+		step := 1
+		if stepped {
+			n, err := strconv.Atoi(stepText)
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("step %q is not a positive number", stepText)
+			}
+			step = n
+		}
+		low, high := min, max
+		if span != "*" {
+			first, last, ranged := strings.Cut(span, "-")
+			var err error
+			if low, err = value(first); err != nil {
+				return 0, err
+			}
+			high = low
+			if ranged {
+				if high, err = value(last); err != nil {
+					return 0, err
+				}
+			} else if stepped {
+				// N/STEP runs from N to the maximum
+				high = max
+			}
+			if high < low {
+				return 0, fmt.Errorf("range %q ends before it starts", span)
+			}
+		}
+		for n := low; n <= high; n += step {
+			bits |= 1 << n
+		}
+	}
+	return bits, nil
+}
+
+// dayMatches applies cron's rule for the two day fields
+func (s cronSchedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<t.Day()) != 0
+	dow := s.dow&(1<<int(t.Weekday())) != 0
+	if s.domAny || s.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// next returns the first time after t the schedule fires, in t's location,
+// or the zero time for @reboot and expressions that never match, such as
+// February 30
+func (s cronSchedule) next(t time.Time) time.Time {
+	if s.reboot {
+		return time.Time{}
+	}
+	if s.every > 0 {
+		return t.Add(s.every)
+	}
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<int(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case s.hour&(1<<t.Hour()) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case s.minute&(1<<t.Minute()) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
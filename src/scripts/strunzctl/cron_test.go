@@ -0,0 +1,105 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCronField(t *testing.T) {
+	bits := func(values ...int) uint64 {
+		var b uint64
+		for _, v := range values {
+			b |= 1 << v
+		}
+		return b
+	}
+	tests := []struct {
+		field    string
+		min, max int
+		names    []string
+		want     uint64
+	}{
+		{"*", 0, 6, nil, bits(0, 1, 2, 3, 4, 5, 6)},
+		{"5", 0, 59, nil, bits(5)},
+		{"1-4", 0, 59, nil, bits(1, 2, 3, 4)},
+		{"*/15", 0, 59, nil, bits(0, 15, 30, 45)},
+		{"10-30/10", 0, 59, nil, bits(10, 20, 30)},
+		{"50/5", 0, 59, nil, bits(50, 55)},
+		{"1,3,5-6", 0, 59, nil, bits(1, 3, 5, 6)},
+		{"*/2", 1, 31, nil, bits(1, 3, 5, 7, 9, 11, 13, 15, 17, 19, 21, 23, 25, 27, 29, 31)},
+		{"jan,Mar-may", 1, 12, cronMonths, bits(1, 3, 4, 5)},
+		{"mon-fri", 0, 7, cronWeekdays, bits(1, 2, 3, 4, 5)},
+		{"sun,7", 0, 7, cronWeekdays, bits(0, 7)},
+	}
+	for _, test := range tests {
+		got, err := parseCronField(test.field, test.min, test.max, test.names)
+		if err != nil {
+			t.Errorf("%q: %v", test.field, err)
+		} else if got != test.want {
+			t.Errorf("%q = %b, want %b", test.field, got, test.want)
+		}
+	}
+
+	for _, field := range []string{"60", "-1", "5-1", "*/0", "*/x", "monday", "1,,2", ""} {
+		if _, err := parseCronField(field, 0, 59, nil); err == nil {
+			t.Errorf("%q: want an error", field)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	// A Wednesday
+	from := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		spec string
+		want []string
+	}{
+		{"*/20 * * * *", []string{"2025-01-01 12:20", "2025-01-01 12:40", "2025-01-01 13:00"}},
+		{"30 9-10 * * *", []string{"2025-01-02 09:30", "2025-01-02 10:30", "2025-01-03 09:30"}},
+		{"0 0 1 jan,jul *", []string{"2025-07-01 00:00", "2026-01-01 00:00"}},
+		{"@weekly", []string{"2025-01-05 00:00", "2025-01-12 00:00"}},
+		{"0 8 * * mon-fri", []string{"2025-01-02 08:00", "2025-01-03 08:00", "2025-01-06 08:00"}},
+		{"0 0 * * 7", []string{"2025-01-05 00:00"}},
+		// Both day fields restricted: either matches
+		{"0 0 13 * fri", []string{"2025-01-03 00:00", "2025-01-10 00:00", "2025-01-13 00:00", "2025-01-17 00:00"}},
+		// A day field starting with * is unrestricted, so both must match
+		{"0 0 */2 * mon", []string{"2025-01-13 00:00", "2025-01-27 00:00", "2025-02-03 00:00"}},
+		{"0 0 1-7 * */2", []string{"2025-01-02 00:00", "2025-01-04 00:00", "2025-01-05 00:00", "2025-01-07 00:00", "2025-02-01 00:00"}},
+		{"0 0 30 2 *", nil},
+	}
+	for _, test := range tests {
+		schedule, err := parseCron(test.spec)
+		if err != nil {
+			t.Errorf("%q: %v", test.spec, err)
+			continue
+		}
+		at := from
+		for _, want := range test.want {
+			at = schedule.next(at)
+			if got := at.Format("2006-01-02 15:04"); got != want {
+				t.Errorf("%q: next after %s = %s, want %s", test.spec, from.Format("2006-01-02 15:04"), got, want)
+				break
+			}
+		}
+		if test.want == nil {
+			if next := schedule.next(at); !next.IsZero() {
+				t.Errorf("%q: next = %s, want never", test.spec, next)
+			}
+		}
+	}
+}
+
+func TestParseCronMacros(t *testing.T) {
+	every, err := parseCron("@every 90m")
+	if err != nil || every.every != 90*time.Minute {
+		t.Errorf("@every 90m = %+v, %v", every, err)
+	}
+	if reboot, err := parseCron("@reboot"); err != nil || !reboot.reboot {
+		t.Errorf("@reboot = %+v, %v", reboot, err)
+	}
+	for _, spec := range []string{"@every 30s", "@fortnightly", "0 0 * *", "0 24 * * *"} {
+		if _, err := parseCron(spec); err == nil {
+			t.Errorf("%q: want an error", spec)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// defaultServerURL is the production MCP server on Railway
+const defaultServerURL = "https://strunz.up.railway.app"
+
+// HealthStatus is the subset of the server's /health response we rely on
+type HealthStatus struct {
+	Status          string `json:"status"`
+	Service         string `json:"service"`
+	Version         string `json:"version"`
+	Transport       string `json:"transport"`
+	ProtocolVersion string `json:"protocol_version"`
+	ToolsCount      int    `json:"tools_count"`
+
+	// ImageDigest is reported by servers that know which image they run
+	ImageDigest string `json:"image_digest"`
+}
+
+func newDeployCommand() *Command {
+	return newGroup("deploy", "Check and manage the deployed MCP server.",
+		newDeployVerifyCommand(),
+		newDeployStatusCommand(),
+		newDeployLogsCommand(),
+		newDeployRedeployCommand(),
+		newDeployRollbackCommand(),
+		newDeployCanaryCommand(),
+		newDeploySwitchCommand(),
+		newDeployDriftCommand(),
+		newDeployPinCommand(),
+	)
+}
+
+func newDeployVerifyCommand() *Command {
+	cmd := newCommand("verify", "", "Verify the deployed server runs the image published under a GHCR tag.")
+	serverURL := cmd.Flags.String("url", defaultServerURL, "base URL of the deployed server")
+	tag := cmd.Flags.String("tag", "latest", "GHCR tag the deployment is expected to run")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 0); err != nil {
+			return err
+		}
+		registry := newRegistryClient(config.Registry, imageRepository())
+
+		health, err := fetchHealth(*serverURL)
+		if err != nil {
+			return err
+		}
+		manifest, err := registry.GetManifest(*tag)
+		if err != nil {
+			return err
+		}
+		platformManifest, err := selectPlatform(registry, manifest, defaultPlatform)
+		if err != nil {
+			return err
+		}
+		config, err := registry.GetImageConfig(platformManifest)
+		if err != nil {
+			return err
+		}
+		tagVersion := config.Config.Labels[ociLabelPrefix+"version"]
+
+		fmt.Printf("\n🚀 Deployment at %s\n", *serverURL)
+		fmt.Printf("Status:  %s\n", health.Status)
+		fmt.Printf("Version: %s\n", health.Version)
+		if health.ImageDigest != "" {
+			fmt.Printf("Digest:  %s\n", health.ImageDigest)
+		}
+
+		fmt.Printf("\n📦 %s\n", registry.Reference(*tag))
+		fmt.Printf("Version: %s\n", tagVersion)
+		fmt.Printf("Digest:  %s\n", manifest.Digest)
+
+		var mismatches []string
+		if tagVersion == "" {
+			mismatches = append(mismatches, "tag has no org.opencontainers.image.version label")
+		} else if normalizeVersion(health.Version) != normalizeVersion(tagVersion) {
+			mismatches = append(mismatches, fmt.Sprintf("version %s deployed, tag has %s", health.Version, tagVersion))
+		}
+		if health.ImageDigest != "" && health.ImageDigest != manifest.Digest && health.ImageDigest != platformManifest.Digest {
+			mismatches = append(mismatches, fmt.Sprintf("digest %s deployed, tag points to %s", health.ImageDigest, manifest.Digest))
+		}
+
+		if len(mismatches) > 0 {
+			fmt.Println("\n❌ Deployment does not match the tag:")
+			for _, mismatch := range mismatches {
+				fmt.Printf("  - %s\n", mismatch)
+			}
+			return fmt.Errorf("%w: deployment at %s does not match %s", errPolicy, *serverURL, registry.Reference(*tag))
+		}
+
+		if health.ImageDigest == "" {
+			fmt.Println("\nNote: the server does not report an image digest, only the version was compared.")
+		}
+		fmt.Println("\n✅ Deployment matches the tag")
+		return nil
+	}
+	return cmd
+}
+
+// fetchHealth queries the server's /health endpoint
+func fetchHealth(serverURL string) (*HealthStatus, error) {
+	url := strings.TrimRight(serverURL, "/") + "/health"
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("health check failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("health check returned %s", resp.Status)
+	}
+
+	var health HealthStatus
+	if err := json.NewDecoder(resp.Body).Decode(&health); err != nil {
+		return nil, fmt.Errorf("failed to parse health response: %w", err)
+	}
+	return &health, nil
+}
+
+// normalizeVersion strips the optional "v" prefix used by git tags
+func normalizeVersion(version string) string {
+	return strings.TrimPrefix(strings.TrimSpace(version), "v")
+}
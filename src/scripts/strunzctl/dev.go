@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// devImage is the tag of images built by dev up --build
+const devImage = "strunzknowledge:dev"
+
+// devPassthroughEnv are handed to the dev container when set locally
+var devPassthroughEnv = []string{"GOOGLE_GEMINI_API_KEY", "CLAUDE_AI_SKIP_OAUTH"}
+
+// devStartCommand rebuilds the index from the mounted chunks, as the
+// Dockerfile does at build time, before starting the server
+const devStartCommand = "bash src/scripts/data/reconstruct_indices.sh && exec python -u main.py"
+
+func newDevCommand() *Command {
+	return newGroup("dev", "Run the server locally in Docker with the working tree mounted.",
+		newDevUpCommand(),
+		newDevDownCommand(),
+	)
+}
+
+// devMounts mounts the local source and FAISS chunks over the image's copies
+func devMounts(root string) ([]string, error) {
+	var args []string
+	for _, mount := range []struct{ local, container string }{
+		{"src", "/app/src"},
+		{"main.py", "/app/main.py"},
+		{"data/faiss_indices/chunks", "/app/data/faiss_indices/chunks"},
+	} {
+		path := filepath.Join(root, mount.local)
+		if _, err := os.Stat(path); err != nil {
+			return nil, fmt.Errorf("%w: %v", errUsage, err)
+		}
+		args = append(args, "--volume", path+":"+mount.container+":ro")
+	}
+	return args, nil
+}
+
+// runDocker runs a docker command with its output on the terminal
+func runDocker(args ...string) error {
+	cmd := newProcess("docker", args...)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("docker %s failed: %w", args[0], err)
+	}
+	return nil
+}
+
+// devImageFor builds the dev image or pulls the release the working tree is
+// at, falling back to latest when that tag is not published yet
+func devImageFor(root, tag string, build bool, platform string) (string, error) {
+	if build {
+		fmt.Printf("\n🔨 Building %s from %s\n", devImage, filepath.Join(root, "Dockerfile"))
+		return devImage, runDocker("build", "--platform", platform, "--tag", devImage, root)
+	}
+	registry := newRegistryClient(config.Registry, imageRepository())
+	explicit := tag != ""
+	if !explicit {
+		tag = "latest"
+		if _, edits, err := findVersionStrings(root); err == nil {
+			if current, err := currentVersion(edits); err == nil {
+				tag = current.String()
+			}
+		}
+	}
+	reference := registry.Reference(tag)
+	fmt.Printf("\n⬇️  Pulling %s\n", reference)
+	err := runDocker("pull", "--platform", platform, reference)
+	if err != nil && !explicit && tag != "latest" {
+		slog.Warn("Version is not published, using latest", "tag", tag, "error", err)
+		reference = registry.Reference("latest")
+		err = runDocker("pull", "--platform", platform, reference)
+	}
+	return reference, err
+}
+
+// logColors highlight Python logging and uvicorn levels
+var logColors = []struct {
+	markers []string
+	color   string
+}{
+	{[]string{" - ERROR - ", " - CRITICAL - ", "ERROR:", "Traceback (most recent call last)"}, "\033[31m"},
+	{[]string{" - WARNING - ", "WARNING:"}, "\033[33m"},
+	{[]string{" - DEBUG - ", "DEBUG:"}, "\033[2m"},
+	{[]string{"✅", "Uvicorn running on", "Application startup complete"}, "\033[32m"},
+}
+
+// colorLogLine wraps a log line in the color of its level
+func colorLogLine(line string) string {
+	for _, level := range logColors {
+		for _, marker := range level.markers {
+			if strings.Contains(line, marker) {
+				return level.color + line + "\033[0m"
+			}
+		}
+	}
+	return line
+}
+
+// tailContainerLogs follows the logs of a container until ctx ends, in
+// color when writing to a terminal
+func tailContainerLogs(ctx context.Context, container string) error {
+	reader, writer := io.Pipe()
+	cmd := exec.CommandContext(ctx, "docker", "logs", "--follow", "--tail", "100", container)
+	cmd.Stdout, cmd.Stderr = writer, writer
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("docker logs failed: %w", err)
+	}
+	go func() {
+		cmd.Wait()
+		writer.Close()
+	}()
+	color := isTerminal(os.Stdout) && os.Getenv("NO_COLOR") == ""
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if color {
+			line = colorLogLine(line)
+		}
+		fmt.Println(line)
+	}
+	return scanner.Err()
+}
+
+func newDevUpCommand() *Command {
+	cmd := newCommand("up", "", "Start the server with the local source and FAISS chunks mounted, the SSE transport on --port, and tail its logs.")
+	tag := cmd.Flags.String("tag", "", "image tag to run (default: the version of the working tree, or latest when it is not published)")
+	build := cmd.Flags.Bool("build", false, "build "+devImage+" from the repository's Dockerfile instead of pulling")
+	port := cmd.Flags.Int("port", 8000, "local port to serve on")
+	platform := cmd.Flags.String("platform", defaultPlatform, "platform of the image")
+	name := cmd.Flags.String("name", "strunzknowledge-dev", "container name")
+	logLevel := cmd.Flags.String("log-level", "INFO", "LOG_LEVEL of the server: DEBUG, INFO, WARNING or ERROR")
+	detach := cmd.Flags.Bool("detach", false, "return once the container started instead of tailing its logs")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 0); err != nil {
+			return err
+		}
+		if *build && *tag != "" {
+			return fmt.Errorf("%w: --build and --tag exclude each other", errUsage)
+		}
+		root, err := repoPath("")
+		if err != nil {
+			return err
+		}
+		mounts, err := devMounts(root)
+		if err != nil {
+			return err
+		}
+		image, err := devImageFor(root, *tag, *build, *platform)
+		if err != nil {
+			return err
+		}
+
+		// A container left by an earlier dev up would hold the name and port
+		newProcess("docker", "rm", "--force", *name).Run()
+		runArgs := []string{"run", "--detach", "--name", *name, "--platform", *platform,
+			"--publish", fmt.Sprintf("127.0.0.1:%d:8000", *port),
+			"--env", "PORT=8000", "--env", "TRANSPORT=sse", "--env", "LOG_LEVEL=" + strings.ToUpper(*logLevel)}
+		for _, variable := range devPassthroughEnv {
+			if _, ok := os.LookupEnv(variable); ok {
+				runArgs = append(runArgs, "--env", variable)
+			}
+		}
+		runArgs = append(append(runArgs, mounts...), image, "sh", "-c", devStartCommand)
+		var stderr bytes.Buffer
+		start := newProcess("docker", runArgs...)
+		start.Stderr = &stderr
+		if err := start.Run(); err != nil {
+			return fmt.Errorf("failed to start %s: %w: %s", image, err, strings.TrimSpace(stderr.String()))
+		}
+
+		fmt.Printf("\n🚀 %s runs %s with src/, main.py and data/faiss_indices/chunks/ mounted\n", *name, image)
+		fmt.Printf("   SSE endpoint: http://127.0.0.1:%d/sse, health: http://127.0.0.1:%d/health\n", *port, *port)
+		fmt.Printf("   Restart after code changes with `docker restart %s`, stop with `strunzctl dev down`\n\n", *name)
+		if *detach {
+			return nil
+		}
+		ctx, stop := context.WithCancel(commandContext)
+		defer stop()
+		if err := tailContainerLogs(ctx, *name); err != nil && ctx.Err() == nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			fmt.Printf("\n%s keeps running; stop it with `strunzctl dev down`\n", *name)
+			return nil
+		}
+		// The logs end when the server exits
+		return fmt.Errorf("%s exited; see `docker logs %s`", *name, *name)
+	}
+	return cmd
+}
+
+func newDevDownCommand() *Command {
+	cmd := newCommand("down", "", "Stop and remove the container of dev up.")
+	name := cmd.Flags.String("name", "strunzknowledge-dev", "container name")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 0); err != nil {
+			return err
+		}
+		output, err := newProcess("docker", "rm", "--force", *name).CombinedOutput()
+		if err != nil && strings.Contains(string(output), "No such container") {
+			fmt.Printf("%s is not running\n", *name)
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to remove %s: %w: %s", *name, err, strings.TrimSpace(string(output)))
+		}
+		fmt.Printf("✅ Removed %s\n", *name)
+		return nil
+	}
+	return cmd
+}
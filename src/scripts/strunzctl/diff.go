@@ -0,0 +1,200 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/longevitycoach/StrunzKnowledge/src/scripts/strunzctl/pkg/ghcr"
+)
+
+// ImageComparison holds the resolved platform manifests of two tags
+type ImageComparison struct {
+	BaseTag, TargetTag string
+	Base, Target       *Manifest
+}
+
+func newImageDiffCommand() *Command {
+	cmd := newCommand("diff", "<base-tag> <target-tag>", "Compare digests, layers, size and container config between two image tags.")
+	platform := cmd.Flags.String("platform", defaultPlatform, "platform image to compare from manifest lists")
+	skipConfig := cmd.Flags.Bool("layers-only", false, "skip the container config comparison")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 2); err != nil {
+			return err
+		}
+		registry := newRegistryClient(config.Registry, imageRepository())
+
+		comparison, err := compareImages(registry, args[0], args[1], *platform)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("\n🔀 Comparing %s → %s (%s)\n", args[0], args[1], *platform)
+		printLayerDiff(comparison)
+
+		if *skipConfig {
+			return nil
+		}
+		baseConfig, err := registry.GetImageConfig(comparison.Base)
+		if err != nil {
+			return err
+		}
+		targetConfig, err := registry.GetImageConfig(comparison.Target)
+		if err != nil {
+			return err
+		}
+		fmt.Println("\n⚙️  Container config:")
+		if printConfigDiff(baseConfig, targetConfig) == 0 {
+			fmt.Println("  (no changes)")
+		}
+		return nil
+	}
+	return cmd
+}
+
+func compareImages(registry *RegistryClient, baseTag, targetTag, platform string) (*ImageComparison, error) {
+	base, err := resolvePlatformManifest(registry, baseTag, platform)
+	if err != nil {
+		return nil, err
+	}
+	target, err := resolvePlatformManifest(registry, targetTag, platform)
+	if err != nil {
+		return nil, err
+	}
+	return &ImageComparison{BaseTag: baseTag, TargetTag: targetTag, Base: base, Target: target}, nil
+}
+
+func printLayerDiff(c *ImageComparison) {
+	baseSize := ghcr.ManifestSize(c.Base, make(map[string]bool))
+	targetSize := ghcr.ManifestSize(c.Target, make(map[string]bool))
+
+	fmt.Printf("Digest: %s → %s\n", shortDigest(c.Base.Digest), shortDigest(c.Target.Digest))
+	fmt.Printf("Layers: %d → %d\n", len(c.Base.Layers), len(c.Target.Layers))
+	fmt.Printf("Size:   %s → %s (%s)\n", formatBytes(baseSize), formatBytes(targetSize), formatSizeChange(baseSize, targetSize))
+
+	if c.Base.Digest != "" && c.Base.Digest == c.Target.Digest {
+		fmt.Println("\n✅ Both tags point to the same image")
+		return
+	}
+
+	baseLayers := make(map[string]bool)
+	for _, layer := range c.Base.Layers {
+		baseLayers[layer.Digest] = true
+	}
+	targetLayers := make(map[string]bool)
+	for _, layer := range c.Target.Layers {
+		targetLayers[layer.Digest] = true
+	}
+
+	var unchanged int
+	var unchangedSize int64
+	fmt.Println("\nRemoved layers:")
+	removed := 0
+	for _, layer := range c.Base.Layers {
+		if targetLayers[layer.Digest] {
+			unchanged++
+			unchangedSize += layer.Size
+			continue
+		}
+		removed++
+		fmt.Printf("  - %s (%s)\n", shortDigest(layer.Digest), formatBytes(layer.Size))
+	}
+	if removed == 0 {
+		fmt.Println("  (none)")
+	}
+
+	fmt.Println("\nAdded layers:")
+	added := 0
+	for _, layer := range c.Target.Layers {
+		if baseLayers[layer.Digest] {
+			continue
+		}
+		added++
+		fmt.Printf("  + %s (%s)\n", shortDigest(layer.Digest), formatBytes(layer.Size))
+	}
+	if added == 0 {
+		fmt.Println("  (none)")
+	}
+
+	fmt.Printf("\nUnchanged: %d layer(s), %s\n", unchanged, formatBytes(unchangedSize))
+}
+
+// printConfigDiff reports changes to the runtime configuration that affect
+// how the container starts and returns the number of changes found
+func printConfigDiff(base, target *ImageConfig) int {
+	changes := 0
+	scalar := func(name, before, after string) {
+		if before != after {
+			changes++
+			fmt.Printf("  ~ %s: %q → %q\n", name, before, after)
+		}
+	}
+
+	scalar("Entrypoint", strings.Join(base.Config.Entrypoint, " "), strings.Join(target.Config.Entrypoint, " "))
+	scalar("Cmd", strings.Join(base.Config.Cmd, " "), strings.Join(target.Config.Cmd, " "))
+	scalar("User", base.Config.User, target.Config.User)
+	scalar("WorkingDir", base.Config.WorkingDir, target.Config.WorkingDir)
+
+	changes += printMapDiff("Env", envMap(base.Config.Env), envMap(target.Config.Env), true)
+	changes += printMapDiff("ExposedPorts", portMap(base.Config.ExposedPorts), portMap(target.Config.ExposedPorts), false)
+	return changes
+}
+
+// printMapDiff prints added, removed and changed keys in sorted order
+func printMapDiff(name string, before, after map[string]string, withValues bool) int {
+	keys := make(map[string]bool)
+	for key := range before {
+		keys[key] = true
+	}
+	for key := range after {
+		keys[key] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	changes := 0
+	for _, key := range sorted {
+		oldValue, hadOld := before[key]
+		newValue, hasNew := after[key]
+		entry := func(value string) string {
+			if withValues {
+				return key + "=" + value
+			}
+			return key
+		}
+
+		switch {
+		case !hadOld:
+			fmt.Printf("  + %s %s\n", name, entry(newValue))
+		case !hasNew:
+			fmt.Printf("  - %s %s\n", name, entry(oldValue))
+		case oldValue != newValue:
+			fmt.Printf("  ~ %s %s: %q → %q\n", name, key, oldValue, newValue)
+		default:
+			continue
+		}
+		changes++
+	}
+	return changes
+}
+
+func envMap(env []string) map[string]string {
+	values := make(map[string]string, len(env))
+	for _, entry := range env {
+		key, value, _ := strings.Cut(entry, "=")
+		values[key] = value
+	}
+	return values
+}
+
+func portMap(ports map[string]struct{}) map[string]string {
+	values := make(map[string]string, len(ports))
+	for port := range ports {
+		values[port] = ""
+	}
+	return values
+}
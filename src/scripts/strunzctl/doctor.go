@@ -0,0 +1,248 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+)
+
+// tokenOperation is what a group of commands does with the GitHub token.
+// Each entry of Scopes is satisfied by any one of its alternatives.
+type tokenOperation struct {
+	Name     string
+	Scopes   [][]string
+	Commands string
+}
+
+// tokenOperations are the classic token scopes the commands need
+var tokenOperations = []tokenOperation{
+	{"read", [][]string{{"read:packages"}}, "packages, image, scan, tui browsing"},
+	{"write", [][]string{{"write:packages"}}, "image build --push, image promote, image sign, deploy rollback and canary"},
+	{"delete", [][]string{{"read:packages"}, {"delete:packages"}}, "deleting versions in tui"},
+	{"release", [][]string{{"repo", "public_repo"}}, "release create, kb rebuild --upload, secrets rotate, ci clean-artifacts, clean-caches and triage, repo clean-branches"},
+	{"audit", [][]string{{"gist"}}, "audit log publishing to audit.gist"},
+	{"policy", [][]string{{"read:packages"}, {"repo"}}, "audit packages"},
+}
+
+// impliedScopes are granted along with a scope
+var impliedScopes = map[string][]string{
+	"write:packages": {"read:packages"},
+	"repo":           {"public_repo"},
+}
+
+// githubTokenInfo is what GitHub reveals about a token
+type githubTokenInfo struct {
+	Source, Kind, Login string
+	// Scopes is nil when GitHub does not report scopes for the token kind
+	Scopes  []string
+	Expires string
+}
+
+// githubTokenSource names where githubToken finds the token
+func githubTokenSource() string {
+	switch {
+	case config.Token != "":
+		return "the token setting (config file or STRUNZCTL_TOKEN)"
+	case githubAppConfigured():
+		return "GitHub App " + config.GitHubApp.ID
+	case runningAsGHExtension():
+		return "gh auth token (running as gh strunz)"
+	case os.Getenv("GITHUB_TOKEN") != "":
+		return "GITHUB_TOKEN"
+	case loadCredential(githubCredentialServer) != "":
+		return "the keychain (strunzctl login github)"
+	default:
+		return "gh auth token"
+	}
+}
+
+// githubTokenKind tells the token kinds apart by their prefix
+func githubTokenKind(token string) string {
+	switch {
+	case strings.HasPrefix(token, "ghp_"):
+		return "classic personal access token"
+	case strings.HasPrefix(token, "gho_"):
+		return "OAuth token"
+	case strings.HasPrefix(token, "github_pat_"):
+		return "fine-grained personal access token"
+	case strings.HasPrefix(token, "ghs_"):
+		return "GitHub Actions or app installation token"
+	case strings.HasPrefix(token, "ghu_"):
+		return "GitHub App user token"
+	default:
+		return "token"
+	}
+}
+
+// inspectToken reads the scopes GitHub reports in X-OAuth-Scopes. Tokens
+// whose permissions are not scopes, like GITHUB_TOKEN in Actions, are
+// refused by /user but still authenticate.
+func inspectToken(github *GitHubClient) (*githubTokenInfo, error) {
+	resp, err := doWithRetry(github.httpClient, func() (*http.Request, error) {
+		return github.newRequest(http.MethodGet, github.url("/user"))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("GitHub API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read GitHub API response: %w", err)
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		return nil, fmt.Errorf("%w: GitHub rejects the token from %s; it is invalid, expired or revoked", errAuth, githubTokenSource())
+	}
+	info := &githubTokenInfo{Source: githubTokenSource(), Kind: githubTokenKind(github.token),
+		Expires: resp.Header.Get("GitHub-Authentication-Token-Expiration")}
+	if resp.StatusCode == http.StatusOK {
+		var user struct {
+			Login string `json:"login"`
+		}
+		json.Unmarshal(body, &user)
+		info.Login = user.Login
+	}
+	if values, ok := resp.Header["X-Oauth-Scopes"]; ok {
+		info.Scopes = []string{}
+		for _, scope := range strings.Split(strings.Join(values, ","), ",") {
+			if scope = strings.TrimSpace(scope); scope != "" {
+				info.Scopes = append(info.Scopes, scope)
+			}
+		}
+	}
+	return info, nil
+}
+
+// missingScopes returns the requirements of an operation no granted scope
+// satisfies, each with its alternatives
+func missingScopes(operation tokenOperation, granted []string) [][]string {
+	have := make(map[string]bool)
+	for _, scope := range granted {
+		have[scope] = true
+		for _, implied := range impliedScopes[scope] {
+			have[implied] = true
+		}
+	}
+	var missing [][]string
+	for _, alternatives := range operation.Scopes {
+		if !slices.ContainsFunc(alternatives, func(scope string) bool { return have[scope] }) {
+			missing = append(missing, alternatives)
+		}
+	}
+	return missing
+}
+
+func newDoctorCommand() *Command {
+	cmd := newGroup("doctor", "Check the local setup before running commands: config, network, tokens, package access and tools.",
+		newDoctorAuthCommand(),
+	)
+	cmd.Args = "[command]"
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 0); err != nil {
+			return err
+		}
+		return runDoctor()
+	}
+	return cmd
+}
+
+func newDoctorAuthCommand() *Command {
+	var names []string
+	for _, operation := range tokenOperations {
+		names = append(names, operation.Name)
+	}
+	cmd := newCommand("auth", "[operation...]", "Check that the GitHub token has the scopes an operation needs ("+strings.Join(names, ", ")+"; default read) and explain what is missing.")
+	all := cmd.Flags.Bool("all", false, "check every operation")
+
+	cmd.Run = func(args []string) error {
+		for _, arg := range args {
+			if !slices.Contains(names, arg) {
+				return fmt.Errorf("%w: unknown operation %q, expected one of %s", errUsage, arg, strings.Join(names, ", "))
+			}
+		}
+		var selected []tokenOperation
+		for _, operation := range tokenOperations {
+			if *all || slices.Contains(args, operation.Name) || (len(args) == 0 && operation.Name == "read") {
+				selected = append(selected, operation)
+			}
+		}
+
+		github, err := newGitHubClient()
+		if err != nil {
+			return err
+		}
+		info, err := inspectToken(github)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("\n🔑 %s from %s", info.Kind, info.Source)
+		if info.Login != "" {
+			fmt.Printf(", authenticated as %s", info.Login)
+		}
+		fmt.Println()
+		if info.Expires != "" {
+			fmt.Printf("   expires %s\n", info.Expires)
+		}
+
+		if info.Scopes == nil {
+			// Without scopes to compare, only explain what the token kind needs
+			fmt.Println("⚠️  GitHub does not report scopes for this token kind; check its permissions instead:")
+			for _, operation := range selected {
+				var scopes []string
+				for _, alternatives := range operation.Scopes {
+					scopes = append(scopes, strings.Join(alternatives, " or "))
+				}
+				fmt.Printf("   %-8s %s (%s)\n", operation.Name, strings.Join(scopes, ", "), operation.Commands)
+			}
+			switch {
+			case strings.HasPrefix(github.token, "github_pat_"):
+				fmt.Println("   GitHub Packages only accepts classic tokens; create one at https://github.com/settings/tokens/new")
+			case github.app:
+				fmt.Printf("   Grant them in the settings of GitHub App %s: packages write (read for read only) and contents write for releases\n", config.GitHubApp.ID)
+			case strings.HasPrefix(github.token, "ghs_"):
+				fmt.Println("   In a workflow, grant them with `permissions: packages: write` (read for read only) and `contents: write` for releases")
+			}
+			return nil
+		}
+		fmt.Printf("   scopes: %s\n\n", orNone(strings.Join(info.Scopes, ", ")))
+
+		var missing []string
+		for _, operation := range selected {
+			lacking := missingScopes(operation, info.Scopes)
+			if len(lacking) == 0 {
+				fmt.Printf("✅ %-8s %s\n", operation.Name, operation.Commands)
+				continue
+			}
+			var described []string
+			for _, alternatives := range lacking {
+				described = append(described, strings.Join(alternatives, " or "))
+				missing = append(missing, alternatives[0])
+			}
+			fmt.Printf("❌ %-8s %s: missing %s\n", operation.Name, operation.Commands, strings.Join(described, ", "))
+		}
+		if len(missing) == 0 {
+			return nil
+		}
+
+		slices.Sort(missing)
+		missing = slices.Compact(missing)
+		fmt.Println()
+		if info.Source == "gh auth token" {
+			fmt.Printf("Add them with: gh auth refresh --scopes %s\n", strings.Join(missing, ","))
+		} else {
+			// Scopes of a token cannot change; a new one has to replace it
+			scopes := slices.Clone(info.Scopes)
+			for _, scope := range missing {
+				if !slices.Contains(scopes, scope) {
+					scopes = append(scopes, scope)
+				}
+			}
+			fmt.Printf("Replace the token in %s with one created at https://github.com/settings/tokens/new?scopes=%s\n", info.Source, strings.Join(scopes, ","))
+		}
+		return fmt.Errorf("%w: the token lacks %s", errAuth, strings.Join(missing, ", "))
+	}
+	return cmd
+}
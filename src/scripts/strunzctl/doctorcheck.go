@@ -0,0 +1,260 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// doctorCheck is the outcome of one environment check with what to do
+// about it
+type doctorCheck struct {
+	Name   string
+	Status string // ok, warn or fail
+	Detail string
+	Fix    string
+}
+
+func (c doctorCheck) print() {
+	icon := map[string]string{"ok": "✅", "warn": "⚠️ ", "fail": "❌"}[c.Status]
+	fmt.Printf("%s %-18s %s\n", icon, c.Name, c.Detail)
+	if c.Fix != "" {
+		fmt.Printf("   %-18s → %s\n", "", c.Fix)
+	}
+}
+
+// checkConfig reports the config file in use; a config that does not
+// parse fails before any command runs
+func checkConfig() doctorCheck {
+	path := globalOptions.configPath
+	if path == "" {
+		path = defaultConfigPath()
+	}
+	detail := fmt.Sprintf("%s/%s on %s", config.Org, config.Package, config.Registry)
+	if _, err := os.Stat(path); err != nil {
+		return doctorCheck{Name: "Config", Status: "ok", Detail: detail + " (no " + path + ", defaults and environment)"}
+	}
+	return doctorCheck{Name: "Config", Status: "ok", Detail: detail + " (" + path + ")"}
+}
+
+// checkReachable sends a request to a host and explains why it failed.
+// Any HTTP response, even 401, means the network path works.
+func checkReachable(name, target string) doctorCheck {
+	client := &http.Client{Timeout: 10 * time.Second}
+	start := time.Now()
+	resp, err := client.Get(target)
+	if err == nil {
+		resp.Body.Close()
+		return doctorCheck{Name: name, Status: "ok", Detail: fmt.Sprintf("%s reachable (%s)", target, time.Since(start).Round(time.Millisecond))}
+	}
+
+	check := doctorCheck{Name: name, Status: "fail", Detail: fmt.Sprintf("%s unreachable: %v", target, err)}
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	var certErr *tls.CertificateVerificationError
+	var authorityErr x509.UnknownAuthorityError
+	switch {
+	case errors.As(err, &certErr), errors.As(err, &authorityErr):
+		check.Fix = "a TLS-inspecting proxy re-signs the connection; trust its CA with --ca-bundle or the ca_bundle setting"
+	case errors.As(err, &dnsErr):
+		check.Fix = "the host name does not resolve; check DNS, or set the proxy setting or HTTPS_PROXY if the network only allows a proxy"
+	case errors.As(err, &netErr) && netErr.Timeout():
+		check.Fix = "no answer within 10s; a firewall may block it, set the proxy setting or HTTPS_PROXY if one is needed"
+	case strings.Contains(err.Error(), "proxyconnect"):
+		check.Fix = "the proxy refused the connection; check the proxy setting, HTTPS_PROXY and NO_PROXY"
+	default:
+		check.Fix = "check the network connection and proxy settings"
+	}
+	return check
+}
+
+// checkGitHubToken finds the token and compares its scopes with what
+// reading packages needs
+func checkGitHubToken() (doctorCheck, *GitHubClient) {
+	github, err := newGitHubClient()
+	if err != nil && githubAppConfigured() {
+		return doctorCheck{Name: "GitHub token", Status: "fail", Detail: err.Error(),
+			Fix: "check github_app.id, the installation and the private key"}, nil
+	}
+	if err != nil {
+		return doctorCheck{Name: "GitHub token", Status: "fail", Detail: "no token found",
+			Fix: "run `gh auth login` or `strunzctl login github`, or set GITHUB_TOKEN"}, nil
+	}
+	info, err := inspectToken(github)
+	if err != nil {
+		check := doctorCheck{Name: "GitHub token", Status: "fail", Detail: err.Error()}
+		if errors.Is(err, errAuth) {
+			check.Fix = "replace it with a token created at https://github.com/settings/tokens/new?scopes=read:packages"
+		}
+		return check, nil
+	}
+	detail := info.Kind + " from " + info.Source
+	if info.Login != "" {
+		detail += " (" + info.Login + ")"
+	}
+	if info.Expires != "" {
+		detail += ", expires " + info.Expires
+	}
+	if info.Scopes == nil {
+		return doctorCheck{Name: "GitHub token", Status: "warn", Detail: detail + "; scopes are not reported for this kind",
+			Fix: "run `strunzctl doctor auth --all` for the permissions each operation needs"}, github
+	}
+	if missing := missingScopes(tokenOperations[0], info.Scopes); len(missing) > 0 {
+		fix := "create a token at https://github.com/settings/tokens/new?scopes=read:packages"
+		if info.Source == "gh auth token" {
+			fix = "run `gh auth refresh --scopes read:packages`"
+		}
+		return doctorCheck{Name: "GitHub token", Status: "fail", Detail: detail + "; lacks read:packages", Fix: fix}, github
+	}
+	return doctorCheck{Name: "GitHub token", Status: "ok", Detail: detail + "; scopes " + strings.Join(info.Scopes, ", ")}, github
+}
+
+// checkPackageAccess tells a missing package from one the token cannot
+// see, which GitHub both answers with 404
+func checkPackageAccess(github *GitHubClient) doctorCheck {
+	name := config.Org + "/" + config.Package
+	resp, err := doWithRetry(github.httpClient, func() (*http.Request, error) {
+		return github.newRequest(http.MethodGet, github.url(fmt.Sprintf("/orgs/%s/packages/container/%s", config.Org, config.Package)))
+	})
+	if err != nil {
+		return doctorCheck{Name: "Package", Status: "fail", Detail: err.Error()}
+	}
+	resp.Body.Close()
+	check := doctorCheck{Name: "Package", Status: "fail", Detail: fmt.Sprintf("%s: %s", name, resp.Status)}
+	switch sso := resp.Header.Get("X-GitHub-SSO"); {
+	case resp.StatusCode == http.StatusOK:
+		return doctorCheck{Name: "Package", Status: "ok", Detail: name + " readable"}
+	case strings.HasPrefix(sso, "required"):
+		check.Fix = "the organization enforces SAML SSO; authorize the token at " + strings.TrimPrefix(sso, "required; url=")
+	case resp.StatusCode == http.StatusNotFound:
+		check.Fix = fmt.Sprintf("check org and package in the config; if they are right, the token's account needs access to the package in %s (Package settings → Manage access)", config.Org)
+	case resp.StatusCode == http.StatusForbidden:
+		check.Fix = "the token is not allowed to read packages of " + config.Org + "; run `strunzctl doctor auth read`"
+	}
+	return check
+}
+
+// checkRailway looks for a Railway token, which only the deploy, secrets
+// and logs commands need
+func checkRailway() doctorCheck {
+	railway, err := newRailwayClient()
+	if err != nil {
+		return doctorCheck{Name: "Railway token", Status: "warn", Detail: "none (only deploy, secrets and logs need one)",
+			Fix: "run `strunzctl login railway` or set RAILWAY_API_TOKEN"}
+	}
+	if railway.ProjectToken {
+		return doctorCheck{Name: "Railway token", Status: "ok", Detail: "project token from RAILWAY_TOKEN"}
+	}
+	projects, err := validateRailwayToken(railway.Token)
+	if err != nil {
+		return doctorCheck{Name: "Railway token", Status: "fail", Detail: err.Error(),
+			Fix: "create an account or team token at https://railway.com/account/tokens and run `strunzctl login railway`"}
+	}
+	return doctorCheck{Name: "Railway token", Status: "ok", Detail: fmt.Sprintf("sees %d project(s)", projects)}
+}
+
+// checkDocker tells a missing docker CLI from a daemon that is not running
+func checkDocker() doctorCheck {
+	if _, err := exec.LookPath("docker"); err != nil {
+		return doctorCheck{Name: "Docker", Status: "warn", Detail: "not installed (only image build, dev and test need it)",
+			Fix: "install Docker Desktop or Docker Engine from https://docs.docker.com/get-docker/"}
+	}
+	output, err := newProcess("docker", "version", "--format", "{{.Server.Version}}").CombinedOutput()
+	if err != nil {
+		return doctorCheck{Name: "Docker", Status: "warn", Detail: "the daemon is not reachable: " + truncate(strings.TrimSpace(string(output)), 100),
+			Fix: "start Docker (Docker Desktop, or `sudo systemctl start docker`), or check DOCKER_HOST"}
+	}
+	return doctorCheck{Name: "Docker", Status: "ok", Detail: "daemon " + strings.TrimSpace(string(output))}
+}
+
+// doctorTools are the external programs some commands run
+var doctorTools = []struct{ names, usedBy string }{
+	{"git", "release, kb and repo commands"},
+	{"gh", "the token fallback and secrets rotate"},
+	{"cosign", "image sign, verify-signature and self-update"},
+	{"trivy grype", "scan; either one"},
+}
+
+// checkTools reports the optional programs that are not installed
+func checkTools() []doctorCheck {
+	var checks []doctorCheck
+	for _, tool := range doctorTools {
+		found := ""
+		for _, name := range strings.Fields(tool.names) {
+			if _, err := exec.LookPath(name); err == nil {
+				found = name
+				break
+			}
+		}
+		name := strings.ReplaceAll(tool.names, " ", "/")
+		if found == "" {
+			checks = append(checks, doctorCheck{Name: name, Status: "warn", Detail: "not installed (" + tool.usedBy + ")"})
+			continue
+		}
+		checks = append(checks, doctorCheck{Name: name, Status: "ok", Detail: found + " found"})
+	}
+	return checks
+}
+
+// runDoctor checks the whole environment and prints a fix for every
+// problem found
+func runDoctor() error {
+	fmt.Printf("\n🩺 Checking the strunzctl environment\n\n")
+	var checks []doctorCheck
+	add := func(check doctorCheck) doctorCheck {
+		check.print()
+		checks = append(checks, check)
+		return check
+	}
+	add(checkConfig())
+
+	registryHost := config.Registry
+	if registryHost == "docker.io" {
+		registryHost = "registry-1.docker.io"
+	}
+	githubReachable := add(checkReachable("GitHub API", githubAPIURL())).Status == "ok"
+	add(checkReachable("Registry", "https://"+registryHost+"/v2/"))
+	add(checkReachable("Railway API", railwayAPI))
+
+	if githubReachable {
+		token, github := checkGitHubToken()
+		add(token)
+		if github != nil {
+			add(checkPackageAccess(github))
+		}
+	} else {
+		add(doctorCheck{Name: "GitHub token", Status: "warn", Detail: "not checked, the GitHub API is unreachable"})
+	}
+	add(checkRailway())
+	add(checkDocker())
+	for _, check := range checkTools() {
+		add(check)
+	}
+
+	failed, warned := 0, 0
+	for _, check := range checks {
+		switch check.Status {
+		case "fail":
+			failed++
+		case "warn":
+			warned++
+		}
+	}
+	fmt.Println()
+	if failed > 0 {
+		return fmt.Errorf("%w: %d check(s) failed, %d warning(s)", errPolicy, failed, warned)
+	}
+	if warned > 0 {
+		fmt.Printf("✅ Ready, with %d warning(s) for optional features\n", warned)
+		return nil
+	}
+	fmt.Println("✅ Everything strunzctl needs is in place")
+	return nil
+}
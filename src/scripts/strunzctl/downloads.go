@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// downloadCount matches the "Total downloads" figure on a package page.
+// GitHub exposes no API for container download counts, so the public web
+// page is the only source; the match is loose to survive markup changes.
+var downloadCount = regexp.MustCompile(`(?s)Total downloads.{0,300}?title="([\d,]+)"`)
+
+func newPackagesDownloadsCommand() *Command {
+	cmd := newCommand("downloads", "", "Show per-version download counts from the GitHub package pages.")
+	limit := cmd.Flags.Int("limit", 20, "number of most recent versions to check (0 for all)")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 0); err != nil {
+			return err
+		}
+		github, err := newGitHubClient()
+		if err != nil {
+			return err
+		}
+		versions, err := listPackageVersions(github)
+		if err != nil {
+			return err
+		}
+		if *limit > 0 && len(versions) > *limit {
+			versions = versions[:*limit]
+		}
+
+		client := &http.Client{Timeout: 30 * time.Second}
+		counts := make([]int64, len(versions))
+		countErr := forEachConcurrent(versions, globalOptions.concurrency, func(i int, version PackageVersion) error {
+			count, err := versionDownloads(client, version)
+			if err != nil {
+				counts[i] = -1
+				return fmt.Errorf("downloads of %s: %w", version.Tag(), err)
+			}
+			counts[i] = count
+			return nil
+		})
+
+		fmt.Println("\n📥 Downloads:")
+		var total int64
+		for i, version := range versions {
+			count := "unknown"
+			if counts[i] >= 0 {
+				count = strconv.FormatInt(counts[i], 10)
+				total += counts[i]
+			}
+			fmt.Printf("  - %s (ID: %d, Created: %s, Downloads: %s)\n",
+				strings.Join(version.Metadata.Container.Tags, ", "), version.ID, version.CreatedAt.Format(time.DateOnly), count)
+		}
+		fmt.Printf("\nTotal downloads of listed versions: %d\n", total)
+		fmt.Println("Note: counts are scraped from the package pages and include CI pulls; GitHub offers no download API for containers.")
+
+		if countErr != nil {
+			fmt.Println("\n⚠️  Some download counts could not be read:")
+			fmt.Println(countErr)
+			return fmt.Errorf("incomplete download counts")
+		}
+		return nil
+	}
+	return cmd
+}
+
+// versionDownloads reads the download count from the version's package page
+func versionDownloads(client *http.Client, version PackageVersion) (int64, error) {
+	url := fmt.Sprintf("https://github.com/orgs/%s/packages/container/%s/%d", config.Org, config.Package, version.ID)
+	resp, err := doWithRetry(client, func() (*http.Request, error) {
+		return http.NewRequest(http.MethodGet, url, nil)
+	})
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("package page returned %s", resp.Status)
+	}
+
+	page, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read package page: %w", err)
+	}
+	match := downloadCount.FindSubmatch(page)
+	if match == nil {
+		return 0, fmt.Errorf("no download count on %s (private package or changed page layout)", url)
+	}
+	return strconv.ParseInt(strings.ReplaceAll(string(match[1]), ",", ""), 10, 64)
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// versionDrift describes how far production lags behind the releases
+type versionDrift struct {
+	production Semver
+	newestTag  *ReleaseTag
+	release    *GitHubRelease
+	// releaseRuns are the workflow runs of the latest release's tag
+	releaseRuns []releaseRun
+	// missing are the released tags newer than production, oldest first
+	missing []ReleaseTag
+}
+
+// behindSince is when the oldest release production lacks was published
+func (d versionDrift) behindSince() time.Time {
+	if len(d.missing) == 0 {
+		return time.Time{}
+	}
+	return d.missing[0].Version.CreatedAt
+}
+
+func newDeployDriftCommand() *Command {
+	cmd := newCommand("drift", "", "Report how far the production server lags behind the newest image tag and GitHub release.")
+	serverURL := cmd.Flags.String("url", defaultServerURL, "base URL of the production server")
+	prereleases := cmd.Flags.Bool("prereleases", false, "count prerelease tags as releases")
+	maxBehind := cmd.Flags.Int("max-behind", 0, "releases production may lag before the drift is reported as a failure")
+	maxAge := cmd.Flags.Duration("max-age", 0, "how long production may lag before the drift is reported as a failure, e.g. 72h (0 to ignore)")
+	notify := cmd.Flags.Bool("notify-webhook", false, "post to webhooks.notify and the alerts sinks when a threshold is exceeded")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 0); err != nil {
+			return err
+		}
+		notifier, err := newNotifier(*notify)
+		if err != nil {
+			return err
+		}
+		github, err := newGitHubClient()
+		if err != nil {
+			return err
+		}
+
+		health, err := fetchHealth(*serverURL)
+		if err != nil {
+			return err
+		}
+		production, ok := parseSemver(health.Version)
+		if !ok {
+			return fmt.Errorf("production reports version %q, which is not semver", health.Version)
+		}
+		versions, err := listPackageVersions(github)
+		if err != nil {
+			return err
+		}
+		// The latest release is usually among the first few
+		activity, err := fetchReleaseActivity(github, 5)
+		if err != nil {
+			return err
+		}
+
+		drift := computeDrift(production, versions, *prereleases)
+		if activity.Latest != nil {
+			drift.release = activity.Latest
+			drift.releaseRuns = activity.Runs[activity.Latest.TagName]
+		}
+		printDrift(*serverURL, drift)
+
+		var exceeded []string
+		if len(drift.missing) > *maxBehind {
+			exceeded = append(exceeded, fmt.Sprintf("%d release(s) behind, at most %d allowed", len(drift.missing), *maxBehind))
+		}
+		if lag := time.Since(drift.behindSince()); *maxAge > 0 && len(drift.missing) > 0 && lag > *maxAge {
+			exceeded = append(exceeded, fmt.Sprintf("behind for %s, at most %s allowed", formatAge(lag), formatAge(*maxAge)))
+		}
+		if drift.release != nil {
+			if latest, ok := parseSemver(drift.release.TagName); ok && latest.Compare(production) > 0 &&
+				(drift.newestTag == nil || latest.Compare(drift.newestTag.Semver) > 0) {
+				reason := fmt.Sprintf("release %s has no image tag yet", drift.release.TagName)
+				if len(drift.releaseRuns) > 0 {
+					reason += " (" + summarizeRuns(drift.releaseRuns) + ")"
+				}
+				exceeded = append(exceeded, reason)
+			}
+		}
+
+		if len(exceeded) == 0 {
+			if len(drift.missing) == 0 {
+				fmt.Println("\n✅ Production runs the newest release")
+			} else {
+				fmt.Println("\n✅ Drift within thresholds")
+			}
+			return nil
+		}
+		fmt.Println("\n❌ Production drift exceeds thresholds:")
+		message := fmt.Sprintf("%s runs %s", *serverURL, production)
+		for _, reason := range exceeded {
+			fmt.Printf("  - %s\n", reason)
+			message += "\n- " + reason
+		}
+		notifier.alert(severityWarning, "drift "+*serverURL, "Production version drift", message)
+		return fmt.Errorf("%w: production at %s is behind the newest release", errPolicy, *serverURL)
+	}
+	return cmd
+}
+
+// computeDrift finds the released tags newer than the production version
+func computeDrift(production Semver, versions []PackageVersion, prereleases bool) versionDrift {
+	drift := versionDrift{production: production}
+	seen := make(map[string]bool)
+	for _, version := range versions {
+		for _, tag := range version.Metadata.Container.Tags {
+			semver, ok := parseSemver(tag)
+			if !ok || (semver.Prerelease != "" && !prereleases) || seen[semver.String()] {
+				continue
+			}
+			// v2.3.0 and 2.3.0 are usually both pushed for one release
+			seen[semver.String()] = true
+			release := ReleaseTag{Semver: semver, Version: version}
+			if drift.newestTag == nil || semver.Compare(drift.newestTag.Semver) > 0 {
+				drift.newestTag = &release
+			}
+			if semver.Compare(production) > 0 {
+				drift.missing = append(drift.missing, release)
+			}
+		}
+	}
+	slices.SortFunc(drift.missing, func(a, b ReleaseTag) int { return a.Compare(b.Semver) })
+	return drift
+}
+
+func printDrift(serverURL string, drift versionDrift) {
+	fmt.Printf("\n🧭 Version drift of %s\n", serverURL)
+	fmt.Printf("Production:     %s\n", drift.production)
+	if drift.newestTag != nil {
+		fmt.Printf("Newest image:   %s (pushed %s)\n", drift.newestTag.Original, drift.newestTag.Version.CreatedAt.Format(time.DateOnly))
+	} else {
+		fmt.Println("Newest image:   (no semver tags)")
+	}
+	if drift.release != nil {
+		fmt.Printf("Latest release: %s (%s)\n", drift.release.TagName, drift.release.HTMLURL)
+		if len(drift.releaseRuns) > 0 {
+			fmt.Printf("Release runs:   %s\n", summarizeRuns(drift.releaseRuns))
+		}
+	} else {
+		fmt.Println("Latest release: (none)")
+	}
+
+	if len(drift.missing) == 0 {
+		return
+	}
+	fmt.Printf("\nBehind by %d release(s) for %s:\n", len(drift.missing), formatAge(time.Since(drift.behindSince())))
+	for _, release := range drift.missing {
+		fmt.Printf("  %-16s %s\n", release.Original, release.Version.CreatedAt.Format(time.DateOnly))
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/longevitycoach/StrunzKnowledge/src/scripts/strunzctl/pkg/ghcr"
+	railwayapi "github.com/longevitycoach/StrunzKnowledge/src/scripts/strunzctl/pkg/railway"
+)
+
+// Exit codes are a stable contract for CI scripts; only add new ones
+const (
+	exitOK          = 0
+	exitFailure     = 1   // any error not covered below
+	exitNotFound    = 2   // package, tag, manifest or blob does not exist
+	exitAuth        = 3   // missing, invalid or insufficient credentials
+	exitPolicy      = 4   // a check ran and failed: labels, signatures, deploy drift
+	exitUnavailable = 5   // network failure or service unavailable after retries
+	exitUsage       = 64  // invalid command line (sysexits EX_USAGE)
+	exitTimeout     = 124 // --timeout expired (as timeout(1) exits)
+	exitInterrupted = 130 // SIGINT or SIGTERM (as shells report SIGINT)
+)
+
+var (
+	// errPolicy marks checks that ran successfully and found a violation
+	errPolicy = errors.New("policy violation")
+	// errAuth marks missing or rejected credentials
+	errAuth = errors.New("authentication failed")
+	// errOffline marks requests --offline cannot answer from the cache
+	errOffline = errors.New("not available offline")
+)
+
+// errorKinds names the exit codes in machine-readable errors
+var errorKinds = map[int]string{
+	exitFailure:     "failure",
+	exitNotFound:    "not_found",
+	exitAuth:        "auth",
+	exitPolicy:      "policy",
+	exitUnavailable: "unavailable",
+	exitUsage:       "usage",
+	exitTimeout:     "timeout",
+	exitInterrupted: "interrupted",
+}
+
+// exitCode classifies an error returned by a command
+func exitCode(err error) int {
+	var registryErr *RegistryError
+	var githubErr *GitHubError
+	var netErr net.Error
+	status := 0
+	if errors.As(err, &registryErr) {
+		status = registryErr.StatusCode
+	} else if errors.As(err, &githubErr) {
+		status = githubErr.StatusCode
+	}
+
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return exitOK
+	case errors.Is(err, errInterrupted):
+		return exitInterrupted
+	case errors.Is(err, errTimeout):
+		return exitTimeout
+	case errors.Is(err, errUsage):
+		return exitUsage
+	case errors.Is(err, errPolicy):
+		return exitPolicy
+	case errors.Is(err, errAuth), errors.Is(err, ghcr.ErrUnauthorized), errors.Is(err, railwayapi.ErrUnauthorized), status == http.StatusUnauthorized, status == http.StatusForbidden:
+		return exitAuth
+	case status == http.StatusNotFound:
+		return exitNotFound
+	case errors.Is(err, errOffline), status == http.StatusTooManyRequests, status >= 500, errors.As(err, &netErr):
+		return exitUnavailable
+	}
+	return exitFailure
+}
+
+// reportError writes the final error to stderr in the requested format
+func reportError(err error, format string) {
+	code := exitCode(err)
+	if format == "json" {
+		json.NewEncoder(os.Stderr).Encode(map[string]any{
+			"error": err.Error(),
+			"kind":  errorKinds[code],
+			"code":  code,
+		})
+		return
+	}
+	if err == errUsage {
+		// Usage has already been printed
+		return
+	}
+	slog.Error(fmt.Sprintf("Command failed (%s)", errorKinds[code]), "error", err, "exit_code", code)
+}
@@ -0,0 +1,905 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fakeImage is an image the fake registry serves. Its manifests, config
+// and one small layer per platform are generated from the fixture, so
+// the same fixture always yields the same digests.
+type fakeImage struct {
+	Tags    []string  `json:"tags"`
+	Created time.Time `json:"created"`
+	// Platforms defaults to linux/amd64; more than one makes an OCI index
+	Platforms []string          `json:"platforms,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	// SBOM is an SPDX document attached to each platform image as a
+	// BuildKit attestation, which makes an OCI index of the image
+	SBOM json.RawMessage `json:"sbom,omitempty"`
+}
+
+// fakeRelease is a release of the fake repository
+type fakeRelease struct {
+	Tag        string    `json:"tag"`
+	Name       string    `json:"name"`
+	Body       string    `json:"body"`
+	Draft      bool      `json:"draft,omitempty"`
+	Prerelease bool      `json:"prerelease,omitempty"`
+	Published  time.Time `json:"published"`
+	Runs       []fakeRun `json:"runs,omitempty"`
+}
+
+// fakeRun is a workflow run of a release tag; an empty Conclusion means it
+// is still in progress
+type fakeRun struct {
+	Workflow   string `json:"workflow"`
+	Event      string `json:"event"`
+	Conclusion string `json:"conclusion,omitempty"`
+}
+
+func (r fakeRun) status() string {
+	if r.Conclusion == "" {
+		return "in_progress"
+	}
+	return "completed"
+}
+
+// fakeFixtures is everything the fake API serves. Only requests carrying
+// Token authenticate.
+type fakeFixtures struct {
+	Org      string        `json:"org"`
+	Repo     string        `json:"repo"`
+	Package  string        `json:"package"`
+	Token    string        `json:"token"`
+	Login    string        `json:"login"`
+	Scopes   string        `json:"scopes"`
+	Images   []fakeImage   `json:"images"`
+	Releases []fakeRelease `json:"releases"`
+}
+
+// defaultFakeFixtures mirror the published package: a multi-platform
+// release, the current release with the latest tag and an untagged build
+func defaultFakeFixtures() fakeFixtures {
+	day := func(d int) time.Time { return time.Date(2025, 7, d, 9, 30, 0, 0, time.UTC) }
+	labels := func(version, revision string) map[string]string {
+		return map[string]string{
+			ociLabelPrefix + "description": "Dr. Strunz Knowledge Base MCP server",
+			ociLabelPrefix + "source":      "https://github.com/longevitycoach/StrunzKnowledge",
+			ociLabelPrefix + "revision":    revision,
+			ociLabelPrefix + "version":     version,
+		}
+	}
+	return fakeFixtures{
+		Org:     "longevitycoach",
+		Repo:    "longevitycoach/StrunzKnowledge",
+		Package: "strunzknowledge",
+		Token:   "fake-token",
+		Login:   "fake-user",
+		Scopes:  "read:packages, write:packages, delete:packages, repo",
+		Images: []fakeImage{
+			{Tags: nil, Created: day(1), Labels: labels("0.9.0", "1f0c2d3")},
+			{Tags: []string{"1.0.0"}, Created: day(3), Platforms: []string{"linux/amd64", "linux/arm64"}, Labels: labels("1.0.0", "8e41b7a")},
+			{Tags: []string{"1.1.0", "latest"}, Created: day(10), Labels: labels("1.1.0", "c2d9f04")},
+		},
+		Releases: []fakeRelease{
+			{Tag: "v1.0.0", Name: "v1.0.0", Body: "First stable release", Published: day(3), Runs: []fakeRun{
+				{Workflow: "Docker Build", Event: "push", Conclusion: "success"},
+			}},
+			{Tag: "v1.1.0", Name: "v1.1.0", Body: "Forum search", Published: day(10), Runs: []fakeRun{
+				{Workflow: "Docker Build", Event: "push", Conclusion: "success"},
+				{Workflow: "Security Scan", Event: "release", Conclusion: "failure"},
+			}},
+		},
+	}
+}
+
+// loadFakeFixtures reads a JSON fixtures file; fields it leaves out keep
+// their defaults
+func loadFakeFixtures(path string) (fakeFixtures, error) {
+	fixtures := defaultFakeFixtures()
+	if path == "" {
+		return fixtures, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fakeFixtures{}, fmt.Errorf("failed to read fixtures: %w", err)
+	}
+	if err := json.Unmarshal(data, &fixtures); err != nil {
+		return fakeFixtures{}, fmt.Errorf("%w: %s: %v", errUsage, path, err)
+	}
+	return fixtures, nil
+}
+
+// fakeFault makes requests whose path contains Match fail with Status, or
+// with a connection reset when Status is 0. Times limits it to the first
+// matching requests; 0 fails every one.
+type fakeFault struct {
+	Match  string
+	Status int
+	Times  int
+	hits   int
+}
+
+// parseFakeFault parses PATH=STATUS[xTIMES], e.g. /versions=503x2 or
+// /blobs/=reset
+func parseFakeFault(value string) (*fakeFault, error) {
+	match, rest, ok := strings.Cut(value, "=")
+	if !ok || match == "" {
+		return nil, fmt.Errorf("%w: fault %q is not PATH=STATUS[xTIMES]", errUsage, value)
+	}
+	fault := &fakeFault{Match: match}
+	status, times, limited := strings.Cut(rest, "x")
+	if limited {
+		n, err := strconv.Atoi(times)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("%w: fault %q: TIMES must be a positive number", errUsage, value)
+		}
+		fault.Times = n
+	}
+	if status != "reset" {
+		code, err := strconv.Atoi(status)
+		if err != nil || code < 400 || code > 599 {
+			return nil, fmt.Errorf("%w: fault %q: STATUS must be a 4xx or 5xx code or reset", errUsage, value)
+		}
+		fault.Status = code
+	}
+	return fault, nil
+}
+
+// fakeVersion is a package version: a top-level manifest and its tags
+type fakeVersion struct {
+	ID      int64
+	Digest  string
+	Tags    []string
+	Created time.Time
+}
+
+// fakeAPI serves the GitHub REST endpoints, the registry v2 API and an MCP
+// server the commands use, from fixtures held in memory. Deletes and pushes change
+// the state, so a command's effect can be checked by the next one.
+type fakeAPI struct {
+	server *httptest.Server
+	// logRequests prints every request with its status
+	logRequests bool
+
+	mu        sync.Mutex
+	fixtures  fakeFixtures
+	versions  []*fakeVersion
+	nextID    int64
+	blobs     map[string][]byte
+	manifests map[string]string // digest → media type
+	uploads   map[string]bool
+	faults    []*fakeFault
+	latency   time.Duration
+	requests  int
+	mcp       fakeMCPSessions
+}
+
+// newFakeAPI generates the images of the fixtures; start serves them
+func newFakeAPI(fixtures fakeFixtures) (*fakeAPI, error) {
+	api := &fakeAPI{}
+	if err := api.reset(fixtures); err != nil {
+		return nil, err
+	}
+	api.server = httptest.NewUnstartedServer(api)
+	// Clients dropping connections, e.g. after an injected reset, are
+	// not worth reporting
+	api.server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	return api, nil
+}
+
+// reset replaces the state with freshly generated fixtures and clears
+// the faults
+func (a *fakeAPI) reset(fixtures fakeFixtures) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.fixtures = fixtures
+	a.versions, a.nextID = nil, 1000
+	a.blobs, a.manifests, a.uploads = make(map[string][]byte), make(map[string]string), make(map[string]bool)
+	a.faults, a.latency = nil, 0
+	for _, image := range fixtures.Images {
+		digest, err := a.addImage(image)
+		if err != nil {
+			return err
+		}
+		a.nextID++
+		a.versions = append(a.versions, &fakeVersion{ID: a.nextID, Digest: digest, Tags: slices.Clone(image.Tags), Created: image.Created})
+	}
+	return nil
+}
+
+// setFaults replaces the injected faults and latency
+func (a *fakeAPI) setFaults(faults []*fakeFault, latency time.Duration) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.faults, a.latency = faults, latency
+}
+
+// start serves over TLS with the test certificate of httptest, which
+// is valid for 127.0.0.1
+func (a *fakeAPI) start(listener net.Listener) {
+	if listener != nil {
+		a.server.Listener.Close()
+		a.server.Listener = listener
+	}
+	a.server.StartTLS()
+}
+
+// host is the address the registry is configured with
+func (a *fakeAPI) host() string {
+	return a.server.Listener.Addr().String()
+}
+
+// writeCA stores the certificate clients must trust as a PEM file
+func (a *fakeAPI) writeCA(path string) error {
+	block := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: a.server.Certificate().Raw})
+	return os.WriteFile(path, block, 0o644)
+}
+
+// environment points strunzctl at the fake API
+func (a *fakeAPI) environment(caPath string) []string {
+	return []string{
+		"STRUNZCTL_GITHUB_API=" + a.server.URL,
+		"STRUNZCTL_REGISTRY=" + a.host(),
+		"STRUNZCTL_ORG=" + a.fixtures.Org,
+		"STRUNZCTL_REPO=" + a.fixtures.Repo,
+		"STRUNZCTL_PACKAGE=" + a.fixtures.Package,
+		"STRUNZCTL_CA_BUNDLE=" + caPath,
+		"GITHUB_TOKEN=" + a.fixtures.Token,
+	}
+}
+
+func fakeDigest(content []byte) string {
+	sum := sha256.Sum256(content)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// addBlob stores content and returns its descriptor
+func (a *fakeAPI) addBlob(mediaType string, content []byte) Descriptor {
+	digest := fakeDigest(content)
+	a.blobs[digest] = content
+	return Descriptor{MediaType: mediaType, Digest: digest, Size: int64(len(content))}
+}
+
+// addManifest stores a manifest and returns its descriptor
+func (a *fakeAPI) addManifest(mediaType string, manifest any) Descriptor {
+	body, _ := json.Marshal(manifest)
+	desc := a.addBlob(mediaType, body)
+	a.manifests[desc.Digest] = mediaType
+	return desc
+}
+
+// addImage generates the manifests and blobs of a fixture image and
+// returns the digest of its top-level manifest
+func (a *fakeAPI) addImage(image fakeImage) (string, error) {
+	platforms := image.Platforms
+	if len(platforms) == 0 {
+		platforms = []string{"linux/amd64"}
+	}
+	version := image.Labels[ociLabelPrefix+"version"]
+	var children []Descriptor
+	for _, platform := range platforms {
+		parts := strings.Split(platform, "/")
+		if len(parts) < 2 {
+			return "", fmt.Errorf("%w: platform %q is not os/arch", errUsage, platform)
+		}
+		target := Platform{OS: parts[0], Architecture: parts[1]}
+		if len(parts) > 2 {
+			target.Variant = parts[2]
+		}
+
+		var layer, rootfs bytes.Buffer
+		content := []byte(version + " " + platform + "\n")
+		archive := tar.NewWriter(&rootfs)
+		archive.WriteHeader(&tar.Header{Name: "app/VERSION", Mode: 0o644, Size: int64(len(content)), ModTime: image.Created, Typeflag: tar.TypeReg})
+		archive.Write(content)
+		archive.Close()
+		compressed := gzip.NewWriter(&layer)
+		compressed.Write(rootfs.Bytes())
+		compressed.Close()
+		layerDesc := a.addBlob("application/vnd.oci.image.layer.v1.tar+gzip", layer.Bytes())
+
+		config, _ := json.Marshal(map[string]any{
+			"created":      image.Created,
+			"architecture": target.Architecture,
+			"os":           target.OS,
+			"variant":      target.Variant,
+			"config": map[string]any{
+				"User":         "app",
+				"Env":          []string{"PATH=/usr/local/bin:/usr/bin:/bin", "PORT=8000"},
+				"Cmd":          []string{"python", "-u", "main.py"},
+				"WorkingDir":   "/app",
+				"ExposedPorts": map[string]struct{}{"8000/tcp": {}},
+				"Labels":       image.Labels,
+			},
+			"rootfs": map[string]any{"type": "layers", "diff_ids": []string{fakeDigest(rootfs.Bytes())}},
+			"history": []map[string]any{
+				{"created": image.Created, "created_by": "WORKDIR /app", "empty_layer": true},
+				{"created": image.Created, "created_by": "COPY VERSION /app/VERSION # buildkit"},
+			},
+		})
+		configDesc := a.addBlob("application/vnd.oci.image.config.v1+json", config)
+		desc := a.addManifest(mediaTypeOCIManifest, map[string]any{
+			"schemaVersion": 2,
+			"mediaType":     mediaTypeOCIManifest,
+			"config":        configDesc,
+			"layers":        []Descriptor{layerDesc},
+		})
+		desc.Platform = &target
+		children = append(children, desc)
+	}
+	if image.SBOM != nil {
+		for _, child := range slices.Clone(children) {
+			children = append(children, a.addAttestation(child, image.SBOM))
+		}
+	}
+	if len(children) == 1 {
+		return children[0].Digest, nil
+	}
+	index := a.addManifest(mediaTypeOCIIndex, map[string]any{
+		"schemaVersion": 2,
+		"mediaType":     mediaTypeOCIIndex,
+		"manifests":     children,
+	})
+	return index.Digest, nil
+}
+
+// addAttestation stores the attestation manifest BuildKit writes for a
+// platform image, with the SBOM as its SPDX statement, and returns its
+// index entry
+func (a *fakeAPI) addAttestation(image Descriptor, sbom json.RawMessage) Descriptor {
+	statement, _ := json.Marshal(map[string]any{
+		"_type":         "https://in-toto.io/Statement/v0.1",
+		"predicateType": predicateSPDX,
+		"subject":       []map[string]any{{"name": "_", "digest": map[string]string{"sha256": strings.TrimPrefix(image.Digest, "sha256:")}}},
+		"predicate":     sbom,
+	})
+	layer := a.addBlob("application/vnd.in-toto+json", statement)
+	layer.Annotations = map[string]string{"in-toto.io/predicate-type": predicateSPDX}
+	desc := a.addManifest(mediaTypeOCIManifest, map[string]any{
+		"schemaVersion": 2,
+		"mediaType":     mediaTypeOCIManifest,
+		"config":        a.addBlob("application/vnd.oci.image.config.v1+json", []byte("{}")),
+		"layers":        []Descriptor{layer},
+	})
+	desc.Platform = &Platform{OS: "unknown", Architecture: "unknown"}
+	desc.Annotations = map[string]string{"vnd.docker.reference.digest": image.Digest, "vnd.docker.reference.type": "attestation-manifest"}
+	return desc
+}
+
+// fakeRecorder keeps the status of a response for the request log
+type fakeRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *fakeRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *fakeRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+func (a *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	a.requests++
+	latency := a.latency
+	var fault *fakeFault
+	for _, candidate := range a.faults {
+		if strings.Contains(r.URL.Path, candidate.Match) && (candidate.Times == 0 || candidate.hits < candidate.Times) {
+			candidate.hits++
+			fault = candidate
+			break
+		}
+	}
+	a.mu.Unlock()
+
+	time.Sleep(latency)
+	target := r.Method + " " + r.URL.RequestURI()
+	if fault != nil && fault.Status == 0 {
+		if a.logRequests {
+			fmt.Printf("  💥 reset %s\n", target)
+		}
+		resetConnection(w)
+		return
+	}
+	recorder := &fakeRecorder{ResponseWriter: w, status: http.StatusOK}
+	switch {
+	case fault != nil:
+		if fault.Status == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", "1")
+		}
+		fakeJSON(recorder, fault.Status, map[string]string{"message": "fake-api: injected fault"})
+	case r.URL.Path == "/token":
+		a.serveToken(recorder, r)
+	case strings.HasPrefix(r.URL.Path, "/v2/"):
+		a.serveRegistry(recorder, r)
+	case r.URL.Path == "/sse" || r.URL.Path == "/messages/":
+		a.serveMCP(recorder, r)
+	default:
+		a.serveGitHub(recorder, r)
+	}
+	if a.logRequests {
+		icon := "✅"
+		if fault != nil {
+			icon = "💥"
+		} else if recorder.status >= 400 {
+			icon = "❌"
+		}
+		fmt.Printf("  %s %d %s\n", icon, recorder.status, target)
+	}
+}
+
+func fakeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// authorized checks the bearer token GitHub clients and the registry
+// token exchange send
+func (a *fakeAPI) authorized(r *http.Request, prefix string) bool {
+	a.mu.Lock()
+	token := a.fixtures.Token
+	a.mu.Unlock()
+	header := r.Header.Get("Authorization")
+	return header == "Bearer "+prefix+token || header == "token "+prefix+token
+}
+
+// fakePage slices a list by the page and per_page query parameters and sets
+// the Link header GitHub paginates with
+func fakePage[T any](w http.ResponseWriter, r *http.Request, items []T) []T {
+	perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+	if perPage < 1 || perPage > 100 {
+		perPage = 30
+	}
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	start := min((page-1)*perPage, len(items))
+	end := min(start+perPage, len(items))
+	if end < len(items) {
+		next := *r.URL
+		query := next.Query()
+		query.Set("page", strconv.Itoa(page+1))
+		query.Set("per_page", strconv.Itoa(perPage))
+		next.RawQuery = query.Encode()
+		w.Header().Set("Link", fmt.Sprintf(`<https://%s%s>; rel="next"`, r.Host, next.RequestURI()))
+	}
+	return items[start:end]
+}
+
+func (a *fakeAPI) serveGitHub(w http.ResponseWriter, r *http.Request) {
+	if !a.authorized(r, "") {
+		fakeJSON(w, http.StatusUnauthorized, map[string]string{"message": "Bad credentials"})
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	fixtures := a.fixtures
+	w.Header().Set("X-OAuth-Scopes", fixtures.Scopes)
+	w.Header().Set("X-RateLimit-Remaining", "4999")
+
+	notFound := map[string]string{"message": "Not Found"}
+	packagePath := fmt.Sprintf("/orgs/%s/packages/container/%s", fixtures.Org, fixtures.Package)
+	pkg := map[string]any{
+		"name":          fixtures.Package,
+		"package_type":  "container",
+		"visibility":    "public",
+		"html_url":      fmt.Sprintf("https://github.com/orgs/%s/packages/container/package/%s", fixtures.Org, fixtures.Package),
+		"version_count": len(a.versions),
+	}
+	if len(a.versions) > 0 {
+		pkg["created_at"], pkg["updated_at"] = a.versions[0].Created, a.versions[len(a.versions)-1].Created
+	}
+
+	path := r.URL.Path
+	switch {
+	case path == "/graphql" && r.Method == http.MethodPost:
+		a.serveGraphQL(w, r, fixtures)
+	case path == "/user" && r.Method == http.MethodGet:
+		fakeJSON(w, http.StatusOK, map[string]string{"login": fixtures.Login})
+	case path == "/orgs/"+fixtures.Org+"/packages" && r.Method == http.MethodGet:
+		fakeJSON(w, http.StatusOK, []any{pkg})
+	case path == packagePath && r.Method == http.MethodGet:
+		fakeJSON(w, http.StatusOK, pkg)
+	case path == packagePath+"/versions" && r.Method == http.MethodGet:
+		// Newest first, as GitHub lists them
+		versions := make([]map[string]any, 0, len(a.versions))
+		for i := len(a.versions) - 1; i >= 0; i-- {
+			versions = append(versions, a.versionJSON(a.versions[i]))
+		}
+		fakeJSON(w, http.StatusOK, fakePage(w, r, versions))
+	case strings.HasPrefix(path, packagePath+"/versions/"):
+		id, _ := strconv.ParseInt(strings.TrimPrefix(path, packagePath+"/versions/"), 10, 64)
+		i := slices.IndexFunc(a.versions, func(v *fakeVersion) bool { return v.ID == id })
+		switch {
+		case i < 0:
+			fakeJSON(w, http.StatusNotFound, notFound)
+		case r.Method == http.MethodDelete:
+			a.versions = slices.Delete(a.versions, i, i+1)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			fakeJSON(w, http.StatusOK, a.versionJSON(a.versions[i]))
+		}
+	case strings.HasPrefix(path, "/repos/"+fixtures.Repo+"/releases") && r.Method == http.MethodGet:
+		rest := strings.TrimPrefix(path, "/repos/"+fixtures.Repo+"/releases")
+		releases := make([]map[string]any, 0, len(fixtures.Releases))
+		for i := len(fixtures.Releases) - 1; i >= 0; i-- {
+			release := fixtures.Releases[i]
+			if rest == "/latest" && (release.Draft || release.Prerelease) {
+				continue
+			}
+			if tag, ok := strings.CutPrefix(rest, "/tags/"); ok && tag != release.Tag {
+				continue
+			}
+			releases = append(releases, map[string]any{
+				"id":           2000 + i,
+				"tag_name":     release.Tag,
+				"name":         release.Name,
+				"body":         release.Body,
+				"draft":        release.Draft,
+				"prerelease":   release.Prerelease,
+				"published_at": release.Published,
+				"created_at":   release.Published,
+				"html_url":     fmt.Sprintf("https://github.com/%s/releases/tag/%s", fixtures.Repo, release.Tag),
+				"assets":       []any{},
+			})
+		}
+		switch {
+		case rest == "" || rest == "/":
+			fakeJSON(w, http.StatusOK, fakePage(w, r, releases))
+		case len(releases) > 0 && (rest == "/latest" || strings.HasPrefix(rest, "/tags/")):
+			fakeJSON(w, http.StatusOK, releases[0])
+		default:
+			fakeJSON(w, http.StatusNotFound, notFound)
+		}
+	case path == "/repos/"+fixtures.Repo+"/actions/runs" && r.Method == http.MethodGet:
+		runs := []map[string]any{}
+		for i := len(fixtures.Releases) - 1; i >= 0; i-- {
+			release := fixtures.Releases[i]
+			for j, run := range release.Runs {
+				id := 3000 + i*10 + j
+				runs = append(runs, map[string]any{
+					"id":          id,
+					"name":        run.Workflow,
+					"head_branch": release.Tag,
+					"event":       run.Event,
+					"status":      run.status(),
+					"conclusion":  run.Conclusion,
+					"html_url":    fmt.Sprintf("https://github.com/%s/actions/runs/%d", fixtures.Repo, id),
+					"created_at":  release.Published,
+				})
+			}
+		}
+		fakeJSON(w, http.StatusOK, map[string]any{"total_count": len(runs), "workflow_runs": fakePage(w, r, runs)})
+	default:
+		fakeJSON(w, http.StatusNotFound, notFound)
+	}
+}
+
+// serveGraphQL answers the releaseActivity query from the fixtures. Any
+// other query fails as one on fields the fake does not know would.
+func (a *fakeAPI) serveGraphQL(w http.ResponseWriter, r *http.Request, fixtures fakeFixtures) {
+	var request struct {
+		Query     string         `json:"query"`
+		Variables map[string]any `json:"variables"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		fakeJSON(w, http.StatusBadRequest, map[string]string{"message": "Problems parsing JSON"})
+		return
+	}
+	if !strings.HasPrefix(request.Query, "query releaseActivity(") {
+		fakeJSON(w, http.StatusOK, map[string]any{"errors": []map[string]string{{"message": "fake-api: unsupported query"}}})
+		return
+	}
+	if fmt.Sprintf("%v/%v", request.Variables["owner"], request.Variables["name"]) != fixtures.Repo {
+		fakeJSON(w, http.StatusOK, map[string]any{
+			"data":   map[string]any{"repository": nil},
+			"errors": []map[string]string{{"type": "NOT_FOUND", "message": "Could not resolve to a Repository"}},
+		})
+		return
+	}
+	first, _ := request.Variables["first"].(float64)
+
+	node := func(i int, withCommit bool) map[string]any {
+		release := fixtures.Releases[i]
+		result := map[string]any{
+			"tagName":      release.Tag,
+			"name":         release.Name,
+			"url":          fmt.Sprintf("https://github.com/%s/releases/tag/%s", fixtures.Repo, release.Tag),
+			"isDraft":      release.Draft,
+			"isPrerelease": release.Prerelease,
+			"publishedAt":  release.Published,
+		}
+		if withCommit {
+			suites := []map[string]any{}
+			for j, run := range release.Runs {
+				suites = append(suites, map[string]any{
+					"status":     strings.ToUpper(run.status()),
+					"conclusion": strings.ToUpper(run.Conclusion),
+					"workflowRun": map[string]any{
+						"url":      fmt.Sprintf("https://github.com/%s/actions/runs/%d", fixtures.Repo, 3000+i*10+j),
+						"event":    strings.ToUpper(run.Event),
+						"workflow": map[string]string{"name": run.Workflow},
+					},
+				})
+			}
+			result["tagCommit"] = map[string]any{"checkSuites": map[string]any{"nodes": suites}}
+		}
+		return result
+	}
+	var latest any
+	nodes := []map[string]any{}
+	for i := len(fixtures.Releases) - 1; i >= 0; i-- {
+		release := fixtures.Releases[i]
+		if latest == nil && !release.Draft && !release.Prerelease {
+			latest = node(i, false)
+		}
+		if len(nodes) < int(first) {
+			nodes = append(nodes, node(i, true))
+		}
+	}
+	fakeJSON(w, http.StatusOK, map[string]any{"data": map[string]any{
+		"repository": map[string]any{"latestRelease": latest, "releases": map[string]any{"nodes": nodes}},
+		"rateLimit":  map[string]int{"cost": 1, "remaining": 4999},
+	}})
+}
+
+func (a *fakeAPI) versionJSON(version *fakeVersion) map[string]any {
+	return map[string]any{
+		"id":         version.ID,
+		"name":       version.Digest,
+		"created_at": version.Created,
+		"updated_at": version.Created,
+		"metadata": map[string]any{
+			"package_type": "container",
+			"container":    map[string]any{"tags": append([]string{}, version.Tags...)},
+		},
+	}
+}
+
+// serveToken hands out registry tokens for Basic credentials whose
+// password is the GitHub token, as ghcr.io does
+func (a *fakeAPI) serveToken(w http.ResponseWriter, r *http.Request) {
+	_, password, _ := r.BasicAuth()
+	a.mu.Lock()
+	token := a.fixtures.Token
+	a.mu.Unlock()
+	if password != token {
+		fakeJSON(w, http.StatusUnauthorized, map[string]any{"errors": []map[string]string{{"code": "UNAUTHORIZED", "message": "authentication required"}}})
+		return
+	}
+	fakeJSON(w, http.StatusOK, map[string]string{"token": "registry-" + token})
+}
+
+func (a *fakeAPI) serveRegistry(w http.ResponseWriter, r *http.Request) {
+	registryError := func(status int, code string) {
+		fakeJSON(w, status, map[string]any{"errors": []map[string]string{{"code": code, "message": strings.ToLower(strings.ReplaceAll(code, "_", " "))}}})
+	}
+	authorized := a.authorized(r, "registry-")
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	name := a.fixtures.Org + "/" + a.fixtures.Package
+	if !authorized {
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="https://%s/token",service="%s",scope="repository:%s:pull"`, r.Host, r.Host, name))
+		registryError(http.StatusUnauthorized, "UNAUTHORIZED")
+		return
+	}
+	if r.URL.Path == "/v2/" {
+		fakeJSON(w, http.StatusOK, map[string]any{})
+		return
+	}
+	repository := "/v2/" + name + "/"
+	rest, ok := strings.CutPrefix(r.URL.Path, repository)
+	if !ok {
+		registryError(http.StatusNotFound, "NAME_UNKNOWN")
+		return
+	}
+
+	kind, reference, _ := strings.Cut(rest, "/")
+	switch {
+	case kind == "tags" && reference == "list":
+		var tags []string
+		for _, version := range a.versions {
+			tags = append(tags, version.Tags...)
+		}
+		slices.Sort(tags)
+		fakeJSON(w, http.StatusOK, map[string]any{"name": name, "tags": tags})
+	case kind == "manifests" && r.Method == http.MethodPut:
+		a.putManifest(w, r, reference)
+	case kind == "manifests":
+		digest := reference
+		if !strings.HasPrefix(reference, "sha256:") {
+			digest = ""
+			for _, version := range a.versions {
+				if slices.Contains(version.Tags, reference) {
+					digest = version.Digest
+				}
+			}
+		}
+		mediaType, ok := a.manifests[digest]
+		if !ok {
+			registryError(http.StatusNotFound, "MANIFEST_UNKNOWN")
+			return
+		}
+		body := a.blobs[digest]
+		w.Header().Set("Content-Type", mediaType)
+		w.Header().Set("Docker-Content-Digest", digest)
+		w.Header().Set("ETag", `"`+digest+`"`)
+		if r.Header.Get("If-None-Match") == `"`+digest+`"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		if r.Method != http.MethodHead {
+			w.Write(body)
+		}
+	case kind == "blobs" && r.Method == http.MethodPost && reference == "uploads/":
+		id := fmt.Sprintf("upload-%d", len(a.uploads)+1)
+		a.uploads[id] = true
+		w.Header().Set("Location", repository+"blobs/uploads/"+id)
+		w.WriteHeader(http.StatusAccepted)
+	case kind == "blobs" && r.Method == http.MethodPut && strings.HasPrefix(reference, "uploads/"):
+		if !a.uploads[strings.TrimPrefix(reference, "uploads/")] {
+			registryError(http.StatusNotFound, "BLOB_UPLOAD_UNKNOWN")
+			return
+		}
+		content, err := io.ReadAll(r.Body)
+		if err != nil || fakeDigest(content) != r.URL.Query().Get("digest") {
+			registryError(http.StatusBadRequest, "DIGEST_INVALID")
+			return
+		}
+		a.addBlob("", content)
+		w.Header().Set("Docker-Content-Digest", fakeDigest(content))
+		w.WriteHeader(http.StatusCreated)
+	case kind == "blobs":
+		body, ok := a.blobs[reference]
+		if !ok {
+			registryError(http.StatusNotFound, "BLOB_UNKNOWN")
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Docker-Content-Digest", reference)
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		if r.Method != http.MethodHead {
+			w.Write(body)
+		}
+	default:
+		registryError(http.StatusNotFound, "NAME_UNKNOWN")
+	}
+}
+
+// putManifest stores a pushed manifest. Tagging moves the tag from the
+// version that had it, the way GHCR does on a retag.
+func (a *fakeAPI) putManifest(w http.ResponseWriter, r *http.Request, reference string) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		fakeJSON(w, http.StatusBadRequest, map[string]string{"message": err.Error()})
+		return
+	}
+	digest := fakeDigest(body)
+	if strings.HasPrefix(reference, "sha256:") && reference != digest {
+		fakeJSON(w, http.StatusBadRequest, map[string]any{"errors": []map[string]string{{"code": "DIGEST_INVALID"}}})
+		return
+	}
+	a.blobs[digest] = body
+	a.manifests[digest] = r.Header.Get("Content-Type")
+
+	var target *fakeVersion
+	for _, version := range a.versions {
+		if version.Digest == digest {
+			target = version
+		}
+	}
+	if target == nil {
+		a.nextID++
+		target = &fakeVersion{ID: a.nextID, Digest: digest, Created: time.Now().UTC()}
+		a.versions = append(a.versions, target)
+	}
+	if !strings.HasPrefix(reference, "sha256:") {
+		for _, version := range a.versions {
+			version.Tags = slices.DeleteFunc(version.Tags, func(tag string) bool { return tag == reference })
+		}
+		target.Tags = append(target.Tags, reference)
+	}
+	w.Header().Set("Docker-Content-Digest", digest)
+	w.Header().Set("Location", r.URL.Path)
+	w.WriteHeader(http.StatusCreated)
+}
+
+func newTestFakeAPICommand() *Command {
+	cmd := newCommand("fake-api", "", "Serve a fake GitHub packages/releases API and registry from fixtures, with injected faults, to run commands offline.")
+	listen := cmd.Flags.String("listen", "127.0.0.1:0", "address to listen on")
+	fixturesPath := cmd.Flags.String("fixtures", "", "JSON fixtures file (default: the built-in package with three versions and two releases)")
+	faults := cmd.Flags.String("fault", "", "comma-separated PATH=STATUS[xTIMES] faults, e.g. /versions=503x2,/blobs/=reset")
+	latency := cmd.Flags.Duration("latency", 0, "delay added to every response")
+	envFile := cmd.Flags.String("env-file", "", "also write the environment to this file, for `source` or a CI step")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 0); err != nil {
+			return err
+		}
+		fixtures, err := loadFakeFixtures(*fixturesPath)
+		if err != nil {
+			return err
+		}
+		var injected []*fakeFault
+		for _, value := range splitList(*faults) {
+			fault, err := parseFakeFault(value)
+			if err != nil {
+				return err
+			}
+			injected = append(injected, fault)
+		}
+		api, err := newFakeAPI(fixtures)
+		if err != nil {
+			return err
+		}
+		api.setFaults(injected, *latency)
+		api.logRequests = true
+
+		listener, err := net.Listen("tcp", *listen)
+		if err != nil {
+			return fmt.Errorf("failed to listen: %w", err)
+		}
+		api.start(listener)
+		defer api.server.Close()
+
+		dir, err := os.MkdirTemp("", "strunzctl-fake-api-")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+		caPath := filepath.Join(dir, "ca.pem")
+		if err := api.writeCA(caPath); err != nil {
+			return err
+		}
+
+		var exports strings.Builder
+		for _, variable := range api.environment(caPath) {
+			exports.WriteString("export " + variable + "\n")
+		}
+		if *envFile != "" {
+			if err := os.WriteFile(*envFile, []byte(exports.String()), 0o600); err != nil {
+				return fmt.Errorf("failed to write environment: %w", err)
+			}
+		}
+		fmt.Printf("\n🧪 Fake GitHub API and registry on %s (%d version(s), %d release(s), Ctrl-C to stop)\n\n",
+			api.server.URL, len(fixtures.Images), len(fixtures.Releases))
+		fmt.Print(exports.String())
+		fmt.Println()
+
+		ctx, stop := context.WithCancel(commandContext)
+		defer stop()
+		<-ctx.Done()
+		api.mu.Lock()
+		defer api.mu.Unlock()
+		fmt.Printf("\n📊 %d request(s) served\n", api.requests)
+		return nil
+	}
+	return cmd
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// fakeMCPDocuments are what the fake server's search_knowledge finds
+var fakeMCPDocuments = []struct{ Source, Content string }{
+	{"book: Fixture book A", "Vitamin D supports the immune system; a blood level of 60 to 80 ng/ml is the target for most adults."},
+	{"book: Fixture book A", "Magnesium is needed by hundreds of enzymes and is best taken in the evening."},
+	{"news: Fixture news 1", "Morning runs at a low heart rate train the fat metabolism."},
+	{"news: Fixture news 2", "Omega-3 fatty acids lower the inflammation markers measured in the blood."},
+	{"forum: Fixture thread", "Which protein powder do you take after training?"},
+}
+
+// fakeMCPSessions are the open SSE streams by session ID
+type fakeMCPSessions struct {
+	next    int
+	streams map[string]chan []byte
+}
+
+// serveMCP is a FastMCP server on the SSE transport with the
+// search_knowledge tool, searching fakeMCPDocuments by word overlap
+func (a *fakeAPI) serveMCP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/sse" {
+		a.serveMCPStream(w, r)
+		return
+	}
+	a.mu.Lock()
+	stream := a.mcp.streams[r.URL.Query().Get("session_id")]
+	a.mu.Unlock()
+	if stream == nil || r.Method != http.MethodPost {
+		http.Error(w, "Could not find session", http.StatusNotFound)
+		return
+	}
+	var request struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+		Params json.RawMessage `json:"params"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Could not parse message", http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+	if len(request.ID) == 0 {
+		return
+	}
+	response := map[string]any{"jsonrpc": "2.0", "id": request.ID}
+	switch request.Method {
+	case "initialize":
+		response["result"] = map[string]any{
+			"protocolVersion": "2024-11-05",
+			"capabilities":    map[string]any{"tools": map[string]any{"listChanged": false}},
+			"serverInfo":      map[string]string{"name": "Dr. Strunz Knowledge Base", "version": "1.1.0"},
+		}
+	case "tools/list":
+		response["result"] = map[string]any{"tools": []map[string]any{{
+			"name":        "search_knowledge",
+			"description": "Search Dr. Strunz's knowledge base with semantic search.",
+			"inputSchema": map[string]any{"type": "object", "required": []string{"query"}, "properties": map[string]any{
+				"query": map[string]string{"type": "string"},
+				"limit": map[string]any{"type": "integer", "default": 10},
+			}},
+		}}}
+	case "tools/call":
+		var params struct {
+			Name      string `json:"name"`
+			Arguments struct {
+				Query string `json:"query"`
+				Limit int    `json:"limit"`
+			} `json:"arguments"`
+		}
+		json.Unmarshal(request.Params, &params)
+		if params.Name != "search_knowledge" {
+			response["error"] = map[string]any{"code": -32602, "message": "Unknown tool: " + params.Name}
+			break
+		}
+		text := fakeSearch(params.Arguments.Query, cmp.Or(params.Arguments.Limit, 10))
+		response["result"] = map[string]any{"content": []map[string]string{{"type": "text", "text": text}}, "isError": false}
+	default:
+		response["error"] = map[string]any{"code": -32601, "message": "Method not found"}
+	}
+	message, _ := json.Marshal(response)
+	select {
+	case stream <- message:
+	case <-r.Context().Done():
+	}
+}
+
+// serveMCPStream announces the message endpoint of a new session and
+// sends the session's responses as message events until the client leaves
+func (a *fakeAPI) serveMCPStream(w http.ResponseWriter, r *http.Request) {
+	stream := make(chan []byte, 16)
+	a.mu.Lock()
+	if a.mcp.streams == nil {
+		a.mcp.streams = make(map[string]chan []byte)
+	}
+	a.mcp.next++
+	session := strconv.Itoa(a.mcp.next)
+	a.mcp.streams[session] = stream
+	a.mu.Unlock()
+	defer func() {
+		a.mu.Lock()
+		delete(a.mcp.streams, session)
+		a.mu.Unlock()
+	}()
+
+	controller := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	fmt.Fprintf(w, "event: endpoint\ndata: /messages/?session_id=%s\n\n", session)
+	controller.Flush()
+	for {
+		select {
+		case message := <-stream:
+			fmt.Fprintf(w, "event: message\ndata: %s\n\n", message)
+			controller.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// fakeSearch ranks the documents by the share of query words they contain
+// and formats them the way search_knowledge does
+func fakeSearch(query string, limit int) string {
+	split := func(text string) []string {
+		return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+	}
+	words := split(query)
+	type hit struct {
+		Source, Content string
+		Score           float64
+	}
+	var hits []hit
+	for _, doc := range fakeMCPDocuments {
+		content := split(doc.Content)
+		found := 0
+		for _, word := range words {
+			if slices.Contains(content, word) {
+				found++
+			}
+		}
+		if found > 0 {
+			hits = append(hits, hit{doc.Source, doc.Content, float64(found) / float64(len(words))})
+		}
+	}
+	if len(hits) == 0 {
+		return "No results found for query: " + query
+	}
+	slices.SortStableFunc(hits, func(a, b hit) int { return cmp.Compare(b.Score, a.Score) })
+	hits = hits[:min(limit, len(hits))]
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Found %d results for %q:\n\n", len(hits), query)
+	for i, hit := range hits {
+		fmt.Fprintf(&b, "**Result %d:**\n**Source:** %s\n**Content:** %s\n**Relevance Score:** %.3f\n\n", i+1, hit.Source, hit.Content, hit.Score)
+	}
+	return b.String()
+}
@@ -0,0 +1,434 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// packageTarget is one container package the fleet commands cover
+type packageTarget struct {
+	Org, Package string
+}
+
+// configuredTarget is the package of the org and package settings
+func configuredTarget() packageTarget {
+	return packageTarget{Org: config.Org, Package: config.Package}
+}
+
+func (t packageTarget) String() string {
+	return t.Org + "/" + t.Package
+}
+
+// path is the GitHub API path of the package
+func (t packageTarget) path() string {
+	return fmt.Sprintf("/orgs/%s/packages/container/%s", t.Org, t.Package)
+}
+
+// parseTargets reads a comma-separated list of org/package entries; a bare
+// package name belongs to the configured org. An empty list is the
+// configured package alone.
+func parseTargets(list string) ([]packageTarget, error) {
+	if strings.TrimSpace(list) == "" {
+		return []packageTarget{configuredTarget()}, nil
+	}
+	var targets []packageTarget
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		target := packageTarget{Org: config.Org, Package: entry}
+		if org, name, ok := strings.Cut(entry, "/"); ok {
+			target = packageTarget{Org: org, Package: name}
+		}
+		if target.Org == "" || target.Package == "" || strings.Contains(target.Package, "/") {
+			return nil, fmt.Errorf("%w: invalid target %q, expected org/package", errUsage, entry)
+		}
+		if !slices.Contains(targets, target) {
+			targets = append(targets, target)
+		}
+	}
+	return targets, nil
+}
+
+// fleetFlags adds the flags every fleet command shares and returns the
+// targets they select
+func fleetFlags(cmd *Command) (targets func() ([]packageTarget, error), parallel *int) {
+	list := cmd.Flags.String("targets", "", "comma-separated org/package entries (default: the targets setting, else the configured package)")
+	parallel = cmd.Flags.Int("parallel", 4, "packages processed at once; each also makes up to --concurrency registry requests")
+	return func() ([]packageTarget, error) {
+		if *list != "" {
+			return parseTargets(*list)
+		}
+		return parseTargets(config.Targets)
+	}, parallel
+}
+
+// forEachTarget runs fn for every target, at most parallel at once, and
+// returns the failures joined and prefixed with their target
+func forEachTarget(targets []packageTarget, parallel int, fn func(i int, target packageTarget) error) error {
+	return forEachConcurrent(targets, parallel, func(i int, target packageTarget) error {
+		if err := fn(i, target); err != nil {
+			return fmt.Errorf("%s: %w", target, err)
+		}
+		return nil
+	})
+}
+
+func newFleetCommand() *Command {
+	return newGroup("fleet", "List, audit and prune every package of the targets setting at once, with one consolidated report.",
+		newFleetVersionsCommand(),
+		newFleetAuditCommand(),
+		newFleetPruneCommand(),
+	)
+}
+
+// fleetListing summarizes the versions of one target
+type fleetListing struct {
+	Versions, Untagged int
+	Newest             *PackageVersion
+	Err                error
+}
+
+func newFleetVersionsCommand() *Command {
+	cmd := newCommand("versions", "", "Count the versions of every target and show its newest, fetching the targets concurrently.")
+	targets, parallel := fleetFlags(cmd)
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 0); err != nil {
+			return err
+		}
+		list, err := targets()
+		if err != nil {
+			return err
+		}
+		github, err := newGitHubClient()
+		if err != nil {
+			return err
+		}
+
+		listings := make([]fleetListing, len(list))
+		err = forEachTarget(list, *parallel, func(i int, target packageTarget) error {
+			listing := &listings[i]
+			listing.Err = eachTargetVersion(github, target, func(version PackageVersion) error {
+				listing.Versions++
+				if len(version.Metadata.Container.Tags) == 0 {
+					listing.Untagged++
+				}
+				if listing.Newest == nil || version.CreatedAt.After(listing.Newest.CreatedAt) {
+					listing.Newest = &version
+				}
+				return nil
+			})
+			return listing.Err
+		})
+
+		printf("\n📋 Versions of %d package(s)\n\n", len(list))
+		fmt.Printf("  %-40s %8s %9s  %s\n", message("PACKAGE"), message("VERSIONS"), message("UNTAGGED"), message("NEWEST"))
+		versions, untagged, listed := 0, 0, 0
+		for i, target := range list {
+			listing := listings[i]
+			if listing.Err != nil {
+				fmt.Printf("  %-40s ❌ %s\n", truncate(target.String(), 40), errorClass(listing.Err))
+				continue
+			}
+			newest := message("(none)")
+			if listing.Newest != nil {
+				newest = fmt.Sprintf("%s (%s)", message(listing.Newest.Tag()), listing.Newest.CreatedAt.Format(time.DateOnly))
+			}
+			fmt.Printf("  %-40s %8d %9d  %s\n", truncate(target.String(), 40), listing.Versions, listing.Untagged, newest)
+			versions += listing.Versions
+			untagged += listing.Untagged
+			listed++
+		}
+		printf("\nTotal: %d version(s), %d untagged, in %d of %d package(s)\n", versions, untagged, listed, len(list))
+		return err
+	}
+	return cmd
+}
+
+// fleetAudit is the policy check of one target
+type fleetAudit struct {
+	Package      orgPackage
+	Drift, Notes []string
+	Undeclared   bool
+}
+
+func newFleetAuditCommand() *Command {
+	cmd := newCommand("audit", "", "Check the visibility, linked repository and access of every target against the package policy, as `audit packages` does for one org.")
+	targets, parallel := fleetFlags(cmd)
+	policyPath := cmd.Flags.String("policy", "", "package policy, keyed by package name or org/package (default: "+defaultPackagePolicy+" in the repository)")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 0); err != nil {
+			return err
+		}
+		list, err := targets()
+		if err != nil {
+			return err
+		}
+		path, policies, err := loadPackagePolicy(*policyPath)
+		if err != nil {
+			return err
+		}
+		github, err := newGitHubClient()
+		if err != nil {
+			return err
+		}
+
+		// Targets linked to one repository share its access lookup
+		var mu sync.Mutex
+		accessByRepository := make(map[string]*repositoryAccess)
+		access := func(repository string) (*repositoryAccess, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			if access, ok := accessByRepository[repository]; ok {
+				return access, nil
+			}
+			access, err := fetchRepositoryAccess(github, repository)
+			if err == nil {
+				accessByRepository[repository] = access
+			}
+			return access, err
+		}
+
+		audits := make([]*fleetAudit, len(list))
+		err = forEachTarget(list, *parallel, func(i int, target packageTarget) error {
+			audit := &fleetAudit{}
+			if err := github.Get(target.path(), &audit.Package); err != nil {
+				return err
+			}
+			policy := policies[target.String()]
+			if policy == nil {
+				policy = policies[target.Package]
+			}
+			if policy == nil {
+				audit.Undeclared = true
+				audits[i] = audit
+				return nil
+			}
+			var err error
+			if audit.Drift, audit.Notes, err = checkPackagePolicy(audit.Package, policy, access); err != nil {
+				return err
+			}
+			audits[i] = audit
+			return nil
+		})
+
+		printf("\n🔏 Package policy audit of %d package(s) (%s)\n", len(list), path)
+		drifts, checked := 0, 0
+		var undeclared []string
+		for i, target := range list {
+			audit := audits[i]
+			switch {
+			case audit == nil:
+				printf("\n❌ %s could not be audited\n", target)
+			case audit.Undeclared:
+				undeclared = append(undeclared, target.String())
+			default:
+				printPackagePolicyResult(target.String(), audit.Package, audit.Drift, audit.Notes)
+				drifts += len(audit.Drift)
+				checked++
+			}
+		}
+		if len(undeclared) > 0 {
+			printf("\n⚠️  %d package(s) are not declared in the policy: %s\n", len(undeclared), strings.Join(undeclared, " "))
+		}
+		if len(accessByRepository) > 0 {
+			fmt.Println("ℹ️  Team and user access is read from the linked repository, which packages inherit with \"Inherit access from source repository\"")
+		}
+
+		if drifts > 0 {
+			err = errors.Join(err, fmt.Errorf("%w: %d setting(s) drifted from %s", errPolicy, drifts, path))
+		}
+		if err != nil {
+			return err
+		}
+		printf("\n✅ %d package(s) match the policy\n", checked)
+		return nil
+	}
+	return cmd
+}
+
+// fleetPrune is the retention plan of one target and what came of it
+type fleetPrune struct {
+	Versions int
+	Estimate *reclaimEstimate
+	// Deleted are the versions the plan removes, or removed unless dry run
+	Deleted []retentionDecision
+	Failed  int
+}
+
+func newFleetPruneCommand() *Command {
+	cmd := newCommand("prune", "", "Delete the versions the retention policy does not keep from every target, keeping the platform images of kept manifest lists.")
+	targets, parallel := fleetFlags(cmd)
+	retention := retentionFlags(cmd)
+	dryRun := cmd.Flags.Bool("dry-run", false, "report what would be deleted without deleting")
+	yes := cmd.Flags.Bool("yes", false, "do not ask for confirmation")
+	notify := cmd.Flags.Bool("notify-webhook", false, "post each pruned target to webhooks.notify and the alerts sinks")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 0); err != nil {
+			return err
+		}
+		// Fail before crawling every target rather than at the question
+		if !*dryRun && !*yes && !isTerminal(os.Stdin) {
+			return fmt.Errorf("%w: stdin is not a terminal, pass --yes to confirm the deletions or --dry-run to preview them", errUsage)
+		}
+		list, err := targets()
+		if err != nil {
+			return err
+		}
+		notifier, err := newNotifier(*notify)
+		if err != nil {
+			return err
+		}
+		policy := retention()
+		github, err := newGitHubClient()
+		if err != nil {
+			return err
+		}
+
+		prunes := make([]*fleetPrune, len(list))
+		planErr := forEachTarget(list, *parallel, func(i int, target packageTarget) error {
+			var versions []PackageVersion
+			err := eachTargetVersion(github, target, func(version PackageVersion) error {
+				versions = append(versions, version)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			// Deleting a platform image breaks the kept manifest list
+			// pointing to it, so the plan needs every version's manifest
+			blobs, err := resolveAllVersionBlobs(newRegistryClient(config.Registry, target.String()), versions)
+			if err != nil {
+				return err
+			}
+			prune := &fleetPrune{Versions: len(versions), Estimate: estimateReclaim(applyRetention(versions, policy, time.Now()), blobs)}
+			for _, decision := range prune.Estimate.Decisions {
+				if !decision.Keep {
+					prune.Deleted = append(prune.Deleted, decision)
+				}
+			}
+			prunes[i] = prune
+			return nil
+		})
+
+		printf("\n🧹 Retention plan for %d package(s): %s\n", len(list), describeRetention(policy))
+		total, planned := 0, 0
+		for i, target := range list {
+			if prunes[i] == nil {
+				continue
+			}
+			total += prunes[i].Versions
+			planned += len(prunes[i].Deleted)
+			if len(prunes[i].Deleted) == 0 {
+				continue
+			}
+			fmt.Printf("\n%s:\n", target)
+			for _, decision := range prunes[i].Deleted {
+				fmt.Printf("  🗑️  %-24s %s  %s (ID: %d)\n", truncate(message(decision.Version.Tag()), 24), decision.Version.CreatedAt.Format(time.DateOnly),
+					decision.Reason, decision.Version.ID)
+			}
+		}
+		if *dryRun {
+			printFleetPrunes(list, prunes)
+			var freed int64
+			for _, prune := range prunes {
+				if prune != nil {
+					freed += prune.Estimate.Freed
+				}
+			}
+			printf("\nWould delete %d of %d version(s) and free %s (dry run)\n", planned, total, formatBytes(freed))
+			return planErr
+		}
+		if planned == 0 {
+			printFleetPrunes(list, prunes)
+			printf("\n✅ Nothing to delete\n")
+			return planErr
+		}
+
+		// The question lists what each target loses first
+		fmt.Println()
+		for i, target := range list {
+			if prunes[i] != nil && len(prunes[i].Deleted) > 0 {
+				printf("  %-40s %d of %d version(s), %s\n", truncate(target.String(), 40), len(prunes[i].Deleted), prunes[i].Versions,
+					formatBytes(prunes[i].Estimate.Freed))
+			}
+		}
+		ok, err := confirm(fmt.Sprintf(message("Delete %d version(s) from %d package(s)?"), planned, len(list)), *yes)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.Join(planErr, errors.New("prune cancelled"))
+		}
+
+		var auditMu sync.Mutex
+		err = forEachTarget(list, *parallel, func(i int, target packageTarget) error {
+			prune := prunes[i]
+			if prune == nil || len(prune.Deleted) == 0 {
+				return nil
+			}
+			var errs []error
+			for _, decision := range prune.Deleted {
+				version := decision.Version
+				if err := github.Delete(fmt.Sprintf("%s/versions/%d", target.path(), version.ID)); err != nil {
+					errs = append(errs, fmt.Errorf("failed to delete version %d: %w", version.ID, err))
+					prune.Failed++
+					continue
+				}
+				auditMu.Lock()
+				err := recordAudit(AuditEntry{Action: auditDelete, Tag: strings.Join(version.Metadata.Container.Tags, ", "),
+					Digest: version.Name, Target: fmt.Sprintf("%s version %d", target, version.ID)})
+				auditMu.Unlock()
+				if err != nil {
+					errs = append(errs, err)
+				}
+			}
+			key, deleted := "prune "+target.String(), len(prune.Deleted)-prune.Failed
+			if prune.Failed > 0 {
+				notifier.alert(severityWarning, key, "Fleet prune incomplete", fmt.Sprintf("Deleted %d of %d version(s) of `%s`; %d deletion(s) failed (%s)",
+					deleted, len(prune.Deleted), target, prune.Failed, describeRetention(policy)))
+			} else {
+				notifier.alert(severityInfo, key, "Fleet prune", fmt.Sprintf("Deleted %d version(s) of `%s`, freeing about %s (%s)",
+					deleted, target, formatBytes(prune.Estimate.Freed), describeRetention(policy)))
+			}
+			return errors.Join(errs...)
+		})
+
+		printFleetPrunes(list, prunes)
+		deleted := 0
+		var freed int64
+		for _, prune := range prunes {
+			if prune != nil {
+				deleted += len(prune.Deleted) - prune.Failed
+				freed += prune.Estimate.Freed
+			}
+		}
+		printf("\n✅ Deleted %d of %d version(s), freeing about %s\n", deleted, total, formatBytes(freed))
+		return errors.Join(planErr, err)
+	}
+	return cmd
+}
+
+// printFleetPrunes prints the table of versions deleted, or to be deleted,
+// per target
+func printFleetPrunes(list []packageTarget, prunes []*fleetPrune) {
+	fmt.Printf("\n  %-40s %8s %8s  %s\n", message("PACKAGE"), message("VERSIONS"), message("DELETED"), message("FREED"))
+	for i, target := range list {
+		prune := prunes[i]
+		switch {
+		case prune == nil:
+			printf("  %-40s ❌ not pruned\n", truncate(target.String(), 40))
+		case prune.Failed > 0:
+			printf("  %-40s %8d %8d  %s, %d failed\n", truncate(target.String(), 40), prune.Versions, len(prune.Deleted)-prune.Failed,
+				formatBytes(prune.Estimate.Freed), prune.Failed)
+		default:
+			fmt.Printf("  %-40s %8d %8d  %s\n", truncate(target.String(), 40), prune.Versions, len(prune.Deleted), formatBytes(prune.Estimate.Freed))
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func formatBytes(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%s %ciB", decimal(float64(bytes)/float64(div), 1), "KMGTPE"[exp])
+}
+
+// shortDigest abbreviates a sha256 digest for table output
+func shortDigest(digest string) string {
+	const prefix = len("sha256:")
+	if len(digest) > prefix+12 {
+		return digest[:prefix+12]
+	}
+	return digest
+}
+
+// formatSizeChange renders the signed difference between two sizes
+func formatSizeChange(before, after int64) string {
+	delta := after - before
+	sign := "+"
+	if delta < 0 {
+		sign = "-"
+		delta = -delta
+	}
+	if before == 0 {
+		return sign + formatBytes(delta)
+	}
+	return fmt.Sprintf("%s%s, %s%.1f%%", sign, formatBytes(delta), sign, float64(delta)/float64(before)*100)
+}
+
+// parseAge parses a duration that may also use d (days) and w (weeks),
+// such as 30d or 2w
+func parseAge(value string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if number, ok := strings.CutSuffix(value, suffix); ok {
+			n, err := strconv.Atoi(number)
+			if err != nil || n < 0 {
+				return 0, fmt.Errorf("invalid age %q", value)
+			}
+			return time.Duration(n) * unit, nil
+		}
+	}
+	age, err := time.ParseDuration(value)
+	if err != nil || age < 0 {
+		return 0, fmt.Errorf("invalid age %q", value)
+	}
+	return age, nil
+}
+
+// formatAge renders a duration in the units parseAge accepts, such as 5d
+// or 3h
+func formatAge(age time.Duration) string {
+	switch {
+	case age >= 24*time.Hour:
+		return fmt.Sprintf("%dd", int(age/(24*time.Hour)))
+	case age >= time.Hour:
+		return fmt.Sprintf("%dh", int(age/time.Hour))
+	default:
+		return fmt.Sprintf("%dm", int(age/time.Minute))
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// gateToolCalls are the fixed tool calls a release candidate must answer
+var gateToolCalls = []toolCall{
+	{smokeTool, map[string]any{"query": "Vitamin D", "limit": 3}},
+	{smokeTool, map[string]any{"query": "Magnesium Mangel", "limit": 3}},
+	{smokeTool, map[string]any{"query": "Omega-3 Dosierung", "limit": 3}},
+}
+
+func newReleaseGateCommand() *Command {
+	cmd := newCommand("gate", "<tag>", "Run a candidate image and block promotion unless the MCP handshake and tool calls pass.")
+	serverURL := cmd.Flags.String("url", "", "test a running staging server instead of starting the image locally")
+	port := cmd.Flags.Int("port", 8000, "local port for the candidate container")
+	platform := cmd.Flags.String("platform", defaultPlatform, "platform of the image to run")
+	startup := cmd.Flags.Duration("startup-timeout", 5*time.Minute, "how long the container may take to become healthy")
+	junit := cmd.Flags.String("junit", "", "write a JUnit XML report to this file")
+	skipSecrets := cmd.Flags.Bool("skip-secret-scan", false, "do not scan the image layers for secrets")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 1); err != nil {
+			return err
+		}
+		tag := args[0]
+		registry := newRegistryClient(config.Registry, imageRepository())
+		reference := registry.Reference(tag)
+
+		// A leaked credential blocks the release however well the image runs
+		var checks []smokeCheck
+		if !*skipSecrets {
+			fmt.Printf("\n🔐 Scanning %s (%s) for secrets\n", reference, *platform)
+			start := time.Now()
+			check := smokeCheck{Name: "no secrets in image layers"}
+			result, err := scanImageSecrets(registry, tag, *platform, defaultSecretScanOptions())
+			if err == nil {
+				printSecretFindings(result)
+				if len(result.Findings) > 0 {
+					err = fmt.Errorf("%d likely secret(s), see `strunzctl image scan-secrets %s`", len(result.Findings), tag)
+				}
+			}
+			check.Err, check.Duration = err, time.Since(start)
+			checks = append(checks, check)
+		}
+
+		url := *serverURL
+		if url == "" {
+			container, err := startCandidate(reference, *platform, *port)
+			if err != nil {
+				return err
+			}
+			defer stopCandidate(container)
+			url = fmt.Sprintf("http://127.0.0.1:%d", *port)
+
+			fmt.Printf("\n🐳 Started %s as %s, waiting for %s/health\n", reference, container[:min(12, len(container))], url)
+			if err := waitHealthy(url, *startup); err != nil {
+				printContainerLogs(container)
+				return err
+			}
+		}
+
+		version := ""
+		if _, ok := parseSemver(tag); ok {
+			version = tag
+		}
+		fmt.Printf("\n🚦 Release gate for %s at %s\n", reference, url)
+		checks = append(checks, runSmokeTests(url, version, gateToolCalls)...)
+		failed := printSmokeChecks(checks)
+
+		if *junit != "" {
+			if err := writeJUnitReport(*junit, "release gate "+tag, checks); err != nil {
+				return err
+			}
+			fmt.Printf("\nJUnit report written to %s\n", *junit)
+		}
+		if failed > 0 {
+			return fmt.Errorf("%w: %s failed %d of %d gate check(s), do not promote", errPolicy, tag, failed, len(checks))
+		}
+		fmt.Printf("\n✅ %s passed the gate\n", tag)
+		return nil
+	}
+	return cmd
+}
+
+// startCandidate runs the image detached with the SSE transport and returns
+// the container ID
+func startCandidate(reference, platform string, port int) (string, error) {
+	cmd := newProcess("docker", "run", "--detach", "--rm", "--platform", platform,
+		"--publish", fmt.Sprintf("127.0.0.1:%d:8000", port),
+		"--env", "PORT=8000", "--env", "TRANSPORT=sse", reference)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to start %s: %w: %s", reference, err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+func stopCandidate(container string) {
+	exec.Command("docker", "rm", "--force", container).Run()
+}
+
+func printContainerLogs(container string) {
+	output, _ := newProcess("docker", "logs", "--tail", "50", container).CombinedOutput()
+	fmt.Printf("\nLast container log lines:\n%s\n", output)
+}
+
+// waitHealthy polls /health until it answers or the timeout expires
+func waitHealthy(url string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		_, err := fetchHealth(url)
+		if err == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("server did not become healthy within %s: %w", timeout, err)
+		}
+		if err := sleepContext(2 * time.Second); err != nil {
+			return err
+		}
+	}
+}
+
+type junitTestSuite struct {
+	XMLName  xml.Name        `xml:"testsuite"`
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name    string        `xml:"name,attr"`
+	Time    string        `xml:"time,attr"`
+	Failure *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+}
+
+// writeJUnitReport writes checks as a JUnit test suite for CI test reporters
+func writeJUnitReport(path, name string, checks []smokeCheck) error {
+	suite := junitTestSuite{Name: name, Tests: len(checks)}
+	var total time.Duration
+	for _, check := range checks {
+		testCase := junitTestCase{Name: check.Name, Time: junitSeconds(check.Duration)}
+		if check.Err != nil {
+			suite.Failures++
+			testCase.Failure = &junitFailure{Message: check.Err.Error()}
+		}
+		total += check.Duration
+		suite.Cases = append(suite.Cases, testCase)
+	}
+	suite.Time = junitSeconds(total)
+
+	data, err := xml.MarshalIndent(suite, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode JUnit report: %w", err)
+	}
+	if err := os.WriteFile(path, append([]byte(xml.Header), append(data, '\n')...), 0o644); err != nil {
+		return fmt.Errorf("failed to write JUnit report: %w", err)
+	}
+	return nil
+}
+
+func junitSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// docPage is one generated reference file
+type docPage struct {
+	name    string
+	content []byte
+}
+
+// visibleCommands lists the command tree depth first, without hidden commands
+func visibleCommands(root *Command) []*Command {
+	commands := []*Command{root}
+	for _, sub := range root.Subcommands {
+		if !sub.Hidden {
+			commands = append(commands, visibleCommands(sub)...)
+		}
+	}
+	return commands
+}
+
+// docFlag is a flag as the reference shows it
+type docFlag struct {
+	name, value, usage, defaultValue string
+}
+
+// commandFlags lists the flags of a command. Paths under the home
+// directory, like the config file default, are shown relative to ~ so the
+// output does not depend on who generates it.
+func commandFlags(fs *flag.FlagSet) []docFlag {
+	home, _ := os.UserHomeDir()
+	var flags []docFlag
+	fs.VisitAll(func(f *flag.Flag) {
+		value, usage := flag.UnquoteUsage(f)
+		if home != "" {
+			usage = strings.ReplaceAll(usage, home, "~")
+		}
+		entry := docFlag{name: f.Name, value: value, usage: usage}
+		switch f.DefValue {
+		case "", "false", "0", "[]":
+		default:
+			entry.defaultValue = f.DefValue
+		}
+		flags = append(flags, entry)
+	})
+	return flags
+}
+
+// inheritedFlags are the flags of the groups above a command, which must
+// precede its name
+func inheritedFlags(cmd *Command) []docFlag {
+	var flags []docFlag
+	for parent := cmd.parent; parent != nil; parent = parent.parent {
+		flags = append(commandFlags(parent.Flags), flags...)
+	}
+	return flags
+}
+
+// docFileName is the page name of a command, e.g. strunzctl_image_manifest
+func docFileName(cmd *Command) string {
+	return strings.ReplaceAll(cmd.Path(), " ", "_")
+}
+
+// roffEscape keeps text from being read as roff requests or escapes
+func roffEscape(text string) string {
+	text = strings.NewReplacer(`\`, `\e`, "-", `\-`).Replace(text)
+	if strings.HasPrefix(text, ".") || strings.HasPrefix(text, "'") {
+		text = `\&` + text
+	}
+	return text
+}
+
+// manPage renders a command as a section 1 man page
+func manPage(cmd *Command, version string) []byte {
+	var b bytes.Buffer
+	name := strings.ReplaceAll(cmd.Path(), " ", "-")
+	fmt.Fprintf(&b, ".TH %q 1 \"\" %q \"strunzctl Manual\"\n", strings.ToUpper(name), "strunzctl "+version)
+	fmt.Fprintf(&b, ".SH NAME\n%s \\- %s\n", roffEscape(name), roffEscape(cmd.Summary))
+	fmt.Fprintf(&b, ".SH SYNOPSIS\n.B %s\n", roffEscape(cmd.Path()))
+	if cmd.Args != "" {
+		fmt.Fprintf(&b, "%s\n", roffEscape(cmd.Args))
+	}
+	b.WriteString("[flags]\n")
+	fmt.Fprintf(&b, ".SH DESCRIPTION\n%s\n", roffEscape(cmd.Summary))
+
+	var subs []*Command
+	for _, sub := range cmd.Subcommands {
+		if !sub.Hidden {
+			subs = append(subs, sub)
+		}
+	}
+	if len(subs) > 0 {
+		b.WriteString(".SH COMMANDS\n")
+		for _, sub := range subs {
+			fmt.Fprintf(&b, ".TP\n.B %s\n%s\n", roffEscape(sub.Name), roffEscape(sub.Summary))
+		}
+	}
+	writeFlags := func(title string, flags []docFlag) {
+		if len(flags) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, ".SH %s\n", title)
+		for _, f := range flags {
+			fmt.Fprintf(&b, ".TP\n\\fB\\-\\-%s\\fR", roffEscape(f.name))
+			if f.value != "" {
+				fmt.Fprintf(&b, " \\fI%s\\fR", roffEscape(f.value))
+			}
+			fmt.Fprintf(&b, "\n%s", roffEscape(f.usage))
+			if f.defaultValue != "" {
+				fmt.Fprintf(&b, " (default: %s)", roffEscape(f.defaultValue))
+			}
+			b.WriteString("\n")
+		}
+	}
+	writeFlags("OPTIONS", commandFlags(cmd.Flags))
+	writeFlags("INHERITED OPTIONS", inheritedFlags(cmd))
+
+	var related []string
+	if cmd.parent != nil {
+		related = append(related, fmt.Sprintf(".BR %s (1)", strings.ReplaceAll(cmd.parent.Path(), " ", "-")))
+	}
+	for _, sub := range subs {
+		related = append(related, fmt.Sprintf(".BR %s (1)", strings.ReplaceAll(sub.Path(), " ", "-")))
+	}
+	if len(related) > 0 {
+		fmt.Fprintf(&b, ".SH SEE ALSO\n%s\n", strings.Join(related, "\n"))
+	}
+	return b.Bytes()
+}
+
+// markdownEscapeCell keeps a value from breaking a table row
+func markdownEscapeCell(text string) string {
+	return strings.ReplaceAll(text, "|", `\|`)
+}
+
+// markdownReference renders a command as a Markdown page linking its
+// parent and subcommands
+func markdownReference(cmd *Command) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# %s\n\n%s\n\n", cmd.Path(), cmd.Summary)
+	usage := cmd.Path()
+	if cmd.Args != "" {
+		usage += " " + cmd.Args
+	}
+	fmt.Fprintf(&b, "```\n%s [flags]\n```\n", usage)
+
+	var subs []*Command
+	for _, sub := range cmd.Subcommands {
+		if !sub.Hidden {
+			subs = append(subs, sub)
+		}
+	}
+	if len(subs) > 0 {
+		b.WriteString("\n## Commands\n\n| Command | Description |\n|---|---|\n")
+		for _, sub := range subs {
+			fmt.Fprintf(&b, "| [%s](%s.md) | %s |\n", sub.Name, docFileName(sub), markdownEscapeCell(sub.Summary))
+		}
+	}
+	writeFlags := func(title string, flags []docFlag) {
+		if len(flags) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "\n## %s\n\n| Flag | Default | Description |\n|---|---|---|\n", title)
+		for _, f := range flags {
+			name := "--" + f.name
+			if f.value != "" {
+				name += " " + f.value
+			}
+			defaultValue := ""
+			if f.defaultValue != "" {
+				defaultValue = "`" + markdownEscapeCell(f.defaultValue) + "`"
+			}
+			fmt.Fprintf(&b, "| `%s` | %s | %s |\n", name, defaultValue, markdownEscapeCell(f.usage))
+		}
+	}
+	writeFlags("Flags", commandFlags(cmd.Flags))
+	writeFlags("Inherited flags", inheritedFlags(cmd))
+
+	if cmd.parent != nil {
+		fmt.Fprintf(&b, "\nSee also [%s](%s.md).\n", cmd.parent.Path(), docFileName(cmd.parent))
+	}
+	return b.Bytes()
+}
+
+// writeDocPages writes the pages to dir, or with check reports the pages
+// that are missing or differ from what is on disk
+func writeDocPages(dir string, pages []docPage, check bool) ([]string, error) {
+	if !check {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+	var stale []string
+	for _, page := range pages {
+		path := filepath.Join(dir, page.name)
+		if check {
+			if current, err := os.ReadFile(path); err != nil || !bytes.Equal(current, page.content) {
+				stale = append(stale, path)
+			}
+			continue
+		}
+		if err := os.WriteFile(path, page.content, 0o644); err != nil {
+			return nil, err
+		}
+	}
+	return stale, nil
+}
+
+func newDocsCommand(root *Command) *Command {
+	generate := newCommand("generate", "", "Generate man pages and a Markdown reference from the command tree, so the CLI reference always matches the flags.")
+	man := generate.Flags.String("man", "", "directory for the man pages (section 1)")
+	markdown := generate.Flags.String("markdown", "", "directory for the Markdown reference, one file per command")
+	check := generate.Flags.Bool("check", false, "write nothing; fail when a file in the directories is missing or out of date")
+
+	generate.Run = func(args []string) error {
+		if err := generate.ExactArgs(args, 0); err != nil {
+			return err
+		}
+		if *man == "" && *markdown == "" {
+			return fmt.Errorf("%w: pass --man, --markdown or both", errUsage)
+		}
+		commands := visibleCommands(root)
+		version := currentBuild().Version
+
+		var stale []string
+		for _, target := range []struct {
+			dir    string
+			ext    string
+			render func(*Command) []byte
+		}{
+			{*man, ".1", func(cmd *Command) []byte { return manPage(cmd, version) }},
+			{*markdown, ".md", markdownReference},
+		} {
+			if target.dir == "" {
+				continue
+			}
+			pages := make([]docPage, len(commands))
+			for i, cmd := range commands {
+				name := docFileName(cmd)
+				if target.ext == ".1" {
+					name = strings.ReplaceAll(cmd.Path(), " ", "-")
+				}
+				pages[i] = docPage{name: name + target.ext, content: target.render(cmd)}
+			}
+			found, err := writeDocPages(target.dir, pages, *check)
+			if err != nil {
+				return err
+			}
+			stale = append(stale, found...)
+			if !*check {
+				fmt.Printf("✅ Wrote %d page(s) to %s\n", len(pages), target.dir)
+			}
+		}
+		if *check {
+			for _, path := range stale {
+				fmt.Printf("❌ %s is missing or out of date\n", path)
+			}
+			if len(stale) > 0 {
+				return fmt.Errorf("%w: %d reference file(s) out of date, run `strunzctl docs generate`", errPolicy, len(stale))
+			}
+			fmt.Printf("✅ Reference of %d command(s) is up to date\n", len(commands))
+		}
+		return nil
+	}
+	return newGroup("docs", "Generate the CLI reference.", generate)
+}
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ghExtensionName is the executable gh runs for `gh strunz`; the
+// entrypoint in src/scripts/gh-strunz starts strunzctl under this name
+const ghExtensionName = "gh-strunz"
+
+// runningAsGHExtension reports whether strunzctl was started by gh as an
+// extension. It then authenticates the way gh does and names itself
+// `gh strunz` in usage.
+func runningAsGHExtension() bool {
+	return strings.TrimSuffix(filepath.Base(os.Args[0]), ".exe") == ghExtensionName
+}
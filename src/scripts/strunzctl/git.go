@@ -0,0 +1,33 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// runGit runs git in the current repository and returns trimmed stdout
+func runGit(args ...string) (string, error) {
+	cmd := newProcess("git", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		// Name the subcommand rather than -C in the message
+		if len(args) > 2 && args[0] == "-C" {
+			args = args[2:]
+		}
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", fmt.Errorf("git %s failed: %s", args[0], message)
+		}
+		return "", fmt.Errorf("git %s failed: %w", args[0], err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// gitTagExists reports whether a tag exists in the local repository
+func gitTagExists(tag string) bool {
+	_, err := runGit("rev-parse", "--verify", "--quiet", "refs/tags/"+tag)
+	return err == nil
+}
@@ -0,0 +1,391 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// githubAPI is the base URL of the GitHub REST API
+const githubAPI = "https://api.github.com"
+
+// githubAPIURL is the configured API base URL, github.com's by default
+func githubAPIURL() string {
+	if config.GitHubAPI != "" {
+		return strings.TrimRight(config.GitHubAPI, "/")
+	}
+	return githubAPI
+}
+
+// linkNext extracts the next page URL from a Link header
+var linkNext = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+// GitHubError is returned for non-successful GitHub API responses
+type GitHubError struct {
+	StatusCode int
+	Status     string
+	Path       string
+	Message    string
+}
+
+func (e *GitHubError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("GitHub API returned %s for %s: %s", e.Status, e.Path, e.Message)
+	}
+	return fmt.Sprintf("GitHub API returned %s for %s", e.Status, e.Path)
+}
+
+// GitHubClient is a minimal GitHub REST API client
+type GitHubClient struct {
+	baseURL string
+	// token is the token the client started with. Installation tokens of
+	// a GitHub App expire and are refreshed per request when app is set.
+	token      string
+	app        bool
+	httpClient *http.Client
+}
+
+func newGitHubClient() (*GitHubClient, error) {
+	token, err := githubToken()
+	if err != nil && config.Token == "" && githubAppConfigured() {
+		return nil, fmt.Errorf("%w: failed to authenticate as GitHub App %s: %w", errAuth, config.GitHubApp.ID, err)
+	}
+	if err != nil && os.Getenv("GITHUB_ACTIONS") == "true" {
+		// The job token is short-lived and needs no stored secret
+		return nil, fmt.Errorf("%w: no GitHub token available, pass the job's token with `env: GITHUB_TOKEN: ${{ github.token }}`: %w", errAuth, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: no GitHub token available (set GITHUB_TOKEN or run `strunzctl login github` or `gh auth login`): %w", errAuth, err)
+	}
+	return &GitHubClient{
+		baseURL:    githubAPIURL(),
+		token:      token,
+		app:        config.Token == "" && githubAppConfigured(),
+		httpClient: &http.Client{Timeout: 60 * time.Second},
+	}, nil
+}
+
+// Get fetches a single API resource into v
+func (g *GitHubClient) Get(path string, v any) error {
+	body, _, err := g.fetch(g.url(path))
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return nil
+}
+
+// errStopPages ends GetPages early without an error, e.g. once a limit
+// is reached
+var errStopPages = errors.New("stop pagination")
+
+// GetPages follows Link pagination, calling page with each raw JSON page.
+// When page returns errStopPages no further pages are fetched.
+func (g *GitHubClient) GetPages(path string, page func(body []byte) error) error {
+	return g.paginate(g.firstPage(path), func(body io.Reader, _ string) error {
+		data, err := io.ReadAll(body)
+		if err != nil {
+			return fmt.Errorf("failed to read GitHub API response: %w", err)
+		}
+		return page(data)
+	})
+}
+
+// firstPage is the URL of the first page of path, 100 items per page
+// unless path asks otherwise
+func (g *GitHubClient) firstPage(path string) string {
+	next := g.url(path)
+	if !strings.Contains(next, "per_page=") {
+		next += separator(next) + "per_page=100"
+	}
+	return next
+}
+
+// paginate follows Link pagination from the URL next, calling page with
+// the body of each page as it arrives and the URL of the following page,
+// empty on the last. When page returns errStopPages no further pages are
+// fetched.
+func (g *GitHubClient) paginate(next string, page func(body io.Reader, next string) error) error {
+	for next != "" {
+		body, header, err := g.open(next)
+		if err != nil {
+			return err
+		}
+		next = ""
+		if match := linkNext.FindStringSubmatch(header.Get("Link")); match != nil {
+			next = match[1]
+		}
+		err = page(body, next)
+		if err == nil {
+			// The rest of the page completes its cache entry
+			_, err = io.Copy(io.Discard, body)
+		}
+		body.Close()
+		if errors.Is(err, errStopPages) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeArray decodes a JSON array of what one item at a time from r, so a
+// page is never held decoded as a whole. It stops with fn's error.
+func decodeArray[T any](r io.Reader, what string, fn func(T) error) error {
+	decoder := json.NewDecoder(r)
+	if token, err := decoder.Token(); err != nil || token != json.Delim('[') {
+		return fmt.Errorf("failed to parse %s: expected an array", what)
+	}
+	for decoder.More() {
+		var item T
+		if err := decoder.Decode(&item); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", what, err)
+		}
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+	if _, err := decoder.Token(); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", what, err)
+	}
+	return nil
+}
+
+// fetch GETs a URL, see open
+func (g *GitHubClient) fetch(target string) ([]byte, http.Header, error) {
+	body, header, err := g.open(target)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read GitHub API response: %w", err)
+	}
+	return data, header, nil
+}
+
+// open GETs a URL and returns the body as it arrives. Responses are cached
+// with their ETag and revalidated with If-None-Match; GitHub does not count
+// 304 responses against the quota. Responses without an ETag are cached
+// too, as snapshots for --offline. A response is cached once its body has
+// been read to the end.
+func (g *GitHubClient) open(target string) (io.ReadCloser, http.Header, error) {
+	cached := loadCachedResponse(target, g.token)
+	if cached != nil && globalOptions.offline {
+		return io.NopCloser(bytes.NewReader(cached.Body)), cached.HTTPHeader(), nil
+	}
+
+	resp, err := doWithRetry(g.httpClient, func() (*http.Request, error) {
+		req, err := g.newRequest(http.MethodGet, target)
+		if err != nil {
+			return nil, err
+		}
+		if cached != nil && cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		return req, nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("GitHub API request failed: %w", err)
+	}
+
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		resp.Body.Close()
+		return io.NopCloser(bytes.NewReader(cached.Body)), cached.HTTPHeader(), nil
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read GitHub API response: %w", err)
+		}
+		return nil, nil, newGitHubError(resp, body)
+	}
+	if globalOptions.noCache {
+		return resp.Body, resp.Header, nil
+	}
+	return &cachingBody{ReadCloser: resp.Body, store: func(body []byte) {
+		storeCachedResponse(target, g.token, resp.Header, body)
+	}}, resp.Header, nil
+}
+
+// cachingBody keeps a copy of a response body as it is read and stores it
+// when the end is reached
+type cachingBody struct {
+	io.ReadCloser
+	copy  bytes.Buffer
+	store func(body []byte)
+}
+
+func (b *cachingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.copy.Write(p[:n])
+	if err == io.EOF && b.store != nil {
+		b.store(b.copy.Bytes())
+		b.store = nil
+	}
+	return n, err
+}
+
+// GetText fetches a resource that is not JSON, such as a job log, without
+// caching it
+func (g *GitHubClient) GetText(path string) ([]byte, error) {
+	resp, err := doWithRetry(g.httpClient, func() (*http.Request, error) {
+		return g.newRequest(http.MethodGet, g.url(path))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("GitHub API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read GitHub API response: %w", err)
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, newGitHubError(resp, body)
+	}
+	return body, nil
+}
+
+// Delete sends a DELETE request for an API resource
+func (g *GitHubClient) Delete(path string) error {
+	return g.Send(http.MethodDelete, path, nil, nil)
+}
+
+// Send performs a write request with an optional JSON body, decoding the
+// response into v unless v is nil
+func (g *GitHubClient) Send(method, path string, body, v any) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return fmt.Errorf("failed to encode request for %s: %w", path, err)
+		}
+	}
+
+	resp, err := doWithRetry(g.httpClient, func() (*http.Request, error) {
+		req, err := g.newRequest(method, g.url(path))
+		if err != nil {
+			return nil, err
+		}
+		if payload != nil {
+			req.Body = io.NopCloser(bytes.NewReader(payload))
+			req.ContentLength = int64(len(payload))
+			req.Header.Set("Content-Type", "application/json")
+		}
+		return req, nil
+	})
+	if err != nil {
+		return fmt.Errorf("GitHub API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read GitHub API response: %w", err)
+	}
+	if resp.StatusCode/100 != 2 {
+		return newGitHubError(resp, data)
+	}
+	if v != nil {
+		if err := json.Unmarshal(data, v); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// UploadAsset attaches a file to a release. Uploads go to the host of the
+// release's upload_url and can take longer than the API timeout allows.
+func (g *GitHubClient) UploadAsset(release *GitHubRelease, name, path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	base, _, _ := strings.Cut(release.UploadURL, "{")
+	target := base + "?name=" + url.QueryEscape(name)
+	client := &http.Client{Timeout: 30 * time.Minute}
+
+	resp, err := doWithRetry(client, func() (*http.Request, error) {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		req, err := g.newRequest(http.MethodPost, target)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		req.Body = file
+		req.ContentLength = info.Size()
+		req.Header.Set("Content-Type", "application/octet-stream")
+		return req, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload %s: %w", name, err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return newGitHubError(resp, data)
+	}
+	return nil
+}
+
+func (g *GitHubClient) newRequest(method, target string) (*http.Request, error) {
+	req, err := http.NewRequest(method, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	token, err := g.currentToken()
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return req, nil
+}
+
+// currentToken is the token to send now, refreshing app installation
+// tokens before they expire
+func (g *GitHubClient) currentToken() (string, error) {
+	if !g.app {
+		return g.token, nil
+	}
+	return appTokens.Token()
+}
+
+func newGitHubError(resp *http.Response, body []byte) *GitHubError {
+	var apiErr struct {
+		Message string `json:"message"`
+	}
+	json.Unmarshal(body, &apiErr)
+	return &GitHubError{StatusCode: resp.StatusCode, Status: resp.Status, Path: resp.Request.URL.Path, Message: apiErr.Message}
+}
+
+func (g *GitHubClient) url(path string) string {
+	if strings.HasPrefix(path, "https://") {
+		return path
+	}
+	return g.baseURL + path
+}
+
+func separator(url string) string {
+	if strings.Contains(url, "?") {
+		return "&"
+	}
+	return "?"
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// appTokenRefresh is how long before expiry an installation token is
+// replaced; GitHub issues them for an hour
+const appTokenRefresh = 5 * time.Minute
+
+// githubAppTokens mints installation tokens for config.GitHubApp and
+// reuses them until shortly before they expire, so long-running monitors
+// keep working past the hour
+type githubAppTokens struct {
+	mu           sync.Mutex
+	installation string
+	token        string
+	expires      time.Time
+}
+
+var appTokens githubAppTokens
+
+// githubAppConfigured reports whether the tool authenticates as an app
+func githubAppConfigured() bool {
+	return config.GitHubApp.ID != "" && config.GitHubApp.PrivateKey != ""
+}
+
+// Token returns a valid installation token, minting one when needed
+func (t *githubAppTokens) Token() (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.token != "" && time.Until(t.expires) > appTokenRefresh {
+		return t.token, nil
+	}
+	if t.installation == "" {
+		t.installation = config.GitHubApp.Installation
+	}
+	token, expires, err := mintInstallationToken(config.GitHubApp, &t.installation)
+	if err != nil {
+		return "", err
+	}
+	slog.Debug("Minted GitHub App installation token", "app", config.GitHubApp.ID, "expires", expires)
+	t.token, t.expires = token, expires
+	return token, nil
+}
+
+// loadAppKey reads the app's private key, given inline or as a file path
+func loadAppKey(value string) (*rsa.PrivateKey, error) {
+	data := []byte(value)
+	if !strings.HasPrefix(strings.TrimSpace(value), "-----BEGIN") {
+		var err error
+		if data, err = os.ReadFile(value); err != nil {
+			return nil, fmt.Errorf("failed to read GitHub App private key: %w", err)
+		}
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("GitHub App private key is not PEM encoded")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse GitHub App private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("GitHub App private key is not an RSA key")
+	}
+	return key, nil
+}
+
+// appJWT signs the short-lived RS256 token that authenticates as the app
+// itself. It is backdated a minute to allow for clock drift.
+func appJWT(id string, key *rsa.PrivateKey) (string, error) {
+	now := time.Now()
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	claims, _ := json.Marshal(map[string]any{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": id,
+	})
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign GitHub App token: %w", err)
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// mintInstallationToken exchanges an app JWT for an installation token.
+// Without an installation ID the app's installation on the repository is
+// looked up and stored in installation.
+func mintInstallationToken(app GitHubAppConfig, installation *string) (string, time.Time, error) {
+	key, err := loadAppKey(app.PrivateKey)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	jwt, err := appJWT(app.ID, key)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+
+	if *installation == "" {
+		var found struct {
+			ID int64 `json:"id"`
+		}
+		if err := appRequest(client, jwt, http.MethodGet, "/repos/"+config.Repo+"/installation", &found); err != nil {
+			return "", time.Time{}, fmt.Errorf("GitHub App %s is not installed on %s: %w", app.ID, config.Repo, err)
+		}
+		*installation = fmt.Sprint(found.ID)
+	}
+
+	var token struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	if err := appRequest(client, jwt, http.MethodPost, "/app/installations/"+*installation+"/access_tokens", &token); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to create installation token: %w", err)
+	}
+	return token.Token, token.ExpiresAt, nil
+}
+
+// appRequest calls an endpoint that authenticates with the app JWT
+func appRequest(client *http.Client, jwt, method, path string, v any) error {
+	resp, err := doWithRetry(client, func() (*http.Request, error) {
+		req, err := http.NewRequest(method, githubAPIURL()+path, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/vnd.github+json")
+		req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+		req.Header.Set("Authorization", "Bearer "+jwt)
+		return req, nil
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode/100 != 2 {
+		return newGitHubError(resp, body)
+	}
+	return json.Unmarshal(body, v)
+}
@@ -0,0 +1,313 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// GraphQLError is returned when a GitHub GraphQL query answers with errors
+// instead of, or next to, its data
+type GraphQLError struct {
+	// Type is the type of the first error, such as NOT_FOUND or FORBIDDEN
+	Type     string
+	Messages []string
+}
+
+func (e *GraphQLError) Error() string {
+	return "GitHub GraphQL API: " + strings.Join(e.Messages, "; ")
+}
+
+// githubGraphQLURL is the GraphQL endpoint next to the configured REST API:
+// /graphql on github.com, /api/graphql next to /api/v3 on GitHub Enterprise
+// Server
+func githubGraphQLURL(restURL string) string {
+	if base, ok := strings.CutSuffix(restURL, "/api/v3"); ok {
+		return base + "/api/graphql"
+	}
+	return restURL + "/graphql"
+}
+
+// GraphQL runs a query against the GitHub GraphQL API and decodes its data
+// into v. Responses are not revalidated, as the API sends no ETags and the
+// points of a query are charged either way, but stored for --offline, which
+// answers the same query and variables with the last response.
+func (g *GitHubClient) GraphQL(query string, variables map[string]any, v any) error {
+	payload, err := json.Marshal(map[string]any{"query": query, "variables": variables})
+	if err != nil {
+		return fmt.Errorf("failed to encode GraphQL query: %w", err)
+	}
+	// The cache is keyed by URL, so the query becomes a fragment of one
+	sum := sha256.Sum256(payload)
+	cacheKey := githubGraphQLURL(g.baseURL) + "#" + hex.EncodeToString(sum[:8])
+	if globalOptions.offline {
+		if cached := loadCachedResponse(cacheKey, g.token); cached != nil {
+			return decodeGraphQL(cached.Body, v)
+		}
+	}
+	resp, err := doWithRetry(g.httpClient, func() (*http.Request, error) {
+		req, err := g.newRequest(http.MethodPost, githubGraphQLURL(g.baseURL))
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(payload))
+		req.ContentLength = int64(len(payload))
+		req.Header.Set("Content-Type", "application/json")
+		return req, nil
+	})
+	if err != nil {
+		return fmt.Errorf("GitHub GraphQL request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read GitHub GraphQL response: %w", err)
+	}
+	if resp.StatusCode/100 != 2 {
+		return newGitHubError(resp, body)
+	}
+	if err := decodeGraphQL(body, v); err != nil {
+		return err
+	}
+	storeCachedResponse(cacheKey, g.token, resp.Header, body)
+	return nil
+}
+
+// decodeGraphQL decodes the data of a GraphQL response into v, or returns
+// its errors
+func decodeGraphQL(body []byte, v any) error {
+	var result struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("failed to parse GitHub GraphQL response: %w", err)
+	}
+	if len(result.Errors) > 0 {
+		graphQLErr := &GraphQLError{Type: result.Errors[0].Type}
+		for _, e := range result.Errors {
+			graphQLErr.Messages = append(graphQLErr.Messages, e.Message)
+		}
+		return graphQLErr
+	}
+	if err := json.Unmarshal(result.Data, v); err != nil {
+		return fmt.Errorf("failed to parse GitHub GraphQL response: %w", err)
+	}
+	return nil
+}
+
+// releaseRun is a workflow run of a release tag's commit
+type releaseRun struct {
+	Workflow   string
+	Event      string
+	Status     string // queued, in_progress or completed
+	Conclusion string // success, failure, ... once completed
+	URL        string
+}
+
+// releaseActivity is what the report and drift commands need besides the
+// package: the latest release, the recent releases and the workflow runs
+// of their tags
+type releaseActivity struct {
+	// Latest is the latest final release, nil when there is none
+	Latest *GitHubRelease
+	// Releases are the most recent releases, newest first
+	Releases []GitHubRelease
+	// Runs are the workflow runs by release tag
+	Runs map[string][]releaseRun
+}
+
+// releaseActivityQuery fetches the releases with the check suites of their
+// tag commits in one request, where REST needs a call per list and tag
+const releaseActivityQuery = `query releaseActivity($owner: String!, $name: String!, $first: Int!) {
+  repository(owner: $owner, name: $name) {
+    latestRelease { ...release }
+    releases(first: $first, orderBy: {field: CREATED_AT, direction: DESC}) {
+      nodes {
+        ...release
+        tagCommit {
+          checkSuites(first: 20) {
+            nodes { status conclusion workflowRun { url event workflow { name } } }
+          }
+        }
+      }
+    }
+  }
+  rateLimit { cost remaining }
+}
+
+fragment release on Release { tagName name url isDraft isPrerelease publishedAt }`
+
+// graphQLRelease is a Release of the GraphQL API
+type graphQLRelease struct {
+	TagName      string    `json:"tagName"`
+	Name         string    `json:"name"`
+	URL          string    `json:"url"`
+	IsDraft      bool      `json:"isDraft"`
+	IsPrerelease bool      `json:"isPrerelease"`
+	PublishedAt  time.Time `json:"publishedAt"`
+	TagCommit    *struct {
+		CheckSuites struct {
+			Nodes []struct {
+				Status      string `json:"status"`
+				Conclusion  string `json:"conclusion"`
+				WorkflowRun *struct {
+					URL      string `json:"url"`
+					Event    string `json:"event"`
+					Workflow struct {
+						Name string `json:"name"`
+					} `json:"workflow"`
+				} `json:"workflowRun"`
+			} `json:"nodes"`
+		} `json:"checkSuites"`
+	} `json:"tagCommit"`
+}
+
+func (r graphQLRelease) release() GitHubRelease {
+	return GitHubRelease{TagName: r.TagName, Name: r.Name, HTMLURL: r.URL, Draft: r.IsDraft, Prerelease: r.IsPrerelease, PublishedAt: r.PublishedAt}
+}
+
+// fetchReleaseActivity fetches the latest of limit releases with one GraphQL
+// query. GitHub's GraphQL API has no container packages, so their versions
+// still come from REST; when the query fails, say on a server without the
+// GraphQL API or with a token it rejects, the releases and runs do too.
+func fetchReleaseActivity(github *GitHubClient, limit int) (releaseActivity, error) {
+	activity, err := graphQLReleaseActivity(github, limit)
+	if err == nil || commandContext.Err() != nil {
+		return activity, err
+	}
+	slog.Warn("GraphQL query failed, falling back to REST", "error", err)
+	return restReleaseActivity(github, limit)
+}
+
+func graphQLReleaseActivity(github *GitHubClient, limit int) (releaseActivity, error) {
+	owner, name, _ := strings.Cut(config.Repo, "/")
+	var data struct {
+		Repository *struct {
+			LatestRelease *graphQLRelease `json:"latestRelease"`
+			Releases      struct {
+				Nodes []graphQLRelease `json:"nodes"`
+			} `json:"releases"`
+		} `json:"repository"`
+		RateLimit struct {
+			Cost      int `json:"cost"`
+			Remaining int `json:"remaining"`
+		} `json:"rateLimit"`
+	}
+	variables := map[string]any{"owner": owner, "name": name, "first": limit}
+	if err := github.GraphQL(releaseActivityQuery, variables, &data); err != nil {
+		return releaseActivity{}, err
+	}
+	if data.Repository == nil {
+		return releaseActivity{}, &GraphQLError{Type: "NOT_FOUND", Messages: []string{"repository " + config.Repo + " not found"}}
+	}
+	slog.Debug("GraphQL query", "query", "releaseActivity", "cost", data.RateLimit.Cost, "remaining", data.RateLimit.Remaining)
+
+	activity := releaseActivity{Runs: make(map[string][]releaseRun)}
+	if latest := data.Repository.LatestRelease; latest != nil {
+		release := latest.release()
+		activity.Latest = &release
+	}
+	for _, node := range data.Repository.Releases.Nodes {
+		activity.Releases = append(activity.Releases, node.release())
+		if node.TagCommit == nil {
+			continue
+		}
+		for _, suite := range node.TagCommit.CheckSuites.Nodes {
+			// Suites of other apps have no workflow run
+			if run := suite.WorkflowRun; run != nil {
+				activity.Runs[node.TagName] = append(activity.Runs[node.TagName], releaseRun{
+					Workflow:   run.Workflow.Name,
+					Event:      strings.ToLower(run.Event),
+					Status:     strings.ToLower(suite.Status),
+					Conclusion: strings.ToLower(suite.Conclusion),
+					URL:        run.URL,
+				})
+			}
+		}
+	}
+	return activity, nil
+}
+
+// restReleaseActivity collects the same with three REST calls. Runs come
+// from the most recent page of runs whose branch is a release tag, as tag
+// pushes and release events record it, so older releases may show none.
+func restReleaseActivity(github *GitHubClient, limit int) (releaseActivity, error) {
+	activity := releaseActivity{Runs: make(map[string][]releaseRun)}
+	var latest GitHubRelease
+	if err := github.Get(fmt.Sprintf("/repos/%s/releases/latest", config.Repo), &latest); err != nil && !isNotFound(err) {
+		return activity, err
+	}
+	if latest.TagName != "" {
+		activity.Latest = &latest
+	}
+	err := github.GetPages(fmt.Sprintf("/repos/%s/releases?per_page=%d", config.Repo, min(limit, 100)), func(body []byte) error {
+		var page []GitHubRelease
+		if err := json.Unmarshal(body, &page); err != nil {
+			return fmt.Errorf("failed to parse releases: %w", err)
+		}
+		activity.Releases = append(activity.Releases, page...)
+		if len(activity.Releases) >= limit {
+			activity.Releases = activity.Releases[:limit]
+			return errStopPages
+		}
+		return nil
+	})
+	if err != nil {
+		return activity, err
+	}
+
+	tags := make(map[string]bool)
+	for _, release := range activity.Releases {
+		tags[release.TagName] = true
+	}
+	var runs struct {
+		WorkflowRuns []WorkflowRun `json:"workflow_runs"`
+	}
+	if err := github.Get(fmt.Sprintf("/repos/%s/actions/runs?per_page=100", config.Repo), &runs); err != nil {
+		return activity, err
+	}
+	for _, run := range runs.WorkflowRuns {
+		if tags[run.HeadBranch] {
+			activity.Runs[run.HeadBranch] = append(activity.Runs[run.HeadBranch], releaseRun{
+				Workflow: run.Name, Event: run.Event, Status: run.Status, Conclusion: run.Conclusion, URL: run.HTMLURL,
+			})
+		}
+	}
+	return activity, nil
+}
+
+// summarizeRuns lists the workflows of runs with their outcome, e.g.
+// "Docker Build ✅, Security Scan ❌"
+func summarizeRuns(runs []releaseRun) string {
+	if len(runs) == 0 {
+		return "-"
+	}
+	parts := make([]string, len(runs))
+	for i, run := range runs {
+		icon := "⚠️"
+		switch {
+		case run.Status != "completed":
+			icon = "⏳"
+		case run.Conclusion == "success":
+			icon = "✅"
+		case run.Conclusion == "failure" || run.Conclusion == "timed_out" || run.Conclusion == "startup_failure":
+			icon = "❌"
+		case run.Conclusion == "skipped" || run.Conclusion == "neutral":
+			icon = "➖"
+		}
+		parts[i] = run.Workflow + " " + icon
+	}
+	return strings.Join(parts, ", ")
+}
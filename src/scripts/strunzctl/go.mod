@@ -0,0 +1,3 @@
+module github.com/longevitycoach/StrunzKnowledge/src/scripts/strunzctl
+
+go 1.24
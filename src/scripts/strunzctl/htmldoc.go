@@ -0,0 +1,233 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// htmlNode is an element or a text node of a parsed page. The parser is
+// forgiving rather than spec-compliant: it is meant for extracting
+// content from known sites, not for rendering.
+type htmlNode struct {
+	Tag      string // "" for text nodes
+	Attrs    map[string]string
+	Text     string
+	Children []*htmlNode
+	Parent   *htmlNode
+}
+
+var (
+	htmlTagPattern  = regexp.MustCompile(`<(/?)([a-zA-Z][a-zA-Z0-9-]*)((?:[^>"']|"[^"]*"|'[^']*')*?)(/?)>`)
+	htmlAttrPattern = regexp.MustCompile(`([a-zA-Z_:][-a-zA-Z0-9_:.]*)(?:\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'=<>` + "`" + `]+)))?`)
+	// Comments, doctypes and the content of raw text elements never hold markup
+	htmlSkipPattern = regexp.MustCompile(`(?is)<!--.*?-->|<!doctype[^>]*>|<(script|style|noscript|template)\b[^>]*>.*?</(script|style|noscript|template)\s*>`)
+)
+
+// htmlVoidElements never have children or end tags
+var htmlVoidElements = []string{"area", "base", "br", "col", "embed", "hr", "img", "input", "link", "meta", "source", "track", "wbr"}
+
+// parseHTML builds the element tree of a page. End tags without a matching
+// open element are ignored and unclosed elements end with their parent.
+func parseHTML(page string) *htmlNode {
+	page = htmlSkipPattern.ReplaceAllString(page, "")
+	root := &htmlNode{Tag: "#document", Attrs: map[string]string{}}
+	current := root
+	text := func(s string) {
+		if s != "" {
+			current.Children = append(current.Children, &htmlNode{Text: html.UnescapeString(s), Parent: current})
+		}
+	}
+	last := 0
+	for _, match := range htmlTagPattern.FindAllStringSubmatchIndex(page, -1) {
+		text(page[last:match[0]])
+		last = match[1]
+		closing := match[3] > match[2]
+		tag := strings.ToLower(page[match[4]:match[5]])
+		if closing {
+			for open := current; open != nil; open = open.Parent {
+				if open.Tag == tag {
+					current = open.Parent
+					break
+				}
+			}
+			continue
+		}
+		node := &htmlNode{Tag: tag, Attrs: parseHTMLAttrs(page[match[6]:match[7]]), Parent: current}
+		current.Children = append(current.Children, node)
+		if match[9] == match[8] && !slices.Contains(htmlVoidElements, tag) {
+			current = node
+		}
+	}
+	text(page[last:])
+	return root
+}
+
+func parseHTMLAttrs(s string) map[string]string {
+	attrs := make(map[string]string)
+	for _, match := range htmlAttrPattern.FindAllStringSubmatch(s, -1) {
+		attrs[strings.ToLower(match[1])] = html.UnescapeString(match[2] + match[3] + match[4])
+	}
+	return attrs
+}
+
+// HasClass reports whether the element has the class
+func (n *htmlNode) HasClass(class string) bool {
+	return slices.Contains(strings.Fields(n.Attrs["class"]), class)
+}
+
+// FindAll returns the elements below n accepted by match, in document order
+func (n *htmlNode) FindAll(match func(*htmlNode) bool) []*htmlNode {
+	var found []*htmlNode
+	for _, child := range n.Children {
+		if child.Tag == "" {
+			continue
+		}
+		if match(child) {
+			found = append(found, child)
+		}
+		found = append(found, child.FindAll(match)...)
+	}
+	return found
+}
+
+// Find returns the first element below n accepted by match, or nil
+func (n *htmlNode) Find(match func(*htmlNode) bool) *htmlNode {
+	if found := n.FindAll(match); len(found) > 0 {
+		return found[0]
+	}
+	return nil
+}
+
+// Remove detaches the element from its parent
+func (n *htmlNode) Remove() {
+	if n.Parent != nil {
+		n.Parent.Children = slices.DeleteFunc(n.Parent.Children, func(child *htmlNode) bool { return child == n })
+		n.Parent = nil
+	}
+}
+
+// htmlBlockElements start a new line in InnerText
+var htmlBlockElements = []string{"address", "article", "aside", "blockquote", "br", "dd", "div", "dl", "dt", "figcaption",
+	"footer", "h1", "h2", "h3", "h4", "h5", "h6", "header", "hr", "li", "main", "nav", "ol", "p", "pre", "section", "table", "td", "th", "tr", "ul"}
+
+// InnerText returns the text below n with a line break per block element
+// and whitespace collapsed within lines
+func (n *htmlNode) InnerText() string {
+	var b strings.Builder
+	var walk func(*htmlNode)
+	walk = func(node *htmlNode) {
+		if node.Tag == "" {
+			b.WriteString(node.Text)
+			return
+		}
+		block := slices.Contains(htmlBlockElements, node.Tag)
+		if block {
+			b.WriteString("\n")
+		}
+		for _, child := range node.Children {
+			walk(child)
+		}
+		if block {
+			b.WriteString("\n")
+		}
+	}
+	walk(n)
+	var lines []string
+	for _, line := range strings.Split(b.String(), "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// byTag and byClass are the matchers most selectors need
+func byTag(tag string) func(*htmlNode) bool {
+	return func(n *htmlNode) bool { return n.Tag == tag }
+}
+
+func byClass(class string) func(*htmlNode) bool {
+	return func(n *htmlNode) bool { return n.HasClass(class) }
+}
+
+// simpleSelector is one compound selector: tag, #id, .class and [attr],
+// [attr=value] or [attr*=value], all of which must match
+type simpleSelector struct {
+	tag     string
+	id      string
+	classes []string
+	attrs   [][3]string // name, operator ("", "=", "*="), value
+}
+
+var selectorPartPattern = regexp.MustCompile(`^(?:([a-zA-Z][a-zA-Z0-9-]*|\*)|#([-\w]+)|\.([-\w]+)|\[\s*([-\w:]+)\s*(?:(\*?=)\s*(?:"([^"]*)"|'([^']*)'|([^\]\s]*)))?\s*\])`)
+
+// compileSelector supports the CSS needed to point at content: compound
+// selectors joined by the descendant combinator, e.g. "main div.post-content"
+// or "meta[property=article:published_time]"
+func compileSelector(selector string) (func(*htmlNode) bool, error) {
+	var chain []simpleSelector
+	for _, compound := range strings.Fields(selector) {
+		var simple simpleSelector
+		for rest := compound; rest != ""; {
+			m := selectorPartPattern.FindStringSubmatch(rest)
+			if m == nil {
+				return nil, fmt.Errorf("unsupported selector %q at %q", selector, rest)
+			}
+			rest = rest[len(m[0]):]
+			switch {
+			case m[1] != "":
+				simple.tag = strings.ToLower(strings.TrimPrefix(m[1], "*"))
+			case m[2] != "":
+				simple.id = m[2]
+			case m[3] != "":
+				simple.classes = append(simple.classes, m[3])
+			default:
+				simple.attrs = append(simple.attrs, [3]string{strings.ToLower(m[4]), m[5], m[6] + m[7] + m[8]})
+			}
+		}
+		chain = append(chain, simple)
+	}
+	if len(chain) == 0 {
+		return nil, errors.New("empty selector")
+	}
+	return func(n *htmlNode) bool {
+		if !chain[len(chain)-1].matches(n) {
+			return false
+		}
+		// The remaining compounds must match ancestors, innermost first
+		i := len(chain) - 2
+		for ancestor := n.Parent; ancestor != nil && i >= 0; ancestor = ancestor.Parent {
+			if chain[i].matches(ancestor) {
+				i--
+			}
+		}
+		return i < 0
+	}, nil
+}
+
+func (s simpleSelector) matches(n *htmlNode) bool {
+	if n.Tag == "" || (s.tag != "" && n.Tag != s.tag) || (s.id != "" && n.Attrs["id"] != s.id) {
+		return false
+	}
+	for _, class := range s.classes {
+		if !n.HasClass(class) {
+			return false
+		}
+	}
+	for _, attr := range s.attrs {
+		value, ok := n.Attrs[attr[0]]
+		switch {
+		case !ok:
+			return false
+		case attr[1] == "=" && value != attr[2]:
+			return false
+		case attr[1] == "*=" && !strings.Contains(value, attr[2]):
+			return false
+		}
+	}
+	return true
+}
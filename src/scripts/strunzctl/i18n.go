@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// languages are the values of --lang; English is the source language
+var languages = []string{"en", "de"}
+
+// catalogs translate output format strings per language, keyed by the
+// English format, which is printed where a translation is missing. A
+// translation takes the same verbs; explicit indexes such as %[2]s reorder
+// them. Machine-readable output such as JSON, CSV columns and logs stays
+// in English.
+var catalogs = map[string]map[string]string{
+	"de": catalogDE,
+}
+
+// setLanguage selects the output language; empty is English
+func setLanguage(lang string) error {
+	if lang == "" {
+		lang = "en"
+	}
+	if !slices.Contains(languages, lang) {
+		return fmt.Errorf("%w: unsupported language %q (want %s)", errUsage, lang, strings.Join(languages, " or "))
+	}
+	globalOptions.lang = lang
+	return nil
+}
+
+// message returns the translation of format into the output language
+func message(format string) string {
+	if translated, ok := catalogs[globalOptions.lang][format]; ok {
+		return translated
+	}
+	return format
+}
+
+// printf is fmt.Printf in the output language
+func printf(format string, args ...any) {
+	fmt.Printf(message(format), args...)
+}
+
+// fprintf is fmt.Fprintf in the output language
+func fprintf(w io.Writer, format string, args ...any) {
+	fmt.Fprintf(w, message(format), args...)
+}
+
+// decimal formats v with the given number of decimals and the output
+// language's decimal separator
+func decimal(v float64, decimals int) string {
+	text := strconv.FormatFloat(v, 'f', decimals, 64)
+	if globalOptions.lang == "de" {
+		return strings.Replace(text, ".", ",", 1)
+	}
+	return text
+}
+
+var catalogDE = map[string]string{
+	// Shared values
+	"untagged":    "ohne Tag",
+	"unknown":     "unbekannt",
+	"not scanned": "nicht gescannt",
+	"(none)":      "(keine)",
+
+	// packages info
+	"Fetching package information for %s/%s...\n":                                               "Lade Paketinformationen für %s/%s...\n",
+	"GitHub cannot show %s/%s: it does not exist or the token's account has no access to it.\n": "GitHub kann %s/%s nicht anzeigen: Das Paket existiert nicht, oder das Konto des Tokens hat keinen Zugriff.\n",
+	"Run `strunzctl doctor` to check the token, its scopes and access to the package.\n":        "`strunzctl doctor` prüft das Token, seine Scopes und den Zugriff auf das Paket.\n",
+	"\n📦 Package Information:\n":                                                                "\n📦 Paketinformationen:\n",
+	"Type: %s\n":                                                                                "Typ: %s\n",
+	"Visibility: %s\n":                                                                          "Sichtbarkeit: %s\n",
+	"Created: %s\n":                                                                             "Erstellt: %s\n",
+	"Updated: %s\n":                                                                             "Aktualisiert: %s\n",
+	"\n📝 Package Description:\n":                                                                "\n📝 Paketbeschreibung:\n",
+	"Note: GitHub Container Registry packages don't have editable descriptions via API.\n": "Hinweis: Pakete der GitHub Container Registry haben keine über die API bearbeitbare Beschreibung.\n",
+	"Descriptions are typically set through:\n":                                            "Die Beschreibung kommt üblicherweise aus:\n",
+	"  1. The Dockerfile LABEL org.opencontainers.image.description\n":                     "  1. dem Dockerfile-LABEL org.opencontainers.image.description\n",
+	"  2. Repository README that's linked to the package\n":                                "  2. dem README des mit dem Paket verknüpften Repositorys\n",
+	"  3. GitHub Actions workflow annotations\n":                                           "  3. Annotationen des GitHub-Actions-Workflows\n",
+	"\nRun `strunzctl lint dockerfile` to check that the Dockerfiles set the required\n":   "\n`strunzctl lint dockerfile` prüft, ob die Dockerfiles die nötigen\n",
+	"%s* labels (%s) for the release.\n":                                                   "%s*-Labels (%s) für das Release setzen.\n",
+
+	// packages versions
+	"\n📋 Package Versions:\n":                                                 "\n📋 Paketversionen:\n",
+	"  - %s (ID: %d, Created: %s, Size: %s, CVEs: %s)\n":                      "  - %s (ID: %d, erstellt: %s, Größe: %s, CVEs: %s)\n",
+	"\n(Showing the %d most recent versions; older pages were not fetched)\n": "\n(Die %d neuesten Versionen; ältere Seiten wurden nicht abgerufen)\n",
+	"\n(Showing %d of %d versions, sorted by %s)\n":                           "\n(%d von %d Versionen, sortiert nach %s)\n",
+	"Total size of listed versions: %s\n":                                     "Gesamtgröße der aufgeführten Versionen: %s\n",
+	"Note: layers shared between versions are counted once per version.\n":    "Hinweis: Layer, die sich mehrere Versionen teilen, zählen bei jeder Version mit.\n",
+	"\n⚠️  Some version details could not be fetched:\n":                      "\n⚠️  Einige Versionsdetails konnten nicht abgerufen werden:\n",
+
+	// packages releases
+	"\n📦 Release Series:\n":                                           "\n📦 Release-Reihen:\n",
+	"No semver tags found\n":                                          "Keine Semver-Tags gefunden\n",
+	"\n%s  (%d releases, %d prereleases)\n":                           "\n%s  (%d Releases, %d Vorabversionen)\n",
+	"  ⚠️  stale":                                                     "  ⚠️  veraltet",
+	"\n(%d non-semver tags such as latest or sha-* not shown)\n":      "\n(%d Tags ohne Semver wie latest oder sha-* nicht angezeigt)\n",
+	"\n🧹 Stale Prereleases:\n":                                        "\n🧹 Veraltete Vorabversionen:\n",
+	"✅ No prerelease tags with an existing final release\n":           "✅ Keine Vorabversions-Tags, deren finales Release existiert\n",
+	"  - %s (ID: %d) %s released; image also tagged %s, untag only\n": "  - %s (ID: %d) %s ist veröffentlicht; das Image trägt auch %s, nur den Tag entfernen\n",
+	"  - %s (ID: %d) %s released; version can be deleted\n":           "  - %s (ID: %d) %s ist veröffentlicht; die Version kann gelöscht werden\n",
+
+	// packages report --format markdown
+	"# Container Image Audit: %s/%s\n\n":                            "# Audit der Container-Images: %s/%s\n\n",
+	"Generated: %s\n\n":                                             "Erstellt: %s\n\n",
+	"Offline snapshot: cached %s to %s\n\n":                         "Offline-Stand: zwischengespeichert %s bis %s\n\n",
+	"## Summary\n\n":                                                "## Zusammenfassung\n\n",
+	"- Versions: %d (%d tagged, %d untagged)\n":                     "- Versionen: %d (%d mit Tag, %d ohne Tag)\n",
+	"- Scanned: %d of %d\n":                                         "- Gescannt: %d von %d\n",
+	"- Total size: %s (shared layers counted once per version)\n\n": "- Gesamtgröße: %s (geteilte Layer zählen bei jeder Version mit)\n\n",
+	"## Versions\n\n":                                               "## Versionen\n\n",
+	"| Tags | Digest | Created | Size | Vulnerabilities | Scanned |\n|------|--------|---------|------|-----------------|---------|\n": "| Tags | Digest | Erstellt | Größe | Schwachstellen | Gescannt |\n|------|--------|----------|-------|----------------|----------|\n",
+	"| Release | Published | Image | Workflow runs |\n|---------|-----------|-------|---------------|\n":                               "| Release | Veröffentlicht | Image | Workflow-Läufe |\n|---------|----------------|-------|----------------|\n",
+	"(prerelease)": "(Vorabversion)",
+	"missing":      "fehlt",
+
+	// fleet
+	"\n📋 Versions of %d package(s)\n\n": "\n📋 Versionen von %d Paket(en)\n\n",
+	"PACKAGE":                           "PAKET",
+	"VERSIONS":                          "VERSIONEN",
+	"UNTAGGED":                          "OHNE TAG",
+	"NEWEST":                            "NEUESTE",
+	"DELETED":                           "GELÖSCHT",
+	"FREED":                             "FREI",
+	"\nTotal: %d version(s), %d untagged, in %d of %d package(s)\n": "\nGesamt: %d Version(en), %d ohne Tag, in %d von %d Paket(en)\n",
+	"\n🔏 Package policy audit of %d package(s) (%s)\n":              "\n🔏 Richtlinienprüfung von %d Paket(en) (%s)\n",
+	"\n❌ %s could not be audited\n":                                 "\n❌ %s konnte nicht geprüft werden\n",
+	"\n⚠️  %d package(s) are not declared in the policy: %s\n":      "\n⚠️  %d Paket(e) sind in der Richtlinie nicht deklariert: %s\n",
+	"\n✅ %d package(s) match the policy\n":                          "\n✅ %d Paket(e) entsprechen der Richtlinie\n",
+	"\n🧹 Retention plan for %d package(s): %s\n":                    "\n🧹 Aufbewahrungsplan für %d Paket(e): %s\n",
+	"  %-40s ❌ not pruned\n":                                        "  %-40s ❌ nicht bereinigt\n",
+	"  %-40s %8d %8d  %s, %d failed\n":                              "  %-40s %8d %8d  %s, %d fehlgeschlagen\n",
+	"\nWould delete %d of %d version(s) and free %s (dry run)\n":    "\nWürde %d von %d Version(en) löschen und %s freigeben (Probelauf)\n",
+	"\n✅ Deleted %d of %d version(s), freeing about %s\n":           "\n✅ %d von %d Version(en) gelöscht, etwa %s freigegeben\n",
+	"\n✅ Nothing to delete\n":                                       "\n✅ Nichts zu löschen\n",
+	"  %-40s %d of %d version(s), %s\n":                             "  %-40s %d von %d Version(en), %s\n",
+	"Delete %d version(s) from %d package(s)?":                      "%d Version(en) aus %d Paket(en) löschen?",
+
+	// Retention plans
+	"keep the last %d":           "die letzten %d behalten",
+	"the last %d days":           "die letzten %d Tage",
+	"semver tags":                "Semver-Tags",
+	"tagged versions":            "Versionen mit Tag",
+	"; delete stale prereleases": "; veraltete Vorabversionen löschen",
+	"stale prerelease":           "veraltete Vorabversion",
+	"one of the last %d":         "eine der letzten %d",
+	"semver tag":                 "Semver-Tag",
+	"tagged":                     "mit Tag",
+	"younger than %d days":       "jünger als %d Tage",
+	"not protected":              "nicht geschützt",
+	", %d days old":              ", %d Tage alt",
+	"platform image of %s":       "Plattform-Image von %s",
+
+	// ask
+	"%s on %s in %s\n\n%s\n":             "%s auf %s in %s\n\n%s\n",
+	"No results from %s on %s (%s)\n":    "Keine Treffer von %s auf %s (%s)\n",
+	"%d result(s) from %s on %s in %s\n": "%d Treffer von %s auf %s in %s\n",
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/longevitycoach/StrunzKnowledge/src/scripts/strunzctl/pkg/ghcr"
+)
+
+// defaultPlatforms are the architectures every release image is built for
+const defaultPlatforms = "linux/amd64,linux/arm64"
+
+// defaultPlatform is used when a command needs a single platform image
+const defaultPlatform = "linux/amd64"
+
+// ociLabelPrefix is the namespace of the standard OCI image annotations
+const ociLabelPrefix = "org.opencontainers.image."
+
+// requiredLabels must be present on every published image
+var requiredLabels = []string{"description", "source", "revision"}
+
+// PlatformImage is one runnable image resolved from a tag
+type PlatformImage struct {
+	Platform Platform
+	Digest   string
+	Size     int64
+	Created  time.Time
+}
+
+func newImageCommand() *Command {
+	return newGroup("image", "Inspect and manage published container images via the registry API.",
+		newImageBuildCommand(),
+		newImageManifestCommand(),
+		newImageLabelsCommand(),
+		newImageInspectCommand(),
+		newImageDiffCommand(),
+		newImageAnalyzeCommand(),
+		newImageCheckBaseCommand(),
+		newImageSmokeCommand(),
+		newImageScanSecretsCommand(),
+		newImageSBOMCommand(),
+		newImageLicensesCommand(),
+		newImageSignCommand(),
+		newImageVerifySignatureCommand(),
+		newImageVerifyProvenanceCommand(),
+		newImagePromoteCommand(),
+		newImageMirrorCommand(),
+		newImagePullCommand(),
+	)
+}
+
+func newImageManifestCommand() *Command {
+	cmd := newCommand("manifest", "<tag>", "Resolve a tag's manifest list and show per-platform digests, sizes and creation times.")
+	platforms := cmd.Flags.String("platforms", defaultPlatforms, "comma-separated platforms the tag is expected to provide")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 1); err != nil {
+			return err
+		}
+		tag := args[0]
+		registry := newRegistryClient(config.Registry, imageRepository())
+
+		manifest, err := registry.GetManifest(tag)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("\n🔎 Manifest for %s\n", registry.Reference(tag))
+		fmt.Printf("Digest: %s\n", manifest.Digest)
+		fmt.Printf("Media type: %s\n", manifest.MediaType)
+
+		images, err := resolvePlatformImages(registry, manifest)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println("\n🖥️  Platforms:")
+		for _, image := range images {
+			fmt.Printf("  - %-16s %s  %10s  created %s\n", image.Platform, image.Digest,
+				formatBytes(image.Size), image.Created.Format(time.RFC3339))
+		}
+
+		if missing := missingPlatforms(images, splitList(*platforms)); len(missing) > 0 {
+			fmt.Printf("\n⚠️  Missing expected platform(s): %s\n", strings.Join(missing, ", "))
+			return fmt.Errorf("%w: tag %s is missing platform(s) %s", errPolicy, tag, strings.Join(missing, ", "))
+		}
+
+		fmt.Println("\n✅ All expected platforms present")
+		return nil
+	}
+	return cmd
+}
+
+// missingPlatforms returns the expected platforms without an image
+func missingPlatforms(images []PlatformImage, expected []string) []string {
+	found := make(map[string]bool)
+	for _, image := range images {
+		found[image.Platform.String()] = true
+	}
+	var missing []string
+	for _, platform := range expected {
+		if !found[platform] {
+			missing = append(missing, platform)
+		}
+	}
+	return missing
+}
+
+func newImageLabelsCommand() *Command {
+	cmd := newCommand("labels", "<tag>", "Show the OCI labels and annotations baked into a published image.")
+	platform := cmd.Flags.String("platform", defaultPlatform, "platform image to read from a manifest list")
+	all := cmd.Flags.Bool("all", false, "show all labels, not only org.opencontainers.image.*")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 1); err != nil {
+			return err
+		}
+		tag := args[0]
+		registry := newRegistryClient(config.Registry, imageRepository())
+
+		index, err := registry.GetManifest(tag)
+		if err != nil {
+			return err
+		}
+		manifest, err := selectPlatform(registry, index, *platform)
+		if err != nil {
+			return err
+		}
+		config, err := registry.GetImageConfig(manifest)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("\n🏷️  Labels for %s (%s)\n", registry.Reference(tag), *platform)
+		printLabels(config.Config.Labels, *all)
+
+		if len(index.Annotations) > 0 || len(manifest.Annotations) > 0 {
+			fmt.Println("\n📎 Manifest annotations:")
+			printLabels(index.Annotations, true)
+			if manifest != index {
+				printLabels(manifest.Annotations, true)
+			}
+		}
+
+		var missing []string
+		for _, name := range requiredLabels {
+			if config.Config.Labels[ociLabelPrefix+name] == "" {
+				missing = append(missing, ociLabelPrefix+name)
+			}
+		}
+		if len(missing) > 0 {
+			fmt.Printf("\n⚠️  Missing required label(s): %s\n", strings.Join(missing, ", "))
+			return fmt.Errorf("%w: image %s is missing %d required label(s)", errPolicy, tag, len(missing))
+		}
+
+		fmt.Println("\n✅ All required labels present")
+		return nil
+	}
+	return cmd
+}
+
+func printLabels(labels map[string]string, all bool) {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		if all || strings.HasPrefix(key, ociLabelPrefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	if len(keys) == 0 {
+		fmt.Println("  (none)")
+		return
+	}
+	for _, key := range keys {
+		fmt.Printf("  %s = %s\n", key, labels[key])
+	}
+}
+
+// selectPlatform returns the image manifest for a platform, descending into
+// a manifest list if necessary. Single-platform manifests are returned as is.
+func selectPlatform(registry *RegistryClient, manifest *Manifest, platform string) (*Manifest, error) {
+	if !manifest.IsIndex() {
+		return manifest, nil
+	}
+
+	var available []string
+	for _, desc := range manifest.Manifests {
+		if desc.IsAttestation() || desc.Platform == nil {
+			continue
+		}
+		if desc.Platform.String() == platform || desc.Platform.OS+"/"+desc.Platform.Architecture == platform {
+			return registry.GetManifest(desc.Digest)
+		}
+		available = append(available, desc.Platform.String())
+	}
+	return nil, fmt.Errorf("platform %s not found in manifest list (available: %s)", platform, strings.Join(available, ", "))
+}
+
+// resolvePlatformManifest fetches a tag and selects the given platform image
+func resolvePlatformManifest(registry *RegistryClient, tag, platform string) (*Manifest, error) {
+	manifest, err := registry.GetManifest(tag)
+	if err != nil {
+		return nil, err
+	}
+	selected, err := selectPlatform(registry, manifest, platform)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", tag, err)
+	}
+	return selected, nil
+}
+
+// resolvePlatformImages returns the runnable images behind a manifest,
+// descending into manifest lists and skipping attestation entries
+func resolvePlatformImages(registry *RegistryClient, manifest *Manifest) ([]PlatformImage, error) {
+	if !manifest.IsIndex() {
+		image, err := describePlatformImage(registry, manifest)
+		if err != nil {
+			return nil, err
+		}
+		return []PlatformImage{*image}, nil
+	}
+
+	var images []PlatformImage
+	for _, desc := range manifest.Manifests {
+		if desc.IsAttestation() {
+			continue
+		}
+
+		child, err := registry.GetManifest(desc.Digest)
+		if err != nil {
+			return nil, err
+		}
+		image, err := describePlatformImage(registry, child)
+		if err != nil {
+			return nil, err
+		}
+		if desc.Platform != nil {
+			image.Platform = *desc.Platform
+		}
+		images = append(images, *image)
+	}
+	return images, nil
+}
+
+func describePlatformImage(registry *RegistryClient, manifest *Manifest) (*PlatformImage, error) {
+	config, err := registry.GetImageConfig(manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PlatformImage{
+		Platform: Platform{OS: config.OS, Architecture: config.Architecture, Variant: config.Variant},
+		Digest:   manifest.Digest,
+		Size:     ghcr.ManifestSize(manifest, make(map[string]bool)),
+		Created:  config.Created,
+	}, nil
+}
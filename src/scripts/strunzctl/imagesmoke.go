@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"time"
+)
+
+// freePort asks the kernel for an unused local port
+func freePort() (int, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
+
+func newImageSmokeCommand() *Command {
+	cmd := newCommand("smoke", "<tag>", "Pull and run a published image locally, wait for /health, do the MCP handshake and one search.")
+	platform := cmd.Flags.String("platform", defaultPlatform, "platform of the image to run")
+	port := cmd.Flags.Int("port", 0, "local port for the container (default: a free one)")
+	startup := cmd.Flags.Duration("startup-timeout", 5*time.Minute, "how long the container may take to become healthy")
+	noPull := cmd.Flags.Bool("no-pull", false, "run the locally cached image instead of pulling the tag")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 1); err != nil {
+			return err
+		}
+		tag := args[0]
+		registry := newRegistryClient(config.Registry, imageRepository())
+		reference := registry.Reference(tag)
+
+		if !*noPull {
+			fmt.Printf("\n⬇️  Pulling %s (%s)\n", reference, *platform)
+			if err := runDocker("pull", "--quiet", "--platform", *platform, reference); err != nil {
+				return err
+			}
+		}
+		hostPort := *port
+		if hostPort == 0 {
+			var err error
+			if hostPort, err = freePort(); err != nil {
+				return err
+			}
+		}
+		container, err := startCandidate(reference, *platform, hostPort)
+		if err != nil {
+			return err
+		}
+		defer stopCandidate(container)
+		url := fmt.Sprintf("http://127.0.0.1:%d", hostPort)
+
+		fmt.Printf("🐳 Started %s as %s, waiting for %s/health\n", reference, container[:min(12, len(container))], url)
+		start := time.Now()
+		if err := waitHealthy(url, *startup); err != nil {
+			printContainerLogs(container)
+			return err
+		}
+		fmt.Printf("✅ Healthy after %s\n", time.Since(start).Round(time.Second))
+
+		version := ""
+		if _, ok := parseSemver(tag); ok {
+			version = tag
+		}
+		fmt.Printf("\n🧪 Smoke testing %s\n", reference)
+		checks := runSmokeTests(url, version, smokeToolCalls)
+		if failed := printSmokeChecks(checks); failed > 0 {
+			printContainerLogs(container)
+			return fmt.Errorf("%w: %s failed %d of %d smoke check(s)", errPolicy, tag, failed, len(checks))
+		}
+		fmt.Printf("\n✅ %s boots and answers searches\n", tag)
+		return nil
+	}
+	return cmd
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// inspectedImage is what `image inspect` reports: the platform manifest
+// with its config blob as stored
+type inspectedImage struct {
+	Reference string          `json:"reference"`
+	Platform  string          `json:"platform"`
+	Digest    string          `json:"digest"`
+	Layers    []Descriptor    `json:"layers"`
+	Config    json.RawMessage `json:"config"`
+	parsed    ImageConfig
+}
+
+// inspectImage reads the config of a platform image: the manifest and
+// the config blob, no layers
+func inspectImage(registry *RegistryClient, tag, platform string) (*inspectedImage, error) {
+	manifest, err := resolvePlatformManifest(registry, tag, platform)
+	if err != nil {
+		return nil, err
+	}
+	body, err := registry.GetBlob(manifest.Config.Digest)
+	if err != nil {
+		return nil, err
+	}
+	image := &inspectedImage{Reference: registry.Reference(tag), Platform: platform, Digest: manifest.Digest, Layers: manifest.Layers, Config: body}
+	if err := json.Unmarshal(body, &image.parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse image config %s: %w", manifest.Config.Digest, err)
+	}
+	return image, nil
+}
+
+// execForm renders an entrypoint or command the way a Dockerfile writes it
+func execForm(args []string) string {
+	if len(args) == 0 {
+		return "(none)"
+	}
+	encoded, _ := json.Marshal(args)
+	return string(encoded)
+}
+
+// setKeys lists the keys of a set such as exposed ports or volumes
+func setKeys(set map[string]struct{}) string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return orNone(strings.Join(keys, ", "))
+}
+
+func printImageInspection(image *inspectedImage, full bool) {
+	config := image.parsed
+	var total int64
+	for _, layer := range image.Layers {
+		total += layer.Size
+	}
+	fmt.Printf("Digest:  %s\n", image.Digest)
+	fmt.Printf("Created: %s\n", config.Created.Format("2006-01-02 15:04:05 MST"))
+	fmt.Printf("Size:    %s in %d layer(s)\n", formatBytes(total), len(image.Layers))
+
+	user := config.Config.User
+	if user == "" {
+		user = "(none, runs as root)"
+	}
+	fmt.Println("\n⚙️  Config:")
+	fmt.Printf("  User:        %s\n", user)
+	fmt.Printf("  Entrypoint:  %s\n", execForm(config.Config.Entrypoint))
+	fmt.Printf("  Cmd:         %s\n", execForm(config.Config.Cmd))
+	fmt.Printf("  WorkingDir:  %s\n", orNone(config.Config.WorkingDir))
+	fmt.Printf("  Ports:       %s\n", setKeys(config.Config.ExposedPorts))
+	fmt.Printf("  Volumes:     %s\n", setKeys(config.Config.Volumes))
+	if config.Config.StopSignal != "" {
+		fmt.Printf("  StopSignal:  %s\n", config.Config.StopSignal)
+	}
+	if check := config.Config.Healthcheck; check != nil && len(check.Test) > 0 {
+		fmt.Printf("  Healthcheck: %s (interval %s, timeout %s, retries %d)\n", execForm(check.Test), check.Interval, check.Timeout, check.Retries)
+	}
+
+	fmt.Println("\n🌱 Env:")
+	if len(config.Config.Env) == 0 {
+		fmt.Println("  (none)")
+	}
+	for _, variable := range config.Config.Env {
+		fmt.Printf("  %s\n", variable)
+	}
+	fmt.Println("\n🏷️  Labels:")
+	printLabels(config.Config.Labels, true)
+
+	width := 90
+	if full {
+		width = 1 << 20
+	}
+	fmt.Printf("\n📜 History (%d step(s)):\n", len(config.History))
+	fmt.Printf("%-3s %-16s %10s  %s\n", "#", "CREATED", "SIZE", "INSTRUCTION")
+	layer := 0
+	for i, entry := range config.History {
+		size := "-"
+		if !entry.EmptyLayer {
+			if layer < len(image.Layers) {
+				size = formatBytes(image.Layers[layer].Size)
+			}
+			layer++
+		}
+		instruction := instructionFromHistory(entry.CreatedBy)
+		if entry.Comment != "" {
+			instruction += "  # " + entry.Comment
+		}
+		fmt.Printf("%-3d %-16s %10s  %s\n", i+1, entry.Created.Format("2006-01-02 15:04"), size, truncate(instruction, width))
+	}
+	if layer != len(image.Layers) {
+		fmt.Println("⚠️  The image history does not match its layers; sizes may be attributed wrongly")
+	}
+}
+
+func newImageInspectCommand() *Command {
+	cmd := newCommand("inspect", "<tag>", "Show a tag's config (entrypoint, cmd, env, user, ports, labels) and build history without pulling its layers.")
+	platform := cmd.Flags.String("platform", defaultPlatform, "platform image to read from a manifest list")
+	format := cmd.Flags.String("format", "text", "output format: text or json (the config blob as stored)")
+	full := cmd.Flags.Bool("full", false, "do not shorten long history instructions")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 1); err != nil {
+			return err
+		}
+		if *format != "text" && *format != "json" {
+			return fmt.Errorf("%w: unknown format %q (want text or json)", errUsage, *format)
+		}
+		registry := newRegistryClient(config.Registry, imageRepository())
+		image, err := inspectImage(registry, args[0], *platform)
+		if err != nil {
+			return err
+		}
+		if *format == "json" {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(image)
+		}
+		fmt.Printf("\n🔍 %s (%s)\n", image.Reference, image.Platform)
+		printImageInspection(image, *full)
+		return nil
+	}
+	return cmd
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// integrationComposeFile runs the server image the way Railway does. The
+// port is published on an ephemeral host port so parallel CI jobs do not
+// collide.
+const integrationComposeFile = `# Generated by strunzctl test integration
+services:
+  server:
+    %s
+    platform: %s
+    environment:
+      PORT: "8000"
+      TRANSPORT: sse
+    ports:
+      - "127.0.0.1::8000"
+`
+
+func newTestCommand() *Command {
+	return newGroup("test", "Run the server in containers and test it end to end, or serve a fake API to test the commands against.",
+		newTestIntegrationCommand(),
+		newTestFakeAPICommand(),
+	)
+}
+
+// composeProject is a compose project brought up by the integration test
+type composeProject struct {
+	command []string
+	name    string
+	file    string
+	dir     string
+}
+
+// findCompose returns the compose CLI: the docker plugin, or the standalone
+// docker-compose of older installations
+func findCompose() ([]string, error) {
+	if newProcess("docker", "compose", "version").Run() == nil {
+		return []string{"docker", "compose"}, nil
+	}
+	if path, err := exec.LookPath("docker-compose"); err == nil {
+		return []string{path}, nil
+	}
+	return nil, fmt.Errorf("docker compose is not installed; see https://docs.docker.com/compose/install/")
+}
+
+func (p *composeProject) run(args ...string) ([]byte, error) {
+	return p.output(newProcess, args)
+}
+
+// down removes the project; unlike run it works after an interrupt, too
+func (p *composeProject) down(args ...string) ([]byte, error) {
+	return p.output(exec.Command, append([]string{"down"}, args...))
+}
+
+func (p *composeProject) output(command func(string, ...string) *exec.Cmd, args []string) ([]byte, error) {
+	full := append(append(p.command[1:len(p.command):len(p.command)], "--project-name", p.name, "--file", p.file), args...)
+	cmd := command(p.command[0], full...)
+	cmd.Dir = p.dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return output, fmt.Errorf("%s %s failed: %w: %s", strings.Join(p.command, " "), args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return output, nil
+}
+
+// publishedURL is the host address compose published a service port on
+func (p *composeProject) publishedURL(service string, port int) (string, error) {
+	output, err := p.run("port", service, strconv.Itoa(port))
+	if err != nil {
+		return "", err
+	}
+	address := strings.TrimSpace(string(output))
+	if address == "" || strings.HasSuffix(address, ":0") {
+		return "", fmt.Errorf("service %s does not publish port %d", service, port)
+	}
+	// Compose reports 0.0.0.0 for ports published on all interfaces
+	address = strings.Replace(address, "0.0.0.0:", "127.0.0.1:", 1)
+	return "http://" + address, nil
+}
+
+func newTestIntegrationCommand() *Command {
+	cmd := newCommand("integration", "[tag]", "Bring up the server image and its dependencies with docker compose, wait for health, run the smoke suite and every advertised tool, then tear everything down.")
+	composeFile := cmd.Flags.String("compose-file", "", "compose file with the server and its dependencies (default: the server image alone)")
+	service := cmd.Flags.String("service", "server", "compose service running the MCP server")
+	containerPort := cmd.Flags.Int("container-port", 8000, "port the server listens on inside its container")
+	build := cmd.Flags.Bool("build", false, "build the image from the repository's Dockerfile instead of pulling the tag")
+	platform := cmd.Flags.String("platform", defaultPlatform, "platform of the image to run")
+	startup := cmd.Flags.Duration("startup-timeout", 5*time.Minute, "how long the server may take to become healthy")
+	timeout := cmd.Flags.Duration("timeout", 60*time.Second, "timeout for connecting and for each tool call")
+	only := cmd.Flags.String("only", "", "comma-separated tools to call (default: all advertised)")
+	skip := cmd.Flags.String("skip", "", "comma-separated tools not to call")
+	argsFile := cmd.Flags.String("args-file", "", "JSON object mapping tool names to arguments, overriding the canned ones")
+	junit := cmd.Flags.String("junit", "", "write a JUnit XML report to this file")
+	logs := cmd.Flags.String("logs", "", "write the logs of all services to this file when the test fails")
+	keep := cmd.Flags.Bool("keep", false, "leave the services running afterwards")
+
+	cmd.Run = func(args []string) error {
+		if len(args) > 1 {
+			return cmd.ExactArgs(args, 1)
+		}
+		tag := "latest"
+		if len(args) == 1 {
+			tag = args[0]
+		}
+		overrides, err := loadToolArguments(*argsFile)
+		if err != nil {
+			return err
+		}
+		compose, err := findCompose()
+		if err != nil {
+			return err
+		}
+		registry := newRegistryClient(config.Registry, imageRepository())
+
+		project := &composeProject{command: compose, name: fmt.Sprintf("strunzctl-it-%d", os.Getpid())}
+		target := registry.Reference(tag)
+		if *composeFile != "" {
+			if project.file, err = filepath.Abs(*composeFile); err != nil {
+				return err
+			}
+			target = *composeFile
+		} else {
+			work, err := os.MkdirTemp("", "strunzctl-it-")
+			if err != nil {
+				return err
+			}
+			defer os.RemoveAll(work)
+			source := "image: " + registry.Reference(tag)
+			if *build {
+				root, err := repoPath("")
+				if err != nil {
+					return err
+				}
+				source = fmt.Sprintf("build: {context: %s, dockerfile: Dockerfile}", strconv.Quote(root))
+				target = "the repository's Dockerfile"
+			}
+			project.file = filepath.Join(work, "compose.yaml")
+			if err := os.WriteFile(project.file, []byte(fmt.Sprintf(integrationComposeFile, source, *platform)), 0o644); err != nil {
+				return err
+			}
+		}
+		// Relative paths in a compose file are relative to the file
+		project.dir = filepath.Dir(project.file)
+
+		// Ctrl-C must not leave containers behind: it fails the running
+		// step, and the deferred teardown follows
+		defer func() {
+			if *keep {
+				fmt.Printf("\n⏸️  Left %s running; stop it with: %s --project-name %s --file %s down --volumes\n",
+					project.name, strings.Join(compose, " "), project.name, project.file)
+				return
+			}
+			slog.Info("Tearing down", "project", project.name)
+			if _, err := project.down("--volumes", "--remove-orphans", "--timeout", "10"); err != nil {
+				slog.Warn("Teardown failed", "error", err)
+			}
+		}()
+
+		fmt.Printf("\n🐳 Starting %s as compose project %s\n", target, project.name)
+		upArgs := []string{"up", "--detach", "--remove-orphans"}
+		if *build {
+			upArgs = append(upArgs, "--build")
+		}
+		if _, err := project.run(upArgs...); err != nil {
+			collectComposeLogs(project, *logs)
+			return err
+		}
+		url, err := project.publishedURL(*service, *containerPort)
+		if err != nil {
+			collectComposeLogs(project, *logs)
+			return err
+		}
+		fmt.Printf("Waiting for %s/health (up to %s)\n", url, *startup)
+		start := time.Now()
+		if err := waitHealthy(url, *startup); err != nil {
+			collectComposeLogs(project, *logs)
+			return err
+		}
+		fmt.Printf("✅ Healthy after %s\n", time.Since(start).Round(time.Second))
+
+		version := ""
+		if _, ok := parseSemver(tag); ok && *composeFile == "" && !*build {
+			version = tag
+		}
+		fmt.Printf("\n🚦 Smoke tests against %s\n", url)
+		checks := runSmokeTests(url, version, smokeToolCalls)
+		failed := printSmokeChecks(checks)
+
+		if mcp, err := connectMCP(url, *timeout); err != nil {
+			checks = append(checks, smokeCheck{Name: "mcp tools", Err: err})
+			failed++
+		} else {
+			var tools []MCPTool
+			_, err := mcp.Initialize(commandContext)
+			if err == nil {
+				tools, err = mcp.ListTools(commandContext)
+			}
+			if err != nil {
+				checks = append(checks, smokeCheck{Name: "mcp tools", Err: fmt.Errorf("%s", describeMCPError(err))})
+				failed++
+			} else {
+				fmt.Printf("\n🧪 Calling %d advertised tool(s)\n\n", len(tools))
+				for _, check := range smokeTools(mcp, tools, splitList(*only), splitList(*skip), overrides) {
+					checks = append(checks, check)
+					if check.Err != nil {
+						failed++
+					}
+				}
+			}
+			mcp.Close()
+		}
+
+		if *junit != "" {
+			if err := writeJUnitReport(*junit, "test integration "+tag, checks); err != nil {
+				return err
+			}
+			fmt.Printf("\nJUnit report written to %s\n", *junit)
+		}
+		if failed > 0 {
+			collectComposeLogs(project, *logs)
+			return fmt.Errorf("%w: %d of %d integration check(s) failed", errPolicy, failed, len(checks))
+		}
+		fmt.Printf("\n✅ All %d integration check(s) passed\n", len(checks))
+		return nil
+	}
+	return cmd
+}
+
+// collectComposeLogs prints the last log lines of every service and writes
+// all of them to path, if given
+func collectComposeLogs(project *composeProject, path string) {
+	output, err := project.run("logs", "--no-color", "--timestamps", "--tail", "50")
+	if err != nil {
+		slog.Warn("Failed to collect logs", "error", err)
+		return
+	}
+	fmt.Printf("\nLast log lines of %s:\n%s\n", project.name, output)
+	if path == "" {
+		return
+	}
+	all, err := project.run("logs", "--no-color", "--timestamps")
+	if err == nil {
+		err = os.WriteFile(path, all, 0o644)
+	}
+	if err != nil {
+		slog.Warn("Failed to write logs", "path", path, "error", err)
+		return
+	}
+	fmt.Printf("Logs of all services written to %s\n", path)
+}
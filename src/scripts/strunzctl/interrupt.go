@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+var (
+	// errInterrupted is the cause of commands stopped by SIGINT or SIGTERM
+	errInterrupted = errors.New("interrupted")
+	// errTimeout is the cause of commands stopped by --timeout
+	errTimeout = errors.New("timed out")
+)
+
+// commandContext is cancelled by SIGINT, SIGTERM and --timeout. Every HTTP
+// request, retry delay, poll and subprocess of a command is bound to it.
+var commandContext = context.Background()
+
+// startCommandContext replaces commandContext with one that ends on the
+// first SIGINT or SIGTERM or after timeout, unless it is 0. A second
+// signal kills the process as usual, in case a command ignores the first.
+func startCommandContext(timeout time.Duration) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case sig := <-signals:
+			slog.Warn("Stopping, signal again to force", "signal", sig)
+			cancel(errInterrupted)
+		case <-ctx.Done():
+		}
+		signal.Stop(signals)
+	}()
+	if timeout > 0 {
+		time.AfterFunc(timeout, func() {
+			cancel(fmt.Errorf("%w after %s", errTimeout, timeout))
+		})
+	}
+	commandContext = ctx
+}
+
+// interruption returns err marked with the reason commandContext ended, so
+// the exit code tells an interrupt or timeout from the failure it caused
+func interruption(err error) error {
+	cause := context.Cause(commandContext)
+	if err == nil || cause == nil || errors.Is(err, cause) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", cause, err)
+}
+
+// sleepContext waits for d unless commandContext ends first
+func sleepContext(d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-commandContext.Done():
+		return context.Cause(commandContext)
+	}
+}
+
+// newProcess is exec.Command bound to commandContext: when it ends, the
+// process gets SIGINT to clean up and is killed 10s later. Cleanup that
+// must also run after an interrupt, such as removing a container, uses
+// exec.Command.
+func newProcess(name string, args ...string) *exec.Cmd {
+	return newProcessContext(commandContext, name, args...)
+}
+
+// newProcessContext is newProcess bound to ctx, which should derive from
+// commandContext, such as one with a timeout
+func newProcessContext(ctx context.Context, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(os.Interrupt)
+	}
+	cmd.WaitDelay = 10 * time.Second
+	return cmd
+}
+
+// contextTransport binds every request to commandContext as well as to its
+// own context, so interrupts abort the requests of every client, streams
+// included
+type contextTransport struct {
+	base http.RoundTripper
+}
+
+func (t contextTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := context.Cause(commandContext); err != nil {
+		return nil, err
+	}
+	if globalOptions.offline {
+		// Whatever reaches the network was not answered from the cache
+		return nil, fmt.Errorf("%w: no cached response for %s %s, run the command online once to cache it", errOffline, req.Method, req.URL.Redacted())
+	}
+	ctx, cancel := context.WithCancelCause(req.Context())
+	stop := context.AfterFunc(commandContext, func() {
+		cancel(context.Cause(commandContext))
+	})
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		stop()
+		cancel(nil)
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusSwitchingProtocols {
+		// The body is the connection, which must stay writable
+		return resp, nil
+	}
+	resp.Body = &contextBody{ReadCloser: resp.Body, release: func() {
+		stop()
+		cancel(nil)
+	}}
+	return resp, nil
+}
+
+// contextBody releases the request context once the body is closed
+type contextBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *contextBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
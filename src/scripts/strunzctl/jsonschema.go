@@ -0,0 +1,333 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// jsonSchema is the subset of JSON Schema (draft 2020-12) that tool
+// schemas generated by pydantic and FastMCP use
+type jsonSchema struct {
+	Type                 schemaTypes            `json:"type"`
+	Properties           map[string]*jsonSchema `json:"properties"`
+	Required             []string               `json:"required"`
+	AdditionalProperties *jsonSchema            `json:"additionalProperties"`
+	Items                *jsonSchema            `json:"items"`
+	Enum                 []any                  `json:"enum"`
+	Const                *any                   `json:"const"`
+	Minimum              *float64               `json:"minimum"`
+	Maximum              *float64               `json:"maximum"`
+	ExclusiveMinimum     *float64               `json:"exclusiveMinimum"`
+	ExclusiveMaximum     *float64               `json:"exclusiveMaximum"`
+	MinLength            *int                   `json:"minLength"`
+	MaxLength            *int                   `json:"maxLength"`
+	Pattern              string                 `json:"pattern"`
+	MinItems             *int                   `json:"minItems"`
+	MaxItems             *int                   `json:"maxItems"`
+	AnyOf                []*jsonSchema          `json:"anyOf"`
+	OneOf                []*jsonSchema          `json:"oneOf"`
+	AllOf                []*jsonSchema          `json:"allOf"`
+	If                   *jsonSchema            `json:"if"`
+	Then                 *jsonSchema            `json:"then"`
+	Else                 *jsonSchema            `json:"else"`
+	Ref                  string                 `json:"$ref"`
+	Defs                 map[string]*jsonSchema `json:"$defs"`
+	Definitions          map[string]*jsonSchema `json:"definitions"`
+
+	// never is set for the schema false, which matches nothing
+	never bool
+}
+
+// schemaTypes accepts "type" as a string or a list of strings
+type schemaTypes []string
+
+func (t *schemaTypes) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*t = schemaTypes{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return fmt.Errorf("type must be a string or a list of strings")
+	}
+	*t = many
+	return nil
+}
+
+// UnmarshalJSON also accepts the boolean schemas true and false
+func (s *jsonSchema) UnmarshalJSON(data []byte) error {
+	switch string(bytes.TrimSpace(data)) {
+	case "true":
+		*s = jsonSchema{}
+		return nil
+	case "false":
+		*s = jsonSchema{never: true}
+		return nil
+	}
+	type plain jsonSchema
+	return json.Unmarshal(data, (*plain)(s))
+}
+
+// parseJSONSchema parses a schema and checks its patterns and references
+func parseJSONSchema(data []byte) (*jsonSchema, error) {
+	var schema jsonSchema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	validator := schemaValidator{root: &schema}
+	if err := validator.check(&schema, "#"); err != nil {
+		return nil, err
+	}
+	return &schema, nil
+}
+
+// validateJSON returns the violations of a JSON document against schema,
+// each prefixed with the JSON pointer of the offending value
+func validateJSON(schema *jsonSchema, data []byte) ([]string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	validator := schemaValidator{root: schema}
+	validator.validate(schema, value, "")
+	return validator.errors, nil
+}
+
+type schemaValidator struct {
+	root   *jsonSchema
+	errors []string
+	depth  int
+}
+
+// check verifies a schema before use, so validation cannot fail halfway
+func (v *schemaValidator) check(s *jsonSchema, at string) error {
+	if s == nil {
+		return nil
+	}
+	if s.Pattern != "" {
+		if _, err := regexp.Compile(s.Pattern); err != nil {
+			return fmt.Errorf("%s: pattern %q: %w", at, s.Pattern, err)
+		}
+	}
+	if s.Ref != "" && v.resolve(s.Ref) == nil {
+		return fmt.Errorf("%s: unresolvable $ref %q", at, s.Ref)
+	}
+	for name, property := range s.Properties {
+		if err := v.check(property, at+"/properties/"+name); err != nil {
+			return err
+		}
+	}
+	for _, sub := range []*jsonSchema{s.AdditionalProperties, s.Items, s.If, s.Then, s.Else} {
+		if err := v.check(sub, at); err != nil {
+			return err
+		}
+	}
+	for _, list := range [][]*jsonSchema{s.AnyOf, s.OneOf, s.AllOf} {
+		for _, sub := range list {
+			if err := v.check(sub, at); err != nil {
+				return err
+			}
+		}
+	}
+	for name, def := range s.Defs {
+		if err := v.check(def, at+"/$defs/"+name); err != nil {
+			return err
+		}
+	}
+	for name, def := range s.Definitions {
+		if err := v.check(def, at+"/definitions/"+name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolve looks up a local reference such as #/$defs/Result
+func (v *schemaValidator) resolve(ref string) *jsonSchema {
+	switch {
+	case ref == "#":
+		return v.root
+	case strings.HasPrefix(ref, "#/$defs/"):
+		return v.root.Defs[strings.TrimPrefix(ref, "#/$defs/")]
+	case strings.HasPrefix(ref, "#/definitions/"):
+		return v.root.Definitions[strings.TrimPrefix(ref, "#/definitions/")]
+	}
+	return nil
+}
+
+func (v *schemaValidator) fail(at, format string, args ...any) {
+	if at == "" {
+		at = "(root)"
+	}
+	v.errors = append(v.errors, fmt.Sprintf("%s: %s", at, fmt.Sprintf(format, args...)))
+}
+
+// matches validates value on a scratch validator and reports success
+func (v *schemaValidator) matches(s *jsonSchema, value any, at string) bool {
+	scratch := schemaValidator{root: v.root, depth: v.depth}
+	scratch.validate(s, value, at)
+	return len(scratch.errors) == 0
+}
+
+func (v *schemaValidator) validate(s *jsonSchema, value any, at string) {
+	if s == nil {
+		return
+	}
+	if s.never {
+		v.fail(at, "not allowed")
+		return
+	}
+	if s.Ref != "" {
+		// Recursive schemas are fine, recursive documents this deep are not
+		if v.depth++; v.depth > 64 {
+			v.fail(at, "$ref nested too deeply")
+			return
+		}
+		v.validate(v.resolve(s.Ref), value, at)
+		v.depth--
+	}
+
+	if len(s.Type) > 0 && !slices.ContainsFunc(s.Type, func(t string) bool { return hasJSONType(value, t) }) {
+		v.fail(at, "is %s, expected %s", jsonTypeOf(value), strings.Join(s.Type, " or "))
+		return
+	}
+	if len(s.Enum) > 0 && !slices.ContainsFunc(s.Enum, func(e any) bool { return jsonEqual(e, value) }) {
+		v.fail(at, "%s is not one of the allowed values", truncate(fmt.Sprint(value), 40))
+	}
+	if s.Const != nil && !jsonEqual(*s.Const, value) {
+		v.fail(at, "must be %v", *s.Const)
+	}
+
+	switch value := value.(type) {
+	case json.Number:
+		n, _ := value.Float64()
+		if s.Minimum != nil && n < *s.Minimum {
+			v.fail(at, "%v is less than the minimum %v", value, *s.Minimum)
+		}
+		if s.Maximum != nil && n > *s.Maximum {
+			v.fail(at, "%v is greater than the maximum %v", value, *s.Maximum)
+		}
+		if s.ExclusiveMinimum != nil && n <= *s.ExclusiveMinimum {
+			v.fail(at, "%v must be greater than %v", value, *s.ExclusiveMinimum)
+		}
+		if s.ExclusiveMaximum != nil && n >= *s.ExclusiveMaximum {
+			v.fail(at, "%v must be less than %v", value, *s.ExclusiveMaximum)
+		}
+	case string:
+		length := utf8.RuneCountInString(value)
+		if s.MinLength != nil && length < *s.MinLength {
+			v.fail(at, "is shorter than %d characters", *s.MinLength)
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			v.fail(at, "is longer than %d characters", *s.MaxLength)
+		}
+		if s.Pattern != "" && !regexp.MustCompile(s.Pattern).MatchString(value) {
+			v.fail(at, "does not match %s", s.Pattern)
+		}
+	case []any:
+		if s.MinItems != nil && len(value) < *s.MinItems {
+			v.fail(at, "has %d items, expected at least %d", len(value), *s.MinItems)
+		}
+		if s.MaxItems != nil && len(value) > *s.MaxItems {
+			v.fail(at, "has %d items, expected at most %d", len(value), *s.MaxItems)
+		}
+		for i, item := range value {
+			v.validate(s.Items, item, fmt.Sprintf("%s/%d", at, i))
+		}
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := value[name]; !ok {
+				v.fail(at, "missing required property %q", name)
+			}
+		}
+		for name, property := range value {
+			if schema, ok := s.Properties[name]; ok {
+				v.validate(schema, property, at+"/"+name)
+			} else {
+				v.validate(s.AdditionalProperties, property, at+"/"+name)
+			}
+		}
+	}
+
+	for _, sub := range s.AllOf {
+		v.validate(sub, value, at)
+	}
+	if len(s.AnyOf) > 0 && !slices.ContainsFunc(s.AnyOf, func(sub *jsonSchema) bool { return v.matches(sub, value, at) }) {
+		v.fail(at, "matches none of the anyOf schemas")
+	}
+	if s.If != nil {
+		if v.matches(s.If, value, at) {
+			v.validate(s.Then, value, at)
+		} else {
+			v.validate(s.Else, value, at)
+		}
+	}
+	if len(s.OneOf) > 0 {
+		matched := 0
+		for _, sub := range s.OneOf {
+			if v.matches(sub, value, at) {
+				matched++
+			}
+		}
+		if matched != 1 {
+			v.fail(at, "matches %d of the oneOf schemas, expected exactly 1", matched)
+		}
+	}
+}
+
+// hasJSONType reports whether a decoded value has a JSON Schema type
+func hasJSONType(value any, t string) bool {
+	switch t {
+	case "integer":
+		n, ok := value.(json.Number)
+		if !ok {
+			return false
+		}
+		f, err := n.Float64()
+		return err == nil && f == math.Trunc(f)
+	case "number":
+		_, ok := value.(json.Number)
+		return ok
+	}
+	return jsonTypeOf(value) == t
+}
+
+func jsonTypeOf(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// jsonEqual compares a schema value with a decoded document value, which
+// holds numbers as json.Number
+func jsonEqual(a, b any) bool {
+	normalize := func(v any) any {
+		data, _ := json.Marshal(v)
+		var out any
+		json.Unmarshal(data, &out)
+		return out
+	}
+	return reflect.DeepEqual(normalize(a), normalize(b))
+}
@@ -0,0 +1,29 @@
+package main
+
+import "path/filepath"
+
+func newKBCommand() *Command {
+	return newGroup("kb", "Check and maintain the knowledge base: scraped content, processed documents and the FAISS index.",
+		newKBVerifyIndexCommand(),
+		newKBProcessCommand(),
+		newKBDedupCommand(),
+		newKBStatsCommand(),
+		newKBValidateCommand(),
+		newKBExportCommand(),
+		newKBLinkCheckCommand(),
+		newKBBooksCommand(),
+		newKBEvalCommand(),
+		newKBRebuildCommand(),
+		newKBBM25Command(),
+		newKBDiffCommand(),
+	)
+}
+
+// repoPath resolves a path relative to the repository root
+func repoPath(path string) (string, error) {
+	root, err := runGit("rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(root, path), nil
+}
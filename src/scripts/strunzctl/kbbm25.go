@@ -0,0 +1,312 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// bm25Stopwords are left out of the index; they match nearly every chunk
+// and only slow queries down
+var bm25Stopwords = func() map[string]bool {
+	words := make(map[string]bool)
+	for _, word := range strings.Fields(`der die das den dem des ein eine einen einem einer eines und oder aber
+		ist sind war sein nicht auch auf aus bei mit nach von vor zu zum zur im in an am als wie so es er sie wir ihr
+		ich du man sich dass daß was wer wenn noch nur schon sehr mehr doch ja nein hat haben wird werden kann
+		the a an and or of to in on for is are was be it this that with as by at from`) {
+		words[foldTerm(word)] = true
+	}
+	return words
+}()
+
+var umlautSpelling = strings.NewReplacer("ä", "ae", "ö", "oe", "ü", "ue", "ß", "ss")
+
+// foldTerm lowercases a word and spells umlauts and ß out, so Müsli,
+// MUESLI and muesli are one term
+func foldTerm(word string) string {
+	return umlautSpelling.Replace(strings.ToLower(word))
+}
+
+// bm25Tokens splits text into folded terms. Decimal numbers stay whole, 2,5
+// and 0.5 are terms, so are mixed ones like d3 and b12.
+func bm25Tokens(text string) []string {
+	var tokens []string
+	runes := []rune(text)
+	start := -1
+	for i, r := range runes {
+		inWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		// A separator between two digits belongs to the number
+		if !inWord && (r == ',' || r == '.') && start >= 0 && i+1 < len(runes) && unicode.IsDigit(runes[i-1]) && unicode.IsDigit(runes[i+1]) {
+			inWord = true
+		}
+		switch {
+		case inWord && start < 0:
+			start = i
+		case !inWord && start >= 0:
+			tokens = append(tokens, foldTerm(strings.ReplaceAll(string(runes[start:i]), ",", ".")))
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, foldTerm(strings.ReplaceAll(string(runes[start:]), ",", ".")))
+	}
+	return tokens
+}
+
+type bm25Posting struct {
+	doc  int32
+	freq int32
+}
+
+// bm25Index is an in-memory inverted index of the corpus, titles and text
+type bm25Index struct {
+	corpus   *corpus
+	postings map[string][]bm25Posting
+	lengths  []int32
+	average  float64
+	k1, b    float64
+}
+
+func newBM25Index(c *corpus, k1, b float64) *bm25Index {
+	index := &bm25Index{corpus: c, postings: make(map[string][]bm25Posting), lengths: make([]int32, len(c.Documents)), k1: k1, b: b}
+	total := 0
+	for i := range c.Documents {
+		doc := &c.Documents[i]
+		freqs := make(map[string]int32)
+		for _, token := range bm25Tokens(documentTitle(doc) + "\n" + doc.Text) {
+			if !bm25Stopwords[token] {
+				freqs[token]++
+				index.lengths[i]++
+			}
+		}
+		for term, freq := range freqs {
+			index.postings[term] = append(index.postings[term], bm25Posting{doc: int32(i), freq: freq})
+		}
+		total += int(index.lengths[i])
+	}
+	if len(c.Documents) > 0 {
+		index.average = float64(total) / float64(len(c.Documents))
+	}
+	return index
+}
+
+// documentTitle is the title of a document, from the document or its metadata
+func documentTitle(doc *corpusDocument) string {
+	if doc.Title != "" {
+		return doc.Title
+	}
+	return doc.meta("title")
+}
+
+// bm25Query is a parsed query: terms to rank by, and "quoted phrases" a
+// hit must contain
+type bm25Query struct {
+	terms   []string
+	phrases [][]string
+}
+
+func parseBM25Query(query string) bm25Query {
+	var parsed bm25Query
+	parts := strings.Split(query, `"`)
+	for i, part := range parts {
+		tokens := bm25Tokens(part)
+		// Odd parts are inside quotes; an unclosed quote ranks as words
+		if i%2 == 1 && i < len(parts)-1 && len(tokens) > 1 {
+			parsed.phrases = append(parsed.phrases, tokens)
+		}
+		for _, token := range tokens {
+			if !bm25Stopwords[token] && !slices.Contains(parsed.terms, token) {
+				parsed.terms = append(parsed.terms, token)
+			}
+		}
+	}
+	return parsed
+}
+
+// bm25Hit is one ranked document of a keyword search
+type bm25Hit struct {
+	Rank    int     `json:"rank"`
+	ID      string  `json:"id"`
+	Index   int     `json:"index"`
+	Score   float64 `json:"score"`
+	Source  string  `json:"source"`
+	Title   string  `json:"title,omitempty"`
+	URL     string  `json:"url,omitempty"`
+	Snippet string  `json:"snippet"`
+}
+
+// containsPhrase reports whether the tokens of a text contain phrase in order
+func containsPhrase(tokens, phrase []string) bool {
+	for i := 0; i+len(phrase) <= len(tokens); i++ {
+		if slices.Equal(tokens[i:i+len(phrase)], phrase) {
+			return true
+		}
+	}
+	return false
+}
+
+// search ranks the documents matching any term by BM25, optionally only
+// those of a source
+func (x *bm25Index) search(query bm25Query, source string, limit int) (hits []bm25Hit, matched int) {
+	scores := make(map[int32]float64)
+	n := float64(len(x.lengths))
+	for _, term := range query.terms {
+		postings := x.postings[term]
+		if len(postings) == 0 {
+			continue
+		}
+		idf := math.Log(1 + (n-float64(len(postings))+0.5)/(float64(len(postings))+0.5))
+		for _, p := range postings {
+			freq := float64(p.freq)
+			norm := x.k1 * (1 - x.b + x.b*float64(x.lengths[p.doc])/x.average)
+			scores[p.doc] += idf * freq * (x.k1 + 1) / (freq + norm)
+		}
+	}
+
+	type scored struct {
+		doc   int32
+		score float64
+	}
+	ranked := make([]scored, 0, len(scores))
+	for doc, score := range scores {
+		d := &x.corpus.Documents[doc]
+		if source != "" && !strings.EqualFold(d.meta("source"), source) {
+			continue
+		}
+		ranked = append(ranked, scored{doc, score})
+	}
+	slices.SortFunc(ranked, func(a, b scored) int {
+		if a.score != b.score {
+			return cmp.Compare(b.score, a.score)
+		}
+		return int(a.doc - b.doc)
+	})
+
+	for _, r := range ranked {
+		doc := &x.corpus.Documents[r.doc]
+		if len(query.phrases) > 0 {
+			tokens := bm25Tokens(documentTitle(doc) + "\n" + doc.Text)
+			if !slices.ContainsFunc(query.phrases, func(phrase []string) bool { return containsPhrase(tokens, phrase) }) {
+				continue
+			}
+		}
+		matched++
+		if len(hits) < limit {
+			hits = append(hits, bm25Hit{Rank: len(hits) + 1, ID: doc.id(int(r.doc)), Index: int(r.doc), Score: math.Round(r.score*1000) / 1000,
+				Source: doc.meta("source"), Title: documentTitle(doc), URL: doc.meta("url"), Snippet: truncate(strings.Join(strings.Fields(doc.Text), " "), 200)})
+		}
+	}
+	return hits, matched
+}
+
+// bm25Response is the JSON of GET /search
+type bm25Response struct {
+	Query   string    `json:"query"`
+	Source  string    `json:"source,omitempty"`
+	Matched int       `json:"matched"`
+	TookMS  float64   `json:"took_ms"`
+	Results []bm25Hit `json:"results"`
+}
+
+func (x *bm25Index) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	respond := func(status int, v any) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		encoder := json.NewEncoder(w)
+		encoder.SetEscapeHTML(false)
+		encoder.Encode(v)
+	}
+	switch r.URL.Path {
+	case "/health":
+		respond(http.StatusOK, map[string]any{"status": "ok", "documents": len(x.lengths), "terms": len(x.postings), "corpus": x.corpus.path})
+	case "/search":
+		query := strings.TrimSpace(r.URL.Query().Get("q"))
+		limit := 10
+		if value := r.URL.Query().Get("limit"); value != "" {
+			var err error
+			if limit, err = strconv.Atoi(value); err != nil || limit < 1 || limit > 100 {
+				respond(http.StatusBadRequest, map[string]string{"error": "limit must be a number from 1 to 100"})
+				return
+			}
+		}
+		if query == "" {
+			respond(http.StatusBadRequest, map[string]string{"error": "missing query parameter q"})
+			return
+		}
+		start := time.Now()
+		source := r.URL.Query().Get("source")
+		hits, matched := x.search(parseBM25Query(query), source, limit)
+		took := float64(time.Since(start).Microseconds()) / 1000
+		slog.Debug("Keyword search", "query", query, "source", source, "matched", matched, "took_ms", took)
+		respond(http.StatusOK, bm25Response{Query: query, Source: source, Matched: matched, TookMS: took, Results: append([]bm25Hit{}, hits...)})
+	default:
+		respond(http.StatusNotFound, map[string]string{"error": "the keyword index serves /search and /health"})
+	}
+}
+
+func newKBBM25Command() *Command {
+	cmd := newCommand("bm25", "[query]", "Serve a BM25 keyword index of the corpus over HTTP for hybrid ranking with the vector search, or run one query.")
+	metadata := addCorpusFlag(cmd)
+	listen := cmd.Flags.String("listen", "127.0.0.1:8765", "address to serve /search and /health on")
+	limit := cmd.Flags.Int("limit", 10, "results of a query given as argument")
+	source := cmd.Flags.String("source", "", "only documents of this source (news, forum or book) for a query given as argument")
+	k1 := cmd.Flags.Float64("k1", 1.2, "BM25 term frequency saturation")
+	b := cmd.Flags.Float64("b", 0.75, "BM25 document length normalization, 0 to 1")
+
+	cmd.Run = func(args []string) error {
+		if len(args) > 1 {
+			return cmd.ExactArgs(args, 1)
+		}
+		if *k1 < 0 || *b < 0 || *b > 1 {
+			return fmt.Errorf("%w: --k1 must not be negative and --b must be from 0 to 1", errUsage)
+		}
+		c, err := loadCorpus(*metadata)
+		if err != nil {
+			return err
+		}
+		start := time.Now()
+		index := newBM25Index(c, *k1, *b)
+		slog.Info("Keyword index built", "documents", len(c.Documents), "terms", len(index.postings), "duration", time.Since(start).Round(time.Millisecond))
+
+		if len(args) == 1 {
+			hits, matched := index.search(parseBM25Query(args[0]), *source, max(*limit, 1))
+			fmt.Printf("\n🔎 %d document(s) match %q\n\n", matched, args[0])
+			for _, hit := range hits {
+				fmt.Printf("%2d. %6.2f  [%s] %s\n    %s\n", hit.Rank, hit.Score, hit.Source, orNone(hit.Title), hit.Snippet)
+				if hit.URL != "" {
+					fmt.Printf("    %s\n", hit.URL)
+				}
+			}
+			return nil
+		}
+
+		listener, err := net.Listen("tcp", *listen)
+		if err != nil {
+			return fmt.Errorf("failed to listen: %w", err)
+		}
+		server := &http.Server{Handler: index, ReadHeaderTimeout: 10 * time.Second}
+		ctx, stop := context.WithCancel(commandContext)
+		defer stop()
+		go func() {
+			<-ctx.Done()
+			server.Close()
+		}()
+		fmt.Printf("\n🔎 Serving the keyword index of %s on http://%s/search?q=... (Ctrl-C to stop)\n", c.path, listener.Addr())
+		if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	}
+	return cmd
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	// bookPageMarker is how src/rag/pdf_processor.py marks the pages it
+	// extracted; the chunker keeps the markers
+	bookPageMarker = regexp.MustCompile(`--- Page (\d+) ---`)
+	bookChapter    = regexp.MustCompile(`(?m)^\s*(?:Kapitel|KAPITEL|Chapter|CHAPTER)\s+(\d+)\b`)
+	// UTF-8 read as Latin-1 or Windows-1252: Ã¼ for ü, â€ž for „
+	bookMojibake = regexp.MustCompile(`Ã[\x{80}-\x{BF}]|â€|Â[\x{A0}-\x{BF}]`)
+	// Umlauts a PDF extractor wrote as vowel and diaeresis, which neither
+	// search nor the tokenizer treat as the umlaut
+	bookSplitUmlaut = regexp.MustCompile(`[aouAOU](?:\x{0308}|\x{00A8})|\x{00A8}[aouAOU]`)
+	// A word hyphenated across the end of the chunk
+	bookBrokenWord = regexp.MustCompile(`\p{L}-$`)
+)
+
+// bookProblem kinds; the first group blocks the index rebuild
+const (
+	bookEmpty        = "empty"
+	bookIndexGap     = "missing chunk_index"
+	bookIndexDup     = "duplicate chunk_index"
+	bookMojibakeText = "mojibake"
+	bookReplacement  = "replacement characters"
+	bookControl      = "control characters"
+	bookLowCoverage  = "low page coverage"
+
+	bookShort        = "short"
+	bookTruncated    = "truncated"
+	bookOversized    = "oversized"
+	bookUmlaut       = "decomposed umlauts"
+	bookPageOrder    = "pages out of order"
+	bookMissingChaps = "missing chapters"
+)
+
+var bookErrors = []string{bookEmpty, bookIndexGap, bookIndexDup, bookMojibakeText, bookReplacement, bookControl, bookLowCoverage}
+
+// bookReport is the validation result of one book
+type bookReport struct {
+	Title  string `json:"title"`
+	Year   string `json:"year,omitempty"`
+	File   string `json:"file"`
+	Chunks int    `json:"chunks"`
+	Chars  int    `json:"chars"`
+	// Pages is the highest page number seen, Covered how many of its pages
+	// have text in some chunk; both are 0 without page markers
+	Pages    int     `json:"pages"`
+	Covered  int     `json:"covered"`
+	Coverage float64 `json:"coverage"`
+	// Problems maps a problem kind to the chunks (or pages, chapters) it
+	// was found in
+	Problems map[string][]string `json:"problems,omitempty"`
+}
+
+func (r *bookReport) add(kind, where string) {
+	if r.Problems == nil {
+		r.Problems = make(map[string][]string)
+	}
+	r.Problems[kind] = append(r.Problems[kind], where)
+}
+
+func (r *bookReport) errors() int {
+	n := 0
+	for _, kind := range bookErrors {
+		n += len(r.Problems[kind])
+	}
+	return n
+}
+
+// compressRanges writes sorted numbers as ranges: 2-5, 9
+func compressRanges(numbers []int) []string {
+	var ranges []string
+	for i := 0; i < len(numbers); {
+		j := i
+		for j+1 < len(numbers) && numbers[j+1] == numbers[j]+1 {
+			j++
+		}
+		if i == j {
+			ranges = append(ranges, strconv.Itoa(numbers[i]))
+		} else {
+			ranges = append(ranges, fmt.Sprintf("%d-%d", numbers[i], numbers[j]))
+		}
+		i = j + 1
+	}
+	return ranges
+}
+
+// missingNumbers returns the numbers from first to last not in seen
+func missingNumbers(seen map[int]bool, first, last int) []int {
+	var missing []int
+	for n := first; n <= last; n++ {
+		if !seen[n] {
+			missing = append(missing, n)
+		}
+	}
+	return missing
+}
+
+type bookCheckOptions struct {
+	minChars, maxChars int
+	minCoverage        float64
+}
+
+// checkBook validates the chunks of one book, given in corpus order
+func checkBook(docs []*corpusDocument, ids []string, options bookCheckOptions) *bookReport {
+	first := docs[0]
+	report := &bookReport{Title: first.meta("title"), Year: first.meta("year"), File: first.meta("file_path"), Chunks: len(docs)}
+	if report.File == "" {
+		report.File = first.meta("filename")
+	}
+
+	type chunk struct {
+		index int
+		doc   *corpusDocument
+		id    string
+	}
+	chunks := make([]chunk, len(docs))
+	indexes := make(map[int]bool)
+	for i, doc := range docs {
+		index := atoiOrZero(doc.meta("chunk_index"))
+		if doc.meta("chunk_index") == "" {
+			index = i
+		}
+		chunks[i] = chunk{index: index, doc: doc, id: ids[i]}
+		if indexes[index] {
+			report.add(bookIndexDup, fmt.Sprintf("%d (%s)", index, ids[i]))
+		}
+		indexes[index] = true
+	}
+	slices.SortStableFunc(chunks, func(a, b chunk) int { return a.index - b.index })
+	if len(chunks) > 0 {
+		for _, gap := range compressRanges(missingNumbers(indexes, 0, chunks[len(chunks)-1].index)) {
+			report.add(bookIndexGap, gap)
+		}
+	}
+
+	pages := make(map[int]bool)
+	chapters := make(map[int]bool)
+	lastPage := 0
+	for i, c := range chunks {
+		text := c.doc.Text
+		report.Chars += len(text)
+
+		// What is left without page markers and page numbers is the content
+		content := strings.TrimSpace(bookPageMarker.ReplaceAllString(text, ""))
+		if strings.TrimFunc(content, func(r rune) bool { return unicode.IsSpace(r) || unicode.IsDigit(r) }) == "" {
+			report.add(bookEmpty, c.id)
+			continue
+		}
+		switch runes := len([]rune(content)); {
+		case runes < options.minChars:
+			report.add(bookShort, c.id)
+		case options.maxChars > 0 && runes > options.maxChars:
+			report.add(bookOversized, c.id)
+		}
+		// The last chunk of a book ends wherever the book does
+		if i < len(chunks)-1 && bookBrokenWord.MatchString(content) {
+			report.add(bookTruncated, c.id)
+		}
+
+		if bookMojibake.MatchString(text) {
+			report.add(bookMojibakeText, c.id)
+		}
+		if strings.ContainsRune(text, unicode.ReplacementChar) {
+			report.add(bookReplacement, c.id)
+		}
+		// PDF extraction leaves e.g. U+0002 where the book has a hyphen
+		if i := strings.IndexFunc(text, func(r rune) bool { return unicode.IsControl(r) && r != '\n' && r != '\r' && r != '\t' }); i >= 0 {
+			r, _ := utf8.DecodeRuneInString(text[i:])
+			report.add(bookControl, fmt.Sprintf("%s (%U)", c.id, r))
+		}
+		if bookSplitUmlaut.MatchString(text) {
+			report.add(bookUmlaut, c.id)
+		}
+
+		// Overlap repeats the last page of the previous chunk, so only a
+		// page before that is out of order
+		markers := bookPageMarker.FindAllStringSubmatch(text, -1)
+		for j, m := range markers {
+			page := atoiOrZero(m[1])
+			if j == 0 && page < lastPage-1 {
+				report.add(bookPageOrder, fmt.Sprintf("page %d after %d (%s)", page, lastPage, c.id))
+			}
+			pages[page] = true
+			lastPage = max(lastPage, page)
+		}
+		for _, m := range bookChapter.FindAllStringSubmatch(text, -1) {
+			chapters[atoiOrZero(m[1])] = true
+		}
+	}
+
+	if len(pages) > 0 {
+		report.Pages = slices.Max(slices.Collect(maps.Keys(pages)))
+		report.Covered = len(pages)
+		report.Coverage = float64(report.Covered) / float64(report.Pages)
+		missing := missingNumbers(pages, 1, report.Pages)
+		if report.Coverage < options.minCoverage {
+			report.add(bookLowCoverage, fmt.Sprintf("%.1f%%, pages without text: %s", 100*report.Coverage, strings.Join(compressRanges(missing), ", ")))
+		}
+	}
+	if len(chapters) > 0 {
+		last := slices.Max(slices.Collect(maps.Keys(chapters)))
+		for _, gap := range compressRanges(missingNumbers(chapters, 1, last)) {
+			report.add(bookMissingChaps, gap)
+		}
+	}
+	return report
+}
+
+func newKBBooksCommand() *Command {
+	cmd := newCommand("books", "", "Validate the processed book chunks before an index rebuild: chunk and page continuity, empty or truncated chunks, encoding, page coverage.")
+	metadata := addCorpusFlag(cmd)
+	minChars := cmd.Flags.Int("min-chars", 100, "chunks with less text are reported as short")
+	maxChars := cmd.Flags.Int("max-chars", 3000, "chunks with more text are reported as oversized; 0 disables the check (book_processor.py chunks at 1500)")
+	minCoverage := cmd.Flags.Float64("min-coverage", 85, "percentage of its pages a book must have text for")
+	examples := cmd.Flags.Int("examples", 3, "chunks to name per problem")
+	output := cmd.Flags.String("output", "", "write the report of every book to this JSON file")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 0); err != nil {
+			return err
+		}
+		if *minCoverage < 0 || *minCoverage > 100 {
+			return fmt.Errorf("%w: --min-coverage must be a percentage", errUsage)
+		}
+		c, err := loadCorpus(*metadata)
+		if err != nil {
+			return err
+		}
+
+		// Books are told apart by their file; the title comes from it
+		docs := make(map[string][]*corpusDocument)
+		ids := make(map[string][]string)
+		for i := range c.Documents {
+			doc := &c.Documents[i]
+			if source := doc.meta("source"); source != "book" && source != "books" {
+				continue
+			}
+			key := doc.meta("file_path")
+			if key == "" {
+				key = doc.meta("filename")
+			}
+			docs[key] = append(docs[key], doc)
+			ids[key] = append(ids[key], doc.id(i))
+		}
+		if len(docs) == 0 {
+			return fmt.Errorf("%w: %s has no book chunks", errUsage, c.path)
+		}
+		fmt.Printf("\n📚 Validating %d book(s) in %s\n\n", len(docs), c.path)
+
+		options := bookCheckOptions{minChars: *minChars, maxChars: *maxChars, minCoverage: *minCoverage / 100}
+		var reports []*bookReport
+		failed := 0
+		for _, key := range slices.Sorted(maps.Keys(docs)) {
+			report := checkBook(docs[key], ids[key], options)
+			reports = append(reports, report)
+
+			coverage := "no page markers"
+			if report.Pages > 0 {
+				coverage = fmt.Sprintf("%d/%d pages (%.1f%%)", report.Covered, report.Pages, 100*report.Coverage)
+			}
+			icon := "✅"
+			if report.errors() > 0 {
+				icon = "❌"
+				failed++
+			} else if len(report.Problems) > 0 {
+				icon = "⚠️ "
+			}
+			fmt.Printf("%s %s (%s): %d chunk(s), %s\n", icon, report.Title, orNone(report.Year), report.Chunks, coverage)
+			for _, kind := range slices.Sorted(maps.Keys(report.Problems)) {
+				where := report.Problems[kind]
+				named := where[:min(max(*examples, 0), len(where))]
+				more := ""
+				if len(where) > len(named) {
+					more = fmt.Sprintf(" … and %d more", len(where)-len(named))
+				}
+				fmt.Printf("     %s: %d, %s%s\n", kind, len(where), strings.Join(named, ", "), more)
+			}
+		}
+
+		if *output != "" {
+			data, err := json.MarshalIndent(reports, "", "  ")
+			if err != nil {
+				return err
+			}
+			if err := os.WriteFile(*output, data, 0o644); err != nil {
+				return err
+			}
+			fmt.Printf("\n✅ Report written to %s\n", *output)
+		}
+		if failed > 0 {
+			return fmt.Errorf("%w: %d of %d book(s) have %s", errPolicy, failed, len(reports), strings.Join(bookErrors, ", ")+" problems")
+		}
+		fmt.Printf("\n✅ All %d book(s) can be indexed\n", len(reports))
+		return nil
+	}
+	return cmd
+}
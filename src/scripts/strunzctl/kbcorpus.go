@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// corpusManifest is the split manifest of the metadata the combined index
+// was built from
+const corpusManifest = "combined_metadata.json.metadata.json"
+
+// corpusDocument is one embedded chunk of the metadata JSON
+type corpusDocument struct {
+	Text     string         `json:"text"`
+	Title    string         `json:"title"`
+	Metadata map[string]any `json:"metadata"`
+}
+
+// corpus is a metadata JSON as src/rag/update_combined_index.py writes it.
+// The processors write bare document lists, which load as a corpus
+// without the header fields.
+type corpus struct {
+	Documents      []corpusDocument `json:"documents"`
+	TotalDocuments int              `json:"total_documents"`
+	EmbeddingModel string           `json:"embedding_model"`
+	EmbeddingDim   int              `json:"embedding_dim"`
+	CreatedDate    string           `json:"created_date"`
+	SourceCounts   map[string]int   `json:"source_counts"`
+
+	path string
+}
+
+// addCorpusFlag registers the --metadata flag of the commands that read
+// the corpus
+func addCorpusFlag(cmd *Command) *string {
+	return cmd.Flags.String("metadata", "", "corpus metadata JSON: a split manifest, a directory holding "+corpusManifest+
+		" or a plain JSON file (default: the combined index in "+indexChunksDir+")")
+}
+
+// loadCorpus reads the corpus at path, reassembling it from its chunks
+// when path is a split manifest
+func loadCorpus(path string) (*corpus, error) {
+	if path == "" {
+		dir, err := repoPath(indexChunksDir)
+		if err != nil {
+			return nil, err
+		}
+		path = dir
+	}
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		path = filepath.Join(path, corpusManifest)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errUsage, err)
+	}
+
+	manifest := &splitManifest{path: path}
+	if strings.HasSuffix(path, ".metadata.json") && json.Unmarshal(data, manifest) == nil && len(manifest.Chunks) > 0 {
+		if problems := manifest.checkChunks(); len(problems) > 0 {
+			return nil, fmt.Errorf("%s: %s (see kb verify-index)", manifest.OriginalFile, problems[0])
+		}
+		reader, closeChunks, err := manifest.open()
+		if err != nil {
+			return nil, err
+		}
+		defer closeChunks()
+		if data, err = io.ReadAll(reader); err != nil {
+			return nil, err
+		}
+	}
+
+	c := &corpus{path: path}
+	if trimmed := bytes.TrimLeft(data, " \t\r\n"); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(data, &c.Documents)
+	} else {
+		err = json.Unmarshal(data, c)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// meta returns a metadata field as text, "" when missing or null
+func (d *corpusDocument) meta(key string) string {
+	switch value := d.Metadata[key].(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// id names a document in reports: its chunk_id, or its position
+func (d *corpusDocument) id(index int) string {
+	if id := d.meta("chunk_id"); id != "" {
+		return id
+	}
+	return "#" + strconv.Itoa(index)
+}
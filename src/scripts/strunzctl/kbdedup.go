@@ -0,0 +1,335 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"maps"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// MinHash signatures have minhashBands bands of minhashRows values; two
+// documents become candidates when any band matches, which catches pairs
+// from a Jaccard similarity of about (1/32)^(1/4) ≈ 0.42
+const (
+	minhashBands = 32
+	minhashRows  = 4
+)
+
+// dedupSourceRank decides which document of a duplicate group is kept:
+// books are the original, news is quoted in the forum, not the reverse
+var dedupSourceRank = map[string]int{"book": 0, "books": 0, "news": 1, "forum": 2}
+
+// shingledDocument holds the sorted, distinct shingle hashes of a document
+type shingledDocument struct {
+	words     int
+	shingles  []uint64
+	signature []uint64
+}
+
+// shingleText hashes the runs of size words of a text, ignoring case and
+// punctuation; texts shorter than size are one shingle
+func shingleText(text string, size int) (string, shingledDocument) {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+	doc := shingledDocument{words: len(words)}
+	for i := 0; i+size <= len(words) || (i == 0 && len(words) > 0); i++ {
+		h := fnv.New64a()
+		h.Write([]byte(strings.Join(words[i:min(i+size, len(words))], " ")))
+		doc.shingles = append(doc.shingles, h.Sum64())
+	}
+	slices.Sort(doc.shingles)
+	doc.shingles = slices.Compact(doc.shingles)
+	return strings.Join(words, " "), doc
+}
+
+// minhash signs the shingles with minhashBands*minhashRows seeded mixes
+func (d *shingledDocument) minhash() {
+	d.signature = make([]uint64, minhashBands*minhashRows)
+	for i := range d.signature {
+		d.signature[i] = ^uint64(0)
+		seed := uint64(i+1) * 0x9e3779b97f4a7c15
+		for _, shingle := range d.shingles {
+			d.signature[i] = min(d.signature[i], splitmix64(shingle^seed))
+		}
+	}
+}
+
+func splitmix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
+
+// similarity returns the Jaccard similarity of two shingle sets and how
+// much of the smaller one the larger contains
+func similarity(a, b []uint64) (jaccard, containment float64) {
+	shared := 0
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] == b[j]:
+			shared++
+			i++
+			j++
+		case a[i] < b[j]:
+			i++
+		default:
+			j++
+		}
+	}
+	if len(a) == 0 || len(b) == 0 {
+		return 0, 0
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared), float64(shared) / float64(min(len(a), len(b)))
+}
+
+// duplicateGroup is a set of documents with the same or nearly the same
+// text; keep is the one the embedding pipeline should use
+type duplicateGroup struct {
+	keep    int
+	members []int
+	exact   bool
+}
+
+// exclusionEntry is a document of the exclusion list
+type exclusionEntry struct {
+	Index       int     `json:"index"`
+	ChunkID     string  `json:"chunk_id,omitempty"`
+	URL         string  `json:"url,omitempty"`
+	Source      string  `json:"source"`
+	Kind        string  `json:"kind"` // exact or near
+	Similarity  float64 `json:"similarity"`
+	DuplicateOf int     `json:"duplicate_of"`
+}
+
+type exclusionList struct {
+	Generated string           `json:"generated"`
+	Corpus    string           `json:"corpus"`
+	Threshold float64          `json:"threshold"`
+	Shingle   int              `json:"shingle"`
+	Excluded  []exclusionEntry `json:"excluded"`
+}
+
+func newKBDedupCommand() *Command {
+	cmd := newCommand("dedup", "", "Report exact and near-duplicate documents in the corpus, using MinHash over word shingles.")
+	metadata := addCorpusFlag(cmd)
+	threshold := cmd.Flags.Float64("threshold", 0.8, "similarity from which documents are near-duplicates: Jaccard of their shingles, or how much of the shorter one the longer contains")
+	shingle := cmd.Flags.Int("shingle", 5, "words per shingle")
+	minWords := cmd.Flags.Int("min-words", 20, "words a document needs to be compared for near-duplicates; exact duplicates are found at any length")
+	top := cmd.Flags.Int("top", 10, "largest duplicate groups to print")
+	exclude := cmd.Flags.String("exclude", "", "write all but one document of every group, the list the embedding pipeline should skip, to this JSON file")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 0); err != nil {
+			return err
+		}
+		if *threshold <= 0 || *threshold > 1 || *shingle < 1 {
+			return fmt.Errorf("%w: --threshold must be in (0, 1] and --shingle at least 1", errUsage)
+		}
+		c, err := loadCorpus(*metadata)
+		if err != nil {
+			return err
+		}
+		started := time.Now()
+		fmt.Printf("\n🔁 Looking for duplicates among %d document(s) of %s\n", len(c.Documents), c.path)
+
+		docs := make([]shingledDocument, len(c.Documents))
+		normalized := make([]string, len(c.Documents))
+		forEachConcurrent(c.Documents, runtime.NumCPU(), func(i int, doc corpusDocument) error {
+			normalized[i], docs[i] = shingleText(doc.Text, *shingle)
+			if docs[i].words >= *minWords {
+				docs[i].minhash()
+			}
+			return nil
+		})
+
+		// Documents are kept in order of preference, so the kept document of
+		// a group is the best source of that text
+		order := make([]int, len(docs))
+		for i := range order {
+			order[i] = i
+		}
+		slices.SortStableFunc(order, func(a, b int) int { return dedupRank(&c.Documents[a]) - dedupRank(&c.Documents[b]) })
+
+		// Exact duplicates share their normalized text; only the kept one
+		// takes part in the near-duplicate search
+		duplicateOf := make(map[int]int)
+		keeper := make(map[string]int)
+		for _, i := range order {
+			if normalized[i] == "" {
+				continue
+			}
+			if j, ok := keeper[normalized[i]]; ok {
+				duplicateOf[i] = j
+			} else {
+				keeper[normalized[i]] = i
+			}
+		}
+
+		similar := make(map[[2]int]float64)
+		for band := range minhashBands {
+			buckets := make(map[uint64][]int)
+			for i, doc := range docs {
+				if _, excluded := duplicateOf[i]; doc.signature == nil || excluded {
+					continue
+				}
+				key := uint64(band)
+				for _, value := range doc.signature[band*minhashRows : (band+1)*minhashRows] {
+					key = splitmix64(key ^ value)
+				}
+				buckets[key] = append(buckets[key], i)
+			}
+			for _, bucket := range buckets {
+				for x := 0; x < len(bucket); x++ {
+					for y := x + 1; y < len(bucket); y++ {
+						pair := [2]int{bucket[x], bucket[y]}
+						if _, seen := similar[pair]; seen {
+							continue
+						}
+						jaccard, containment := similarity(docs[pair[0]].shingles, docs[pair[1]].shingles)
+						similar[pair] = max(jaccard, containment)
+					}
+				}
+			}
+		}
+		neighbors := make(map[int][]int)
+		for pair, score := range similar {
+			if score >= *threshold {
+				neighbors[pair[0]] = append(neighbors[pair[0]], pair[1])
+				neighbors[pair[1]] = append(neighbors[pair[1]], pair[0])
+			}
+		}
+
+		// A document is a near-duplicate only of a document that is kept, so
+		// similarity never chains across a group
+		score := func(a, b int) float64 {
+			if a > b {
+				a, b = b, a
+			}
+			return similar[[2]int{a, b}]
+		}
+		kept := make(map[int]bool)
+		nearOf := make(map[int]int)
+		for _, i := range order {
+			if _, excluded := duplicateOf[i]; excluded {
+				continue
+			}
+			best := -1
+			for _, j := range neighbors[i] {
+				if kept[j] && (best < 0 || score(i, j) > score(i, best)) {
+					best = j
+				}
+			}
+			if best >= 0 {
+				nearOf[i] = best
+			} else {
+				kept[i] = true
+			}
+		}
+
+		list := exclusionList{Generated: time.Now().Format(time.RFC3339), Corpus: c.path, Threshold: *threshold, Shingle: *shingle, Excluded: []exclusionEntry{}}
+		members := make(map[int][]int)
+		hasNear := make(map[int]bool)
+		for i := range docs {
+			if kept[i] {
+				members[i] = append(members[i], i)
+				continue
+			}
+			doc := &c.Documents[i]
+			entry := exclusionEntry{Index: i, ChunkID: doc.meta("chunk_id"), URL: doc.meta("url"), Source: doc.meta("source")}
+			if j, ok := duplicateOf[i]; ok {
+				entry.Kind, entry.Similarity, entry.DuplicateOf = "exact", 1, j
+				if k, ok := nearOf[j]; ok {
+					// The text it repeats is a near-duplicate itself
+					entry.Kind, entry.Similarity, entry.DuplicateOf = "near", score(j, k), k
+				}
+			} else if j, ok := nearOf[i]; ok {
+				entry.Kind, entry.Similarity, entry.DuplicateOf = "near", score(i, j), j
+			} else {
+				continue
+			}
+			entry.Similarity = float64(int(entry.Similarity*1000)) / 1000
+			members[entry.DuplicateOf] = append(members[entry.DuplicateOf], i)
+			hasNear[entry.DuplicateOf] = hasNear[entry.DuplicateOf] || entry.Kind == "near"
+			list.Excluded = append(list.Excluded, entry)
+		}
+
+		var groups []duplicateGroup
+		exactGroups, nearGroups := 0, 0
+		for keep, group := range members {
+			if len(group) < 2 {
+				continue
+			}
+			slices.Sort(group)
+			groups = append(groups, duplicateGroup{keep: keep, members: group, exact: !hasNear[keep]})
+			if !hasNear[keep] {
+				exactGroups++
+			} else {
+				nearGroups++
+			}
+		}
+		slices.SortFunc(groups, func(a, b duplicateGroup) int {
+			if len(a.members) != len(b.members) {
+				return len(b.members) - len(a.members)
+			}
+			return a.keep - b.keep
+		})
+
+		fmt.Printf("📊 %d group(s) of exact duplicates, %d with near-duplicates (%d candidate pair(s) compared) in %s\n",
+			exactGroups, nearGroups, len(similar), time.Since(started).Round(time.Millisecond))
+		for _, g := range groups[:min(*top, len(groups))] {
+			kind := "near-duplicates"
+			if g.exact {
+				kind = "exact duplicates"
+			}
+			sources := make(map[string]int)
+			for _, i := range g.members {
+				sources[orNone(c.Documents[i].meta("source"))]++
+			}
+			var counts []string
+			for _, source := range slices.Sorted(maps.Keys(sources)) {
+				counts = append(counts, fmt.Sprintf("%s %d", source, sources[source]))
+			}
+			keep := &c.Documents[g.keep]
+			fmt.Printf("\n  %d %s (%s): %q\n", len(g.members), kind, strings.Join(counts, ", "), truncate(strings.Join(strings.Fields(keep.Text), " "), 100))
+			fmt.Printf("    keep    %s %s\n", keep.id(g.keep), orNone(keep.meta("url")))
+			for _, i := range g.members[:min(4, len(g.members))] {
+				if i != g.keep {
+					fmt.Printf("    exclude %s %s\n", c.Documents[i].id(i), orNone(c.Documents[i].meta("url")))
+				}
+			}
+			if len(g.members) > 4 {
+				fmt.Printf("    … and %d more\n", len(g.members)-4)
+			}
+		}
+		fmt.Printf("\n🧹 %d of %d document(s) (%.1f%%) duplicate another and can be skipped\n",
+			len(list.Excluded), len(c.Documents), 100*float64(len(list.Excluded))/float64(max(len(c.Documents), 1)))
+
+		if *exclude != "" {
+			data, err := json.MarshalIndent(list, "", "  ")
+			if err != nil {
+				return err
+			}
+			if err := os.WriteFile(*exclude, data, 0o644); err != nil {
+				return err
+			}
+			fmt.Printf("✅ Exclusion list written to %s\n", *exclude)
+		}
+		return nil
+	}
+	return cmd
+}
+
+// dedupRank orders the documents of a group by which to keep; unknown
+// sources rank last
+func dedupRank(doc *corpusDocument) int {
+	if rank, ok := dedupSourceRank[doc.meta("source")]; ok {
+		return rank
+	}
+	return len(dedupSourceRank)
+}
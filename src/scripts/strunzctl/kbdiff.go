@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// snapshotPage is one page, thread or book of a snapshot, its chunks put
+// back together
+type snapshotPage struct {
+	Key    string
+	Source string
+	Title  string
+	Date   string
+	Chars  int
+	Chunks int
+	SHA256 string
+}
+
+// loadSnapshot reads a scrape result (data/scraped/*_scraping_*.json) or
+// anything loadCorpus reads, keyed by page
+func loadSnapshot(path string) (map[string]*snapshotPage, error) {
+	var probe map[string]json.RawMessage
+	if info, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("%w: %v", errUsage, err)
+	} else if data, err := os.ReadFile(path); !info.IsDir() && err == nil && json.Unmarshal(data, &probe) == nil && probe["news"] != nil {
+		var results scrapeResults
+		if err := json.Unmarshal(data, &results); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		pages := make(map[string]*snapshotPage, len(results.News))
+		for i := range results.News {
+			article := &results.News[i]
+			date := ""
+			if article.Date != nil {
+				date = *article.Date
+			}
+			pages[article.SourceURL] = &snapshotPage{Key: article.SourceURL, Source: "news", Title: article.Title, Date: date,
+				Chars: len([]rune(article.Content)), Chunks: 1, SHA256: article.contentHash()}
+		}
+		return pages, nil
+	}
+
+	c, err := loadCorpus(path)
+	if err != nil {
+		return nil, err
+	}
+	pages := make(map[string]*snapshotPage)
+	texts := make(map[string][]string)
+	for i := range c.Documents {
+		doc := &c.Documents[i]
+		key := documentKey(doc)
+		page, ok := pages[key]
+		if !ok {
+			page = &snapshotPage{Key: key, Source: doc.meta("source"), Title: documentTitle(doc), Date: documentDate(doc)}
+			pages[key] = page
+		}
+		page.Chunks++
+		page.Chars += len([]rune(doc.Text))
+		texts[key] = append(texts[key], documentTitle(doc)+"\x00"+doc.Text)
+	}
+	// Reprocessing may reorder chunks, which is no change of content
+	for key, page := range pages {
+		slices.Sort(texts[key])
+		sum := sha256.Sum256([]byte(strings.Join(texts[key], "\x00")))
+		page.SHA256 = hex.EncodeToString(sum[:])
+	}
+	return pages, nil
+}
+
+// snapshotDiff is what changed between two snapshots
+type snapshotDiff struct {
+	Old, New                 string
+	Added, Removed, Modified []*snapshotPage
+	// previous holds the old version of each modified page
+	previous  map[string]*snapshotPage
+	Unchanged int
+}
+
+func diffSnapshots(old, current map[string]*snapshotPage) *snapshotDiff {
+	diff := &snapshotDiff{previous: make(map[string]*snapshotPage)}
+	for _, key := range slices.Sorted(maps.Keys(current)) {
+		before, ok := old[key]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, current[key])
+		case before.SHA256 != current[key].SHA256:
+			diff.Modified = append(diff.Modified, current[key])
+			diff.previous[key] = before
+		default:
+			diff.Unchanged++
+		}
+	}
+	for _, key := range slices.Sorted(maps.Keys(old)) {
+		if _, ok := current[key]; !ok {
+			diff.Removed = append(diff.Removed, old[key])
+		}
+	}
+	return diff
+}
+
+// pageLink is a page as a Markdown link when its key is a URL
+func pageLink(page *snapshotPage) string {
+	title := strings.ReplaceAll(cmp.Or(page.Title, page.Key), "|", `\|`)
+	title = strings.NewReplacer("[", `\[`, "]", `\]`).Replace(title)
+	if strings.HasPrefix(page.Key, "http") {
+		return fmt.Sprintf("[%s](%s)", title, page.Key)
+	}
+	return title
+}
+
+func writeSnapshotDiffMarkdown(w io.Writer, diff *snapshotDiff, limit int) error {
+	var b strings.Builder
+	b.WriteString("## Knowledge Base Changes\n\n")
+	fmt.Fprintf(&b, "From `%s` to `%s`: %s added, %s modified, %s removed, %s unchanged.\n\n",
+		diff.Old, diff.New, formatCount(len(diff.Added)), formatCount(len(diff.Modified)), formatCount(len(diff.Removed)), formatCount(diff.Unchanged))
+
+	sources := make(map[string][3]int)
+	for i, pages := range [][]*snapshotPage{diff.Added, diff.Modified, diff.Removed} {
+		for _, page := range pages {
+			counts := sources[orNone(page.Source)]
+			counts[i]++
+			sources[orNone(page.Source)] = counts
+		}
+	}
+	if len(sources) > 0 {
+		b.WriteString("| Source | Added | Modified | Removed |\n| --- | ---: | ---: | ---: |\n")
+		for _, source := range slices.Sorted(maps.Keys(sources)) {
+			counts := sources[source]
+			fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", source, formatCount(counts[0]), formatCount(counts[1]), formatCount(counts[2]))
+		}
+	}
+
+	section := func(title string, pages []*snapshotPage, describe func(*snapshotPage) string) {
+		if len(pages) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "\n### %s\n\n", title)
+		for _, page := range pages[:min(len(pages), limit)] {
+			fmt.Fprintf(&b, "- %s%s\n", pageLink(page), describe(page))
+		}
+		if len(pages) > limit {
+			fmt.Fprintf(&b, "- … and %s more\n", formatCount(len(pages)-limit))
+		}
+	}
+	describe := func(page *snapshotPage) string {
+		var details []string
+		if page.Source != "" {
+			details = append(details, page.Source)
+		}
+		if page.Date != "" {
+			details = append(details, page.Date)
+		}
+		if len(details) == 0 {
+			return ""
+		}
+		return " (" + strings.Join(details, ", ") + ")"
+	}
+	section("Added", diff.Added, describe)
+	section("Modified", diff.Modified, func(page *snapshotPage) string {
+		before := diff.previous[page.Key]
+		details := []string{fmt.Sprintf("%+d characters", page.Chars-before.Chars)}
+		if before.Title != page.Title {
+			details = append(details, fmt.Sprintf("was %q", before.Title))
+		}
+		if before.Date != page.Date {
+			details = append(details, fmt.Sprintf("date %s → %s", orNone(before.Date), orNone(page.Date)))
+		}
+		if before.Chunks != page.Chunks && page.Chunks > 1 {
+			details = append(details, fmt.Sprintf("%d → %d chunks", before.Chunks, page.Chunks))
+		}
+		return " (" + strings.Join(details, ", ") + ")"
+	})
+	section("Removed", diff.Removed, describe)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func newKBDiffCommand() *Command {
+	cmd := newCommand("diff", "", "Summarize the pages added, removed and modified between two scrape snapshots or corpora as a Markdown report for release notes.")
+	oldPath := cmd.Flags.String("old", "", "the earlier snapshot: a scrape result, corpus metadata or split manifest")
+	newPath := cmd.Flags.String("new", "", "the later snapshot")
+	output := cmd.Flags.String("output", "", "write the report to a file instead of stdout")
+	limit := cmd.Flags.Int("limit", 50, "pages to list per section")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 0); err != nil {
+			return err
+		}
+		if *oldPath == "" || *newPath == "" {
+			return fmt.Errorf("%w: --old and --new are required", errUsage)
+		}
+		old, err := loadSnapshot(*oldPath)
+		if err != nil {
+			return err
+		}
+		current, err := loadSnapshot(*newPath)
+		if err != nil {
+			return err
+		}
+		diff := diffSnapshots(old, current)
+		diff.Old, diff.New = filepath.Base(*oldPath), filepath.Base(*newPath)
+
+		var report bytes.Buffer
+		if err := writeSnapshotDiffMarkdown(&report, diff, max(*limit, 0)); err != nil {
+			return err
+		}
+		if *output == "" {
+			_, err := os.Stdout.Write(report.Bytes())
+			return err
+		}
+		if err := os.WriteFile(*output, report.Bytes(), 0o644); err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
+		}
+		fmt.Printf("✅ %d added, %d modified, %d removed; report written to %s\n", len(diff.Added), len(diff.Modified), len(diff.Removed), *output)
+		return nil
+	}
+	return cmd
+}
@@ -0,0 +1,359 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// evalQuery is a golden query with the documents a good search returns.
+// Grades are 1 for relevant and 2 for highly relevant documents.
+type evalQuery struct {
+	Name   string
+	Query  string
+	Limit  int
+	Grades map[string]int // chunk_id, url, file_path or filename → grade
+}
+
+// loadEvalQueries reads sections of query, limit and the comma-separated
+// relevant and highly_relevant documents; the query defaults to the
+// section name
+//
+//	Vitamin D Dosierung:
+//	  limit: 10
+//	  highly_relevant: https://www.strunz.com/news/vitamin-d-dosierung.html
+//	  relevant: 82d007cf1a220525931d18617d64c77a, data/books/Dr.Ulrich-Strunz_Der_Gen-Trick_2025.pdf
+func loadEvalQueries(path string) ([]evalQuery, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: --golden: %v", errUsage, err)
+	}
+	values, err := parseYAML(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to parse %s: %v", errUsage, path, err)
+	}
+	var queries []evalQuery
+	for name, value := range values {
+		entry, ok := value.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s: query %q must be a section with relevant documents", errUsage, path, name)
+		}
+		query := evalQuery{Name: name, Grades: make(map[string]int)}
+		for key, value := range entry {
+			text, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("%w: %s: %s.%s must be a value", errUsage, path, name, key)
+			}
+			switch key {
+			case "query":
+				query.Query = text
+			case "limit":
+				if query.Limit, err = strconv.Atoi(text); err != nil || query.Limit < 1 {
+					return nil, fmt.Errorf("%w: %s: %s.limit must be a positive number", errUsage, path, name)
+				}
+			case "relevant", "highly_relevant":
+				for _, id := range splitList(text) {
+					query.Grades[id] = max(query.Grades[id], map[string]int{"relevant": 1, "highly_relevant": 2}[key])
+				}
+			default:
+				return nil, fmt.Errorf("%w: %s: unknown key %s.%s", errUsage, path, name, key)
+			}
+		}
+		if len(query.Grades) == 0 {
+			return nil, fmt.Errorf("%w: %s: %s needs relevant or highly_relevant documents", errUsage, path, name)
+		}
+		query.Query = cmp.Or(query.Query, name)
+		query.Limit = cmp.Or(query.Limit, 10)
+		queries = append(queries, query)
+	}
+	if len(queries) == 0 {
+		return nil, fmt.Errorf("%w: %s has no queries", errUsage, path)
+	}
+	slices.SortFunc(queries, func(a, b evalQuery) int { return strings.Compare(a.Name, b.Name) })
+	return queries, nil
+}
+
+// hitResolver finds the corpus documents of a search hit. Hits only carry
+// the start of the chunk text, so that is what they are looked up by.
+type hitResolver struct {
+	byPrefix map[string][]*corpusDocument
+	ids      map[string]bool
+}
+
+// evalIDKeys are the metadata fields golden files name documents by
+var evalIDKeys = []string{"chunk_id", "url", "file_path", "filename"}
+
+// hitPrefixLength stays below what the 200 characters the search tools
+// show leave after folding whitespace
+const hitPrefixLength = 100
+
+func hitPrefix(text string) string {
+	normalized := []rune(strings.Join(strings.Fields(strings.TrimSuffix(strings.TrimSpace(text), "...")), " "))
+	return string(normalized[:min(len(normalized), hitPrefixLength)])
+}
+
+func newHitResolver(c *corpus) *hitResolver {
+	r := &hitResolver{byPrefix: make(map[string][]*corpusDocument), ids: make(map[string]bool)}
+	for i := range c.Documents {
+		doc := &c.Documents[i]
+		prefix := hitPrefix(doc.Text)
+		r.byPrefix[prefix] = append(r.byPrefix[prefix], doc)
+		for _, key := range evalIDKeys {
+			if value := doc.meta(key); value != "" {
+				r.ids[value] = true
+			}
+		}
+	}
+	return r
+}
+
+// grade returns the golden id a hit matches with the highest grade, or ""
+// when the hit is not relevant; resolved reports whether the hit is in the
+// corpus at all
+func (r *hitResolver) grade(hit searchHit, grades map[string]int) (id string, grade int, resolved bool) {
+	docs := r.byPrefix[hitPrefix(hit.Content)]
+	for _, doc := range docs {
+		for _, key := range evalIDKeys {
+			if value := doc.meta(key); value != "" && grades[value] > grade {
+				id, grade = value, grades[value]
+			}
+		}
+	}
+	return id, grade, len(docs) > 0
+}
+
+// EvalScore is how well the hits of one golden query are ranked
+type EvalScore struct {
+	Query      string  `json:"query"`
+	NDCG       float64 `json:"ndcg"`
+	MRR        float64 `json:"mrr"`
+	Recall     float64 `json:"recall"`
+	Hits       int     `json:"hits"`
+	Unresolved int     `json:"unresolved,omitempty"`
+	// Found are the golden ids in the hits, by rank
+	Found   []string `json:"found,omitempty"`
+	Missing []string `json:"missing,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// EvalScorecard is the relevance of a server for all golden queries
+type EvalScorecard struct {
+	URL     string               `json:"url"`
+	Version string               `json:"version,omitempty"`
+	Tool    string               `json:"tool"`
+	Golden  string               `json:"golden"`
+	At      time.Time            `json:"at"`
+	NDCG    float64              `json:"ndcg"`
+	MRR     float64              `json:"mrr"`
+	Recall  float64              `json:"recall"`
+	Queries map[string]EvalScore `json:"queries"`
+}
+
+// scoreEvalHits computes NDCG@limit with the gain 2^grade-1, the
+// reciprocal rank of the first relevant hit and the recall of the golden
+// documents. A golden id only earns its gain once, so an article split
+// into several chunks cannot lift the score above the ideal.
+func scoreEvalHits(query evalQuery, hits []searchHit, resolver *hitResolver) EvalScore {
+	score := EvalScore{Query: query.Query, Hits: len(hits)}
+	credited := make(map[string]bool)
+	dcg := 0.0
+	for i, hit := range hits[:min(len(hits), query.Limit)] {
+		id, grade, resolved := resolver.grade(hit, query.Grades)
+		if !resolved {
+			score.Unresolved++
+		}
+		if grade == 0 || credited[id] {
+			continue
+		}
+		credited[id] = true
+		score.Found = append(score.Found, id)
+		dcg += (math.Pow(2, float64(grade)) - 1) / math.Log2(float64(i+2))
+		if score.MRR == 0 {
+			score.MRR = 1 / float64(i+1)
+		}
+	}
+	ideal := slices.Sorted(maps.Values(query.Grades))
+	slices.Reverse(ideal)
+	idcg := 0.0
+	for i, grade := range ideal[:min(len(ideal), query.Limit)] {
+		idcg += (math.Pow(2, float64(grade)) - 1) / math.Log2(float64(i+2))
+	}
+	score.NDCG = dcg / idcg
+	score.Recall = float64(len(credited)) / float64(len(query.Grades))
+	for _, id := range slices.Sorted(maps.Keys(query.Grades)) {
+		if !credited[id] {
+			score.Missing = append(score.Missing, id)
+		}
+	}
+	return score
+}
+
+func newKBEvalCommand() *Command {
+	cmd := newCommand("eval", "", "Run golden queries through the live MCP search and score the ranking (NDCG, MRR, recall) against the expected documents.")
+	golden := cmd.Flags.String("golden", "", "YAML file of golden queries with their relevant documents (required)")
+	metadata := addCorpusFlag(cmd)
+	serverURL := cmd.Flags.String("url", defaultServerURL, "base URL of the server")
+	tool := cmd.Flags.String("tool", "", "search tool to call (default: search_knowledge or knowledge_search, whichever is advertised)")
+	token := cmd.Flags.String("token", "", "bearer token to send")
+	timeout := cmd.Flags.Duration("timeout", 60*time.Second, "timeout for connecting and for each search")
+	output := cmd.Flags.String("output", "", "write the scorecard as JSON to this file, e.g. one per release")
+	baselinePath := cmd.Flags.String("baseline", "", "scorecard of an earlier release to compare against")
+	maxDrop := cmd.Flags.Float64("max-drop", 0.05, "largest allowed drop of the mean NDCG below the baseline")
+	minNDCG := cmd.Flags.Float64("min-ndcg", 0, "lowest allowed mean NDCG")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 0); err != nil {
+			return err
+		}
+		if *golden == "" {
+			return fmt.Errorf("%w: --golden is required", errUsage)
+		}
+		queries, err := loadEvalQueries(*golden)
+		if err != nil {
+			return err
+		}
+		var baseline *EvalScorecard
+		if *baselinePath != "" {
+			data, err := os.ReadFile(*baselinePath)
+			if err != nil {
+				return fmt.Errorf("%w: --baseline: %v", errUsage, err)
+			}
+			if err := json.Unmarshal(data, &baseline); err != nil {
+				return fmt.Errorf("failed to parse scorecard %s: %w", *baselinePath, err)
+			}
+		}
+		c, err := loadCorpus(*metadata)
+		if err != nil {
+			return err
+		}
+		resolver := newHitResolver(c)
+		// A golden id no document has can never be found, which is a typo
+		// or a document the corpus has lost
+		for _, query := range queries {
+			for _, id := range slices.Sorted(maps.Keys(query.Grades)) {
+				if !resolver.ids[id] {
+					fmt.Printf("⚠️  %s: %s is not in %s\n", query.Name, id, c.path)
+				}
+			}
+		}
+
+		var header http.Header
+		if *token != "" {
+			header = http.Header{"Authorization": {"Bearer " + *token}}
+		}
+		mcp, err := connectMCPWithHeader(*serverURL, *timeout, header)
+		if err != nil {
+			return err
+		}
+		defer mcp.Close()
+		if _, err := mcp.Initialize(commandContext); err != nil {
+			return fmt.Errorf("initialize failed: %s", describeMCPError(err))
+		}
+		name := *tool
+		if name == "" {
+			if name, err = pickSearchTool(mcp); err != nil {
+				return err
+			}
+		}
+
+		card := &EvalScorecard{URL: *serverURL, Tool: name, Golden: *golden, At: time.Now().UTC(), Queries: make(map[string]EvalScore)}
+		if health, err := fetchHealth(*serverURL); err == nil {
+			card.Version = health.Version
+		}
+		failed := 0
+		for _, query := range queries {
+			score := EvalScore{Query: query.Query}
+			result, err := mcp.CallTool(commandContext, name, map[string]any{"query": query.Query, "limit": query.Limit})
+			switch {
+			case err != nil:
+				score.Error = describeMCPError(err)
+			case result.IsError:
+				score.Error = "isError: " + truncate(strings.TrimSpace(result.Text()), 200)
+			default:
+				if hits, ok := parseSearchHits(result.Text()); ok {
+					score = scoreEvalHits(query, hits, resolver)
+				} else {
+					score.Error = "result is not a list of search hits: " + truncate(strings.TrimSpace(result.Text()), 200)
+				}
+			}
+			if score.Error != "" {
+				failed++
+			}
+			card.Queries[query.Name] = score
+			// A failed query scores 0, it does not drop out of the mean
+			card.NDCG += score.NDCG / float64(len(queries))
+			card.MRR += score.MRR / float64(len(queries))
+			card.Recall += score.Recall / float64(len(queries))
+		}
+		printEvalScorecard(card, baseline)
+
+		if *output != "" {
+			data, err := json.MarshalIndent(card, "", "  ")
+			if err != nil {
+				return err
+			}
+			if err := os.WriteFile(*output, append(data, '\n'), 0o644); err != nil {
+				return fmt.Errorf("failed to write scorecard: %w", err)
+			}
+			fmt.Printf("\nScorecard written to %s\n", *output)
+		}
+
+		var reasons []string
+		if failed > 0 {
+			reasons = append(reasons, fmt.Sprintf("%d of %d queries failed", failed, len(queries)))
+		}
+		if card.NDCG < *minNDCG {
+			reasons = append(reasons, fmt.Sprintf("mean NDCG %.3f is below %.3f", card.NDCG, *minNDCG))
+		}
+		if baseline != nil && baseline.NDCG-card.NDCG > *maxDrop {
+			reasons = append(reasons, fmt.Sprintf("mean NDCG dropped %.3f → %.3f since %s", baseline.NDCG, card.NDCG, orNone(baseline.Version)))
+		}
+		if len(reasons) > 0 {
+			return fmt.Errorf("%w: %s", errPolicy, strings.Join(reasons, "; "))
+		}
+		return nil
+	}
+	return cmd
+}
+
+func printEvalScorecard(card, baseline *EvalScorecard) {
+	fmt.Printf("\n📐 Relevance of %s (%s, %s) for %d golden queries\n\n", card.URL, orNone(card.Version), card.Tool, len(card.Queries))
+	fmt.Printf("  %-32s %6s %6s %6s  %s\n", "QUERY", "NDCG", "MRR", "RECALL", "BASELINE NDCG")
+	unresolved := 0
+	for _, name := range slices.Sorted(maps.Keys(card.Queries)) {
+		score := card.Queries[name]
+		if score.Error != "" {
+			fmt.Printf("  %-32s ❌ %s\n", truncate(name, 32), truncate(score.Error, 80))
+			continue
+		}
+		change := ""
+		if baseline != nil {
+			if before, ok := baseline.Queries[name]; ok && before.Error == "" {
+				change = fmt.Sprintf("%.3f (%+.3f)", before.NDCG, score.NDCG-before.NDCG)
+			}
+		}
+		fmt.Printf("  %-32s %6.3f %6.3f %6.3f  %s\n", truncate(name, 32), score.NDCG, score.MRR, score.Recall, change)
+		if len(score.Missing) > 0 {
+			fmt.Printf("  %-32s missing: %s\n", "", truncate(strings.Join(score.Missing, ", "), 100))
+		}
+		unresolved += score.Unresolved
+	}
+	overall := fmt.Sprintf("\nMean: NDCG %.3f, MRR %.3f, recall %.3f", card.NDCG, card.MRR, card.Recall)
+	if baseline != nil {
+		overall += fmt.Sprintf(" (baseline NDCG %.3f from %s, %s)", baseline.NDCG, baseline.At.Format(time.DateOnly), orNone(baseline.Version))
+	}
+	fmt.Println(overall)
+	if unresolved > 0 {
+		// The server indexes other text than the local corpus, so its hits
+		// cannot all be matched to golden documents
+		fmt.Printf("⚠️  %d hit(s) are not in the local corpus; evaluate against the metadata the server was built from\n", unresolved)
+	}
+}
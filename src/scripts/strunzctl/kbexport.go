@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+)
+
+// corpusFilter is one key=value or key!=value condition of --filter
+type corpusFilter struct {
+	key, value string
+	negate     bool
+}
+
+// parseCorpusFilters parses comma-separated conditions. Conditions on
+// different keys must all hold; = conditions on the same key are
+// alternatives, so source=news,source=forum selects both.
+func parseCorpusFilters(value string) ([]corpusFilter, error) {
+	var filters []corpusFilter
+	for _, item := range splitList(value) {
+		filter := corpusFilter{}
+		key, value, ok := strings.Cut(item, "=")
+		if filter.negate = strings.HasSuffix(key, "!"); filter.negate {
+			key = strings.TrimSuffix(key, "!")
+		}
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("%w: filter %q is not key=value or key!=value", errUsage, item)
+		}
+		filter.key, filter.value = strings.TrimSpace(key), strings.TrimSpace(value)
+		filters = append(filters, filter)
+	}
+	return filters, nil
+}
+
+// matchFilters reports whether a document passes every filter; "" matches
+// a missing field
+func matchFilters(doc *corpusDocument, filters []corpusFilter) bool {
+	alternatives := make(map[string]bool)
+	for _, filter := range filters {
+		value := doc.meta(filter.key)
+		if filter.key == "title" && value == "" {
+			value = doc.Title
+		}
+		if filter.negate {
+			if strings.EqualFold(value, filter.value) {
+				return false
+			}
+			continue
+		}
+		alternatives[filter.key] = alternatives[filter.key] || strings.EqualFold(value, filter.value)
+	}
+	for _, matched := range alternatives {
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// inDateRange reports whether the date of a document, see documentDate,
+// falls between since and until, both YYYY-MM-DD and inclusive. Books
+// only have a year, which is compared by year.
+func inDateRange(doc *corpusDocument, since, until string) bool {
+	if since == "" && until == "" {
+		return true
+	}
+	date := documentDate(doc)
+	if date == "" {
+		return false
+	}
+	length := min(len(date), 10)
+	return (since == "" || date >= since[:length]) && (until == "" || date <= until[:length])
+}
+
+// flattenDocument puts the metadata of a document next to its text
+func flattenDocument(doc *corpusDocument, index int, fields []string) map[string]any {
+	record := make(map[string]any, len(doc.Metadata)+3)
+	for key, value := range doc.Metadata {
+		record[key] = value
+	}
+	if record["title"] == nil && doc.Title != "" {
+		record["title"] = doc.Title
+	}
+	record["id"] = doc.id(index)
+	record["text"] = doc.Text
+	if len(fields) == 0 {
+		return record
+	}
+	selected := make(map[string]any, len(fields))
+	for _, field := range fields {
+		if value, ok := record[field]; ok {
+			selected[field] = value
+		}
+	}
+	return selected
+}
+
+func newKBExportCommand() *Command {
+	cmd := newCommand("export", "", "Export a filtered, flattened copy of the corpus, text with metadata, for evaluation sets, fine-tuning experiments or sharing subsets.")
+	metadata := addCorpusFlag(cmd)
+	format := cmd.Flags.String("format", "jsonl", "export format (jsonl or json)")
+	output := cmd.Flags.String("output", "", "write the export to a file instead of stdout")
+	filter := cmd.Flags.String("filter", "", "comma-separated metadata conditions, key=value or key!=value, e.g. source=news,author!=Anonymous")
+	since := cmd.Flags.String("since", "", "only documents dated on or after this day (YYYY-MM-DD); undated documents are left out")
+	until := cmd.Flags.String("until", "", "only documents dated on or before this day (YYYY-MM-DD)")
+	fields := cmd.Flags.String("fields", "", "comma-separated fields to export (default: id, text and all metadata)")
+	exclude := cmd.Flags.String("exclude", "", "leave out the documents of an exclusion list written by kb dedup --exclude")
+	limit := cmd.Flags.Int("limit", 0, "export at most this many documents (0: all)")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 0); err != nil {
+			return err
+		}
+		if *format != "jsonl" && *format != "json" {
+			return fmt.Errorf("%w: unsupported export format %q", errUsage, *format)
+		}
+		filters, err := parseCorpusFilters(*filter)
+		if err != nil {
+			return err
+		}
+		for _, date := range []string{*since, *until} {
+			if _, err := time.Parse("2006-01-02", date); date != "" && err != nil {
+				return fmt.Errorf("%w: --since and --until take a day as YYYY-MM-DD, not %q", errUsage, date)
+			}
+		}
+		c, err := loadCorpus(*metadata)
+		if err != nil {
+			return err
+		}
+		excluded := make(map[int]bool)
+		if *exclude != "" {
+			data, err := os.ReadFile(*exclude)
+			if err != nil {
+				return fmt.Errorf("%w: %v", errUsage, err)
+			}
+			var list exclusionList
+			if err := json.Unmarshal(data, &list); err != nil {
+				return fmt.Errorf("failed to parse %s: %w", *exclude, err)
+			}
+			// Entries refer to documents by position
+			if list.Corpus != c.path {
+				return fmt.Errorf("%w: %s lists duplicates of %s, not of %s", errUsage, *exclude, list.Corpus, c.path)
+			}
+			for _, entry := range list.Excluded {
+				excluded[entry.Index] = true
+			}
+		}
+
+		var out io.Writer = os.Stdout
+		if *output != "" {
+			file, err := os.Create(*output)
+			if err != nil {
+				return fmt.Errorf("failed to create export: %w", err)
+			}
+			defer file.Close()
+			out = file
+		}
+		buffered := bufio.NewWriter(out)
+		encoder := json.NewEncoder(buffered)
+		encoder.SetEscapeHTML(false)
+
+		// JSON is written as one array at the end, JSONL line by line
+		records := []map[string]any{}
+		exported := 0
+		for i := range c.Documents {
+			doc := &c.Documents[i]
+			if excluded[i] || !matchFilters(doc, filters) || !inDateRange(doc, *since, *until) {
+				continue
+			}
+			if *limit > 0 && exported == *limit {
+				break
+			}
+			record := flattenDocument(doc, i, splitList(*fields))
+			exported++
+			if *format == "json" {
+				records = append(records, record)
+			} else if err := encoder.Encode(record); err != nil {
+				return fmt.Errorf("failed to write export: %w", err)
+			}
+		}
+		if *format == "json" {
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(records); err != nil {
+				return fmt.Errorf("failed to write export: %w", err)
+			}
+		}
+		if err := buffered.Flush(); err != nil {
+			return fmt.Errorf("failed to write export: %w", err)
+		}
+		slog.Info("Corpus exported", "documents", exported, "of", len(c.Documents), "format", *format, "path", orNone(*output))
+		return nil
+	}
+	return cmd
+}
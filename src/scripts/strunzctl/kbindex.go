@@ -0,0 +1,332 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// indexChunksDir is where src/scripts/data/split_faiss_index.py puts the
+// chunks of files too large for GitHub, relative to the repository root
+const indexChunksDir = "data/faiss_indices/chunks"
+
+// splitManifest is the <file>.metadata.json the splitter writes next to
+// the chunks of a file
+type splitManifest struct {
+	OriginalFile string   `json:"original_file"`
+	OriginalSize int64    `json:"original_size"`
+	NumChunks    int      `json:"num_chunks"`
+	ChunkSize    int64    `json:"chunk_size"`
+	Chunks       []string `json:"chunks"`
+	SHA256       string   `json:"sha256,omitempty"`
+
+	path string
+}
+
+// loadSplitManifests reads every split manifest in dir
+func loadSplitManifests(dir string) ([]*splitManifest, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.metadata.json"))
+	if err != nil {
+		return nil, err
+	}
+	var manifests []*splitManifest
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		manifest := &splitManifest{path: path}
+		if err := json.Unmarshal(data, manifest); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		if manifest.OriginalFile != "" {
+			manifests = append(manifests, manifest)
+		}
+	}
+	return manifests, nil
+}
+
+// checkChunks compares the chunk files on disk with the manifest
+func (m *splitManifest) checkChunks() []string {
+	var problems []string
+	if len(m.Chunks) != m.NumChunks {
+		problems = append(problems, fmt.Sprintf("lists %d chunk(s) but num_chunks is %d", len(m.Chunks), m.NumChunks))
+	}
+	var total int64
+	for i, chunk := range m.Chunks {
+		info, err := os.Stat(filepath.Join(filepath.Dir(m.path), chunk))
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("chunk %s is missing", chunk))
+			continue
+		}
+		total += info.Size()
+		last := i == len(m.Chunks)-1
+		if (!last && info.Size() != m.ChunkSize) || (last && info.Size() > m.ChunkSize) {
+			problems = append(problems, fmt.Sprintf("chunk %s is %d bytes, chunk_size is %d", chunk, info.Size(), m.ChunkSize))
+		}
+	}
+	if total != m.OriginalSize && len(problems) == 0 {
+		problems = append(problems, fmt.Sprintf("chunks add up to %d bytes, original_size is %d", total, m.OriginalSize))
+	}
+	return problems
+}
+
+// open returns the chunks as one stream, the way the reconstruct scripts
+// concatenate them
+func (m *splitManifest) open() (io.Reader, func(), error) {
+	var readers []io.Reader
+	var files []*os.File
+	closeAll := func() {
+		for _, file := range files {
+			file.Close()
+		}
+	}
+	for _, chunk := range m.Chunks {
+		file, err := os.Open(filepath.Join(filepath.Dir(m.path), chunk))
+		if err != nil {
+			closeAll()
+			return nil, nil, err
+		}
+		files = append(files, file)
+		readers = append(readers, file)
+	}
+	return bufio.NewReaderSize(io.MultiReader(readers...), 1<<20), closeAll, nil
+}
+
+// faissIndex is what the header and vectors of a flat FAISS index say
+type faissIndex struct {
+	Type      string
+	Dim       int
+	Count     int64
+	NonFinite int64 // NaN or infinite vector components
+}
+
+// flatIndexTypes are the index types whose layout readFlatIndex knows
+var flatIndexTypes = map[string]string{"IxF2": "IndexFlatL2", "IxFI": "IndexFlatIP"}
+
+// flatIndexHeader is what faiss.write_index writes before the vectors of a
+// flat index
+type flatIndexHeader struct {
+	FourCC    [4]byte
+	Dim       int32
+	Count     int64
+	_, _      int64 // unused by flat indexes
+	IsTrained uint8
+	Metric    int32
+	Floats    uint64
+}
+
+// readFlatIndex reads a flat FAISS index as written by faiss.write_index:
+// the fourcc, the index header, then every vector as little-endian float32
+func readFlatIndex(r io.Reader) (*faissIndex, error) {
+	var header flatIndexHeader
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return nil, fmt.Errorf("failed to read the index header: %w", err)
+	}
+	name, ok := flatIndexTypes[string(header.FourCC[:])]
+	if !ok {
+		return nil, fmt.Errorf("unsupported index type %q (want one of IxF2, IxFI)", header.FourCC[:])
+	}
+	index := &faissIndex{Type: name, Dim: int(header.Dim), Count: header.Count}
+	if header.Dim <= 0 || header.Count < 0 || header.Floats != uint64(header.Count)*uint64(header.Dim) {
+		return index, fmt.Errorf("header says %d vectors of %d dimensions but stores %d floats", header.Count, header.Dim, header.Floats)
+	}
+
+	buffer := make([]byte, 4<<16)
+	remaining := header.Floats * 4
+	for remaining > 0 {
+		n, err := io.ReadFull(r, buffer[:min(uint64(len(buffer)), remaining)])
+		for i := 0; i+4 <= n; i += 4 {
+			value := float64(math.Float32frombits(binary.LittleEndian.Uint32(buffer[i:])))
+			if math.IsNaN(value) || math.IsInf(value, 0) {
+				index.NonFinite++
+			}
+		}
+		remaining -= uint64(n)
+		if err != nil {
+			return index, fmt.Errorf("index ends %d bytes early", remaining)
+		}
+	}
+	if trailing, _ := io.Copy(io.Discard, r); trailing > 0 {
+		return index, fmt.Errorf("%d unexpected bytes after the vectors", trailing)
+	}
+	return index, nil
+}
+
+// corpusMetadata is the part of the metadata JSON that must agree with the
+// index built from it
+type corpusMetadata struct {
+	Documents      []json.RawMessage `json:"documents"`
+	TotalDocuments int               `json:"total_documents"`
+	EmbeddingModel string            `json:"embedding_model"`
+	EmbeddingDim   int               `json:"embedding_dim"`
+}
+
+// metadataFileFor names the metadata JSON that belongs to an index, e.g.
+// combined_metadata.json for combined_index.faiss
+func metadataFileFor(index string) string {
+	base := strings.TrimSuffix(index, ".faiss")
+	if trimmed, ok := strings.CutSuffix(base, "_index"); ok {
+		return trimmed + "_metadata.json"
+	}
+	return base + ".metadata.json"
+}
+
+func newKBVerifyIndexCommand() *Command {
+	cmd := newCommand("verify-index", "[chunks-dir]", "Check that the split FAISS index and metadata reassemble intact and agree on vector count and dimensions.")
+	requireChecksum := cmd.Flags.Bool("require-checksum", false, "fail when a manifest records no sha256 instead of warning")
+	writeChecksums := cmd.Flags.Bool("write-checksums", false, "record the sha256 of files whose manifest has none")
+
+	cmd.Run = func(args []string) error {
+		if len(args) > 1 {
+			return cmd.ExactArgs(args, 1)
+		}
+		dir := ""
+		if len(args) == 1 {
+			dir = args[0]
+		} else {
+			var err error
+			if dir, err = repoPath(indexChunksDir); err != nil {
+				return err
+			}
+		}
+		problems, err := verifyIndexChunks(dir, *requireChecksum, *writeChecksums)
+		if err != nil {
+			return err
+		}
+		if len(problems) > 0 {
+			return fmt.Errorf("%w: the index artifacts are inconsistent (%d problem(s))", errPolicy, len(problems))
+		}
+		fmt.Println("\n✅ The index artifacts are intact and consistent")
+		return nil
+	}
+	return cmd
+}
+
+// verifyIndexChunks reassembles the split files in dir, checks them against
+// their manifests and each index against its metadata, and returns the
+// problems found
+func verifyIndexChunks(dir string, requireChecksum, writeChecksums bool) ([]string, error) {
+	manifests, err := loadSplitManifests(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(manifests) == 0 {
+		return nil, fmt.Errorf("%w: no split manifests (*.metadata.json) in %s", errUsage, dir)
+	}
+
+	fmt.Printf("\n🗂️  Verifying the split files in %s\n", dir)
+	var problems []string
+	fail := func(format string, args ...any) {
+		problem := fmt.Sprintf(format, args...)
+		problems = append(problems, problem)
+		fmt.Printf("❌ %s\n", problem)
+	}
+
+	indexes := make(map[string]*faissIndex)
+	metadata := make(map[string]*corpusMetadata)
+	for _, manifest := range manifests {
+		name := manifest.OriginalFile
+		if chunkProblems := manifest.checkChunks(); len(chunkProblems) > 0 {
+			for _, problem := range chunkProblems {
+				fail("%s: %s", name, problem)
+			}
+			continue
+		}
+		reader, closeChunks, err := manifest.open()
+		if err != nil {
+			return nil, err
+		}
+		hash := sha256.New()
+		tee := io.TeeReader(reader, hash)
+		switch {
+		case strings.HasSuffix(name, ".faiss"):
+			index, err := readFlatIndex(tee)
+			if err != nil {
+				fail("%s: %v", name, err)
+			} else {
+				indexes[name] = index
+			}
+		case strings.HasSuffix(name, ".json"):
+			var corpus corpusMetadata
+			if err := json.NewDecoder(tee).Decode(&corpus); err != nil {
+				fail("%s: not valid JSON: %v", name, err)
+			} else {
+				metadata[name] = &corpus
+			}
+		}
+		// Hash whatever the parsers did not read
+		io.Copy(io.Discard, tee)
+		closeChunks()
+
+		sum := hex.EncodeToString(hash.Sum(nil))
+		switch {
+		case manifest.SHA256 != "" && !strings.EqualFold(manifest.SHA256, sum):
+			fail("%s: reassembles to sha256 %s, the manifest records %s", name, sum, manifest.SHA256)
+		case manifest.SHA256 != "":
+			fmt.Printf("✅ %s: %d chunk(s) reassemble to %d bytes, sha256 matches\n", name, len(manifest.Chunks), manifest.OriginalSize)
+		case writeChecksums:
+			manifest.SHA256 = sum
+			if err := writeSplitManifest(manifest); err != nil {
+				return nil, err
+			}
+			fmt.Printf("✅ %s: %d chunk(s) reassemble to %d bytes, recorded sha256 %s\n", name, len(manifest.Chunks), manifest.OriginalSize, sum)
+		case requireChecksum:
+			fail("%s: the manifest records no sha256 (reassembles to %s)", name, sum)
+		default:
+			fmt.Printf("⚠️  %s: %d chunk(s) reassemble to %d bytes, but the manifest records no sha256 to compare (sha256 %s)\n",
+				name, len(manifest.Chunks), manifest.OriginalSize, sum)
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(indexes)) {
+		index := indexes[name]
+		if index.NonFinite > 0 {
+			fail("%s: %d NaN or infinite vector component(s) in %d vectors of %d dimensions", name, index.NonFinite, index.Count, index.Dim)
+		} else {
+			fmt.Printf("✅ %s: %s with %d vectors of %d dimensions\n", name, index.Type, index.Count, index.Dim)
+		}
+		corpus, ok := metadata[metadataFileFor(name)]
+		if !ok {
+			// A metadata file that failed to reassemble is reported already
+			if !slices.ContainsFunc(manifests, func(m *splitManifest) bool { return m.OriginalFile == metadataFileFor(name) }) {
+				fail("%s: no %s to compare with", name, metadataFileFor(name))
+			}
+			continue
+		}
+		switch {
+		case int64(len(corpus.Documents)) != index.Count:
+			fail("%s has %d documents for %d vectors", metadataFileFor(name), len(corpus.Documents), index.Count)
+		case corpus.TotalDocuments != len(corpus.Documents):
+			fail("%s: total_documents is %d but it lists %d", metadataFileFor(name), corpus.TotalDocuments, len(corpus.Documents))
+		case corpus.EmbeddingDim != index.Dim:
+			fail("%s: embedding_dim is %d, the index has %d", metadataFileFor(name), corpus.EmbeddingDim, index.Dim)
+		default:
+			fmt.Printf("✅ %s: %d documents embedded with %s (%d dimensions)\n", metadataFileFor(name), len(corpus.Documents), orNone(corpus.EmbeddingModel), corpus.EmbeddingDim)
+		}
+	}
+
+	return problems, nil
+}
+
+// writeSplitManifest rewrites a manifest the way the splitter formats it
+func writeSplitManifest(manifest *splitManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(manifest.path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to update %s: %w", manifest.path, err)
+	}
+	return nil
+}
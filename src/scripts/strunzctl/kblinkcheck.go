@@ -0,0 +1,378 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// corpusURL finds links in document text, with or without a scheme
+var corpusURL = regexp.MustCompile(`(?i)\b(?:https?://|www\.)[^\s<>"'\[\]{}|\\^` + "`" + `]+`)
+
+// extractLinks returns the distinct URLs in a text. Sentence punctuation
+// and unbalanced closing parentheses after a link are not part of it.
+func extractLinks(text string) []string {
+	var links []string
+	for _, match := range corpusURL.FindAllString(text, -1) {
+		for {
+			trimmed := strings.TrimRight(match, ".,;:!?'\"»«“”„")
+			if strings.HasSuffix(trimmed, ")") && strings.Count(trimmed, "(") < strings.Count(trimmed, ")") {
+				trimmed = strings.TrimSuffix(trimmed, ")")
+			}
+			if trimmed == match {
+				break
+			}
+			match = trimmed
+		}
+		if !strings.HasPrefix(strings.ToLower(match), "http") {
+			match = "https://" + match
+		}
+		target, err := url.Parse(match)
+		if err != nil || !strings.Contains(target.Host, ".") {
+			continue
+		}
+		// Fragments and text fragments (#:~:text=) never change what is fetched
+		target.Fragment, target.RawFragment = "", ""
+		if !slices.Contains(links, target.String()) {
+			links = append(links, target.String())
+		}
+	}
+	return links
+}
+
+// linkHop is one response of a redirect chain
+type linkHop struct {
+	URL    string `json:"url"`
+	Status int    `json:"status"`
+}
+
+// linkResult is the outcome of checking one URL
+type linkResult struct {
+	URL string `json:"url"`
+	// Status is ok, redirected, dead (the server says the page is gone),
+	// blocked (the server refuses bots, the link may be fine) or error
+	Status    string    `json:"status"`
+	Code      int       `json:"code,omitempty"`
+	Final     string    `json:"final,omitempty"`
+	Chain     []linkHop `json:"chain,omitempty"`
+	Permanent bool      `json:"permanent,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	Checked   time.Time `json:"checked"`
+	// Documents are the ids of the documents linking here
+	Documents []string `json:"documents,omitempty"`
+}
+
+// linkCache keeps results between runs, so a rerun only checks new links
+// and links checked longer ago than the maximum age
+type linkCache struct {
+	Results map[string]*linkResult `json:"results"`
+
+	path string
+	mu   sync.Mutex
+}
+
+func loadLinkCache() (*linkCache, error) {
+	cache := &linkCache{Results: make(map[string]*linkResult)}
+	dir, err := cacheDir("linkcheck")
+	if err != nil {
+		return nil, err
+	}
+	cache.path = filepath.Join(dir, "results.json")
+	if globalOptions.noCache {
+		return cache, nil
+	}
+	data, err := os.ReadFile(cache.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cache, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, cache); err != nil || cache.Results == nil {
+		// A damaged cache only costs a full check
+		cache.Results = make(map[string]*linkResult)
+	}
+	return cache, nil
+}
+
+func (c *linkCache) get(link string, maxAge time.Duration) *linkResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if result, ok := c.Results[link]; ok && time.Since(result.Checked) < maxAge {
+		copied := *result
+		return &copied
+	}
+	return nil
+}
+
+func (c *linkCache) put(result *linkResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stored := *result
+	stored.Documents = nil
+	c.Results[result.URL] = &stored
+}
+
+func (c *linkCache) save() error {
+	if globalOptions.noCache {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(c.path, data)
+}
+
+// linkChecker follows redirects itself, so it can report the chain
+type linkChecker struct {
+	client *http.Client
+	// hosts limits the requests in flight per host
+	hosts   map[string]chan struct{}
+	perHost int
+	mu      sync.Mutex
+}
+
+func newLinkChecker(timeout time.Duration, perHost int) *linkChecker {
+	return &linkChecker{
+		client: &http.Client{
+			Timeout:       timeout,
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		hosts:   make(map[string]chan struct{}),
+		perHost: max(perHost, 1),
+	}
+}
+
+// request sends a HEAD request, or a GET when the server does not answer
+// HEAD properly
+func (l *linkChecker) request(target string) (*http.Response, error) {
+	host := ""
+	if u, err := url.Parse(target); err == nil {
+		host = u.Host
+	}
+	l.mu.Lock()
+	slots, ok := l.hosts[host]
+	if !ok {
+		slots = make(chan struct{}, l.perHost)
+		l.hosts[host] = slots
+	}
+	l.mu.Unlock()
+	slots <- struct{}{}
+	defer func() { <-slots }()
+
+	var resp *http.Response
+	for _, method := range []string{http.MethodHead, http.MethodGet} {
+		req, err := http.NewRequest(method, target, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("User-Agent", scrapeUserAgent)
+		if resp, err = l.client.Do(req); err != nil {
+			return nil, err
+		}
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		resp.Body.Close()
+		switch resp.StatusCode {
+		case http.StatusMethodNotAllowed, http.StatusNotImplemented, http.StatusForbidden, http.StatusNotFound:
+			// Servers that mishandle HEAD answer these; GET decides
+			continue
+		}
+		break
+	}
+	return resp, nil
+}
+
+func (l *linkChecker) check(link string) *linkResult {
+	result := &linkResult{URL: link, Checked: time.Now().UTC(), Permanent: true}
+	current := link
+	for hop := 0; ; hop++ {
+		resp, err := l.request(current)
+		if err != nil {
+			result.Status, result.Error = "error", err.Error()
+			var urlErr *url.Error
+			if errors.As(err, &urlErr) {
+				result.Error = urlErr.Err.Error()
+			}
+			break
+		}
+		result.Code = resp.StatusCode
+		if resp.StatusCode < 300 || resp.StatusCode >= 400 || resp.StatusCode == http.StatusNotModified {
+			result.Status = classifyLinkStatus(resp.StatusCode, len(result.Chain) > 0)
+			break
+		}
+		result.Chain = append(result.Chain, linkHop{URL: current, Status: resp.StatusCode})
+		result.Permanent = result.Permanent && (resp.StatusCode == http.StatusMovedPermanently || resp.StatusCode == http.StatusPermanentRedirect)
+		next, err := url.Parse(resp.Header.Get("Location"))
+		if err != nil || resp.Header.Get("Location") == "" {
+			result.Status, result.Error = "error", fmt.Sprintf("redirect %d without a valid Location", resp.StatusCode)
+			break
+		}
+		base, _ := url.Parse(current)
+		current = base.ResolveReference(next).String()
+		if hop == 9 || slices.ContainsFunc(result.Chain, func(h linkHop) bool { return h.URL == current }) {
+			result.Status, result.Error = "error", "redirect loop"
+			break
+		}
+	}
+	if len(result.Chain) > 0 {
+		result.Final = current
+	} else {
+		result.Permanent = false
+	}
+	return result
+}
+
+func classifyLinkStatus(code int, redirected bool) string {
+	switch {
+	case code < 400 && redirected:
+		return "redirected"
+	case code < 400:
+		return "ok"
+	case code == http.StatusUnauthorized || code == http.StatusForbidden || code == http.StatusTooManyRequests || code == 999:
+		// 999 is what LinkedIn answers crawlers
+		return "blocked"
+	case code == http.StatusNotFound || code == http.StatusGone || code == 451:
+		return "dead"
+	}
+	return "error"
+}
+
+func newKBLinkCheckCommand() *Command {
+	cmd := newCommand("linkcheck", "", "Check the outbound links of the corpus and report dead links and redirect chains.")
+	metadata := addCorpusFlag(cmd)
+	concurrency := cmd.Flags.Int("concurrency", 16, "links to check at the same time")
+	perHost := cmd.Flags.Int("per-host", 2, "requests in flight per host")
+	timeout := cmd.Flags.Duration("timeout", 15*time.Second, "timeout per request")
+	maxAge := cmd.Flags.Duration("max-age", 7*24*time.Hour, "reuse cached results younger than this; 0 checks every link again")
+	hosts := cmd.Flags.String("host", "", "comma-separated hosts to check, with their subdomains (default: all)")
+	skipHosts := cmd.Flags.String("skip-host", "", "comma-separated hosts not to check")
+	output := cmd.Flags.String("output", "", "write every result with the documents linking to it to this JSON file")
+	top := cmd.Flags.Int("top", 20, "dead links and redirects to print")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 0); err != nil {
+			return err
+		}
+		c, err := loadCorpus(*metadata)
+		if err != nil {
+			return err
+		}
+		matchesHost := func(link string, hosts []string) bool {
+			u, err := url.Parse(link)
+			if err != nil {
+				return false
+			}
+			host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+			return slices.ContainsFunc(hosts, func(h string) bool {
+				h = strings.ToLower(h)
+				return host == h || strings.HasSuffix(host, "."+h)
+			})
+		}
+
+		linkedFrom := make(map[string][]string)
+		for i := range c.Documents {
+			for _, link := range extractLinks(c.Documents[i].Text) {
+				if (*hosts == "" || matchesHost(link, splitList(*hosts))) && !matchesHost(link, splitList(*skipHosts)) {
+					linkedFrom[link] = append(linkedFrom[link], c.Documents[i].id(i))
+				}
+			}
+		}
+		links := slices.Sorted(maps.Keys(linkedFrom))
+
+		cache, err := loadLinkCache()
+		if err != nil {
+			return err
+		}
+		results := make([]*linkResult, len(links))
+		var pending []int
+		for i, link := range links {
+			if results[i] = cache.get(link, *maxAge); results[i] == nil {
+				pending = append(pending, i)
+			}
+		}
+		fmt.Printf("\n🔗 %d distinct link(s) in %d document(s) of %s: %d cached, %d to check\n",
+			len(links), len(c.Documents), c.path, len(links)-len(pending), len(pending))
+
+		checker := newLinkChecker(*timeout, *perHost)
+		started := time.Now()
+		var done int
+		var mu sync.Mutex
+		forEachConcurrent(pending, *concurrency, func(_ int, i int) error {
+			results[i] = checker.check(links[i])
+			cache.put(results[i])
+			mu.Lock()
+			defer mu.Unlock()
+			if done++; done%100 == 0 {
+				fmt.Printf("   %d/%d checked\n", done, len(pending))
+				// A long run that is interrupted keeps what it learned
+				cache.save()
+			}
+			return nil
+		})
+		if err := cache.save(); err != nil {
+			return fmt.Errorf("failed to save the link cache: %w", err)
+		}
+
+		counts := make(map[string]int)
+		for i, result := range results {
+			result.Documents = linkedFrom[links[i]]
+			counts[result.Status]++
+		}
+		fmt.Printf("📊 %d ok, %d redirected, %d dead, %d blocked, %d error(s) in %s\n",
+			counts["ok"], counts["redirected"], counts["dead"], counts["blocked"], counts["error"], time.Since(started).Round(time.Second))
+
+		// Links found in many documents are the most worth fixing
+		byReach := slices.Clone(results)
+		slices.SortStableFunc(byReach, func(a, b *linkResult) int { return len(b.Documents) - len(a.Documents) })
+		printed := 0
+		for _, result := range byReach {
+			if (result.Status != "dead" && result.Status != "error") || printed == *top {
+				continue
+			}
+			printed++
+			reason := result.Error
+			if reason == "" {
+				reason = fmt.Sprintf("HTTP %d", result.Code)
+			}
+			fmt.Printf("❌ %s: %s, linked from %d document(s), e.g. %s\n", result.URL, reason, len(result.Documents), result.Documents[0])
+		}
+		printed = 0
+		for _, result := range byReach {
+			if result.Status != "redirected" || !result.Permanent || printed == *top {
+				continue
+			}
+			printed++
+			var chain []string
+			for _, hop := range result.Chain {
+				chain = append(chain, fmt.Sprintf("%d", hop.Status))
+			}
+			fmt.Printf("↪️  %s → %s (%s), linked from %d document(s)\n", result.URL, result.Final, strings.Join(chain, " → "), len(result.Documents))
+		}
+
+		if *output != "" {
+			data, err := json.MarshalIndent(map[string]any{"generated": time.Now().Format(time.RFC3339), "corpus": c.path, "links": results}, "", "  ")
+			if err != nil {
+				return err
+			}
+			if err := os.WriteFile(*output, data, 0o644); err != nil {
+				return err
+			}
+			fmt.Printf("✅ Results written to %s\n", *output)
+		}
+		return nil
+	}
+	return cmd
+}
@@ -0,0 +1,352 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// processProfile tells `kb process` where a content type keeps its parts.
+// Selectors of a list are tried in order and the first match wins.
+type processProfile struct {
+	Title []string `json:"title"`
+	// Posts selects the posts of a page, e.g. forum replies; without it the
+	// page is one post
+	Posts         string   `json:"posts,omitempty"`
+	Content       []string `json:"content"`
+	Author        []string `json:"author"`
+	Date          []string `json:"date"`
+	Remove        []string `json:"remove"`
+	DefaultAuthor string   `json:"default_author,omitempty"`
+	// URLPrefix plus the path below the content type's raw directory is the
+	// source URL of a page without a canonical link
+	URLPrefix string `json:"url_prefix"`
+}
+
+// defaultProcessProfiles follow the selectors of src/rag/news_processor.py
+// and src/rag/forum_processor.py
+var defaultProcessProfiles = map[string]*processProfile{
+	"news": {
+		Title: []string{"h1", "h2", "title"},
+		Content: []string{"div.post-content", "div.news-content", "div.article-content", "div.content", "div#content",
+			"div.entry-content", "div.main-content", "article", "main", "body"},
+		Author:        []string{".author", ".post-author"},
+		Date:          []string{"meta[property=article:published_time]", "time", ".date", ".post-date"},
+		Remove:        []string{"nav", "header", "footer", "form", ".ads", ".advertisement", ".social-share", ".breadcrumbs"},
+		DefaultAuthor: "Dr. Ulrich Strunz",
+		URLPrefix:     "https://www.strunz.com/news/",
+	},
+	"forum": {
+		Title:     []string{"h1.page-title", "h1", "meta[property=og:title]", "title"},
+		Posts:     "div.forum-post-wrapper",
+		Content:   []string{"div.post-content"},
+		Author:    []string{"span.forum-user-nickname"},
+		Date:      []string{"div.post-date"},
+		Remove:    []string{"div.post-date", "span.post-action", ".signature"},
+		URLPrefix: "https://www.strunz.com/forum/",
+	},
+}
+
+// loadProcessProfiles applies the profiles of a JSON file, if any, over
+// the defaults; a type in the file replaces the default of that type
+func loadProcessProfiles(path string) (map[string]*processProfile, error) {
+	profiles := maps.Clone(defaultProcessProfiles)
+	if path == "" {
+		return profiles, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var custom map[string]*processProfile
+	if err := json.Unmarshal(data, &custom); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	maps.Copy(profiles, custom)
+	return profiles, nil
+}
+
+// pageExtractor is a profile with its selectors compiled
+type pageExtractor struct {
+	profile                              *processProfile
+	title, content, author, date, remove []func(*htmlNode) bool
+	posts                                func(*htmlNode) bool
+}
+
+func newPageExtractor(name string, profile *processProfile) (*pageExtractor, error) {
+	e := &pageExtractor{profile: profile}
+	for _, field := range []struct {
+		name      string
+		selectors []string
+		matchers  *[]func(*htmlNode) bool
+	}{
+		{"title", profile.Title, &e.title},
+		{"content", profile.Content, &e.content},
+		{"author", profile.Author, &e.author},
+		{"date", profile.Date, &e.date},
+		{"remove", profile.Remove, &e.remove},
+	} {
+		for _, selector := range field.selectors {
+			match, err := compileSelector(selector)
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %w", name, field.name, err)
+			}
+			*field.matchers = append(*field.matchers, match)
+		}
+	}
+	if profile.Posts != "" {
+		var err error
+		if e.posts, err = compileSelector(profile.Posts); err != nil {
+			return nil, fmt.Errorf("%s.posts: %w", name, err)
+		}
+	}
+	if len(e.content) == 0 {
+		return nil, fmt.Errorf("%s: the profile has no content selectors", name)
+	}
+	return e, nil
+}
+
+// processedPost is one post of a page as the Python chunker reads it
+type processedPost struct {
+	Date   string // DD.MM.YYYY
+	Author string
+	Body   string
+}
+
+// convert renders a page as Markdown in the layout document_processor.py
+// parses: a header with source URL and date, then the posts separated by
+// --- lines, each with a date heading, title and author line. It returns
+// "" for a page without content.
+func (e *pageExtractor) convert(page []byte, rel string) string {
+	doc := parseHTML(string(page))
+	source := e.profile.URLPrefix + rel
+	if canonical := doc.Find(func(n *htmlNode) bool { return n.Tag == "link" && n.Attrs["rel"] == "canonical" }); canonical != nil && canonical.Attrs["href"] != "" {
+		source = canonical.Attrs["href"]
+	}
+	base, _ := url.Parse(source)
+	renderer := &markdownRenderer{base: base, headingShift: 3}
+
+	title := strings.TrimSuffix(path.Base(rel), path.Ext(rel))
+	titleNode := firstMatch(doc, e.title)
+	if titleNode != nil {
+		if text := nodeValue(titleNode); text != "" {
+			title = text
+		}
+	}
+
+	scopes := []*htmlNode{doc}
+	if e.posts != nil {
+		scopes = doc.FindAll(e.posts)
+	}
+	var posts []processedPost
+	for _, scope := range scopes {
+		post := processedPost{Author: e.profile.DefaultAuthor}
+		if node := firstMatch(scope, e.author); node != nil && nodeValue(node) != "" {
+			post.Author = nodeValue(node)
+		}
+		if node := firstMatch(scope, e.date); node != nil {
+			if date, ok := parseGermanDate(nodeValue(node)); ok {
+				post.Date = date[8:10] + "." + date[5:7] + "." + date[:4]
+			}
+		}
+		content := firstMatch(scope, e.content)
+		if content == nil {
+			if e.posts == nil {
+				continue
+			}
+			content = scope
+		}
+		// Metadata is read, so it can go from the text
+		for _, unwanted := range e.remove {
+			for _, node := range content.FindAll(unwanted) {
+				node.Remove()
+			}
+		}
+		if titleNode != nil && e.posts == nil {
+			for ancestor := titleNode.Parent; ancestor != nil; ancestor = ancestor.Parent {
+				if ancestor == content {
+					titleNode.Remove()
+					break
+				}
+			}
+		}
+		if post.Body = renderer.render(content); post.Body != "" {
+			posts = append(posts, post)
+		}
+	}
+	if len(posts) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\nSource URL: %s\n", title, source)
+	if updated := posts[len(posts)-1].Date; updated != "" {
+		fmt.Fprintf(&b, "Last Updated: %s\n", updated)
+	}
+	for i, post := range posts {
+		if i > 0 {
+			b.WriteString("\n---\n")
+		}
+		b.WriteString("\n")
+		if post.Date != "" {
+			fmt.Fprintf(&b, "## %s\n", post.Date)
+		}
+		fmt.Fprintf(&b, "### %s\n", title)
+		if post.Author != "" {
+			fmt.Fprintf(&b, "**Autor:** %s\n", post.Author)
+		}
+		fmt.Fprintf(&b, "\n%s\n", post.Body)
+	}
+	return b.String()
+}
+
+func firstMatch(n *htmlNode, matchers []func(*htmlNode) bool) *htmlNode {
+	for _, match := range matchers {
+		if found := n.Find(match); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// nodeValue is the text of an element on one line, or the machine-readable
+// value of meta and time elements
+func nodeValue(n *htmlNode) string {
+	for _, attr := range []string{"content", "datetime"} {
+		if value := strings.TrimSpace(n.Attrs[attr]); value != "" && (n.Tag == "meta" || n.Tag == "time") {
+			return value
+		}
+	}
+	return strings.Join(strings.Fields(n.InnerText()), " ")
+}
+
+func newKBProcessCommand() *Command {
+	cmd := newCommand("process", "[raw-dir]", "Convert scraped HTML into clean Markdown for chunking, one file per page, with selectors per content type.")
+	outputDir := cmd.Flags.String("output-dir", "", "directory for the Markdown, one subdirectory per content type (default: data/processed/markdown in the repository)")
+	selectors := cmd.Flags.String("selectors", "", "JSON file of selector profiles by content type, replacing the built-in profile of each type it names")
+	types := cmd.Flags.String("type", "", "comma-separated content types to process (default: every subdirectory with a profile)")
+	printSelectors := cmd.Flags.Bool("print-selectors", false, "print the selector profiles in effect as a starting point for --selectors and exit")
+	check := cmd.Flags.Bool("check", false, "write nothing; fail when any Markdown file is missing or out of date")
+
+	cmd.Run = func(args []string) error {
+		if len(args) > 1 {
+			return cmd.ExactArgs(args, 1)
+		}
+		profiles, err := loadProcessProfiles(*selectors)
+		if err != nil {
+			return err
+		}
+		if *printSelectors {
+			data, err := json.MarshalIndent(profiles, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+			return nil
+		}
+
+		rawDir := ""
+		if len(args) == 1 {
+			rawDir = args[0]
+		} else if rawDir, err = repoPath("data/raw"); err != nil {
+			return err
+		}
+		if *outputDir == "" {
+			if *outputDir, err = repoPath("data/processed/markdown"); err != nil {
+				return err
+			}
+		}
+		selected := splitList(*types)
+		if len(selected) == 0 {
+			entries, err := os.ReadDir(rawDir)
+			if err != nil {
+				return fmt.Errorf("%w: %v", errUsage, err)
+			}
+			for _, entry := range entries {
+				if _, ok := profiles[entry.Name()]; ok && entry.IsDir() {
+					selected = append(selected, entry.Name())
+				}
+			}
+			if len(selected) == 0 {
+				return fmt.Errorf("%w: no directory in %s has a selector profile (%s)", errUsage, rawDir, strings.Join(slices.Sorted(maps.Keys(profiles)), ", "))
+			}
+		}
+
+		var written, unchanged, empty, stale int
+		for _, name := range selected {
+			profile, ok := profiles[name]
+			if !ok {
+				return fmt.Errorf("%w: no selector profile for content type %q", errUsage, name)
+			}
+			extractor, err := newPageExtractor(name, profile)
+			if err != nil {
+				return fmt.Errorf("%w: %v", errUsage, err)
+			}
+			typeDir := filepath.Join(rawDir, name)
+			var pages []string
+			err = filepath.WalkDir(typeDir, func(path string, entry fs.DirEntry, err error) error {
+				if err == nil && !entry.IsDir() && (strings.HasSuffix(path, ".html") || strings.HasSuffix(path, ".htm")) {
+					pages = append(pages, path)
+				}
+				return err
+			})
+			if err != nil {
+				return err
+			}
+			fmt.Printf("\n📝 %s: converting %d page(s) from %s\n", name, len(pages), typeDir)
+
+			for _, page := range pages {
+				rel, _ := filepath.Rel(typeDir, page)
+				data, err := os.ReadFile(page)
+				if err != nil {
+					return err
+				}
+				markdown := extractor.convert(data, filepath.ToSlash(rel))
+				if markdown == "" {
+					empty++
+					fmt.Printf("⚠️  %s: no content found with the %s selectors\n", rel, name)
+					continue
+				}
+				target := filepath.Join(*outputDir, name, strings.TrimSuffix(rel, filepath.Ext(rel))+".md")
+				if existing, err := os.ReadFile(target); err == nil && bytes.Equal(existing, []byte(markdown)) {
+					unchanged++
+					continue
+				} else if err != nil && !errors.Is(err, os.ErrNotExist) {
+					return err
+				}
+				if *check {
+					stale++
+					fmt.Printf("❌ %s is missing or out of date\n", target)
+					continue
+				}
+				if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+					return err
+				}
+				if err := os.WriteFile(target, []byte(markdown), 0o644); err != nil {
+					return err
+				}
+				written++
+			}
+		}
+
+		fmt.Printf("\n📊 %d written, %d unchanged, %d without content", written, unchanged, empty)
+		if *check {
+			fmt.Printf(", %d out of date", stale)
+		}
+		fmt.Printf(" in %s\n", *outputDir)
+		if stale > 0 {
+			return fmt.Errorf("%w: %d Markdown file(s) are out of date; run kb process", errPolicy, stale)
+		}
+		return nil
+	}
+	return cmd
+}
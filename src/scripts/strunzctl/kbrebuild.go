@@ -0,0 +1,534 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	combinedIndexFile    = "combined_index.faiss"
+	combinedMetadataFile = "combined_metadata.json"
+	// pythonTimestamp is how the Python pipeline writes created_date and
+	// last_updated, datetime.isoformat() in local time
+	pythonTimestamp = "2006-01-02T15:04:05.999999"
+)
+
+// pendingDelta is a scrape delta the index has not taken in yet
+type pendingDelta struct {
+	name  string
+	delta *scrapeDelta
+	at    time.Time
+}
+
+// pendingDeltas returns the news_delta_*.json files in dir generated after
+// the index was built and not applied to it, oldest first
+func pendingDeltas(dir string, built time.Time, applied []string) ([]pendingDelta, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "news_delta_*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var pending []pendingDelta
+	for _, path := range paths {
+		name := filepath.Base(path)
+		if slices.Contains(applied, name) {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		delta := &scrapeDelta{}
+		if err := json.Unmarshal(data, delta); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		at, err := time.Parse(time.RFC3339, delta.Generated)
+		if err != nil {
+			return nil, fmt.Errorf("%s: generated is not a timestamp: %q", path, delta.Generated)
+		}
+		if at.After(built) {
+			pending = append(pending, pendingDelta{name: name, delta: delta, at: at})
+		}
+	}
+	slices.SortFunc(pending, func(a, b pendingDelta) int { return a.at.Compare(b.at) })
+	return pending, nil
+}
+
+// foldDeltas merges deltas into one change set, URL → the article to embed,
+// or nil for a removed page. A later delta overrides an earlier one.
+func foldDeltas(dir string, deltas []pendingDelta) (map[string]*newsArticle, error) {
+	changes := make(map[string]*newsArticle)
+	for _, pending := range deltas {
+		var results scrapeResults
+		if len(pending.delta.Reembed) > 0 {
+			data, err := os.ReadFile(filepath.Join(dir, pending.delta.Results))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", pending.name, err)
+			}
+			if err := json.Unmarshal(data, &results); err != nil {
+				return nil, fmt.Errorf("failed to parse %s: %w", pending.delta.Results, err)
+			}
+		}
+		articles := make(map[string]*newsArticle, len(results.News))
+		for i := range results.News {
+			articles[results.News[i].SourceURL] = &results.News[i]
+		}
+		for _, page := range pending.delta.Reembed {
+			article, ok := articles[page]
+			if !ok {
+				return nil, fmt.Errorf("%s lists %s for embedding, but %s does not have it", pending.name, page, pending.delta.Results)
+			}
+			changes[page] = article
+		}
+		for _, entry := range pending.delta.Removed {
+			changes[entry.URL] = nil
+		}
+	}
+	return changes, nil
+}
+
+// parseBuildTime reads the created_date or last_updated of a metadata file
+func parseBuildTime(value string) (time.Time, error) {
+	if at, err := time.Parse(time.RFC3339, value); err == nil {
+		return at, nil
+	}
+	return time.ParseInLocation(pythonTimestamp, value, time.Local)
+}
+
+// readSplitFile reassembles the chunks of one split file for read
+func readSplitFile(manifests []*splitManifest, name string, read func(io.Reader) error) error {
+	index := slices.IndexFunc(manifests, func(m *splitManifest) bool { return m.OriginalFile == name })
+	if index < 0 {
+		return fmt.Errorf("%w: no split manifest for %s", errUsage, name)
+	}
+	if problems := manifests[index].checkChunks(); len(problems) > 0 {
+		return fmt.Errorf("%s: %s; run kb verify-index", name, strings.Join(problems, "; "))
+	}
+	reader, closeChunks, err := manifests[index].open()
+	if err != nil {
+		return err
+	}
+	defer closeChunks()
+	return read(reader)
+}
+
+// readFlatVectors reads a flat FAISS index into its header and the raw
+// bytes of its vectors
+func readFlatVectors(r io.Reader) (flatIndexHeader, []byte, error) {
+	var header flatIndexHeader
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return header, nil, fmt.Errorf("failed to read the index header: %w", err)
+	}
+	if _, ok := flatIndexTypes[string(header.FourCC[:])]; !ok {
+		return header, nil, fmt.Errorf("unsupported index type %q (want one of IxF2, IxFI)", header.FourCC[:])
+	}
+	if header.Dim <= 0 || header.Count < 0 || header.Floats != uint64(header.Count)*uint64(header.Dim) {
+		return header, nil, fmt.Errorf("header says %d vectors of %d dimensions but stores %d floats", header.Count, header.Dim, header.Floats)
+	}
+	vectors := make([]byte, header.Floats*4)
+	if _, err := io.ReadFull(r, vectors); err != nil {
+		return header, nil, fmt.Errorf("failed to read the vectors: %w", err)
+	}
+	return header, vectors, nil
+}
+
+// writeFlatIndex writes a flat FAISS index the way faiss.write_index does
+func writeFlatIndex(path string, header flatIndexHeader, vectors []byte) error {
+	header.Count = int64(len(vectors) / 4 / int(header.Dim))
+	header.Floats = uint64(len(vectors) / 4)
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	writer := bufio.NewWriterSize(file, 1<<20)
+	if err := binary.Write(writer, binary.LittleEndian, header); err != nil {
+		return err
+	}
+	if _, err := writer.Write(vectors); err != nil {
+		return err
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// splitFile cuts a file into chunks of chunkSize in dir, named and listed
+// the way src/scripts/data/split_faiss_index.py does, with its sha256
+func splitFile(path, dir string, chunkSize int64) (*splitManifest, error) {
+	name := filepath.Base(path)
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	manifest := &splitManifest{OriginalFile: name, OriginalSize: info.Size(), ChunkSize: chunkSize,
+		path: filepath.Join(dir, name+".metadata.json")}
+	hash := sha256.New()
+	reader := io.TeeReader(file, hash)
+	for written := int64(0); written < info.Size() || len(manifest.Chunks) == 0; {
+		chunk := fmt.Sprintf("%s.part%03d", name, len(manifest.Chunks))
+		out, err := os.Create(filepath.Join(dir, chunk))
+		if err != nil {
+			return nil, err
+		}
+		n, err := io.CopyN(out, reader, chunkSize)
+		out.Close()
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("failed to write %s: %w", chunk, err)
+		}
+		manifest.Chunks = append(manifest.Chunks, chunk)
+		written += n
+	}
+	manifest.NumChunks = len(manifest.Chunks)
+	manifest.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	return manifest, writeSplitManifest(manifest)
+}
+
+// installChunks moves the split files of staging into dir and removes the
+// chunks of the previous split that the new one does not overwrite
+func installChunks(staging, dir string, manifests []*splitManifest) error {
+	for _, manifest := range manifests {
+		old, err := filepath.Glob(filepath.Join(dir, manifest.OriginalFile+".part*"))
+		if err != nil {
+			return err
+		}
+		for _, name := range append(slices.Clone(manifest.Chunks), filepath.Base(manifest.path)) {
+			if err := os.Rename(filepath.Join(staging, name), filepath.Join(dir, name)); err != nil {
+				return fmt.Errorf("failed to install %s: %w", name, err)
+			}
+		}
+		for _, path := range old {
+			if !slices.Contains(manifest.Chunks, filepath.Base(path)) {
+				if err := os.Remove(path); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func newKBRebuildCommand() *Command {
+	cmd := newCommand("rebuild", "", "Re-embed only the documents the scrape deltas changed since the last index build, merge them into the combined index, verify and optionally upload it.")
+	chunksDir := cmd.Flags.String("chunks-dir", "", "directory of the split combined index (default: "+indexChunksDir+" in the repository)")
+	deltasDir := cmd.Flags.String("deltas", "", "directory of the news_delta_*.json files and the results they name (default: data/scraped in the repository)")
+	python := cmd.Flags.String("python", "python3", "Python interpreter with the embedding pipeline's requirements")
+	script := cmd.Flags.String("embed-script", "src/rag/embed_delta.py", "script that chunks and embeds the changed articles, relative to the repository root")
+	dryRun := cmd.Flags.Bool("dry-run", false, "show what would be re-embedded and removed without changing anything")
+	upload := cmd.Flags.String("upload", "", "attach the rebuilt index and metadata to the GitHub release with this tag")
+	keepWork := cmd.Flags.Bool("keep-work", false, "keep the working directory with the embedding input and output")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 0); err != nil {
+			return err
+		}
+		root, err := repoPath("")
+		if err != nil {
+			return err
+		}
+		if *chunksDir == "" {
+			*chunksDir = filepath.Join(root, indexChunksDir)
+		}
+		if *deltasDir == "" {
+			*deltasDir = filepath.Join(root, "data", "scraped")
+		}
+		manifests, err := loadSplitManifests(*chunksDir)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("\n🔁 Rebuilding the index in %s from the deltas in %s\n", *chunksDir, *deltasDir)
+		var metadata map[string]json.RawMessage
+		err = readSplitFile(manifests, combinedMetadataFile, func(r io.Reader) error {
+			return json.NewDecoder(r).Decode(&metadata)
+		})
+		if err != nil {
+			return err
+		}
+		// The rest of the metadata is carried over as it is
+		var header struct {
+			Documents      []json.RawMessage
+			EmbeddingModel string
+			EmbeddingDim   int
+			CreatedDate    string
+			LastUpdated    string
+			Deltas         []string // delta files applied since the full build
+		}
+		for key, v := range map[string]any{"documents": &header.Documents, "embedding_model": &header.EmbeddingModel,
+			"embedding_dim": &header.EmbeddingDim, "created_date": &header.CreatedDate, "last_updated": &header.LastUpdated, "deltas": &header.Deltas} {
+			if raw, ok := metadata[key]; ok {
+				if err := json.Unmarshal(raw, v); err != nil {
+					return fmt.Errorf("failed to parse %s: %s: %w", combinedMetadataFile, key, err)
+				}
+			}
+		}
+		built, err := parseBuildTime(cmp.Or(header.LastUpdated, header.CreatedDate))
+		if err != nil {
+			return fmt.Errorf("%s records no build time: %w", combinedMetadataFile, err)
+		}
+
+		deltas, err := pendingDeltas(*deltasDir, built, header.Deltas)
+		if err != nil {
+			return err
+		}
+		changes, err := foldDeltas(*deltasDir, deltas)
+		if err != nil {
+			return err
+		}
+		var reembed []newsArticle
+		removed := 0
+		for _, page := range slices.Sorted(maps.Keys(changes)) {
+			if article := changes[page]; article != nil {
+				reembed = append(reembed, *article)
+			} else {
+				removed++
+			}
+		}
+		fmt.Printf("📋 Built %s; %d delta(s) since: %d article(s) to embed, %d to remove\n",
+			built.Format(time.DateTime), len(deltas), len(reembed), removed)
+		for _, pending := range deltas {
+			fmt.Printf("   %s: %d added, %d changed, %d removed\n", pending.name, len(pending.delta.Added), len(pending.delta.Changed), len(pending.delta.Removed))
+		}
+		if len(changes) == 0 {
+			fmt.Println("✅ The index is up to date")
+			return nil
+		}
+
+		// The documents of a changed or removed page go, with their vectors
+		type documentPage struct {
+			Metadata struct {
+				Source string `json:"source"`
+				URL    string `json:"url"`
+			} `json:"metadata"`
+		}
+		var keep []int
+		for i, raw := range header.Documents {
+			var doc documentPage
+			if err := json.Unmarshal(raw, &doc); err != nil {
+				return fmt.Errorf("%s: document %d: %w", combinedMetadataFile, i, err)
+			}
+			if _, changed := changes[doc.Metadata.URL]; doc.Metadata.Source == "news" && changed {
+				continue
+			}
+			keep = append(keep, i)
+		}
+		fmt.Printf("📋 %d of %d document(s) will be replaced or dropped\n", len(header.Documents)-len(keep), len(header.Documents))
+		if *dryRun {
+			for _, page := range slices.Sorted(maps.Keys(changes)) {
+				action := "embed "
+				if changes[page] == nil {
+					action = "remove"
+				}
+				fmt.Printf("   %s %s\n", action, page)
+			}
+			return nil
+		}
+
+		work, err := os.MkdirTemp(filepath.Dir(*chunksDir), ".rebuild-")
+		if err != nil {
+			return err
+		}
+		if *keepWork {
+			fmt.Printf("📁 Working directory: %s\n", work)
+		} else {
+			defer os.RemoveAll(work)
+		}
+
+		// Only the changed articles go through the Python pipeline
+		input, err := json.MarshalIndent(scrapeResults{News: reembed, Forums: map[string]any{}}, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(work, "articles.json"), input, 0o644); err != nil {
+			return err
+		}
+		slog.Info("Embedding changed articles", "articles", len(reembed), "script", *script)
+		embed := newProcess(*python, filepath.Join(root, *script), "--input", filepath.Join(work, "articles.json"), "--output-dir", work)
+		embed.Dir = root
+		embed.Stdout = os.Stderr
+		embed.Stderr = os.Stderr
+		if err := embed.Run(); err != nil {
+			return fmt.Errorf("the embedding pipeline failed: %w", err)
+		}
+		var embedded corpusMetadata
+		data, err := os.ReadFile(filepath.Join(work, "documents.json"))
+		if err != nil {
+			return fmt.Errorf("the embedding pipeline wrote no documents: %w", err)
+		}
+		if err := json.Unmarshal(data, &embedded); err != nil {
+			return fmt.Errorf("failed to parse the embedded documents: %w", err)
+		}
+		if embedded.EmbeddingModel != header.EmbeddingModel || embedded.EmbeddingDim != header.EmbeddingDim {
+			return fmt.Errorf("the pipeline embedded with %s (%d dimensions), the index with %s (%d dimensions)",
+				embedded.EmbeddingModel, embedded.EmbeddingDim, header.EmbeddingModel, header.EmbeddingDim)
+		}
+		file, err := os.Open(filepath.Join(work, "index.faiss"))
+		if err != nil {
+			return fmt.Errorf("the embedding pipeline wrote no index: %w", err)
+		}
+		newHeader, newVectors, err := readFlatVectors(bufio.NewReader(file))
+		file.Close()
+		if err != nil {
+			return fmt.Errorf("index.faiss: %w", err)
+		}
+		if newHeader.Count != int64(len(embedded.Documents)) || int(newHeader.Dim) != header.EmbeddingDim {
+			return fmt.Errorf("the pipeline wrote %d vectors of %d dimensions for %d documents", newHeader.Count, newHeader.Dim, len(embedded.Documents))
+		}
+
+		// Merge: kept vectors in their order, then the new ones
+		var indexHeader flatIndexHeader
+		var vectors []byte
+		err = readSplitFile(manifests, combinedIndexFile, func(r io.Reader) error {
+			var err error
+			indexHeader, vectors, err = readFlatVectors(r)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("%s: %w", combinedIndexFile, err)
+		}
+		if indexHeader.Count != int64(len(header.Documents)) || indexHeader.FourCC != newHeader.FourCC {
+			return fmt.Errorf("%w: %s does not match its metadata or the new vectors; run kb verify-index", errPolicy, combinedIndexFile)
+		}
+		row := int(indexHeader.Dim) * 4
+		merged := make([]byte, 0, (len(keep)+len(embedded.Documents))*row)
+		documents := make([]json.RawMessage, 0, len(keep)+len(embedded.Documents))
+		for _, i := range keep {
+			merged = append(merged, vectors[i*row:(i+1)*row]...)
+			documents = append(documents, header.Documents[i])
+		}
+		merged = append(merged, newVectors...)
+		documents = append(documents, embedded.Documents...)
+
+		counts := make(map[string]int)
+		var sourceCounts map[string]int
+		json.Unmarshal(metadata["source_counts"], &sourceCounts)
+		for _, raw := range documents {
+			var doc documentPage
+			json.Unmarshal(raw, &doc)
+			key := doc.Metadata.Source
+			if _, ok := sourceCounts[key]; !ok && sourceCounts[key+"s"] > 0 {
+				key += "s"
+			}
+			counts[key]++
+		}
+		set := func(key string, value any) {
+			metadata[key], _ = json.Marshal(value)
+		}
+		set("documents", documents)
+		set("total_documents", len(documents))
+		set("source_counts", counts)
+		set("last_updated", time.Now().Format(pythonTimestamp))
+		for _, pending := range deltas {
+			header.Deltas = append(header.Deltas, pending.name)
+		}
+		set("deltas", header.Deltas)
+
+		indexPath := filepath.Join(work, combinedIndexFile)
+		metadataPath := filepath.Join(work, combinedMetadataFile)
+		if err := writeFlatIndex(indexPath, indexHeader, merged); err != nil {
+			return fmt.Errorf("failed to write %s: %w", combinedIndexFile, err)
+		}
+		out, err := os.Create(metadataPath)
+		if err != nil {
+			return err
+		}
+		writer := bufio.NewWriterSize(out, 1<<20)
+		encoder := json.NewEncoder(writer)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(metadata); err != nil {
+			out.Close()
+			return fmt.Errorf("failed to write %s: %w", combinedMetadataFile, err)
+		}
+		if err := writer.Flush(); err != nil {
+			out.Close()
+			return err
+		}
+		if err := out.Close(); err != nil {
+			return err
+		}
+		slog.Info("Index merged", "documents", len(documents), "kept", len(keep), "embedded", len(embedded.Documents))
+
+		// Split into a staging directory and verify before replacing
+		// anything, with the chunk size of the current split
+		staging := filepath.Join(work, "chunks")
+		if err := os.Mkdir(staging, 0o755); err != nil {
+			return err
+		}
+		var staged []*splitManifest
+		for _, path := range []string{indexPath, metadataPath} {
+			chunkSize := int64(40 << 20)
+			for _, manifest := range manifests {
+				if manifest.OriginalFile == filepath.Base(path) && manifest.ChunkSize > 0 {
+					chunkSize = manifest.ChunkSize
+				}
+			}
+			manifest, err := splitFile(path, staging, chunkSize)
+			if err != nil {
+				return err
+			}
+			staged = append(staged, manifest)
+		}
+		problems, err := verifyIndexChunks(staging, true, false)
+		if err != nil {
+			return err
+		}
+		if len(problems) > 0 {
+			return fmt.Errorf("%w: the rebuilt index is inconsistent (%d problem(s)); the current index is unchanged", errPolicy, len(problems))
+		}
+		if err := installChunks(staging, *chunksDir, staged); err != nil {
+			return err
+		}
+		fmt.Printf("\n✅ Rebuilt %s: %d document(s), %d re-embedded from %d article(s), %d page(s) removed\n",
+			*chunksDir, len(documents), len(embedded.Documents), len(reembed), removed)
+
+		if *upload == "" {
+			return nil
+		}
+		github, err := newGitHubClient()
+		if err != nil {
+			return err
+		}
+		release, err := findRelease(github, *upload)
+		if err != nil {
+			return err
+		}
+		if release == nil {
+			return fmt.Errorf("%w: there is no release %s to upload to", errUsage, *upload)
+		}
+		for _, path := range []string{indexPath, metadataPath} {
+			name := filepath.Base(path)
+			for _, asset := range release.Assets {
+				if asset.Name == name {
+					if err := github.Delete(fmt.Sprintf("/repos/%s/releases/assets/%d", config.Repo, asset.ID)); err != nil {
+						return fmt.Errorf("failed to replace %s: %w", name, err)
+					}
+				}
+			}
+			if err := github.UploadAsset(release, name, path); err != nil {
+				return err
+			}
+			fmt.Printf("✅ Uploaded %s to %s\n", name, release.HTMLURL)
+		}
+		return nil
+	}
+	return cmd
+}
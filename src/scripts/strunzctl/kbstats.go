@@ -0,0 +1,350 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// kbStatsMarker and kbStatsEndMarker delimit the generated section of the
+// release notes, so updating it replaces the section in place
+const (
+	kbStatsMarker    = "<!-- strunzctl:kb-stats -->"
+	kbStatsEndMarker = "<!-- /strunzctl:kb-stats -->"
+)
+
+// chunkSizeBuckets are the upper bounds, in characters, of the chunk size
+// histogram; the splitters aim at 1000
+var chunkSizeBuckets = []int{200, 500, 800, 1000, 1200, 1500}
+
+// kbStats describes the corpus. It holds no timestamp of its own, so the
+// same corpus always gives the same report.
+type kbStats struct {
+	Corpus         string        `json:"corpus"`
+	Created        string        `json:"created,omitempty"`
+	EmbeddingModel string        `json:"embedding_model,omitempty"`
+	EmbeddingDim   int           `json:"embedding_dim,omitempty"`
+	Chunks         int           `json:"chunks"`
+	Documents      int           `json:"documents"`
+	Tokens         int           `json:"tokens"` // estimated as characters / 4
+	Sources        []sourceStats `json:"sources"`
+	ChunkChars     distribution  `json:"chunk_chars"`
+	ChunkSizes     []countEntry  `json:"chunk_sizes"`
+	Years          []countEntry  `json:"years"`
+	TopAuthors     []countEntry  `json:"top_authors"`
+	TopTopics      []countEntry  `json:"top_topics"`
+	Undated        int           `json:"undated_chunks"`
+	bySource       map[string]*sourceStats
+}
+
+type sourceStats struct {
+	Source            string       `json:"source"`
+	Chunks            int          `json:"chunks"`
+	Documents         int          `json:"documents"`
+	Tokens            int          `json:"tokens"`
+	TokensPerDocument distribution `json:"tokens_per_document"`
+	FirstDate         string       `json:"first_date,omitempty"`
+	LastDate          string       `json:"last_date,omitempty"`
+
+	documentTokens map[string]int
+}
+
+// distribution summarizes a set of sizes
+type distribution struct {
+	Min    int `json:"min"`
+	Median int `json:"median"`
+	P90    int `json:"p90"`
+	Max    int `json:"max"`
+	Mean   int `json:"mean"`
+}
+
+type countEntry struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+func newDistribution(values []int) distribution {
+	if len(values) == 0 {
+		return distribution{}
+	}
+	sorted := slices.Sorted(slices.Values(values))
+	total := 0
+	for _, value := range sorted {
+		total += value
+	}
+	return distribution{Min: sorted[0], Median: sorted[len(sorted)/2], P90: sorted[len(sorted)*9/10], Max: sorted[len(sorted)-1], Mean: total / len(sorted)}
+}
+
+// estimateTokens is the usual rule of thumb for the multilingual MiniLM
+// tokenizer on German prose
+func estimateTokens(text string) int {
+	return (len([]rune(text)) + 3) / 4
+}
+
+// documentKey groups the chunks of one page, post thread or book
+func documentKey(doc *corpusDocument) string {
+	for _, key := range []string{"url", "file_path", "filename"} {
+		if value := doc.meta(key); value != "" {
+			return value
+		}
+	}
+	return doc.Title
+}
+
+// documentDate is the date of a chunk as YYYY-MM-DD, or just the year
+// for books
+func documentDate(doc *corpusDocument) string {
+	for _, key := range []string{"date", "post_date", "year"} {
+		if value := doc.meta(key); len(value) >= 4 {
+			if date, ok := parseGermanDate(value); ok {
+				return date[:10]
+			}
+			return value[:4]
+		}
+	}
+	return ""
+}
+
+func collectKBStats(c *corpus, top int) *kbStats {
+	stats := &kbStats{Corpus: filepath.Base(c.path), Created: c.CreatedDate, EmbeddingModel: c.EmbeddingModel, EmbeddingDim: c.EmbeddingDim,
+		Chunks: len(c.Documents), bySource: make(map[string]*sourceStats)}
+	var chars []int
+	sizes := make([]int, len(chunkSizeBuckets)+1)
+	years, authors, topics := make(map[string]int), make(map[string]int), make(map[string]int)
+	for i := range c.Documents {
+		doc := &c.Documents[i]
+		source := orNone(doc.meta("source"))
+		s, ok := stats.bySource[source]
+		if !ok {
+			s = &sourceStats{Source: source, documentTokens: make(map[string]int)}
+			stats.bySource[source] = s
+		}
+		tokens := estimateTokens(doc.Text)
+		s.Chunks++
+		s.Tokens += tokens
+		s.documentTokens[documentKey(doc)] += tokens
+		stats.Tokens += tokens
+
+		length := len([]rune(doc.Text))
+		chars = append(chars, length)
+		bucket, _ := slices.BinarySearch(chunkSizeBuckets, length)
+		sizes[bucket]++
+
+		if date := documentDate(doc); date != "" {
+			years[date[:4]]++
+			if s.FirstDate == "" || date < s.FirstDate {
+				s.FirstDate = date
+			}
+			s.LastDate = max(s.LastDate, date)
+		} else {
+			stats.Undated++
+		}
+		if author := doc.meta("author"); author != "" {
+			authors[author]++
+		} else if author := doc.meta("post_author"); author != "" && author != "disabled" {
+			authors[author]++
+		}
+		switch {
+		case doc.meta("category") != "":
+			topics[source+": "+doc.meta("category")]++
+		case source == "book" && doc.Title != "":
+			topics[source+": "+doc.Title]++
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(stats.bySource)) {
+		s := stats.bySource[name]
+		s.Documents = len(s.documentTokens)
+		s.TokensPerDocument = newDistribution(slices.Collect(maps.Values(s.documentTokens)))
+		stats.Documents += s.Documents
+		stats.Sources = append(stats.Sources, *s)
+	}
+	stats.ChunkChars = newDistribution(chars)
+	for i, count := range sizes {
+		label := fmt.Sprintf("> %d", chunkSizeBuckets[len(chunkSizeBuckets)-1])
+		if i < len(chunkSizeBuckets) {
+			lower := 0
+			if i > 0 {
+				lower = chunkSizeBuckets[i-1] + 1
+			}
+			label = fmt.Sprintf("%d–%d", lower, chunkSizeBuckets[i])
+		}
+		stats.ChunkSizes = append(stats.ChunkSizes, countEntry{Name: label, Count: count})
+	}
+	for _, year := range slices.Sorted(maps.Keys(years)) {
+		stats.Years = append(stats.Years, countEntry{Name: year, Count: years[year]})
+	}
+	stats.TopAuthors = topCounts(authors, top)
+	stats.TopTopics = topCounts(topics, top)
+	return stats
+}
+
+// topCounts returns the n largest counts, ties by name
+func topCounts(counts map[string]int, n int) []countEntry {
+	entries := []countEntry{}
+	for name, count := range counts {
+		entries = append(entries, countEntry{Name: name, Count: count})
+	}
+	slices.SortFunc(entries, func(a, b countEntry) int {
+		if a.Count != b.Count {
+			return b.Count - a.Count
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	return entries[:min(n, len(entries))]
+}
+
+// formatCount groups the thousands of n, e.g. 43,373
+func formatCount(n int) string {
+	digits := strconv.Itoa(n)
+	var b strings.Builder
+	for i, digit := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(digit)
+	}
+	return b.String()
+}
+
+// writeKBStatsMarkdown renders the "Knowledge Base Contents" section of
+// the release notes
+func writeKBStatsMarkdown(w io.Writer, stats *kbStats) error {
+	var b strings.Builder
+	b.WriteString("## Knowledge Base Contents\n\n")
+	fmt.Fprintf(&b, "%s chunks of %s documents from %d sources, about %s tokens", formatCount(stats.Chunks), formatCount(stats.Documents),
+		len(stats.Sources), formatCount(stats.Tokens))
+	if stats.EmbeddingModel != "" {
+		fmt.Fprintf(&b, ", embedded with `%s` (%d dimensions)", stats.EmbeddingModel, stats.EmbeddingDim)
+	}
+	if len(stats.Created) >= 10 {
+		fmt.Fprintf(&b, " on %s", stats.Created[:10])
+	}
+	b.WriteString(".\n\n")
+
+	b.WriteString("| Source | Documents | Chunks | Tokens | Tokens per document (median / p90) | Dates |\n")
+	b.WriteString("| --- | ---: | ---: | ---: | ---: | --- |\n")
+	for _, s := range stats.Sources {
+		dates := "–"
+		if s.FirstDate != "" {
+			dates = s.FirstDate + " – " + s.LastDate
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s | %s | %s / %s | %s |\n", s.Source, formatCount(s.Documents), formatCount(s.Chunks), formatCount(s.Tokens),
+			formatCount(s.TokensPerDocument.Median), formatCount(s.TokensPerDocument.P90), dates)
+	}
+
+	fmt.Fprintf(&b, "\n### Chunk sizes\n\nMedian %s characters, 90%% below %s, longest %s.\n\n| Characters | Chunks |\n| --- | ---: |\n",
+		formatCount(stats.ChunkChars.Median), formatCount(stats.ChunkChars.P90), formatCount(stats.ChunkChars.Max))
+	for _, bucket := range stats.ChunkSizes {
+		fmt.Fprintf(&b, "| %s | %s |\n", bucket.Name, formatCount(bucket.Count))
+	}
+
+	b.WriteString("\n### Date coverage\n\n| Year | Chunks |\n| --- | ---: |\n")
+	for _, year := range stats.Years {
+		fmt.Fprintf(&b, "| %s | %s |\n", year.Name, formatCount(year.Count))
+	}
+	if stats.Undated > 0 {
+		fmt.Fprintf(&b, "| undated | %s |\n", formatCount(stats.Undated))
+	}
+
+	for _, table := range []struct {
+		title   string
+		entries []countEntry
+	}{{"Top authors", stats.TopAuthors}, {"Top topics", stats.TopTopics}} {
+		if len(table.entries) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n### %s\n\n| Name | Chunks |\n| --- | ---: |\n", table.title)
+		for _, entry := range table.entries {
+			fmt.Fprintf(&b, "| %s | %s |\n", strings.ReplaceAll(entry.Name, "|", `\|`), formatCount(entry.Count))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// withKBStatsSection replaces the generated section of the notes, or adds
+// it at the end when the notes have none yet
+func withKBStatsSection(notes, section string) string {
+	section = kbStatsMarker + "\n" + strings.TrimRight(section, "\n") + "\n" + kbStatsEndMarker
+	start := strings.Index(notes, kbStatsMarker)
+	end := strings.Index(notes, kbStatsEndMarker)
+	if start >= 0 && end > start {
+		return notes[:start] + section + notes[end+len(kbStatsEndMarker):]
+	}
+	return strings.TrimRight(notes, "\n") + "\n\n" + section + "\n"
+}
+
+func newKBStatsCommand() *Command {
+	cmd := newCommand("stats", "", "Report the contents of the corpus per source: documents, tokens, chunk sizes, dates, authors and topics.")
+	metadata := addCorpusFlag(cmd)
+	format := cmd.Flags.String("format", "markdown", "report format (markdown or json)")
+	output := cmd.Flags.String("output", "", "write the report to a file instead of stdout")
+	notes := cmd.Flags.String("notes", "", "update the Knowledge Base Contents section of this release notes file")
+	top := cmd.Flags.Int("top", 10, "authors and topics to list")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 0); err != nil {
+			return err
+		}
+		var write func(io.Writer, *kbStats) error
+		switch *format {
+		case "markdown", "md":
+			write = writeKBStatsMarkdown
+		case "json":
+			write = func(w io.Writer, stats *kbStats) error {
+				data, err := json.MarshalIndent(stats, "", "  ")
+				if err == nil {
+					_, err = fmt.Fprintln(w, string(data))
+				}
+				return err
+			}
+		default:
+			return fmt.Errorf("%w: unsupported report format %q", errUsage, *format)
+		}
+		c, err := loadCorpus(*metadata)
+		if err != nil {
+			return err
+		}
+		stats := collectKBStats(c, *top)
+
+		if *notes != "" {
+			data, err := os.ReadFile(*notes)
+			if err != nil {
+				return fmt.Errorf("failed to read release notes: %w", err)
+			}
+			var section strings.Builder
+			if err := writeKBStatsMarkdown(&section, stats); err != nil {
+				return err
+			}
+			if err := os.WriteFile(*notes, []byte(withKBStatsSection(string(data), section.String())), 0o644); err != nil {
+				return fmt.Errorf("failed to update release notes: %w", err)
+			}
+			fmt.Printf("✅ Updated the Knowledge Base Contents of %s\n", *notes)
+			if *output == "" {
+				return nil
+			}
+		}
+
+		out := os.Stdout
+		if *output != "" {
+			file, err := os.Create(*output)
+			if err != nil {
+				return fmt.Errorf("failed to create report: %w", err)
+			}
+			defer file.Close()
+			out = file
+		}
+		if err := write(out, stats); err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
+		}
+		return nil
+	}
+	return cmd
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// corpusDocumentSchema is the contract between the processors and the
+// index builder. Bump the version in $id with any change that makes
+// records valid which were not, or the reverse.
+const corpusDocumentSchema = `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "$id": "https://github.com/longevitycoach/StrunzKnowledge/schemas/corpus-document/v1",
+  "title": "Corpus document v1",
+  "type": "object",
+  "required": ["text", "metadata"],
+  "properties": {
+    "text": {"type": "string", "pattern": "\\S"},
+    "title": {"type": ["string", "null"]},
+    "metadata": {"$ref": "#/$defs/metadata"}
+  },
+  "$defs": {
+    "date": {"type": "string", "pattern": "^\\d{4}-\\d{2}-\\d{2}$"},
+    "metadata": {
+      "type": "object",
+      "required": ["source", "content_type", "filename", "processed_date"],
+      "properties": {
+        "source": {"enum": ["news", "forum", "book"]},
+        "content_type": {"enum": ["news", "forum", "books"]},
+        "filename": {"type": "string", "minLength": 1},
+        "processed_date": {"type": "string", "pattern": "^\\d{4}-\\d{2}-\\d{2}T\\d{2}:\\d{2}:\\d{2}"},
+        "chunk_id": {"type": "string", "pattern": "^[0-9a-f]{32}$"},
+        "chunk_index": {"type": "integer", "minimum": 0},
+        "url": {"type": "string", "pattern": "^https?://"},
+        "author": {"type": ["string", "null"]}
+      },
+      "allOf": [
+        {"if": {"required": ["source"], "properties": {"source": {"const": "news"}}}, "then": {"$ref": "#/$defs/news"}},
+        {"if": {"required": ["source"], "properties": {"source": {"const": "forum"}}}, "then": {"$ref": "#/$defs/forum"}},
+        {"if": {"required": ["source"], "properties": {"source": {"const": "book"}}}, "then": {"$ref": "#/$defs/book"}}
+      ]
+    },
+    "news": {
+      "required": ["url", "date", "title", "author", "chunk_id"],
+      "properties": {
+        "content_type": {"const": "news"},
+        "url": {"type": "string", "pattern": "^https://www\\.strunz\\.com/news/"},
+        "date": {"$ref": "#/$defs/date"},
+        "title": {"type": "string", "minLength": 1},
+        "author": {"type": "string", "minLength": 1}
+      }
+    },
+    "forum": {
+      "required": ["type", "category"],
+      "properties": {
+        "content_type": {"const": "forum"},
+        "type": {"enum": ["thread", "category"]},
+        "category": {"type": "string", "minLength": 1}
+      },
+      "if": {"properties": {"type": {"const": "category"}}},
+      "else": {
+        "required": ["url", "title", "post_date", "chunk_id", "chunk_index"],
+        "properties": {
+          "url": {"type": "string", "pattern": "^https://www\\.strunz\\.com/forum/"},
+          "title": {"type": "string", "minLength": 1},
+          "post_date": {"anyOf": [{"$ref": "#/$defs/date"}, {"type": "null"}]}
+        }
+      }
+    },
+    "book": {
+      "required": ["title", "author", "file_path", "year", "chunk_id", "chunk_index"],
+      "properties": {
+        "content_type": {"const": "books"},
+        "title": {"type": "string", "minLength": 1},
+        "file_path": {"type": "string", "pattern": "\\.pdf$"},
+        "year": {"type": "string", "pattern": "^\\d{4}$"},
+        "page": {"type": "integer", "minimum": 1}
+      }
+    }
+  }
+}
+`
+
+// schemaViolation is one distinct problem and the documents that have it
+type schemaViolation struct {
+	message   string
+	sources   map[string]int
+	documents []string
+}
+
+func newKBValidateCommand() *Command {
+	cmd := newCommand("validate", "", "Check the metadata of every corpus document against the versioned corpus document schema.")
+	metadata := addCorpusFlag(cmd)
+	schemaFile := cmd.Flags.String("schema", "", "validate against this JSON Schema file instead of the built-in schema")
+	printSchema := cmd.Flags.Bool("print-schema", false, "print the built-in schema and exit")
+	examples := cmd.Flags.Int("examples", 3, "documents to name per violation")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 0); err != nil {
+			return err
+		}
+		if *printSchema {
+			fmt.Print(corpusDocumentSchema)
+			return nil
+		}
+		data := []byte(corpusDocumentSchema)
+		if *schemaFile != "" {
+			var err error
+			if data, err = os.ReadFile(*schemaFile); err != nil {
+				return fmt.Errorf("%w: %v", errUsage, err)
+			}
+		}
+		schema, err := parseJSONSchema(data)
+		if err != nil {
+			return fmt.Errorf("%w: %v", errUsage, err)
+		}
+		var header struct {
+			ID string `json:"$id"`
+		}
+		json.Unmarshal(data, &header)
+
+		c, err := loadCorpus(*metadata)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("\n🧾 Validating %d document(s) of %s against %s\n", len(c.Documents), c.path, orNone(header.ID))
+
+		var mu sync.Mutex
+		violations := make(map[string]*schemaViolation)
+		invalid := 0
+		record := func(message, source, document string) {
+			v, ok := violations[message]
+			if !ok {
+				v = &schemaViolation{message: message, sources: make(map[string]int)}
+				violations[message] = v
+			}
+			v.sources[orNone(source)]++
+			v.documents = append(v.documents, document)
+		}
+		err = forEachConcurrent(c.Documents, runtime.NumCPU(), func(i int, doc corpusDocument) error {
+			data, err := json.Marshal(doc)
+			if err != nil {
+				return err
+			}
+			problems, err := validateJSON(schema, data)
+			if err != nil || len(problems) == 0 {
+				return err
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			invalid++
+			for _, problem := range slices.Compact(slices.Sorted(slices.Values(problems))) {
+				record(problem, doc.meta("source"), doc.id(i))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		// The header of a combined index must describe its documents; its
+		// source_counts are keyed by index name, "books" for book
+		if c.TotalDocuments != 0 && c.TotalDocuments != len(c.Documents) {
+			record(fmt.Sprintf("total_documents is %d, the corpus has %d", c.TotalDocuments, len(c.Documents)), "", "(header)")
+		}
+		if len(c.SourceCounts) > 0 {
+			counted := make(map[string]int)
+			for i := range c.Documents {
+				counted[c.Documents[i].meta("source")]++
+			}
+			for _, source := range slices.Sorted(maps.Keys(counted)) {
+				key := source
+				if _, ok := c.SourceCounts[key]; !ok {
+					key = source + "s"
+				}
+				if c.SourceCounts[key] != counted[source] {
+					record(fmt.Sprintf("source_counts.%s is %d, the corpus has %d", key, c.SourceCounts[key], counted[source]), source, "(header)")
+				}
+			}
+		}
+
+		if len(violations) == 0 {
+			fmt.Printf("✅ All %d document(s) match the schema\n", len(c.Documents))
+			return nil
+		}
+		sorted := slices.SortedFunc(maps.Values(violations), func(a, b *schemaViolation) int {
+			if len(a.documents) != len(b.documents) {
+				return len(b.documents) - len(a.documents)
+			}
+			return strings.Compare(a.message, b.message)
+		})
+		for _, v := range sorted {
+			var counts []string
+			for _, source := range slices.Sorted(maps.Keys(v.sources)) {
+				counts = append(counts, fmt.Sprintf("%s %d", source, v.sources[source]))
+			}
+			slices.Sort(v.documents)
+			named := v.documents[:min(max(*examples, 0), len(v.documents))]
+			more := ""
+			if len(v.documents) > len(named) {
+				more = fmt.Sprintf(" … and %d more", len(v.documents)-len(named))
+			}
+			fmt.Printf("❌ %s (%s)\n   %s%s\n", v.message, strings.Join(counts, ", "), strings.Join(named, ", "), more)
+		}
+		fmt.Printf("\n📊 %d of %d document(s) violate the schema, %d distinct violation(s)\n", invalid, len(c.Documents), len(violations))
+		return fmt.Errorf("%w: the corpus metadata does not match the schema", errPolicy)
+	}
+	return cmd
+}
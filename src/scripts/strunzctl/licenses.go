@@ -0,0 +1,515 @@
+package main
+
+import (
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"path"
+	"slices"
+	"strings"
+)
+
+// licenseClass orders licenses from least to most restrictive for a
+// distributor; expressions take the best of OR and the worst of AND
+type licenseClass int
+
+const (
+	licensePermissive licenseClass = iota
+	licenseWeakCopyleft
+	licenseCopyleft
+	licenseUnknown
+	licenseDenied
+)
+
+var licenseClassNames = map[licenseClass]string{
+	licensePermissive:   "permissive",
+	licenseWeakCopyleft: "weak copyleft",
+	licenseCopyleft:     "copyleft",
+	licenseUnknown:      "unknown",
+	licenseDenied:       "denied",
+}
+
+func (c licenseClass) String() string { return licenseClassNames[c] }
+
+// License identifier prefixes per class, matched case-insensitively. SBOMs
+// of Debian packages use the short names of debian/copyright, like GPL-2+.
+var (
+	weakCopyleftPrefixes = []string{"LGPL", "MPL", "EPL", "CDDL", "CPL", "MS-RL"}
+	copyleftPrefixes     = []string{"GPL", "AGPL", "SSPL", "EUPL", "OSL", "CC-BY-SA", "GFDL", "SLEEPYCAT"}
+	permissivePrefixes   = []string{"MIT", "BSD", "0BSD", "APACHE", "ISC", "ZLIB", "PSF", "PYTHON", "UNLICENSE", "CC0", "CC-BY-", "X11",
+		"BSL", "BOOST", "HPND", "PUBLIC-DOMAIN", "PUBLICDOMAIN", "ARTISTIC", "CURL", "OPENSSL", "SSLEAY", "WTFPL", "UNICODE", "NCSA",
+		"W3C", "FTL", "IJG", "LIBPNG", "POSTGRESQL", "MS-PL", "UPL", "BLUEOAK", "SMLNJ", "TCL"}
+)
+
+// unknownLicenses are placeholders for a missing license
+var unknownLicenses = []string{"", "NOASSERTION", "NONE", "UNKNOWN", "OTHER"}
+
+// licenseNames map the spelled out names of Python package metadata to
+// their SPDX prefix, so deny patterns match them too
+var licenseNames = []struct{ name, prefix string }{
+	{"GNU AFFERO GENERAL PUBLIC", "AGPL-"},
+	{"GNU LESSER GENERAL PUBLIC", "LGPL-"},
+	{"GNU LIBRARY OR LESSER GENERAL PUBLIC", "LGPL-"},
+	{"GNU LIBRARY GENERAL PUBLIC", "LGPL-"},
+	{"GNU GENERAL PUBLIC", "GPL-"},
+	{"MOZILLA PUBLIC", "MPL-"},
+	{"ECLIPSE PUBLIC", "EPL-"},
+}
+
+// classifyLicense classifies a single license identifier or name
+func classifyLicense(id string, deny []string) licenseClass {
+	// Trove classifiers: License :: OSI Approved :: MIT License
+	if i := strings.LastIndex(id, "::"); i >= 0 {
+		id = id[i+2:]
+	}
+	id = strings.TrimPrefix(strings.TrimSpace(id), "LicenseRef-")
+	upper := strings.ToUpper(id)
+	for _, name := range licenseNames {
+		if strings.HasPrefix(upper, name.name) {
+			upper = name.prefix + strings.TrimSpace(strings.TrimPrefix(upper, name.name))
+			break
+		}
+	}
+	for _, pattern := range deny {
+		if ok, _ := path.Match(strings.ToUpper(pattern), upper); ok {
+			return licenseDenied
+		}
+	}
+	if slices.Contains(unknownLicenses, upper) {
+		return licenseUnknown
+	}
+	// Weak copyleft first: LGPL would otherwise not be told from GPL by
+	// later, looser matches
+	for _, class := range []struct {
+		class    licenseClass
+		prefixes []string
+	}{{licenseWeakCopyleft, weakCopyleftPrefixes}, {licenseCopyleft, copyleftPrefixes}, {licensePermissive, permissivePrefixes}} {
+		for _, prefix := range class.prefixes {
+			if strings.HasPrefix(upper, prefix) {
+				return class.class
+			}
+		}
+	}
+	return licenseUnknown
+}
+
+// licenseExpression evaluates SPDX license expressions. AND binds tighter
+// than OR; an exception (GPL-2.0 WITH Classpath-exception-2.0) weakens a
+// copyleft license to weak copyleft.
+type licenseExpression struct {
+	tokens []string
+	deny   []string
+	// IDs collects the license identifiers the expression mentions
+	IDs []string
+}
+
+func tokenizeLicenseExpression(expression string) []string {
+	expression = strings.NewReplacer("(", " ( ", ")", " ) ").Replace(expression)
+	return strings.Fields(expression)
+}
+
+func (e *licenseExpression) peek() string {
+	if len(e.tokens) == 0 {
+		return ""
+	}
+	return strings.ToUpper(e.tokens[0])
+}
+
+func (e *licenseExpression) or() licenseClass {
+	class := e.and()
+	for e.peek() == "OR" {
+		e.tokens = e.tokens[1:]
+		class = min(class, e.and())
+	}
+	return class
+}
+
+func (e *licenseExpression) and() licenseClass {
+	class := e.factor()
+	for e.peek() == "AND" {
+		e.tokens = e.tokens[1:]
+		class = max(class, e.factor())
+	}
+	return class
+}
+
+func (e *licenseExpression) factor() licenseClass {
+	if len(e.tokens) == 0 {
+		e.IDs = append(e.IDs, "NOASSERTION")
+		return licenseUnknown
+	}
+	token := e.tokens[0]
+	e.tokens = e.tokens[1:]
+	if token == "(" {
+		class := e.or()
+		if e.peek() == ")" {
+			e.tokens = e.tokens[1:]
+		}
+		return class
+	}
+	e.IDs = append(e.IDs, token)
+	class := classifyLicense(token, e.deny)
+	if e.peek() == "WITH" && len(e.tokens) > 1 {
+		e.tokens = e.tokens[2:]
+		if class == licenseCopyleft {
+			class = licenseWeakCopyleft
+		}
+	}
+	return class
+}
+
+// evaluateLicenses classifies the licenses of a package; several entries
+// all apply, as for Debian packages licensing files differently
+func evaluateLicenses(licenses []string, deny []string) (licenseClass, []string) {
+	if len(licenses) == 0 {
+		return licenseUnknown, []string{"NOASSERTION"}
+	}
+	worst := licensePermissive
+	var ids []string
+	for _, license := range licenses {
+		tokens := tokenizeLicenseExpression(license)
+		// Without operators the entry is one license, possibly a name
+		if !slices.ContainsFunc(tokens, func(token string) bool {
+			return slices.Contains([]string{"AND", "OR", "WITH", "("}, strings.ToUpper(token))
+		}) {
+			tokens = []string{strings.TrimSpace(license)}
+		}
+		expression := &licenseExpression{tokens: tokens, deny: deny}
+		worst = max(worst, expression.or())
+		ids = append(ids, expression.IDs...)
+	}
+	return worst, ids
+}
+
+// sbomPackage is a dependency listed in an SBOM
+type sbomPackage struct {
+	Name, Version string
+	// Type is the purl type: deb, pypi, npm and so on
+	Type     string
+	Licenses []string
+}
+
+func purlType(purl string) string {
+	kind, _, _ := strings.Cut(strings.TrimPrefix(purl, "pkg:"), "/")
+	return kind
+}
+
+// parseSBOMPackages reads the packages of an SPDX or CycloneDX JSON SBOM,
+// leaving out the image and operating system entries
+func parseSBOMPackages(sbom []byte) ([]sbomPackage, error) {
+	var doc struct {
+		SPDXVersion string `json:"spdxVersion"`
+		Packages    []struct {
+			Name             string `json:"name"`
+			VersionInfo      string `json:"versionInfo"`
+			LicenseConcluded string `json:"licenseConcluded"`
+			LicenseDeclared  string `json:"licenseDeclared"`
+			Purpose          string `json:"primaryPackagePurpose"`
+			ExternalRefs     []struct {
+				Type    string `json:"referenceType"`
+				Locator string `json:"referenceLocator"`
+			} `json:"externalRefs"`
+		} `json:"packages"`
+		BOMFormat  string `json:"bomFormat"`
+		Components []struct {
+			Type     string `json:"type"`
+			Name     string `json:"name"`
+			Version  string `json:"version"`
+			Purl     string `json:"purl"`
+			Licenses []struct {
+				License struct {
+					ID   string `json:"id"`
+					Name string `json:"name"`
+				} `json:"license"`
+				Expression string `json:"expression"`
+			} `json:"licenses"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(sbom, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse SBOM: %w", err)
+	}
+
+	var packages []sbomPackage
+	switch {
+	case doc.SPDXVersion != "":
+		for _, p := range doc.Packages {
+			if p.Purpose == "CONTAINER" || p.Purpose == "OPERATING-SYSTEM" {
+				continue
+			}
+			pkg := sbomPackage{Name: p.Name, Version: p.VersionInfo}
+			for _, ref := range p.ExternalRefs {
+				if ref.Type == "purl" {
+					pkg.Type = purlType(ref.Locator)
+				}
+			}
+			// The concluded license is what a reviewer settled on; tools
+			// often only fill in the declared one
+			license := p.LicenseConcluded
+			if slices.Contains(unknownLicenses, strings.ToUpper(license)) {
+				license = p.LicenseDeclared
+			}
+			if !slices.Contains(unknownLicenses, strings.ToUpper(license)) {
+				pkg.Licenses = []string{license}
+			}
+			packages = append(packages, pkg)
+		}
+	case doc.BOMFormat == "CycloneDX":
+		for _, c := range doc.Components {
+			if c.Type == "operating-system" || c.Type == "container" {
+				continue
+			}
+			pkg := sbomPackage{Name: c.Name, Version: c.Version, Type: purlType(c.Purl)}
+			for _, license := range c.Licenses {
+				if value := cmp.Or(license.Expression, license.License.ID, license.License.Name); value != "" {
+					pkg.Licenses = append(pkg.Licenses, value)
+				}
+			}
+			packages = append(packages, pkg)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported SBOM: neither SPDX nor CycloneDX JSON")
+	}
+	return packages, nil
+}
+
+// licenseFinding is the verdict on one package
+type licenseFinding struct {
+	Package sbomPackage
+	Class   licenseClass
+	// Verdict is ok, warn or fail
+	Verdict string
+}
+
+// licenseVerdict applies the policy: denied licenses fail, copyleft and
+// unknown licenses do what the policy says, the rest passes
+func licenseVerdict(class licenseClass, policy LicensePolicy) string {
+	switch class {
+	case licenseDenied:
+		return "fail"
+	case licenseCopyleft:
+		return cmp.Or(policy.Copyleft, "warn")
+	case licenseUnknown:
+		return cmp.Or(policy.Unknown, "warn")
+	}
+	return "ok"
+}
+
+// licenseReport is the outcome for an image
+type licenseReport struct {
+	Reference string           `json:"reference"`
+	Platform  string           `json:"platform"`
+	Source    string           `json:"source"`
+	Counts    map[string]int   `json:"licenses"`
+	Findings  []licenseFinding `json:"-"`
+}
+
+func buildLicenseReport(packages []sbomPackage, policy LicensePolicy) *licenseReport {
+	deny := splitList(policy.Deny)
+	exceptions := splitList(policy.Exceptions)
+	report := &licenseReport{Counts: make(map[string]int)}
+	for _, pkg := range packages {
+		class, ids := evaluateLicenses(pkg.Licenses, deny)
+		verdict := licenseVerdict(class, policy)
+		if verdict != "ok" && slices.Contains(exceptions, pkg.Name) {
+			verdict = "ok"
+		}
+		for _, id := range slices.Compact(slices.Sorted(slices.Values(ids))) {
+			report.Counts[id]++
+		}
+		report.Findings = append(report.Findings, licenseFinding{Package: pkg, Class: class, Verdict: verdict})
+	}
+	slices.SortStableFunc(report.Findings, func(a, b licenseFinding) int {
+		return cmp.Or(cmp.Compare(b.Class, a.Class), cmp.Compare(a.Package.Name, b.Package.Name))
+	})
+	return report
+}
+
+func (f licenseFinding) expression() string {
+	if len(f.Package.Licenses) == 0 {
+		return "NOASSERTION"
+	}
+	return strings.Join(f.Package.Licenses, " AND ")
+}
+
+func writeLicenseReportMarkdown(w io.Writer, report *licenseReport) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# License report: %s\n\n", report.Reference)
+	fmt.Fprintf(&b, "Platform %s, SBOM %s, %d packages.\n\n", report.Platform, report.Source, len(report.Findings))
+	b.WriteString("## Licenses\n\n| License | Packages |\n|---|---|\n")
+	for _, id := range sortedLicenseCounts(report.Counts) {
+		fmt.Fprintf(&b, "| %s | %d |\n", markdownCell(id), report.Counts[id])
+	}
+	b.WriteString("\n## Packages\n\n| Package | Version | Type | License | Class | Verdict |\n|---|---|---|---|---|---|\n")
+	for _, f := range report.Findings {
+		fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %s |\n", markdownCell(f.Package.Name), markdownCell(f.Package.Version),
+			f.Package.Type, markdownCell(f.expression()), f.Class, f.Verdict)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeLicenseReportCSV(w io.Writer, report *licenseReport) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"package", "version", "type", "license", "class", "verdict"})
+	for _, f := range report.Findings {
+		out.Write([]string{f.Package.Name, f.Package.Version, f.Package.Type, f.expression(), f.Class.String(), f.Verdict})
+	}
+	out.Flush()
+	return out.Error()
+}
+
+func writeLicenseReportJSON(w io.Writer, report *licenseReport) error {
+	type jsonPackage struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+		Type    string `json:"type,omitempty"`
+		License string `json:"license"`
+		Class   string `json:"class"`
+		Verdict string `json:"verdict"`
+	}
+	doc := struct {
+		*licenseReport
+		Packages []jsonPackage `json:"packages"`
+	}{licenseReport: report}
+	for _, f := range report.Findings {
+		doc.Packages = append(doc.Packages, jsonPackage{f.Package.Name, f.Package.Version, f.Package.Type, f.expression(), f.Class.String(), f.Verdict})
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(doc)
+}
+
+// sortedLicenseCounts orders licenses by how many packages use them
+func sortedLicenseCounts(counts map[string]int) []string {
+	ids := make([]string, 0, len(counts))
+	for id := range counts {
+		ids = append(ids, id)
+	}
+	slices.SortFunc(ids, func(a, b string) int { return cmp.Or(cmp.Compare(counts[b], counts[a]), cmp.Compare(a, b)) })
+	return ids
+}
+
+func printLicenseReport(report *licenseReport, top int) {
+	classes := make(map[licenseClass]int)
+	flagged := 0
+	for _, f := range report.Findings {
+		classes[f.Class]++
+		if f.Verdict != "ok" {
+			flagged++
+		}
+	}
+	var parts []string
+	for class := licensePermissive; class <= licenseDenied; class++ {
+		parts = append(parts, fmt.Sprintf("%d %s", classes[class], class))
+	}
+	fmt.Printf("%d packages: %s\n", len(report.Findings), strings.Join(parts, ", "))
+
+	ids := sortedLicenseCounts(report.Counts)
+	fmt.Println("\nMost used licenses:")
+	for _, id := range ids[:min(top, len(ids))] {
+		fmt.Printf("  %-32s %5d  %s\n", truncate(id, 32), report.Counts[id], classifyLicense(id, splitList(config.Licenses.Deny)))
+	}
+	if flagged == 0 {
+		return
+	}
+
+	// Warnings are grouped by license, a base image has many GPL packages
+	fmt.Println("\nFlagged:")
+	warnings := make(map[string][]string)
+	for _, f := range report.Findings {
+		switch f.Verdict {
+		case "fail":
+			fmt.Printf("  ❌ %-10s %-40s %s %s (%s)\n", f.Class, truncate(f.expression(), 40), f.Package.Name, f.Package.Version, orNone(f.Package.Type))
+		case "warn":
+			key := fmt.Sprintf("%s (%s)", truncate(f.expression(), 48), f.Class)
+			warnings[key] = append(warnings[key], f.Package.Name)
+		}
+	}
+	for _, key := range slices.Sorted(maps.Keys(warnings)) {
+		names := warnings[key]
+		listed := strings.Join(names[:min(6, len(names))], ", ")
+		if len(names) > 6 {
+			listed += fmt.Sprintf(" (+%d more)", len(names)-6)
+		}
+		fmt.Printf("  ⚠️  %s: %s\n", key, listed)
+	}
+}
+
+func newImageLicensesCommand() *Command {
+	cmd := newCommand("licenses", "<tag>", "Aggregate the dependency licenses from the image's SBOM and check them against the license policy.")
+	platform := cmd.Flags.String("platform", defaultPlatform, "platform image whose SBOM to read")
+	sbomFormat := cmd.Flags.String("sbom", "spdx", "SBOM format to fetch: spdx or cyclonedx")
+	generate := cmd.Flags.Bool("generate", false, "always generate the SBOM with syft, ignoring attached SBOMs")
+	format := cmd.Flags.String("format", "markdown", "report format for --output (markdown, csv or json)")
+	output := cmd.Flags.String("output", "", "write the full report to this file")
+	top := cmd.Flags.Int("top", 15, "number of licenses to list")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 1); err != nil {
+			return err
+		}
+		if _, ok := sbomFormats[*sbomFormat]; !ok {
+			return fmt.Errorf("%w: unsupported SBOM format %q", errUsage, *sbomFormat)
+		}
+		var write func(io.Writer, *licenseReport) error
+		switch *format {
+		case "markdown", "md":
+			write = writeLicenseReportMarkdown
+		case "csv":
+			write = writeLicenseReportCSV
+		case "json":
+			write = writeLicenseReportJSON
+		default:
+			return fmt.Errorf("%w: unsupported report format %q", errUsage, *format)
+		}
+		for key, value := range map[string]string{"licenses.copyleft": config.Licenses.Copyleft, "licenses.unknown": config.Licenses.Unknown} {
+			if !slices.Contains([]string{"", "fail", "warn", "ok"}, value) {
+				return fmt.Errorf("%w: %s must be fail, warn or ok, got %q", errUsage, key, value)
+			}
+		}
+
+		registry := newRegistryClient(config.Registry, imageRepository())
+		sbom, source, err := fetchSBOM(registry, args[0], *platform, *sbomFormat, *generate)
+		if err != nil {
+			return err
+		}
+		packages, err := parseSBOMPackages(sbom)
+		if err != nil {
+			return err
+		}
+		report := buildLicenseReport(packages, config.Licenses)
+		report.Reference, report.Platform, report.Source = registry.Reference(args[0]), *platform, source
+
+		fmt.Printf("\n📜 Licenses of %s (%s, SBOM %s)\n", report.Reference, *platform, source)
+		printLicenseReport(report, *top)
+		if *output != "" {
+			file, err := os.Create(*output)
+			if err != nil {
+				return fmt.Errorf("failed to create report: %w", err)
+			}
+			defer file.Close()
+			if err := write(file, report); err != nil {
+				return fmt.Errorf("failed to write report: %w", err)
+			}
+			slog.Info("Report written", "path", *output, "format", *format)
+		}
+
+		failed := 0
+		for _, f := range report.Findings {
+			if f.Verdict == "fail" {
+				failed++
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("%w: %d package(s) violate the license policy; add accepted ones to licenses.exceptions", errPolicy, failed)
+		}
+		fmt.Printf("\n✅ No license policy violations\n")
+		return nil
+	}
+	return cmd
+}
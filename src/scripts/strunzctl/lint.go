@@ -0,0 +1,284 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// dockerfileLabels must be set by the final stage of every Dockerfile
+var dockerfileLabels = append(slices.Clone(requiredLabels), "version")
+
+// buildArgReference matches a value that is only known at build time
+var buildArgReference = regexp.MustCompile(`^\$\{?([A-Za-z_][A-Za-z0-9_]*)(?::?-[^}]*)?\}?$`)
+
+func newLintCommand() *Command {
+	return newGroup("lint", "Check the repository's build files against the release conventions.",
+		newLintDockerfileCommand(),
+	)
+}
+
+// dockerInstruction is one instruction of a Dockerfile, continuation lines
+// joined
+type dockerInstruction struct {
+	Line    int
+	Keyword string
+	Args    string
+}
+
+// parseDockerfile splits a Dockerfile into instructions, dropping comments
+// and joining lines that end in a backslash
+func parseDockerfile(content string) []dockerInstruction {
+	var instructions []dockerInstruction
+	var current strings.Builder
+	start := 0
+	for i, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if current.Len() == 0 && (trimmed == "" || strings.HasPrefix(trimmed, "#")) {
+			continue
+		}
+		// Comment lines inside a continued instruction are skipped too
+		if current.Len() > 0 && strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if current.Len() == 0 {
+			start = i + 1
+		}
+		if continued, ok := strings.CutSuffix(trimmed, `\`); ok {
+			current.WriteString(continued + " ")
+			continue
+		}
+		current.WriteString(trimmed)
+		keyword, args, _ := strings.Cut(strings.TrimSpace(current.String()), " ")
+		instructions = append(instructions, dockerInstruction{Line: start, Keyword: strings.ToUpper(keyword), Args: strings.TrimSpace(args)})
+		current.Reset()
+	}
+	return instructions
+}
+
+// shellWords splits instruction arguments the way Docker does for LABEL and
+// ARG: on whitespace, with quotes grouping and backslashes escaping
+func shellWords(args string) []string {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	quote := rune(0)
+	escaped := false
+	for _, r := range args {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inWord = true
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote == 0 && (r == '"' || r == '\''):
+			quote = r
+			inWord = true
+		case quote == 0 && (r == ' ' || r == '\t'):
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words
+}
+
+// labelValue is a LABEL of the final stage and where it is set
+type labelValue struct {
+	Value string
+	Line  int
+}
+
+// finalStageLabels returns the labels and build arguments in effect in the
+// image the Dockerfile builds, which is its last stage
+func finalStageLabels(instructions []dockerInstruction) (labels map[string]labelValue, args map[string]string) {
+	labels = make(map[string]labelValue)
+	args = make(map[string]string)
+	global := make(map[string]string)
+	seenFrom := false
+	for _, instruction := range instructions {
+		switch instruction.Keyword {
+		case "FROM":
+			// ARGs before the first FROM are global; a stage sees them only
+			// when it declares them again
+			labels, args = make(map[string]labelValue), make(map[string]string)
+			seenFrom = true
+		case "ARG":
+			for _, word := range shellWords(instruction.Args) {
+				name, value, ok := strings.Cut(word, "=")
+				switch {
+				case !seenFrom:
+					global[name] = value
+				case !ok:
+					args[name] = global[name]
+				default:
+					args[name] = value
+				}
+			}
+		case "LABEL":
+			words := shellWords(instruction.Args)
+			// The legacy form is LABEL key value with a single label
+			if len(words) > 0 && !strings.Contains(words[0], "=") {
+				labels[words[0]] = labelValue{Value: strings.Join(words[1:], " "), Line: instruction.Line}
+				continue
+			}
+			for _, word := range words {
+				key, value, _ := strings.Cut(word, "=")
+				labels[key] = labelValue{Value: value, Line: instruction.Line}
+			}
+		}
+	}
+	return labels, args
+}
+
+// lintDockerfile checks the OCI labels of a Dockerfile's image; version is
+// the release the labels must name
+func lintDockerfile(content, version, source string) (problems, notes []string) {
+	instructions := parseDockerfile(content)
+	if !slices.ContainsFunc(instructions, func(i dockerInstruction) bool { return i.Keyword == "FROM" }) {
+		return []string{"no FROM instruction"}, nil
+	}
+	labels, args := finalStageLabels(instructions)
+	for _, name := range dockerfileLabels {
+		key := ociLabelPrefix + name
+		label, ok := labels[key]
+		if !ok || strings.TrimSpace(label.Value) == "" {
+			problems = append(problems, fmt.Sprintf("%s is missing", key))
+			continue
+		}
+		value := label.Value
+		arg := ""
+		if m := buildArgReference.FindStringSubmatch(value); m != nil {
+			arg = m[1]
+			if _, declared := args[arg]; !declared {
+				problems = append(problems, fmt.Sprintf("line %d: %s refers to $%s, which the stage does not declare with ARG", label.Line, key, arg))
+				continue
+			}
+			value = args[arg]
+		}
+
+		switch name {
+		case "revision":
+			// A commit written into the Dockerfile is stale by the next one
+			if arg == "" {
+				problems = append(problems, fmt.Sprintf("line %d: %s is the fixed value %q; pass the commit as a build argument, e.g. LABEL %s=$VCS_REF", label.Line, key, value, key))
+			} else {
+				notes = append(notes, fmt.Sprintf("%s comes from --build-arg %s", key, arg))
+			}
+		case "version":
+			switch {
+			case arg != "" && value == "":
+				notes = append(notes, fmt.Sprintf("%s comes from --build-arg %s", key, arg))
+			case normalizeVersion(value) != normalizeVersion(version):
+				problems = append(problems, fmt.Sprintf("line %d: %s is %s, the release is %s", label.Line, key, value, version))
+			default:
+				notes = append(notes, fmt.Sprintf("%s is %s", key, value))
+			}
+		case "source":
+			if source != "" && strings.TrimSuffix(value, ".git") != source {
+				problems = append(problems, fmt.Sprintf("line %d: %s is %s, the repository is %s", label.Line, key, value, source))
+			}
+		}
+	}
+	// The legacy version label must not contradict the OCI one
+	if legacy, ok := labels["version"]; ok && buildArgReference.FindStringSubmatch(legacy.Value) == nil && normalizeVersion(legacy.Value) != normalizeVersion(version) {
+		problems = append(problems, fmt.Sprintf("line %d: version is %s, the release is %s", legacy.Line, legacy.Value, version))
+	}
+	return problems, notes
+}
+
+// repositoryDockerfiles lists the Dockerfiles tracked in the repository
+func repositoryDockerfiles(root string) ([]string, error) {
+	files, err := runGit("-C", root, "ls-files", "--", "Dockerfile*", "*/Dockerfile*")
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, file := range strings.Fields(files) {
+		paths = append(paths, filepath.Join(root, file))
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("%w: no Dockerfiles in the repository", errUsage)
+	}
+	return paths, nil
+}
+
+func newLintDockerfileCommand() *Command {
+	cmd := newCommand("dockerfile", "[dockerfile...]", "Check that the Dockerfiles set the required org.opencontainers.image.* labels and that they match the release.")
+	version := cmd.Flags.String("version", "", "release version the labels must name (default: the highest of the version strings and v* tags, as release bump sees it)")
+
+	cmd.Run = func(args []string) error {
+		root, err := repoPath("")
+		if err != nil {
+			return err
+		}
+		paths := args
+		if len(paths) == 0 {
+			if paths, err = repositoryDockerfiles(root); err != nil {
+				return err
+			}
+		}
+		release := *version
+		if release == "" {
+			_, edits, err := findVersionStrings(root)
+			if err != nil {
+				return err
+			}
+			current, err := currentVersion(edits)
+			if err != nil {
+				return err
+			}
+			release = current.String()
+		}
+		source := ""
+		if config.Repo != "" {
+			source = "https://github.com/" + config.Repo
+		}
+
+		fmt.Printf("\n🏷️  Checking the OCI labels of %d Dockerfile(s) against release %s\n", len(paths), release)
+		failed := 0
+		for _, path := range paths {
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("%w: %v", errUsage, err)
+			}
+			name := path
+			if rel, err := filepath.Rel(root, path); err == nil && !strings.HasPrefix(rel, "..") {
+				name = rel
+			}
+			problems, notes := lintDockerfile(string(content), release, source)
+			if len(problems) == 0 {
+				fmt.Printf("✅ %s\n", name)
+			} else {
+				failed++
+				fmt.Printf("❌ %s\n", name)
+			}
+			for _, problem := range problems {
+				fmt.Printf("   ❌ %s\n", problem)
+			}
+			for _, note := range notes {
+				fmt.Printf("   ℹ️  %s\n", note)
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("%w: %d of %d Dockerfile(s) lack required labels or name another release", errPolicy, failed, len(paths))
+		}
+		fmt.Println("\n✅ All Dockerfiles carry the required labels")
+		return nil
+	}
+	return cmd
+}
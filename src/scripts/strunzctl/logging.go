@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// setupLogging installs the slog handler for diagnostics on stderr.
+// Command results stay on stdout so they can be piped independently.
+// Records go below a progress line rather than into it.
+func setupLogging(verbose, quiet bool, format string) error {
+	if verbose && quiet {
+		return fmt.Errorf("%w: --verbose and --quiet are mutually exclusive", errUsage)
+	}
+	level := slog.LevelInfo
+	switch {
+	case verbose:
+		level = slog.LevelDebug
+	case quiet:
+		level = slog.LevelWarn
+	}
+
+	options := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch format {
+	case "text":
+		handler = slog.NewTextHandler(progressAwareWriter{os.Stderr}, options)
+	case "json":
+		handler = slog.NewJSONHandler(progressAwareWriter{os.Stderr}, options)
+	default:
+		return fmt.Errorf("%w: --log-format must be text or json", errUsage)
+	}
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
+
+// requestTarget describes a request for logs. Webhook URLs carry their
+// secret in the path, so only the host is logged for them.
+func requestTarget(req *http.Request) string {
+	host := req.URL.Host
+	if strings.HasPrefix(host, "hooks.") || strings.Contains(req.URL.Path, "/webhooks/") {
+		return host
+	}
+	return host + req.URL.Path
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// pythonLogLine matches the server's logging format
+// '%(asctime)s - %(name)s - %(levelname)s - %(message)s'
+var pythonLogLine = regexp.MustCompile(`^(\d{4}-\d\d-\d\d \d\d:\d\d:\d\d(?:,\d+)?) - (\S+) - (DEBUG|INFO|WARNING|ERROR|CRITICAL) - (.*)$`)
+
+// uvicornLogLine matches uvicorn's own "LEVEL:     message" lines
+var uvicornLogLine = regexp.MustCompile(`^(DEBUG|INFO|WARNING|ERROR|CRITICAL):\s+(.*)$`)
+
+// logPattern classifies a problem by its message or stack trace
+type logPattern struct {
+	Class string
+	Match *regexp.Regexp
+}
+
+// logPatterns are tried in order; OOM comes first since the kernel's kill
+// also ends every open SSE stream
+var logPatterns = []logPattern{
+	{"oom", regexp.MustCompile(`(?i)MemoryError|out of memory|OOMKilled|\bKilled\b|exit(ed)? (with )?code 137|signal 9`)},
+	{"oauth", regexp.MustCompile(`(?i)oauth|token endpoint|invalid_(client|grant|request|token)|authoriz(e|ation) error|jwt|ExpiredSignature|InvalidTokenError|unauthorized`)},
+	{"tool", regexp.MustCompile(`(?i)tool (execution|call) error|(search|stats|book content|prompt) error|KnowledgeSearcher|vector store`)},
+	{"sse", regexp.MustCompile(`(?i)\bsse\b|disconnect|ClientDisconnect|CancelledError|BrokenPipe|connection (reset|closed|lost)|EndOfStream`)},
+	{"startup", regexp.MustCompile(`(?i)failed to (initialize|load|start)|ModuleNotFoundError|ImportError|address already in use`)},
+}
+
+// logEvent is one record, with the traceback lines that follow it
+type logEvent struct {
+	At        time.Time
+	Level     string
+	Logger    string
+	Message   string
+	Traceback []string
+}
+
+// parseLogEvents groups raw lines into events. Tracebacks print without
+// the log prefix, so they join the event before them.
+func parseLogEvents(lines []RailwayLog) []logEvent {
+	var events []logEvent
+	inTraceback := false
+	for _, line := range lines {
+		text := strings.TrimRight(line.Message, "\r\n")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		if match := pythonLogLine.FindStringSubmatch(text); match != nil {
+			at, err := time.ParseInLocation("2006-01-02 15:04:05,000", match[1], time.Local)
+			if err != nil {
+				at = line.Timestamp
+			}
+			events = append(events, logEvent{At: at, Logger: match[2], Level: match[3], Message: match[4]})
+			inTraceback = false
+			continue
+		}
+		if match := uvicornLogLine.FindStringSubmatch(text); match != nil {
+			events = append(events, logEvent{At: line.Timestamp, Logger: "uvicorn", Level: match[1], Message: match[2]})
+			inTraceback = false
+			continue
+		}
+		if strings.HasPrefix(text, "Traceback (most recent call last)") {
+			inTraceback = true
+			if len(events) == 0 || events[len(events)-1].Traceback != nil {
+				// A traceback printed on its own, like an unhandled exception
+				events = append(events, logEvent{At: line.Timestamp, Level: "ERROR", Message: "unhandled exception"})
+			}
+			events[len(events)-1].Traceback = []string{text}
+			continue
+		}
+		if inTraceback && len(events) > 0 {
+			last := &events[len(events)-1]
+			last.Traceback = append(last.Traceback, text)
+			// The exception line after the frames ends the traceback
+			if !strings.HasPrefix(text, " ") && !strings.HasPrefix(text, "During handling") && !strings.HasPrefix(text, "The above exception") {
+				inTraceback = false
+			}
+			continue
+		}
+		level := strings.ToUpper(cmp.Or(line.Severity, "INFO"))
+		if level == "ERR" {
+			level = "ERROR"
+		}
+		events = append(events, logEvent{At: line.Timestamp, Level: level, Message: text})
+	}
+	// Lines without a timestamp of their own happened after the last one
+	for i := 1; i < len(events); i++ {
+		if events[i].At.IsZero() {
+			events[i].At = events[i-1].At
+		}
+	}
+	return events
+}
+
+// classifyLogEvent returns the class of a problem event, or "" for
+// events that are not problems
+func classifyLogEvent(event logEvent) string {
+	text := event.Message
+	if len(event.Traceback) > 0 {
+		text += "\n" + strings.Join(event.Traceback, "\n")
+	}
+	problem := event.Level == "ERROR" || event.Level == "CRITICAL" || len(event.Traceback) > 0
+	for _, pattern := range logPatterns {
+		if pattern.Match.MatchString(text) && (problem || pattern.Class == "oom" || event.Level == "WARNING") {
+			return pattern.Class
+		}
+	}
+	if problem {
+		return "other"
+	}
+	return ""
+}
+
+// logVariables are the parts of a message that differ between repeats of
+// the same problem
+var logVariables = regexp.MustCompile(`[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}|0x[0-9a-fA-F]+|\b\d+(\.\d+)*\b|'[^']*'|"[^"]*"`)
+
+// logSignature reduces a message to what repeats of it share
+func logSignature(message string) string {
+	return truncate(logVariables.ReplaceAllString(message, "…"), 120)
+}
+
+// logClassSummary is the triage of one problem class
+type logClassSummary struct {
+	Class     string            `json:"class"`
+	Count     int               `json:"count"`
+	First     time.Time         `json:"first"`
+	Last      time.Time         `json:"last"`
+	Messages  []logMessageCount `json:"messages"`
+	Traceback []string          `json:"sample_traceback,omitempty"`
+}
+
+// logMessageCount counts one kind of message of a class
+type logMessageCount struct {
+	Message string `json:"message"`
+	Count   int    `json:"count"`
+	Sample  string `json:"sample"`
+}
+
+// summarizeLogs counts the problem events per class, most frequent first
+func summarizeLogs(events []logEvent) []*logClassSummary {
+	byClass := make(map[string]*logClassSummary)
+	messages := make(map[string]map[string]*logMessageCount)
+	for _, event := range events {
+		class := classifyLogEvent(event)
+		if class == "" {
+			continue
+		}
+		summary, ok := byClass[class]
+		if !ok {
+			summary = &logClassSummary{Class: class, First: event.At}
+			byClass[class] = summary
+			messages[class] = make(map[string]*logMessageCount)
+		}
+		summary.Count++
+		summary.Last = event.At
+		if summary.Traceback == nil && len(event.Traceback) > 0 {
+			summary.Traceback = event.Traceback
+		}
+		signature := logSignature(event.Message)
+		count, ok := messages[class][signature]
+		if !ok {
+			count = &logMessageCount{Message: signature, Sample: event.Message}
+			messages[class][signature] = count
+		}
+		count.Count++
+	}
+
+	summaries := make([]*logClassSummary, 0, len(byClass))
+	for class, summary := range byClass {
+		for _, count := range messages[class] {
+			summary.Messages = append(summary.Messages, *count)
+		}
+		slices.SortFunc(summary.Messages, func(a, b logMessageCount) int {
+			return cmp.Or(b.Count-a.Count, strings.Compare(a.Message, b.Message))
+		})
+		summaries = append(summaries, summary)
+	}
+	slices.SortFunc(summaries, func(a, b *logClassSummary) int {
+		return cmp.Or(b.Count-a.Count, strings.Compare(a.Class, b.Class))
+	})
+	return summaries
+}
+
+// readLogLines reads a log file, or stdin for "-", as it arrives
+func readLogLines(r io.Reader) ([]RailwayLog, error) {
+	var lines []RailwayLog
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, RailwayLog{Message: scanner.Text()})
+	}
+	return lines, scanner.Err()
+}
+
+// latestDeploymentID returns the newest deployment of a service
+func latestDeploymentID(railway *RailwayClient, service string) (string, error) {
+	target, err := resolveRailwayTarget(railway, service)
+	if err != nil {
+		return "", err
+	}
+	deployments, err := railway.Deployments(target, 1)
+	if err != nil {
+		return "", err
+	}
+	if len(deployments) == 0 {
+		return "", fmt.Errorf("service %s has no deployments", target.ServiceName)
+	}
+	return deployments[0].ID, nil
+}
+
+func newLogsCommand() *Command {
+	return newGroup("logs", "Triage the server's logs.",
+		newLogsAnalyzeCommand(),
+	)
+}
+
+func newLogsAnalyzeCommand() *Command {
+	cmd := newCommand("analyze", "[file]", "Classify the errors in the server's logs (OAuth, tool, SSE, OOM, startup) and summarize them with sample stack traces; reads the latest Railway deployment, a file, or - for stdin.")
+	service := cmd.Flags.String("service", "", "Railway service name or ID (default: railway.service)")
+	deployment := cmd.Flags.String("deployment", "", "Railway deployment ID (default: the latest one)")
+	limit := cmd.Flags.Int("limit", 5000, "number of Railway log lines to fetch")
+	top := cmd.Flags.Int("top", 5, "messages listed per class")
+	traces := cmd.Flags.Bool("traces", true, "print a sample stack trace per class")
+	format := cmd.Flags.String("format", "text", "output format: text or json")
+
+	cmd.Run = func(args []string) error {
+		if len(args) > 1 {
+			return cmd.ExactArgs(args, 1)
+		}
+		if *format != "text" && *format != "json" {
+			return fmt.Errorf("%w: unknown format %q (want text or json)", errUsage, *format)
+		}
+
+		var lines []RailwayLog
+		source := ""
+		switch {
+		case len(args) == 1 && args[0] == "-":
+			source = "stdin"
+			var err error
+			if lines, err = readLogLines(os.Stdin); err != nil {
+				return err
+			}
+		case len(args) == 1:
+			source = args[0]
+			file, err := os.Open(args[0])
+			if err != nil {
+				return fmt.Errorf("%w: %v", errUsage, err)
+			}
+			defer file.Close()
+			if lines, err = readLogLines(file); err != nil {
+				return err
+			}
+		default:
+			railway, err := newRailwayClient()
+			if err != nil {
+				return err
+			}
+			id := *deployment
+			if id == "" {
+				if id, err = latestDeploymentID(railway, *service); err != nil {
+					return err
+				}
+			}
+			source = "deployment " + id
+			if lines, err = railway.DeploymentLogs(id, *limit); err != nil {
+				return err
+			}
+		}
+
+		events := parseLogEvents(lines)
+		summaries := summarizeLogs(events)
+		if *format == "json" {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(map[string]any{"source": source, "lines": len(lines), "events": len(events), "classes": summaries})
+		}
+
+		fmt.Printf("\n🩺 %d log line(s), %d event(s) from %s\n", len(lines), len(events), source)
+		if len(summaries) == 0 {
+			fmt.Println("\n✅ No errors found")
+			return nil
+		}
+		fmt.Printf("\n  %-8s %6s  %-19s  %-19s\n", "CLASS", "COUNT", "FIRST", "LAST")
+		for _, summary := range summaries {
+			fmt.Printf("  %-8s %6d  %-19s  %-19s\n", summary.Class, summary.Count, formatLogTime(summary.First), formatLogTime(summary.Last))
+		}
+		for _, summary := range summaries {
+			fmt.Printf("\n%s (%d)\n", summary.Class, summary.Count)
+			for _, message := range summary.Messages[:min(*top, len(summary.Messages))] {
+				fmt.Printf("  %5d× %s\n", message.Count, message.Message)
+			}
+			if rest := len(summary.Messages) - *top; rest > 0 {
+				fmt.Printf("         … and %d other message(s)\n", rest)
+			}
+			if *traces && len(summary.Traceback) > 0 {
+				fmt.Println("  sample stack trace:")
+				for _, line := range summary.Traceback[max(0, len(summary.Traceback)-12):] {
+					fmt.Printf("    %s\n", line)
+				}
+			}
+		}
+		return nil
+	}
+	return cmd
+}
+
+// formatLogTime shows "-" for lines without a timestamp
+func formatLogTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format(time.DateTime)
+}
@@ -0,0 +1,136 @@
+// Command strunzctl manages the StrunzKnowledge container images published
+// to the GitHub Container Registry.
+//
+// Build with:
+//
+//	cd src/scripts/strunzctl && go build -o strunzctl *.go
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// globalOptions are set by flags on the root command
+var globalOptions struct {
+	maxRetries  int
+	concurrency int
+	noCache     bool
+	offline     bool
+	configPath  string
+	verbose     bool
+	quiet       bool
+	logFormat   string
+	errorFormat string
+	caBundle    string
+	resume      bool
+	timeout     time.Duration
+	lang        string
+}
+
+func main() {
+	root := newRootCommand()
+	err := interruption(root.Execute(os.Args[1:]))
+	warnOfflineSnapshots()
+	code := exitCode(err)
+	if code != exitOK {
+		reportError(err, globalOptions.errorFormat)
+	}
+	os.Exit(code)
+}
+
+func newRootCommand() *Command {
+	root := newGroup("strunzctl", "Registry and release tooling for the StrunzKnowledge MCP server images.",
+		newPackagesCommand(),
+		newFleetCommand(),
+		newImageCommand(),
+		newDeployCommand(),
+		newSecretsCommand(),
+		newLogsCommand(),
+		newMCPCommand(),
+		newMonitorCommand(),
+		newScheduleCommand(),
+		newServeCommand(),
+		newAskCommand(),
+		newKBCommand(),
+		newScrapeCommand(),
+		newScanCommand(),
+		newLintCommand(),
+		newTestCommand(),
+		newDevCommand(),
+		newReleaseCommand(),
+		newCICommand(),
+		newRepoCommand(),
+		newAuditCommand(),
+		newCacheCommand(),
+		newPluginsCommand(),
+		newTUICommand(),
+		newLoginCommand(),
+		newDoctorCommand(),
+		newConfigCommand(),
+		newVersionCommand(),
+		newSelfUpdateCommand(),
+		newCompletionCommand(),
+	)
+	if runningAsGHExtension() {
+		root.Name = "gh strunz"
+	}
+	root.AddCommand(newDocsCommand(root))
+	root.AddCommand(newCompleteCommand(root))
+	root.Flags.IntVar(&globalOptions.maxRetries, "max-retries", 5, "retries for rate-limited or failed API requests")
+	root.Flags.IntVar(&globalOptions.concurrency, "concurrency", 8, "parallel registry requests for multi-version operations")
+	root.Flags.BoolVar(&globalOptions.noCache, "no-cache", false, "bypass the local HTTP response cache")
+	root.Flags.BoolVar(&globalOptions.offline, "offline", false, "answer GitHub and registry reads from the response cache of earlier runs without any network request")
+	root.Flags.StringVar(&globalOptions.configPath, "config", "", "config file (default "+defaultConfigPath()+")")
+	root.Flags.BoolVar(&globalOptions.verbose, "verbose", false, "log debug details such as every HTTP request")
+	root.Flags.BoolVar(&globalOptions.quiet, "quiet", false, "only log warnings and errors")
+	root.Flags.StringVar(&globalOptions.logFormat, "log-format", "text", "log format on stderr (text or json)")
+	root.Flags.StringVar(&globalOptions.errorFormat, "error-format", "text", "final error on stderr as text or json with an exit code kind")
+	root.Flags.StringVar(&globalOptions.caBundle, "ca-bundle", "", "PEM file of extra CA certificates to trust, e.g. of a TLS-inspecting proxy (default: ca_bundle setting)")
+	root.Flags.BoolVar(&globalOptions.resume, "resume", false, "continue an interrupted paginated crawl from its last checkpoint instead of the first page")
+	root.Flags.DurationVar(&globalOptions.timeout, "timeout", 0, "abort the command after this long, as Ctrl-C does (0: no limit); give it before the command name, since after it --timeout is the command's own flag where it has one, such as the deployment wait of deploy rollback")
+	root.Flags.StringVar(&globalOptions.lang, "lang", "", "output language of reports and command output: en or de (default: lang setting, else en)")
+	root.Before = func() error {
+		startCommandContext(globalOptions.timeout)
+		if globalOptions.errorFormat != "text" && globalOptions.errorFormat != "json" {
+			return fmt.Errorf("%w: --error-format must be text or json", errUsage)
+		}
+		if err := setupLogging(globalOptions.verbose, globalOptions.quiet, globalOptions.logFormat); err != nil {
+			return err
+		}
+		if globalOptions.offline && globalOptions.noCache {
+			return fmt.Errorf("%w: --offline reads the response cache that --no-cache bypasses", errUsage)
+		}
+		path, explicit := globalOptions.configPath, globalOptions.configPath != ""
+		if !explicit {
+			path = defaultConfigPath()
+		}
+		loaded, err := loadConfig(path, explicit)
+		if err != nil {
+			return err
+		}
+		config = loaded
+		if globalOptions.lang == "" {
+			globalOptions.lang = config.Lang
+		}
+		if err := setLanguage(globalOptions.lang); err != nil {
+			return err
+		}
+		if globalOptions.caBundle != "" {
+			config.CABundle = globalOptions.caBundle
+		}
+		if err := configureNetwork(config.Proxy, config.CABundle); err != nil {
+			return err
+		}
+		slog.Debug("Configuration loaded", "path", path, "org", config.Org, "package", config.Package, "registry", config.Registry)
+		return nil
+	}
+	return root
+}
+
+// imageRepository returns the registry repository path of the package
+func imageRepository() string {
+	return config.Org + "/" + config.Package
+}
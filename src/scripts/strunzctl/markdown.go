@@ -0,0 +1,284 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// markdownRenderer turns an element tree into Markdown. The output depends
+// only on the tree, so an unchanged page converts to an identical file.
+type markdownRenderer struct {
+	base *url.URL // resolves relative links
+	// headingShift is added to the level of headings in the content, so
+	// they nest below the headings a document adds around it
+	headingShift int
+}
+
+func (r *markdownRenderer) render(n *htmlNode) string {
+	return strings.Join(r.blocks(n), "\n\n")
+}
+
+// blocks renders the children of n as Markdown blocks; runs of inline
+// content become paragraphs
+func (r *markdownRenderer) blocks(n *htmlNode) []string {
+	var blocks []string
+	var paragraph strings.Builder
+	add := func(block string) {
+		if text := tidyInline(paragraph.String()); text != "" {
+			blocks = append(blocks, text)
+		}
+		paragraph.Reset()
+		if block != "" {
+			blocks = append(blocks, block)
+		}
+	}
+	for _, child := range n.Children {
+		switch child.Tag {
+		case "":
+			paragraph.WriteString(escapeMarkdown(child.Text))
+		case "h1", "h2", "h3", "h4", "h5", "h6":
+			if text := strings.ReplaceAll(tidyInline(r.inline(child)), "\n", " "); text != "" {
+				add(strings.Repeat("#", min(int(child.Tag[1]-'0')+r.headingShift, 6)) + " " + text)
+			}
+		case "p":
+			add(tidyInline(r.inline(child)))
+		case "ul", "ol":
+			add(r.list(child))
+		case "blockquote":
+			quoted := r.render(child)
+			if quoted != "" {
+				quoted = "> " + strings.ReplaceAll(quoted, "\n", "\n> ")
+				quoted = strings.ReplaceAll(quoted, "\n> \n", "\n>\n")
+			}
+			add(quoted)
+		case "pre":
+			code := strings.Trim(rawText(child), "\n")
+			if strings.TrimSpace(code) == "" {
+				add("")
+				continue
+			}
+			fence := "```"
+			for strings.Contains(code, fence) {
+				fence += "`"
+			}
+			add(fence + "\n" + code + "\n" + fence)
+		case "table":
+			add(r.table(child))
+		case "hr":
+			// Not ---, which separates the posts of a processed file
+			add("* * *")
+		case "br":
+			paragraph.WriteString("\n")
+		default:
+			if slices.Contains(htmlBlockElements, child.Tag) {
+				add("")
+				blocks = append(blocks, r.blocks(child)...)
+			} else {
+				paragraph.WriteString(r.inlineNode(child))
+			}
+		}
+	}
+	add("")
+	return blocks
+}
+
+// inline renders the children of n as one paragraph
+func (r *markdownRenderer) inline(n *htmlNode) string {
+	var b strings.Builder
+	for _, child := range n.Children {
+		b.WriteString(r.inlineNode(child))
+	}
+	return b.String()
+}
+
+// inlineNode renders one node in inline context, where block elements
+// only break lines
+func (r *markdownRenderer) inlineNode(n *htmlNode) string {
+	switch n.Tag {
+	case "":
+		return escapeMarkdown(n.Text)
+	case "br":
+		return "\n"
+	case "img":
+		return ""
+	case "strong", "b":
+		return wrapInline(r.inline(n), "**")
+	case "em", "i":
+		return wrapInline(r.inline(n), "*")
+	case "code":
+		code := strings.Join(strings.Fields(rawText(n)), " ")
+		if code == "" {
+			return ""
+		}
+		if strings.Contains(code, "`") {
+			return "`` " + code + " ``"
+		}
+		return "`" + code + "`"
+	case "a":
+		text := r.inline(n)
+		if title, href := strings.Join(strings.Fields(text), " "), r.link(n.Attrs["href"]); href != "" && title != "" {
+			return "[" + title + "](" + href + ")"
+		}
+		return text
+	}
+	if slices.Contains(htmlBlockElements, n.Tag) {
+		return "\n" + r.inline(n) + "\n"
+	}
+	return r.inline(n)
+}
+
+// list renders ul and ol; nested lists indent below their item
+func (r *markdownRenderer) list(n *htmlNode) string {
+	var items []string
+	number := 1
+	if start := atoiOrZero(n.Attrs["start"]); start > 0 {
+		number = start
+	}
+	for _, li := range n.Children {
+		if li.Tag != "li" {
+			continue
+		}
+		body := strings.Join(r.blocks(li), "\n")
+		if body == "" {
+			continue
+		}
+		marker := "- "
+		if n.Tag == "ol" {
+			marker = fmt.Sprintf("%d. ", number)
+			number++
+		}
+		items = append(items, marker+strings.ReplaceAll(body, "\n", "\n"+strings.Repeat(" ", len(marker))))
+	}
+	return strings.Join(items, "\n")
+}
+
+// table renders a GitHub-flavored table with the first row as header
+func (r *markdownRenderer) table(n *htmlNode) string {
+	var rows [][]string
+	width := 0
+	for _, tr := range n.FindAll(byTag("tr")) {
+		var cells []string
+		for _, cell := range tr.Children {
+			if cell.Tag == "th" || cell.Tag == "td" {
+				text := strings.ReplaceAll(tidyInline(r.inline(cell)), "\n", " ")
+				cells = append(cells, strings.ReplaceAll(text, "|", `\|`))
+			}
+		}
+		if len(cells) > 0 {
+			rows = append(rows, cells)
+			width = max(width, len(cells))
+		}
+	}
+	if len(rows) == 0 {
+		return ""
+	}
+	var lines []string
+	for i, row := range rows {
+		for len(row) < width {
+			row = append(row, "")
+		}
+		lines = append(lines, "| "+strings.Join(row, " | ")+" |")
+		if i == 0 {
+			lines = append(lines, "|"+strings.Repeat(" --- |", width))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// link resolves href against the page; anchors and scripts are no links
+func (r *markdownRenderer) link(href string) string {
+	href = strings.TrimSpace(href)
+	if href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(strings.ToLower(href), "javascript:") {
+		return ""
+	}
+	target, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+	if r.base != nil {
+		target = r.base.ResolveReference(target)
+	}
+	return strings.NewReplacer("(", "%28", ")", "%29", " ", "%20").Replace(target.String())
+}
+
+var (
+	markdownEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`", "[", `\[`, "]", `\]`)
+	// Text at the start of a line that Markdown would read as a heading,
+	// quote or list item
+	markdownLineStart = regexp.MustCompile(`^(>|(#{1,6}|[+-]|\d+[.)])(\s|$))`)
+)
+
+// escapeMarkdown escapes text and folds its whitespace; only <br> and
+// block elements break lines
+func escapeMarkdown(text string) string {
+	folded := strings.Join(strings.Fields(text), " ")
+	if folded == "" {
+		if text != "" {
+			return " "
+		}
+		return ""
+	}
+	if strings.TrimLeftFunc(text[:1], isHTMLSpace) == "" {
+		folded = " " + folded
+	}
+	if strings.TrimRightFunc(text[len(text)-1:], isHTMLSpace) == "" {
+		folded += " "
+	}
+	return markdownEscaper.Replace(folded)
+}
+
+func isHTMLSpace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == '\f'
+}
+
+// tidyInline collapses spaces within lines, drops empty lines and escapes
+// line starts that would change the block type
+func tidyInline(s string) string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.Join(strings.Fields(line), " ")
+		if line == "" {
+			continue
+		}
+		if m := markdownLineStart.FindString(line); m != "" {
+			if m[0] >= '0' && m[0] <= '9' {
+				digits := strings.TrimRight(m, ".) \t")
+				line = digits + `\` + line[len(digits):]
+			} else {
+				line = `\` + line
+			}
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// wrapInline puts emphasis markers around text, keeping surrounding
+// spaces outside where Markdown needs them
+func wrapInline(text, marker string) string {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" {
+		return text
+	}
+	lead := text[:strings.Index(text, trimmed)]
+	trail := text[len(lead)+len(trimmed):]
+	return lead + marker + trimmed + marker + trail
+}
+
+// rawText is the text below n as written, for preformatted content
+func rawText(n *htmlNode) string {
+	if n.Tag == "" {
+		return n.Text
+	}
+	var b strings.Builder
+	for _, child := range n.Children {
+		if child.Tag == "br" {
+			b.WriteString("\n")
+		}
+		b.WriteString(rawText(child))
+	}
+	return b.String()
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/longevitycoach/StrunzKnowledge/src/scripts/strunzctl/pkg/mcpclient"
+)
+
+// The MCP client lives in pkg/mcpclient so other tools can import it; the
+// aliases keep the names the commands use
+type (
+	MCPClient           = mcpclient.Client
+	MCPError            = mcpclient.Error
+	MCPTool             = mcpclient.Tool
+	MCPToolResult       = mcpclient.ToolResult
+	MCPInitializeResult = mcpclient.InitializeResult
+	StreamStats         = mcpclient.StreamStats
+	HTTPStatusError     = mcpclient.HTTPStatusError
+)
+
+// mcpClientInfo is how strunzctl introduces itself to servers
+var mcpClientInfo = mcpclient.Implementation{Name: "strunzctl", Version: "1.0"}
+
+// connectMCP opens the SSE stream and waits for the message endpoint
+func connectMCP(serverURL string, timeout time.Duration) (*MCPClient, error) {
+	return connectMCPWithHeader(serverURL, timeout, nil)
+}
+
+// connectMCPWithHeader is connectMCP sending extra headers, such as the
+// Authorization and User-Agent of another client
+func connectMCPWithHeader(serverURL string, timeout time.Duration, header http.Header) (*MCPClient, error) {
+	client, err := mcpclient.Connect(commandContext, serverURL, timeout, header)
+	if err != nil {
+		return nil, err
+	}
+	client.ClientInfo = mcpClientInfo
+	return client, nil
+}
+
+func newMCPCommand() *Command {
+	return newGroup("mcp", "Test MCP servers the way Claude.ai and Claude Desktop talk to them.",
+		newMCPCheckCommand(),
+		newMCPSmokeCommand(),
+		newMCPOAuthTestCommand(),
+		newMCPLoadCommand(),
+		newMCPBenchCommand(),
+		newMCPConformanceCommand(),
+		newMCPSimulateClaudeCommand(),
+		newMCPStdioTestCommand(),
+		newMCPReconnectTestCommand(),
+		newMCPChaosProxyCommand(),
+		newMCPValidateCommand(),
+		newMCPDiffCommand(),
+		newMCPGatewayCommand(),
+		newMCPRateLimitTestCommand(),
+		newMCPLifecycleTestCommand(),
+		newMCPRecordCommand(),
+		newMCPReplayCommand(),
+		newMCPIsolationTestCommand(),
+		newMCPTraceTestCommand(),
+	)
+}
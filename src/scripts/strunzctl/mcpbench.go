@@ -0,0 +1,294 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// benchPercentiles are the rows of the HDR-style distribution table
+var benchPercentiles = []float64{0, 50, 75, 90, 95, 99, 99.9, 100}
+
+// BenchResult is one benchmark run as written by --output
+type BenchResult struct {
+	URL        string                    `json:"url"`
+	Version    string                    `json:"version,omitempty"`
+	At         time.Time                 `json:"at"`
+	Operations map[string]BenchOperation `json:"operations"`
+}
+
+// BenchOperation summarizes the latencies of one operation in milliseconds
+type BenchOperation struct {
+	Count  int     `json:"count"`
+	Errors int     `json:"errors"`
+	Min    float64 `json:"min_ms"`
+	Mean   float64 `json:"mean_ms"`
+	P50    float64 `json:"p50_ms"`
+	P90    float64 `json:"p90_ms"`
+	P95    float64 `json:"p95_ms"`
+	P99    float64 `json:"p99_ms"`
+	Max    float64 `json:"max_ms"`
+}
+
+func newMCPBenchCommand() *Command {
+	cmd := newCommand("bench", "[url]", "Measure latency percentiles of initialize, tools/list and tool calls, and compare runs against a budget.")
+	iterations := cmd.Flags.Int("iterations", 30, "measured requests per operation")
+	warmup := cmd.Flags.Int("warmup", 3, "unmeasured requests per operation before measuring")
+	tools := cmd.Flags.String("tools", smokeTool, "comma-separated tools to call (canned arguments as in mcp smoke)")
+	output := cmd.Flags.String("output", "", "write the results as JSON to this file")
+	baseline := cmd.Flags.String("baseline", "", "JSON results of an earlier run to compare with")
+	results := cmd.Flags.String("results", "", "compare these recorded JSON results with --baseline instead of running")
+	budget := cmd.Flags.Float64("budget", 20, "allowed p95 regression against the baseline, in percent")
+	timeout := cmd.Flags.Duration("timeout", 60*time.Second, "timeout for connecting and for each request")
+
+	cmd.Run = func(args []string) error {
+		if len(args) > 1 {
+			return cmd.ExactArgs(args, 1)
+		}
+		if *results != "" && *baseline == "" {
+			return fmt.Errorf("%w: --results needs --baseline to compare with", errUsage)
+		}
+		if *iterations < 1 {
+			return fmt.Errorf("%w: --iterations must be at least 1", errUsage)
+		}
+
+		var current *BenchResult
+		if *results != "" {
+			var err error
+			if current, err = loadBenchResult(*results); err != nil {
+				return err
+			}
+		} else {
+			serverURL := defaultServerURL
+			if len(args) == 1 {
+				serverURL = args[0]
+			}
+			samples, err := runBenchmark(serverURL, splitList(*tools), *iterations, *warmup, *timeout)
+			if err != nil {
+				return err
+			}
+			current = summarizeBenchmark(serverURL, samples)
+			if health, err := fetchHealth(serverURL); err == nil {
+				current.Version = health.Version
+			}
+			printBenchHistograms(samples)
+		}
+		printBenchSummary(current)
+
+		if *output != "" {
+			data, err := json.MarshalIndent(current, "", "  ")
+			if err != nil {
+				return err
+			}
+			if err := os.WriteFile(*output, append(data, '\n'), 0o644); err != nil {
+				return fmt.Errorf("failed to write benchmark results: %w", err)
+			}
+			fmt.Printf("\nResults written to %s\n", *output)
+		}
+		if *baseline == "" {
+			return nil
+		}
+		previous, err := loadBenchResult(*baseline)
+		if err != nil {
+			return err
+		}
+		if regressions := compareBenchmarks(previous, current, *budget); regressions > 0 {
+			return fmt.Errorf("%w: %d operation(s) regressed by more than %.0f%% at p95", errPolicy, regressions, *budget)
+		}
+		fmt.Printf("\n✅ p95 within %.0f%% of the baseline\n", *budget)
+		return nil
+	}
+	return cmd
+}
+
+// benchSamples holds the measured latencies and error count per operation,
+// in the order the operations were run
+type benchSamples struct {
+	order     []string
+	latencies map[string][]time.Duration
+	errors    map[string]int
+}
+
+func (s *benchSamples) add(operation string, latency time.Duration, err error) {
+	if _, ok := s.latencies[operation]; !ok && s.errors[operation] == 0 {
+		s.order = append(s.order, operation)
+	}
+	if err != nil {
+		s.errors[operation]++
+		return
+	}
+	s.latencies[operation] = append(s.latencies[operation], latency)
+}
+
+// runBenchmark measures each operation sequentially on one session, except
+// initialize, which needs a fresh session every time
+func runBenchmark(serverURL string, tools []string, iterations, warmup int, timeout time.Duration) (*benchSamples, error) {
+	samples := &benchSamples{latencies: map[string][]time.Duration{}, errors: map[string]int{}}
+	fmt.Printf("\n⏱️  Benchmarking %s (%d iterations, %d warmup)\n", serverURL, iterations, warmup)
+
+	for i := range warmup + iterations {
+		mcp, err := connectMCP(serverURL, timeout)
+		if err != nil {
+			return nil, err
+		}
+		start := time.Now()
+		_, err = mcp.Initialize(commandContext)
+		if i >= warmup {
+			samples.add("initialize", time.Since(start), err)
+		}
+		mcp.Close()
+	}
+
+	mcp, err := connectMCP(serverURL, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer mcp.Close()
+	if _, err := mcp.Initialize(commandContext); err != nil {
+		return nil, fmt.Errorf("initialize failed: %s", describeMCPError(err))
+	}
+	var advertised []MCPTool
+	for i := range warmup + iterations {
+		start := time.Now()
+		advertised, err = mcp.ListTools(commandContext)
+		if i >= warmup {
+			samples.add("tools/list", time.Since(start), err)
+		}
+	}
+
+	for _, name := range tools {
+		index := slices.IndexFunc(advertised, func(tool MCPTool) bool { return tool.Name == name })
+		if index < 0 {
+			return nil, fmt.Errorf("%w: tool %s is not advertised", errUsage, name)
+		}
+		arguments := toolArguments(advertised[index])
+		for i := range warmup + iterations {
+			start := time.Now()
+			_, err := callToolChecked(mcp, name, arguments)
+			if i >= warmup {
+				samples.add("tools/call "+name, time.Since(start), err)
+			}
+		}
+	}
+	return samples, nil
+}
+
+func summarizeBenchmark(serverURL string, samples *benchSamples) *BenchResult {
+	result := &BenchResult{URL: serverURL, At: time.Now().UTC(), Operations: map[string]BenchOperation{}}
+	for _, operation := range samples.order {
+		latencies := slices.Clone(samples.latencies[operation])
+		slices.Sort(latencies)
+		summary := BenchOperation{Count: len(latencies), Errors: samples.errors[operation]}
+		if len(latencies) > 0 {
+			var total time.Duration
+			for _, latency := range latencies {
+				total += latency
+			}
+			summary.Min = milliseconds(latencies[0])
+			summary.Mean = milliseconds(total / time.Duration(len(latencies)))
+			summary.P50 = milliseconds(percentile(latencies, 50))
+			summary.P90 = milliseconds(percentile(latencies, 90))
+			summary.P95 = milliseconds(percentile(latencies, 95))
+			summary.P99 = milliseconds(percentile(latencies, 99))
+			summary.Max = milliseconds(latencies[len(latencies)-1])
+		}
+		result.Operations[operation] = summary
+	}
+	return result
+}
+
+// printBenchHistograms prints a percentile distribution per operation in
+// the layout of HdrHistogram's outputPercentileDistribution
+func printBenchHistograms(samples *benchSamples) {
+	for _, operation := range samples.order {
+		latencies := slices.Clone(samples.latencies[operation])
+		slices.Sort(latencies)
+		fmt.Printf("\n%s\n", operation)
+		fmt.Printf("  %12s %12s %10s %14s\n", "Value(ms)", "Percentile", "TotalCount", "1/(1-Percentile)")
+		if len(latencies) == 0 {
+			fmt.Println("  (no successful requests)")
+			continue
+		}
+		for _, p := range benchPercentiles {
+			value := percentile(latencies, p)
+			count := slices.IndexFunc(latencies, func(l time.Duration) bool { return l > value })
+			if count < 0 {
+				count = len(latencies)
+			}
+			inverse := "inf"
+			if p < 100 {
+				inverse = fmt.Sprintf("%.2f", 1/(1-p/100))
+			}
+			fmt.Printf("  %12.3f %12.6f %10d %14s\n", milliseconds(value), p/100, count, inverse)
+		}
+	}
+}
+
+func printBenchSummary(result *BenchResult) {
+	fmt.Printf("\n📊 Latency of %s %s (ms)\n", result.URL, result.Version)
+	fmt.Printf("  %-36s %6s %6s %8s %8s %8s %8s %8s\n", "OPERATION", "COUNT", "ERRORS", "P50", "P90", "P95", "P99", "MAX")
+	for _, operation := range sortedOperations(result) {
+		o := result.Operations[operation]
+		fmt.Printf("  %-36s %6d %6d %8.1f %8.1f %8.1f %8.1f %8.1f\n", operation, o.Count, o.Errors, o.P50, o.P90, o.P95, o.P99, o.Max)
+	}
+}
+
+// compareBenchmarks prints the p95 change per operation and returns how many
+// regressed by more than budget percent
+func compareBenchmarks(previous, current *BenchResult, budget float64) int {
+	fmt.Printf("\n⚖️  p95 against %s %s (%s)\n", previous.URL, previous.Version, previous.At.Format(time.DateOnly))
+	regressions := 0
+	for _, operation := range sortedOperations(current) {
+		now := current.Operations[operation]
+		before, ok := previous.Operations[operation]
+		if !ok || before.P95 == 0 {
+			fmt.Printf("  ·  %-36s %8.1f  (not in baseline)\n", operation, now.P95)
+			continue
+		}
+		change := (now.P95 - before.P95) / before.P95 * 100
+		icon := "✅"
+		if change > budget {
+			icon = "❌"
+			regressions++
+		}
+		fmt.Printf("  %s %-36s %8.1f → %8.1f  %+6.1f%%\n", icon, operation, before.P95, now.P95, change)
+	}
+	return regressions
+}
+
+func sortedOperations(result *BenchResult) []string {
+	operations := make([]string, 0, len(result.Operations))
+	for operation := range result.Operations {
+		operations = append(operations, operation)
+	}
+	// initialize and tools/list first, then the tool calls
+	slices.SortFunc(operations, func(a, b string) int {
+		if ca, cb := strings.HasPrefix(a, "tools/call"), strings.HasPrefix(b, "tools/call"); ca != cb {
+			if ca {
+				return 1
+			}
+			return -1
+		}
+		return strings.Compare(a, b)
+	})
+	return operations
+}
+
+func loadBenchResult(path string) (*BenchResult, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read benchmark results: %w", err)
+	}
+	var result BenchResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse benchmark results %s: %w", path, err)
+	}
+	return &result, nil
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// chaosFaults are the configured fault rates, each between 0 and 1
+type chaosFaults struct {
+	latencyRate  float64
+	latency      time.Duration
+	jitter       time.Duration
+	errorRate    float64
+	resetRate    float64
+	truncateRate float64
+	// match limits faults to requests whose path contains it
+	match string
+}
+
+// chaosProxy forwards MCP traffic to the upstream server and injects faults
+type chaosProxy struct {
+	faults chaosFaults
+	proxy  *httputil.ReverseProxy
+
+	mu  sync.Mutex
+	rng *rand.Rand
+
+	requests, delayed, errors, resets, truncated atomic.Int64
+}
+
+// roll reports whether a fault with the given rate strikes
+func (p *chaosProxy) roll(rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.rng.Float64() < rate
+}
+
+// delay returns the latency plus a random jitter
+func (p *chaosProxy) delay() time.Duration {
+	if p.faults.jitter <= 0 {
+		return p.faults.latency
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.faults.latency + time.Duration(p.rng.Int64N(int64(p.faults.jitter)))
+}
+
+func (p *chaosProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.requests.Add(1)
+	target := r.Method + " " + r.URL.RequestURI()
+	if p.faults.match != "" && !strings.Contains(r.URL.Path, p.faults.match) {
+		p.proxy.ServeHTTP(w, r)
+		return
+	}
+
+	if p.roll(p.faults.resetRate) {
+		p.resets.Add(1)
+		fmt.Printf("  💥 reset     %s\n", target)
+		resetConnection(w)
+		return
+	}
+	if p.roll(p.faults.errorRate) {
+		p.errors.Add(1)
+		fmt.Printf("  💥 502       %s\n", target)
+		http.Error(w, "chaos-proxy: injected bad gateway", http.StatusBadGateway)
+		return
+	}
+	if p.roll(p.faults.latencyRate) {
+		d := p.delay()
+		p.delayed.Add(1)
+		fmt.Printf("  🐢 +%-8s %s\n", d.Round(time.Millisecond), target)
+		time.Sleep(d)
+	}
+	p.proxy.ServeHTTP(&chaosWriter{ResponseWriter: w, proxy: p, target: target}, r)
+}
+
+// chaosWriter delays and truncates the frames of a response as they are
+// written, which for SSE streams means individual events
+type chaosWriter struct {
+	http.ResponseWriter
+	proxy  *chaosProxy
+	target string
+	frames int
+}
+
+func (w *chaosWriter) Write(b []byte) (int, error) {
+	w.frames++
+	// The first frame of a stream is the endpoint event, which the request
+	// latency already delays
+	if w.frames > 1 && w.proxy.roll(w.proxy.faults.latencyRate) && isEventStream(w.Header()) {
+		w.proxy.delayed.Add(1)
+		time.Sleep(w.proxy.delay())
+	}
+	if len(b) > 1 && w.proxy.roll(w.proxy.faults.truncateRate) {
+		w.proxy.truncated.Add(1)
+		fmt.Printf("  ✂️  truncated %s after %d of %d bytes\n", w.target, len(b)/2, len(b))
+		w.ResponseWriter.Write(b[:len(b)/2])
+		http.NewResponseController(w.ResponseWriter).Flush()
+		// Aborting closes the connection without finishing the response
+		panic(http.ErrAbortHandler)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap lets the reverse proxy flush the underlying writer
+func (w *chaosWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func isEventStream(header http.Header) bool {
+	return strings.HasPrefix(header.Get("Content-Type"), "text/event-stream")
+}
+
+// resetConnection closes the client connection with a TCP RST
+func resetConnection(w http.ResponseWriter) {
+	conn, _, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		panic(http.ErrAbortHandler)
+	}
+	if tcp, ok := conn.(*net.TCPConn); ok {
+		tcp.SetLinger(0)
+	}
+	conn.Close()
+}
+
+func newMCPChaosProxyCommand() *Command {
+	cmd := newCommand("chaos-proxy", "[upstream-url]", "Proxy MCP traffic to a server while injecting latency, 502s, connection resets and truncated frames.")
+	listen := cmd.Flags.String("listen", "127.0.0.1:8090", "address to listen on")
+	latencyRate := cmd.Flags.Float64("latency-rate", 0.2, "share of requests and SSE events to delay")
+	latency := cmd.Flags.Duration("latency", 500*time.Millisecond, "added latency")
+	jitter := cmd.Flags.Duration("jitter", 250*time.Millisecond, "random extra latency up to this much")
+	errorRate := cmd.Flags.Float64("error-rate", 0.05, "share of requests answered with 502 Bad Gateway")
+	resetRate := cmd.Flags.Float64("reset-rate", 0.02, "share of requests whose connection is reset")
+	truncateRate := cmd.Flags.Float64("truncate-rate", 0.02, "share of response frames cut in half, closing the connection")
+	match := cmd.Flags.String("match", "", "only inject faults into requests whose path contains this (e.g. /messages)")
+	seed := cmd.Flags.Uint64("seed", 0, "random seed for reproducible runs (default: random)")
+
+	cmd.Run = func(args []string) error {
+		if len(args) > 1 {
+			return cmd.ExactArgs(args, 1)
+		}
+		upstream := defaultServerURL
+		if len(args) == 1 {
+			upstream = args[0]
+		}
+		target, err := url.Parse(upstream)
+		if err != nil || target.Host == "" {
+			return fmt.Errorf("%w: invalid upstream URL %q", errUsage, upstream)
+		}
+		for name, rate := range map[string]float64{"latency-rate": *latencyRate, "error-rate": *errorRate, "reset-rate": *resetRate, "truncate-rate": *truncateRate} {
+			if rate < 0 || rate > 1 {
+				return fmt.Errorf("%w: --%s must be between 0 and 1", errUsage, name)
+			}
+		}
+		if *seed == 0 {
+			*seed = rand.Uint64()
+		}
+
+		proxy := &chaosProxy{
+			faults: chaosFaults{
+				latencyRate: *latencyRate, latency: *latency, jitter: *jitter,
+				errorRate: *errorRate, resetRate: *resetRate, truncateRate: *truncateRate,
+				match: *match,
+			},
+			rng: rand.New(rand.NewPCG(*seed, *seed)),
+		}
+		proxy.proxy = &httputil.ReverseProxy{
+			Rewrite: func(r *httputil.ProxyRequest) {
+				r.SetURL(target)
+				r.Out.Host = target.Host
+			},
+			// Stream SSE events as they arrive
+			FlushInterval: -1,
+		}
+
+		listener, err := net.Listen("tcp", *listen)
+		if err != nil {
+			return fmt.Errorf("failed to listen: %w", err)
+		}
+		server := &http.Server{Handler: proxy}
+		ctx, stop := context.WithCancel(commandContext)
+		defer stop()
+		go func() {
+			<-ctx.Done()
+			server.Close()
+		}()
+
+		fmt.Printf("\n🌪️  Proxying http://%s → %s (seed %d, Ctrl-C to stop)\n", listener.Addr(), upstream, *seed)
+		fmt.Printf("  latency %.0f%% (%s + up to %s), 502 %.0f%%, reset %.0f%%, truncate %.0f%%\n\n",
+			*latencyRate*100, *latency, *jitter, *errorRate*100, *resetRate*100, *truncateRate*100)
+		if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+
+		fmt.Printf("\n📊 %d request(s): %d delayed, %d 502, %d reset, %d truncated\n",
+			proxy.requests.Load(), proxy.delayed.Load(), proxy.errors.Load(), proxy.resets.Load(), proxy.truncated.Load())
+		return nil
+	}
+	return cmd
+}
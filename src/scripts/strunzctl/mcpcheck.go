@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// supportedProtocolVersions are the MCP revisions a client may accept in
+// the initialize response; anything else must be treated as a failed
+// negotiation
+var supportedProtocolVersions = []string{"2024-11-05", "2025-03-26", "2025-06-18"}
+
+func newMCPCheckCommand() *Command {
+	cmd := newCommand("check", "[url]", "Run the MCP client handshake over SSE and verify the expected tools are discoverable.")
+	expect := cmd.Flags.String("expect", smokeTool, "comma-separated tools that must be advertised")
+	timeout := cmd.Flags.Duration("timeout", 30*time.Second, "timeout for connecting and for each request")
+
+	cmd.Run = func(args []string) error {
+		if len(args) > 1 {
+			return cmd.ExactArgs(args, 1)
+		}
+		serverURL := defaultServerURL
+		if len(args) == 1 {
+			serverURL = args[0]
+		}
+
+		fmt.Printf("\n🔌 MCP check of %s\n", serverURL)
+		problems, err := checkMCPServer(serverURL, splitList(*expect), *timeout)
+		if err != nil {
+			return err
+		}
+		if problems > 0 {
+			return fmt.Errorf("%w: %s failed %d MCP check(s)", errPolicy, serverURL, problems)
+		}
+		fmt.Println("\n✅ MCP handshake and tool discovery work")
+		return nil
+	}
+	return cmd
+}
+
+// checkMCPServer connects, negotiates and lists tools, printing each step,
+// and returns the number of problems found. Only an unreachable server is
+// an error.
+func checkMCPServer(serverURL string, expected []string, timeout time.Duration) (int, error) {
+	start := time.Now()
+	mcp, err := connectMCP(serverURL, timeout)
+	if err != nil {
+		fmt.Printf("  ❌ SSE connect: %v\n", err)
+		return 0, err
+	}
+	defer mcp.Close()
+	fmt.Printf("  ✅ SSE connected, messages go to %s (%s)\n", mcp.Endpoint(), time.Since(start).Round(time.Millisecond))
+
+	problems, tools := checkMCPSession(mcp, expected)
+	// /health reports a tool count; a mismatch means the SSE app and the
+	// health endpoint disagree about which tools are registered
+	if health, err := fetchHealth(serverURL); err == nil && tools != nil && health.ToolsCount > 0 && health.ToolsCount != len(tools) {
+		fmt.Printf("  ⚠️  /health reports %d tools, tools/list returned %d\n", health.ToolsCount, len(tools))
+	}
+	return problems, nil
+}
+
+// checkMCPSession negotiates and lists tools on a connected client of any
+// transport, printing each step. It returns the number of problems and the
+// tools, which are nil if tools/list was not reached.
+func checkMCPSession(mcp *MCPClient, expected []string) (int, []MCPTool) {
+	problems := 0
+	fail := func(format string, args ...any) {
+		problems++
+		fmt.Printf("  ❌ %s\n", fmt.Sprintf(format, args...))
+	}
+
+	start := time.Now()
+	result, err := mcp.Initialize(commandContext)
+	if err != nil {
+		fail("initialize: %s", describeMCPError(err))
+		return problems, nil
+	}
+	fmt.Printf("  ✅ initialize: %s %s, protocol %s (%s)\n", result.ServerInfo.Name, orNone(result.ServerInfo.Version),
+		result.ProtocolVersion, time.Since(start).Round(time.Millisecond))
+	if !slices.Contains(supportedProtocolVersions, result.ProtocolVersion) {
+		fail("server negotiated protocol %q, client supports %s", result.ProtocolVersion, strings.Join(supportedProtocolVersions, ", "))
+	}
+
+	var capabilities map[string]json.RawMessage
+	if err := json.Unmarshal(result.Capabilities, &capabilities); err != nil {
+		fail("capabilities are not an object: %v", err)
+	} else {
+		names := make([]string, 0, len(capabilities))
+		for name := range capabilities {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		if _, ok := capabilities["tools"]; !ok {
+			fail("capabilities do not include tools (got %s)", orNone(strings.Join(names, ", ")))
+		} else {
+			fmt.Printf("  ✅ capabilities: %s\n", strings.Join(names, ", "))
+		}
+	}
+
+	start = time.Now()
+	tools, err := mcp.ListTools(commandContext)
+	if err != nil {
+		fail("tools/list: %s", describeMCPError(err))
+		return problems, nil
+	}
+	fmt.Printf("  ✅ tools/list: %d tool(s) (%s)\n", len(tools), time.Since(start).Round(time.Millisecond))
+
+	seen := make(map[string]bool)
+	for _, tool := range tools {
+		if problem := toolDefinitionProblem(tool); problem != "" {
+			fail("tool %s: %s", orNone(tool.Name), problem)
+		} else if seen[tool.Name] {
+			fail("tool %s is advertised twice", tool.Name)
+		} else {
+			fmt.Printf("     %s\n", tool.Name)
+		}
+		seen[tool.Name] = true
+	}
+	for _, name := range expected {
+		if !seen[name] {
+			fail("expected tool %s is not advertised", name)
+		}
+	}
+	return problems, tools
+}
+
+// toolDefinitionProblem reports why Claude.ai would reject a tool
+// definition, or "" if it is valid
+func toolDefinitionProblem(tool MCPTool) string {
+	if tool.Name == "" {
+		return "has no name"
+	}
+	var schema struct {
+		Type string `json:"type"`
+	}
+	if len(tool.InputSchema) == 0 {
+		return "has no inputSchema"
+	}
+	if err := json.Unmarshal(tool.InputSchema, &schema); err != nil {
+		return fmt.Sprintf("inputSchema is not an object: %v", err)
+	}
+	if schema.Type != "object" {
+		return fmt.Sprintf("inputSchema type is %q, must be \"object\"", schema.Type)
+	}
+	return ""
+}
+
+// describeMCPError adds the JSON-RPC error data, which the server uses for
+// details such as validation errors
+func describeMCPError(err error) string {
+	var rpcErr *MCPError
+	if errors.As(err, &rpcErr) && len(rpcErr.Data) > 0 {
+		return fmt.Sprintf("%v (data: %s)", err, rpcErr.Data)
+	}
+	return err.Error()
+}
@@ -0,0 +1,352 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// JSON-RPC 2.0 error codes (section 5.1)
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+)
+
+// rpcResponse is a response as received, keeping the raw id so its JSON
+// type can be compared with the request
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result"`
+	Error   *MCPError       `json:"error"`
+}
+
+// conformanceCase is one check of the suite and the spec section it covers
+type conformanceCase struct {
+	Section string
+	Name    string
+	run     func(*conformanceRunner) (oauthResult, string)
+}
+
+// conformanceCases are run in order; each gets a fresh, initialized session
+// unless it needs to test the lifecycle itself
+var conformanceCases = []conformanceCase{
+	{"JSON-RPC §4", "notification gets no response", checkNotification},
+	{"JSON-RPC §4", "string id is echoed unchanged", checkStringID},
+	{"JSON-RPC §5", "out-of-order ids are matched", checkOutOfOrder},
+	{"JSON-RPC §5.1", "parse error (-32700)", checkParseError},
+	{"JSON-RPC §5.1", "invalid request (-32600)", checkInvalidRequest},
+	{"JSON-RPC §5.1", "method not found (-32601)", checkMethodNotFound},
+	{"JSON-RPC §5.1", "invalid params (-32602)", checkInvalidParams},
+	{"JSON-RPC §6", "batch request", checkBatch},
+	{"MCP lifecycle", "version negotiation", checkVersionNegotiation},
+	{"MCP lifecycle", "request before initialize", checkBeforeInitialize},
+	{"MCP utilities", "ping", checkPing},
+	{"MCP tools", "unknown tool", checkUnknownTool},
+}
+
+// conformanceRunner sends raw messages on one session and collects the
+// responses that arrive on the stream
+type conformanceRunner struct {
+	mcp     *MCPClient
+	timeout time.Duration
+	// received holds stream messages not yet claimed by a check
+	received []string
+}
+
+// send posts a raw body and returns the HTTP status and body of the POST
+func (r *conformanceRunner) send(body string) (int, string, error) {
+	return r.mcp.PostRaw(commandContext, []byte(body))
+}
+
+// await returns the first stream message accepted by match, or "" when none
+// arrives within the timeout
+func (r *conformanceRunner) await(match func(raw string) bool, timeout time.Duration) string {
+	for i, raw := range r.received {
+		if match(raw) {
+			r.received = append(r.received[:i], r.received[i+1:]...)
+			return raw
+		}
+	}
+	deadline := time.After(timeout)
+	for {
+		select {
+		case raw := <-r.mcp.Unmatched():
+			if match(raw) {
+				return raw
+			}
+			r.received = append(r.received, raw)
+		case <-deadline:
+			return ""
+		}
+	}
+}
+
+// awaitID waits for the response with the given JSON id
+func (r *conformanceRunner) awaitID(id string) *rpcResponse {
+	raw := r.await(func(raw string) bool {
+		var response rpcResponse
+		return json.Unmarshal([]byte(raw), &response) == nil && string(response.ID) == id
+	}, r.timeout)
+	if raw == "" {
+		return nil
+	}
+	var response rpcResponse
+	json.Unmarshal([]byte(raw), &response)
+	return &response
+}
+
+// expectError checks for an error response with the given code, accepting
+// a null id when the request id could not be read
+func (r *conformanceRunner) expectError(body, id string, code int) (oauthResult, string) {
+	status, text, err := r.send(body)
+	if err != nil {
+		return oauthFail, err.Error()
+	}
+	response := r.awaitID(id)
+	if response == nil && id != "null" {
+		response = r.awaitID("null")
+	}
+	switch {
+	case response == nil && status/100 != 2:
+		return oauthWarn, fmt.Sprintf("rejected with HTTP %d (%s) instead of a JSON-RPC error", status, truncate(text, 60))
+	case response == nil:
+		return oauthFail, "no response"
+	case response.Error == nil:
+		return oauthFail, "answered with a result instead of an error"
+	case response.Error.Code != code:
+		return oauthFail, fmt.Sprintf("error code %d, expected %d (%s)", response.Error.Code, code, response.Error.Message)
+	case response.JSONRPC != "2.0":
+		return oauthFail, fmt.Sprintf("jsonrpc is %q", response.JSONRPC)
+	}
+	return oauthPass, response.Error.Message
+}
+
+func checkNotification(r *conformanceRunner) (oauthResult, string) {
+	if _, _, err := r.send(`{"jsonrpc":"2.0","method":"notifications/strunzctl-conformance"}`); err != nil {
+		return oauthFail, err.Error()
+	}
+	raw := r.await(func(raw string) bool {
+		var response rpcResponse
+		return json.Unmarshal([]byte(raw), &response) == nil && (response.Result != nil || response.Error != nil)
+	}, 2*time.Second)
+	if raw != "" {
+		return oauthFail, "server answered a notification: " + truncate(raw, 60)
+	}
+	return oauthPass, "no response within 2s"
+}
+
+func checkStringID(r *conformanceRunner) (oauthResult, string) {
+	if _, _, err := r.send(`{"jsonrpc":"2.0","id":"strunzctl-7","method":"ping"}`); err != nil {
+		return oauthFail, err.Error()
+	}
+	if response := r.awaitID(`"strunzctl-7"`); response == nil {
+		if numeric := r.awaitID("7"); numeric != nil {
+			return oauthFail, "string id came back as a number"
+		}
+		return oauthFail, "no response with the string id"
+	}
+	return oauthPass, `"strunzctl-7" echoed`
+}
+
+func checkOutOfOrder(r *conformanceRunner) (oauthResult, string) {
+	// A slow tools/list between two pings; each answer must carry its own id
+	requests := []struct{ id, method string }{{"923", "tools/list"}, {"921", "ping"}, {"922", "ping"}}
+	var wg sync.WaitGroup
+	errs := make([]error, len(requests))
+	for i, request := range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, errs[i] = r.send(fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,"method":%q}`, request.id, request.method))
+		}()
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return oauthFail, err.Error()
+	}
+	for _, request := range requests {
+		response := r.awaitID(request.id)
+		if response == nil {
+			return oauthFail, fmt.Sprintf("no response for id %s", request.id)
+		}
+		if response.Error != nil {
+			return oauthFail, fmt.Sprintf("%s (id %s): %s", request.method, request.id, describeMCPError(response.Error))
+		}
+		if isList := bytes.Contains(response.Result, []byte(`"tools"`)); isList != (request.method == "tools/list") {
+			return oauthFail, fmt.Sprintf("id %s got the answer of another request", request.id)
+		}
+	}
+	return oauthPass, "3 concurrent requests answered with matching ids"
+}
+
+func checkParseError(r *conformanceRunner) (oauthResult, string) {
+	return r.expectError(`{"jsonrpc":"2.0","id":931,"method":`, "null", rpcParseError)
+}
+
+func checkInvalidRequest(r *conformanceRunner) (oauthResult, string) {
+	return r.expectError(`{"jsonrpc":"2.0","id":932,"method":42}`, "932", rpcInvalidRequest)
+}
+
+func checkMethodNotFound(r *conformanceRunner) (oauthResult, string) {
+	return r.expectError(`{"jsonrpc":"2.0","id":933,"method":"strunzctl/unknown"}`, "933", rpcMethodNotFound)
+}
+
+func checkInvalidParams(r *conformanceRunner) (oauthResult, string) {
+	return r.expectError(`{"jsonrpc":"2.0","id":934,"method":"tools/call","params":{"arguments":{}}}`, "934", rpcInvalidParams)
+}
+
+func checkBatch(r *conformanceRunner) (oauthResult, string) {
+	status, text, err := r.send(`[{"jsonrpc":"2.0","id":941,"method":"ping"},{"jsonrpc":"2.0","id":942,"method":"ping"}]`)
+	if err != nil {
+		return oauthFail, err.Error()
+	}
+	raw := r.await(func(raw string) bool { return strings.HasPrefix(strings.TrimSpace(raw), "[") }, r.timeout)
+	if raw == "" {
+		first, second := r.awaitID("941"), r.awaitID("942")
+		if first != nil && second != nil {
+			return oauthWarn, "answered as two single responses instead of one array"
+		}
+		// MCP 2025-06-18 dropped batching, so rejecting it is allowed
+		return oauthWarn, fmt.Sprintf("batches not supported (HTTP %d %s)", status, truncate(text, 40))
+	}
+	var responses []rpcResponse
+	if err := json.Unmarshal([]byte(raw), &responses); err != nil || len(responses) != 2 {
+		return oauthFail, "batch response is not an array of 2 responses"
+	}
+	return oauthPass, "2 responses in one array"
+}
+
+func checkVersionNegotiation(r *conformanceRunner) (oauthResult, string) {
+	mcp, err := connectMCP(r.mcp.URL(), r.timeout)
+	if err != nil {
+		return oauthFail, err.Error()
+	}
+	defer mcp.Close()
+	var result MCPInitializeResult
+	err = mcp.Request(commandContext, "initialize", map[string]any{
+		"protocolVersion": "1999-01-01",
+		"capabilities":    map[string]any{},
+		"clientInfo":      map[string]string{"name": "strunzctl", "version": "1.0"},
+	}, &result)
+	switch {
+	case err != nil:
+		return oauthFail, "unsupported version rejected instead of answered with a supported one: " + describeMCPError(err)
+	case result.ProtocolVersion == "1999-01-01":
+		return oauthFail, "server echoed a version it cannot support"
+	}
+	return oauthPass, "server proposed " + result.ProtocolVersion
+}
+
+func checkBeforeInitialize(r *conformanceRunner) (oauthResult, string) {
+	mcp, err := connectMCP(r.mcp.URL(), r.timeout)
+	if err != nil {
+		return oauthFail, err.Error()
+	}
+	defer mcp.Close()
+	if _, err := mcp.ListTools(commandContext); err == nil {
+		return oauthWarn, "tools/list answered before initialize"
+	}
+	return oauthPass, "rejected before initialize"
+}
+
+func checkPing(r *conformanceRunner) (oauthResult, string) {
+	if _, _, err := r.send(`{"jsonrpc":"2.0","id":951,"method":"ping"}`); err != nil {
+		return oauthFail, err.Error()
+	}
+	response := r.awaitID("951")
+	switch {
+	case response == nil:
+		return oauthFail, "no response"
+	case response.Error != nil:
+		return oauthFail, describeMCPError(response.Error)
+	case strings.ReplaceAll(string(response.Result), " ", "") != "{}":
+		return oauthWarn, "result is " + truncate(string(response.Result), 40) + ", expected {}"
+	}
+	return oauthPass, "empty result"
+}
+
+func checkUnknownTool(r *conformanceRunner) (oauthResult, string) {
+	if _, _, err := r.send(`{"jsonrpc":"2.0","id":961,"method":"tools/call","params":{"name":"strunzctl_unknown_tool","arguments":{}}}`); err != nil {
+		return oauthFail, err.Error()
+	}
+	response := r.awaitID("961")
+	switch {
+	case response == nil:
+		return oauthFail, "no response"
+	case response.Error != nil && response.Error.Code == rpcInvalidParams:
+		return oauthPass, response.Error.Message
+	case response.Error != nil:
+		return oauthWarn, fmt.Sprintf("error code %d, the spec uses %d", response.Error.Code, rpcInvalidParams)
+	}
+	var result MCPToolResult
+	if json.Unmarshal(response.Result, &result) == nil && result.IsError {
+		return oauthWarn, "reported as a tool result with isError instead of a protocol error"
+	}
+	return oauthFail, "unknown tool answered with a successful result"
+}
+
+func newMCPConformanceCommand() *Command {
+	cmd := newCommand("conformance", "[url]", "Send malformed, unknown, batched and concurrent requests and check the answers against JSON-RPC 2.0 and MCP.")
+	timeout := cmd.Flags.Duration("timeout", 10*time.Second, "how long to wait for each response")
+	junit := cmd.Flags.String("junit", "", "write a JUnit XML report to this file")
+	strict := cmd.Flags.Bool("strict", false, "fail on warnings, not only on violations")
+
+	cmd.Run = func(args []string) error {
+		if len(args) > 1 {
+			return cmd.ExactArgs(args, 1)
+		}
+		serverURL := defaultServerURL
+		if len(args) == 1 {
+			serverURL = args[0]
+		}
+
+		fmt.Printf("\n📐 JSON-RPC and MCP conformance of %s\n", serverURL)
+		var checks []smokeCheck
+		failed, section := 0, ""
+		for _, c := range conformanceCases {
+			mcp, err := connectMCP(serverURL, *timeout)
+			if err != nil {
+				return err
+			}
+			if _, err := mcp.Initialize(commandContext); err != nil {
+				mcp.Close()
+				return fmt.Errorf("initialize failed: %s", describeMCPError(err))
+			}
+			start := time.Now()
+			result, detail := c.run(&conformanceRunner{mcp: mcp, timeout: *timeout})
+			mcp.Close()
+
+			if c.Section != section {
+				section = c.Section
+				fmt.Printf("\n%s\n", section)
+			}
+			fmt.Printf("  %s %-36s %s\n", result, c.Name, detail)
+			check := smokeCheck{Name: c.Section + ": " + c.Name, Duration: time.Since(start)}
+			if result == oauthFail || (*strict && result == oauthWarn) {
+				failed++
+				check.Err = errors.New(detail)
+			}
+			checks = append(checks, check)
+		}
+
+		if *junit != "" {
+			if err := writeJUnitReport(*junit, "mcp conformance "+serverURL, checks); err != nil {
+				return err
+			}
+			fmt.Printf("\nJUnit report written to %s\n", *junit)
+		}
+		if failed > 0 {
+			return fmt.Errorf("%w: %d conformance check(s) failed", errPolicy, failed)
+		}
+		fmt.Println("\n✅ No protocol violations")
+		return nil
+	}
+	return cmd
+}
@@ -0,0 +1,434 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// goldenQuery is one tool call of a --queries file
+type goldenQuery struct {
+	Name      string         `json:"name"`
+	Tool      string         `json:"tool"`
+	Arguments map[string]any `json:"arguments"`
+}
+
+// searchHit is one result in the text search tools return
+type searchHit struct {
+	Rank    int     `json:"rank"`
+	Source  string  `json:"source"`
+	Content string  `json:"content"`
+	Score   float64 `json:"score"`
+}
+
+// key identifies a hit across versions; the same chunk keeps its source
+// and text even when its rank or score moves
+func (h searchHit) key() string {
+	return h.Source + " | " + truncate(h.Content, 60)
+}
+
+// searchHitPattern matches the result blocks of search_knowledge
+var searchHitPattern = regexp.MustCompile(`\*\*Result (\d+):\*\*\s*\n\*\*Source:\*\* (.*)\n\*\*Content:\*\* ([\s\S]*?)\n\*\*Relevance Score:\*\* ([-\d.]+)`)
+
+// QueryDiff is the difference of one golden query between two servers
+type QueryDiff struct {
+	Name      string         `json:"name"`
+	Tool      string         `json:"tool"`
+	Arguments map[string]any `json:"arguments"`
+	// Status is unchanged, changed or error
+	Status         string        `json:"status"`
+	BaseError      string        `json:"base_error,omitempty"`
+	CandidateError string        `json:"candidate_error,omitempty"`
+	Hits           *HitDiff      `json:"hits,omitempty"`
+	Fields         []FieldChange `json:"fields,omitempty"`
+	LinesAdded     []string      `json:"lines_added,omitempty"`
+	LinesRemoved   []string      `json:"lines_removed,omitempty"`
+}
+
+// HitDiff compares the hits of a search between two servers
+type HitDiff struct {
+	Base      int           `json:"base_count"`
+	Candidate int           `json:"candidate_count"`
+	Added     []searchHit   `json:"added,omitempty"`
+	Removed   []searchHit   `json:"removed,omitempty"`
+	Moved     []FieldChange `json:"moved,omitempty"`
+	Rescored  []FieldChange `json:"rescored,omitempty"`
+}
+
+// FieldChange is a value that differs, identified by a path or hit key
+type FieldChange struct {
+	Path      string `json:"path"`
+	Base      string `json:"base"`
+	Candidate string `json:"candidate"`
+}
+
+func newMCPDiffCommand() *Command {
+	cmd := newCommand("diff", "", "Run the same tool calls against two servers or image tags and diff the results.")
+	base := cmd.Flags.String("base", "", "URL or image tag of the reference server (required)")
+	candidate := cmd.Flags.String("candidate", "", "URL or image tag of the server to compare (required)")
+	queries := cmd.Flags.String("queries", "", "golden queries file (default: built-in searches)")
+	output := cmd.Flags.String("output", "", "write the diff as JSON to this file")
+	scoreTolerance := cmd.Flags.Float64("score-tolerance", 0.01, "ignore relevance score changes up to this much")
+	failOnChange := cmd.Flags.Bool("fail-on-change", false, "exit with the policy code when any result changed")
+	platform := cmd.Flags.String("platform", defaultPlatform, "platform of images started for tags")
+	startup := cmd.Flags.Duration("startup-timeout", 5*time.Minute, "how long a started image may take to become healthy")
+	timeout := cmd.Flags.Duration("timeout", 60*time.Second, "timeout for connecting and for each tool call")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 0); err != nil {
+			return err
+		}
+		if *base == "" || *candidate == "" {
+			return fmt.Errorf("%w: --base and --candidate are required", errUsage)
+		}
+		golden, err := loadGoldenQueries(*queries)
+		if err != nil {
+			return err
+		}
+
+		baseURL, stopBase, err := startDiffTarget(*base, 8001, *platform, *startup)
+		if err != nil {
+			return err
+		}
+		defer stopBase()
+		candidateURL, stopCandidate, err := startDiffTarget(*candidate, 8002, *platform, *startup)
+		if err != nil {
+			return err
+		}
+		defer stopCandidate()
+
+		fmt.Printf("\n🔬 Diffing %d golden queries: %s → %s\n", len(golden), *base, *candidate)
+		baseResults, err := runGoldenQueries(baseURL, golden, *timeout)
+		if err != nil {
+			return fmt.Errorf("base %s: %w", *base, err)
+		}
+		candidateResults, err := runGoldenQueries(candidateURL, golden, *timeout)
+		if err != nil {
+			return fmt.Errorf("candidate %s: %w", *candidate, err)
+		}
+
+		var diffs []QueryDiff
+		changed := 0
+		for i, query := range golden {
+			diff := diffToolResults(query, baseResults[i], candidateResults[i], *scoreTolerance)
+			if diff.Status != "unchanged" {
+				changed++
+			}
+			printQueryDiff(diff)
+			diffs = append(diffs, diff)
+		}
+
+		if *output != "" {
+			data, err := json.MarshalIndent(map[string]any{"base": *base, "candidate": *candidate, "queries": diffs}, "", "  ")
+			if err != nil {
+				return err
+			}
+			if err := os.WriteFile(*output, append(data, '\n'), 0o644); err != nil {
+				return fmt.Errorf("failed to write diff: %w", err)
+			}
+			fmt.Printf("\nDiff written to %s\n", *output)
+		}
+		fmt.Printf("\n%d of %d queries changed\n", changed, len(golden))
+		if changed > 0 && *failOnChange {
+			return fmt.Errorf("%w: %d queries changed between %s and %s", errPolicy, changed, *base, *candidate)
+		}
+		return nil
+	}
+	return cmd
+}
+
+// loadGoldenQueries reads a queries file of the form
+//
+//	vitamin-d:
+//	  tool: search_knowledge
+//	  arguments:
+//	    query: Vitamin D
+//	    limit: 5
+//
+// Argument values that are valid JSON (numbers, booleans, [lists]) keep
+// their type; everything else is a string. Without a file the load test
+// queries are searched.
+func loadGoldenQueries(path string) ([]goldenQuery, error) {
+	if path == "" {
+		var golden []goldenQuery
+		for _, query := range loadQueries {
+			golden = append(golden, goldenQuery{Name: query, Tool: smokeTool, Arguments: map[string]any{"query": query, "limit": 5}})
+		}
+		return golden, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: --queries: %v", errUsage, err)
+	}
+	values, err := parseYAML(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to parse %s: %v", errUsage, path, err)
+	}
+
+	var golden []goldenQuery
+	for name, value := range values {
+		entry, ok := value.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s: query %q must be a section with tool and arguments", errUsage, path, name)
+		}
+		query := goldenQuery{Name: name, Tool: smokeTool, Arguments: map[string]any{}}
+		for key, value := range entry {
+			switch key {
+			case "tool":
+				tool, ok := value.(string)
+				if !ok {
+					return nil, fmt.Errorf("%w: %s: %s.tool must be a tool name", errUsage, path, name)
+				}
+				query.Tool = tool
+			case "arguments":
+				arguments, ok := value.(map[string]any)
+				if !ok {
+					return nil, fmt.Errorf("%w: %s: %s.arguments must be a section", errUsage, path, name)
+				}
+				for argument, raw := range arguments {
+					text, ok := raw.(string)
+					if !ok {
+						return nil, fmt.Errorf("%w: %s: %s.arguments.%s must be a value", errUsage, path, name, argument)
+					}
+					var typed any
+					if json.Unmarshal([]byte(text), &typed) != nil {
+						typed = text
+					}
+					query.Arguments[argument] = typed
+				}
+			default:
+				return nil, fmt.Errorf("%w: %s: unknown key %s.%s", errUsage, path, name, key)
+			}
+		}
+		golden = append(golden, query)
+	}
+	slices.SortFunc(golden, func(a, b goldenQuery) int { return strings.Compare(a.Name, b.Name) })
+	return golden, nil
+}
+
+// startDiffTarget returns the URL of a server, starting an image locally
+// when target is a tag rather than a URL
+func startDiffTarget(target string, port int, platform string, startup time.Duration) (string, func(), error) {
+	if strings.Contains(target, "://") {
+		return target, func() {}, nil
+	}
+	reference := newRegistryClient(config.Registry, imageRepository()).Reference(target)
+	container, err := startCandidate(reference, platform, port)
+	if err != nil {
+		return "", nil, err
+	}
+	url := fmt.Sprintf("http://127.0.0.1:%d", port)
+	fmt.Printf("\n🐳 Started %s as %s, waiting for %s/health\n", reference, container[:min(12, len(container))], url)
+	if err := waitHealthy(url, startup); err != nil {
+		printContainerLogs(container)
+		stopCandidate(container)
+		return "", nil, err
+	}
+	return url, func() { stopCandidate(container) }, nil
+}
+
+// goldenResult is the text a query returned, or why it failed
+type goldenResult struct {
+	Text string
+	Err  string
+}
+
+func runGoldenQueries(serverURL string, golden []goldenQuery, timeout time.Duration) ([]goldenResult, error) {
+	mcp, err := connectMCP(serverURL, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer mcp.Close()
+	if _, err := mcp.Initialize(commandContext); err != nil {
+		return nil, fmt.Errorf("initialize failed: %s", describeMCPError(err))
+	}
+	results := make([]goldenResult, len(golden))
+	for i, query := range golden {
+		result, err := mcp.CallTool(commandContext, query.Tool, query.Arguments)
+		switch {
+		case err != nil:
+			results[i].Err = describeMCPError(err)
+		case result.IsError:
+			results[i].Err = "isError: " + result.Text()
+		default:
+			results[i].Text = result.Text()
+		}
+	}
+	return results, nil
+}
+
+// diffToolResults compares search hits when both sides return them, JSON
+// values when both return JSON, and lines otherwise
+func diffToolResults(query goldenQuery, base, candidate goldenResult, scoreTolerance float64) QueryDiff {
+	diff := QueryDiff{Name: query.Name, Tool: query.Tool, Arguments: query.Arguments, Status: "unchanged"}
+	if base.Err != "" || candidate.Err != "" {
+		diff.BaseError, diff.CandidateError = base.Err, candidate.Err
+		if base.Err != candidate.Err {
+			diff.Status = "error"
+		}
+		return diff
+	}
+
+	baseHits, baseOK := parseSearchHits(base.Text)
+	candidateHits, candidateOK := parseSearchHits(candidate.Text)
+	var baseJSON, candidateJSON any
+	switch {
+	case baseOK && candidateOK:
+		diff.Hits = diffSearchHits(baseHits, candidateHits, scoreTolerance)
+		if len(diff.Hits.Added)+len(diff.Hits.Removed)+len(diff.Hits.Moved)+len(diff.Hits.Rescored) > 0 {
+			diff.Status = "changed"
+		}
+	case json.Unmarshal([]byte(base.Text), &baseJSON) == nil && json.Unmarshal([]byte(candidate.Text), &candidateJSON) == nil:
+		baseFields, candidateFields := map[string]string{}, map[string]string{}
+		flattenJSON(baseJSON, "", baseFields)
+		flattenJSON(candidateJSON, "", candidateFields)
+		for _, path := range sortedKeys(baseFields, candidateFields) {
+			if baseFields[path] != candidateFields[path] {
+				diff.Fields = append(diff.Fields, FieldChange{Path: path, Base: baseFields[path], Candidate: candidateFields[path]})
+			}
+		}
+		if len(diff.Fields) > 0 {
+			diff.Status = "changed"
+		}
+	default:
+		baseLines, candidateLines := strings.Split(base.Text, "\n"), strings.Split(candidate.Text, "\n")
+		for _, line := range candidateLines {
+			if !slices.Contains(baseLines, line) {
+				diff.LinesAdded = append(diff.LinesAdded, line)
+			}
+		}
+		for _, line := range baseLines {
+			if !slices.Contains(candidateLines, line) {
+				diff.LinesRemoved = append(diff.LinesRemoved, line)
+			}
+		}
+		if len(diff.LinesAdded)+len(diff.LinesRemoved) > 0 {
+			diff.Status = "changed"
+		}
+	}
+	return diff
+}
+
+func parseSearchHits(text string) ([]searchHit, bool) {
+	matches := searchHitPattern.FindAllStringSubmatch(text, -1)
+	if len(matches) == 0 {
+		return nil, strings.HasPrefix(text, "No results found")
+	}
+	hits := make([]searchHit, 0, len(matches))
+	for _, match := range matches {
+		rank, _ := strconv.Atoi(match[1])
+		score, _ := strconv.ParseFloat(match[4], 64)
+		hits = append(hits, searchHit{Rank: rank, Source: strings.TrimSpace(match[2]), Content: strings.TrimSpace(match[3]), Score: score})
+	}
+	return hits, true
+}
+
+func diffSearchHits(base, candidate []searchHit, scoreTolerance float64) *HitDiff {
+	diff := &HitDiff{Base: len(base), Candidate: len(candidate)}
+	baseByKey := make(map[string]searchHit)
+	for _, hit := range base {
+		baseByKey[hit.key()] = hit
+	}
+	seen := make(map[string]bool)
+	for _, hit := range candidate {
+		seen[hit.key()] = true
+		before, ok := baseByKey[hit.key()]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, hit)
+		case before.Rank != hit.Rank:
+			diff.Moved = append(diff.Moved, FieldChange{Path: hit.key(), Base: strconv.Itoa(before.Rank), Candidate: strconv.Itoa(hit.Rank)})
+		}
+		if ok && math.Abs(before.Score-hit.Score) > scoreTolerance {
+			diff.Rescored = append(diff.Rescored, FieldChange{Path: hit.key(), Base: fmt.Sprintf("%.3f", before.Score), Candidate: fmt.Sprintf("%.3f", hit.Score)})
+		}
+	}
+	for _, hit := range base {
+		if !seen[hit.key()] {
+			diff.Removed = append(diff.Removed, hit)
+		}
+	}
+	return diff
+}
+
+// flattenJSON collects the leaf values of a document by path
+func flattenJSON(value any, path string, into map[string]string) {
+	switch value := value.(type) {
+	case map[string]any:
+		for key, child := range value {
+			flattenJSON(child, path+"/"+key, into)
+		}
+	case []any:
+		for i, child := range value {
+			flattenJSON(child, fmt.Sprintf("%s/%d", path, i), into)
+		}
+	default:
+		data, _ := json.Marshal(value)
+		into[orNone(path)] = string(data)
+	}
+}
+
+// sortedKeys returns the keys of both maps, sorted
+func sortedKeys(a, b map[string]string) []string {
+	var keys []string
+	for _, m := range []map[string]string{a, b} {
+		for key := range m {
+			if !slices.Contains(keys, key) {
+				keys = append(keys, key)
+			}
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func printQueryDiff(diff QueryDiff) {
+	switch diff.Status {
+	case "unchanged":
+		fmt.Printf("\n  = %s (%s)\n", diff.Name, diff.Tool)
+		if diff.BaseError != "" {
+			fmt.Printf("      fails on both: %s\n", truncate(diff.BaseError, 100))
+		}
+		return
+	case "error":
+		fmt.Printf("\n  ❌ %s (%s)\n", diff.Name, diff.Tool)
+		fmt.Printf("      base:      %s\n", orNone(truncate(diff.BaseError, 100)))
+		fmt.Printf("      candidate: %s\n", orNone(truncate(diff.CandidateError, 100)))
+		return
+	}
+
+	fmt.Printf("\n  ≠ %s (%s)\n", diff.Name, diff.Tool)
+	if hits := diff.Hits; hits != nil {
+		fmt.Printf("      %d → %d hit(s)\n", hits.Base, hits.Candidate)
+		for _, hit := range hits.Added {
+			fmt.Printf("      + #%d %s (%.3f)\n", hit.Rank, truncate(hit.key(), 90), hit.Score)
+		}
+		for _, hit := range hits.Removed {
+			fmt.Printf("      - #%d %s (%.3f)\n", hit.Rank, truncate(hit.key(), 90), hit.Score)
+		}
+		for _, move := range hits.Moved {
+			fmt.Printf("      ↕ #%s → #%s %s\n", move.Base, move.Candidate, truncate(move.Path, 90))
+		}
+		for _, score := range hits.Rescored {
+			fmt.Printf("      ~ %s → %s %s\n", score.Base, score.Candidate, truncate(score.Path, 90))
+		}
+	}
+	for _, field := range diff.Fields {
+		fmt.Printf("      ~ %s: %s → %s\n", field.Path, truncate(field.Base, 40), truncate(field.Candidate, 40))
+	}
+	for _, line := range diff.LinesRemoved[:min(len(diff.LinesRemoved), 5)] {
+		fmt.Printf("      - %s\n", truncate(line, 100))
+	}
+	for _, line := range diff.LinesAdded[:min(len(diff.LinesAdded), 5)] {
+		fmt.Printf("      + %s\n", truncate(line, 100))
+	}
+	if hidden := len(diff.LinesAdded) + len(diff.LinesRemoved) - min(len(diff.LinesRemoved), 5) - min(len(diff.LinesAdded), 5); hidden > 0 {
+		fmt.Printf("      … %d more changed line(s)\n", hidden)
+	}
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// gatewayIdentityHeaders carry the validated token owner to the upstream
+// server; client-supplied values are dropped so they cannot be spoofed
+var gatewayIdentityHeaders = []string{"X-Auth-Subject", "X-Auth-Scope"}
+
+// tokenInfo is what the upstream userinfo endpoint returns for a token
+type tokenInfo struct {
+	Subject string `json:"sub"`
+	Scope   string `json:"scope"`
+}
+
+var errInvalidToken = errors.New("invalid or expired token")
+
+// tokenCache remembers validated and rejected tokens, keyed by their hash
+// so the raw tokens are not kept in memory
+type tokenCache struct {
+	validate func(token string) (*tokenInfo, error)
+	ttl      time.Duration
+
+	mu      sync.Mutex
+	entries map[[32]byte]tokenCacheEntry
+
+	hits, misses atomic.Int64
+}
+
+type tokenCacheEntry struct {
+	info    *tokenInfo // nil for rejected tokens
+	expires time.Time
+}
+
+// lookup returns the owner of a token, asking upstream only on a miss.
+// Rejections are cached briefly so a bad token cannot hammer upstream.
+func (c *tokenCache) lookup(token string) (*tokenInfo, error) {
+	key := sha256.Sum256([]byte(token))
+	now := time.Now()
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && now.Before(entry.expires) {
+		c.hits.Add(1)
+		if entry.info == nil {
+			return nil, errInvalidToken
+		}
+		return entry.info, nil
+	}
+
+	c.misses.Add(1)
+	info, err := c.validate(token)
+	switch {
+	case errors.Is(err, errInvalidToken):
+		entry = tokenCacheEntry{expires: now.Add(min(c.ttl, 30*time.Second))}
+	case err != nil:
+		// Upstream trouble says nothing about the token
+		return nil, err
+	default:
+		entry = tokenCacheEntry{info: info, expires: now.Add(c.ttl)}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, old := range c.entries {
+		if now.After(old.expires) {
+			delete(c.entries, key)
+		}
+	}
+	c.entries[key] = entry
+	return entry.info, err
+}
+
+// userinfoValidator validates tokens against the upstream userinfo endpoint
+func userinfoValidator(endpoint string, timeout time.Duration) func(string) (*tokenInfo, error) {
+	client := &http.Client{Timeout: timeout}
+	return func(token string) (*tokenInfo, error) {
+		req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Accept", "application/json")
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("userinfo request failed: %w", err)
+		}
+		defer resp.Body.Close()
+		switch {
+		case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+			return nil, errInvalidToken
+		case resp.StatusCode != http.StatusOK:
+			return nil, fmt.Errorf("userinfo returned %s", resp.Status)
+		}
+		var info tokenInfo
+		if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+			return nil, fmt.Errorf("failed to parse userinfo: %w", err)
+		}
+		return &info, nil
+	}
+}
+
+// rateLimiter is a token bucket per client
+type rateLimiter struct {
+	rate  float64 // requests per second
+	burst float64
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// allow takes a token from the client's bucket, or returns how long until
+// one is available
+func (l *rateLimiter) allow(client string) (bool, time.Duration) {
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	bucket, ok := l.buckets[client]
+	if !ok {
+		// Buckets that have refilled are the same as new ones
+		for key, idle := range l.buckets {
+			if idle.tokens+now.Sub(idle.last).Seconds()*l.rate >= l.burst {
+				delete(l.buckets, key)
+			}
+		}
+		bucket = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[client] = bucket
+	}
+	bucket.tokens = min(l.burst, bucket.tokens+now.Sub(bucket.last).Seconds()*l.rate)
+	bucket.last = now
+	if bucket.tokens < 1 {
+		return false, time.Duration((1 - bucket.tokens) / l.rate * float64(time.Second))
+	}
+	bucket.tokens--
+	return true, 0
+}
+
+// mcpGateway authenticates and rate limits MCP traffic before forwarding
+// it to the upstream server
+type mcpGateway struct {
+	proxy        *httputil.ReverseProxy
+	tokens       *tokenCache
+	limiter      *rateLimiter
+	protected    []string
+	forwardToken bool
+	maxBody      int64
+
+	requests, forwarded, unauthorized, limited atomic.Int64
+}
+
+func (g *mcpGateway) isProtected(path string) bool {
+	for _, prefix := range g.protected {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *mcpGateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	g.requests.Add(1)
+	for _, header := range gatewayIdentityHeaders {
+		r.Header.Del(header)
+	}
+	client, _, _ := net.SplitHostPort(r.RemoteAddr)
+	client = "ip:" + client
+
+	if g.isProtected(r.URL.Path) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			g.challenge(w, r, "")
+			return
+		}
+		info, err := g.tokens.lookup(token)
+		switch {
+		case errors.Is(err, errInvalidToken):
+			g.challenge(w, r, "invalid_token")
+			return
+		case err != nil:
+			slog.Warn("Token validation failed", "error", err)
+			http.Error(w, "gateway: token validation unavailable", http.StatusBadGateway)
+			return
+		}
+		client = "sub:" + info.Subject
+		r.Header.Set("X-Auth-Subject", info.Subject)
+		r.Header.Set("X-Auth-Scope", info.Scope)
+		if !g.forwardToken {
+			r.Header.Del("Authorization")
+		}
+	}
+
+	if ok, wait := g.limiter.allow(client); !ok {
+		g.limited.Add(1)
+		seconds := int(math.Ceil(wait.Seconds()))
+		slog.Info("Rate limited", "client", client, "method", r.Method, "path", r.URL.Path, "retry_after", seconds)
+		w.Header().Set("Retry-After", fmt.Sprint(seconds))
+		http.Error(w, "gateway: rate limit exceeded", http.StatusTooManyRequests)
+		return
+	}
+	if g.maxBody > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, g.maxBody)
+	}
+	g.forwarded.Add(1)
+	slog.Debug("Forwarding", "client", client, "method", r.Method, "path", r.URL.Path)
+	g.proxy.ServeHTTP(w, r)
+}
+
+// challenge answers 401 with the resource metadata clients use to find
+// the authorization server (RFC 9728)
+func (g *mcpGateway) challenge(w http.ResponseWriter, r *http.Request, reason string) {
+	g.unauthorized.Add(1)
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	value := fmt.Sprintf(`Bearer resource_metadata="%s://%s/.well-known/oauth-protected-resource"`, scheme, r.Host)
+	if reason != "" {
+		value += fmt.Sprintf(`, error="%s"`, reason)
+	}
+	slog.Info("Unauthorized", "method", r.Method, "path", r.URL.Path, "reason", cmp.Or(reason, "missing_token"))
+	w.Header().Set("WWW-Authenticate", value)
+	http.Error(w, "gateway: authorization required", http.StatusUnauthorized)
+}
+
+func newMCPGatewayCommand() *Command {
+	cmd := newCommand("gateway", "[upstream-url]", "Serve MCP traffic in front of the server: validate and cache OAuth tokens, rate limit per client, forward the rest.")
+	listen := cmd.Flags.String("listen", ":8080", "address to listen on")
+	protect := cmd.Flags.String("protect", "/sse,/messages,/mcp", "comma-separated path prefixes that need a bearer token")
+	userinfo := cmd.Flags.String("userinfo", "/oauth/userinfo", "upstream path or URL that validates tokens")
+	tokenTTL := cmd.Flags.Duration("token-ttl", 5*time.Minute, "how long a validated token is trusted before asking upstream again")
+	rate := cmd.Flags.Float64("rate", 5, "requests per second per client (token owner, or IP address without a token)")
+	burst := cmd.Flags.Int("burst", 20, "requests a client may make at once before the rate applies")
+	forwardToken := cmd.Flags.Bool("forward-token", false, "pass the Authorization header upstream instead of only X-Auth-Subject and X-Auth-Scope")
+	maxBody := cmd.Flags.Int64("max-body", 1<<20, "largest request body in bytes (0: unlimited)")
+	timeout := cmd.Flags.Duration("timeout", 10*time.Second, "timeout for token validation requests")
+
+	cmd.Run = func(args []string) error {
+		if len(args) > 1 {
+			return cmd.ExactArgs(args, 1)
+		}
+		upstream := defaultServerURL
+		if len(args) == 1 {
+			upstream = args[0]
+		}
+		target, err := url.Parse(upstream)
+		if err != nil || target.Host == "" {
+			return fmt.Errorf("%w: invalid upstream URL %q", errUsage, upstream)
+		}
+		if *rate <= 0 || *burst < 1 {
+			return fmt.Errorf("%w: --rate must be positive and --burst at least 1", errUsage)
+		}
+		endpoint := *userinfo
+		if !strings.Contains(endpoint, "://") {
+			endpoint = strings.TrimRight(upstream, "/") + endpoint
+		}
+
+		gateway := &mcpGateway{
+			tokens: &tokenCache{
+				validate: userinfoValidator(endpoint, *timeout),
+				ttl:      *tokenTTL,
+				entries:  make(map[[32]byte]tokenCacheEntry),
+			},
+			limiter:      &rateLimiter{rate: *rate, burst: float64(*burst), buckets: make(map[string]*tokenBucket)},
+			protected:    splitList(*protect),
+			forwardToken: *forwardToken,
+			maxBody:      *maxBody,
+		}
+		gateway.proxy = &httputil.ReverseProxy{
+			Rewrite: func(r *httputil.ProxyRequest) {
+				r.SetURL(target)
+				r.SetXForwarded()
+				r.Out.Host = target.Host
+			},
+			// Stream SSE events as they arrive
+			FlushInterval: -1,
+		}
+
+		listener, err := net.Listen("tcp", *listen)
+		if err != nil {
+			return fmt.Errorf("failed to listen: %w", err)
+		}
+		// No write timeout: SSE streams stay open for as long as the session
+		server := &http.Server{
+			Handler:           gateway,
+			ReadHeaderTimeout: 10 * time.Second,
+			IdleTimeout:       2 * time.Minute,
+			MaxHeaderBytes:    64 << 10,
+		}
+		ctx, stop := context.WithCancel(commandContext)
+		defer stop()
+		go func() {
+			<-ctx.Done()
+			server.Close()
+		}()
+
+		fmt.Printf("\n🛡️  Gateway http://%s → %s (Ctrl-C to stop)\n", listener.Addr(), upstream)
+		fmt.Printf("  tokens for %s validated by %s, trusted %s; %.4g req/s per client, burst %d\n\n",
+			strings.Join(gateway.protected, ", "), endpoint, *tokenTTL, *rate, *burst)
+		if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+
+		fmt.Printf("\n📊 %d request(s): %d forwarded, %d unauthorized, %d rate limited; token cache %d hit(s), %d miss(es)\n",
+			gateway.requests.Load(), gateway.forwarded.Load(), gateway.unauthorized.Load(), gateway.limited.Load(),
+			gateway.tokens.hits.Load(), gateway.tokens.misses.Load())
+		return nil
+	}
+	return cmd
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// isolationProbe is a tool call whose result is known from an idle session
+type isolationProbe struct {
+	query string
+	limit int
+}
+
+func (p isolationProbe) String() string {
+	return fmt.Sprintf("%q (limit %d)", p.query, p.limit)
+}
+
+// isolationProbes are the distinct calls the sessions interleave, so that
+// a result delivered to the wrong call can be told apart
+func isolationProbes() []isolationProbe {
+	var probes []isolationProbe
+	for limit := 1; limit <= 3; limit++ {
+		for _, query := range loadQueries {
+			probes = append(probes, isolationProbe{query, limit})
+		}
+	}
+	return probes
+}
+
+// Call states of an isolation session, by request id
+const (
+	callWaiting = iota + 1
+	callAnswered
+	callTimedOut
+)
+
+// isolationStats tallies the outcome of all sessions
+type isolationStats struct {
+	mu                          sync.Mutex
+	opened, unopened            int
+	calls, answered             int
+	errors, timeouts            int
+	foreign, duplicates         int
+	crossTalk, nondeterministic int
+	examples                    map[string]string // first example of each kind of problem
+}
+
+func (s *isolationStats) add(count *int, kind, format string, args ...any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	*count++
+	if _, ok := s.examples[kind]; !ok && format != "" {
+		s.examples[kind] = fmt.Sprintf(format, args...)
+	}
+}
+
+func newMCPIsolationTestCommand() *Command {
+	cmd := newCommand("isolation-test", "[url]", "Run concurrent sessions with interleaved tool calls and check every response reaches the session and call that asked.")
+	sessions := cmd.Flags.Int("sessions", 20, "number of concurrent MCP sessions")
+	calls := cmd.Flags.Int("calls", 30, "tool calls per session")
+	inflight := cmd.Flags.Int("inflight", 4, "tool calls in flight at once on each session")
+	tool := cmd.Flags.String("tool", smokeTool, "tool to call; it receives a query and a limit")
+	timeout := cmd.Flags.Duration("timeout", 30*time.Second, "timeout for connecting and for each call")
+	junit := cmd.Flags.String("junit", "", "write a JUnit XML report to this file")
+
+	cmd.Run = func(args []string) error {
+		if len(args) > 1 {
+			return cmd.ExactArgs(args, 1)
+		}
+		serverURL := defaultServerURL
+		if len(args) == 1 {
+			serverURL = args[0]
+		}
+		if *sessions < 2 || *calls < 1 || *inflight < 1 {
+			return fmt.Errorf("%w: --sessions must be at least 2, --calls and --inflight at least 1", errUsage)
+		}
+		tester := &reconnectTester{serverURL: serverURL, timeout: *timeout}
+		probes := isolationProbes()
+
+		fmt.Printf("\n🧪 %d session(s) × %d interleaved %s call(s) on %s, %d in flight per session\n", *sessions, *calls, *tool, serverURL, *inflight)
+		fmt.Printf("Recording the results of %d calls on an idle session...\n", len(probes))
+		baseline, err := isolationBaseline(tester, *tool, probes)
+		if err != nil {
+			return err
+		}
+
+		tester.begin("Session isolation")
+		stats := &isolationStats{examples: make(map[string]string)}
+		start := time.Now()
+		var wg sync.WaitGroup
+		for i := range *sessions {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				runIsolationSession(tester, i, *tool, probes, baseline, *calls, *inflight, stats)
+			}()
+		}
+		wg.Wait()
+		elapsed := time.Since(start)
+		recordIsolation(tester, stats, *sessions, elapsed)
+
+		failed, err := tester.report("mcp isolation-test "+serverURL, *junit)
+		if err != nil {
+			return err
+		}
+		if failed > 0 {
+			return fmt.Errorf("%w: %d of %d isolation check(s) failed", errPolicy, failed, len(tester.checks))
+		}
+		fmt.Println("\n✅ Every response reached the session and call that asked for it")
+		return nil
+	}
+	return cmd
+}
+
+// isolationBaseline calls every probe once on an idle session
+func isolationBaseline(t *reconnectTester, tool string, probes []isolationProbe) (map[isolationProbe]string, error) {
+	mcp, err := t.session(nil)
+	if err != nil {
+		return nil, err
+	}
+	defer mcp.Close()
+	baseline := make(map[isolationProbe]string)
+	for _, probe := range probes {
+		result, err := mcp.CallTool(commandContext, tool, map[string]any{"query": probe.query, "limit": probe.limit})
+		if err != nil {
+			return nil, fmt.Errorf("%s %s failed on an idle session: %s", tool, probe, describeMCPError(err))
+		}
+		if result.IsError {
+			return nil, fmt.Errorf("%s %s returned an error on an idle session: %s", tool, probe, truncate(result.Text(), 200))
+		}
+		baseline[probe] = result.Text()
+	}
+	return baseline, nil
+}
+
+// runIsolationSession sends calls with ids naming the session, several at
+// once, and checks each response against the probe's baseline. Another
+// session's id on this stream, or another probe's result, is leakage.
+func runIsolationSession(t *reconnectTester, index int, tool string, probes []isolationProbe, baseline map[isolationProbe]string, calls, inflight int, stats *isolationStats) {
+	mcp, err := t.session(nil)
+	if err != nil {
+		stats.add(&stats.unopened, "connect", "session %d: %v", index, err)
+		return
+	}
+	defer mcp.Close()
+	stats.add(&stats.opened, "", "")
+
+	prefix := fmt.Sprintf("s%d-", index)
+	var mu sync.Mutex
+	states := make(map[string]int)
+	waiting := make(map[string]chan *rpcResponse)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			var raw string
+			select {
+			case raw = <-mcp.Unmatched():
+			case <-done:
+				return
+			}
+			var response rpcResponse
+			var id string
+			if json.Unmarshal([]byte(raw), &response) != nil || len(response.ID) == 0 {
+				continue
+			}
+			if json.Unmarshal(response.ID, &id) != nil || !strings.HasPrefix(id, prefix) {
+				stats.add(&stats.foreign, "foreign", "session %d received the response to %s", index, response.ID)
+				continue
+			}
+			mu.Lock()
+			switch states[id] {
+			case callWaiting:
+				states[id] = callAnswered
+				waiting[id] <- &response
+				delete(waiting, id)
+			case callAnswered:
+				stats.add(&stats.duplicates, "duplicate", "session %d received %s twice", index, response.ID)
+			case callTimedOut:
+				// Late, and already counted as a timeout
+			default:
+				stats.add(&stats.foreign, "foreign", "session %d received %s, which it never sent", index, response.ID)
+			}
+			mu.Unlock()
+		}
+	}()
+
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for range inflight {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := range work {
+				// Offset by session so concurrent sessions ask different things
+				probe := probes[(index*7+n)%len(probes)]
+				id := fmt.Sprintf("%s%d", prefix, n)
+				body, _ := json.Marshal(map[string]any{
+					"jsonrpc": "2.0", "id": id, "method": "tools/call",
+					"params": map[string]any{"name": tool, "arguments": map[string]any{"query": probe.query, "limit": probe.limit}},
+				})
+				ch := make(chan *rpcResponse, 1)
+				mu.Lock()
+				states[id], waiting[id] = callWaiting, ch
+				mu.Unlock()
+				stats.add(&stats.calls, "", "")
+
+				status, _, err := mcp.PostRaw(commandContext, body)
+				if err != nil || status/100 != 2 {
+					mu.Lock()
+					states[id] = callTimedOut
+					delete(waiting, id)
+					mu.Unlock()
+					if err != nil {
+						stats.add(&stats.errors, "post", "session %d: %s", index, describeMCPError(err))
+					} else {
+						stats.add(&stats.errors, "post", "session %d: POST %s answered %d", index, id, status)
+					}
+					continue
+				}
+				var response *rpcResponse
+				select {
+				case response = <-ch:
+				case <-time.After(t.timeout):
+					mu.Lock()
+					if states[id] == callWaiting {
+						states[id] = callTimedOut
+						delete(waiting, id)
+					}
+					mu.Unlock()
+					// The reader may have answered in the meantime
+					select {
+					case response = <-ch:
+					default:
+						stats.add(&stats.timeouts, "", "")
+						continue
+					}
+				}
+				checkIsolatedResult(index, probe, response, probes, baseline, stats)
+			}
+		}()
+	}
+	for n := range calls {
+		work <- n
+	}
+	close(work)
+	wg.Wait()
+}
+
+// checkIsolatedResult compares a result with the baseline of its probe and,
+// when it differs, with those of the other probes
+func checkIsolatedResult(index int, probe isolationProbe, response *rpcResponse, probes []isolationProbe, baseline map[isolationProbe]string, stats *isolationStats) {
+	if response.Error != nil {
+		stats.add(&stats.errors, "rpc", "session %d: %s", index, response.Error.Error())
+		return
+	}
+	var result MCPToolResult
+	if err := json.Unmarshal(response.Result, &result); err != nil || result.IsError {
+		stats.add(&stats.errors, "result", "session %d: %s returned %s", index, probe, truncate(string(response.Result), 100))
+		return
+	}
+	stats.add(&stats.answered, "", "")
+	text := result.Text()
+	if text == baseline[probe] {
+		return
+	}
+	for _, other := range probes {
+		if other != probe && baseline[other] != baseline[probe] && text == baseline[other] {
+			stats.add(&stats.crossTalk, "cross-talk", "session %d asked %s and got the result of %s", index, probe, other)
+			return
+		}
+	}
+	stats.add(&stats.nondeterministic, "nondeterministic", "session %d: %s differs from the idle result", index, probe)
+}
+
+func recordIsolation(t *reconnectTester, stats *isolationStats, sessions int, elapsed time.Duration) {
+	example := func(kind string) string {
+		return truncate(stats.examples[kind], 100)
+	}
+	if stats.opened < sessions {
+		t.record("sessions opened", oauthFail, "%d of %d, e.g. %s", stats.opened, sessions, example("connect"))
+	} else {
+		t.record("sessions opened", oauthPass, "%d concurrent", sessions)
+	}
+
+	if stats.foreign > 0 {
+		t.record("ids stay in their session", oauthFail, "%d response(s) on the wrong stream, e.g. %s", stats.foreign, example("foreign"))
+	} else {
+		t.record("ids stay in their session", oauthPass, "every response arrived on the stream of the session that sent it")
+	}
+
+	if stats.duplicates > 0 {
+		t.record("one response per call", oauthFail, "%d duplicate(s), e.g. %s", stats.duplicates, example("duplicate"))
+	} else {
+		t.record("one response per call", oauthPass, "no duplicates")
+	}
+
+	switch {
+	case stats.crossTalk > 0:
+		t.record("results match their call", oauthFail, "%d result(s) of another call, e.g. %s", stats.crossTalk, example("cross-talk"))
+	case stats.nondeterministic > 0:
+		t.record("results match their call", oauthWarn, "%d of %d result(s) differ from the idle session but match no other call; results are not deterministic, e.g. %s",
+			stats.nondeterministic, stats.answered, example("nondeterministic"))
+	default:
+		t.record("results match their call", oauthPass, "all %d result(s) identical to the idle session", stats.answered)
+	}
+
+	rate := float64(stats.calls) / elapsed.Seconds()
+	if lost := stats.errors + stats.timeouts; lost > 0 {
+		detail := fmt.Sprintf("%d of %d call(s) failed or timed out in %s (%.1f/s)", lost, stats.calls, elapsed.Round(time.Millisecond), rate)
+		for _, kind := range []string{"post", "rpc", "result"} {
+			if stats.examples[kind] != "" {
+				detail += ", e.g. " + example(kind)
+				break
+			}
+		}
+		t.record("every call answered", oauthWarn, "%s", detail)
+	} else {
+		t.record("every call answered", oauthPass, "%d call(s) in %s (%.1f/s)", stats.calls, elapsed.Round(time.Millisecond), rate)
+	}
+}
@@ -0,0 +1,359 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+// capabilityMethods are the list methods each server capability enables
+var capabilityMethods = map[string]string{
+	"tools":     "tools/list",
+	"prompts":   "prompts/list",
+	"resources": "resources/list",
+}
+
+func newMCPLifecycleTestCommand() *Command {
+	cmd := newCommand("lifecycle-test", "[url]", "Walk sessions through initialize, renegotiation, cancellation and teardown and check the server's answers.")
+	cycles := cmd.Flags.Int("cycles", 5, "sessions to tear down with a tool call in flight")
+	timeout := cmd.Flags.Duration("timeout", 10*time.Second, "timeout for connecting and for each response")
+	junit := cmd.Flags.String("junit", "", "write a JUnit XML report to this file")
+
+	cmd.Run = func(args []string) error {
+		if len(args) > 1 {
+			return cmd.ExactArgs(args, 1)
+		}
+		serverURL := defaultServerURL
+		if len(args) == 1 {
+			serverURL = args[0]
+		}
+		tester := &reconnectTester{serverURL: serverURL, timeout: *timeout}
+
+		fmt.Printf("\n♻️  MCP session lifecycle of %s\n", serverURL)
+		capabilities, err := lifecycleInitialize(tester)
+		if err != nil {
+			return err
+		}
+		lifecycleRenegotiate(tester, capabilities)
+		lifecycleCancel(tester)
+		lifecycleTeardown(tester, *cycles)
+
+		failed, err := tester.report("mcp lifecycle-test "+serverURL, *junit)
+		if err != nil {
+			return err
+		}
+		if failed > 0 {
+			return fmt.Errorf("%w: %d of %d lifecycle check(s) failed", errPolicy, failed, len(tester.checks))
+		}
+		fmt.Println("\n✅ Sessions initialize, renegotiate, cancel and shut down cleanly")
+		return nil
+	}
+	return cmd
+}
+
+// rawSession opens a stream without initializing it
+func rawSession(t *reconnectTester) (*conformanceRunner, error) {
+	mcp, err := connectMCP(t.serverURL, t.timeout)
+	if err != nil {
+		return nil, err
+	}
+	return &conformanceRunner{mcp: mcp, timeout: t.timeout}, nil
+}
+
+// initializeRaw sends initialize with the given version and capabilities
+func (r *conformanceRunner) initializeRaw(id int, version string, capabilities map[string]any) (*rpcResponse, error) {
+	body, _ := json.Marshal(map[string]any{
+		"jsonrpc": "2.0", "id": id, "method": "initialize",
+		"params": map[string]any{
+			"protocolVersion": version,
+			"capabilities":    capabilities,
+			"clientInfo":      map[string]string{"name": "strunzctl", "version": "1.0"},
+		},
+	})
+	if err := r.mcp.Send(commandContext, body); err != nil {
+		return nil, err
+	}
+	return r.awaitID(fmt.Sprint(id)), nil
+}
+
+// quiet returns a response that arrives within a second, which after a
+// notification can only be an error about it
+func (r *conformanceRunner) quiet() string {
+	return r.await(func(raw string) bool {
+		var response rpcResponse
+		return json.Unmarshal([]byte(raw), &response) == nil && (response.Error != nil || len(response.Result) > 0)
+	}, time.Second)
+}
+
+// lifecycleInitialize runs the handshake step by step and returns the
+// server capabilities. Only an unreachable server is an error.
+func lifecycleInitialize(t *reconnectTester) (map[string]json.RawMessage, error) {
+	t.begin("Initialization")
+	r, err := rawSession(t)
+	if err != nil {
+		return nil, err
+	}
+	defer r.mcp.Close()
+
+	response, err := r.initializeRaw(1, "2025-06-18", map[string]any{"roots": map[string]any{"listChanged": true}})
+	var result MCPInitializeResult
+	var capabilities map[string]json.RawMessage
+	switch {
+	case err != nil:
+		t.record("initialize", oauthFail, "%v", err)
+		return nil, nil
+	case response == nil:
+		t.record("initialize", oauthFail, "no response within %s", t.timeout)
+		return nil, nil
+	case response.Error != nil:
+		t.record("initialize", oauthFail, "%s", describeMCPError(response.Error))
+		return nil, nil
+	case json.Unmarshal(response.Result, &result) != nil || json.Unmarshal(result.Capabilities, &capabilities) != nil:
+		t.record("initialize", oauthFail, "result is not an InitializeResult: %s", truncate(string(response.Result), 60))
+		return nil, nil
+	case !slices.Contains(supportedProtocolVersions, result.ProtocolVersion):
+		t.record("initialize", oauthFail, "unknown protocolVersion %q", result.ProtocolVersion)
+	case result.ServerInfo.Name == "":
+		t.record("initialize", oauthWarn, "protocol %s, but serverInfo has no name", result.ProtocolVersion)
+	default:
+		t.record("initialize", oauthPass, "protocol %s, %s %s", result.ProtocolVersion, result.ServerInfo.Name, result.ServerInfo.Version)
+	}
+
+	status, body, err := r.send(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+	switch {
+	case err != nil:
+		t.record("notifications/initialized", oauthFail, "%v", err)
+	case status/100 != 2:
+		t.record("notifications/initialized", oauthFail, "rejected with %d %s", status, truncate(body, 40))
+	default:
+		if raw := r.quiet(); raw != "" {
+			t.record("notifications/initialized", oauthFail, "answered: %s", truncate(raw, 60))
+		} else {
+			t.record("notifications/initialized", oauthPass, "accepted without a response")
+		}
+	}
+
+	// Advertised capabilities must work, others should be method not found
+	var working, missing, extra []string
+	for _, capability := range slices.Sorted(maps.Keys(capabilityMethods)) {
+		method := capabilityMethods[capability]
+		_, advertised := capabilities[capability]
+		var reply json.RawMessage
+		err := r.mcp.Request(commandContext, method, map[string]any{}, &reply)
+		var rpcErr *MCPError
+		switch {
+		case advertised && err == nil:
+			working = append(working, capability)
+		case advertised:
+			missing = append(missing, fmt.Sprintf("%s (%s)", method, describeMCPError(err)))
+		case err == nil:
+			extra = append(extra, method)
+		case !errors.As(err, &rpcErr) || rpcErr.Code != rpcMethodNotFound:
+			extra = append(extra, fmt.Sprintf("%s (%s)", method, describeMCPError(err)))
+		}
+	}
+	switch {
+	case len(missing) > 0:
+		t.record("capabilities honored", oauthFail, "advertised but failing: %s", strings.Join(missing, ", "))
+	case len(extra) > 0:
+		t.record("capabilities honored", oauthWarn, "not advertised but not method not found: %s", strings.Join(extra, ", "))
+	default:
+		t.record("capabilities honored", oauthPass, "%s answer, the rest are method not found", orNone(strings.Join(working, ", ")))
+	}
+	return capabilities, nil
+}
+
+// lifecycleRenegotiate initializes again on a live session and on new
+// sessions with another version and other client capabilities
+func lifecycleRenegotiate(t *reconnectTester, capabilities map[string]json.RawMessage) {
+	t.begin("Renegotiation")
+	r, err := rawSession(t)
+	if err != nil {
+		t.record("second initialize", oauthFail, "%v", err)
+		return
+	}
+	defer r.mcp.Close()
+	if _, err := r.initializeRaw(11, "2025-06-18", map[string]any{}); err != nil {
+		t.record("second initialize", oauthFail, "%v", err)
+		return
+	}
+	r.send(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+
+	response, err := r.initializeRaw(12, "2025-06-18", map[string]any{"sampling": map[string]any{}})
+	switch {
+	case err != nil:
+		t.record("second initialize", oauthFail, "%v", err)
+	case response == nil:
+		t.record("second initialize", oauthFail, "no response; clients hang instead of learning they must reconnect")
+	case response.Error != nil:
+		t.record("second initialize", oauthPass, "rejected: %s", describeMCPError(response.Error))
+	default:
+		t.record("second initialize", oauthWarn, "answered again; a session is initialized once, renegotiating needs a new one")
+	}
+	if _, err := r.mcp.ListTools(commandContext); err != nil {
+		t.record("session after second initialize", oauthFail, "%s", describeMCPError(err))
+	} else {
+		t.record("session after second initialize", oauthPass, "tools/list still works")
+	}
+
+	older, err := rawSession(t)
+	if err != nil {
+		t.record("older protocol version", oauthFail, "%v", err)
+		return
+	}
+	defer older.mcp.Close()
+	response, err = older.initializeRaw(13, supportedProtocolVersions[0], map[string]any{})
+	var result MCPInitializeResult
+	switch {
+	case err != nil:
+		t.record("older protocol version", oauthFail, "%v", err)
+	case response == nil || response.Error != nil || json.Unmarshal(response.Result, &result) != nil:
+		t.record("older protocol version", oauthFail, "initialize with %s failed", supportedProtocolVersions[0])
+	case result.ProtocolVersion != supportedProtocolVersions[0]:
+		t.record("older protocol version", oauthWarn, "offered %s, got %s; clients on the older version disconnect", supportedProtocolVersions[0], result.ProtocolVersion)
+	default:
+		t.record("older protocol version", oauthPass, "%s accepted", result.ProtocolVersion)
+	}
+
+	// The same server capabilities whatever the client declares
+	var declared MCPInitializeResult
+	rich, err := rawSession(t)
+	if err != nil {
+		t.record("other client capabilities", oauthFail, "%v", err)
+		return
+	}
+	defer rich.mcp.Close()
+	clientCapabilities := map[string]any{"roots": map[string]any{"listChanged": true}, "sampling": map[string]any{}, "elicitation": map[string]any{}}
+	response, err = rich.initializeRaw(14, "2025-06-18", clientCapabilities)
+	var got map[string]json.RawMessage
+	switch {
+	case err != nil:
+		t.record("other client capabilities", oauthFail, "%v", err)
+	case response == nil || response.Error != nil || json.Unmarshal(response.Result, &declared) != nil || json.Unmarshal(declared.Capabilities, &got) != nil:
+		t.record("other client capabilities", oauthFail, "initialize declaring roots, sampling and elicitation failed")
+	case !slices.Equal(slices.Sorted(maps.Keys(got)), slices.Sorted(maps.Keys(capabilities))):
+		t.record("other client capabilities", oauthPass, "server adapted its capabilities: %s", strings.Join(slices.Sorted(maps.Keys(got)), ", "))
+	default:
+		t.record("other client capabilities", oauthPass, "roots, sampling and elicitation declared, same server capabilities")
+	}
+}
+
+// lifecycleCancel cancels an in-flight call and an unknown request
+func lifecycleCancel(t *reconnectTester) {
+	t.begin("Cancellation")
+	mcp, err := t.session(nil)
+	if err != nil {
+		t.record("cancel in-flight call", oauthFail, "%v", err)
+		return
+	}
+	defer mcp.Close()
+	r := &conformanceRunner{mcp: mcp, timeout: t.timeout}
+
+	call, _ := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": 31, "method": "tools/call",
+		"params": map[string]any{"name": smokeTool, "arguments": cannedToolArguments[smokeTool]}})
+	if _, _, err := r.send(string(call)); err != nil {
+		t.record("cancel in-flight call", oauthFail, "%v", err)
+		return
+	}
+	status, body, err := r.send(`{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":31,"reason":"strunzctl lifecycle-test"}}`)
+	switch {
+	case err != nil:
+		t.record("cancel in-flight call", oauthFail, "%v", err)
+	case status/100 != 2:
+		t.record("cancel in-flight call", oauthFail, "notifications/cancelled rejected with %d %s", status, truncate(body, 40))
+	default:
+		response := r.awaitID("31")
+		switch {
+		case response == nil:
+			t.record("cancel in-flight call", oauthPass, "no response after cancelling")
+		case response.Error != nil:
+			t.record("cancel in-flight call", oauthPass, "answered with an error: %s", describeMCPError(response.Error))
+		default:
+			t.record("cancel in-flight call", oauthWarn, "answered anyway; fine if the call finished before the cancellation arrived")
+		}
+	}
+
+	status, body, err = r.send(`{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":987654,"reason":"strunzctl lifecycle-test"}}`)
+	switch {
+	case err != nil:
+		t.record("cancel unknown request", oauthFail, "%v", err)
+	case status/100 != 2:
+		t.record("cancel unknown request", oauthWarn, "rejected with %d %s; unknown ids should be ignored", status, truncate(body, 40))
+	default:
+		if raw := r.quiet(); raw != "" {
+			t.record("cancel unknown request", oauthFail, "answered: %s", truncate(raw, 60))
+		} else {
+			t.record("cancel unknown request", oauthPass, "ignored")
+		}
+	}
+
+	if _, err := mcp.ListTools(commandContext); err != nil || mcp.Disconnected() {
+		t.record("session after cancellations", oauthFail, "%s", describeMCPError(err))
+	} else {
+		t.record("session after cancellations", oauthPass, "tools/list still works")
+	}
+}
+
+// lifecycleTeardown closes sessions with a call in flight and checks the
+// server stays healthy and accepts new sessions
+func lifecycleTeardown(t *reconnectTester, cycles int) {
+	t.begin("Shutdown")
+	if cycles > 0 {
+		closed := 0
+		for range cycles {
+			mcp, err := t.session(nil)
+			if err != nil {
+				t.record("close with call in flight", oauthFail, "session %d: %v", closed+1, err)
+				return
+			}
+			go mcp.CallTool(commandContext, smokeTool, cannedToolArguments[smokeTool])
+			time.Sleep(50 * time.Millisecond)
+			mcp.Close()
+			<-mcp.Done()
+			closed++
+		}
+		t.record("close with call in flight", oauthPass, "%d session(s) closed mid-call", closed)
+	}
+
+	mcp, err := t.session(nil)
+	if err == nil {
+		defer mcp.Close()
+		_, err = mcp.ListTools(commandContext)
+	}
+	if err != nil {
+		t.record("new session after teardown", oauthFail, "%s", describeMCPError(err))
+	} else {
+		t.record("new session after teardown", oauthPass, "initialize and tools/list work")
+	}
+
+	health, err := fetchHealth(t.serverURL)
+	switch {
+	case err != nil:
+		t.record("health after teardown", oauthFail, "%v", err)
+	case health.Status != "ok" && health.Status != "healthy":
+		t.record("health after teardown", oauthFail, "status is %q", health.Status)
+	default:
+		t.record("health after teardown", oauthPass, "status %s", health.Status)
+	}
+
+	// Closing a session is the client's shutdown on SSE; the server must
+	// notice rather than keep writing into the dead stream
+	if mcp != nil {
+		mcp.Close()
+		<-mcp.Done()
+		time.Sleep(time.Second)
+		status, _, err := mcp.PostRaw(commandContext, []byte(`{"jsonrpc":"2.0","id":41,"method":"ping"}`))
+		switch {
+		case err != nil:
+			t.record("closed session released", oauthFail, "%v", err)
+		case status/100 == 2:
+			t.record("closed session released", oauthFail, "message to the closed session accepted with %d", status)
+		default:
+			t.record("closed session released", oauthPass, "messages to it are rejected with %d", status)
+		}
+	}
+}
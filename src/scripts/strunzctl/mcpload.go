@@ -0,0 +1,212 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// loadQueries rotate through the tool calls of a load test so the server
+// cannot answer everything from one cached embedding
+var loadQueries = []string{"Vitamin D", "Magnesium Mangel", "Omega-3 Dosierung", "Eiweiß Bedarf", "Schlaf verbessern", "Laufen Anfänger"}
+
+// loadStats aggregates the outcome of a load test across all clients
+type loadStats struct {
+	calls, failures, disconnects, connectFailures atomic.Int64
+	active                                        atomic.Int64
+
+	mu        sync.Mutex
+	latencies []time.Duration
+	errors    map[string]int
+}
+
+func (s *loadStats) recordCall(latency time.Duration, err error) {
+	s.calls.Add(1)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		s.failures.Add(1)
+		s.errors[errorClass(err)]++
+		return
+	}
+	s.latencies = append(s.latencies, latency)
+}
+
+func newMCPLoadCommand() *Command {
+	cmd := newCommand("load", "[url]", "Open concurrent MCP sessions and call a tool continuously, reporting throughput, errors and SSE disconnects.")
+	clients := cmd.Flags.Int("clients", 10, "number of concurrent MCP sessions")
+	duration := cmd.Flags.Duration("duration", time.Minute, "how long to generate load")
+	rampUp := cmd.Flags.Duration("ramp-up", 10*time.Second, "time over which the clients are started")
+	tool := cmd.Flags.String("tool", smokeTool, "tool to call; it receives a rotating query and limit 3")
+	think := cmd.Flags.Duration("think", 0, "pause between the calls of a client")
+	timeout := cmd.Flags.Duration("timeout", 60*time.Second, "timeout for connecting and for each call")
+	maxErrorRate := cmd.Flags.Float64("max-error-rate", 0.01, "error rate above which the run fails")
+
+	cmd.Run = func(args []string) error {
+		if len(args) > 1 {
+			return cmd.ExactArgs(args, 1)
+		}
+		if *clients < 1 {
+			return fmt.Errorf("%w: --clients must be at least 1", errUsage)
+		}
+		serverURL := defaultServerURL
+		if len(args) == 1 {
+			serverURL = args[0]
+		}
+
+		stats := &loadStats{errors: make(map[string]int)}
+		start := time.Now()
+		deadline := start.Add(*duration)
+		fmt.Printf("\n🏋️  %d client(s) calling %s on %s for %s\n\n", *clients, *tool, serverURL, *duration)
+
+		var wg sync.WaitGroup
+		for i := range *clients {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if sleepContext(*rampUp*time.Duration(i)/time.Duration(*clients)) != nil {
+					return
+				}
+				runLoadClient(serverURL, *tool, i, deadline, *think, *timeout, stats)
+			}()
+		}
+
+		stop := make(chan struct{})
+		go func() {
+			ticker := time.NewTicker(10 * time.Second)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					fmt.Printf("  %6s  %3d active  %6d calls  %4d errors  %3d disconnects\n",
+						time.Since(start).Round(time.Second), stats.active.Load(), stats.calls.Load(), stats.failures.Load(), stats.disconnects.Load())
+				case <-stop:
+					return
+				}
+			}
+		}()
+		wg.Wait()
+		close(stop)
+
+		return printLoadReport(stats, time.Since(start), *maxErrorRate)
+	}
+	return cmd
+}
+
+// runLoadClient keeps one session busy until the deadline, reconnecting
+// when the SSE stream drops. An interrupt ends the run early, and the
+// report covers the calls made so far.
+func runLoadClient(serverURL, tool string, client int, deadline time.Time, think, timeout time.Duration, stats *loadStats) {
+	var mcp *MCPClient
+	defer func() {
+		if mcp != nil {
+			mcp.Close()
+		}
+	}()
+
+	for n := client; time.Now().Before(deadline) && commandContext.Err() == nil; n++ {
+		if mcp == nil {
+			var err error
+			if mcp, err = connectMCP(serverURL, timeout); err == nil {
+				if _, err = mcp.Initialize(commandContext); err != nil {
+					mcp.Close()
+					mcp = nil
+				}
+			}
+			if err != nil {
+				stats.connectFailures.Add(1)
+				stats.mu.Lock()
+				stats.errors["connect: "+errorClass(err)]++
+				stats.mu.Unlock()
+				sleepContext(time.Second)
+				continue
+			}
+			stats.active.Add(1)
+		}
+
+		start := time.Now()
+		_, err := callToolChecked(mcp, tool, map[string]any{"query": loadQueries[n%len(loadQueries)], "limit": 3})
+		stats.recordCall(time.Since(start), err)
+		if mcp.Disconnected() {
+			stats.disconnects.Add(1)
+			stats.active.Add(-1)
+			mcp.Close()
+			mcp = nil
+			continue
+		}
+		if think > 0 {
+			sleepContext(think)
+		}
+	}
+	if mcp != nil {
+		stats.active.Add(-1)
+	}
+}
+
+func printLoadReport(stats *loadStats, elapsed time.Duration, maxErrorRate float64) error {
+	calls := stats.calls.Load()
+	failures := stats.failures.Load()
+	slices.Sort(stats.latencies)
+
+	fmt.Printf("\n📊 Load test results after %s\n", elapsed.Round(time.Second))
+	fmt.Printf("Calls:            %d (%.1f/s)\n", calls, float64(calls)/elapsed.Seconds())
+	fmt.Printf("Succeeded:        %d\n", calls-failures)
+	errorRate := 0.0
+	if calls > 0 {
+		errorRate = float64(failures) / float64(calls)
+	}
+	fmt.Printf("Failed:           %d (%.2f%%)\n", failures, errorRate*100)
+	fmt.Printf("SSE disconnects:  %d\n", stats.disconnects.Load())
+	fmt.Printf("Connect failures: %d\n", stats.connectFailures.Load())
+	if len(stats.latencies) > 0 {
+		fmt.Printf("Latency:          p50 %s  p95 %s  p99 %s  max %s\n",
+			percentile(stats.latencies, 50).Round(time.Millisecond), percentile(stats.latencies, 95).Round(time.Millisecond),
+			percentile(stats.latencies, 99).Round(time.Millisecond),
+			stats.latencies[len(stats.latencies)-1].Round(time.Millisecond))
+	}
+
+	if len(stats.errors) > 0 {
+		type errorCount struct {
+			class string
+			count int
+		}
+		var counts []errorCount
+		for class, count := range stats.errors {
+			counts = append(counts, errorCount{class, count})
+		}
+		slices.SortFunc(counts, func(a, b errorCount) int { return cmp.Compare(b.count, a.count) })
+		fmt.Println("\nErrors:")
+		for _, c := range counts {
+			fmt.Printf("  %6d  %s\n", c.count, c.class)
+		}
+	}
+
+	if calls == 0 {
+		return fmt.Errorf("%w: no tool call completed", errPolicy)
+	}
+	if errorRate > maxErrorRate {
+		return fmt.Errorf("%w: error rate %.2f%% exceeds %.2f%%", errPolicy, errorRate*100, maxErrorRate*100)
+	}
+	fmt.Println("\n✅ Error rate within budget")
+	return nil
+}
+
+// percentile returns the nearest-rank p-th percentile of sorted latencies
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[max(0, min(rank-1, len(sorted)-1))]
+}
+
+// errorClass shortens an error to its first line for grouping
+func errorClass(err error) string {
+	message, _, _ := strings.Cut(err.Error(), "\n")
+	return truncate(message, 100)
+}
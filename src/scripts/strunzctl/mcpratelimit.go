@@ -0,0 +1,275 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// floodOutcome tallies what the server answered to a burst of requests
+type floodOutcome struct {
+	mu          sync.Mutex
+	sent        int
+	ok          int
+	throttled   int
+	firstLimit  time.Duration // since the flood started, 0 if never throttled
+	retryAfter  []time.Duration
+	badHeaders  []string // Retry-After values that are missing or unparseable
+	rpcLimits   int      // rate limits reported as JSON-RPC errors
+	otherErrors map[string]int
+}
+
+func (o *floodOutcome) record(err error, elapsed time.Duration) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var status *HTTPStatusError
+	var rpcErr *MCPError
+	switch {
+	case err == nil:
+		o.ok++
+	case errors.As(err, &status) && status.Status == http.StatusTooManyRequests:
+		o.throttled++
+		if o.firstLimit == 0 {
+			o.firstLimit = elapsed
+		}
+		if wait, ok := parseRetryAfter(status.Header.Get("Retry-After")); ok {
+			o.retryAfter = append(o.retryAfter, wait)
+		} else {
+			o.badHeaders = append(o.badHeaders, status.Header.Get("Retry-After"))
+		}
+	case errors.As(err, &rpcErr) && strings.Contains(strings.ToLower(rpcErr.Message), "rate"):
+		o.rpcLimits++
+	case errors.As(err, &status):
+		o.otherErrors[fmt.Sprintf("%d %s", status.Status, http.StatusText(status.Status))]++
+	default:
+		o.otherErrors[errorClass(err)]++
+	}
+}
+
+// parseRetryAfter reads delay-seconds or an HTTP date (RFC 9110 10.2.3)
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if seconds, err := strconv.Atoi(strings.TrimSpace(value)); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(0, time.Until(at)), true
+	}
+	return 0, false
+}
+
+func newMCPRateLimitTestCommand() *Command {
+	cmd := newCommand("rate-limit-test", "[url]", "Exceed the server's rate limits and check for 429s with Retry-After that leave the MCP session intact.")
+	requests := cmd.Flags.Int("requests", 300, "most tool calls to send while trying to hit the limit")
+	concurrency := cmd.Flags.Int("concurrency", 10, "tool calls in flight at once")
+	connects := cmd.Flags.Int("connects", 30, "SSE streams to open in quick succession")
+	maxRetryAfter := cmd.Flags.Duration("max-retry-after", 2*time.Minute, "longest Retry-After considered reasonable, and waited for")
+	token := cmd.Flags.String("token", "", "bearer token to send, for limits that apply per client")
+	timeout := cmd.Flags.Duration("timeout", 30*time.Second, "timeout for connecting and for each request")
+	junit := cmd.Flags.String("junit", "", "write a JUnit XML report to this file")
+
+	cmd.Run = func(args []string) error {
+		if len(args) > 1 {
+			return cmd.ExactArgs(args, 1)
+		}
+		serverURL := defaultServerURL
+		if len(args) == 1 {
+			serverURL = args[0]
+		}
+		if *requests < 1 || *concurrency < 1 {
+			return fmt.Errorf("%w: --requests and --concurrency must be at least 1", errUsage)
+		}
+		var header http.Header
+		if *token != "" {
+			header = http.Header{"Authorization": {"Bearer " + *token}}
+		}
+		tester := &reconnectTester{serverURL: serverURL, timeout: *timeout}
+
+		fmt.Printf("\n🚦 Rate limits of %s (up to %d tool calls, %d at once)\n", serverURL, *requests, *concurrency)
+		if err := floodSession(tester, header, *requests, *concurrency, *maxRetryAfter); err != nil {
+			return err
+		}
+		floodConnects(tester, header, *connects, *maxRetryAfter)
+
+		failed, err := tester.report("mcp rate-limit-test "+serverURL, *junit)
+		if err != nil {
+			return err
+		}
+		if failed > 0 {
+			return fmt.Errorf("%w: %d of %d rate limit check(s) failed", errPolicy, failed, len(tester.checks))
+		}
+		fmt.Println("\n✅ Throttling answers 429 with Retry-After and keeps sessions alive")
+		return nil
+	}
+	return cmd
+}
+
+// floodSession sends tool calls on one session until the server throttles
+// them, then checks the session survives and recovers after Retry-After.
+// Only an unreachable server is an error.
+func floodSession(t *reconnectTester, header http.Header, requests, concurrency int, maxRetryAfter time.Duration) error {
+	t.begin("Tool call flood")
+	mcp, err := t.session(header)
+	if err != nil {
+		return err
+	}
+	defer mcp.Close()
+
+	outcome := &floodOutcome{otherErrors: make(map[string]int)}
+	start := time.Now()
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := range work {
+				_, err := mcp.CallTool(commandContext, smokeTool, map[string]any{"query": loadQueries[n%len(loadQueries)], "limit": 1})
+				outcome.record(err, time.Since(start))
+			}
+		}()
+	}
+	// Enough 429s to see consistent Retry-After values; more only adds load
+	for n := 0; n < requests && !mcp.Disconnected(); n++ {
+		outcome.mu.Lock()
+		enough := outcome.throttled >= 3*concurrency
+		outcome.mu.Unlock()
+		if enough {
+			break
+		}
+		work <- n
+		outcome.mu.Lock()
+		outcome.sent++
+		outcome.mu.Unlock()
+	}
+	close(work)
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	switch {
+	case outcome.throttled > 0:
+		t.record("throttled with 429", oauthPass, "first 429 after %s; %d of %d call(s) throttled, %d answered",
+			outcome.firstLimit.Round(time.Millisecond), outcome.throttled, outcome.sent, outcome.ok)
+	case outcome.rpcLimits > 0:
+		t.record("throttled with 429", oauthWarn, "%d call(s) rejected with a JSON-RPC rate limit error instead; clients get no Retry-After", outcome.rpcLimits)
+	default:
+		t.record("throttled with 429", oauthFail, "no 429 in %d call(s) over %s; no limit, or one above --requests", outcome.sent, elapsed.Round(time.Millisecond))
+	}
+
+	if len(outcome.otherErrors) > 0 {
+		var errs []string
+		for _, class := range slices.Sorted(maps.Keys(outcome.otherErrors)) {
+			errs = append(errs, fmt.Sprintf("%d× %s", outcome.otherErrors[class], class))
+		}
+		t.record("no other errors", oauthFail, "%s", strings.Join(errs, ", "))
+	} else {
+		t.record("no other errors", oauthPass, "every call was answered or throttled")
+	}
+
+	var longest time.Duration
+	if outcome.throttled > 0 {
+		longest = slices.Max(append(outcome.retryAfter, 0))
+		switch {
+		case len(outcome.badHeaders) > 0:
+			t.record("Retry-After", oauthFail, "%d of %d 429(s) without a valid Retry-After (e.g. %q)",
+				len(outcome.badHeaders), outcome.throttled, outcome.badHeaders[0])
+		case longest > maxRetryAfter:
+			t.record("Retry-After", oauthWarn, "up to %s, longer than --max-retry-after %s", longest, maxRetryAfter)
+		default:
+			t.record("Retry-After", oauthPass, "%s to %s", slices.Min(outcome.retryAfter), longest)
+		}
+	}
+
+	if mcp.Disconnected() {
+		t.record("session kept", oauthFail, "the SSE stream closed while throttled: %v", mcp.Err())
+		return nil
+	}
+	t.record("session kept", oauthPass, "the SSE stream stayed open through the flood")
+
+	if outcome.throttled == 0 {
+		return nil
+	}
+	if longest > maxRetryAfter {
+		t.record("recovers after Retry-After", oauthSkip, "would wait %s", longest)
+		return nil
+	}
+	// Whole-second Retry-After values round down, so allow one more
+	if err := sleepContext(longest + time.Second); err != nil {
+		return err
+	}
+	t.started = time.Now()
+	if _, err := mcp.CallTool(commandContext, smokeTool, map[string]any{"query": loadQueries[0], "limit": 1}); err != nil {
+		t.record("recovers after Retry-After", oauthFail, "same session after waiting %s: %s", longest+time.Second, describeMCPError(err))
+	} else {
+		t.record("recovers after Retry-After", oauthPass, "same session answers after waiting %s", longest+time.Second)
+	}
+	return nil
+}
+
+// floodConnects opens SSE streams in quick succession; the server may
+// refuse some, but only with 429 and Retry-After
+func floodConnects(t *reconnectTester, header http.Header, connects int, maxRetryAfter time.Duration) {
+	if connects < 1 {
+		return
+	}
+	t.begin("Connection flood")
+	var sessions []*MCPClient
+	defer func() {
+		for _, mcp := range sessions {
+			mcp.Close()
+		}
+	}()
+
+	throttled, badHeaders := 0, 0
+	others := make(map[string]int)
+	for range connects {
+		mcp, err := connectMCPWithHeader(t.serverURL, t.timeout, header)
+		var status *HTTPStatusError
+		switch {
+		case err == nil:
+			sessions = append(sessions, mcp)
+		case errors.As(err, &status) && status.Status == http.StatusTooManyRequests:
+			throttled++
+			if wait, ok := parseRetryAfter(status.Header.Get("Retry-After")); !ok || wait > maxRetryAfter {
+				badHeaders++
+			}
+		default:
+			others[errorClass(err)]++
+		}
+	}
+
+	switch {
+	case len(others) > 0:
+		var errs []string
+		for _, class := range slices.Sorted(maps.Keys(others)) {
+			errs = append(errs, fmt.Sprintf("%d× %s", others[class], class))
+		}
+		t.record("streams opened or throttled", oauthFail, "%d opened, %d throttled, %s", len(sessions), throttled, strings.Join(errs, ", "))
+	case badHeaders > 0:
+		t.record("streams opened or throttled", oauthFail, "%d of %d refused stream(s) without a valid Retry-After", badHeaders, throttled)
+	case throttled > 0:
+		t.record("streams opened or throttled", oauthPass, "%d opened, %d throttled with Retry-After", len(sessions), throttled)
+	default:
+		t.record("streams opened or throttled", oauthPass, "all %d opened", len(sessions))
+	}
+
+	// Streams that were accepted must work, even with others refused; a
+	// throttled initialize is fine
+	broken := 0
+	for _, mcp := range sessions {
+		var status *HTTPStatusError
+		if _, err := mcp.Initialize(commandContext); err != nil && !(errors.As(err, &status) && status.Status == http.StatusTooManyRequests) {
+			broken++
+		}
+	}
+	if broken > 0 {
+		t.record("opened streams work", oauthFail, "%d of %d failed to initialize", broken, len(sessions))
+	} else if len(sessions) > 0 {
+		t.record("opened streams work", oauthPass, "%d initialized", len(sessions))
+	}
+}
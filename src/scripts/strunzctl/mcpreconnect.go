@@ -0,0 +1,299 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// reconnectCheck is one result of mcp reconnect-test
+type reconnectCheck struct {
+	Section, Name string
+	Result        oauthResult
+	Detail        string
+	Duration      time.Duration
+}
+
+// reconnectTester exercises the failure modes of long-lived SSE streams
+type reconnectTester struct {
+	serverURL string
+	timeout   time.Duration
+	checks    []reconnectCheck
+	section   string
+	started   time.Time
+}
+
+func (t *reconnectTester) begin(section string) {
+	t.section, t.started = section, time.Now()
+}
+
+func (t *reconnectTester) record(name string, result oauthResult, format string, args ...any) {
+	t.checks = append(t.checks, reconnectCheck{
+		Section: t.section, Name: name, Result: result,
+		Detail: fmt.Sprintf(format, args...), Duration: time.Since(t.started),
+	})
+	t.started = time.Now()
+}
+
+// report prints the checks by section, writes them as JUnit XML if junit
+// is set and returns how many failed
+func (t *reconnectTester) report(suite, junit string) (int, error) {
+	var checks []smokeCheck
+	failed, section := 0, ""
+	for _, c := range t.checks {
+		if c.Section != section {
+			section = c.Section
+			fmt.Printf("\n%s\n", section)
+		}
+		fmt.Printf("  %s %-32s %s\n", c.Result, c.Name, c.Detail)
+		check := smokeCheck{Name: c.Section + ": " + c.Name, Duration: c.Duration}
+		if c.Result == oauthFail {
+			failed++
+			check.Err = errors.New(c.Detail)
+		}
+		checks = append(checks, check)
+	}
+	if junit != "" {
+		if err := writeJUnitReport(junit, suite, checks); err != nil {
+			return failed, err
+		}
+		fmt.Printf("\nJUnit report written to %s\n", junit)
+	}
+	return failed, nil
+}
+
+// session opens an initialized session
+func (t *reconnectTester) session(header http.Header) (*MCPClient, error) {
+	mcp, err := connectMCPWithHeader(t.serverURL, t.timeout, header)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := mcp.Initialize(commandContext); err != nil {
+		mcp.Close()
+		return nil, errors.New(describeMCPError(err))
+	}
+	return mcp, nil
+}
+
+func newMCPReconnectTestCommand() *Command {
+	cmd := newCommand("reconnect-test", "[url]", "Idle, stall and drop SSE streams and check keepalives, session resumption and recovery.")
+	idle := cmd.Flags.Duration("idle", time.Minute, "how long to keep a session idle while watching for keepalives")
+	maxGap := cmd.Flags.Duration("max-gap", 30*time.Second, "longest silence on the stream before proxies may drop it")
+	stall := cmd.Flags.Duration("stall", 10*time.Second, "how long to stop reading the stream while responses are pending")
+	churn := cmd.Flags.Int("churn", 10, "streams to open and drop in quick succession")
+	timeout := cmd.Flags.Duration("timeout", 30*time.Second, "timeout for connecting and for each request, beyond --stall")
+	junit := cmd.Flags.String("junit", "", "write a JUnit XML report to this file")
+
+	cmd.Run = func(args []string) error {
+		if len(args) > 1 {
+			return cmd.ExactArgs(args, 1)
+		}
+		serverURL := defaultServerURL
+		if len(args) == 1 {
+			serverURL = args[0]
+		}
+		tester := &reconnectTester{serverURL: serverURL, timeout: *timeout}
+
+		fmt.Printf("\n🔁 SSE robustness of %s (about %s)\n", serverURL, (*idle + *stall).Round(time.Second))
+		if err := tester.keepalive(*idle, *maxGap); err != nil {
+			return err
+		}
+		tester.slowReader(*stall)
+		tester.drop()
+		tester.churn(*churn)
+
+		failed, err := tester.report("mcp reconnect-test "+serverURL, *junit)
+		if err != nil {
+			return err
+		}
+		if failed > 0 {
+			return fmt.Errorf("%w: %d of %d stream check(s) failed", errPolicy, failed, len(tester.checks))
+		}
+		fmt.Println("\n✅ Streams survive idling, stalls and drops")
+		return nil
+	}
+	return cmd
+}
+
+// keepalive idles an initialized session and watches for comments and
+// pings. Only an unreachable server is an error.
+func (t *reconnectTester) keepalive(idle, maxGap time.Duration) error {
+	t.begin("Keepalive")
+	mcp, err := t.session(nil)
+	if err != nil {
+		return err
+	}
+	defer mcp.Close()
+
+	select {
+	case <-mcp.Done():
+	case <-time.After(idle):
+	}
+	stream := mcp.Stream()
+	switch {
+	case mcp.Disconnected():
+		t.record("idle stream", oauthFail, "closed by the server after %s: %v", time.Since(t.started).Round(time.Second), mcp.Err())
+		return nil
+	case stream.MaxGap > maxGap:
+		t.record("idle stream", oauthFail, "silent for %s; proxies drop streams idle for longer than %s", stream.MaxGap.Round(time.Second), maxGap)
+	case stream.Keepalives == 0 && stream.Pings == 0:
+		t.record("idle stream", oauthWarn, "open after %s but no keepalives were sent", idle)
+	default:
+		t.record("idle stream", oauthPass, "%d keepalive(s), %d ping(s), longest silence %s",
+			stream.Keepalives, stream.Pings, stream.MaxGap.Round(time.Second))
+	}
+
+	if _, err := mcp.ListTools(commandContext); err != nil {
+		t.record("request after idling", oauthFail, "%s", describeMCPError(err))
+	} else {
+		t.record("request after idling", oauthPass, "tools/list answered")
+	}
+	return nil
+}
+
+// slowReader stops consuming the stream while requests are pending; the
+// responses must arrive once reading resumes
+func (t *reconnectTester) slowReader(stall time.Duration) {
+	t.begin("Slow reader")
+	mcp, err := t.session(nil)
+	if err != nil {
+		t.record("stalled stream", oauthFail, "%v", err)
+		return
+	}
+	defer mcp.Close()
+	mcp.Timeout = t.timeout + stall
+
+	const requests = 5
+	mcp.PauseReading(stall)
+	var wg sync.WaitGroup
+	errs := make([]error, requests)
+	for i := range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = mcp.ListTools(commandContext)
+		}()
+	}
+	wg.Wait()
+	switch err := errors.Join(errs...); {
+	case mcp.Disconnected():
+		t.record("stalled stream", oauthFail, "server dropped the stream while it was not read for %s: %v", stall, mcp.Err())
+	case err != nil:
+		t.record("stalled stream", oauthFail, "%s", describeMCPError(err))
+	default:
+		t.record("stalled stream", oauthPass, "%d responses delivered after a %s stall", requests, stall)
+	}
+}
+
+// drop closes a stream with a tool call in flight, then checks what the
+// server does with the dead session and whether it can be resumed
+func (t *reconnectTester) drop() {
+	t.begin("Dropped stream")
+	mcp, err := t.session(nil)
+	if err != nil {
+		t.record("drop mid-call", oauthFail, "%v", err)
+		return
+	}
+	go mcp.CallTool(commandContext, smokeTool, cannedToolArguments[smokeTool])
+	time.Sleep(100 * time.Millisecond)
+	mcp.Close()
+	<-mcp.Done()
+	t.record("drop mid-call", oauthPass, "stream closed with %s in flight", smokeTool)
+
+	// Give the server a moment to notice the closed connection
+	time.Sleep(time.Second)
+	status, body, err := mcp.PostRaw(commandContext, []byte(`{"jsonrpc":"2.0","id":990,"method":"tools/list"}`))
+	switch {
+	case err != nil:
+		t.record("message to dropped session", oauthFail, "%v", err)
+	case status/100 == 2:
+		t.record("message to dropped session", oauthFail, "accepted with %d; the response is lost and clients wait until they time out", status)
+	case status == http.StatusNotFound || status == http.StatusGone:
+		t.record("message to dropped session", oauthPass, "rejected with %d, clients know to reconnect", status)
+	default:
+		t.record("message to dropped session", oauthWarn, "rejected with %d %s; 404 tells clients to re-initialize", status, truncate(body, 40))
+	}
+
+	if id := mcp.Stream().LastEventID; id == "" {
+		t.record("resume with Last-Event-ID", oauthWarn, "events carry no ids; clients cannot resume and must re-initialize")
+	} else {
+		t.resume(mcp, id)
+	}
+
+	fresh, err := t.session(nil)
+	if err != nil {
+		t.record("new session after drop", oauthFail, "%v", err)
+		return
+	}
+	defer fresh.Close()
+	if _, err := fresh.ListTools(commandContext); err != nil {
+		t.record("new session after drop", oauthFail, "%s", describeMCPError(err))
+		return
+	}
+	t.record("new session after drop", oauthPass, "initialize and tools/list work")
+}
+
+// resume reconnects with the last event id and expects the old session and
+// the events missed since
+func (t *reconnectTester) resume(dropped *MCPClient, lastEventID string) {
+	resumed, err := connectMCPWithHeader(t.serverURL, t.timeout, http.Header{"Last-Event-ID": {lastEventID}})
+	if err != nil {
+		t.record("resume with Last-Event-ID", oauthFail, "%v", err)
+		return
+	}
+	defer resumed.Close()
+	if resumed.Endpoint() != dropped.Endpoint() {
+		t.record("resume with Last-Event-ID", oauthWarn, "Last-Event-ID %s ignored, a new session was started", lastEventID)
+		return
+	}
+	select {
+	case <-resumed.Unmatched():
+		t.record("resume with Last-Event-ID", oauthPass, "session resumed and missed events replayed")
+	case <-time.After(t.timeout):
+		t.record("resume with Last-Event-ID", oauthWarn, "session resumed but the in-flight response was not replayed")
+	}
+}
+
+// churn opens and drops streams in quick succession, some during
+// initialize, and checks the server still serves new sessions
+func (t *reconnectTester) churn(n int) {
+	t.begin("Reconnect churn")
+	if n < 1 {
+		return
+	}
+	failures := 0
+	for i := range n {
+		mcp, err := connectMCP(t.serverURL, t.timeout)
+		if err != nil {
+			failures++
+			continue
+		}
+		if i%2 == 1 {
+			go mcp.Initialize(commandContext)
+		}
+		mcp.Close()
+	}
+	if failures > 0 {
+		t.record("rapid reconnects", oauthFail, "%d of %d connects failed", failures, n)
+	} else {
+		t.record("rapid reconnects", oauthPass, "%d streams opened and dropped", n)
+	}
+
+	mcp, err := t.session(nil)
+	if err != nil {
+		t.record("session after churn", oauthFail, "%v", err)
+		return
+	}
+	defer mcp.Close()
+	if _, err := mcp.ListTools(commandContext); err != nil {
+		t.record("session after churn", oauthFail, "%s", describeMCPError(err))
+		return
+	}
+	if _, err := fetchHealth(t.serverURL); err != nil {
+		t.record("session after churn", oauthFail, "/health: %v", err)
+		return
+	}
+	t.record("session after churn", oauthPass, "tools/list and /health work")
+}
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// recordedFrame is one line of a session recording: a JSON-RPC message
+// the client posted or the server sent, or a non-JSON response body
+type recordedFrame struct {
+	At        time.Time       `json:"at"`
+	Session   string          `json:"session"`
+	Direction string          `json:"direction"` // "client" or "server"
+	Status    int             `json:"status,omitempty"`
+	Frame     json.RawMessage `json:"frame,omitempty"`
+	Raw       string          `json:"raw,omitempty"` // bodies that are not JSON
+}
+
+// recorder appends frames to a recording as JSON lines
+type recorder struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+	frames  int
+}
+
+func (r *recorder) record(session, direction string, status int, body []byte) {
+	frame := recordedFrame{At: time.Now().UTC(), Session: session, Direction: direction, Status: status}
+	var compact bytes.Buffer
+	if json.Compact(&compact, body) == nil {
+		frame.Frame = compact.Bytes()
+	} else {
+		frame.Raw = string(body)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.encoder.Encode(frame); err != nil {
+		fmt.Printf("  ❌ failed to write the recording: %v\n", err)
+		return
+	}
+	r.frames++
+	arrow := "→"
+	if direction == "server" {
+		arrow = "←"
+	}
+	summary := frameSummary(frame.Frame)
+	if frame.Frame == nil {
+		summary = truncate(frame.Raw, 60)
+	}
+	if status != 0 {
+		summary = fmt.Sprintf("%d %s", status, summary)
+	}
+	fmt.Printf("  %s %-12s %s\n", arrow, truncate(orNone(session), 12), summary)
+}
+
+// frameSummary names a JSON-RPC message for progress lines
+func frameSummary(frame json.RawMessage) string {
+	var batch []json.RawMessage
+	if json.Unmarshal(frame, &batch) == nil {
+		return fmt.Sprintf("batch of %d", len(batch))
+	}
+	var message struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+		Params struct {
+			Name string `json:"name"`
+		} `json:"params"`
+		Error *MCPError `json:"error"`
+	}
+	if json.Unmarshal(frame, &message) != nil {
+		return "invalid JSON-RPC"
+	}
+	switch {
+	case message.Method != "" && message.Params.Name != "":
+		return fmt.Sprintf("%s %s #%s", message.Method, message.Params.Name, message.ID)
+	case message.Method != "" && len(message.ID) > 0:
+		return fmt.Sprintf("%s #%s", message.Method, message.ID)
+	case message.Method != "":
+		return message.Method
+	case message.Error != nil:
+		return fmt.Sprintf("#%s error %d", message.ID, message.Error.Code)
+	default:
+		return fmt.Sprintf("#%s result", message.ID)
+	}
+}
+
+// sessionOf identifies a session by the query of its message endpoint, as
+// the SSE transport hands it out, or by the Mcp-Session-Id header
+func sessionOf(rawQuery, header string) string {
+	if header != "" {
+		return header
+	}
+	query, _ := url.ParseQuery(rawQuery)
+	if id := query.Get("session_id"); id != "" {
+		return id
+	}
+	return rawQuery
+}
+
+// recordingProxy forwards MCP traffic to the upstream server and records
+// every JSON-RPC frame in both directions
+type recordingProxy struct {
+	proxy    *httputil.ReverseProxy
+	recorder *recorder
+	maxBody  int64
+}
+
+func (p *recordingProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	session := sessionOf(r.URL.RawQuery, r.Header.Get("Mcp-Session-Id"))
+	if r.Method == http.MethodPost {
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, p.maxBody))
+		if err != nil {
+			http.Error(w, "recorder: request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		// Recorded before forwarding so it precedes its response on the stream
+		p.recorder.record(session, "client", 0, bytes.TrimSpace(body))
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		r.ContentLength = int64(len(body))
+	}
+	writer := &recordingWriter{ResponseWriter: w, recorder: p.recorder, session: session, status: http.StatusOK}
+	p.proxy.ServeHTTP(writer, r)
+	writer.finish()
+}
+
+// recordingWriter records the server frames of a response: each message
+// event of an SSE stream, or a JSON body once the response is complete
+type recordingWriter struct {
+	http.ResponseWriter
+	recorder *recorder
+	session  string
+	status   int
+	buffer   []byte
+}
+
+func (w *recordingWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	w.buffer = append(w.buffer, b[:n]...)
+	if !isEventStream(w.Header()) {
+		return n, err
+	}
+	for {
+		normalized := bytes.ReplaceAll(w.buffer, []byte("\r\n"), []byte("\n"))
+		block, rest, ok := bytes.Cut(normalized, []byte("\n\n"))
+		if !ok {
+			w.buffer = normalized
+			break
+		}
+		w.buffer = rest
+		event, data := parseSSEBlock(string(block))
+		switch event {
+		case "endpoint":
+			// The stream's session is known once the server names its endpoint
+			if endpoint, err := url.Parse(data); err == nil {
+				w.session = sessionOf(endpoint.RawQuery, "")
+				fmt.Printf("  🔗 %-12s session opened\n", truncate(orNone(w.session), 12))
+			}
+		case "", "message":
+			if data != "" {
+				w.recorder.record(w.session, "server", 0, []byte(data))
+			}
+		}
+	}
+	return n, err
+}
+
+// finish records a response body that was not a stream
+func (w *recordingWriter) finish() {
+	if isEventStream(w.Header()) {
+		return
+	}
+	body := bytes.TrimSpace(w.buffer)
+	status := 0
+	if w.status/100 != 2 {
+		status = w.status
+	}
+	if len(body) > 0 || status != 0 {
+		w.recorder.record(w.session, "server", status, body)
+	}
+}
+
+// Unwrap lets the reverse proxy flush the underlying writer
+func (w *recordingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// parseSSEBlock reads the event name and data of one SSE event
+func parseSSEBlock(block string) (event, data string) {
+	for _, line := range strings.Split(block, "\n") {
+		switch {
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			if data != "" {
+				data += "\n"
+			}
+			data += strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " ")
+		}
+	}
+	return event, data
+}
+
+// loadRecording reads a recording and groups its frames by session, in
+// the order the sessions first appear
+func loadRecording(path string) ([][]recordedFrame, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var sessions [][]recordedFrame
+	index := make(map[string]int)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64<<10), 16<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var frame recordedFrame
+		if err := json.Unmarshal(scanner.Bytes(), &frame); err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", path, line, err)
+		}
+		i, ok := index[frame.Session]
+		if !ok {
+			i = len(sessions)
+			index[frame.Session] = i
+			sessions = append(sessions, nil)
+		}
+		sessions[i] = append(sessions[i], frame)
+	}
+	return sessions, scanner.Err()
+}
+
+func newMCPRecordCommand() *Command {
+	cmd := newCommand("record", "[upstream-url]", "Proxy MCP traffic to the server and record every JSON-RPC frame with timestamps for mcp replay.")
+	listen := cmd.Flags.String("listen", "127.0.0.1:8091", "address to listen on")
+	output := cmd.Flags.String("output", "mcp-session.jsonl", "file to write the recording to")
+	appendTo := cmd.Flags.Bool("append", false, "append to the recording instead of replacing it")
+	maxBody := cmd.Flags.Int64("max-body", 16<<20, "largest request body in bytes to record and forward")
+
+	cmd.Run = func(args []string) error {
+		if len(args) > 1 {
+			return cmd.ExactArgs(args, 1)
+		}
+		upstream := defaultServerURL
+		if len(args) == 1 {
+			upstream = args[0]
+		}
+		target, err := url.Parse(upstream)
+		if err != nil || target.Host == "" {
+			return fmt.Errorf("%w: invalid upstream URL %q", errUsage, upstream)
+		}
+		flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+		if *appendTo {
+			flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+		}
+		file, err := os.OpenFile(*output, flags, 0o644)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		rec := &recorder{encoder: json.NewEncoder(file)}
+		proxy := &recordingProxy{
+			recorder: rec,
+			maxBody:  *maxBody,
+			proxy: &httputil.ReverseProxy{
+				Rewrite: func(r *httputil.ProxyRequest) {
+					r.SetURL(target)
+					r.Out.Host = target.Host
+				},
+				// Stream SSE events as they arrive
+				FlushInterval: -1,
+			},
+		}
+
+		listener, err := net.Listen("tcp", *listen)
+		if err != nil {
+			return fmt.Errorf("failed to listen: %w", err)
+		}
+		// No write timeout: SSE streams stay open for as long as the session
+		server := &http.Server{Handler: proxy, ReadHeaderTimeout: 10 * time.Second}
+		ctx, stop := context.WithCancel(commandContext)
+		defer stop()
+		go func() {
+			<-ctx.Done()
+			server.Close()
+		}()
+
+		fmt.Printf("\n⏺️  Recording http://%s → %s into %s (Ctrl-C to stop)\n\n", listener.Addr(), upstream, *output)
+		if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		rec.mu.Lock()
+		defer rec.mu.Unlock()
+		fmt.Printf("\n💾 %d frame(s) recorded in %s; replay with: strunzctl mcp replay %s <url>\n", rec.frames, *output, *output)
+		return nil
+	}
+	return cmd
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// replayedRequest is a recorded request sent again, waiting for its response
+type replayedRequest struct {
+	id     string
+	label  string
+	offset time.Duration // since the start of the recorded session
+	status int           // HTTP status of the POST, when not 2xx
+}
+
+// replayer sends the client frames of recorded sessions to a server and
+// compares its responses with the recorded ones
+type replayer struct {
+	serverURL string
+	header    http.Header
+	timeout   time.Duration
+	speed     float64 // 0: send each frame once the previous one is answered
+	ignore    []string
+
+	same, changed, differs, skipped int
+}
+
+// replay runs one recorded session on a fresh session of the server. Only
+// an unreachable server is an error.
+func (p *replayer) replay(frames []recordedFrame) error {
+	expected := make(map[string]*rpcResponse)
+	for _, frame := range frames {
+		if frame.Direction != "server" {
+			continue
+		}
+		for _, raw := range splitBatch(frame.Frame) {
+			var response rpcResponse
+			var method struct {
+				Method string `json:"method"`
+			}
+			if json.Unmarshal(raw, &response) == nil && json.Unmarshal(raw, &method) == nil && method.Method == "" && len(response.ID) > 0 {
+				if _, seen := expected[string(response.ID)]; !seen {
+					expected[string(response.ID)] = &response
+				}
+			}
+		}
+	}
+
+	mcp, err := connectMCPWithHeader(p.serverURL, p.timeout, p.header)
+	if err != nil {
+		return err
+	}
+	defer mcp.Close()
+	runner := &conformanceRunner{mcp: mcp, timeout: p.timeout}
+
+	first, start := frames[0].At, time.Now()
+	var pending []replayedRequest
+	resolve := func() {
+		for _, request := range pending {
+			p.compare(runner, request, expected[request.id])
+		}
+		pending = nil
+	}
+	for _, frame := range frames {
+		if frame.Direction != "client" || (frame.Frame == nil && frame.Raw == "") {
+			continue
+		}
+		var requests []replayedRequest
+		answers := 0
+		for _, raw := range splitBatch(frame.Frame) {
+			var message struct {
+				ID     json.RawMessage `json:"id"`
+				Method string          `json:"method"`
+			}
+			json.Unmarshal(raw, &message)
+			switch {
+			case message.Method == "" && len(message.ID) > 0:
+				answers++
+			case message.Method != "" && len(message.ID) > 0:
+				requests = append(requests, replayedRequest{id: string(message.ID), label: frameSummary(raw), offset: frame.At.Sub(first)})
+			}
+		}
+		// Answers to server pings belong to the recorded session; the
+		// client answers the new session's pings itself
+		if answers > 0 && len(requests) == 0 && frame.Frame != nil {
+			p.skipped++
+			continue
+		}
+
+		if p.speed > 0 {
+			time.Sleep(time.Until(start.Add(time.Duration(float64(frame.At.Sub(first)) / p.speed))))
+		}
+		body := string(frame.Frame)
+		if frame.Frame == nil {
+			body = frame.Raw
+		}
+		status, text, err := runner.send(body)
+		switch {
+		case err != nil:
+			fmt.Printf("  ❌ +%-8s %s  %s\n", frame.At.Sub(first).Round(time.Millisecond), frameSummary(frame.Frame), describeMCPError(err))
+			p.differs += len(requests)
+			continue
+		case status/100 != 2:
+			for i := range requests {
+				requests[i].status = status
+			}
+		}
+		// Servers may answer in the POST response instead of on the stream
+		for _, raw := range splitBatch(json.RawMessage(text)) {
+			runner.received = append(runner.received, string(raw))
+		}
+		pending = append(pending, requests...)
+		if p.speed == 0 {
+			resolve()
+		}
+	}
+	resolve()
+	return nil
+}
+
+// compare prints how the server answered a replayed request next to what
+// was recorded
+func (p *replayer) compare(runner *conformanceRunner, request replayedRequest, expected *rpcResponse) {
+	var actual *rpcResponse
+	if request.status == 0 {
+		actual = runner.awaitID(request.id)
+	}
+	icon, detail := "✅", "same"
+	switch {
+	case actual == nil && expected == nil:
+		detail = "no response, as recorded"
+	case actual == nil && request.status != 0:
+		icon, detail = "❌", fmt.Sprintf("POST answered %d %s (recorded: %s)", request.status, http.StatusText(request.status), replayOutcome(expected))
+	case actual == nil:
+		icon, detail = "❌", fmt.Sprintf("no response within %s (recorded: %s)", p.timeout, replayOutcome(expected))
+	case expected == nil:
+		icon, detail = "❌", "recorded without a response, now "+replayOutcome(actual)
+	case (expected.Error == nil) != (actual.Error == nil) || (expected.Error != nil && expected.Error.Code != actual.Error.Code):
+		icon, detail = "❌", fmt.Sprintf("recorded %s, now %s", replayOutcome(expected), replayOutcome(actual))
+	case expected.Error != nil:
+		if expected.Error.Message != actual.Error.Message {
+			icon, detail = "≠", fmt.Sprintf("error message %q, now %q", truncate(expected.Error.Message, 60), truncate(actual.Error.Message, 60))
+		}
+	default:
+		if fields := p.changedFields(expected.Result, actual.Result); len(fields) > 0 {
+			icon, detail = "≠", fmt.Sprintf("%d field(s) changed: %s", len(fields), truncate(strings.Join(fields, ", "), 100))
+		}
+	}
+	switch icon {
+	case "✅":
+		p.same++
+	case "≠":
+		p.changed++
+	default:
+		p.differs++
+	}
+	fmt.Printf("  %s +%-8s %s  %s\n", icon, request.offset.Round(time.Millisecond), request.label, detail)
+}
+
+// changedFields returns the paths of result fields that differ, leaving
+// out those matching --ignore
+func (p *replayer) changedFields(expected, actual json.RawMessage) []string {
+	var expectedJSON, actualJSON any
+	json.Unmarshal(expected, &expectedJSON)
+	json.Unmarshal(actual, &actualJSON)
+	expectedFields, actualFields := map[string]string{}, map[string]string{}
+	flattenJSON(expectedJSON, "", expectedFields)
+	flattenJSON(actualJSON, "", actualFields)
+	var changed []string
+	for _, path := range sortedKeys(expectedFields, actualFields) {
+		ignored := slices.ContainsFunc(p.ignore, func(ignore string) bool { return strings.Contains(path, ignore) })
+		if !ignored && expectedFields[path] != actualFields[path] {
+			changed = append(changed, path)
+		}
+	}
+	return changed
+}
+
+// replayOutcome describes a response as a result or an error code
+func replayOutcome(response *rpcResponse) string {
+	switch {
+	case response == nil:
+		return "no response"
+	case response.Error != nil:
+		return fmt.Sprintf("error %d %s", response.Error.Code, truncate(response.Error.Message, 60))
+	default:
+		return "result"
+	}
+}
+
+// splitBatch returns the messages of a batch, or the message itself
+func splitBatch(frame json.RawMessage) []json.RawMessage {
+	var batch []json.RawMessage
+	if json.Unmarshal(frame, &batch) == nil {
+		return batch
+	}
+	if json.Valid(frame) {
+		return []json.RawMessage{frame}
+	}
+	return nil
+}
+
+func newMCPReplayCommand() *Command {
+	cmd := newCommand("replay", "<recording> [url]", "Replay a session recorded with mcp record against a server and compare its responses with the recorded ones.")
+	speed := cmd.Flags.Float64("speed", 0, "replay at this multiple of the recorded timing (0: send each request once the previous one is answered)")
+	session := cmd.Flags.String("session", "", "replay only the recorded session with this id")
+	ignore := cmd.Flags.String("ignore", "/serverInfo/version", "comma-separated result paths (or parts of them) that may change")
+	token := cmd.Flags.String("token", "", "bearer token to send")
+	timeout := cmd.Flags.Duration("timeout", 30*time.Second, "timeout for connecting and for each response")
+	failOnChange := cmd.Flags.Bool("fail-on-change", false, "exit with the policy code when any response changed")
+
+	cmd.Run = func(args []string) error {
+		if len(args) < 1 || len(args) > 2 {
+			return fmt.Errorf("%w: replay needs a recording and optionally a server URL", errUsage)
+		}
+		serverURL := defaultServerURL
+		if len(args) == 2 {
+			serverURL = args[1]
+		}
+		if *speed < 0 {
+			return fmt.Errorf("%w: --speed must not be negative", errUsage)
+		}
+		sessions, err := loadRecording(args[0])
+		if err != nil {
+			return err
+		}
+		if *session != "" {
+			sessions = slices.DeleteFunc(sessions, func(frames []recordedFrame) bool { return frames[0].Session != *session })
+		}
+		if len(sessions) == 0 {
+			return fmt.Errorf("%w: no recorded session to replay in %s", errUsage, args[0])
+		}
+		player := &replayer{serverURL: serverURL, timeout: *timeout, speed: *speed, ignore: splitList(*ignore)}
+		if *token != "" {
+			player.header = http.Header{"Authorization": {"Bearer " + *token}}
+		}
+
+		fmt.Printf("\n🎞️  Replaying %d session(s) from %s against %s\n", len(sessions), args[0], serverURL)
+		for _, frames := range sessions {
+			fmt.Printf("\nSession %s, recorded %s (%d frame(s))\n", orNone(frames[0].Session), frames[0].At.Local().Format(time.DateTime), len(frames))
+			if err := player.replay(frames); err != nil {
+				return err
+			}
+		}
+
+		fmt.Printf("\n📊 %d same, %d changed, %d different", player.same, player.changed, player.differs)
+		if player.skipped > 0 {
+			fmt.Printf("; %d answer(s) to server requests not replayed", player.skipped)
+		}
+		fmt.Println()
+		switch {
+		case player.differs > 0:
+			return fmt.Errorf("%w: %d response(s) differ from the recording", errPolicy, player.differs)
+		case player.changed > 0 && *failOnChange:
+			return fmt.Errorf("%w: %d response(s) changed", errPolicy, player.changed)
+		}
+		fmt.Println("\n✅ The server answers as recorded")
+		return nil
+	}
+	return cmd
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// claudeClientInfo is what Claude.ai sends as clientInfo in initialize
+var claudeClientInfo = map[string]string{"name": "claude-ai", "version": "0.1.0"}
+
+// claudeStep is one step of the simulated session with its offset from
+// the start
+type claudeStep struct {
+	At     time.Duration
+	Name   string
+	Result oauthResult
+	Detail string
+}
+
+// claudeSimulator replays the requests Claude.ai makes after a user adds
+// the server, in the same order and with the same headers
+type claudeSimulator struct {
+	baseURL string
+	header  http.Header
+	timeout time.Duration
+	start   time.Time
+	steps   []claudeStep
+}
+
+func (s *claudeSimulator) record(name string, result oauthResult, format string, args ...any) {
+	s.steps = append(s.steps, claudeStep{At: time.Since(s.start), Name: name, Result: result, Detail: fmt.Sprintf(format, args...)})
+}
+
+// failed returns the first failed step, or nil
+func (s *claudeSimulator) failed() *claudeStep {
+	for i := range s.steps {
+		if s.steps[i].Result == oauthFail {
+			return &s.steps[i]
+		}
+	}
+	return nil
+}
+
+func newMCPSimulateClaudeCommand() *Command {
+	cmd := newCommand("simulate-claude", "[url]", "Replay the auth callback, initialize, tools/list and first tool call exactly as Claude.ai performs them.")
+	token := cmd.Flags.String("token", "", "access token to use instead of running the OAuth flow")
+	orgID := cmd.Flags.String("org-id", "strunzctl-test", "organization ID for the Claude.ai start-auth path")
+	scope := cmd.Flags.String("scope", "read", "scope to request")
+	userAgent := cmd.Flags.String("user-agent", "claude-ai/0.1.0", "User-Agent to send; copy it from the production access log")
+	protocolVersion := cmd.Flags.String("protocol-version", "2025-06-18", "protocolVersion Claude.ai offers in initialize")
+	tool := cmd.Flags.String("tool", smokeTool, "tool of the first call (canned arguments as in mcp smoke)")
+	firstCall := cmd.Flags.Duration("first-call-after", 5*time.Second, "idle time on the open stream before the first tool call, as while the user types")
+	timeout := cmd.Flags.Duration("timeout", 30*time.Second, "timeout for each request")
+
+	cmd.Run = func(args []string) error {
+		if len(args) > 1 {
+			return cmd.ExactArgs(args, 1)
+		}
+		serverURL := defaultServerURL
+		if len(args) == 1 {
+			serverURL = args[0]
+		}
+		sim := &claudeSimulator{
+			baseURL: strings.TrimRight(serverURL, "/"),
+			header: http.Header{
+				"User-Agent": {*userAgent},
+				"Origin":     {"https://claude.ai"},
+			},
+			timeout: *timeout,
+			start:   time.Now(),
+		}
+
+		fmt.Printf("\n🤖 Connecting to %s as Claude.ai\n", serverURL)
+		authorized := true
+		if *token != "" {
+			sim.header.Set("Authorization", "Bearer "+*token)
+		} else {
+			authorized = sim.authenticate(*orgID, *scope)
+		}
+		if authorized {
+			sim.session(*protocolVersion, *tool, *firstCall)
+		}
+
+		fmt.Println()
+		for _, step := range sim.steps {
+			fmt.Printf("  +%7s %s %-28s %s\n", step.At.Round(time.Millisecond), step.Result, step.Name, step.Detail)
+		}
+		if step := sim.failed(); step != nil {
+			if authorized {
+				fmt.Printf("\nClaude.ai would report \"Successfully connected\" and then disable the server: %s failed.\n", step.Name)
+			}
+			return fmt.Errorf("%w: %s: %s", errPolicy, step.Name, step.Detail)
+		}
+		if !authorized {
+			return fmt.Errorf("%w: the OAuth flow needs a user; pass --token to continue with the MCP session", errUsage)
+		}
+		fmt.Println("\n✅ Claude.ai would connect and keep the server enabled")
+		return nil
+	}
+	return cmd
+}
+
+// authenticate probes the stream without a token like Claude.ai does and
+// runs the OAuth flow when the server answers 401. It reports whether the
+// session can go ahead.
+func (s *claudeSimulator) authenticate(orgID, scope string) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.baseURL+"/sse", nil)
+	if err != nil {
+		s.record("probe /sse", oauthFail, "%v", err)
+		return false
+	}
+	req.Header = s.header.Clone()
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		s.record("probe /sse", oauthFail, "%v", err)
+		return false
+	}
+	resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		s.record("probe /sse", oauthPass, "open without a token, OAuth skipped")
+		return true
+	case http.StatusUnauthorized:
+		s.record("probe /sse", oauthPass, "401, starting OAuth (%s)", orNone(resp.Header.Get("WWW-Authenticate")))
+	default:
+		s.record("probe /sse", oauthFail, "returned %s", resp.Status)
+		return false
+	}
+
+	tester := &oauthTester{
+		baseURL: s.baseURL,
+		httpClient: &http.Client{
+			Timeout:       s.timeout,
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+	}
+	// Copies the steps the tester recorded since the last call
+	seen := 0
+	collect := func() {
+		for _, step := range tester.steps[seen:] {
+			s.record(step.Name, step.Result, "%s", step.Detail)
+		}
+		seen = len(tester.steps)
+	}
+
+	metadata, err := tester.discover()
+	if err != nil {
+		s.record("authorization server metadata", oauthFail, "%v", err)
+		return false
+	}
+	tester.startAuth(orgID, metadata)
+	collect()
+	clientID := tester.register(metadata, scope)
+	collect()
+	if clientID == "" {
+		return false
+	}
+	verifier, challenge := pkcePair()
+	code := tester.authorize(metadata, clientID, scope, challenge)
+	collect()
+	if code == "" {
+		return false
+	}
+	s.record("auth callback", oauthPass, "code delivered to %s", oauthRedirectURI)
+	token := tester.exchange(metadata, clientID, code, verifier)
+	collect()
+	if token == nil {
+		return false
+	}
+	s.header.Set("Authorization", "Bearer "+token.AccessToken)
+	return true
+}
+
+// session opens the stream and performs the handshake, listings and first
+// tool call back to back, the way Claude.ai does after the callback
+func (s *claudeSimulator) session(protocolVersion, tool string, firstCall time.Duration) {
+	mcp, err := connectMCPWithHeader(s.baseURL, s.timeout, s.header)
+	if err != nil {
+		s.record("open SSE stream", oauthFail, "%v", err)
+		return
+	}
+	defer mcp.Close()
+	s.record("open SSE stream", oauthPass, "endpoint %s", mcp.Endpoint())
+
+	var initialized MCPInitializeResult
+	err = mcp.Request(commandContext, "initialize", map[string]any{
+		"protocolVersion": protocolVersion,
+		"capabilities":    map[string]any{},
+		"clientInfo":      claudeClientInfo,
+	}, &initialized)
+	if err != nil {
+		s.record("initialize", oauthFail, "%s", describeMCPError(err))
+		return
+	}
+	if initialized.ProtocolVersion != protocolVersion {
+		s.record("initialize", oauthPass, "%s %s, negotiated down to %s", initialized.ServerInfo.Name, initialized.ServerInfo.Version, initialized.ProtocolVersion)
+	} else {
+		s.record("initialize", oauthPass, "%s %s, protocol %s", initialized.ServerInfo.Name, initialized.ServerInfo.Version, initialized.ProtocolVersion)
+	}
+	if err := mcp.Notify(commandContext, "notifications/initialized", nil); err != nil {
+		s.record("notifications/initialized", oauthFail, "%v", err)
+		return
+	}
+	s.record("notifications/initialized", oauthPass, "accepted")
+
+	tools, err := mcp.ListTools(commandContext)
+	switch {
+	case err != nil:
+		s.record("tools/list", oauthFail, "%s", describeMCPError(err))
+		return
+	case len(tools) == 0:
+		s.record("tools/list", oauthFail, "no tools; Claude.ai disables servers without tools")
+		return
+	}
+	s.record("tools/list", oauthPass, "%d tool(s)", len(tools))
+
+	// Claude.ai also lists prompts and resources when they are advertised
+	var capabilities map[string]json.RawMessage
+	json.Unmarshal(initialized.Capabilities, &capabilities)
+	for _, kind := range []string{"prompts", "resources"} {
+		if _, ok := capabilities[kind]; !ok {
+			continue
+		}
+		if err := mcp.Request(commandContext, kind+"/list", map[string]any{}, nil); err != nil {
+			s.record(kind+"/list", oauthFail, "advertised but failed: %s", describeMCPError(err))
+			return
+		}
+		s.record(kind+"/list", oauthPass, "listed")
+	}
+
+	select {
+	case <-mcp.Done():
+	case <-time.After(firstCall):
+	}
+	if mcp.Disconnected() {
+		s.record("idle stream", oauthFail, "server closed the stream after %s: %v", time.Since(s.start).Round(time.Second), mcp.Err())
+		return
+	}
+	s.record("idle stream", oauthPass, "still open after %s", firstCall)
+
+	index := slices.IndexFunc(tools, func(t MCPTool) bool { return t.Name == tool })
+	if index < 0 {
+		s.record("tools/call "+tool, oauthFail, "not advertised")
+		return
+	}
+	detail, err := callToolChecked(mcp, tool, toolArguments(tools[index]))
+	if err != nil {
+		s.record("tools/call "+tool, oauthFail, "%v", err)
+		return
+	}
+	s.record("tools/call "+tool, oauthPass, "%s", truncate(detail, 60))
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// cannedToolArguments are realistic arguments for the tools the Strunz
+// servers advertise. Tools not listed get arguments derived from their
+// input schema.
+var cannedToolArguments = map[string]map[string]any{
+	"search_knowledge":            {"query": "Vitamin D", "limit": 3},
+	"knowledge_search":            {"query": "Vitamin D", "limit": 3},
+	"search_knowledge_advanced":   {"query": "Vitamin D", "content_types": []string{"books"}, "limit": 3},
+	"search_knowledge_gemini":     {"query": "Vitamin D", "limit": 3},
+	"search_news":                 {"query": "Magnesium", "limit": 3},
+	"get_book_content":            {"book_title": "Das Stress-weg-Buch", "page_range": "1-2"},
+	"ask_strunz_gemini":           {"question": "Warum ist Vitamin D wichtig?"},
+	"analyze_health_topic_gemini": {"topic": "Magnesium"},
+	"find_contradictions":         {"topic": "Cholesterin"},
+	"trace_topic_evolution":       {"topic": "Vitamin D"},
+	"create_health_protocol":      {"condition": "Müdigkeit"},
+	"analyze_supplement_stack":    {"supplements": []string{"Vitamin D", "Magnesium"}},
+	"verify_health_claim":         {"claim": "Vitamin D stärkt das Immunsystem"},
+}
+
+// toolErrorPrefixes mark failures the servers return as ordinary text
+var toolErrorPrefixes = []string{"Search failed", "Error:", "Tool error"}
+
+func newMCPSmokeCommand() *Command {
+	cmd := newCommand("smoke", "[url]", "Call every advertised tool with canned arguments and print a pass/fail matrix.")
+	only := cmd.Flags.String("only", "", "comma-separated tools to call (default: all advertised)")
+	skip := cmd.Flags.String("skip", "", "comma-separated tools not to call")
+	argsFile := cmd.Flags.String("args-file", "", "JSON object mapping tool names to arguments, overriding the canned ones")
+	timeout := cmd.Flags.Duration("timeout", 60*time.Second, "timeout for connecting and for each tool call")
+	junit := cmd.Flags.String("junit", "", "write a JUnit XML report to this file")
+
+	cmd.Run = func(args []string) error {
+		if len(args) > 1 {
+			return cmd.ExactArgs(args, 1)
+		}
+		serverURL := defaultServerURL
+		if len(args) == 1 {
+			serverURL = args[0]
+		}
+		overrides, err := loadToolArguments(*argsFile)
+		if err != nil {
+			return err
+		}
+
+		mcp, err := connectMCP(serverURL, *timeout)
+		if err != nil {
+			return err
+		}
+		defer mcp.Close()
+		if _, err := mcp.Initialize(commandContext); err != nil {
+			return fmt.Errorf("initialize failed: %s", describeMCPError(err))
+		}
+		tools, err := mcp.ListTools(commandContext)
+		if err != nil {
+			return fmt.Errorf("tools/list failed: %s", describeMCPError(err))
+		}
+
+		fmt.Printf("\n🧪 Calling %d advertised tool(s) on %s\n\n", len(tools), serverURL)
+		checks := smokeTools(mcp, tools, splitList(*only), splitList(*skip), overrides)
+
+		failed := 0
+		for _, check := range checks {
+			if check.Err != nil {
+				failed++
+			}
+		}
+		if *junit != "" {
+			if err := writeJUnitReport(*junit, "mcp smoke "+serverURL, checks); err != nil {
+				return err
+			}
+			fmt.Printf("\nJUnit report written to %s\n", *junit)
+		}
+		if failed > 0 {
+			return fmt.Errorf("%w: %d of %d tool call(s) failed", errPolicy, failed, len(checks))
+		}
+		fmt.Printf("\n✅ All %d tool call(s) succeeded\n", len(checks))
+		return nil
+	}
+	return cmd
+}
+
+// smokeTools calls the advertised tools, except skipped ones or those not
+// selected, printing a row per call. Selected tools that are not advertised
+// fail.
+func smokeTools(mcp *MCPClient, tools []MCPTool, selected, skipped []string, overrides map[string]map[string]any) []smokeCheck {
+	var checks []smokeCheck
+	for _, tool := range tools {
+		if (len(selected) > 0 && !slices.Contains(selected, tool.Name)) || slices.Contains(skipped, tool.Name) {
+			fmt.Printf("  ⏭️  %-34s skipped\n", tool.Name)
+			continue
+		}
+		arguments, ok := overrides[tool.Name]
+		if !ok {
+			arguments = toolArguments(tool)
+		}
+
+		start := time.Now()
+		detail, err := callToolChecked(mcp, tool.Name, arguments)
+		check := smokeCheck{Name: tool.Name, Err: err, Duration: time.Since(start)}
+		checks = append(checks, check)
+		icon := "✅"
+		if err != nil {
+			icon, detail = "❌", err.Error()
+		}
+		fmt.Printf("  %s %-34s %8s  %s\n", icon, tool.Name, check.Duration.Round(time.Millisecond), truncate(detail, 80))
+	}
+	for _, name := range selected {
+		if !slices.ContainsFunc(tools, func(tool MCPTool) bool { return tool.Name == name }) {
+			checks = append(checks, smokeCheck{Name: name, Err: errors.New("not advertised")})
+			fmt.Printf("  ❌ %-34s %8s  not advertised\n", name, "-")
+		}
+	}
+	return checks
+}
+
+// loadToolArguments reads an --args-file mapping tool names to arguments;
+// an empty path gives no overrides
+func loadToolArguments(path string) (map[string]map[string]any, error) {
+	overrides := map[string]map[string]any{}
+	if path == "" {
+		return overrides, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: --args-file: %v", errUsage, err)
+	}
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		return nil, fmt.Errorf("%w: --args-file must map tool names to argument objects: %v", errUsage, err)
+	}
+	return overrides, nil
+}
+
+// callToolChecked calls a tool and treats isError, empty results and error
+// text as failures. It returns the first line of the answer.
+func callToolChecked(mcp *MCPClient, name string, arguments map[string]any) (string, error) {
+	result, err := mcp.CallTool(commandContext, name, arguments)
+	if err != nil {
+		return "", errors.New(describeMCPError(err))
+	}
+	text := strings.TrimSpace(result.Text())
+	firstLine, _, _ := strings.Cut(text, "\n")
+	if result.IsError {
+		return "", fmt.Errorf("isError: %s", firstLine)
+	}
+	if text == "" && len(result.Content) == 0 {
+		return "", errors.New("empty result")
+	}
+	for _, prefix := range toolErrorPrefixes {
+		if strings.HasPrefix(text, prefix) {
+			return "", errors.New(firstLine)
+		}
+	}
+	return firstLine, nil
+}
+
+// toolArguments returns the canned arguments of a tool, or fills its
+// required parameters with placeholder values of the declared type
+func toolArguments(tool MCPTool) map[string]any {
+	if arguments, ok := cannedToolArguments[tool.Name]; ok {
+		return arguments
+	}
+	var schema struct {
+		Properties map[string]struct {
+			Type string `json:"type"`
+		} `json:"properties"`
+		Required []string `json:"required"`
+	}
+	arguments := map[string]any{}
+	if json.Unmarshal(tool.InputSchema, &schema) != nil {
+		return arguments
+	}
+	for _, name := range schema.Required {
+		switch schema.Properties[name].Type {
+		case "integer", "number":
+			arguments[name] = 1
+		case "boolean":
+			arguments[name] = false
+		case "array":
+			arguments[name] = []string{"Vitamin D"}
+		case "object":
+			arguments[name] = map[string]any{}
+		default:
+			arguments[name] = "Vitamin D"
+		}
+	}
+	return arguments
+}
+
+// truncate shortens s to n runes for table cells
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
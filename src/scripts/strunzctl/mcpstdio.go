@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/longevitycoach/StrunzKnowledge/src/scripts/strunzctl/pkg/mcpclient"
+)
+
+// defaultStdioCommand starts the server the way Claude Desktop's config does
+var defaultStdioCommand = []string{"python", "-m", "src.mcp.server"}
+
+// startMCPStdio launches command and returns a client speaking MCP over its
+// stdin and stdout. The server is asked to exit when commandContext ends.
+func startMCPStdio(command []string, dir string, timeout time.Duration) (*MCPClient, *mcpclient.StdioServer, error) {
+	client, server, err := mcpclient.StartStdio(commandContext, command, dir, timeout)
+	if err != nil {
+		return nil, nil, err
+	}
+	client.ClientInfo = mcpClientInfo
+	return client, server, nil
+}
+
+func newMCPStdioTestCommand() *Command {
+	cmd := newCommand("stdio-test", "[-- command args...]", "Launch the server as a subprocess and run the handshake and tool checks over stdio, as Claude Desktop does.")
+	expect := cmd.Flags.String("expect", smokeTool, "comma-separated tools that must be advertised")
+	call := cmd.Flags.String("call", smokeTool, "comma-separated tools to call with canned arguments (\"\" for none)")
+	dir := cmd.Flags.String("dir", "", "working directory of the server (default: current directory)")
+	timeout := cmd.Flags.Duration("timeout", 2*time.Minute, "timeout for each request; the first includes loading the indices")
+
+	cmd.Run = func(args []string) error {
+		command := args
+		if len(command) == 0 {
+			command = defaultStdioCommand
+		}
+
+		fmt.Printf("\n🔌 MCP stdio check of %s\n", strings.Join(command, " "))
+		start := time.Now()
+		mcp, server, err := startMCPStdio(command, *dir, *timeout)
+		if err != nil {
+			return err
+		}
+		defer mcp.Close()
+		fmt.Printf("  ✅ started process %d\n", server.Pid())
+
+		problems, tools := checkMCPSession(mcp, splitList(*expect))
+		for _, name := range splitList(*call) {
+			if tools == nil {
+				break
+			}
+			index := slices.IndexFunc(tools, func(tool MCPTool) bool { return tool.Name == name })
+			if index < 0 {
+				problems++
+				fmt.Printf("  ❌ tools/call %s: not advertised\n", name)
+				continue
+			}
+			callStart := time.Now()
+			detail, err := callToolChecked(mcp, name, toolArguments(tools[index]))
+			if err != nil {
+				problems++
+				fmt.Printf("  ❌ tools/call %s: %v\n", name, err)
+				continue
+			}
+			fmt.Printf("  ✅ tools/call %s: %s (%s)\n", name, truncate(detail, 60), time.Since(callStart).Round(time.Millisecond))
+		}
+
+		if noise := server.Noise(); len(noise) > 0 {
+			problems++
+			fmt.Printf("  ❌ %d non-JSON line(s) on stdout; log to stderr instead:\n", len(noise))
+			for _, line := range noise[:min(len(noise), 5)] {
+				fmt.Printf("       %s\n", truncate(line, 100))
+			}
+		}
+		if mcp.Disconnected() {
+			problems++
+			fmt.Printf("  ❌ %v\n", mcp.Err())
+		}
+		if problems > 0 {
+			if lines := server.Stderr(20); len(lines) > 0 {
+				fmt.Println("\nLast lines on stderr:")
+				for _, line := range lines {
+					fmt.Printf("  %s\n", line)
+				}
+			}
+			return fmt.Errorf("%w: stdio server failed %d MCP check(s)", errPolicy, problems)
+		}
+		fmt.Printf("\n✅ stdio handshake and tool calls work (%s)\n", time.Since(start).Round(time.Millisecond))
+		return nil
+	}
+	return cmd
+}
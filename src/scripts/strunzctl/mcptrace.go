@@ -0,0 +1,288 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// traceParentHeader matches a W3C Trace Context traceparent of version 00
+// and later: version, trace ID, parent span ID and flags
+var traceParentHeader = regexp.MustCompile(`^([0-9a-f]{2})-([0-9a-f]{32})-([0-9a-f]{16})-([0-9a-f]{2})(-.*)?$`)
+
+// traceParent is one parsed traceparent
+type traceParent struct {
+	TraceID, SpanID, Flags string
+}
+
+func (p traceParent) String() string {
+	return "00-" + p.TraceID + "-" + p.SpanID + "-" + p.Flags
+}
+
+// newTraceParent starts a sampled trace with random IDs
+func newTraceParent() (traceParent, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return traceParent{}, fmt.Errorf("failed to generate a trace ID: %w", err)
+	}
+	return traceParent{TraceID: hex.EncodeToString(b[:16]), SpanID: hex.EncodeToString(b[16:]), Flags: "01"}, nil
+}
+
+// parseTraceParent validates a traceparent as the spec requires: version
+// ff and all-zero IDs are invalid, and only version 00 may not carry more
+func parseTraceParent(value string) (traceParent, bool) {
+	match := traceParentHeader.FindStringSubmatch(strings.TrimSpace(value))
+	if match == nil || match[1] == "ff" || (match[1] == "00" && match[5] != "") {
+		return traceParent{}, false
+	}
+	if strings.Trim(match[2], "0") == "" || strings.Trim(match[3], "0") == "" {
+		return traceParent{}, false
+	}
+	return traceParent{TraceID: match[2], SpanID: match[3], Flags: match[4]}, true
+}
+
+// traceProbe is one request sent with a traceparent and what came back
+type traceProbe struct {
+	Name   string
+	Sent   traceParent
+	Header http.Header
+	Err    error
+}
+
+// propagation judges the response headers of a probe. The server answers
+// with traceresponse (Trace Context Level 2) or its own traceparent; both
+// must keep the trace ID and name the server's span.
+func (p traceProbe) propagation() (oauthResult, string, bool) {
+	for _, name := range []string{"traceresponse", "traceparent"} {
+		value := p.Header.Get(name)
+		if value == "" {
+			continue
+		}
+		got, ok := parseTraceParent(value)
+		switch {
+		case !ok:
+			return oauthFail, fmt.Sprintf("invalid %s %q", name, value), true
+		case got.TraceID != p.Sent.TraceID:
+			return oauthFail, fmt.Sprintf("%s starts a new trace %s instead of continuing %s", name, got.TraceID, p.Sent.TraceID), true
+		case got.SpanID == p.Sent.SpanID:
+			return oauthWarn, fmt.Sprintf("%s echoes the request's span; the server records no span of its own", name), true
+		}
+		detail := fmt.Sprintf("%s continues trace %s in span %s", name, truncate(got.TraceID, 12), got.SpanID)
+		if state := p.Header.Get("tracestate"); state != "" && !strings.Contains(state, "strunzctl=") {
+			return oauthWarn, detail + ", but tracestate dropped the strunzctl entry", true
+		}
+		return oauthPass, detail, true
+	}
+	return oauthFail, "", false
+}
+
+// traceTester sends every kind of request the server handles with its own
+// trace and collects the responses
+type traceTester struct {
+	*reconnectTester
+	header http.Header
+	probes []*traceProbe
+}
+
+// next starts the trace of the next request
+func (t *traceTester) next(name string) (*traceProbe, error) {
+	parent, err := newTraceParent()
+	if err != nil {
+		return nil, err
+	}
+	probe := &traceProbe{Name: name, Sent: parent}
+	t.header.Set("traceparent", parent.String())
+	t.header.Set("tracestate", fmt.Sprintf("strunzctl=%d", len(t.probes)+1))
+	t.probes = append(t.probes, probe)
+	return probe, nil
+}
+
+// get sends a GET and keeps the response headers without reading the
+// body, which for /sse never ends
+func (t *traceTester) get(probe *traceProbe, path string) {
+	ctx, cancel := context.WithTimeout(context.Background(), t.timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(t.serverURL, "/")+path, nil)
+	if err != nil {
+		probe.Err = err
+		return
+	}
+	req.Header = t.header.Clone()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		probe.Err = err
+		return
+	}
+	resp.Body.Close()
+	probe.Header = resp.Header
+	if resp.StatusCode/100 != 2 {
+		probe.Err = &HTTPStatusError{Endpoint: path, Status: resp.StatusCode, Header: resp.Header}
+	}
+}
+
+// session sends the MCP requests, each message with its own traceparent.
+// Only a server that cannot be reached is an error.
+func (t *traceTester) session() error {
+	// The stream request was probed by GET /sse already
+	t.header.Del("traceparent")
+	t.header.Del("tracestate")
+	mcp, err := connectMCPWithHeader(t.serverURL, t.timeout, t.header)
+	if err != nil {
+		return err
+	}
+	defer mcp.Close()
+	var current *traceProbe
+	mcp.OnResponse = func(resp *http.Response) {
+		if current != nil {
+			current.Header = resp.Header
+		}
+	}
+
+	requests := []struct {
+		name string
+		call func() error
+	}{
+		{"initialize", func() error { _, err := mcp.Initialize(commandContext); return err }},
+		{"tools/list", func() error { _, err := mcp.ListTools(commandContext); return err }},
+		{"tools/call " + smokeTool, func() error {
+			_, err := mcp.CallTool(commandContext, smokeTool, cannedToolArguments[smokeTool])
+			return err
+		}},
+	}
+	for _, request := range requests {
+		if current, err = t.next(request.name); err != nil {
+			return err
+		}
+		current.Err = request.call()
+	}
+	return nil
+}
+
+// tracesInLogs reports which trace IDs appear in the deployment's logs
+func tracesInLogs(lines []RailwayLog, probes []*traceProbe) map[string]string {
+	found := make(map[string]string)
+	for _, line := range lines {
+		for _, probe := range probes {
+			if _, ok := found[probe.Sent.TraceID]; !ok && strings.Contains(line.Message, probe.Sent.TraceID) {
+				found[probe.Sent.TraceID] = line.Message
+			}
+		}
+	}
+	return found
+}
+
+func newMCPTraceTestCommand() *Command {
+	cmd := newCommand("trace-test", "[url]", "Send HTTP and MCP requests with W3C traceparent headers and check the server continues the traces in its response headers or logs.")
+	logs := cmd.Flags.Bool("logs", false, "also search the Railway deployment logs for the trace IDs")
+	service := cmd.Flags.String("service", "", "Railway service name or ID with --logs (default: railway.service)")
+	deployment := cmd.Flags.String("deployment", "", "Railway deployment ID with --logs (default: the latest one)")
+	logDelay := cmd.Flags.Duration("log-delay", 15*time.Second, "how long to wait for the logs to reach Railway")
+	limit := cmd.Flags.Int("limit", 1000, "number of Railway log lines to search")
+	token := cmd.Flags.String("token", "", "bearer token to send")
+	timeout := cmd.Flags.Duration("timeout", 30*time.Second, "timeout for connecting and for each request")
+	junit := cmd.Flags.String("junit", "", "write a JUnit XML report to this file")
+
+	cmd.Run = func(args []string) error {
+		if len(args) > 1 {
+			return cmd.ExactArgs(args, 1)
+		}
+		serverURL := defaultServerURL
+		if len(args) == 1 {
+			serverURL = args[0]
+		}
+		var railway *RailwayClient
+		if *logs {
+			var err error
+			if railway, err = newRailwayClient(); err != nil {
+				return err
+			}
+		}
+		t := &traceTester{reconnectTester: &reconnectTester{serverURL: serverURL, timeout: *timeout}, header: http.Header{}}
+		if *token != "" {
+			t.header.Set("Authorization", "Bearer "+*token)
+		}
+
+		fmt.Printf("\n🧵 Trace propagation of %s\n", serverURL)
+		for _, path := range []string{"/health", "/sse"} {
+			probe, err := t.next("GET " + path)
+			if err != nil {
+				return err
+			}
+			t.get(probe, path)
+		}
+		if err := t.session(); err != nil {
+			return err
+		}
+
+		var logged map[string]string
+		if *logs {
+			id := *deployment
+			if id == "" {
+				var err error
+				if id, err = latestDeploymentID(railway, *service); err != nil {
+					return err
+				}
+			}
+			fmt.Printf("⏳ Waiting %s for the logs of deployment %s\n", *logDelay, id)
+			time.Sleep(*logDelay)
+			lines, err := railway.DeploymentLogs(id, *limit)
+			if err != nil {
+				return err
+			}
+			logged = tracesInLogs(lines, t.probes)
+		}
+
+		t.begin("Propagation")
+		for _, probe := range t.probes {
+			result, detail, answered := probe.propagation()
+			line, inLogs := logged[probe.Sent.TraceID]
+			switch {
+			case answered && result != oauthPass && inLogs:
+				t.record(probe.Name, oauthWarn, "%s; logged: %s", detail, truncate(line, 60))
+			case answered:
+				t.record(probe.Name, result, "%s", detail)
+			case inLogs:
+				t.record(probe.Name, oauthPass, "trace %s logged: %s", truncate(probe.Sent.TraceID, 12), truncate(line, 60))
+			case *logs:
+				t.record(probe.Name, oauthFail, "trace %s is neither in the response headers nor in the logs", probe.Sent.TraceID)
+			default:
+				t.record(probe.Name, oauthFail, "no traceresponse or traceparent header (search the logs with --logs)")
+			}
+			if probe.Err != nil {
+				t.record(probe.Name+" answer", oauthWarn, "%s", describeMCPError(probe.Err))
+			}
+		}
+
+		// Invalid headers must start a new trace, not fail the request
+		t.begin("Invalid traceparent")
+		const zeroTrace = "00000000000000000000000000000000"
+		t.header.Set("traceparent", "00-"+zeroTrace+"-0000000000000000-01")
+		t.header.Del("tracestate")
+		invalid := &traceProbe{Name: "GET /health"}
+		t.get(invalid, "/health")
+		switch {
+		case invalid.Err != nil:
+			t.record("all-zero IDs", oauthFail, "%s", errorClass(invalid.Err))
+		case strings.Contains(invalid.Header.Get("traceresponse")+invalid.Header.Get("traceparent"), zeroTrace):
+			t.record("all-zero IDs", oauthFail, "the invalid trace was continued")
+		default:
+			t.record("all-zero IDs", oauthPass, "ignored as the spec requires")
+		}
+
+		failed, err := t.report("mcp trace-test "+serverURL, *junit)
+		if err != nil {
+			return err
+		}
+		if failed > 0 {
+			return fmt.Errorf("%w: %d of %d trace check(s) failed", errPolicy, failed, len(t.checks))
+		}
+		fmt.Println("\n✅ The server continues the traces of its callers")
+		return nil
+	}
+	return cmd
+}
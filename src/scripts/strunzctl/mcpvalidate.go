@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// contentBlockFields are the fields each content block type requires
+var contentBlockFields = map[string][]string{
+	"text":          {"text"},
+	"image":         {"data", "mimeType"},
+	"audio":         {"data", "mimeType"},
+	"resource":      {"resource"},
+	"resource_link": {"uri", "name"},
+}
+
+// rawToolResult is a tools/call result kept raw for validation
+type rawToolResult struct {
+	Content           []map[string]json.RawMessage `json:"content"`
+	StructuredContent json.RawMessage              `json:"structuredContent"`
+	IsError           bool                         `json:"isError"`
+}
+
+func newMCPValidateCommand() *Command {
+	cmd := newCommand("validate", "[url]", "Call every tool and validate arguments and results against the declared input and output schemas.")
+	only := cmd.Flags.String("only", "", "comma-separated tools to validate (default: all advertised)")
+	skip := cmd.Flags.String("skip", "", "comma-separated tools not to validate")
+	argsFile := cmd.Flags.String("args-file", "", "JSON object mapping tool names to arguments, overriding the canned ones")
+	timeout := cmd.Flags.Duration("timeout", 60*time.Second, "timeout for connecting and for each tool call")
+	junit := cmd.Flags.String("junit", "", "write a JUnit XML report to this file")
+
+	cmd.Run = func(args []string) error {
+		if len(args) > 1 {
+			return cmd.ExactArgs(args, 1)
+		}
+		serverURL := defaultServerURL
+		if len(args) == 1 {
+			serverURL = args[0]
+		}
+		overrides, err := loadToolArguments(*argsFile)
+		if err != nil {
+			return err
+		}
+
+		mcp, err := connectMCP(serverURL, *timeout)
+		if err != nil {
+			return err
+		}
+		defer mcp.Close()
+		if _, err := mcp.Initialize(commandContext); err != nil {
+			return fmt.Errorf("initialize failed: %s", describeMCPError(err))
+		}
+		tools, err := mcp.ListTools(commandContext)
+		if err != nil {
+			return fmt.Errorf("tools/list failed: %s", describeMCPError(err))
+		}
+
+		selected := splitList(*only)
+		skipped := splitList(*skip)
+		var checks []smokeCheck
+		fmt.Printf("\n🧾 Validating %d tool(s) of %s against their schemas\n\n", len(tools), serverURL)
+		for _, tool := range tools {
+			if (len(selected) > 0 && !slices.Contains(selected, tool.Name)) || slices.Contains(skipped, tool.Name) {
+				continue
+			}
+			arguments, ok := overrides[tool.Name]
+			if !ok {
+				arguments = toolArguments(tool)
+			}
+			start := time.Now()
+			problems, summary := validateTool(mcp, tool, arguments)
+			check := smokeCheck{Name: tool.Name, Duration: time.Since(start)}
+			if len(problems) > 0 {
+				check.Err = errors.New(strings.Join(problems, "\n"))
+				fmt.Printf("  ❌ %-34s %d problem(s)\n", tool.Name, len(problems))
+				for _, problem := range problems {
+					fmt.Printf("       %s\n", truncate(problem, 120))
+				}
+			} else {
+				fmt.Printf("  ✅ %-34s %s\n", tool.Name, summary)
+			}
+			checks = append(checks, check)
+		}
+
+		failed := 0
+		for _, check := range checks {
+			if check.Err != nil {
+				failed++
+			}
+		}
+		if *junit != "" {
+			if err := writeJUnitReport(*junit, "mcp validate "+serverURL, checks); err != nil {
+				return err
+			}
+			fmt.Printf("\nJUnit report written to %s\n", *junit)
+		}
+		if failed > 0 {
+			return fmt.Errorf("%w: %d of %d tool(s) do not match their schemas", errPolicy, failed, len(checks))
+		}
+		fmt.Printf("\n✅ All %d tool(s) match their schemas\n", len(checks))
+		return nil
+	}
+	return cmd
+}
+
+// validateTool checks the schemas of a tool, the arguments sent to it and
+// the result it returns. It returns the problems found and, if there are
+// none, a short summary.
+func validateTool(mcp *MCPClient, tool MCPTool, arguments map[string]any) ([]string, string) {
+	var problems []string
+	inputSchema, err := parseJSONSchema(tool.InputSchema)
+	if err != nil {
+		problems = append(problems, "inputSchema: "+err.Error())
+	} else {
+		data, _ := json.Marshal(arguments)
+		violations, _ := validateJSON(inputSchema, data)
+		for _, violation := range violations {
+			problems = append(problems, "arguments "+violation)
+		}
+	}
+	var outputSchema *jsonSchema
+	if len(tool.OutputSchema) > 0 {
+		if outputSchema, err = parseJSONSchema(tool.OutputSchema); err != nil {
+			problems = append(problems, "outputSchema: "+err.Error())
+		}
+	}
+
+	var raw json.RawMessage
+	if err := mcp.Request(commandContext, "tools/call", map[string]any{"name": tool.Name, "arguments": arguments}, &raw); err != nil {
+		return append(problems, "tools/call: "+describeMCPError(err)), ""
+	}
+	var result rawToolResult
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return append(problems, "result is not a CallToolResult: "+err.Error()), ""
+	}
+	problems = append(problems, contentProblems(result.Content)...)
+
+	summary := fmt.Sprintf("%d content block(s)", len(result.Content))
+	switch {
+	case result.IsError:
+		summary += ", isError (output schema not checked)"
+	case outputSchema != nil && len(result.StructuredContent) == 0:
+		problems = append(problems, "outputSchema is declared but the result has no structuredContent")
+	case outputSchema != nil:
+		violations, err := validateJSON(outputSchema, result.StructuredContent)
+		if err != nil {
+			problems = append(problems, "structuredContent: "+err.Error())
+		}
+		for _, violation := range violations {
+			problems = append(problems, "structuredContent "+violation)
+		}
+		summary += ", structuredContent matches outputSchema"
+	case len(result.StructuredContent) > 0:
+		summary += ", structuredContent without an outputSchema"
+	}
+	return problems, summary
+}
+
+// contentProblems checks that every content block has a known type and
+// the fields that type requires
+func contentProblems(content []map[string]json.RawMessage) []string {
+	var problems []string
+	for i, block := range content {
+		var blockType string
+		json.Unmarshal(block["type"], &blockType)
+		fields, ok := contentBlockFields[blockType]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("content/%d: unknown type %q", i, blockType))
+			continue
+		}
+		for _, field := range fields {
+			if _, ok := block[field]; !ok {
+				problems = append(problems, fmt.Sprintf("content/%d: %s block without %s", i, blockType, field))
+			}
+		}
+		var text string
+		if blockType == "text" && len(block["text"]) > 0 && json.Unmarshal(block["text"], &text) != nil {
+			problems = append(problems, fmt.Sprintf("content/%d: text is %s, expected a string", i, truncate(string(block["text"]), 40)))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+func newImageMirrorCommand() *Command {
+	cmd := newCommand("mirror", "<tag>", "Copy an image with all platforms and blobs to a secondary registry.")
+	to := cmd.Flags.String("to", "", "destination repository, optionally with :tag (default: mirror from config, same tag as source)")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 1); err != nil {
+			return err
+		}
+		tag := args[0]
+
+		if *to == "" {
+			*to = config.Mirror
+		}
+		host, repository, destTag, err := parseImageReference(*to)
+		if err != nil {
+			return err
+		}
+		if destTag == "" {
+			destTag = tag
+		}
+		source := newRegistryClient(config.Registry, imageRepository())
+		dest := newRegistryClient(host, repository)
+
+		fmt.Printf("\n🪞 Mirroring %s → %s\n", source.Reference(tag), dest.Reference(destTag))
+		stats := &mirrorStats{progress: startProgress("Mirroring "+tag, 0)}
+		digest, err := mirrorManifest(source, dest, tag, destTag, stats)
+		stats.progress.Finish()
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("\n✅ Mirrored %s (%s)\n", dest.Reference(destTag), digest)
+		fmt.Printf("Blobs copied: %d (%s), already present: %d\n", stats.copied, formatBytes(stats.copiedBytes), stats.skipped)
+		return nil
+	}
+	return cmd
+}
+
+type mirrorStats struct {
+	copied, skipped int
+	copiedBytes     int64
+	// progress counts the blobs done and the bytes uploaded
+	progress *progress
+}
+
+// mirrorManifest copies a manifest and everything it references, children
+// first, so the destination never holds a manifest with missing content
+func mirrorManifest(source, dest *RegistryClient, reference, destReference string, stats *mirrorStats) (string, error) {
+	raw, err := source.GetRawManifest(reference)
+	if err != nil {
+		return "", err
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(raw.Body, &manifest); err != nil {
+		return "", fmt.Errorf("failed to parse manifest %s: %w", reference, err)
+	}
+
+	for _, child := range manifest.Manifests {
+		if _, err := mirrorManifest(source, dest, child.Digest, child.Digest, stats); err != nil {
+			return "", err
+		}
+	}
+
+	blobs := manifest.Layers
+	if manifest.Config.Digest != "" {
+		blobs = append([]Descriptor{manifest.Config}, blobs...)
+	}
+	for _, blob := range blobs {
+		if err := mirrorBlob(source, dest, blob, stats); err != nil {
+			return "", err
+		}
+	}
+
+	digest, err := dest.PutManifest(destReference, raw)
+	if err != nil {
+		return "", fmt.Errorf("failed to push manifest %s: %w", destReference, err)
+	}
+	return digest, nil
+}
+
+func mirrorBlob(source, dest *RegistryClient, blob Descriptor, stats *mirrorStats) error {
+	exists, err := dest.BlobExists(blob.Digest)
+	if err != nil {
+		return err
+	}
+	if exists {
+		stats.skipped++
+		stats.progress.Add(1)
+		return nil
+	}
+
+	reader, size, err := source.OpenBlob(blob.Digest)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	if size < 0 {
+		size = blob.Size
+	}
+
+	progressPrintf("  ⬆️  %s (%s)\n", shortDigest(blob.Digest), formatBytes(size))
+	if err := dest.UploadBlob(blob.Digest, size, stats.progress.Reader(reader)); err != nil {
+		return fmt.Errorf("failed to copy blob %s: %w", blob.Digest, err)
+	}
+	stats.copied++
+	stats.copiedBytes += size
+	stats.progress.Add(1)
+	return nil
+}
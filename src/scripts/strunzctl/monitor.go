@@ -0,0 +1,220 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+func newMonitorCommand() *Command {
+	return newGroup("monitor", "Watch a running server and alert when it stops answering.",
+		newMonitorRunCommand(),
+		newMonitorExportCommand(),
+		newMonitorQualityCommand(),
+		newMonitorReportCommand(),
+		newMonitorDashboardsCommand(),
+	)
+}
+
+// monitorProbe is the outcome of one probe of a server
+type monitorProbe struct {
+	At     time.Time
+	Checks []smokeCheck
+	// Version is the version /health reported, if it answered
+	Version string
+}
+
+// Failed returns the first failed check, or nil
+func (p monitorProbe) Failed() *smokeCheck {
+	for i := range p.Checks {
+		if p.Checks[i].Err != nil {
+			return &p.Checks[i]
+		}
+	}
+	return nil
+}
+
+// probeServer checks /health, performs the MCP handshake and runs one
+// search, skipping the later checks once one fails
+func probeServer(serverURL string, timeout time.Duration) monitorProbe {
+	probe := monitorProbe{At: time.Now()}
+	run := func(name string, check func() error) bool {
+		start := time.Now()
+		err := check()
+		probe.Checks = append(probe.Checks, smokeCheck{Name: name, Err: err, Duration: time.Since(start)})
+		return err == nil
+	}
+
+	if !run("health", func() error {
+		health, err := fetchHealth(serverURL)
+		if err == nil {
+			probe.Version = health.Version
+		}
+		if err == nil && health.Status != "ok" && health.Status != "healthy" {
+			err = fmt.Errorf("status is %q", health.Status)
+		}
+		return err
+	}) {
+		return probe
+	}
+
+	var mcp *MCPClient
+	ok := run("handshake", func() error {
+		var err error
+		if mcp, err = connectMCP(serverURL, timeout); err != nil {
+			return err
+		}
+		if _, err := mcp.Initialize(commandContext); err != nil {
+			return errors.New(describeMCPError(err))
+		}
+		tools, err := mcp.ListTools(commandContext)
+		if err != nil {
+			return errors.New(describeMCPError(err))
+		}
+		if len(tools) == 0 {
+			return errors.New("no tools advertised")
+		}
+		return nil
+	})
+	if mcp != nil {
+		defer mcp.Close()
+	}
+	if !ok {
+		return probe
+	}
+
+	call := smokeToolCalls[0]
+	run("search", func() error {
+		_, err := callToolChecked(mcp, call.Tool, call.Arguments)
+		return err
+	})
+	return probe
+}
+
+// monitorState tracks consecutive failures and whether an alert is out
+type monitorState struct {
+	failures  int
+	alerted   bool
+	downSince time.Time
+	lastAlert time.Time
+}
+
+// observe records a probe and returns the alert to send, if any
+func (s *monitorState) observe(serverURL string, probe monitorProbe, threshold int, realert time.Duration) (title, message string) {
+	failed := probe.Failed()
+	if failed == nil {
+		if s.alerted {
+			title = "✅ Server recovered"
+			message = fmt.Sprintf("`%s` answers again after %s down (%d failed probes).", serverURL, formatAge(probe.At.Sub(s.downSince)), s.failures)
+		}
+		*s = monitorState{}
+		return title, message
+	}
+
+	if s.failures == 0 {
+		s.downSince = probe.At
+	}
+	s.failures++
+	switch {
+	case s.failures < threshold:
+		return "", ""
+	case !s.alerted:
+		title = "🚨 Server down"
+	case realert > 0 && probe.At.Sub(s.lastAlert) >= realert:
+		title = "🚨 Server still down"
+	default:
+		return "", ""
+	}
+	s.alerted, s.lastAlert = true, probe.At
+	message = fmt.Sprintf("`%s` failed %d consecutive probes since %s: %s: %v",
+		serverURL, s.failures, s.downSince.Format(time.RFC3339), failed.Name, failed.Err)
+	return title, message
+}
+
+func newMonitorRunCommand() *Command {
+	cmd := newCommand("run", "[url]", "Probe /health, the MCP handshake and a search on an interval and alert after consecutive failures.")
+	interval := cmd.Flags.Duration("interval", time.Minute, "time between probes")
+	threshold := cmd.Flags.Int("threshold", 3, "consecutive failed probes before alerting")
+	realert := cmd.Flags.Duration("realert", time.Hour, "repeat the alert this often while the server stays down (0: never)")
+	timeout := cmd.Flags.Duration("timeout", 30*time.Second, "timeout for connecting and for each request")
+	count := cmd.Flags.Int("count", 0, "stop after this many probes (default: run until stopped)")
+	notify := cmd.Flags.Bool("notify-webhook", false, "post alerts and recoveries to webhooks.notify and the alerts sinks")
+	record := cmd.Flags.Bool("record", true, "append every probe to the history `monitor report` reads")
+	history := cmd.Flags.String("history", "", "probe history file (default: one per server in the strunzctl state directory)")
+
+	cmd.Run = func(args []string) error {
+		if len(args) > 1 {
+			return cmd.ExactArgs(args, 1)
+		}
+		serverURL := defaultServerURL
+		if len(args) == 1 {
+			serverURL = args[0]
+		}
+		if *interval < 10*time.Second {
+			return fmt.Errorf("%w: --interval must be at least 10s", errUsage)
+		}
+		if *threshold < 1 {
+			return fmt.Errorf("%w: --threshold must be at least 1", errUsage)
+		}
+		notifier, err := newNotifier(*notify)
+		if err != nil {
+			return err
+		}
+
+		path := *history
+		if *record && path == "" {
+			if path, err = probeHistoryPath(serverURL); err != nil {
+				return err
+			}
+		}
+
+		fmt.Printf("\n🩺 Monitoring %s every %s, alerting after %d failed probe(s) (Ctrl-C to stop)\n", serverURL, *interval, *threshold)
+		state := &monitorState{}
+		var probe monitorProbe
+		for n := 1; *count == 0 || n <= *count; n++ {
+			if n > 1 {
+				if err := sleepContext(*interval); err != nil {
+					return err
+				}
+			}
+			probe = probeServer(serverURL, *timeout)
+			printMonitorProbe(probe, state.failures)
+			if *record {
+				if err := appendProbeHistory(path, serverURL, probe); err != nil {
+					slog.Warn("Could not record the probe", "path", path, "error", err)
+				}
+			}
+			if title, message := state.observe(serverURL, probe, *threshold, *realert); title != "" {
+				fmt.Printf("[%s] %s\n", probe.At.Format(time.TimeOnly), title)
+				if probe.Failed() == nil {
+					notifier.resolve("down "+serverURL, title, message)
+				} else {
+					notifier.alert(severityCritical, "down "+serverURL, title, message)
+				}
+			}
+		}
+		if failed := probe.Failed(); failed != nil {
+			return fmt.Errorf("%w: last probe failed: %s: %v", errPolicy, failed.Name, failed.Err)
+		}
+		return nil
+	}
+	return cmd
+}
+
+// printMonitorProbe prints one line per probe; previous is the number of
+// failures before it
+func printMonitorProbe(probe monitorProbe, previous int) {
+	icon := "✅"
+	var parts []string
+	for _, check := range probe.Checks {
+		if check.Err != nil {
+			icon = "❌"
+			parts = append(parts, fmt.Sprintf("%s: %s (%d in a row)", check.Name, truncate(errorClass(check.Err), 100), previous+1))
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s %s", check.Name, check.Duration.Round(time.Millisecond)))
+	}
+	fmt.Printf("[%s] %s %s\n", probe.At.Format(time.TimeOnly), icon, strings.Join(parts, "  "))
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// grafanaDatasource points panels at the dashboard's datasource variable
+var grafanaDatasource = map[string]string{"type": "prometheus", "uid": "${datasource}"}
+
+// dashboardSettings parameterize the generated dashboard
+type dashboardSettings struct {
+	Title, UID, Datasource, Server, Refresh string
+	// MaxBehind and MaxLatency turn the drift and latency panels red
+	MaxBehind  int
+	MaxLatency time.Duration
+}
+
+// dashboardLayout places panels left to right, 24 grid units per line
+type dashboardLayout struct {
+	panels []map[string]any
+	x, y   int
+	height int
+}
+
+func (l *dashboardLayout) add(panel map[string]any, width, height int) {
+	if l.x+width > 24 {
+		l.x, l.y, l.height = 0, l.y+l.height, 0
+	}
+	panel["id"] = len(l.panels) + 1
+	panel["gridPos"] = map[string]int{"x": l.x, "y": l.y, "w": width, "h": height}
+	l.panels = append(l.panels, panel)
+	l.x += width
+	l.height = max(l.height, height)
+}
+
+func (l *dashboardLayout) row(title string) {
+	if l.x > 0 {
+		l.x, l.y, l.height = 0, l.y+l.height, 0
+	}
+	l.add(map[string]any{"type": "row", "title": title, "collapsed": false, "panels": []any{}}, 24, 1)
+	l.x, l.y, l.height = 0, l.y+1, 0
+}
+
+// promTarget is one PromQL query; instant queries feed the stat panels
+func promTarget(expr, legend string, instant bool) map[string]any {
+	return map[string]any{"datasource": grafanaDatasource, "expr": expr, "legendFormat": legend, "instant": instant, "range": !instant}
+}
+
+// thresholdSteps colors values from base up to each limit's color
+func thresholdSteps(base string, limits ...any) map[string]any {
+	steps := []map[string]any{{"color": base, "value": nil}}
+	for i := 0; i+1 < len(limits); i += 2 {
+		steps = append(steps, map[string]any{"color": limits[i], "value": limits[i+1]})
+	}
+	return map[string]any{"mode": "absolute", "steps": steps}
+}
+
+func grafanaPanel(kind, title, description, unit string, thresholds map[string]any, targets ...map[string]any) map[string]any {
+	defaults := map[string]any{"unit": unit, "thresholds": thresholds}
+	for i, target := range targets {
+		target["refId"] = string(rune('A' + i))
+	}
+	panel := map[string]any{
+		"type": kind, "title": title, "description": description, "datasource": grafanaDatasource,
+		"targets": targets, "fieldConfig": map[string]any{"defaults": defaults, "overrides": []any{}},
+	}
+	switch kind {
+	case "stat":
+		panel["options"] = map[string]any{"colorMode": "background", "graphMode": "none", "textMode": "value",
+			"reduceOptions": map[string]any{"calcs": []string{"lastNotNull"}, "fields": "", "values": false}}
+	case "timeseries":
+		defaults["custom"] = map[string]any{"drawStyle": "line", "lineWidth": 1, "fillOpacity": 10, "showPoints": "never",
+			"thresholdsStyle": map[string]string{"mode": "line"}}
+		panel["options"] = map[string]any{"legend": map[string]any{"displayMode": "list", "placement": "bottom", "showLegend": true},
+			"tooltip": map[string]string{"mode": "multi", "sort": "desc"}}
+	}
+	return panel
+}
+
+// buildDashboard renders the health, latency, tool error and version drift
+// panels over the metrics monitor export serves
+func buildDashboard(s dashboardSettings) map[string]any {
+	server := `server=~"$server"`
+	layout := &dashboardLayout{}
+
+	layout.row("Health")
+	up := grafanaPanel("stat", "Server", "Whether the last probe of health, MCP handshake and search passed.", "none",
+		thresholdSteps("red", "green", 1), promTarget(fmt.Sprintf("min(%s{%s})", metricUp, server), "", true))
+	up["fieldConfig"].(map[string]any)["defaults"].(map[string]any)["mappings"] = []map[string]any{{
+		"type": "value", "options": map[string]any{"0": map[string]string{"text": "DOWN"}, "1": map[string]string{"text": "UP"}},
+	}}
+	layout.add(up, 4, 5)
+	layout.add(grafanaPanel("stat", "Last probe", "Seconds since the exporter last probed the server.", "s",
+		thresholdSteps("green", "orange", 180, "red", 600),
+		promTarget(fmt.Sprintf("time() - max(%s{%s})", metricLastProbe, server), "", true)), 4, 5)
+	layout.add(grafanaPanel("stat", "Availability", "Share of probes that passed in the dashboard's time range.", "percentunit",
+		thresholdSteps("red", "orange", 0.99, "green", 0.999),
+		promTarget(fmt.Sprintf("1 - sum(increase(%s[$__range])) / sum(increase(%s{%s}[$__range]))", metricProbeFailures, metricProbes, server), "", true)), 4, 5)
+	layout.add(grafanaPanel("timeseries", "Checks", "Checks of the last probe that passed; the ones after a failure are skipped.", "none",
+		thresholdSteps("green"), promTarget(fmt.Sprintf("%s{%s}", metricCheckUp, server), "{{check}}", false)), 12, 5)
+	layout.add(grafanaPanel("timeseries", "Failed probes", "Failed probes by the check that failed.", "short",
+		thresholdSteps("green", "red", 1), promTarget(fmt.Sprintf("sum by (check) (increase(%s[$__rate_interval]))", metricProbeFailures), "{{check}}", false)), 24, 7)
+
+	layout.row("Latency")
+	layout.add(grafanaPanel("timeseries", "Check duration", "Duration of each check; the handshake covers connect, initialize and tools/list.", "s",
+		thresholdSteps("green", "red", s.MaxLatency.Seconds()),
+		promTarget(fmt.Sprintf("%s{%s}", metricCheckDuration, server), "{{check}}", false)), 16, 8)
+	layout.add(grafanaPanel("stat", "Slowest check", "Longest check of the last probe.", "s",
+		thresholdSteps("green", "red", s.MaxLatency.Seconds()),
+		promTarget(fmt.Sprintf("max(%s{%s})", metricCheckDuration, server), "", true)), 8, 8)
+
+	layout.row("Tool errors")
+	layout.add(grafanaPanel("timeseries", "Tool error ratio", "Tool calls of the probes that failed or returned isError.", "percentunit",
+		thresholdSteps("green", "red", 0.05),
+		promTarget(fmt.Sprintf("sum by (tool) (increase(%s[$__rate_interval])) / sum by (tool) (increase(%s[$__rate_interval]))", metricToolErrors, metricToolCalls), "{{tool}}", false)), 16, 8)
+	layout.add(grafanaPanel("stat", "Tool errors", "Failed tool calls in the dashboard's time range.", "short",
+		thresholdSteps("green", "red", 1),
+		promTarget(fmt.Sprintf("sum(increase(%s[$__range]))", metricToolErrors), "", true)), 8, 8)
+
+	layout.row("Version drift")
+	deployed := grafanaPanel("stat", "Deployed", "The version the server reports on /health.", "none",
+		thresholdSteps("blue"), promTarget(fmt.Sprintf("max by (version) (%s{%s})", metricDeployedVersion, server), "{{version}}", true))
+	deployed["options"].(map[string]any)["textMode"] = "name"
+	layout.add(deployed, 5, 5)
+	latest := grafanaPanel("stat", "Latest in GHCR", "The newest semver tag in GHCR.", "none",
+		thresholdSteps("blue"), promTarget(fmt.Sprintf("max by (version) (%s)", metricLatestVersion), "{{version}}", true))
+	latest["options"].(map[string]any)["textMode"] = "name"
+	layout.add(latest, 5, 5)
+	layout.add(grafanaPanel("stat", "Releases behind", "Released image tags newer than the deployed version.", "short",
+		thresholdSteps("green", "red", s.MaxBehind+1),
+		promTarget(fmt.Sprintf("max(%s{%s})", metricReleasesBehind, server), "", true)), 5, 5)
+	layout.add(grafanaPanel("stat", "Behind for", "How long the oldest release the server lacks has been published.", "s",
+		thresholdSteps("green", "orange", 1, "red", 7*24*3600),
+		promTarget(fmt.Sprintf("max(%s{%s})", metricBehindSeconds, server), "", true)), 5, 5)
+	layout.add(grafanaPanel("stat", "Registry", "Seconds since the GHCR package versions were last fetched.", "s",
+		thresholdSteps("green", "orange", 3600, "red", 4*3600),
+		promTarget(fmt.Sprintf("time() - %s", metricRegistryLastSuccess), "", true)), 4, 5)
+	layout.add(grafanaPanel("timeseries", "GHCR versions", "Tagged and untagged package versions, and failed fetches.", "short",
+		thresholdSteps("green"),
+		promTarget(metricGHCRVersions, "{{state}}", false),
+		promTarget(fmt.Sprintf("increase(%s[$__rate_interval])", metricRegistryErrors), "fetch errors", false)), 24, 7)
+
+	datasource := map[string]any{"name": "datasource", "label": "Data source", "type": "datasource", "query": "prometheus"}
+	if s.Datasource != "" {
+		datasource["current"] = map[string]string{"text": s.Datasource, "value": s.Datasource}
+	}
+	servers := map[string]any{
+		"name": "server", "label": "Server", "type": "query", "datasource": grafanaDatasource,
+		"query":      map[string]string{"query": fmt.Sprintf("label_values(%s, server)", metricUp), "refId": "servers"},
+		"definition": fmt.Sprintf("label_values(%s, server)", metricUp),
+		"refresh":    2, "includeAll": true, "multi": true, "sort": 1,
+	}
+	if s.Server != "" {
+		servers["current"] = map[string]any{"text": []string{s.Server}, "value": []string{s.Server}}
+	}
+	return map[string]any{
+		"title": s.Title, "uid": s.UID, "tags": []string{"strunzknowledge", "strunzctl"},
+		"description":   "Generated by strunzctl monitor dashboards from the metrics of monitor export.",
+		"schemaVersion": 39, "version": 1, "editable": true, "refresh": s.Refresh, "timezone": "browser",
+		"time":       map[string]string{"from": "now-24h", "to": "now"},
+		"templating": map[string]any{"list": []any{datasource, servers}},
+		"panels":     layout.panels,
+	}
+}
+
+func newMonitorDashboardsCommand() *Command {
+	cmd := newCommand("dashboards", "", "Render the Grafana dashboard for the metrics of `monitor export`: health, latency, tool errors and version drift.")
+	title := cmd.Flags.String("title", "StrunzKnowledge MCP", "dashboard title")
+	uid := cmd.Flags.String("uid", "strunzknowledge-mcp", "dashboard UID, stable so imports replace the previous version")
+	datasource := cmd.Flags.String("datasource", "", "Prometheus datasource UID or name selected by default (default: Grafana's default)")
+	server := cmd.Flags.String("server", "", "server URL selected by default (default: all servers)")
+	refresh := cmd.Flags.String("refresh", "1m", "dashboard refresh interval")
+	maxBehind := cmd.Flags.Int("max-behind", 1, "releases the deployment may lag before the drift panel turns red, as in deploy drift")
+	maxLatency := cmd.Flags.Duration("max-latency", 2*time.Second, "check duration drawn as the latency threshold")
+	provision := cmd.Flags.Bool("provision", false, "emit the bare dashboard for file provisioning instead of the import API payload")
+	output := cmd.Flags.String("output", "", "write the JSON to this file instead of stdout")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 0); err != nil {
+			return err
+		}
+		if *maxBehind < 0 || *maxLatency <= 0 {
+			return fmt.Errorf("%w: --max-behind must not be negative and --max-latency must be positive", errUsage)
+		}
+		if _, err := time.ParseDuration(*refresh); err != nil {
+			return fmt.Errorf("%w: --refresh must be a duration like 30s or 1m", errUsage)
+		}
+		var dashboard any = buildDashboard(dashboardSettings{
+			Title: *title, UID: *uid, Datasource: *datasource, Server: *server, Refresh: *refresh,
+			MaxBehind: *maxBehind, MaxLatency: *maxLatency,
+		})
+		if !*provision {
+			// POST /api/dashboards/db takes the dashboard wrapped
+			dashboard = map[string]any{"dashboard": dashboard, "overwrite": true, "message": "strunzctl monitor dashboards"}
+		}
+		data, err := json.MarshalIndent(dashboard, "", "  ")
+		if err != nil {
+			return err
+		}
+		data = append(data, '\n')
+		if *output == "" {
+			_, err := os.Stdout.Write(data)
+			return err
+		}
+		if err := os.WriteFile(*output, data, 0o644); err != nil {
+			return err
+		}
+		fmt.Printf("📊 Wrote dashboard %s to %s\n", *uid, *output)
+		return nil
+	}
+	return cmd
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The metric names, shared with the dashboards of monitor dashboards
+const (
+	metricUp                  = "strunz_up"
+	metricCheckUp             = "strunz_check_up"
+	metricCheckDuration       = "strunz_check_duration_seconds"
+	metricLastProbe           = "strunz_last_probe_timestamp_seconds"
+	metricDeployedVersion     = "strunz_deployed_version_info"
+	metricProbes              = "strunz_probes_total"
+	metricProbeFailures       = "strunz_probe_failures_total"
+	metricToolCalls           = "strunz_tool_calls_total"
+	metricToolErrors          = "strunz_tool_errors_total"
+	metricRegistryErrors      = "strunz_registry_errors_total"
+	metricRegistryLastSuccess = "strunz_registry_last_success_timestamp_seconds"
+	metricGHCRVersions        = "strunz_ghcr_versions"
+	metricLatestVersion       = "strunz_latest_version_info"
+	metricReleasesBehind      = "strunz_releases_behind"
+	metricBehindSeconds       = "strunz_behind_seconds"
+	metricExporterBuild       = "strunzctl_build_info"
+)
+
+// metricsExporter holds the latest probe and registry results and renders
+// them in the Prometheus text format
+type metricsExporter struct {
+	serverURL   string
+	prereleases bool
+
+	mu         sync.Mutex
+	probe      *monitorProbe
+	probes     int
+	failures   map[string]int // failed probes by check
+	toolCalls  map[string]int
+	toolErrors map[string]int
+
+	versions       []PackageVersion
+	registryOK     time.Time
+	registryErrors int
+}
+
+// observe records a probe
+func (e *metricsExporter) observe(probe monitorProbe) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.probe = &probe
+	e.probes++
+	// Counting zeros keeps every series present, so rate() sees the first failure
+	for _, check := range probe.Checks {
+		failed := boolValue(check.Err != nil)
+		if check.Name == "search" {
+			tool := smokeToolCalls[0].Tool
+			e.toolCalls[tool]++
+			e.toolErrors[tool] += int(failed)
+		}
+		e.failures[check.Name] += int(failed)
+	}
+}
+
+// refreshRegistry fetches the package versions from GHCR
+func (e *metricsExporter) refreshRegistry(github *GitHubClient) error {
+	versions, err := listPackageVersions(github)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err != nil {
+		e.registryErrors++
+		return err
+	}
+	e.versions, e.registryOK = versions, time.Now()
+	return nil
+}
+
+type metricSample struct {
+	labels string
+	value  float64
+}
+
+// promLabels formats name/value pairs as a Prometheus label set
+func promLabels(pairs ...string) string {
+	var labels []string
+	for i := 0; i+1 < len(pairs); i += 2 {
+		value := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(pairs[i+1])
+		labels = append(labels, fmt.Sprintf(`%s="%s"`, pairs[i], value))
+	}
+	return "{" + strings.Join(labels, ",") + "}"
+}
+
+func writeMetric(w io.Writer, name, kind, help string, samples ...metricSample) {
+	if len(samples) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	for _, sample := range samples {
+		labels := sample.labels
+		if labels == "{}" {
+			labels = ""
+		}
+		fmt.Fprintf(w, "%s%s %s\n", name, labels, strconv.FormatFloat(sample.value, 'f', -1, 64))
+	}
+}
+
+// countSamples turns a count per label value into samples, in label order
+func countSamples(label string, counts map[string]int) []metricSample {
+	var samples []metricSample
+	for _, key := range slices.Sorted(maps.Keys(counts)) {
+		samples = append(samples, metricSample{promLabels(label, key), float64(counts[key])})
+	}
+	return samples
+}
+
+func boolValue(ok bool) float64 {
+	if ok {
+		return 1
+	}
+	return 0
+}
+
+func (e *metricsExporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/metrics" {
+		http.Error(w, "metrics are served at /metrics", http.StatusNotFound)
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	server := promLabels("server", e.serverURL)
+	build := currentBuild()
+	writeMetric(w, metricExporterBuild, "gauge", "The strunzctl build serving these metrics.",
+		metricSample{promLabels("version", build.Version, "commit", build.Commit, "go_version", build.GoVersion), 1})
+
+	if e.probe != nil {
+		probe := e.probe
+		writeMetric(w, metricUp, "gauge", "Whether the last probe of health, MCP handshake and search passed.",
+			metricSample{server, boolValue(probe.Failed() == nil)})
+		var checks, durations []metricSample
+		for _, check := range probe.Checks {
+			labels := promLabels("server", e.serverURL, "check", check.Name)
+			checks = append(checks, metricSample{labels, boolValue(check.Err == nil)})
+			durations = append(durations, metricSample{labels, check.Duration.Seconds()})
+		}
+		writeMetric(w, metricCheckUp, "gauge", "Whether a check of the last probe passed; later checks are skipped once one fails.", checks...)
+		writeMetric(w, metricCheckDuration, "gauge", "Duration of each check of the last probe, the handshake being connect, initialize and tools/list.", durations...)
+		writeMetric(w, metricLastProbe, "gauge", "When the last probe started.",
+			metricSample{server, float64(probe.At.Unix())})
+		if probe.Version != "" {
+			writeMetric(w, metricDeployedVersion, "gauge", "The version the server reports on /health.",
+				metricSample{promLabels("server", e.serverURL, "version", probe.Version), 1})
+		}
+	}
+	writeMetric(w, metricProbes, "counter", "Probes run since the exporter started.",
+		metricSample{server, float64(e.probes)})
+	writeMetric(w, metricProbeFailures, "counter", "Failed probes by the check that failed.", countSamples("check", e.failures)...)
+	writeMetric(w, metricToolCalls, "counter", "Tool calls made by probes.", countSamples("tool", e.toolCalls)...)
+	writeMetric(w, metricToolErrors, "counter", "Tool calls made by probes that failed or returned isError.", countSamples("tool", e.toolErrors)...)
+
+	writeMetric(w, metricRegistryErrors, "counter", "Failed fetches of the GHCR package versions.",
+		metricSample{"", float64(e.registryErrors)})
+	if e.registryOK.IsZero() {
+		return
+	}
+	writeMetric(w, metricRegistryLastSuccess, "gauge", "When the GHCR package versions were last fetched.",
+		metricSample{"", float64(e.registryOK.Unix())})
+	tagged := 0
+	for _, version := range e.versions {
+		if len(version.Metadata.Container.Tags) > 0 {
+			tagged++
+		}
+	}
+	writeMetric(w, metricGHCRVersions, "gauge", "Package versions in GHCR.",
+		metricSample{promLabels("state", "tagged"), float64(tagged)},
+		metricSample{promLabels("state", "untagged"), float64(len(e.versions) - tagged)})
+
+	if e.probe == nil {
+		return
+	}
+	production, ok := parseSemver(e.probe.Version)
+	if !ok {
+		return
+	}
+	drift := computeDrift(production, e.versions, e.prereleases)
+	if drift.newestTag != nil {
+		writeMetric(w, metricLatestVersion, "gauge", "The newest semver tag in GHCR.",
+			metricSample{promLabels("version", drift.newestTag.Original), 1})
+	}
+	var behindFor float64
+	if len(drift.missing) > 0 {
+		behindFor = time.Since(drift.behindSince()).Seconds()
+	}
+	writeMetric(w, metricReleasesBehind, "gauge", "Released image tags newer than the deployed version.",
+		metricSample{server, float64(len(drift.missing))})
+	writeMetric(w, metricBehindSeconds, "gauge", "How long the oldest release the server lacks has been published, 0 when up to date.",
+		metricSample{server, behindFor})
+}
+
+func newMonitorExportCommand() *Command {
+	cmd := newCommand("export", "[url]", "Serve Prometheus metrics on server health, handshake latency, tool errors, GHCR versions and version drift.")
+	listen := cmd.Flags.String("listen", ":9100", "address to serve /metrics on")
+	interval := cmd.Flags.Duration("interval", time.Minute, "time between probes of the server")
+	registryInterval := cmd.Flags.Duration("registry-interval", 15*time.Minute, "time between fetches of the GHCR package versions")
+	timeout := cmd.Flags.Duration("timeout", 30*time.Second, "timeout for connecting and for each request")
+	prereleases := cmd.Flags.Bool("prereleases", false, "count prerelease tags as releases for the drift")
+
+	cmd.Run = func(args []string) error {
+		if len(args) > 1 {
+			return cmd.ExactArgs(args, 1)
+		}
+		serverURL := defaultServerURL
+		if len(args) == 1 {
+			serverURL = args[0]
+		}
+		if *interval < 10*time.Second || *registryInterval < time.Minute {
+			return fmt.Errorf("%w: --interval must be at least 10s and --registry-interval at least 1m", errUsage)
+		}
+		github, err := newGitHubClient()
+		if err != nil {
+			return err
+		}
+
+		exporter := &metricsExporter{
+			serverURL:   serverURL,
+			prereleases: *prereleases,
+			failures:    make(map[string]int),
+			toolCalls:   make(map[string]int),
+			toolErrors:  make(map[string]int),
+		}
+		listener, err := net.Listen("tcp", *listen)
+		if err != nil {
+			return fmt.Errorf("failed to listen: %w", err)
+		}
+		server := &http.Server{Handler: exporter}
+		ctx, stop := context.WithCancel(commandContext)
+		defer stop()
+		go func() {
+			<-ctx.Done()
+			server.Close()
+		}()
+
+		every := func(d time.Duration, run func()) {
+			ticker := time.NewTicker(d)
+			defer ticker.Stop()
+			for {
+				run()
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}
+		go every(*interval, func() {
+			probe := probeServer(serverURL, *timeout)
+			exporter.observe(probe)
+			if failed := probe.Failed(); failed != nil {
+				fmt.Printf("[%s] ❌ %s: %s\n", probe.At.Format(time.TimeOnly), failed.Name, errorClass(failed.Err))
+			}
+		})
+		go every(*registryInterval, func() {
+			if err := exporter.refreshRegistry(github); err != nil {
+				fmt.Printf("[%s] ⚠️  GHCR: %v\n", time.Now().Format(time.TimeOnly), err)
+			}
+		})
+
+		fmt.Printf("\n📈 Serving metrics for %s on http://%s/metrics (Ctrl-C to stop)\n", serverURL, listener.Addr())
+		if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	}
+	return cmd
+}
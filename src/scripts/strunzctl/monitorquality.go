@@ -0,0 +1,379 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// qualityQuery is a search whose results should come from known sources
+// and mention known terms
+type qualityQuery struct {
+	Name   string
+	Query  string
+	Tool   string
+	Limit  int
+	Expect []string // substrings of the sources the hits should come from
+	Terms  []string // words the hits should mention
+}
+
+// defaultQualityQueries cover the topics users ask about most
+var defaultQualityQueries = []qualityQuery{
+	{Name: "Vitamin D Dosierung", Expect: []string{"book", "news"}, Terms: []string{"Vitamin D"}},
+	{Name: "Magnesium Mangel", Expect: []string{"book", "news"}, Terms: []string{"Magnesium"}},
+	{Name: "Omega-3 Fettsäuren", Expect: []string{"book", "news"}, Terms: []string{"Omega"}},
+	{Name: "Eiweiß Bedarf", Expect: []string{"book"}, Terms: []string{"Eiweiß"}},
+	{Name: "Schlaf verbessern", Expect: []string{"book", "news"}, Terms: []string{"Schlaf"}},
+	{Name: "Ferritin Eisenmangel", Expect: []string{"book", "news"}, Terms: []string{"Ferritin"}},
+}
+
+// QualityScore is how well one query's hits match what is expected. Score
+// is the mean of the share of relevant hits, the reciprocal rank of the
+// first relevant one and the share of expected sources and terms found.
+type QualityScore struct {
+	Score          float64  `json:"score"`
+	Precision      float64  `json:"precision"`
+	ReciprocalRank float64  `json:"reciprocal_rank"`
+	Coverage       float64  `json:"coverage"`
+	Missing        []string `json:"missing,omitempty"`
+	Sources        []string `json:"sources"`
+	Error          string   `json:"error,omitempty"`
+}
+
+// QualityRun is the outcome of all quality queries against one server
+type QualityRun struct {
+	URL     string                  `json:"url"`
+	Version string                  `json:"version,omitempty"`
+	At      time.Time               `json:"at"`
+	Score   float64                 `json:"score"`
+	Queries map[string]QualityScore `json:"queries"`
+}
+
+func newMonitorQualityCommand() *Command {
+	cmd := newCommand("quality", "[url]", "Run fixed searches, score the hits against expected sources and terms, and alert when quality drifts from the baseline.")
+	queriesFile := cmd.Flags.String("queries", "", "YAML file of queries with expect and terms (default: built-in German health queries)")
+	baselinePath := cmd.Flags.String("baseline", "", "JSON scores to compare against; recorded by the first run if missing")
+	updateBaseline := cmd.Flags.Bool("update-baseline", false, "overwrite the baseline with this run's scores")
+	maxDrop := cmd.Flags.Float64("max-drop", 0.1, "largest allowed drop of the overall score below the baseline")
+	maxQueryDrop := cmd.Flags.Float64("max-query-drop", 0.25, "largest allowed drop of a single query's score below the baseline")
+	minScore := cmd.Flags.Float64("min-score", 0, "lowest allowed overall score, with or without a baseline")
+	interval := cmd.Flags.Duration("interval", 0, "repeat on this interval, e.g. 6h (default: run once)")
+	timeout := cmd.Flags.Duration("timeout", 60*time.Second, "timeout for connecting and for each search")
+	notify := cmd.Flags.Bool("notify-webhook", false, "post drift and recovery to webhooks.notify and the alerts sinks")
+
+	cmd.Run = func(args []string) error {
+		if len(args) > 1 {
+			return cmd.ExactArgs(args, 1)
+		}
+		serverURL := defaultServerURL
+		if len(args) == 1 {
+			serverURL = args[0]
+		}
+		if *interval != 0 && *interval < time.Minute {
+			return fmt.Errorf("%w: --interval must be at least 1m", errUsage)
+		}
+		queries, err := loadQualityQueries(*queriesFile)
+		if err != nil {
+			return err
+		}
+		notifier, err := newNotifier(*notify)
+		if err != nil {
+			return err
+		}
+		var baseline *QualityRun
+		if *baselinePath != "" && !*updateBaseline {
+			if baseline, err = loadQualityRun(*baselinePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+		}
+
+		drifting := false
+		for n := 1; ; n++ {
+			if n > 1 {
+				if err := sleepContext(*interval); err != nil {
+					return err
+				}
+			}
+			run, err := runQualityQueries(serverURL, queries, *timeout)
+			if err != nil {
+				if *interval == 0 {
+					return err
+				}
+				fmt.Printf("\n❌ %v\n", err)
+				continue
+			}
+			printQualityRun(run, baseline)
+
+			if baseline == nil && *baselinePath != "" {
+				if err := writeQualityRun(*baselinePath, run); err != nil {
+					return err
+				}
+				fmt.Printf("\nBaseline written to %s\n", *baselinePath)
+				baseline = run
+			}
+			reasons := compareQuality(baseline, run, *maxDrop, *maxQueryDrop, *minScore)
+			switch {
+			case len(reasons) > 0 && !drifting:
+				message := fmt.Sprintf("Search quality of `%s` dropped to %.2f", serverURL, run.Score)
+				if baseline != nil && baseline.Version != run.Version {
+					message += fmt.Sprintf(" after %s → %s", orNone(baseline.Version), orNone(run.Version))
+				}
+				message += ":\n- " + strings.Join(reasons, "\n- ")
+				notifier.alert(severityWarning, "quality "+serverURL, "📉 Search quality drift", message)
+			case len(reasons) == 0 && drifting:
+				notifier.resolve("quality "+serverURL, "✅ Search quality recovered", fmt.Sprintf("Search quality of `%s` is back at %.2f.", serverURL, run.Score))
+			}
+			drifting = len(reasons) > 0
+
+			if *interval == 0 {
+				if drifting {
+					fmt.Println("\n❌ Search quality drifted:")
+					for _, reason := range reasons {
+						fmt.Printf("  - %s\n", reason)
+					}
+					return fmt.Errorf("%w: search quality of %s drifted", errPolicy, serverURL)
+				}
+				fmt.Println("\n✅ Search quality within thresholds")
+				return nil
+			}
+		}
+	}
+	return cmd
+}
+
+// loadQualityQueries reads sections of query, tool, limit and the
+// comma-separated expect and terms; the query defaults to the section name
+//
+//	Vitamin D Dosierung:
+//	  expect: book, news
+//	  terms: Vitamin D, Dosierung
+func loadQualityQueries(path string) ([]qualityQuery, error) {
+	queries := slices.Clone(defaultQualityQueries)
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("%w: --queries: %v", errUsage, err)
+		}
+		values, err := parseYAML(data)
+		if err != nil {
+			return nil, fmt.Errorf("%w: failed to parse %s: %v", errUsage, path, err)
+		}
+		queries = nil
+		for name, value := range values {
+			entry, ok := value.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("%w: %s: query %q must be a section with expect or terms", errUsage, path, name)
+			}
+			query := qualityQuery{Name: name}
+			for key, value := range entry {
+				text, ok := value.(string)
+				if !ok {
+					return nil, fmt.Errorf("%w: %s: %s.%s must be a value", errUsage, path, name, key)
+				}
+				switch key {
+				case "query":
+					query.Query = text
+				case "tool":
+					query.Tool = text
+				case "limit":
+					if query.Limit, err = strconv.Atoi(text); err != nil || query.Limit < 1 {
+						return nil, fmt.Errorf("%w: %s: %s.limit must be a positive number", errUsage, path, name)
+					}
+				case "expect":
+					query.Expect = splitList(text)
+				case "terms":
+					query.Terms = splitList(text)
+				default:
+					return nil, fmt.Errorf("%w: %s: unknown key %s.%s", errUsage, path, name, key)
+				}
+			}
+			if len(query.Expect) == 0 && len(query.Terms) == 0 {
+				return nil, fmt.Errorf("%w: %s: %s needs expect or terms to score against", errUsage, path, name)
+			}
+			queries = append(queries, query)
+		}
+		slices.SortFunc(queries, func(a, b qualityQuery) int { return strings.Compare(a.Name, b.Name) })
+	}
+
+	for i := range queries {
+		query := &queries[i]
+		query.Query = cmp.Or(query.Query, query.Name)
+		query.Tool = cmp.Or(query.Tool, smokeTool)
+		query.Limit = cmp.Or(query.Limit, 5)
+	}
+	return queries, nil
+}
+
+// scoreHits scores the hits of a query. A hit is relevant when its source
+// is expected and it mentions one of the terms, whichever are configured.
+func scoreHits(query qualityQuery, hits []searchHit) QualityScore {
+	var score QualityScore
+	found := make(map[string]bool)
+	relevant := 0
+	for i, hit := range hits {
+		score.Sources = append(score.Sources, hit.Source)
+		sourceOK := len(query.Expect) == 0
+		for _, source := range query.Expect {
+			if containsFold(hit.Source, source) {
+				found[source], sourceOK = true, true
+			}
+		}
+		termOK := len(query.Terms) == 0
+		for _, term := range query.Terms {
+			if containsFold(hit.Content, term) {
+				found[term], termOK = true, true
+			}
+		}
+		if sourceOK && termOK {
+			relevant++
+			if score.ReciprocalRank == 0 {
+				score.ReciprocalRank = 1 / float64(i+1)
+			}
+		}
+	}
+
+	expected := append(slices.Clone(query.Expect), query.Terms...)
+	for _, item := range expected {
+		if !found[item] {
+			score.Missing = append(score.Missing, item)
+		}
+	}
+	score.Precision = float64(relevant) / float64(query.Limit)
+	score.Coverage = float64(len(expected)-len(score.Missing)) / float64(len(expected))
+	score.Score = (score.Precision + score.ReciprocalRank + score.Coverage) / 3
+	return score
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+func runQualityQueries(serverURL string, queries []qualityQuery, timeout time.Duration) (*QualityRun, error) {
+	mcp, err := connectMCP(serverURL, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer mcp.Close()
+	if _, err := mcp.Initialize(commandContext); err != nil {
+		return nil, fmt.Errorf("initialize failed: %s", describeMCPError(err))
+	}
+
+	run := &QualityRun{URL: serverURL, At: time.Now(), Queries: make(map[string]QualityScore)}
+	if health, err := fetchHealth(serverURL); err == nil {
+		run.Version = health.Version
+	}
+	for _, query := range queries {
+		var score QualityScore
+		result, err := mcp.CallTool(commandContext, query.Tool, map[string]any{"query": query.Query, "limit": query.Limit})
+		switch {
+		case err != nil:
+			score.Error = describeMCPError(err)
+		case result.IsError:
+			score.Error = "isError: " + result.Text()
+		default:
+			if hits, ok := parseSearchHits(result.Text()); ok {
+				score = scoreHits(query, hits)
+			} else {
+				score.Error = "result is not a list of search hits: " + result.Text()
+			}
+		}
+		run.Queries[query.Name] = score
+		run.Score += score.Score / float64(len(queries))
+	}
+	return run, nil
+}
+
+// compareQuality returns why the run fails the thresholds, if it does
+func compareQuality(baseline, run *QualityRun, maxDrop, maxQueryDrop, minScore float64) []string {
+	var reasons []string
+	if run.Score < minScore {
+		reasons = append(reasons, fmt.Sprintf("overall score %.2f is below %.2f", run.Score, minScore))
+	}
+	for _, name := range slices.Sorted(maps.Keys(run.Queries)) {
+		if score := run.Queries[name]; score.Error != "" {
+			reasons = append(reasons, fmt.Sprintf("%q failed: %s", name, truncate(score.Error, 80)))
+		}
+	}
+	if baseline == nil {
+		return reasons
+	}
+	if drop := baseline.Score - run.Score; drop > maxDrop {
+		reasons = append(reasons, fmt.Sprintf("overall score dropped %.2f → %.2f", baseline.Score, run.Score))
+	}
+	for _, name := range slices.Sorted(maps.Keys(run.Queries)) {
+		before, ok := baseline.Queries[name]
+		score := run.Queries[name]
+		if ok && score.Error == "" && before.Score-score.Score > maxQueryDrop {
+			reason := fmt.Sprintf("%q dropped %.2f → %.2f", name, before.Score, score.Score)
+			if len(score.Missing) > 0 {
+				reason += ", missing " + strings.Join(score.Missing, ", ")
+			}
+			reasons = append(reasons, reason)
+		}
+	}
+	return reasons
+}
+
+func printQualityRun(run, baseline *QualityRun) {
+	fmt.Printf("\n🎯 Search quality of %s (%s) at %s\n\n", run.URL, orNone(run.Version), run.At.Format(time.DateTime))
+	fmt.Printf("  %-28s %6s %6s %6s %6s  %s\n", "QUERY", "SCORE", "PREC", "RR", "COVER", "BASELINE")
+	for _, name := range slices.Sorted(maps.Keys(run.Queries)) {
+		score := run.Queries[name]
+		if score.Error != "" {
+			fmt.Printf("  %-28s ❌ %s\n", truncate(name, 28), truncate(score.Error, 80))
+			continue
+		}
+		change := ""
+		if before, ok := baseline.lookup(name); ok {
+			change = fmt.Sprintf("%.2f (%+.2f)", before.Score, score.Score-before.Score)
+		}
+		fmt.Printf("  %-28s %6.2f %6.2f %6.2f %6.2f  %s\n", truncate(name, 28), score.Score, score.Precision, score.ReciprocalRank, score.Coverage, change)
+		if len(score.Missing) > 0 {
+			fmt.Printf("  %-28s missing: %s\n", "", strings.Join(score.Missing, ", "))
+		}
+	}
+	overall := fmt.Sprintf("\nOverall: %.2f", run.Score)
+	if baseline != nil {
+		overall += fmt.Sprintf(" (baseline %.2f from %s, %s)", baseline.Score, baseline.At.Format(time.DateOnly), orNone(baseline.Version))
+	}
+	fmt.Println(overall)
+}
+
+// lookup returns a query's baseline score; a nil run has none
+func (r *QualityRun) lookup(name string) (QualityScore, bool) {
+	if r == nil {
+		return QualityScore{}, false
+	}
+	score, ok := r.Queries[name]
+	return score, ok
+}
+
+func loadQualityRun(path string) (*QualityRun, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read quality baseline: %w", err)
+	}
+	var run QualityRun
+	if err := json.Unmarshal(data, &run); err != nil {
+		return nil, fmt.Errorf("failed to parse quality baseline %s: %w", path, err)
+	}
+	return &run, nil
+}
+
+func writeQualityRun(path string, run *QualityRun) error {
+	data, err := json.MarshalIndent(run, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write quality baseline: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// probeRecord is one probe in the monitor history
+type probeRecord struct {
+	At      time.Time `json:"at"`
+	URL     string    `json:"url"`
+	OK      bool      `json:"ok"`
+	Version string    `json:"version,omitempty"`
+	// Failed names the failed check and Error its error
+	Failed string `json:"failed,omitempty"`
+	Error  string `json:"error,omitempty"`
+	// Durations are the milliseconds of the checks that ran
+	Durations map[string]int64 `json:"durations_ms"`
+}
+
+// probeHistoryPath returns the JSONL history of a server in the strunzctl
+// state directory
+func probeHistoryPath(serverURL string) (string, error) {
+	dir, err := cacheDir("monitor")
+	if err != nil {
+		return "", err
+	}
+	name := serverURL
+	if parsed, err := url.Parse(serverURL); err == nil && parsed.Host != "" {
+		name = parsed.Host
+	}
+	return filepath.Join(dir, strings.NewReplacer(":", "_", "/", "_").Replace(name)+".jsonl"), nil
+}
+
+// appendProbeHistory records a probe; the monitor keeps running when the
+// history cannot be written
+func appendProbeHistory(path, serverURL string, probe monitorProbe) error {
+	record := probeRecord{At: probe.At.UTC(), URL: serverURL, OK: probe.Failed() == nil, Version: probe.Version, Durations: make(map[string]int64)}
+	for _, check := range probe.Checks {
+		record.Durations[check.Name] = check.Duration.Milliseconds()
+	}
+	if failed := probe.Failed(); failed != nil {
+		record.Failed, record.Error = failed.Name, errorClass(failed.Err)
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open probe history: %w", err)
+	}
+	_, err = file.Write(append(line, '\n'))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// loadProbeHistory reads the probes between from and to, oldest first
+func loadProbeHistory(path string, from, to time.Time) ([]probeRecord, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var records []probeRecord
+	scanner := bufio.NewScanner(file)
+	for n := 1; scanner.Scan(); n++ {
+		var record probeRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+		if !record.At.Before(from) && record.At.Before(to) {
+			records = append(records, record)
+		}
+	}
+	slices.SortStableFunc(records, func(a, b probeRecord) int { return a.At.Compare(b.At) })
+	return records, scanner.Err()
+}
+
+// incident is a run of failed probes long enough to alert on
+type incident struct {
+	Start, End time.Time
+	// Resolved is false when the period ended while the server was down
+	Resolved bool
+	Probes   int
+	Failed   string
+	Error    string
+}
+
+func (i incident) Duration() time.Duration {
+	return i.End.Sub(i.Start)
+}
+
+// slaReport summarizes the probes of one period
+type slaReport struct {
+	From, To  time.Time
+	Probes    int
+	Succeeded int
+	// Covered is the part of the period the monitor was probing
+	Covered   time.Duration
+	Incidents []incident
+	// Latencies are the durations of successful checks by check name
+	Latencies map[string][]time.Duration
+	Versions  []string
+}
+
+// buildSLAReport finds incidents of at least threshold consecutive failed
+// probes. Gaps longer than three probe intervals count as unmonitored.
+func buildSLAReport(records []probeRecord, from, to time.Time, threshold int) *slaReport {
+	report := &slaReport{From: from, To: to, Probes: len(records), Latencies: make(map[string][]time.Duration)}
+	var gaps []time.Duration
+	for i := 1; i < len(records); i++ {
+		gaps = append(gaps, records[i].At.Sub(records[i-1].At))
+	}
+	slices.Sort(gaps)
+	interval := time.Minute
+	if len(gaps) > 0 {
+		interval = gaps[len(gaps)/2]
+	}
+	for i := 1; i < len(records); i++ {
+		if gap := records[i].At.Sub(records[i-1].At); gap <= 3*interval {
+			report.Covered += gap
+		}
+	}
+
+	var run []probeRecord
+	closeRun := func(end time.Time, resolved bool) {
+		if len(run) >= threshold {
+			report.Incidents = append(report.Incidents, incident{Start: run[0].At, End: end, Resolved: resolved,
+				Probes: len(run), Failed: run[0].Failed, Error: run[0].Error})
+		}
+		run = nil
+	}
+	for _, record := range records {
+		if record.Version != "" && !slices.Contains(report.Versions, record.Version) {
+			report.Versions = append(report.Versions, record.Version)
+		}
+		if !record.OK {
+			run = append(run, record)
+			continue
+		}
+		report.Succeeded++
+		for check, ms := range record.Durations {
+			report.Latencies[check] = append(report.Latencies[check], time.Duration(ms)*time.Millisecond)
+		}
+		closeRun(record.At, true)
+	}
+	if len(run) > 0 {
+		end := run[len(run)-1].At.Add(interval)
+		if end.After(to) {
+			end = to
+		}
+		closeRun(end, false)
+	}
+	for check := range report.Latencies {
+		slices.Sort(report.Latencies[check])
+	}
+	return report
+}
+
+// Uptime is the share of successful probes in percent
+func (r *slaReport) Uptime() float64 {
+	if r.Probes == 0 {
+		return 0
+	}
+	return 100 * float64(r.Succeeded) / float64(r.Probes)
+}
+
+// MTTR is the mean duration of the resolved incidents
+func (r *slaReport) MTTR() time.Duration {
+	var total time.Duration
+	resolved := 0
+	for _, incident := range r.Incidents {
+		if incident.Resolved {
+			total += incident.Duration()
+			resolved++
+		}
+	}
+	if resolved == 0 {
+		return 0
+	}
+	return total / time.Duration(resolved)
+}
+
+// writeSLAMarkdown renders the report for the monthly status summary
+func writeSLAMarkdown(w io.Writer, serverURL string, r *slaReport) {
+	fmt.Fprintf(w, "# Availability of %s, %s\n\n", serverURL, r.From.Format("January 2006"))
+	fmt.Fprintf(w, "| | |\n|---|---|\n")
+	fmt.Fprintf(w, "| Uptime | %.3f%% (%d of %d probes) |\n", r.Uptime(), r.Succeeded, r.Probes)
+	fmt.Fprintf(w, "| Incidents | %d |\n", len(r.Incidents))
+	if mttr := r.MTTR(); mttr > 0 {
+		fmt.Fprintf(w, "| MTTR | %s |\n", mttr.Round(time.Second))
+	} else {
+		fmt.Fprintf(w, "| MTTR | - |\n")
+	}
+	fmt.Fprintf(w, "| Monitored | %.1f%% of the month |\n", 100*r.Covered.Seconds()/r.To.Sub(r.From).Seconds())
+	if len(r.Versions) > 0 {
+		fmt.Fprintf(w, "| Versions | %s |\n", strings.Join(r.Versions, ", "))
+	}
+
+	fmt.Fprintf(w, "\n## Latency\n\n")
+	fmt.Fprintf(w, "| Check | p50 | p95 | p99 | Max |\n|---|---:|---:|---:|---:|\n")
+	for _, check := range []string{"health", "handshake", "search"} {
+		latencies := r.Latencies[check]
+		if len(latencies) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "| %s | %s | %s | %s | %s |\n", check,
+			percentile(latencies, 50), percentile(latencies, 95), percentile(latencies, 99), latencies[len(latencies)-1])
+	}
+
+	fmt.Fprintf(w, "\n## Incidents\n\n")
+	if len(r.Incidents) == 0 {
+		fmt.Fprintf(w, "None.\n")
+		return
+	}
+	fmt.Fprintf(w, "| Start (UTC) | Duration | Failed check | Error |\n|---|---:|---|---|\n")
+	for _, incident := range r.Incidents {
+		duration := incident.Duration().Round(time.Second).String()
+		if !incident.Resolved {
+			duration += " (ongoing at month end)"
+		}
+		fmt.Fprintf(w, "| %s | %s | %s | %s |\n", incident.Start.UTC().Format("2006-01-02 15:04"), duration,
+			incident.Failed, strings.ReplaceAll(incident.Error, "|", `\|`))
+	}
+}
+
+func newMonitorReportCommand() *Command {
+	cmd := newCommand("report", "[url]", "Compute uptime, MTTR, incidents and latency percentiles of a month from the probe history of `monitor run`, as Markdown.")
+	month := cmd.Flags.String("month", "", "month to report, YYYY-MM in UTC (default: the previous month)")
+	history := cmd.Flags.String("history", "", "probe history to read (default: the one `monitor run` writes for the URL)")
+	threshold := cmd.Flags.Int("threshold", 3, "consecutive failed probes that make an incident, like monitor run's alerts")
+	output := cmd.Flags.String("output", "", "write the Markdown to this file instead of stdout")
+
+	cmd.Run = func(args []string) error {
+		if len(args) > 1 {
+			return cmd.ExactArgs(args, 1)
+		}
+		serverURL := defaultServerURL
+		if len(args) == 1 {
+			serverURL = args[0]
+		}
+		if *threshold < 1 {
+			return fmt.Errorf("%w: --threshold must be at least 1", errUsage)
+		}
+		now := time.Now().UTC()
+		from := time.Date(now.Year(), now.Month()-1, 1, 0, 0, 0, 0, time.UTC)
+		if *month != "" {
+			var err error
+			if from, err = time.Parse("2006-01", *month); err != nil {
+				return fmt.Errorf("%w: --month must be YYYY-MM", errUsage)
+			}
+		}
+		to := from.AddDate(0, 1, 0)
+
+		path := *history
+		if path == "" {
+			var err error
+			if path, err = probeHistoryPath(serverURL); err != nil {
+				return err
+			}
+		}
+		records, err := loadProbeHistory(path, from, to)
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("%w: no probe history at %s; run `strunzctl monitor run %s` to record one", errUsage, path, serverURL)
+		}
+		if err != nil {
+			return err
+		}
+		if len(records) == 0 {
+			return fmt.Errorf("%w: %s has no probes in %s", errUsage, path, from.Format("2006-01"))
+		}
+
+		report := buildSLAReport(records, from, to, *threshold)
+		if *output == "" {
+			writeSLAMarkdown(os.Stdout, serverURL, report)
+			return nil
+		}
+		file, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		writeSLAMarkdown(file, serverURL, report)
+		if err := file.Close(); err != nil {
+			return err
+		}
+		fmt.Printf("📝 Wrote the %s report to %s (uptime %.3f%%, %d incident(s))\n", from.Format("2006-01"), *output, report.Uptime(), len(report.Incidents))
+		return nil
+	}
+	return cmd
+}
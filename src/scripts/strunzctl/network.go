@@ -0,0 +1,59 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// configureNetwork applies the proxy and CA bundle settings to
+// http.DefaultTransport, which every HTTP client of the tool uses, and
+// binds its requests to commandContext. The proxy is exported to the
+// environment, so docker, cosign and the scanners the commands run go
+// through it too.
+func configureNetwork(proxy, caBundle string) error {
+	if proxy != "" {
+		parsed, err := url.Parse(proxy)
+		if err != nil || parsed.Host == "" {
+			return fmt.Errorf("%w: proxy %q is not a URL", errUsage, proxy)
+		}
+		for _, name := range []string{"HTTPS_PROXY", "HTTP_PROXY"} {
+			if os.Getenv(name) == "" && os.Getenv(strings.ToLower(name)) == "" {
+				os.Setenv(name, proxy)
+			}
+		}
+	}
+	base, ok := http.DefaultTransport.(*http.Transport)
+	if !ok {
+		return nil
+	}
+	transport := base.Clone()
+	// HTTPS_PROXY, HTTP_PROXY and NO_PROXY, in either case
+	transport.Proxy = http.ProxyFromEnvironment
+
+	if caBundle != "" {
+		pem, err := os.ReadFile(caBundle)
+		if err != nil {
+			return fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("%w: CA bundle %s contains no PEM certificates", errUsage, caBundle)
+		}
+		if transport.TLSClientConfig == nil {
+			transport.TLSClientConfig = &tls.Config{}
+		}
+		transport.TLSClientConfig.RootCAs = pool
+		slog.Debug("Trusting additional CA certificates", "bundle", caBundle)
+	}
+	http.DefaultTransport = contextTransport{base: transport}
+	return nil
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// imageSectionMarker delimits the generated image section so publishing
+// again replaces it instead of appending a second one
+const imageSectionMarker = "<!-- strunzctl:image -->"
+
+func newReleasePublishNotesCommand() *Command {
+	cmd := newCommand("publish-notes", "<vX.Y.Z>", "Validate docs/RELEASE_NOTES_<tag>.md and publish it as the GitHub release body.")
+	file := cmd.Flags.String("file", "", "release notes file (default: docs/RELEASE_NOTES_<tag>.md in the repository)")
+	require := cmd.Flags.String("require", "Summary,Changes", "comma-separated ## sections the notes must contain")
+	dryRun := cmd.Flags.Bool("dry-run", false, "print the release body instead of publishing it")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 1); err != nil {
+			return err
+		}
+		version, ok := parseSemver(args[0])
+		if !ok {
+			return fmt.Errorf("%w: %q is not a semantic version", errUsage, args[0])
+		}
+		tag := "v" + version.String()
+		imageTag := version.String()
+
+		path := *file
+		if path == "" {
+			root, err := runGit("rev-parse", "--show-toplevel")
+			if err != nil {
+				return err
+			}
+			path = filepath.Join(root, "docs", "RELEASE_NOTES_"+tag+".md")
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read release notes: %w", err)
+		}
+		notes := string(data)
+
+		fmt.Printf("\n📝 Release notes %s\n", path)
+		if problems := validateReleaseNotes(notes, splitList(*require)); len(problems) > 0 {
+			for _, problem := range problems {
+				fmt.Printf("  ❌ %s\n", problem)
+			}
+			return fmt.Errorf("%w: release notes have %d problem(s)", errPolicy, len(problems))
+		}
+		fmt.Println("✅ Required sections present")
+
+		registry := newRegistryClient(config.Registry, imageRepository())
+		manifest, err := registry.GetRawManifest(imageTag)
+		if err != nil {
+			return err
+		}
+		body := withImageSection(notes, registry.Reference(imageTag), registry.Reference(manifest.Digest))
+
+		if *dryRun {
+			fmt.Printf("\n%s", body)
+			return nil
+		}
+		github, err := newGitHubClient()
+		if err != nil {
+			return err
+		}
+		release, err := findRelease(github, tag)
+		if err != nil {
+			return err
+		}
+		if release == nil {
+			return fmt.Errorf("no GitHub release for %s, run `strunzctl release create %s` first", tag, tag)
+		}
+		if release.Body == body {
+			fmt.Printf("✅ %s is up to date\n", release.HTMLURL)
+			return nil
+		}
+		update := fmt.Sprintf("/repos/%s/releases/%d", config.Repo, release.ID)
+		if err := github.Send(http.MethodPatch, update, map[string]string{"body": body}, nil); err != nil {
+			return fmt.Errorf("failed to update release: %w", err)
+		}
+		fmt.Printf("✅ Published notes to %s\n", release.HTMLURL)
+		return nil
+	}
+	return cmd
+}
+
+// validateReleaseNotes checks that each required "## " section exists and
+// has content, and that no placeholders are left
+func validateReleaseNotes(notes string, required []string) []string {
+	sections := make(map[string]string)
+	current := ""
+	for _, line := range strings.Split(notes, "\n") {
+		if heading, ok := strings.CutPrefix(line, "## "); ok {
+			current = strings.ToLower(strings.TrimSpace(heading))
+			sections[current] = ""
+			continue
+		}
+		if current != "" {
+			sections[current] += strings.TrimSpace(line)
+		}
+	}
+
+	var problems []string
+	for _, name := range required {
+		found := false
+		for heading, content := range sections {
+			if strings.HasPrefix(heading, strings.ToLower(name)) {
+				found = true
+				if content == "" {
+					problems = append(problems, fmt.Sprintf("section %q is empty", name))
+				}
+			}
+		}
+		if !found {
+			problems = append(problems, fmt.Sprintf("missing section \"## %s\"", name))
+		}
+	}
+	for _, placeholder := range []string{"TODO", "TBD", "FIXME"} {
+		if strings.Contains(notes, placeholder) {
+			problems = append(problems, fmt.Sprintf("contains placeholder %s", placeholder))
+		}
+	}
+	return problems
+}
+
+// withImageSection appends (or replaces) the section linking the image
+func withImageSection(notes, reference, pinned string) string {
+	if i := strings.Index(notes, imageSectionMarker); i >= 0 {
+		notes = notes[:i]
+	}
+	return fmt.Sprintf("%s\n\n%s\n## Docker Image\n\n`%s`\n\n```bash\ndocker pull %s\n```\n\nPackage: https://github.com/%s/pkgs/container/%s\n",
+		strings.TrimRight(notes, "\n"), imageSectionMarker, reference, pinned, config.Repo, config.Package)
+}
@@ -0,0 +1,408 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// webhookEnv overrides webhooks.notify from the config file. The URL is
+// never taken from a flag so the secret stays out of shell history and
+// process listings.
+const webhookEnv = "STRUNZCTL_NOTIFY_WEBHOOK"
+
+// pagerDutyEventsURL is the PagerDuty Events API v2 endpoint
+const pagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+// Alert severities, weakest first
+const (
+	severityInfo     = "info"
+	severityWarning  = "warning"
+	severityCritical = "critical"
+)
+
+var alertSeverities = []string{severityInfo, severityWarning, severityCritical}
+
+// Alert is one notification. Alerts with the same Key are one problem:
+// repeats within alerts.dedup are dropped and Resolved ends it.
+type Alert struct {
+	Severity string
+	Key      string
+	Title    string
+	Message  string
+	Resolved bool
+}
+
+// dedupKey falls back to the title when the caller names no problem
+func (a Alert) dedupKey() string {
+	key := a.Key
+	if key == "" {
+		key = a.Title
+	}
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:8])
+}
+
+// alertSink delivers alerts of at least its severity
+type alertSink struct {
+	name     string
+	severity string
+	send     func(Alert) error
+}
+
+// accepts reports whether the sink takes alerts of a severity
+func (s alertSink) accepts(severity string) bool {
+	return slices.Index(alertSeverities, severity) >= slices.Index(alertSeverities, s.severity)
+}
+
+// sentAlert is when an alert was last sent and with which severity, so its
+// resolution reaches the same sinks
+type sentAlert struct {
+	At       time.Time `json:"at"`
+	Severity string    `json:"severity"`
+}
+
+// Notifier routes alerts to the configured sinks. A nil Notifier discards
+// alerts, so callers need not check --notify-webhook.
+type Notifier struct {
+	sinks      []alertSink
+	dedup      time.Duration
+	silences   []silenceWindow
+	httpClient *http.Client
+}
+
+// newNotifier returns a Notifier for the configured sinks when enabled,
+// nil otherwise
+func newNotifier(enabled bool) (*Notifier, error) {
+	if !enabled {
+		return nil, nil
+	}
+	alerts := config.Alerts
+	n := &Notifier{httpClient: &http.Client{Timeout: 30 * time.Second}}
+	for _, severity := range []string{alerts.NotifySeverity, alerts.WebhookSeverity, alerts.PagerDutySeverity, alerts.EmailSeverity, alerts.PluginsSeverity} {
+		if !slices.Contains(alertSeverities, severity) {
+			return nil, fmt.Errorf("%w: alert severity must be one of %s, got %q", errUsage, strings.Join(alertSeverities, ", "), severity)
+		}
+	}
+	var err error
+	if n.dedup, err = time.ParseDuration(alerts.Dedup); err != nil {
+		return nil, fmt.Errorf("%w: alerts.dedup must be a duration like 15m, got %q", errUsage, alerts.Dedup)
+	}
+	if n.silences, err = parseSilences(alerts.Silence); err != nil {
+		return nil, fmt.Errorf("%w: alerts.silence: %v", errUsage, err)
+	}
+
+	if url := config.Webhooks.Notify; url != "" {
+		discord := strings.Contains(url, "discord.com/") || strings.Contains(url, "discordapp.com/")
+		n.sinks = append(n.sinks, alertSink{"chat webhook", alerts.NotifySeverity, func(a Alert) error { return n.sendChat(url, discord, a) }})
+	}
+	if alerts.Webhook != "" {
+		n.sinks = append(n.sinks, alertSink{"webhook", alerts.WebhookSeverity, func(a Alert) error { return n.sendWebhook(alerts.Webhook, a) }})
+	}
+	if alerts.PagerDuty != "" {
+		n.sinks = append(n.sinks, alertSink{"PagerDuty", alerts.PagerDutySeverity, func(a Alert) error { return n.sendPagerDuty(alerts.PagerDuty, a) }})
+	}
+	if alerts.Email != "" {
+		if alerts.SMTP == "" || alerts.From == "" {
+			return nil, fmt.Errorf("%w: alerts.email requires alerts.smtp and alerts.from", errUsage)
+		}
+		n.sinks = append(n.sinks, alertSink{"email", alerts.EmailSeverity, func(a Alert) error { return sendEmail(alerts, a) }})
+	}
+	for _, name := range splitList(alerts.Plugins) {
+		path, err := findPlugin(pluginNotify, name)
+		if err != nil {
+			return nil, fmt.Errorf("%w: alerts.plugins: %v", errUsage, err)
+		}
+		n.sinks = append(n.sinks, alertSink{name + " plugin", alerts.PluginsSeverity, func(a Alert) error { return sendPlugin(path, a) }})
+	}
+	if len(n.sinks) == 0 {
+		return nil, fmt.Errorf("%w: --notify-webhook requires webhooks.notify (or %s) or an alerts sink in the config file", errUsage, webhookEnv)
+	}
+	return n, nil
+}
+
+// send delivers an alert to every sink that takes it unless it is silenced
+// or a repeat, returning the errors of failed sinks
+func (n *Notifier) send(alert Alert) error {
+	if n == nil {
+		return nil
+	}
+	now := time.Now()
+	if window, ok := silencedAt(n.silences, now); ok {
+		slog.Info("Alert silenced", "title", alert.Title, "silence", window)
+		return nil
+	}
+	sent, err := loadSentAlerts()
+	if err != nil {
+		slog.Warn("Could not read the alert history, alerts are not deduplicated", "error", err)
+	}
+	key := alert.dedupKey()
+	last, ok := sent[key]
+	if ok && !alert.Resolved && n.dedup > 0 && now.Sub(last.At) < n.dedup && last.Severity == alert.Severity {
+		slog.Info("Alert deduplicated", "title", alert.Title, "last_sent", last.At)
+		return nil
+	}
+	severity := alert.Severity
+	if alert.Resolved && ok {
+		severity = last.Severity
+	}
+
+	var errs []error
+	for _, sink := range n.sinks {
+		if !sink.accepts(severity) {
+			continue
+		}
+		if err := sink.send(alert); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", sink.name, err))
+		}
+	}
+	if alert.Resolved {
+		delete(sent, key)
+	} else {
+		sent[key] = sentAlert{At: now, Severity: alert.Severity}
+	}
+	if err := saveSentAlerts(sent, max(n.dedup, 24*time.Hour)); err != nil {
+		slog.Warn("Could not write the alert history", "error", err)
+	}
+	return errors.Join(errs...)
+}
+
+// alert sends a problem, logging rather than failing on errors so a broken
+// sink never masks the outcome of the command itself
+func (n *Notifier) alert(severity, key, title, message string) {
+	if err := n.send(Alert{Severity: severity, Key: key, Title: title, Message: message}); err != nil {
+		slog.Warn("Notification failed", "title", title, "error", err)
+	}
+}
+
+// resolve ends the problem an earlier alert with the same key reported,
+// on the sinks that received it
+func (n *Notifier) resolve(key, title, message string) {
+	if err := n.send(Alert{Severity: severityInfo, Key: key, Title: title, Message: message, Resolved: true}); err != nil {
+		slog.Warn("Notification failed", "title", title, "error", err)
+	}
+}
+
+// postJSON posts a payload and fails on non-2xx responses
+func (n *Notifier) postJSON(url string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode payload: %w", err)
+	}
+	resp, err := doWithRetry(n.httpClient, func() (*http.Request, error) {
+		req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		return req, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to post: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("returned %s", resp.Status)
+	}
+	return nil
+}
+
+// sendChat posts to a Slack or Discord incoming webhook. Both render
+// `code` spans; bold uses Slack's *single* or Discord's **double**
+// asterisks, so callers pass plain text and a title emphasized here.
+func (n *Notifier) sendChat(url string, discord bool, alert Alert) error {
+	if discord {
+		return n.postJSON(url, map[string]string{"content": fmt.Sprintf("**%s**\n%s", alert.Title, alert.Message)})
+	}
+	return n.postJSON(url, map[string]string{"text": fmt.Sprintf("*%s*\n%s", alert.Title, alert.Message)})
+}
+
+// sendWebhook posts the alert as JSON for receivers of any kind
+func (n *Notifier) sendWebhook(url string, alert Alert) error {
+	return n.postJSON(url, alert.payload())
+}
+
+// payload is the JSON form of an alert for webhooks and notify plugins
+func (a Alert) payload() map[string]any {
+	return map[string]any{
+		"source":   "strunzctl",
+		"severity": a.Severity,
+		"key":      a.dedupKey(),
+		"title":    a.Title,
+		"message":  a.Message,
+		"resolved": a.Resolved,
+		"at":       time.Now().UTC().Format(time.RFC3339),
+	}
+}
+
+// sendPagerDuty triggers or resolves a PagerDuty incident keyed like the
+// alert, so repeats update one incident
+func (n *Notifier) sendPagerDuty(routingKey string, alert Alert) error {
+	event := map[string]any{"routing_key": routingKey, "dedup_key": "strunzctl-" + alert.dedupKey(), "event_action": "trigger"}
+	if alert.Resolved {
+		event["event_action"] = "resolve"
+	} else {
+		event["payload"] = map[string]any{
+			"summary":        truncate(alert.Title+": "+alert.Message, 1024),
+			"source":         "strunzctl",
+			"severity":       alert.Severity,
+			"custom_details": map[string]string{"message": alert.Message},
+		}
+	}
+	return n.postJSON(pagerDutyEventsURL, event)
+}
+
+// sendEmail mails the alert through alerts.smtp, authenticating when
+// alerts.smtp_user is set
+func sendEmail(alerts AlertsConfig, alert Alert) error {
+	host, _, err := net.SplitHostPort(alerts.SMTP)
+	if err != nil {
+		return fmt.Errorf("alerts.smtp must be host:port: %w", err)
+	}
+	var auth smtp.Auth
+	if alerts.SMTPUser != "" {
+		auth = smtp.PlainAuth("", alerts.SMTPUser, alerts.SMTPPassword, host)
+	}
+	to := splitList(alerts.Email)
+	subject := fmt.Sprintf("[strunzctl %s] %s", alert.Severity, alert.Title)
+	if alert.Resolved {
+		subject = "[strunzctl resolved] " + alert.Title
+	}
+	var body strings.Builder
+	fmt.Fprintf(&body, "From: %s\r\nTo: %s\r\nSubject: %s\r\nDate: %s\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n%s\r\n",
+		alerts.From, strings.Join(to, ", "), subject, time.Now().Format(time.RFC1123Z), strings.ReplaceAll(alert.Message, "`", ""))
+	if err := smtp.SendMail(alerts.SMTP, auth, alerts.From, to, []byte(body.String())); err != nil {
+		return fmt.Errorf("failed to send mail: %w", err)
+	}
+	return nil
+}
+
+// silenceWindow suppresses alerts between two times, or every day between
+// two UTC times of day when Daily is set
+type silenceWindow struct {
+	From, To time.Time
+	Daily    bool
+	spec     string
+}
+
+// parseSilences reads comma-separated windows, either
+// 2025-01-04T22:00Z/2025-01-05T02:00Z or a daily 02:00-04:00 in UTC
+func parseSilences(spec string) ([]silenceWindow, error) {
+	var windows []silenceWindow
+	for _, entry := range splitList(spec) {
+		if from, to, ok := strings.Cut(entry, "/"); ok {
+			start, err := parseSilenceTime(from)
+			if err != nil {
+				return nil, err
+			}
+			end, err := parseSilenceTime(to)
+			if err != nil {
+				return nil, err
+			}
+			if !end.After(start) {
+				return nil, fmt.Errorf("%s ends before it starts", entry)
+			}
+			windows = append(windows, silenceWindow{From: start, To: end, spec: entry})
+			continue
+		}
+		from, to, ok := strings.Cut(entry, "-")
+		start, err1 := time.Parse("15:04", from)
+		end, err2 := time.Parse("15:04", to)
+		if !ok || err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("%q is neither <start>/<end> in RFC 3339 nor a daily HH:MM-HH:MM", entry)
+		}
+		windows = append(windows, silenceWindow{From: start, To: end, Daily: true, spec: entry})
+	}
+	return windows, nil
+}
+
+func parseSilenceTime(value string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04Z07:00"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is not an RFC 3339 time like 2025-01-04T22:00Z", value)
+}
+
+// silencedAt returns the window covering t. Daily windows may wrap past
+// midnight, like 22:00-02:00.
+func silencedAt(windows []silenceWindow, t time.Time) (string, bool) {
+	utc := t.UTC()
+	minute := utc.Hour()*60 + utc.Minute()
+	for _, w := range windows {
+		if !w.Daily {
+			if !t.Before(w.From) && t.Before(w.To) {
+				return w.spec, true
+			}
+			continue
+		}
+		from, to := w.From.Hour()*60+w.From.Minute(), w.To.Hour()*60+w.To.Minute()
+		if (from <= to && minute >= from && minute < to) || (from > to && (minute >= from || minute < to)) {
+			return w.spec, true
+		}
+	}
+	return "", false
+}
+
+// sentAlertsPath keeps when each alert was last sent, so repeats are
+// dropped across runs of cron jobs as well as within a daemon
+func sentAlertsPath() (string, error) {
+	dir, err := cacheDir("alerts")
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "sent.json"), nil
+}
+
+func loadSentAlerts() (map[string]sentAlert, error) {
+	sent := make(map[string]sentAlert)
+	path, err := sentAlertsPath()
+	if err != nil {
+		return sent, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return sent, nil
+	}
+	if err != nil {
+		return sent, err
+	}
+	if err := json.Unmarshal(data, &sent); err != nil {
+		return make(map[string]sentAlert), err
+	}
+	return sent, nil
+}
+
+// saveSentAlerts drops entries older than keep, which cannot suppress
+// anything any more
+func saveSentAlerts(sent map[string]sentAlert, keep time.Duration) error {
+	path, err := sentAlertsPath()
+	if err != nil {
+		return err
+	}
+	for key, entry := range sent {
+		if time.Since(entry.At) > keep {
+			delete(sent, key)
+		}
+	}
+	data, err := json.Marshal(sent)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
@@ -0,0 +1,456 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// oauthRedirectURI is registered for the test client; it is never opened,
+// the authorization response is read from the Location header
+const oauthRedirectURI = "https://claude.ai/api/mcp/auth_callback"
+
+// oauthResult is the outcome of one conformance step
+type oauthResult int
+
+const (
+	oauthPass oauthResult = iota
+	oauthWarn
+	oauthFail
+	oauthSkip
+)
+
+func (r oauthResult) String() string {
+	return [...]string{"✅", "⚠️ ", "❌", "⏭️ "}[r]
+}
+
+// oauthStep is one checked step of the flow and the spec it follows
+type oauthStep struct {
+	Name   string
+	Spec   string
+	Result oauthResult
+	Detail string
+}
+
+// oauthServerMetadata is the RFC 8414 authorization server metadata
+type oauthServerMetadata struct {
+	Issuer                        string   `json:"issuer"`
+	AuthorizationEndpoint         string   `json:"authorization_endpoint"`
+	TokenEndpoint                 string   `json:"token_endpoint"`
+	RegistrationEndpoint          string   `json:"registration_endpoint"`
+	ResponseTypesSupported        []string `json:"response_types_supported"`
+	GrantTypesSupported           []string `json:"grant_types_supported"`
+	CodeChallengeMethodsSupported []string `json:"code_challenge_methods_supported"`
+}
+
+// oauthToken is a token endpoint response
+type oauthToken struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	Error        string `json:"error"`
+}
+
+// oauthTester walks the flow Claude.ai performs against an MCP server
+type oauthTester struct {
+	baseURL    string
+	httpClient *http.Client
+	steps      []oauthStep
+}
+
+func (t *oauthTester) record(name, spec string, result oauthResult, format string, args ...any) {
+	t.steps = append(t.steps, oauthStep{Name: name, Spec: spec, Result: result, Detail: fmt.Sprintf(format, args...)})
+}
+
+func newMCPOAuthTestCommand() *Command {
+	cmd := newCommand("oauth-test", "[url]", "Walk the OAuth 2.1 flow Claude.ai uses and report where the server deviates from the specs.")
+	orgID := cmd.Flags.String("org-id", "strunzctl-test", "organization ID for the Claude.ai start-auth path")
+	scope := cmd.Flags.String("scope", "read", "scope to request")
+
+	cmd.Run = func(args []string) error {
+		if len(args) > 1 {
+			return cmd.ExactArgs(args, 1)
+		}
+		serverURL := defaultServerURL
+		if len(args) == 1 {
+			serverURL = args[0]
+		}
+		tester := &oauthTester{
+			baseURL: strings.TrimRight(serverURL, "/"),
+			httpClient: &http.Client{
+				Timeout:       30 * time.Second,
+				CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+			},
+		}
+		if err := tester.run(*orgID, *scope); err != nil {
+			return err
+		}
+
+		fmt.Printf("\n🔐 OAuth conformance of %s\n", serverURL)
+		failed := 0
+		for _, step := range tester.steps {
+			if step.Result == oauthFail {
+				failed++
+			}
+			fmt.Printf("  %s %-30s %-16s %s\n", step.Result, step.Name, step.Spec, step.Detail)
+		}
+		if failed > 0 {
+			return fmt.Errorf("%w: %d OAuth step(s) deviate from the spec", errPolicy, failed)
+		}
+		fmt.Println("\n✅ No deviations that break Claude.ai")
+		return nil
+	}
+	return cmd
+}
+
+// run performs all steps; only an unreachable server is an error
+func (t *oauthTester) run(orgID, scope string) error {
+	metadata, err := t.discover()
+	if err != nil {
+		return err
+	}
+	t.startAuth(orgID, metadata)
+
+	clientID := t.register(metadata, scope)
+	if clientID == "" {
+		t.record("authorization code + PKCE", "OAuth 2.1 §4.1", oauthSkip, "no client to authorize")
+		return nil
+	}
+
+	verifier, challenge := pkcePair()
+	code := t.authorize(metadata, clientID, scope, challenge)
+	if code == "" {
+		return nil
+	}
+	token := t.exchange(metadata, clientID, code, verifier)
+	if token == nil {
+		return nil
+	}
+	t.replay(metadata, clientID, code, verifier)
+	t.pkceEnforced(metadata, clientID, scope)
+	t.refresh(metadata, clientID, token)
+	return nil
+}
+
+// discover reads the authorization server metadata, falling back to the
+// default endpoint paths of the MCP authorization spec
+func (t *oauthTester) discover() (*oauthServerMetadata, error) {
+	const name, spec = "authorization server metadata", "RFC 8414"
+	resp, err := t.httpClient.Get(t.baseURL + "/.well-known/oauth-authorization-server")
+	if err != nil {
+		return nil, fmt.Errorf("OAuth discovery failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var metadata oauthServerMetadata
+	if resp.StatusCode != http.StatusOK || json.NewDecoder(resp.Body).Decode(&metadata) != nil {
+		t.record(name, spec, oauthWarn, "not served (%s), using default endpoints", resp.Status)
+		return &oauthServerMetadata{
+			AuthorizationEndpoint: t.baseURL + "/authorize",
+			TokenEndpoint:         t.baseURL + "/token",
+			RegistrationEndpoint:  t.baseURL + "/register",
+		}, nil
+	}
+
+	var problems []string
+	if metadata.Issuer == "" {
+		problems = append(problems, "no issuer")
+	}
+	if metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" {
+		problems = append(problems, "authorization or token endpoint missing")
+	}
+	if !slices.Contains(metadata.ResponseTypesSupported, "code") {
+		problems = append(problems, "response type code not supported")
+	}
+	if !slices.Contains(metadata.CodeChallengeMethodsSupported, "S256") {
+		problems = append(problems, "PKCE S256 not advertised")
+	}
+	if len(metadata.GrantTypesSupported) > 0 && !slices.Contains(metadata.GrantTypesSupported, "refresh_token") {
+		problems = append(problems, "refresh_token grant not advertised")
+	}
+	if len(problems) > 0 {
+		t.record(name, spec, oauthFail, "%s", strings.Join(problems, "; "))
+	} else {
+		t.record(name, spec, oauthPass, "issuer %s", metadata.Issuer)
+	}
+	return &metadata, nil
+}
+
+// startAuth checks the path Claude.ai opens when a user connects the server
+func (t *oauthTester) startAuth(orgID string, metadata *oauthServerMetadata) {
+	const name, spec = "claude.ai start-auth", "Claude.ai"
+	authID := randomToken(12)
+	path := fmt.Sprintf("/api/organizations/%s/mcp/start-auth/%s?redirect_url=%s", orgID, authID, url.QueryEscape(oauthRedirectURI))
+	resp, err := t.httpClient.Get(t.baseURL + path)
+	if err != nil {
+		t.record(name, spec, oauthFail, "%v", err)
+		return
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		var body struct {
+			AuthNotRequired bool `json:"auth_not_required"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&body) == nil && body.AuthNotRequired {
+			t.record(name, spec, oauthPass, "auth_not_required, Claude.ai connects without OAuth")
+		} else {
+			t.record(name, spec, oauthFail, "200 without auth_not_required and without a redirect")
+		}
+	case http.StatusFound, http.StatusSeeOther, http.StatusTemporaryRedirect:
+		location, err := resp.Location()
+		if err != nil {
+			t.record(name, spec, oauthFail, "redirect without a valid Location")
+			return
+		}
+		query := location.Query()
+		if !strings.HasPrefix(location.String(), strings.TrimRight(metadata.AuthorizationEndpoint, "/")) {
+			t.record(name, spec, oauthWarn, "redirects to %s, not the authorization endpoint", location.Redacted())
+		} else if query.Get("response_type") != "code" || query.Get("client_id") == "" {
+			t.record(name, spec, oauthFail, "redirect lacks response_type=code or client_id")
+		} else {
+			t.record(name, spec, oauthPass, "redirects to the authorization endpoint")
+		}
+	default:
+		t.record(name, spec, oauthFail, "returned %s", resp.Status)
+	}
+}
+
+// register performs dynamic client registration and returns the client ID
+func (t *oauthTester) register(metadata *oauthServerMetadata, scope string) string {
+	const name, spec = "dynamic client registration", "RFC 7591"
+	if metadata.RegistrationEndpoint == "" {
+		t.record(name, spec, oauthFail, "no registration_endpoint; Claude.ai needs dynamic registration")
+		return ""
+	}
+	body, _ := json.Marshal(map[string]any{
+		"client_name":                "strunzctl oauth-test",
+		"redirect_uris":              []string{oauthRedirectURI},
+		"grant_types":                []string{"authorization_code", "refresh_token"},
+		"response_types":             []string{"code"},
+		"token_endpoint_auth_method": "none",
+		"scope":                      scope,
+	})
+	resp, err := t.httpClient.Post(metadata.RegistrationEndpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.record(name, spec, oauthFail, "%v", err)
+		return ""
+	}
+	defer resp.Body.Close()
+
+	var client struct {
+		ClientID     string   `json:"client_id"`
+		RedirectURIs []string `json:"redirect_uris"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&client); err != nil || client.ClientID == "" {
+		t.record(name, spec, oauthFail, "%s without a client_id", resp.Status)
+		return ""
+	}
+	switch {
+	case resp.StatusCode != http.StatusCreated:
+		t.record(name, spec, oauthWarn, "client %s registered with %s, expected 201 Created", client.ClientID, resp.Status)
+	case !slices.Contains(client.RedirectURIs, oauthRedirectURI):
+		t.record(name, spec, oauthWarn, "client %s registered but redirect_uris not echoed", client.ClientID)
+	default:
+		t.record(name, spec, oauthPass, "client %s", client.ClientID)
+	}
+	return client.ClientID
+}
+
+// authorize requests a code and returns it, or "" if the server needs a
+// user to log in or misbehaves
+func (t *oauthTester) authorize(metadata *oauthServerMetadata, clientID, scope, challenge string) string {
+	const name, spec = "authorization request", "OAuth 2.1 §4.1"
+	state := randomToken(16)
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {clientID},
+		"redirect_uri":          {oauthRedirectURI},
+		"scope":                 {scope},
+		"state":                 {state},
+		"code_challenge":        {challenge},
+		"code_challenge_method": {"S256"},
+	}
+	resp, err := t.httpClient.Get(metadata.AuthorizationEndpoint + "?" + query.Encode())
+	if err != nil {
+		t.record(name, spec, oauthFail, "%v", err)
+		return ""
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		t.record(name, spec, oauthSkip, "shows a login page; token steps need a user and were skipped")
+		return ""
+	}
+	location, err := resp.Location()
+	if err != nil {
+		t.record(name, spec, oauthFail, "returned %s without a redirect", resp.Status)
+		return ""
+	}
+	switch redirect := location.Query(); {
+	case !strings.HasPrefix(location.String(), oauthRedirectURI):
+		t.record(name, spec, oauthFail, "redirects to %s instead of the registered redirect_uri", location.Redacted())
+	case redirect.Get("error") != "":
+		t.record(name, spec, oauthFail, "error %s: %s", redirect.Get("error"), redirect.Get("error_description"))
+	case redirect.Get("state") != state:
+		t.record(name, spec, oauthFail, "state %q not returned unchanged", redirect.Get("state"))
+	case redirect.Get("code") == "":
+		t.record(name, spec, oauthFail, "redirect has no code")
+	default:
+		t.record(name, spec, oauthPass, "code issued, state preserved")
+		return redirect.Get("code")
+	}
+	return ""
+}
+
+// exchange redeems the code for tokens
+func (t *oauthTester) exchange(metadata *oauthServerMetadata, clientID, code, verifier string) *oauthToken {
+	const name, spec = "token exchange", "OAuth 2.1 §4.1.3"
+	status, token, err := t.token(metadata, url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {oauthRedirectURI},
+		"client_id":     {clientID},
+		"code_verifier": {verifier},
+	})
+	if err != nil {
+		t.record(name, spec, oauthFail, "%v", err)
+		return nil
+	}
+	var problems []string
+	if status != http.StatusOK || token.AccessToken == "" {
+		t.record(name, spec, oauthFail, "%d %s", status, orNone(token.Error))
+		return nil
+	}
+	if !strings.EqualFold(token.TokenType, "bearer") {
+		problems = append(problems, fmt.Sprintf("token_type %q, expected Bearer", token.TokenType))
+	}
+	if token.ExpiresIn <= 0 {
+		problems = append(problems, "no expires_in")
+	}
+	if len(problems) > 0 {
+		t.record(name, spec, oauthWarn, "%s", strings.Join(problems, "; "))
+	} else {
+		t.record(name, spec, oauthPass, "access token expires in %ds", token.ExpiresIn)
+	}
+	return token
+}
+
+// replay redeems the same code again, which must fail
+func (t *oauthTester) replay(metadata *oauthServerMetadata, clientID, code, verifier string) {
+	const name, spec = "code is single-use", "OAuth 2.1 §4.1.2"
+	status, token, err := t.token(metadata, url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {oauthRedirectURI},
+		"client_id":     {clientID},
+		"code_verifier": {verifier},
+	})
+	switch {
+	case err != nil:
+		t.record(name, spec, oauthFail, "%v", err)
+	case status == http.StatusOK && token.AccessToken != "":
+		t.record(name, spec, oauthFail, "a redeemed code was accepted again")
+	case token.Error != "invalid_grant":
+		t.record(name, spec, oauthWarn, "rejected with %d %s, expected invalid_grant", status, orNone(token.Error))
+	default:
+		t.record(name, spec, oauthPass, "replay rejected with invalid_grant")
+	}
+}
+
+// pkceEnforced redeems a fresh code with the wrong verifier, which must fail
+func (t *oauthTester) pkceEnforced(metadata *oauthServerMetadata, clientID, scope string) {
+	const name, spec = "PKCE verifier enforced", "RFC 7636 §4.6"
+	_, challenge := pkcePair()
+	steps := len(t.steps)
+	code := t.authorize(metadata, clientID, scope, challenge)
+	// The second authorization is not a step of its own
+	t.steps = t.steps[:steps]
+	if code == "" {
+		t.record(name, spec, oauthSkip, "no second code issued")
+		return
+	}
+	wrongVerifier, _ := pkcePair()
+	status, token, err := t.token(metadata, url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {oauthRedirectURI},
+		"client_id":     {clientID},
+		"code_verifier": {wrongVerifier},
+	})
+	switch {
+	case err != nil:
+		t.record(name, spec, oauthFail, "%v", err)
+	case status == http.StatusOK && token.AccessToken != "":
+		t.record(name, spec, oauthFail, "a code was redeemed with the wrong code_verifier")
+	default:
+		t.record(name, spec, oauthPass, "wrong verifier rejected with %d %s", status, orNone(token.Error))
+	}
+}
+
+// refresh uses the refresh token to obtain a new access token
+func (t *oauthTester) refresh(metadata *oauthServerMetadata, clientID string, token *oauthToken) {
+	const name, spec = "token refresh", "OAuth 2.1 §4.3"
+	if token.RefreshToken == "" {
+		t.record(name, spec, oauthFail, "no refresh_token issued; Claude.ai disconnects when the access token expires")
+		return
+	}
+	status, refreshed, err := t.token(metadata, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {token.RefreshToken},
+		"client_id":     {clientID},
+	})
+	switch {
+	case err != nil:
+		t.record(name, spec, oauthFail, "%v", err)
+	case status != http.StatusOK || refreshed.AccessToken == "":
+		t.record(name, spec, oauthFail, "%d %s", status, orNone(refreshed.Error))
+	case refreshed.AccessToken == token.AccessToken:
+		t.record(name, spec, oauthWarn, "the same access token was returned")
+	default:
+		t.record(name, spec, oauthPass, "new access token issued")
+	}
+}
+
+// token posts a form to the token endpoint
+func (t *oauthTester) token(metadata *oauthServerMetadata, form url.Values) (int, *oauthToken, error) {
+	resp, err := t.httpClient.PostForm(metadata.TokenEndpoint, form)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, err
+	}
+	var token oauthToken
+	if err := json.Unmarshal(body, &token); err != nil {
+		return resp.StatusCode, nil, fmt.Errorf("%s with a non-JSON body", resp.Status)
+	}
+	return resp.StatusCode, &token, nil
+}
+
+// pkcePair returns a code verifier and its S256 challenge
+func pkcePair() (verifier, challenge string) {
+	verifier = randomToken(32)
+	sum := sha256.Sum256([]byte(verifier))
+	return verifier, base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// randomToken returns n random bytes, base64url encoded
+func randomToken(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
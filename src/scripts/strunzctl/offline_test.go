@@ -0,0 +1,447 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// offlineStep runs strunzctl once and checks its exit code and that the
+// combined output contains every string of Output. {dir} in Args is
+// replaced by the case's scratch directory, {testdata} by the directory
+// of the golden directory, which holds the input fixtures, and {api} by
+// the URL of the fake API.
+type offlineStep struct {
+	Args   []string
+	Exit   int
+	Output []string
+	// Golden names the file under the golden directory stdout must equal
+	// once the scratch directory and fake API address are replaced by
+	// {dir} and {api}, and Mask matches (or their first group) by {masked}
+	Golden string
+	Mask   []string
+	// Files maps files the step writes, relative to the scratch directory,
+	// to the golden files they must equal
+	Files map[string]string
+	// Cache keeps the case's response cache, which steps bypass otherwise
+	Cache bool
+}
+
+// offlineCase runs its steps against freshly reset fixtures, so state
+// changed by one step is seen by the next but never by another case
+type offlineCase struct {
+	Name   string
+	Faults string
+	// Fixtures is a fixtures file under {testdata} replacing the fields
+	// it sets of the run's fixtures
+	Fixtures string
+	Env      []string
+	Steps    []offlineStep
+}
+
+// offlineCases cover every exit code, the retry paths of the GitHub API
+// and registry clients and each output format of the commands that read
+// the package, the corpus, logs and the MCP server
+var offlineCases = []offlineCase{
+	{Name: "packages info", Steps: []offlineStep{
+		{Args: []string{"packages", "info"}, Output: []string{"Name: strunzknowledge", "Visibility: public"}},
+	}},
+	{Name: "packages versions", Steps: []offlineStep{
+		{Args: []string{"packages", "versions", "--limit", "0"}, Golden: "packages-versions.txt"},
+		{Args: []string{"packages", "versions", "--sort", "tag-semver", "--order", "asc"}, Golden: "packages-versions-semver.txt"},
+	}},
+	{Name: "packages report", Steps: []offlineStep{
+		{Args: []string{"packages", "report"}, Golden: "packages-report.md", Mask: []string{`Generated: (.+)`}},
+		{Args: []string{"packages", "report", "--format", "csv"}, Golden: "packages-report.csv"},
+		{Args: []string{"packages", "report", "--format", "missing"}, Exit: exitUsage, Output: []string{"no format plugin", "strunzctl-format-missing"}},
+	}},
+	{Name: "GraphQL outage falls back to REST", Faults: "/graphql=502", Steps: []offlineStep{
+		{Args: []string{"--max-retries", "0", "packages", "report"}, Output: []string{"falling back to REST"}, Golden: "packages-report.md", Mask: []string{`Generated: (.+)`}},
+	}},
+	{Name: "offline mode answers from cached snapshots", Steps: []offlineStep{
+		{Args: []string{"packages", "report"}, Cache: true, Golden: "packages-report.md", Mask: []string{`Generated: (.+)`}},
+		{Args: []string{"--offline", "packages", "report"}, Cache: true, Output: []string{"Offline: output is from cached snapshots"},
+			Golden: "packages-report-offline.md", Mask: []string{`Generated: (.+)`, `Offline snapshot: cached (.+)`}},
+		{Args: []string{"--offline", "image", "inspect", "9.9.9"}, Cache: true, Exit: exitUnavailable, Output: []string{"not available offline"}},
+	}},
+	{Name: "kb process", Steps: []offlineStep{
+		{Args: []string{"kb", "process", "{testdata}/kb/raw", "--output-dir", "{dir}/markdown"}, Golden: "kb-process.txt",
+			Files: map[string]string{"markdown/forum/fitness/thread-1.md": "kb-process-thread.md"}},
+		{Args: []string{"kb", "process", "{testdata}/kb/raw", "--output-dir", "{dir}/markdown", "--check"}, Output: []string{"0 written, 1 unchanged"}},
+	}},
+	{Name: "kb stats", Steps: []offlineStep{
+		{Args: []string{"kb", "stats", "--metadata", "{testdata}/kb/metadata.json"}, Golden: "kb-stats.md"},
+		{Args: []string{"kb", "stats", "--metadata", "{testdata}/kb/metadata.json", "--format", "json"}, Golden: "kb-stats.json"},
+	}},
+	{Name: "kb export", Steps: []offlineStep{
+		{Args: []string{"kb", "export", "--metadata", "{testdata}/kb/metadata.json", "--filter", "source=news"}, Golden: "kb-export.jsonl"},
+		{Args: []string{"kb", "export", "--metadata", "{testdata}/kb/metadata.json", "--format", "json", "--fields", "id,text,source", "--since", "2025-01-01"}, Golden: "kb-export.json"},
+	}},
+	{Name: "logs analyze", Steps: []offlineStep{
+		{Args: []string{"logs", "analyze", "{testdata}/logs/server.log"}, Golden: "logs-analyze.txt"},
+		{Args: []string{"logs", "analyze", "{testdata}/logs/server.log", "--format", "json"}, Golden: "logs-analyze.json"},
+	}},
+	{Name: "ask", Steps: []offlineStep{
+		{Args: []string{"ask", "--url", "{api}", "--limit", "3", "vitamin", "d", "blood"}, Golden: "ask.txt", Mask: []string{`on \{api\} in (\S+)`}},
+		{Args: []string{"ask", "--url", "{api}", "--format", "json", "vitamin", "d", "blood"}, Golden: "ask.json", Mask: []string{`"duration_ms": (\d+)`}},
+		{Args: []string{"ask", "--url", "{api}", "--tool", "missing", "vitamin"}, Exit: exitFailure, Output: []string{"Unknown tool: missing"}},
+	}},
+	{Name: "image licenses and sbom", Fixtures: "fixtures/sbom.json", Steps: []offlineStep{
+		{Args: []string{"image", "licenses", "1.1.0", "--output", "{dir}/licenses.md"}, Golden: "licenses.txt",
+			Files: map[string]string{"licenses.md": "licenses.md"}},
+		{Args: []string{"image", "licenses", "1.1.0", "--format", "json", "--output", "{dir}/licenses.json"}, Files: map[string]string{"licenses.json": "licenses.json"}},
+		{Args: []string{"image", "sbom", "1.1.0"}, Golden: "sbom.spdx.json"},
+	}},
+	{Name: "version", Steps: []offlineStep{
+		{Args: []string{"version", "--output", "json"}, Golden: "version.json",
+			Mask: []string{`"commit": "([^"]*)"`, `"date": "([^"]*)"`, `"go_version": "([^"]+)"`, `"platform": "([^"]+)"`}},
+	}},
+	{Name: "YAML errors name the line", Steps: []offlineStep{
+		{Args: []string{"--config", "{testdata}/config/list.yaml", "packages", "info"}, Exit: exitFailure, Output: []string{"line 3: lists are not supported"}},
+		{Args: []string{"schedule", "--config", "{testdata}/config/schedule.yaml", "--check"}, Exit: exitUsage, Output: []string{"line 4: cron: values starting with '@' must be quoted"}},
+	}},
+	{Name: "packages releases", Steps: []offlineStep{
+		{Args: []string{"packages", "releases"}, Golden: "packages-releases.txt"},
+	}},
+	{Name: "German output", Steps: []offlineStep{
+		{Args: []string{"--lang", "de", "packages", "versions", "--limit", "0"}, Golden: "packages-versions-de.txt"},
+		{Args: []string{"--lang", "de", "packages", "report"}, Golden: "packages-report-de.md", Mask: []string{`Erstellt: (.+)`}},
+		{Args: []string{"--lang", "fr", "packages", "info"}, Exit: exitUsage, Output: []string{"unsupported language", "want en or de"}},
+	}},
+	{Name: "German report without releases", Fixtures: "fixtures/no-releases.json", Steps: []offlineStep{
+		{Args: []string{"--lang", "de", "packages", "report"}, Golden: "packages-report-de-no-releases.md", Mask: []string{`Erstellt: (.+)`}},
+	}},
+	{Name: "fleet", Steps: []offlineStep{
+		{Args: []string{"fleet", "versions", "--targets", "strunzknowledge,longevitycoach/missing"}, Exit: exitNotFound, Golden: "fleet-versions.txt"},
+		{Args: []string{"fleet", "prune", "--keep-last", "1", "--keep-tagged=false", "--keep-semver=false", "--max-age-days", "0"}, Exit: exitUsage, Output: []string{"pass --yes"}},
+		{Args: []string{"fleet", "prune", "--keep-last", "1", "--keep-tagged=false", "--keep-semver=false", "--max-age-days", "0", "--yes"}, Golden: "fleet-prune.txt"},
+		{Args: []string{"fleet", "versions"}, Output: []string{"Total: 1 version(s), 0 untagged, in 1 of 1 package(s)"}},
+	}},
+	{Name: "image labels", Steps: []offlineStep{
+		{Args: []string{"image", "labels", "1.1.0"}, Output: []string{"All required labels present"}, Golden: "image-labels.txt"},
+	}},
+	{Name: "image inspect of a manifest list", Steps: []offlineStep{
+		{Args: []string{"image", "inspect", "1.0.0", "--platform", "linux/arm64"}, Golden: "image-inspect.txt"},
+		{Args: []string{"image", "inspect", "1.0.0", "--platform", "linux/arm64", "--format", "json"}, Golden: "image-inspect.json"},
+	}},
+	{Name: "image pull", Steps: []offlineStep{
+		{Args: []string{"image", "pull", "1.1.0", "--output", "{dir}/image.tar"}, Golden: "image-pull-docker.txt"},
+		{Args: []string{"image", "pull", "1.0.0", "--format", "oci", "--all-platforms", "--output", "{dir}/oci.tar"}, Golden: "image-pull-oci.txt"},
+	}},
+	{Name: "promote moves a pinned tag", Steps: []offlineStep{
+		{Args: []string{"image", "promote", "1.0.0", "stable"}, Output: []string{"stable moved from (none)"}},
+		{Args: []string{"deploy", "pin", "stable", "--file", "{dir}/image.pins"}, Output: []string{"Pinned in {dir}/image.pins"}},
+		{Args: []string{"deploy", "pin", "--verify", "--file", "{dir}/image.pins"}, Output: []string{"All pins match their tags"}},
+		{Args: []string{"image", "promote", "1.1.0", "stable"}},
+		{Args: []string{"deploy", "pin", "--verify", "--file", "{dir}/image.pins"}, Exit: exitPolicy, Golden: "deploy-pin-verify-moved.txt"},
+		{Args: []string{"audit", "show"}, Golden: "audit-show.txt", Mask: []string{`\d{4}-\d\d-\d\d \d\d:\d\d`}},
+		{Args: []string{"audit", "show", "--format", "jsonl"}, Golden: "audit-show.jsonl", Mask: []string{`"at":"([^"]+)"`}},
+	}},
+	{Name: "missing tag", Steps: []offlineStep{
+		{Args: []string{"image", "inspect", "9.9.9"}, Exit: exitNotFound, Output: []string{"404 Not Found"}},
+	}},
+	{Name: "rejected token", Env: []string{"GITHUB_TOKEN=revoked-token"}, Steps: []offlineStep{
+		{Args: []string{"packages", "info"}, Exit: exitAuth, Output: []string{"Bad credentials"}},
+		{Args: []string{"image", "labels", "1.1.0"}, Exit: exitAuth, Output: []string{"registry token request returned 401"}},
+	}},
+	{Name: "gateway errors are retried", Faults: "/versions=503x2", Steps: []offlineStep{
+		{Args: []string{"packages", "versions"}, Output: []string{"Gateway error, retrying", "1.1.0 (ID: 1003"}},
+	}},
+	{Name: "rate limits are retried", Faults: "/manifests/=429x1", Steps: []offlineStep{
+		{Args: []string{"image", "labels", "latest"}, Output: []string{"Rate limited, retrying", "All required labels present"}},
+	}},
+	{Name: "outage after retries", Faults: "/packages/container/=503", Steps: []offlineStep{
+		{Args: []string{"--max-retries", "1", "packages", "info"}, Exit: exitUnavailable, Output: []string{"503 Service Unavailable"}},
+	}},
+	{Name: "reset while pulling", Faults: "/blobs/=reset", Steps: []offlineStep{
+		{Args: []string{"--max-retries", "0", "image", "pull", "1.1.0", "--output", "{dir}/image.tar"}, Exit: exitUnavailable},
+	}},
+}
+
+// offlineEnvironment is the environment of the tested command: the
+// caller's without any credentials or settings, a scratch home, a fixed
+// time zone and git identity, and the fake API
+func offlineEnvironment(api *fakeAPI, caPath, home string, extra []string) []string {
+	var env []string
+	for _, variable := range os.Environ() {
+		name, _, _ := strings.Cut(variable, "=")
+		if strings.HasPrefix(name, "STRUNZCTL_") || strings.HasPrefix(name, "GITHUB_") || strings.HasPrefix(name, "GH_") ||
+			strings.HasPrefix(name, "RAILWAY_") || strings.HasPrefix(name, "XDG_") || strings.HasPrefix(name, "GIT_CONFIG") ||
+			name == "HOME" || name == "TZ" {
+			continue
+		}
+		env = append(env, variable)
+	}
+	env = append(env,
+		"HOME="+home,
+		"XDG_CACHE_HOME="+filepath.Join(home, ".cache"),
+		"XDG_CONFIG_HOME="+filepath.Join(home, ".config"),
+		"XDG_STATE_HOME="+filepath.Join(home, ".local", "state"),
+		"TZ=UTC",
+		"GIT_CONFIG_COUNT=1", "GIT_CONFIG_KEY_0=user.email", "GIT_CONFIG_VALUE_0=offline@strunzctl.test",
+		"STRUNZCTL_CREDENTIALS_HELPER=none",
+	)
+	env = append(env, api.environment(caPath)...)
+	return append(env, extra...)
+}
+
+// offlineRun is one run of the offline cases
+type offlineRun struct {
+	binary    string
+	api       *fakeAPI
+	fixtures  fakeFixtures
+	caPath    string
+	goldenDir string
+	// testdataDir holds the fixtures the steps read
+	testdataDir string
+	// update rewrites the golden files instead of comparing with them
+	update  bool
+	updated int
+}
+
+// normalize replaces what differs between runs in the output of a step
+func (o *offlineRun) normalize(output []byte, dir string, masks []string) ([]byte, error) {
+	output = bytes.ReplaceAll(output, []byte(dir), []byte("{dir}"))
+	output = bytes.ReplaceAll(output, []byte(o.testdataDir), []byte("{testdata}"))
+	output = bytes.ReplaceAll(output, []byte(o.api.server.URL), []byte("{api}"))
+	output = bytes.ReplaceAll(output, []byte(o.api.host()), []byte("{api}"))
+	for _, mask := range masks {
+		pattern, err := regexp.Compile(mask)
+		if err != nil {
+			return nil, fmt.Errorf("invalid mask %q: %w", mask, err)
+		}
+		output = pattern.ReplaceAllFunc(output, func(match []byte) []byte {
+			// A group masks only itself, keeping the context around it
+			group := pattern.FindSubmatchIndex(match)
+			if len(group) < 4 || group[2] < 0 {
+				return []byte("{masked}")
+			}
+			return slices.Concat(match[:group[2]], []byte("{masked}"), match[group[3]:])
+		})
+	}
+	return output, nil
+}
+
+// compareGolden checks output against a golden file, or rewrites the
+// file when updating. It returns how they differ.
+func (o *offlineRun) compareGolden(name string, output []byte) (string, error) {
+	path := filepath.Join(o.goldenDir, name)
+	if o.update {
+		if existing, err := os.ReadFile(path); err == nil && bytes.Equal(existing, output) {
+			return "", nil
+		}
+		if err := os.MkdirAll(o.goldenDir, 0o755); err != nil {
+			return "", err
+		}
+		o.updated++
+		return "", os.WriteFile(path, output, 0o644)
+	}
+	want, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Sprintf("%s does not exist; rerun with -update to create it", path), nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if bytes.Equal(want, output) {
+		return "", nil
+	}
+	return fmt.Sprintf("output differs from %s (- golden, + got):\n%s", path, lineDiff(string(want), string(output))), nil
+}
+
+// lineDiff lists the lines only in want with - and those only in got
+// with +, in order, by their longest common subsequence
+func lineDiff(want, got string) string {
+	a, b := strings.Split(want, "\n"), strings.Split(got, "\n")
+	common := make([][]int, len(a)+1)
+	for i := range common {
+		common[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else {
+				common[i][j] = max(common[i+1][j], common[i][j+1])
+			}
+		}
+	}
+	var diff strings.Builder
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			i, j = i+1, j+1
+		case i < len(a) && (j == len(b) || common[i+1][j] >= common[i][j+1]):
+			fmt.Fprintf(&diff, "- %s\n", a[i])
+			i++
+		default:
+			fmt.Fprintf(&diff, "+ %s\n", b[j])
+			j++
+		}
+	}
+	return strings.TrimSuffix(diff.String(), "\n")
+}
+
+// runCase runs the steps of a case and returns why the first failing one
+// failed, with its output
+func (o *offlineRun) runCase(test offlineCase) (string, []byte, error) {
+	fixtures := o.fixtures
+	if test.Fixtures != "" {
+		data, err := os.ReadFile(filepath.Join(o.testdataDir, test.Fixtures))
+		if err != nil {
+			return "", nil, err
+		}
+		// Decoding into a copy keeps the run's slices from being overwritten
+		base, err := json.Marshal(o.fixtures)
+		if err != nil {
+			return "", nil, err
+		}
+		fixtures = fakeFixtures{}
+		if err := json.Unmarshal(base, &fixtures); err != nil {
+			return "", nil, err
+		}
+		if err := json.Unmarshal(data, &fixtures); err != nil {
+			return "", nil, fmt.Errorf("%s: %w", test.Fixtures, err)
+		}
+	}
+	if err := o.api.reset(fixtures); err != nil {
+		return "", nil, err
+	}
+	var faults []*fakeFault
+	for _, value := range splitList(test.Faults) {
+		fault, err := parseFakeFault(value)
+		if err != nil {
+			return "", nil, err
+		}
+		faults = append(faults, fault)
+	}
+	o.api.setFaults(faults, 0)
+
+	dir, err := os.MkdirTemp("", "strunzctl-offline-")
+	if err != nil {
+		return "", nil, err
+	}
+	defer os.RemoveAll(dir)
+	env := offlineEnvironment(o.api, o.caPath, dir, test.Env)
+	expand := strings.NewReplacer("{dir}", dir, "{testdata}", o.testdataDir, "{api}", o.api.server.URL).Replace
+
+	for _, step := range test.Steps {
+		var args []string
+		if !step.Cache {
+			args = append(args, "--no-cache")
+		}
+		for _, arg := range step.Args {
+			args = append(args, expand(arg))
+		}
+		var stdout, stderr bytes.Buffer
+		cmd := newProcess(o.binary, args...)
+		cmd.Env, cmd.Dir, cmd.Stdin = env, dir, nil
+		cmd.Stdout, cmd.Stderr = &stdout, &stderr
+		err := cmd.Run()
+		code := 0
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			code = exitErr.ExitCode()
+		} else if err != nil {
+			return "", nil, err
+		}
+		output := append(stdout.Bytes(), stderr.Bytes()...)
+		command := "strunzctl " + strings.Join(step.Args, " ")
+		if code != step.Exit {
+			return fmt.Sprintf("%s exited %d, want %d", command, code, step.Exit), output, nil
+		}
+		for _, want := range step.Output {
+			if !bytes.Contains(output, []byte(expand(want))) {
+				return fmt.Sprintf("%s output lacks %q", command, expand(want)), output, nil
+			}
+		}
+		for _, file := range slices.Sorted(maps.Keys(step.Files)) {
+			written, err := os.ReadFile(filepath.Join(dir, file))
+			if err != nil {
+				return fmt.Sprintf("%s did not write %s: %v", command, file, err), output, nil
+			}
+			normalized, err := o.normalize(written, dir, step.Mask)
+			if err != nil {
+				return "", nil, err
+			}
+			if reason, err := o.compareGolden(step.Files[file], normalized); err != nil || reason != "" {
+				return command + ": " + file + ": " + reason, nil, err
+			}
+		}
+		if step.Golden == "" {
+			continue
+		}
+		normalized, err := o.normalize(stdout.Bytes(), dir, step.Mask)
+		if err != nil {
+			return "", nil, err
+		}
+		if reason, err := o.compareGolden(step.Golden, normalized); err != nil || reason != "" {
+			return command + ": " + reason, stderr.Bytes(), err
+		}
+	}
+	return "", nil, nil
+}
+
+var (
+	updateGolden = flag.Bool("update", false, "rewrite the golden files with the current output; review the diff before committing")
+	testBinary   = flag.String("binary", "", "strunzctl binary to test (default: one built from this package)")
+)
+
+// TestOffline runs the commands against the fake API and checks their exit
+// codes and output against the golden files, with no network or token.
+// go test -run 'TestOffline/<case>' runs one case.
+func TestOffline(t *testing.T) {
+	path := *testBinary
+	if path == "" {
+		path = filepath.Join(t.TempDir(), "strunzctl")
+		build := exec.Command("go", "build", "-o", path, ".")
+		if output, err := build.CombinedOutput(); err != nil {
+			t.Fatalf("go build failed: %v\n%s", err, output)
+		}
+	}
+	fixtures := defaultFakeFixtures()
+	api, err := newFakeAPI(fixtures)
+	if err != nil {
+		t.Fatal(err)
+	}
+	api.start(nil)
+	defer api.server.Close()
+	caPath := filepath.Join(t.TempDir(), "ca.pem")
+	if err := api.writeCA(caPath); err != nil {
+		t.Fatal(err)
+	}
+	golden, err := filepath.Abs(filepath.Join("testdata", "golden"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	runner := &offlineRun{binary: path, api: api, fixtures: fixtures, caPath: caPath, goldenDir: golden, testdataDir: filepath.Dir(golden), update: *updateGolden}
+
+	for _, test := range offlineCases {
+		t.Run(test.Name, func(t *testing.T) {
+			reason, output, err := runner.runCase(test)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if reason != "" {
+				if output := strings.TrimSpace(string(output)); output != "" {
+					reason += "\n" + output
+				}
+				t.Error(reason)
+			}
+		})
+	}
+	if *updateGolden {
+		t.Logf("updated %d golden file(s) in %s", runner.updated, runner.goldenDir)
+	}
+}
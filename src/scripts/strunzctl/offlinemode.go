@@ -0,0 +1,62 @@
+package main
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// offlineStaleAfter is the snapshot age past which --offline warns that
+// its output may no longer match the registry
+const offlineStaleAfter = 24 * time.Hour
+
+// offlineSnapshots records the cached responses --offline answered with,
+// so the command can say how old its data is
+var offlineSnapshots struct {
+	sync.Mutex
+	served         int
+	oldest, newest time.Time
+}
+
+// recordOfflineSnapshot counts a cached response served instead of a request
+func recordOfflineSnapshot(url string, storedAt time.Time) {
+	slog.Debug("Offline: served from cache", "url", url, "stored_at", storedAt)
+	offlineSnapshots.Lock()
+	defer offlineSnapshots.Unlock()
+	offlineSnapshots.served++
+	if offlineSnapshots.oldest.IsZero() || storedAt.Before(offlineSnapshots.oldest) {
+		offlineSnapshots.oldest = storedAt
+	}
+	if storedAt.After(offlineSnapshots.newest) {
+		offlineSnapshots.newest = storedAt
+	}
+}
+
+// offlineSnapshotRange returns when the oldest and newest of the served
+// responses were cached, zero times when none was served
+func offlineSnapshotRange() (oldest, newest time.Time) {
+	offlineSnapshots.Lock()
+	defer offlineSnapshots.Unlock()
+	return offlineSnapshots.oldest, offlineSnapshots.newest
+}
+
+// warnOfflineSnapshots ends an --offline command with the age of the data
+// it showed: a warning once a snapshot is older than offlineStaleAfter
+func warnOfflineSnapshots() {
+	if !globalOptions.offline {
+		return
+	}
+	offlineSnapshots.Lock()
+	served, oldest := offlineSnapshots.served, offlineSnapshots.oldest
+	offlineSnapshots.Unlock()
+	if served == 0 {
+		return
+	}
+	age := time.Since(oldest).Round(time.Minute)
+	attrs := []any{"responses", served, "oldest", oldest.UTC().Format(time.RFC3339), "age", age}
+	if age > offlineStaleAfter {
+		slog.Warn("Offline: cached snapshots are stale, output may be outdated", attrs...)
+		return
+	}
+	slog.Info("Offline: output is from cached snapshots", attrs...)
+}
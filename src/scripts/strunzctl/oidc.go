@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tokenExchangeGrant is the RFC 8693 grant that trades the workflow's OIDC
+// token for an access token of another service
+const tokenExchangeGrant = "urn:ietf:params:oauth:grant-type:token-exchange"
+
+// actionsOIDCAvailable reports whether a GitHub Actions job may request
+// OIDC tokens, which needs `permissions: id-token: write`
+func actionsOIDCAvailable() bool {
+	return os.Getenv("GITHUB_ACTIONS") == "true" &&
+		os.Getenv("ACTIONS_ID_TOKEN_REQUEST_URL") != "" && os.Getenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN") != ""
+}
+
+// actionsIDToken requests a signed OIDC token for the running workflow
+func actionsIDToken(audience string) (string, error) {
+	target := os.Getenv("ACTIONS_ID_TOKEN_REQUEST_URL")
+	if audience != "" {
+		target += separator(target) + "audience=" + url.QueryEscape(audience)
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := doWithRetry(client, func() (*http.Request, error) {
+		req, err := http.NewRequest(http.MethodGet, target, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+os.Getenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN"))
+		return req, nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("OIDC token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("OIDC token request returned %s", resp.Status)
+	}
+	var token struct {
+		Value string `json:"value"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil || token.Value == "" {
+		return "", errors.New("OIDC token response has no token")
+	}
+	return token.Value, nil
+}
+
+// oidcExchange caches the access tokens the exchange endpoint returns
+type oidcExchange struct {
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+var oidcTokens oidcExchange
+
+// oidcApplies reports whether registry credentials for host come from the
+// OIDC token exchange
+func oidcApplies(host string) bool {
+	if config.OIDC.Exchange == "" || !actionsOIDCAvailable() {
+		return false
+	}
+	for _, registry := range splitList(config.OIDC.Registries) {
+		if registry == host {
+			return true
+		}
+	}
+	return false
+}
+
+// Token exchanges the workflow's OIDC token, reusing the result until a
+// minute before it expires
+func (e *oidcExchange) Token() (string, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.token != "" && time.Until(e.expires) > time.Minute {
+		return e.token, nil
+	}
+	idToken, err := actionsIDToken(config.OIDC.Audience)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type":           {tokenExchangeGrant},
+		"subject_token":        {idToken},
+		"subject_token_type":   {"urn:ietf:params:oauth:token-type:id_token"},
+		"requested_token_type": {"urn:ietf:params:oauth:token-type:access_token"},
+	}
+	if config.OIDC.Audience != "" {
+		form.Set("audience", config.OIDC.Audience)
+	}
+	if config.OIDC.Scope != "" {
+		form.Set("scope", config.OIDC.Scope)
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := doWithRetry(client, func() (*http.Request, error) {
+		req, err := http.NewRequest(http.MethodPost, config.OIDC.Exchange, strings.NewReader(form.Encode()))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Accept", "application/json")
+		return req, nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("OIDC token exchange failed: %w", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%w: OIDC token exchange returned %s: %s", errAuth, resp.Status, truncate(strings.TrimSpace(string(body)), 200))
+	}
+	var exchanged struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &exchanged); err != nil || exchanged.AccessToken == "" {
+		return "", errors.New("OIDC token exchange returned no access_token")
+	}
+	lifetime := time.Duration(exchanged.ExpiresIn) * time.Second
+	if lifetime <= 0 {
+		lifetime = 5 * time.Minute
+	}
+	slog.Debug("Exchanged the workflow OIDC token", "exchange", config.OIDC.Exchange, "expires_in", lifetime)
+	e.token, e.expires = exchanged.AccessToken, time.Now().Add(lifetime)
+	return e.token, nil
+}
+
+// oidcRegistryCredentials uses the exchanged token as the registry
+// password, falling back to the static credentials when the exchange fails
+func oidcRegistryCredentials(fallback func() (string, string, error)) func() (string, string, error) {
+	return func() (string, string, error) {
+		token, err := oidcTokens.Token()
+		if err != nil {
+			slog.Warn("OIDC credentials unavailable, using static credentials", "error", err)
+			return fallback()
+		}
+		return config.OIDC.Username, token, nil
+	}
+}
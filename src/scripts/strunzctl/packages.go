@@ -0,0 +1,358 @@
+package main
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+)
+
+// PackageInfo represents the GitHub package information
+type PackageInfo struct {
+	Name        string    `json:"name"`
+	PackageType string    `json:"package_type"`
+	Visibility  string    `json:"visibility"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	HTMLURL     string    `json:"html_url"`
+}
+
+// PackageVersion represents a package version
+type PackageVersion struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	Metadata  struct {
+		Container struct {
+			Tags []string `json:"tags"`
+		} `json:"container"`
+	} `json:"metadata"`
+}
+
+func newPackagesCommand() *Command {
+	return newGroup("packages", "Inspect the GitHub package and its versions.",
+		newPackagesInfoCommand(),
+		newPackagesVersionsCommand(),
+		newPackagesWatchCommand(),
+		newPackagesTagHistoryCommand(),
+		newPackagesReportCommand(),
+		newPackagesReleasesCommand(),
+		newPackagesDownloadsCommand(),
+		newPackagesReclaimCommand(),
+	)
+}
+
+func newPackagesInfoCommand() *Command {
+	cmd := newCommand("info", "", "Show package details and description guidance.")
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 0); err != nil {
+			return err
+		}
+		printf("Fetching package information for %s/%s...\n", config.Org, config.Package)
+
+		github, err := newGitHubClient()
+		if err != nil {
+			return err
+		}
+
+		// Get package details
+		packageInfo, err := getPackageInfo(github)
+		if err != nil {
+			if isNotFound(err) {
+				printf("GitHub cannot show %s/%s: it does not exist or the token's account has no access to it.\n", config.Org, config.Package)
+			}
+			if !errors.Is(err, errOffline) {
+				printf("Run `strunzctl doctor` to check the token, its scopes and access to the package.\n")
+			}
+			return err
+		}
+
+		// Display current package info
+		displayPackageInfo(packageInfo)
+
+		// Display description information
+		displayDescriptionInfo()
+		return nil
+	}
+	return cmd
+}
+
+func newPackagesVersionsCommand() *Command {
+	cmd := newCommand("versions", "", "List package versions with their image sizes and vulnerability counts.")
+	limit := cmd.Flags.Int("limit", 20, "number of most recent versions to list (0 for all)")
+	scan := cmd.Flags.Bool("scan", false, "scan listed versions that have no cached vulnerability scan")
+	scanner := cmd.Flags.String("scanner", "trivy", "vulnerability scanner used with --scan (trivy or grype)")
+	sortBy := cmd.Flags.String("sort", "created", "sort key: created, tag-semver or size")
+	order := cmd.Flags.String("order", "desc", "sort order: asc or desc")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 0); err != nil {
+			return err
+		}
+		options := versionListOptions{limit: *limit, sort: *sortBy}
+		switch *sortBy {
+		case "created", "tag-semver", "size":
+		default:
+			return fmt.Errorf("%w: unsupported sort key %q", errUsage, *sortBy)
+		}
+		switch *order {
+		case "asc":
+		case "desc":
+			options.descending = true
+		default:
+			return fmt.Errorf("%w: --order must be asc or desc", errUsage)
+		}
+		if *scan {
+			options.scanner = *scanner
+		}
+
+		github, err := newGitHubClient()
+		if err != nil {
+			return err
+		}
+
+		registry := newRegistryClient(config.Registry, imageRepository())
+		return displayPackageVersions(github, registry, options)
+	}
+	return cmd
+}
+
+func getPackageInfo(github *GitHubClient) (*PackageInfo, error) {
+	url := fmt.Sprintf("/orgs/%s/packages/container/%s", config.Org, config.Package)
+
+	var packageInfo PackageInfo
+	if err := github.Get(url, &packageInfo); err != nil {
+		return nil, err
+	}
+
+	return &packageInfo, nil
+}
+
+// listPackageVersions fetches all versions, newest first
+func listPackageVersions(github *GitHubClient) ([]PackageVersion, error) {
+	var versions []PackageVersion
+	err := eachPackageVersion(github, func(version PackageVersion) error {
+		versions = append(versions, version)
+		return nil
+	})
+	return versions, err
+}
+
+// eachPackageVersion calls fn with every version, newest first, decoding
+// one version at a time instead of whole pages. Pagination stops when fn
+// returns errStopPages.
+func eachPackageVersion(github *GitHubClient, fn func(PackageVersion) error) error {
+	return eachTargetVersion(github, configuredTarget(), fn)
+}
+
+// eachTargetVersion is eachPackageVersion for any package
+func eachTargetVersion(github *GitHubClient, target packageTarget, fn func(PackageVersion) error) error {
+	url := target.path() + "/versions"
+	progress := startProgress("Listing versions of "+target.String(), 0)
+	defer progress.Finish()
+
+	err := github.GetPagesResumable(url, globalOptions.resume, func(body io.Reader) error {
+		return decodeArray(body, "package versions", func(version PackageVersion) error {
+			progress.Add(1)
+			return fn(version)
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get package versions: %w", err)
+	}
+	return nil
+}
+
+func displayPackageInfo(info *PackageInfo) {
+	printf("\n📦 Package Information:\n")
+	fmt.Printf("Name: %s\n", info.Name)
+	printf("Type: %s\n", info.PackageType)
+	printf("Visibility: %s\n", info.Visibility)
+	printf("Created: %s\n", info.CreatedAt.Format(time.RFC3339))
+	printf("Updated: %s\n", info.UpdatedAt.Format(time.RFC3339))
+	fmt.Printf("HTML URL: %s\n", info.HTMLURL)
+}
+
+// VersionDetails holds registry data gathered for a package version
+type VersionDetails struct {
+	Version PackageVersion
+	Size    int64
+	Scan    *ScanResult
+}
+
+// Tag returns the first tag of the version, or "untagged"
+func (v PackageVersion) Tag() string {
+	if len(v.Metadata.Container.Tags) > 0 {
+		return v.Metadata.Container.Tags[0]
+	}
+	return "untagged"
+}
+
+// versionListOptions control which versions are listed and in what order
+type versionListOptions struct {
+	limit      int    // most versions to list, 0 for all
+	scanner    string // scan versions without a cached result, if set
+	sort       string // created, tag-semver or size
+	descending bool
+}
+
+// displayPackageVersions lists versions in the requested order. Sorting by
+// size needs the details of every version before the limit is applied.
+func displayPackageVersions(github *GitHubClient, registry *RegistryClient, options versionListOptions) error {
+	printf("\n📋 Package Versions:\n")
+
+	// The API lists versions newest first, so the most recent ones need
+	// no more pages than the limit covers
+	newestFirst := options.sort == "created" && options.descending && options.limit > 0
+	var versions []PackageVersion
+	err := eachPackageVersion(github, func(version PackageVersion) error {
+		versions = append(versions, version)
+		if newestFirst && len(versions) >= options.limit {
+			return errStopPages
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	total := len(versions)
+	partial := newestFirst && total >= options.limit
+
+	var details []VersionDetails
+	var detailErr error
+	if options.sort == "size" {
+		details, detailErr = fetchVersionDetails(registry, versions, options.scanner)
+		sortVersionDetailsBySize(details, options.descending)
+		if options.limit > 0 && len(details) > options.limit {
+			details = details[:options.limit]
+		}
+	} else {
+		sortVersions(versions, options.sort, options.descending)
+		if options.limit > 0 && len(versions) > options.limit {
+			versions = versions[:options.limit]
+		}
+		details, detailErr = fetchVersionDetails(registry, versions, options.scanner)
+	}
+
+	var totalSize int64
+	for _, detail := range details {
+		size := message("unknown")
+		if detail.Size > 0 {
+			size = formatBytes(detail.Size)
+			totalSize += detail.Size
+		}
+		cves := message("not scanned")
+		if detail.Scan != nil {
+			cves = detail.Scan.Summary()
+		}
+
+		version := detail.Version
+		printf("  - %s (ID: %d, Created: %s, Size: %s, CVEs: %s)\n",
+			message(version.Tag()), version.ID, version.CreatedAt.Format(time.RFC3339), size, cves)
+	}
+
+	if partial {
+		printf("\n(Showing the %d most recent versions; older pages were not fetched)\n", len(details))
+	} else {
+		printf("\n(Showing %d of %d versions, sorted by %s)\n", len(details), total, options.sort)
+	}
+	printf("Total size of listed versions: %s\n", formatBytes(totalSize))
+	printf("Note: layers shared between versions are counted once per version.\n")
+
+	if detailErr != nil {
+		printf("\n⚠️  Some version details could not be fetched:\n")
+		fmt.Println(detailErr)
+		return fmt.Errorf("incomplete version details")
+	}
+	return nil
+}
+
+// sortVersions orders versions by creation time or highest semver tag.
+// Versions without a semver tag sort last, newest first.
+func sortVersions(versions []PackageVersion, by string, descending bool) {
+	slices.SortStableFunc(versions, func(a, b PackageVersion) int {
+		if by == "tag-semver" {
+			av, aok := highestSemver(a)
+			bv, bok := highestSemver(b)
+			switch {
+			case aok && bok:
+				return orderBy(av.Compare(bv), descending)
+			case aok:
+				return -1
+			case bok:
+				return 1
+			}
+			return b.CreatedAt.Compare(a.CreatedAt)
+		}
+		return orderBy(a.CreatedAt.Compare(b.CreatedAt), descending)
+	})
+}
+
+// sortVersionDetailsBySize orders details by size; unknown sizes sort last
+func sortVersionDetailsBySize(details []VersionDetails, descending bool) {
+	slices.SortStableFunc(details, func(a, b VersionDetails) int {
+		switch {
+		case a.Size <= 0 && b.Size <= 0:
+			return 0
+		case a.Size <= 0:
+			return 1
+		case b.Size <= 0:
+			return -1
+		}
+		return orderBy(cmp.Compare(a.Size, b.Size), descending)
+	})
+}
+
+func orderBy(c int, descending bool) int {
+	if descending {
+		return -c
+	}
+	return c
+}
+
+// fetchVersionDetails resolves sizes and scan results using the worker pool.
+// Details are returned for every version, even when some lookups fail.
+func fetchVersionDetails(registry *RegistryClient, versions []PackageVersion, scanner string) ([]VersionDetails, error) {
+	details := make([]VersionDetails, len(versions))
+	progress := startProgress("Fetching version details", len(versions))
+	defer progress.Finish()
+	err := forEachConcurrent(versions, globalOptions.concurrency, func(i int, version PackageVersion) error {
+		defer progress.Add(1)
+		details[i].Version = version
+
+		var errs []error
+		size, err := registry.ImageSize(version.Name)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("size of %s: %w", version.Tag(), err))
+		}
+		details[i].Size = size
+
+		scan, err := loadScanResult(version.Name)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("cached scan of %s: %w", version.Tag(), err))
+		}
+		if scan == nil && scanner != "" {
+			if scan, err = scanReference(registry, version.Name, defaultPlatform, scanner); err != nil {
+				errs = append(errs, fmt.Errorf("scan of %s: %w", version.Tag(), err))
+			}
+		}
+		details[i].Scan = scan
+		return errors.Join(errs...)
+	})
+	return details, err
+}
+
+func displayDescriptionInfo() {
+	printf("\n📝 Package Description:\n")
+	printf("Note: GitHub Container Registry packages don't have editable descriptions via API.\n")
+	printf("Descriptions are typically set through:\n")
+	printf("  1. The Dockerfile LABEL org.opencontainers.image.description\n")
+	printf("  2. Repository README that's linked to the package\n")
+	printf("  3. GitHub Actions workflow annotations\n")
+
+	printf("\nRun `strunzctl lint dockerfile` to check that the Dockerfiles set the required\n")
+	printf("%s* labels (%s) for the release.\n", ociLabelPrefix, strings.Join(dockerfileLabels, ", "))
+}
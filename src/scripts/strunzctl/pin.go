@@ -0,0 +1,192 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// defaultPinsFile is the pins file in the repository, an env file the
+// compose files and Railway variables read the image references from
+const defaultPinsFile = "config/image.pins"
+
+// defaultPinName is the variable the server image is pinned under
+const defaultPinName = "STRUNZ_IMAGE"
+
+// imagePin is one NAME=host/repo:tag@digest line of a pins file
+type imagePin struct {
+	Name, Reference, Tag, Digest string
+}
+
+// parsePin splits a pinned reference; the tag is kept for humans and for
+// verifying, the digest is what gets pulled
+func parsePin(name, value string) (imagePin, error) {
+	reference, digest, ok := strings.Cut(value, "@")
+	if !ok || !strings.HasPrefix(digest, "sha256:") {
+		return imagePin{}, fmt.Errorf("%s=%s is not pinned to a sha256 digest", name, value)
+	}
+	_, _, tag, err := parseImageReference(reference)
+	if err != nil {
+		return imagePin{}, fmt.Errorf("%s: %w", name, err)
+	}
+	return imagePin{Name: name, Reference: reference, Tag: tag, Digest: digest}, nil
+}
+
+// readPins returns the lines of a pins file and the pins among them
+func readPins(path string) ([]string, []imagePin, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	var pins []imagePin
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, nil, fmt.Errorf("%s: %q is not NAME=reference", path, line)
+		}
+		pin, err := parsePin(name, strings.Trim(value, `"'`))
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", path, err)
+		}
+		pins = append(pins, pin)
+	}
+	return lines, pins, nil
+}
+
+// writePin sets a pin in the file, keeping the other lines and comments
+func writePin(path string, pin imagePin) error {
+	lines, _, err := readPins(path)
+	if os.IsNotExist(err) {
+		lines, err = []string{"# Image digests the deployments pull; update with `strunzctl deploy pin <tag>`"}, nil
+	}
+	if err != nil {
+		return err
+	}
+	entry := fmt.Sprintf("%s=%s@%s", pin.Name, pin.Reference, pin.Digest)
+	comment := fmt.Sprintf("# %s pinned %s", pin.Reference, time.Now().UTC().Format(time.RFC3339))
+	replaced := false
+	for i, line := range lines {
+		if name, _, ok := strings.Cut(strings.TrimSpace(line), "="); ok && name == pin.Name {
+			lines[i] = entry
+			if i > 0 && strings.HasPrefix(lines[i-1], "# ") && strings.Contains(lines[i-1], " pinned ") {
+				lines[i-1] = comment
+			}
+			replaced = true
+		}
+	}
+	if !replaced {
+		lines = append(lines, comment, entry)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o644)
+}
+
+// pinsPath resolves the pins file relative to the repository root
+func pinsPath(file string) string {
+	if filepath.IsAbs(file) {
+		return file
+	}
+	if root, err := runGit("rev-parse", "--show-toplevel"); err == nil {
+		return filepath.Join(root, file)
+	}
+	return file
+}
+
+// manifestDigest is the digest the registry reports for a manifest, or
+// the hash of its content when it reports none
+func manifestDigest(raw *RawManifest) string {
+	if raw.Digest != "" {
+		return raw.Digest
+	}
+	sum := sha256.Sum256(raw.Body)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// verifyPin reports whether the tag of a pin still points to its digest.
+// Pins without a tag only have to exist.
+func verifyPin(pin imagePin) (current string, ok bool, err error) {
+	host, repository, _, _ := parseImageReference(pin.Reference)
+	registry := newRegistryClient(host, repository)
+	reference := pin.Tag
+	if reference == "" {
+		reference = pin.Digest
+	}
+	raw, err := registry.GetRawManifest(reference)
+	if err != nil {
+		return "", false, err
+	}
+	current = manifestDigest(raw)
+	return current, current == pin.Digest, nil
+}
+
+func newDeployPinCommand() *Command {
+	cmd := newCommand("pin", "[tag]", "Pin a tag to its immutable digest in the pins file the deployments read, or verify the pins still match their tags.")
+	file := cmd.Flags.String("file", defaultPinsFile, "pins file, relative to the repository root")
+	name := cmd.Flags.String("name", defaultPinName, "variable to pin the image under")
+	verify := cmd.Flags.Bool("verify", false, "check every pin instead of writing one; fail when a tag has moved")
+
+	cmd.Run = func(args []string) error {
+		path := pinsPath(*file)
+		if *verify {
+			if err := cmd.ExactArgs(args, 0); err != nil {
+				return err
+			}
+			_, pins, err := readPins(path)
+			if err != nil {
+				return fmt.Errorf("failed to read pins: %w", err)
+			}
+			fmt.Printf("\n📌 Verifying %d pin(s) in %s\n", len(pins), path)
+			moved := 0
+			for _, pin := range pins {
+				current, ok, err := verifyPin(pin)
+				switch {
+				case err != nil:
+					moved++
+					fmt.Printf("  ❌ %s: %s: %v\n", pin.Name, pin.Reference, err)
+				case !ok:
+					moved++
+					fmt.Printf("  ❌ %s: %s now points to %s, pinned %s\n", pin.Name, pin.Reference, shortDigest(current), shortDigest(pin.Digest))
+				default:
+					fmt.Printf("  ✅ %s: %s → %s\n", pin.Name, pin.Reference, shortDigest(pin.Digest))
+				}
+			}
+			if moved > 0 {
+				return fmt.Errorf("%w: %d pin(s) no longer match their tags; rerun `strunzctl deploy pin <tag>` and redeploy", errPolicy, moved)
+			}
+			fmt.Println("\n✅ All pins match their tags")
+			return nil
+		}
+
+		if err := cmd.ExactArgs(args, 1); err != nil {
+			return err
+		}
+		tag := args[0]
+		if strings.HasPrefix(tag, "sha256:") {
+			return fmt.Errorf("%w: pin a tag; a digest is already immutable", errUsage)
+		}
+		registry := newRegistryClient(config.Registry, imageRepository())
+		raw, err := registry.GetRawManifest(tag)
+		if err != nil {
+			return err
+		}
+		pin := imagePin{Name: *name, Reference: registry.Reference(tag), Tag: tag, Digest: manifestDigest(raw)}
+		if err := writePin(path, pin); err != nil {
+			return fmt.Errorf("failed to write pins: %w", err)
+		}
+		fmt.Printf("\n📌 %s=%s@%s\n", pin.Name, pin.Reference, pin.Digest)
+		fmt.Printf("✅ Pinned in %s\n", path)
+		return nil
+	}
+	return cmd
+}
@@ -0,0 +1,519 @@
+package ghcr
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrUnauthorized is wrapped by errors for credentials the registry's
+// token service rejects
+var ErrUnauthorized = errors.New("authentication failed")
+
+// Error is returned for non-successful registry responses
+type Error struct {
+	StatusCode int
+	Status     string
+	Path       string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("registry returned %s for %s", e.Status, e.Path)
+}
+
+// Cache keeps registry responses by URL. Content addressed by digest is
+// served from it without a request; tags are revalidated with their ETag.
+type Cache interface {
+	Load(url string) (body []byte, header http.Header, ok bool)
+	Store(url string, header http.Header, body []byte)
+}
+
+// Client talks to a Docker Registry v2 API (e.g. ghcr.io). Configure the
+// exported fields before the first request.
+type Client struct {
+	// Host is the registry named in image references
+	Host string
+	// APIHost serves the API, registry-1.docker.io for docker.io
+	APIHost string
+	// Repository is the image repository, such as org/package
+	Repository string
+	// Credentials returns the user name and password exchanged for a
+	// bearer token. Without them, or when they fail, the token is
+	// requested anonymously, which is enough to pull public images.
+	Credentials func() (username, password string, err error)
+	// HTTPClient sends API requests. Blob transfers share its transport
+	// but not its timeout, since large layers take minutes.
+	HTTPClient *http.Client
+	// Retry sends the request newRequest builds, again if it sees fit;
+	// without it every request is sent once
+	Retry func(client *http.Client, newRequest func() (*http.Request, error)) (*http.Response, error)
+	// Cache keeps manifests and blobs between requests; nil disables it
+	Cache Cache
+	// Offline serves cached tags without revalidating them, to read
+	// snapshots while the registry is unreachable
+	Offline bool
+
+	// token is shared by concurrent workers
+	mu    sync.Mutex
+	token string
+}
+
+// New returns a client for a repository on a registry host, with a 60s
+// API timeout and no credentials
+func New(host, repository string) *Client {
+	client := &Client{
+		Host:       host,
+		APIHost:    host,
+		Repository: repository,
+		HTTPClient: &http.Client{Timeout: 60 * time.Second},
+	}
+	if host == "docker.io" {
+		client.APIHost = "registry-1.docker.io"
+	}
+	return client
+}
+
+// ParseReference splits "registry/org/repo[:tag]" into its parts
+func ParseReference(ref string) (host, repository, tag string, err error) {
+	host, rest, ok := strings.Cut(ref, "/")
+	if !ok || !strings.ContainsAny(host, ".:") {
+		return "", "", "", fmt.Errorf("image reference %q must start with a registry host", ref)
+	}
+	repository = rest
+	if i := strings.LastIndexByte(rest, ':'); i > strings.LastIndexByte(rest, '/') {
+		repository, tag = rest[:i], rest[i+1:]
+	}
+	return host, repository, tag, nil
+}
+
+// Reference returns the fully qualified image reference for a tag or digest
+func (r *Client) Reference(reference string) string {
+	if strings.HasPrefix(reference, "sha256:") {
+		return r.Host + "/" + r.Repository + "@" + reference
+	}
+	return r.Host + "/" + r.Repository + ":" + reference
+}
+
+// GetRawManifest fetches the unparsed manifest for a tag or digest
+func (r *Client) GetRawManifest(reference string) (*RawManifest, error) {
+	path := fmt.Sprintf("/v2/%s/manifests/%s", r.Repository, reference)
+	body, header, err := r.get(path, manifestAccept)
+	if err != nil {
+		return nil, err
+	}
+	return &RawManifest{Body: body, MediaType: header.Get("Content-Type"), Digest: header.Get("Docker-Content-Digest")}, nil
+}
+
+// PutManifest stores a manifest under a tag or digest and returns its digest
+func (r *Client) PutManifest(reference string, manifest *RawManifest) (string, error) {
+	path := fmt.Sprintf("/v2/%s/manifests/%s", r.Repository, reference)
+	_, header, err := r.send(request{
+		method:      http.MethodPut,
+		path:        path,
+		contentType: manifest.MediaType,
+		body:        manifest.Body,
+	}, http.StatusCreated, http.StatusOK)
+	if err != nil {
+		return "", err
+	}
+	return header.Get("Docker-Content-Digest"), nil
+}
+
+// GetManifest fetches the manifest for a tag or digest
+func (r *Client) GetManifest(reference string) (*Manifest, error) {
+	raw, err := r.GetRawManifest(reference)
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(raw.Body, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %w", reference, err)
+	}
+	if manifest.MediaType == "" {
+		manifest.MediaType = raw.MediaType
+	}
+	manifest.Digest = raw.Digest
+	if manifest.Digest == "" && strings.HasPrefix(reference, "sha256:") {
+		manifest.Digest = reference
+	}
+	return &manifest, nil
+}
+
+// GetBlob downloads a blob by digest
+func (r *Client) GetBlob(digest string) ([]byte, error) {
+	path := fmt.Sprintf("/v2/%s/blobs/%s", r.Repository, digest)
+	body, _, err := r.get(path, "")
+	return body, err
+}
+
+// BlobExists checks whether the repository already holds a blob
+func (r *Client) BlobExists(digest string) (bool, error) {
+	path := fmt.Sprintf("/v2/%s/blobs/%s", r.Repository, digest)
+	_, _, err := r.send(request{method: http.MethodHead, path: path}, http.StatusOK)
+	var registryErr *Error
+	if errors.As(err, &registryErr) && registryErr.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// OpenBlob starts streaming a blob; the caller must close the reader
+func (r *Client) OpenBlob(digest string) (io.ReadCloser, int64, error) {
+	path := fmt.Sprintf("/v2/%s/blobs/%s", r.Repository, digest)
+	resp, err := r.stream(request{method: http.MethodGet, path: path, streaming: true})
+	if err != nil {
+		return nil, 0, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, 0, &Error{StatusCode: resp.StatusCode, Status: resp.Status, Path: path}
+	}
+	return resp.Body, resp.ContentLength, nil
+}
+
+// UploadBlob pushes a blob with a monolithic upload: the upload session is
+// opened with a replayable POST, so the streamed PUT already carries a token
+func (r *Client) UploadBlob(digest string, size int64, content io.Reader) error {
+	path := fmt.Sprintf("/v2/%s/blobs/uploads/", r.Repository)
+	_, header, err := r.send(request{method: http.MethodPost, path: path}, http.StatusAccepted)
+	if err != nil {
+		return fmt.Errorf("failed to start blob upload: %w", err)
+	}
+
+	location, err := url.Parse(r.url(header.Get("Location")))
+	if err != nil {
+		return fmt.Errorf("registry returned invalid upload location: %w", err)
+	}
+	query := location.Query()
+	query.Set("digest", digest)
+	location.RawQuery = query.Encode()
+
+	req, err := http.NewRequest(http.MethodPut, location.String(), content)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/octet-stream")
+	if token := r.bearerToken(); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := r.transferClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("blob upload failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return &Error{StatusCode: resp.StatusCode, Status: resp.Status, Path: location.Path}
+	}
+	return nil
+}
+
+// GetImageConfig downloads and parses the config blob of an image manifest
+func (r *Client) GetImageConfig(manifest *Manifest) (*ImageConfig, error) {
+	if manifest.IsIndex() {
+		return nil, fmt.Errorf("manifest %s is a manifest list, select a platform first", manifest.Digest)
+	}
+
+	body, err := r.GetBlob(manifest.Config.Digest)
+	if err != nil {
+		return nil, err
+	}
+
+	var config ImageConfig
+	if err := json.Unmarshal(body, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse image config %s: %w", manifest.Config.Digest, err)
+	}
+	return &config, nil
+}
+
+// ImageSize returns the compressed size of an image: config plus all layers.
+// For manifest lists, the sizes of all referenced platform images are summed,
+// counting layers shared between platforms only once.
+func (r *Client) ImageSize(reference string) (int64, error) {
+	manifest, err := r.GetManifest(reference)
+	if err != nil {
+		return 0, err
+	}
+
+	seen := make(map[string]bool)
+	if !manifest.IsIndex() {
+		return ManifestSize(manifest, seen), nil
+	}
+
+	var total int64
+	for _, desc := range manifest.Manifests {
+		child, err := r.GetManifest(desc.Digest)
+		if err != nil {
+			return 0, err
+		}
+		total += ManifestSize(child, seen)
+	}
+	return total, nil
+}
+
+// request describes a single registry API call. The body is kept as bytes
+// so the request can be replayed after a token challenge.
+type request struct {
+	method      string
+	path        string
+	accept      string
+	contentType string
+	ifNoneMatch string
+	body        []byte
+
+	// streaming requests transfer blobs and are not bound by the client timeout
+	streaming bool
+}
+
+// get performs an authenticated, cached GET. Content addressed by digest
+// never changes and is served from the cache without a request; tags are
+// revalidated with If-None-Match unless the client is Offline.
+func (r *Client) get(path, accept string) ([]byte, http.Header, error) {
+	target := r.url(path)
+	immutable := strings.Contains(path, "/sha256:")
+	var cachedBody []byte
+	var cachedHeader http.Header
+	cached := false
+	if r.Cache != nil {
+		cachedBody, cachedHeader, cached = r.Cache.Load(target)
+	}
+	if cached && (immutable || r.Offline) {
+		return cachedBody, cachedHeader, nil
+	}
+
+	call := request{method: http.MethodGet, path: path, accept: accept}
+	if cached {
+		call.ifNoneMatch = cachedHeader.Get("ETag")
+	}
+	resp, err := r.stream(call)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && cached {
+		return cachedBody, cachedHeader, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, &Error{StatusCode: resp.StatusCode, Status: resp.Status, Path: path}
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read registry response: %w", err)
+	}
+
+	if r.Cache != nil && (immutable || resp.Header.Get("ETag") != "") {
+		r.Cache.Store(target, resp.Header, body)
+	}
+	return body, resp.Header, nil
+}
+
+// send performs an authenticated request and fails unless the response
+// status is one of expected
+func (r *Client) send(request request, expected ...int) ([]byte, http.Header, error) {
+	resp, err := r.stream(request)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read registry response: %w", err)
+	}
+	for _, status := range expected {
+		if resp.StatusCode == status {
+			return body, resp.Header, nil
+		}
+	}
+	return nil, nil, &Error{StatusCode: resp.StatusCode, Status: resp.Status, Path: request.path}
+}
+
+// stream performs an authenticated request, negotiating a bearer token on
+// 401, and returns the response with its body unread
+func (r *Client) stream(request request) (*http.Response, error) {
+	resp, err := r.do(request)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusUnauthorized {
+		return resp, nil
+	}
+
+	challenge := resp.Header.Get("WWW-Authenticate")
+	resp.Body.Close()
+	if err := r.authenticate(challenge); err != nil {
+		return nil, err
+	}
+	return r.do(request)
+}
+
+func (r *Client) url(path string) string {
+	if strings.HasPrefix(path, "https://") || strings.HasPrefix(path, "http://") {
+		return path
+	}
+	return "https://" + r.APIHost + path
+}
+
+func (r *Client) do(request request) (*http.Response, error) {
+	client := r.HTTPClient
+	if request.streaming {
+		client = r.transferClient()
+	}
+
+	newRequest := func() (*http.Request, error) {
+		var body io.Reader
+		if request.body != nil {
+			body = bytes.NewReader(request.body)
+		}
+		req, err := http.NewRequest(request.method, r.url(request.path), body)
+		if err != nil {
+			return nil, err
+		}
+		if request.accept != "" {
+			req.Header.Set("Accept", request.accept)
+		}
+		if request.contentType != "" {
+			req.Header.Set("Content-Type", request.contentType)
+		}
+		if request.ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", request.ifNoneMatch)
+		}
+		if token := r.bearerToken(); token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		return req, nil
+	}
+
+	var resp *http.Response
+	var err error
+	if r.Retry != nil {
+		resp, err = r.Retry(client, newRequest)
+	} else if req, buildErr := newRequest(); buildErr != nil {
+		err = buildErr
+	} else {
+		resp, err = client.Do(req)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("registry request failed: %w", err)
+	}
+	return resp, nil
+}
+
+// transferClient shares the transport of the API client but has no overall
+// timeout, since layers such as the FAISS index take minutes to transfer
+func (r *Client) transferClient() *http.Client {
+	return &http.Client{Transport: r.HTTPClient.Transport}
+}
+
+// authenticate exchanges the credentials for a registry bearer token
+// using the realm/service/scope advertised in the WWW-Authenticate challenge
+func (r *Client) authenticate(challenge string) error {
+	params := parseAuthChallenge(challenge)
+	realm := params["realm"]
+	if realm == "" {
+		return fmt.Errorf("registry sent no bearer realm in challenge %q", challenge)
+	}
+
+	query := url.Values{}
+	if service := params["service"]; service != "" {
+		query.Set("service", service)
+	}
+	scope := params["scope"]
+	if scope == "" {
+		scope = fmt.Sprintf("repository:%s:pull", r.Repository)
+	}
+	query.Set("scope", scope)
+
+	req, err := http.NewRequest(http.MethodGet, realm+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	if r.Credentials != nil {
+		if username, password, err := r.Credentials(); err == nil {
+			req.SetBasicAuth(username, password)
+		}
+	}
+
+	resp, err := r.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("registry token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return fmt.Errorf("%w: registry token request returned %s", ErrUnauthorized, resp.Status)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("registry token request returned %s", resp.Status)
+	}
+
+	var tokenResponse struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResponse); err != nil {
+		return fmt.Errorf("failed to parse registry token: %w", err)
+	}
+
+	token := tokenResponse.Token
+	if token == "" {
+		token = tokenResponse.AccessToken
+	}
+
+	r.mu.Lock()
+	r.token = token
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *Client) bearerToken() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.token
+}
+
+// parseAuthChallenge parses `Bearer realm="...",service="...",scope="..."`
+func parseAuthChallenge(challenge string) map[string]string {
+	params := make(map[string]string)
+	challenge = strings.TrimSpace(challenge)
+	if i := strings.IndexByte(challenge, ' '); i >= 0 {
+		challenge = challenge[i+1:]
+	}
+
+	for challenge != "" {
+		eq := strings.IndexByte(challenge, '=')
+		if eq < 0 {
+			break
+		}
+		key := strings.TrimSpace(challenge[:eq])
+		rest := challenge[eq+1:]
+
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.IndexByte(rest[1:], '"')
+			if end < 0 {
+				value, rest = rest[1:], ""
+			} else {
+				value, rest = rest[1:end+1], rest[end+2:]
+			}
+		} else if comma := strings.IndexByte(rest, ','); comma >= 0 {
+			value, rest = rest[:comma], rest[comma:]
+		} else {
+			value, rest = rest, ""
+		}
+
+		params[key] = value
+		challenge = strings.TrimLeft(rest, ", ")
+	}
+	return params
+}
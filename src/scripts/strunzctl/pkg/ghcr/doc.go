@@ -0,0 +1,35 @@
+// Package ghcr is a client for the Docker Registry v2 API that GitHub
+// Container Registry, Docker Hub and other OCI registries serve. It reads,
+// copies and pushes manifests and blobs without a Docker daemon.
+//
+// Public images need no credentials:
+//
+//	registry := ghcr.New("ghcr.io", "longevitycoach/strunzknowledge")
+//	manifest, err := registry.GetManifest("latest")
+//	if err != nil {
+//		return err
+//	}
+//	for _, image := range manifest.Manifests {
+//		fmt.Println(image.Platform, image.Digest)
+//	}
+//
+// Private images need credentials the registry exchanges for a bearer
+// token. GHCR accepts any user name with a GitHub token that has the
+// read:packages scope, write:packages to push:
+//
+//	registry.Credentials = func() (string, string, error) {
+//		return "token", os.Getenv("GITHUB_TOKEN"), nil
+//	}
+//
+// Copying a tag keeps its digest, since the raw manifest is stored as is:
+//
+//	raw, err := registry.GetRawManifest("1.2.0")
+//	if err != nil {
+//		return err
+//	}
+//	digest, err := registry.PutManifest("latest", raw)
+//
+// A Client is safe for concurrent use once configured. Non-successful
+// responses are returned as *Error; rejected credentials wrap
+// ErrUnauthorized.
+package ghcr
@@ -0,0 +1,122 @@
+package ghcr
+
+import (
+	"strings"
+	"time"
+)
+
+// Manifest media types accepted from the registry
+const (
+	MediaTypeOCIIndex     = "application/vnd.oci.image.index.v1+json"
+	MediaTypeOCIManifest  = "application/vnd.oci.image.manifest.v1+json"
+	MediaTypeDockerList   = "application/vnd.docker.distribution.manifest.list.v2+json"
+	MediaTypeDockerSchema = "application/vnd.docker.distribution.manifest.v2+json"
+)
+
+// manifestAccept lists every manifest media type we understand
+var manifestAccept = strings.Join([]string{
+	MediaTypeOCIIndex, MediaTypeDockerList, MediaTypeOCIManifest, MediaTypeDockerSchema,
+}, ", ")
+
+// Platform identifies the OS/architecture of an image in a manifest list
+type Platform struct {
+	Architecture string `json:"architecture"`
+	OS           string `json:"os"`
+	Variant      string `json:"variant,omitempty"`
+}
+
+func (p Platform) String() string {
+	if p.Variant != "" {
+		return p.OS + "/" + p.Architecture + "/" + p.Variant
+	}
+	return p.OS + "/" + p.Architecture
+}
+
+// Descriptor references a blob or manifest in the registry
+type Descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Platform    *Platform         `json:"platform,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// IsAttestation reports whether a manifest list entry is a buildx
+// attestation manifest rather than a runnable platform image
+func (d Descriptor) IsAttestation() bool {
+	return d.Annotations["vnd.docker.reference.type"] == "attestation-manifest"
+}
+
+// Manifest covers both single image manifests and manifest lists / OCI indexes
+type Manifest struct {
+	SchemaVersion int               `json:"schemaVersion"`
+	MediaType     string            `json:"mediaType"`
+	Config        Descriptor        `json:"config"`
+	Layers        []Descriptor      `json:"layers"`
+	Manifests     []Descriptor      `json:"manifests"`
+	Annotations   map[string]string `json:"annotations,omitempty"`
+
+	// Digest is the content digest reported by the registry
+	Digest string `json:"-"`
+}
+
+// IsIndex reports whether the manifest is a multi-platform manifest list
+func (m *Manifest) IsIndex() bool {
+	return m.MediaType == MediaTypeOCIIndex || m.MediaType == MediaTypeDockerList || len(m.Manifests) > 0
+}
+
+// RawManifest is a manifest exactly as stored in the registry. Copying the
+// raw bytes preserves the digest, which re-encoding would not.
+type RawManifest struct {
+	Body      []byte
+	MediaType string
+	Digest    string
+}
+
+// ImageConfig is the image configuration blob referenced by a manifest
+type ImageConfig struct {
+	Created      time.Time `json:"created"`
+	Architecture string    `json:"architecture"`
+	OS           string    `json:"os"`
+	Variant      string    `json:"variant,omitempty"`
+	Config       struct {
+		User         string              `json:"User"`
+		Env          []string            `json:"Env"`
+		Entrypoint   []string            `json:"Entrypoint"`
+		Cmd          []string            `json:"Cmd"`
+		WorkingDir   string              `json:"WorkingDir"`
+		ExposedPorts map[string]struct{} `json:"ExposedPorts"`
+		Volumes      map[string]struct{} `json:"Volumes"`
+		Labels       map[string]string   `json:"Labels"`
+		StopSignal   string              `json:"StopSignal"`
+		Healthcheck  *struct {
+			Test     []string      `json:"Test"`
+			Interval time.Duration `json:"Interval"`
+			Timeout  time.Duration `json:"Timeout"`
+			Retries  int           `json:"Retries"`
+		} `json:"Healthcheck"`
+	} `json:"config"`
+	// History has one entry per Dockerfile instruction, including those
+	// that add no layer
+	History []struct {
+		Created    time.Time `json:"created"`
+		CreatedBy  string    `json:"created_by"`
+		Comment    string    `json:"comment,omitempty"`
+		EmptyLayer bool      `json:"empty_layer,omitempty"`
+	} `json:"history"`
+}
+
+// ManifestSize sums the config and layer sizes of an image manifest,
+// skipping blobs already in seen and adding the others to it. Sharing seen
+// across the platform images of an index counts shared layers once.
+func ManifestSize(manifest *Manifest, seen map[string]bool) int64 {
+	var total int64
+	for _, desc := range append([]Descriptor{manifest.Config}, manifest.Layers...) {
+		if desc.Digest == "" || seen[desc.Digest] {
+			continue
+		}
+		seen[desc.Digest] = true
+		total += desc.Size
+	}
+	return total
+}
@@ -0,0 +1,527 @@
+package mcpclient
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProtocolVersion is the MCP revision FastMCP's SSE transport implements
+const ProtocolVersion = "2024-11-05"
+
+// Error is a JSON-RPC error returned by the server
+type Error struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("JSON-RPC error %d: %s", e.Code, e.Message)
+}
+
+// Tool is a tool advertised by tools/list
+type Tool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	InputSchema json.RawMessage `json:"inputSchema"`
+	// OutputSchema describes structuredContent; tools may omit it
+	OutputSchema json.RawMessage `json:"outputSchema,omitempty"`
+}
+
+// ToolResult is the result of tools/call
+type ToolResult struct {
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	IsError bool `json:"isError"`
+}
+
+// Text joins the text content blocks of a result
+func (r *ToolResult) Text() string {
+	var parts []string
+	for _, content := range r.Content {
+		if content.Type == "text" {
+			parts = append(parts, content.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// InitializeResult is the server's answer to initialize
+type InitializeResult struct {
+	ProtocolVersion string          `json:"protocolVersion"`
+	Capabilities    json.RawMessage `json:"capabilities"`
+	ServerInfo      struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	} `json:"serverInfo"`
+}
+
+type rpcMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      *int64          `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  any             `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// Implementation names the client in the initialize handshake
+type Implementation struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// Client speaks MCP over the SSE transport: responses arrive on a
+// long-lived GET /sse stream, requests are POSTed to the endpoint the
+// server announces on that stream. StartStdio builds one for the stdio
+// transport instead.
+type Client struct {
+	// Timeout bounds each request; change it only between requests
+	Timeout time.Duration
+	// ClientInfo is sent by Initialize
+	ClientInfo Implementation
+	// OnResponse, when set, sees each response of the message endpoint
+	// with its body already read, such as to inspect its headers
+	OnResponse func(resp *http.Response)
+
+	baseURL    string
+	endpoint   string
+	httpClient *http.Client
+	// ctx lasts as long as the stream, cancel ends it
+	ctx    context.Context
+	cancel context.CancelFunc
+	// header is sent with the stream request and every message
+	header http.Header
+	// deliver sends one encoded message to the server
+	deliver func(ctx context.Context, body []byte) error
+
+	mu      sync.Mutex
+	nextID  int64
+	pending map[int64]chan rpcMessage
+	// unmatched receives raw messages no pending request waits for, such
+	// as batch responses and errors with a null id
+	unmatched chan string
+	// done is closed when the stream ends; err holds the reason
+	done chan struct{}
+	err  error
+	// stream describes what arrived on the SSE stream
+	stream StreamStats
+	// readGate is held to stop dispatching events, see PauseReading
+	readGate sync.Mutex
+}
+
+// StreamStats describes the traffic on an SSE stream
+type StreamStats struct {
+	// Keepalives counts SSE comment lines, Pings ping requests from the
+	// server, which the client answers
+	Keepalives, Pings int
+	// LastEventID is the id of the last event carrying one
+	LastEventID string
+	// LastRead is when the last line arrived, MaxGap the longest silence
+	LastRead time.Time
+	MaxGap   time.Duration
+}
+
+// defaultClientInfo is how clients introduce themselves unless told otherwise
+var defaultClientInfo = Implementation{Name: "mcpclient", Version: "1.0"}
+
+// Connect opens the SSE stream of serverURL and waits up to timeout for the
+// message endpoint. The stream lasts until Close or until ctx is done. The
+// header, which may be nil, is sent with the stream request and every
+// message, such as the Authorization of a signed-in user.
+func Connect(ctx context.Context, serverURL string, timeout time.Duration, header http.Header) (*Client, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	client := &Client{
+		Timeout:    timeout,
+		ClientInfo: defaultClientInfo,
+		baseURL:    strings.TrimRight(serverURL, "/"),
+		httpClient: &http.Client{},
+		ctx:        ctx,
+		cancel:     cancel,
+		header:     header,
+		pending:    make(map[int64]chan rpcMessage),
+		unmatched:  make(chan string, 64),
+		done:       make(chan struct{}),
+	}
+	client.deliver = client.postMessage
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, client.baseURL+"/sse", nil)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	maps.Copy(req.Header, header)
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := client.httpClient.Do(req)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to open SSE stream: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		cancel()
+		return nil, &HTTPStatusError{Endpoint: "SSE stream", Status: resp.StatusCode, Header: resp.Header}
+	}
+
+	endpoint := make(chan string, 1)
+	go client.readEvents(resp.Body, endpoint)
+
+	select {
+	case path := <-endpoint:
+		target, err := url.Parse(client.baseURL + "/")
+		if err == nil {
+			var ref *url.URL
+			if ref, err = url.Parse(path); err == nil {
+				client.endpoint = target.ResolveReference(ref).String()
+			}
+		}
+		if err != nil {
+			client.Close()
+			return nil, fmt.Errorf("invalid message endpoint %q: %w", path, err)
+		}
+		return client, nil
+	case <-client.done:
+		client.Close()
+		return nil, fmt.Errorf("SSE stream closed before the endpoint event: %w", client.err)
+	case <-ctx.Done():
+		client.Close()
+		return nil, context.Cause(ctx)
+	case <-time.After(timeout):
+		client.Close()
+		return nil, fmt.Errorf("no endpoint event within %s", timeout)
+	}
+}
+
+// readEvents dispatches SSE events until the stream ends
+func (c *Client) readEvents(body io.ReadCloser, endpoint chan<- string) {
+	defer body.Close()
+	reader := bufio.NewReader(body)
+	event, data := "", ""
+	c.mu.Lock()
+	c.stream.LastRead = time.Now()
+	c.mu.Unlock()
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			c.finish(fmt.Errorf("SSE stream ended: %w", err))
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		c.mu.Lock()
+		now := time.Now()
+		c.stream.MaxGap = max(c.stream.MaxGap, now.Sub(c.stream.LastRead))
+		c.stream.LastRead = now
+		c.mu.Unlock()
+		switch {
+		case line == "":
+			c.readGate.Lock()
+			c.readGate.Unlock()
+			c.dispatch(event, data, endpoint)
+			event, data = "", ""
+		case strings.HasPrefix(line, ":"):
+			c.mu.Lock()
+			c.stream.Keepalives++
+			c.mu.Unlock()
+		case strings.HasPrefix(line, "id:"):
+			c.mu.Lock()
+			c.stream.LastEventID = strings.TrimSpace(strings.TrimPrefix(line, "id:"))
+			c.mu.Unlock()
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			if data != "" {
+				data += "\n"
+			}
+			data += strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " ")
+		}
+	}
+}
+
+func (c *Client) dispatch(event, data string, endpoint chan<- string) {
+	switch event {
+	case "endpoint":
+		select {
+		case endpoint <- data:
+		default:
+		}
+	case "", "message":
+		var request struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		if json.Unmarshal([]byte(data), &request) == nil && request.Method == "ping" && len(request.ID) > 0 {
+			// Servers ping to detect dead clients and drop those that do
+			// not answer
+			c.mu.Lock()
+			c.stream.Pings++
+			c.mu.Unlock()
+			go c.deliver(c.ctx, []byte(fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,"result":{}}`, request.ID)))
+			return
+		}
+		var message rpcMessage
+		var ch chan rpcMessage
+		if err := json.Unmarshal([]byte(data), &message); err == nil && message.ID != nil && message.Method == "" {
+			c.mu.Lock()
+			ch = c.pending[*message.ID]
+			delete(c.pending, *message.ID)
+			c.mu.Unlock()
+		}
+		if ch != nil {
+			ch <- message
+			return
+		}
+		select {
+		case c.unmatched <- data:
+		default:
+			// Nobody reads unmatched messages outside conformance tests
+		}
+	}
+}
+
+func (c *Client) finish(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	select {
+	case <-c.done:
+	default:
+		c.err = err
+		close(c.done)
+	}
+}
+
+// Request sends a JSON-RPC request and decodes the result into v. It
+// returns early when ctx is done; the response is dropped if it arrives.
+func (c *Client) Request(ctx context.Context, method string, params, v any) error {
+	c.mu.Lock()
+	c.nextID++
+	id := c.nextID
+	ch := make(chan rpcMessage, 1)
+	c.pending[id] = ch
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	if err := c.post(ctx, rpcMessage{JSONRPC: "2.0", ID: &id, Method: method, Params: params}); err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+
+	select {
+	case message := <-ch:
+		if message.Error != nil {
+			return fmt.Errorf("%s: %w", method, message.Error)
+		}
+		if v != nil {
+			if err := json.Unmarshal(message.Result, v); err != nil {
+				return fmt.Errorf("%s: failed to parse result: %w", method, err)
+			}
+		}
+		return nil
+	case <-c.done:
+		return fmt.Errorf("%s: %w", method, c.err)
+	case <-ctx.Done():
+		return fmt.Errorf("%s: %w", method, context.Cause(ctx))
+	case <-time.After(c.Timeout):
+		return fmt.Errorf("%s: no response within %s", method, c.Timeout)
+	}
+}
+
+// Send delivers an encoded message as is, such as a request with
+// parameters Request would not send; its response arrives on Unmatched
+func (c *Client) Send(ctx context.Context, body []byte) error {
+	return c.deliver(ctx, body)
+}
+
+// Notify sends a JSON-RPC notification
+func (c *Client) Notify(ctx context.Context, method string, params any) error {
+	return c.post(ctx, rpcMessage{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+func (c *Client) post(ctx context.Context, message rpcMessage) error {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	return c.deliver(ctx, body)
+}
+
+// postMessage sends a message to the SSE message endpoint
+func (c *Client) postMessage(ctx context.Context, body []byte) error {
+	resp, data, err := c.postBody(ctx, body)
+	if err != nil {
+		return err
+	}
+	if c.OnResponse != nil {
+		c.OnResponse(resp)
+	}
+	if resp.StatusCode/100 != 2 {
+		return &HTTPStatusError{Endpoint: "message endpoint", Status: resp.StatusCode, Header: resp.Header, Body: data}
+	}
+	return nil
+}
+
+// PostRaw sends a body to the message endpoint as is and returns the HTTP
+// status and response body. Responses arrive on the stream, see Unmatched.
+func (c *Client) PostRaw(ctx context.Context, body []byte) (int, string, error) {
+	resp, data, err := c.postBody(ctx, body)
+	if err != nil {
+		return 0, "", err
+	}
+	return resp.StatusCode, data, nil
+}
+
+func (c *Client) postBody(ctx context.Context, body []byte) (*http.Response, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, "", err
+	}
+	maps.Copy(req.Header, c.header)
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: c.Timeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to post message: %w", err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	return resp, strings.TrimSpace(string(data)), nil
+}
+
+// HTTPStatusError is an unexpected HTTP status of the SSE stream or the
+// message endpoint
+type HTTPStatusError struct {
+	Endpoint string
+	Status   int
+	Header   http.Header
+	Body     string
+}
+
+func (e *HTTPStatusError) Error() string {
+	message := fmt.Sprintf("%s returned %d %s", e.Endpoint, e.Status, http.StatusText(e.Status))
+	if e.Body != "" {
+		message += ": " + e.Body
+	}
+	return message
+}
+
+// Unmatched delivers raw messages that answer no request of this client
+func (c *Client) Unmatched() <-chan string {
+	return c.unmatched
+}
+
+// Initialize performs the initialize handshake
+func (c *Client) Initialize(ctx context.Context) (*InitializeResult, error) {
+	var result InitializeResult
+	err := c.Request(ctx, "initialize", map[string]any{
+		"protocolVersion": ProtocolVersion,
+		"capabilities":    map[string]any{},
+		"clientInfo":      c.ClientInfo,
+	}, &result)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Notify(ctx, "notifications/initialized", nil); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ListTools returns all advertised tools, following pagination cursors
+func (c *Client) ListTools(ctx context.Context) ([]Tool, error) {
+	tools := []Tool{}
+	cursor := ""
+	for {
+		params := map[string]any{}
+		if cursor != "" {
+			params["cursor"] = cursor
+		}
+		var page struct {
+			Tools      []Tool `json:"tools"`
+			NextCursor string `json:"nextCursor"`
+		}
+		if err := c.Request(ctx, "tools/list", params, &page); err != nil {
+			return nil, err
+		}
+		tools = append(tools, page.Tools...)
+		if page.NextCursor == "" {
+			return tools, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// CallTool invokes a tool
+func (c *Client) CallTool(ctx context.Context, name string, arguments map[string]any) (*ToolResult, error) {
+	var result ToolResult
+	if err := c.Request(ctx, "tools/call", map[string]any{"name": name, "arguments": arguments}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Close ends the SSE stream, or stops the stdio server
+func (c *Client) Close() {
+	c.cancel()
+}
+
+// Stream returns the traffic seen on the SSE stream so far
+func (c *Client) Stream() StreamStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stream
+}
+
+// PauseReading stops dispatching events for d, as a client that is slow
+// to consume the stream would; the server's writes back up meanwhile
+func (c *Client) PauseReading(d time.Duration) {
+	c.readGate.Lock()
+	time.AfterFunc(d, c.readGate.Unlock)
+}
+
+// Disconnected reports whether the SSE stream has ended, after which no
+// responses can arrive
+func (c *Client) Disconnected() bool {
+	select {
+	case <-c.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Done is closed when the stream ends or the stdio server exits
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err explains why the stream ended, once Done is closed
+func (c *Client) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// URL is the server URL the client connected to
+func (c *Client) URL() string {
+	return c.baseURL
+}
+
+// Endpoint is the message endpoint the server announced
+func (c *Client) Endpoint() string {
+	return c.endpoint
+}
@@ -0,0 +1,14 @@
+// Package mcpclient is a Model Context Protocol client for the SSE
+// transport that FastMCP servers and Claude.ai connectors use, and for the
+// stdio transport of Claude Desktop.
+//
+// Connect opens the event stream and waits for the message endpoint;
+// requests block until their response arrives on the stream, the request
+// timeout passes or their context is done. Extra headers, such as a bearer
+// token for an OAuth-protected server, go with the stream request and every
+// message. StartStdio runs a server as a subprocess and speaks to it over
+// stdin and stdout instead. See the examples of Connect and StartStdio.
+//
+// Errors the server returns for a request are *Error; unexpected HTTP
+// statuses of the stream or the message endpoint are *HTTPStatusError.
+package mcpclient
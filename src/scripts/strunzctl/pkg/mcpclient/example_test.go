@@ -0,0 +1,54 @@
+package mcpclient_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/longevitycoach/StrunzKnowledge/src/scripts/strunzctl/pkg/mcpclient"
+)
+
+func ExampleConnect() {
+	ctx := context.Background()
+	client, err := mcpclient.Connect(ctx, "https://strunz.up.railway.app", 30*time.Second, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer client.Close()
+	if _, err := client.Initialize(ctx); err != nil {
+		log.Fatal(err)
+	}
+	result, err := client.CallTool(ctx, "search_knowledge", map[string]any{"query": "Vitamin D"})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(result.Text())
+}
+
+func ExampleConnect_header() {
+	header := http.Header{"Authorization": {"Bearer " + os.Getenv("MCP_TOKEN")}}
+	client, err := mcpclient.Connect(context.Background(), "https://strunz.up.railway.app", 30*time.Second, header)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer client.Close()
+}
+
+func ExampleStartStdio() {
+	ctx := context.Background()
+	client, _, err := mcpclient.StartStdio(ctx, []string{"python", "-m", "src.mcp.server"}, "", 2*time.Minute)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer client.Close()
+	tools, err := client.ListTools(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, tool := range tools {
+		fmt.Println(tool.Name)
+	}
+}
@@ -0,0 +1,159 @@
+package mcpclient
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StdioServer is an MCP server running as a subprocess. Messages are
+// newline-delimited JSON on stdin and stdout; stderr is free for logging.
+type StdioServer struct {
+	process *exec.Cmd
+	stdin   io.WriteCloser
+	stderr  *tailBuffer
+
+	mu sync.Mutex
+	// noise holds stdout lines that are not JSON-RPC messages, which break
+	// Claude Desktop
+	noise []string
+}
+
+// StartStdio launches command in dir, the current directory if empty, and
+// returns a client speaking MCP over its stdin and stdout. Closing the
+// client or cancelling ctx stops the server.
+func StartStdio(ctx context.Context, command []string, dir string, timeout time.Duration) (*Client, *StdioServer, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	process := exec.CommandContext(ctx, command[0], command[1:]...)
+	process.Dir = dir
+	server := &StdioServer{process: process, stderr: &tailBuffer{limit: 8 << 10}}
+	process.Stderr = server.stderr
+
+	stdin, err := process.StdinPipe()
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+	// Closing stdin is how a stdio server is asked to exit; it is killed if
+	// it does not within WaitDelay
+	process.Cancel = stdin.Close
+	process.WaitDelay = 3 * time.Second
+	stdout, err := process.StdoutPipe()
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+	if err := process.Start(); err != nil {
+		cancel()
+		return nil, nil, fmt.Errorf("failed to start %s: %w", command[0], err)
+	}
+	server.stdin = stdin
+
+	client := &Client{
+		Timeout:    timeout,
+		ClientInfo: defaultClientInfo,
+		baseURL:    "stdio:" + strings.Join(command, " "),
+		pending:    make(map[int64]chan rpcMessage),
+		unmatched:  make(chan string, 64),
+		done:       make(chan struct{}),
+		ctx:        ctx,
+		deliver:    server.write,
+	}
+	client.cancel = func() {
+		cancel()
+		<-client.done
+	}
+	go server.readMessages(stdout, client)
+	return client, server, nil
+}
+
+func (s *StdioServer) write(ctx context.Context, body []byte) error {
+	if err := ctx.Err(); err != nil {
+		return context.Cause(ctx)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.stdin.Write(append(body, '\n')); err != nil {
+		return fmt.Errorf("failed to write to server stdin: %w", err)
+	}
+	return nil
+}
+
+// readMessages dispatches stdout lines until the server exits
+func (s *StdioServer) readMessages(stdout io.Reader, client *Client) {
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64<<10), 16<<20)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if !json.Valid([]byte(line)) {
+			s.mu.Lock()
+			s.noise = append(s.noise, line)
+			s.mu.Unlock()
+			continue
+		}
+		client.dispatch("message", line, nil)
+	}
+	if err := s.process.Wait(); err != nil {
+		client.finish(fmt.Errorf("server exited: %w", err))
+		return
+	}
+	if err := scanner.Err(); err != nil {
+		client.finish(fmt.Errorf("failed to read server stdout: %w", err))
+		return
+	}
+	client.finish(fmt.Errorf("server exited"))
+}
+
+// Pid is the process ID of the server
+func (s *StdioServer) Pid() int {
+	return s.process.Process.Pid
+}
+
+// Stderr returns the last n lines the server wrote to stderr
+func (s *StdioServer) Stderr(n int) []string {
+	return s.stderr.Lines(n)
+}
+
+// Noise returns the non-JSON stdout lines seen so far
+func (s *StdioServer) Noise() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.noise...)
+}
+
+// tailBuffer keeps the last limit bytes written to it
+type tailBuffer struct {
+	mu    sync.Mutex
+	limit int
+	data  []byte
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.data = append(b.data, p...)
+	if len(b.data) > b.limit {
+		b.data = b.data[len(b.data)-b.limit:]
+	}
+	return len(p), nil
+}
+
+// Lines returns the last n lines
+func (b *tailBuffer) Lines(n int) []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	lines := strings.Split(strings.TrimRight(string(b.data), "\n"), "\n")
+	if len(lines) == 1 && lines[0] == "" {
+		return nil
+	}
+	return lines[max(0, len(lines)-n):]
+}
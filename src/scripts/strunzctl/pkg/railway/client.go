@@ -0,0 +1,197 @@
+package railway
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// API is the Railway public GraphQL endpoint
+const API = "https://backboard.railway.com/graphql/v2"
+
+var (
+	// ErrUnauthorized is wrapped by errors for tokens Railway rejects
+	ErrUnauthorized = errors.New("authentication failed")
+	// ErrServiceRequired is wrapped when a project has several services
+	// and none was named
+	ErrServiceRequired = errors.New("no service given")
+)
+
+// Client is a minimal Railway GraphQL API client. Configure the exported
+// fields before the first request.
+type Client struct {
+	// Token is an account, team or project token
+	Token string
+	// ProjectToken selects the Project-Access-Token header used by
+	// RAILWAY_TOKEN instead of a bearer account or team token
+	ProjectToken bool
+	// URL is the GraphQL endpoint, API by default
+	URL        string
+	HTTPClient *http.Client
+	// Retry sends the request newRequest builds, again if it sees fit;
+	// without it every request is sent once
+	Retry func(client *http.Client, newRequest func() (*http.Request, error)) (*http.Response, error)
+}
+
+// New returns a client for a token with a 60s timeout
+func New(token string, projectToken bool) *Client {
+	return &Client{
+		Token:        token,
+		ProjectToken: projectToken,
+		URL:          API,
+		HTTPClient:   &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+// Query runs a GraphQL operation and decodes its data into v unless v is nil
+func (c *Client) Query(query string, variables map[string]any, v any) error {
+	payload, err := json.Marshal(map[string]any{"query": query, "variables": variables})
+	if err != nil {
+		return fmt.Errorf("failed to encode Railway request: %w", err)
+	}
+
+	newRequest := func() (*http.Request, error) {
+		req, err := http.NewRequest(http.MethodPost, c.URL, bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if c.ProjectToken {
+			req.Header.Set("Project-Access-Token", c.Token)
+		} else {
+			req.Header.Set("Authorization", "Bearer "+c.Token)
+		}
+		return req, nil
+	}
+	var resp *http.Response
+	if c.Retry != nil {
+		resp, err = c.Retry(c.HTTPClient, newRequest)
+	} else if req, buildErr := newRequest(); buildErr != nil {
+		err = buildErr
+	} else {
+		resp, err = c.HTTPClient.Do(req)
+	}
+	if err != nil {
+		return fmt.Errorf("Railway API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read Railway API response: %w", err)
+	}
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return fmt.Errorf("%w: Railway API returned %s", ErrUnauthorized, resp.Status)
+	}
+
+	var result struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("Railway API returned %s", resp.Status)
+	}
+	if len(result.Errors) > 0 {
+		messages := make([]string, len(result.Errors))
+		for i, e := range result.Errors {
+			messages[i] = e.Message
+		}
+		message := strings.Join(messages, "; ")
+		// GraphQL reports auth failures with 200 and an error message
+		if strings.Contains(strings.ToLower(message), "not authorized") {
+			return fmt.Errorf("%w: Railway API: %s", ErrUnauthorized, message)
+		}
+		return fmt.Errorf("Railway API error: %s", message)
+	}
+	if v != nil {
+		if err := json.Unmarshal(result.Data, v); err != nil {
+			return fmt.Errorf("failed to parse Railway API response: %w", err)
+		}
+	}
+	return nil
+}
+
+// Target identifies the service instance operations act on
+type Target struct {
+	Project, Environment, Service string
+	ServiceName                   string
+}
+
+// ResolveTarget maps project, environment and service IDs or names to IDs.
+// Project tokens already imply project and environment, which are then
+// ignored. The service may be empty in a project with only one.
+func (c *Client) ResolveTarget(project, environment, service string) (Target, error) {
+	target := Target{Project: project, Environment: environment}
+	if c.ProjectToken {
+		var data struct {
+			ProjectToken struct {
+				ProjectID     string `json:"projectId"`
+				EnvironmentID string `json:"environmentId"`
+			} `json:"projectToken"`
+		}
+		if err := c.Query(`query { projectToken { projectId environmentId } }`, nil, &data); err != nil {
+			return target, err
+		}
+		target.Project, target.Environment = data.ProjectToken.ProjectID, data.ProjectToken.EnvironmentID
+	}
+	if target.Project == "" {
+		return target, errors.New("no Railway project given")
+	}
+
+	var data struct {
+		Project struct {
+			Services struct {
+				Edges []struct {
+					Node struct{ ID, Name string } `json:"node"`
+				} `json:"edges"`
+			} `json:"services"`
+			Environments struct {
+				Edges []struct {
+					Node struct{ ID, Name string } `json:"node"`
+				} `json:"edges"`
+			} `json:"environments"`
+		} `json:"project"`
+	}
+	err := c.Query(`query project($id: String!) {
+  project(id: $id) {
+    services { edges { node { id name } } }
+    environments { edges { node { id name } } }
+  }
+}`, map[string]any{"id": target.Project}, &data)
+	if err != nil {
+		return target, err
+	}
+
+	found := false
+	var environments []string
+	for _, edge := range data.Project.Environments.Edges {
+		if edge.Node.ID == target.Environment || edge.Node.Name == target.Environment {
+			target.Environment, found = edge.Node.ID, true
+			break
+		}
+		environments = append(environments, edge.Node.Name)
+	}
+	if !found {
+		return target, fmt.Errorf("Railway environment %q not found (available: %s)", target.Environment, strings.Join(environments, ", "))
+	}
+
+	var services []string
+	for _, edge := range data.Project.Services.Edges {
+		if edge.Node.ID == service || edge.Node.Name == service || (service == "" && len(data.Project.Services.Edges) == 1) {
+			target.Service, target.ServiceName = edge.Node.ID, edge.Node.Name
+			return target, nil
+		}
+		services = append(services, edge.Node.Name)
+	}
+	if service == "" {
+		return target, fmt.Errorf("%w: the project has %d services (available: %s)", ErrServiceRequired, len(services), strings.Join(services, ", "))
+	}
+	return target, fmt.Errorf("Railway service %q not found (available: %s)", service, strings.Join(services, ", "))
+}
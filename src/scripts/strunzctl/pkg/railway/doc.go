@@ -0,0 +1,26 @@
+// Package railway is a client for the parts of the Railway GraphQL API
+// that deploying an image needs: resolving a service, pointing it at an
+// image, following the deployment and managing variables, project tokens
+// and custom domains.
+//
+// An account or team token sees every project it has access to:
+//
+//	client := railway.New(os.Getenv("RAILWAY_API_TOKEN"), false)
+//	target, err := client.ResolveTarget("strunz-knowledge", "production", "mcp-server")
+//	if err != nil {
+//		return err
+//	}
+//	if err := client.SetImage(target, "ghcr.io/longevitycoach/strunzknowledge:1.2.0"); err != nil {
+//		return err
+//	}
+//	id, err := client.Deploy(target)
+//
+// A project token, the RAILWAY_TOKEN of CI jobs, is bound to one project
+// and environment, which ResolveTarget then ignores:
+//
+//	client := railway.New(os.Getenv("RAILWAY_TOKEN"), true)
+//	target, err := client.ResolveTarget("", "", "mcp-server")
+//
+// To wait for a deployment, poll Client.Deployment until the result is
+// Finished. Rejected tokens wrap ErrUnauthorized.
+package railway
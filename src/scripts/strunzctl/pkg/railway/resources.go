@@ -0,0 +1,275 @@
+package railway
+
+import (
+	"fmt"
+	"time"
+)
+
+// Deployment is the subset of a Railway deployment the client reads
+type Deployment struct {
+	ID        string         `json:"id"`
+	Status    string         `json:"status"`
+	CreatedAt time.Time      `json:"createdAt"`
+	StaticURL string         `json:"staticUrl"`
+	Meta      map[string]any `json:"meta"`
+}
+
+// Source describes what a deployment runs: an image or a commit
+func (d Deployment) Source() string {
+	if image, ok := d.Meta["image"].(string); ok && image != "" {
+		return image
+	}
+	if commit, ok := d.Meta["commitHash"].(string); ok && commit != "" {
+		return "commit " + commit[:min(7, len(commit))]
+	}
+	return "-"
+}
+
+// Finished reports whether Railway will not change the status any more
+func (d Deployment) Finished() bool {
+	switch d.Status {
+	case "SUCCESS", "FAILED", "CRASHED", "REMOVED", "SKIPPED":
+		return true
+	}
+	return false
+}
+
+// Log is one deployment log line
+type Log struct {
+	Timestamp time.Time `json:"timestamp"`
+	Message   string    `json:"message"`
+	Severity  string    `json:"severity"`
+}
+
+// Deployments returns the latest deployments of a service, newest first
+func (c *Client) Deployments(target Target, limit int) ([]Deployment, error) {
+	var data struct {
+		Deployments struct {
+			Edges []struct {
+				Node Deployment `json:"node"`
+			} `json:"edges"`
+		} `json:"deployments"`
+	}
+	err := c.Query(`query deployments($first: Int, $input: DeploymentListInput!) {
+  deployments(first: $first, input: $input) {
+    edges { node { id status createdAt staticUrl meta } }
+  }
+}`, map[string]any{
+		"first": limit,
+		"input": map[string]string{"projectId": target.Project, "environmentId": target.Environment, "serviceId": target.Service},
+	}, &data)
+	if err != nil {
+		return nil, err
+	}
+
+	deployments := make([]Deployment, 0, len(data.Deployments.Edges))
+	for _, edge := range data.Deployments.Edges {
+		deployments = append(deployments, edge.Node)
+	}
+	return deployments, nil
+}
+
+// Deployment fetches a single deployment
+func (c *Client) Deployment(id string) (*Deployment, error) {
+	var data struct {
+		Deployment Deployment `json:"deployment"`
+	}
+	err := c.Query(`query deployment($id: String!) {
+  deployment(id: $id) { id status createdAt staticUrl meta }
+}`, map[string]any{"id": id}, &data)
+	if err != nil {
+		return nil, err
+	}
+	return &data.Deployment, nil
+}
+
+// DeploymentLogs returns the last log lines of a deployment
+func (c *Client) DeploymentLogs(id string, limit int) ([]Log, error) {
+	var data struct {
+		DeploymentLogs []Log `json:"deploymentLogs"`
+	}
+	err := c.Query(`query deploymentLogs($id: String!, $limit: Int) {
+  deploymentLogs(deploymentId: $id, limit: $limit) { timestamp message severity }
+}`, map[string]any{"id": id, "limit": limit}, &data)
+	if err != nil {
+		return nil, err
+	}
+	return data.DeploymentLogs, nil
+}
+
+// SetImage points the service at an image without deploying it
+func (c *Client) SetImage(target Target, image string) error {
+	err := c.Query(`mutation setImage($serviceId: String!, $environmentId: String!, $input: ServiceInstanceUpdateInput!) {
+  serviceInstanceUpdate(serviceId: $serviceId, environmentId: $environmentId, input: $input)
+}`, map[string]any{
+		"serviceId":     target.Service,
+		"environmentId": target.Environment,
+		"input":         map[string]any{"source": map[string]string{"image": image}},
+	}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to set service image: %w", err)
+	}
+	return nil
+}
+
+// Deploy starts a deployment of the service's current source and
+// variables, returning its ID
+func (c *Client) Deploy(target Target) (string, error) {
+	var data struct {
+		ID string `json:"serviceInstanceDeployV2"`
+	}
+	err := c.Query(`mutation deploy($serviceId: String!, $environmentId: String!) {
+  serviceInstanceDeployV2(serviceId: $serviceId, environmentId: $environmentId)
+}`, map[string]any{"serviceId": target.Service, "environmentId": target.Environment}, &data)
+	if err != nil {
+		return "", fmt.Errorf("failed to start deployment: %w", err)
+	}
+	return data.ID, nil
+}
+
+// Variables returns the environment variables of the service
+func (c *Client) Variables(target Target) (map[string]string, error) {
+	var data struct {
+		Variables map[string]string `json:"variables"`
+	}
+	err := c.Query(`query variables($projectId: String!, $environmentId: String!, $serviceId: String) {
+  variables(projectId: $projectId, environmentId: $environmentId, serviceId: $serviceId)
+}`, map[string]any{"projectId": target.Project, "environmentId": target.Environment, "serviceId": target.Service}, &data)
+	if err != nil {
+		return nil, err
+	}
+	return data.Variables, nil
+}
+
+// SetVariable creates or replaces a service variable without deploying;
+// the caller deploys once all variables are set
+func (c *Client) SetVariable(target Target, name, value string) error {
+	err := c.Query(`mutation variableUpsert($input: VariableUpsertInput!) {
+  variableUpsert(input: $input)
+}`, map[string]any{"input": map[string]any{
+		"projectId":     target.Project,
+		"environmentId": target.Environment,
+		"serviceId":     target.Service,
+		"name":          name,
+		"value":         value,
+		"skipDeploys":   true,
+	}}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to set %s: %w", name, err)
+	}
+	return nil
+}
+
+// ProjectToken is a project token without its secret value
+type ProjectToken struct {
+	ID            string    `json:"id"`
+	Name          string    `json:"name"`
+	EnvironmentID string    `json:"environmentId"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+// ProjectTokens lists the project tokens of the target's project
+func (c *Client) ProjectTokens(target Target) ([]ProjectToken, error) {
+	var data struct {
+		ProjectTokens struct {
+			Edges []struct {
+				Node ProjectToken `json:"node"`
+			} `json:"edges"`
+		} `json:"projectTokens"`
+	}
+	err := c.Query(`query projectTokens($projectId: String!) {
+  projectTokens(projectId: $projectId) { edges { node { id name environmentId createdAt } } }
+}`, map[string]any{"projectId": target.Project}, &data)
+	if err != nil {
+		return nil, err
+	}
+	tokens := make([]ProjectToken, 0, len(data.ProjectTokens.Edges))
+	for _, edge := range data.ProjectTokens.Edges {
+		tokens = append(tokens, edge.Node)
+	}
+	return tokens, nil
+}
+
+// CreateProjectToken creates a project token for the target's environment.
+// Railway only accepts account or team tokens for this.
+func (c *Client) CreateProjectToken(target Target, name string) (string, error) {
+	var data struct {
+		Token string `json:"projectTokenCreate"`
+	}
+	err := c.Query(`mutation projectTokenCreate($input: ProjectTokenCreateInput!) {
+  projectTokenCreate(input: $input)
+}`, map[string]any{"input": map[string]string{"projectId": target.Project, "environmentId": target.Environment, "name": name}}, &data)
+	if err != nil {
+		return "", fmt.Errorf("failed to create a project token: %w", err)
+	}
+	return data.Token, nil
+}
+
+// DeleteProjectToken revokes a project token
+func (c *Client) DeleteProjectToken(id string) error {
+	err := c.Query(`mutation projectTokenDelete($id: String!) {
+  projectTokenDelete(id: $id)
+}`, map[string]any{"id": id}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete project token %s: %w", id, err)
+	}
+	return nil
+}
+
+// CustomDomain is a custom domain attached to a service
+type CustomDomain struct {
+	ID     string `json:"id"`
+	Domain string `json:"domain"`
+	Status struct {
+		DNSRecords []struct {
+			Hostlabel     string `json:"hostlabel"`
+			RecordType    string `json:"recordType"`
+			RequiredValue string `json:"requiredValue"`
+			CurrentValue  string `json:"currentValue"`
+		} `json:"dnsRecords"`
+	} `json:"status"`
+}
+
+// CustomDomains lists the custom domains of a service instance
+func (c *Client) CustomDomains(target Target) ([]CustomDomain, error) {
+	var data struct {
+		Domains struct {
+			CustomDomains []CustomDomain `json:"customDomains"`
+		} `json:"domains"`
+	}
+	err := c.Query(`query domains($projectId: String!, $environmentId: String!, $serviceId: String!) {
+  domains(projectId: $projectId, environmentId: $environmentId, serviceId: $serviceId) {
+    customDomains { id domain }
+  }
+}`, map[string]any{"projectId": target.Project, "environmentId": target.Environment, "serviceId": target.Service}, &data)
+	if err != nil {
+		return nil, err
+	}
+	return data.Domains.CustomDomains, nil
+}
+
+// MoveCustomDomain detaches a domain from one service and attaches it to
+// another. from may be nil when no service holds the domain yet.
+func (c *Client) MoveCustomDomain(domain string, from *CustomDomain, to Target) (*CustomDomain, error) {
+	if from != nil {
+		err := c.Query(`mutation deleteDomain($id: String!) { customDomainDelete(id: $id) }`, map[string]any{"id": from.ID}, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to detach %s: %w", domain, err)
+		}
+	}
+	var data struct {
+		Domain CustomDomain `json:"customDomainCreate"`
+	}
+	err := c.Query(`mutation createDomain($input: CustomDomainCreateInput!) {
+  customDomainCreate(input: $input) {
+    id domain
+    status { dnsRecords { hostlabel recordType requiredValue currentValue } }
+  }
+}`, map[string]any{"input": map[string]string{
+		"domain": domain, "projectId": to.Project, "environmentId": to.Environment, "serviceId": to.Service,
+	}}, &data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to attach %s to %s: %w", domain, to.ServiceName, err)
+	}
+	return &data.Domain, nil
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Plugins are executables named strunzctl-<kind>-<name> in plugin_dir or on
+// PATH, in any language. strunzctl writes one JSON request with the
+// protocol version and the kind of request to a plugin's stdin; exit status
+// 0 means success and stderr explains a failure. Subprocesses rather than
+// Go plugins keep the CLI a static binary and plugins independent of its
+// toolchain.
+const pluginProtocol = 1
+
+// Plugin kinds
+const (
+	// pluginFormat writes a packages report in its own format to stdout
+	pluginFormat = "format"
+	// pluginNotify delivers an alert
+	pluginNotify = "notify"
+)
+
+var pluginKinds = []string{pluginFormat, pluginNotify}
+
+// pluginTimeouts bound a run of each kind; a report of many versions takes
+// longer to render than an alert to send
+var pluginTimeouts = map[string]time.Duration{pluginFormat: 5 * time.Minute, pluginNotify: 30 * time.Second}
+
+var pluginName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// pluginInfo is a discovered plugin
+type pluginInfo struct {
+	Kind, Name, Path string
+}
+
+// pluginDirs are the directories searched for plugins, plugin_dir first
+func pluginDirs() []string {
+	dirs := filepath.SplitList(os.Getenv("PATH"))
+	if config.PluginDir != "" {
+		dirs = append([]string{config.PluginDir}, dirs...)
+	}
+	return dirs
+}
+
+// findPlugin returns the path of the plugin of a kind and name
+func findPlugin(kind, name string) (string, error) {
+	if !pluginName.MatchString(name) {
+		return "", fmt.Errorf("invalid plugin name %q: use lowercase letters, digits, - and _", name)
+	}
+	file := "strunzctl-" + kind + "-" + name
+	for _, dir := range pluginDirs() {
+		if dir == "" {
+			continue
+		}
+		path := filepath.Join(dir, file)
+		if isExecutable(path) {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("no %s plugin %q: install an executable %s in plugin_dir or on PATH", kind, name, file)
+}
+
+// discoverPlugins lists the plugins of all kinds by kind and name; a plugin
+// earlier in the search order shadows one of the same name later
+func discoverPlugins() []pluginInfo {
+	var plugins []pluginInfo
+	seen := make(map[string]bool)
+	for _, dir := range pluginDirs() {
+		if dir == "" {
+			continue
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			rest, ok := strings.CutPrefix(entry.Name(), "strunzctl-")
+			if !ok {
+				continue
+			}
+			kind, name, ok := strings.Cut(rest, "-")
+			path := filepath.Join(dir, entry.Name())
+			if !ok || !slices.Contains(pluginKinds, kind) || !pluginName.MatchString(name) || seen[rest] || !isExecutable(path) {
+				continue
+			}
+			seen[rest] = true
+			plugins = append(plugins, pluginInfo{Kind: kind, Name: name, Path: path})
+		}
+	}
+	slices.SortFunc(plugins, func(a, b pluginInfo) int {
+		return strings.Compare(a.Kind+" "+a.Name, b.Kind+" "+b.Name)
+	})
+	return plugins
+}
+
+// isExecutable reports whether path is a regular file anyone may execute
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular() && info.Mode().Perm()&0o111 != 0
+}
+
+// runPlugin sends a request of a kind to the plugin at path and copies its
+// stdout to w. The plugin inherits the environment, so it can take its own
+// secrets from there, and is stopped after the timeout of its kind.
+func runPlugin(path, kind string, request map[string]any, w io.Writer) error {
+	request["protocol"] = pluginProtocol
+	request["kind"] = kind
+	input, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to encode plugin request: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(commandContext, pluginTimeouts[kind])
+	defer cancel()
+
+	var stderr bytes.Buffer
+	process := newProcessContext(ctx, path)
+	process.Stdin = bytes.NewReader(input)
+	process.Stdout, process.Stderr = w, &stderr
+	if err := process.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("%s timed out after %s", filepath.Base(path), pluginTimeouts[kind])
+		}
+		if commandContext.Err() != nil {
+			return context.Cause(commandContext)
+		}
+		if text := strings.TrimSpace(stderr.String()); text != "" {
+			err = fmt.Errorf("%w: %s", err, truncate(text, 500))
+		}
+		return fmt.Errorf("%s failed: %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
+// sendPlugin delivers an alert to a notify plugin as {"alert": {...}} with
+// the fields of the JSON webhook; what the plugin prints is discarded
+func sendPlugin(path string, alert Alert) error {
+	return runPlugin(path, pluginNotify, map[string]any{"alert": alert.payload()}, io.Discard)
+}
+
+// pluginReportWriter renders the packages report with a format plugin,
+// which gets {"report": {...}} with the versions and releases and writes
+// the finished report
+func pluginReportWriter(path string) func(io.Writer, []VersionDetails, *releaseActivity) error {
+	return func(w io.Writer, details []VersionDetails, activity *releaseActivity) error {
+		return runPlugin(path, pluginFormat, map[string]any{"report": newPluginReport(details, activity)}, w)
+	}
+}
+
+// pluginReport is the report data of format plugins. Sizes are bytes and
+// times RFC 3339 in UTC, so plugins need not parse the human forms.
+type pluginReport struct {
+	Registry   string          `json:"registry"`
+	Repository string          `json:"repository"`
+	Generated  time.Time       `json:"generated"`
+	Versions   []pluginVersion `json:"versions"`
+	// Releases is null when they could not be fetched
+	Releases []pluginRelease `json:"releases"`
+}
+
+type pluginVersion struct {
+	ID        int64     `json:"id"`
+	Digest    string    `json:"digest"`
+	Tags      []string  `json:"tags"`
+	Created   time.Time `json:"created"`
+	SizeBytes int64     `json:"size_bytes"`
+	// Scan is null for versions without a scan result
+	Scan *pluginScan `json:"scan"`
+}
+
+type pluginScan struct {
+	Scanner   string         `json:"scanner"`
+	ScannedAt time.Time      `json:"scanned_at"`
+	Counts    map[string]int `json:"counts"`
+}
+
+type pluginRelease struct {
+	Tag        string      `json:"tag"`
+	Name       string      `json:"name"`
+	URL        string      `json:"url"`
+	Prerelease bool        `json:"prerelease"`
+	Published  time.Time   `json:"published"`
+	Image      bool        `json:"image"`
+	Runs       []pluginRun `json:"runs"`
+}
+
+type pluginRun struct {
+	Workflow   string `json:"workflow"`
+	Event      string `json:"event"`
+	Status     string `json:"status"`
+	Conclusion string `json:"conclusion"`
+	URL        string `json:"url"`
+}
+
+func newPluginReport(details []VersionDetails, activity *releaseActivity) pluginReport {
+	report := pluginReport{
+		Registry:   config.Registry,
+		Repository: imageRepository(),
+		Generated:  time.Now().UTC(),
+		Versions:   make([]pluginVersion, 0, len(details)),
+	}
+	for _, detail := range details {
+		version := pluginVersion{
+			ID:        detail.Version.ID,
+			Digest:    detail.Version.Name,
+			Tags:      detail.Version.Metadata.Container.Tags,
+			Created:   detail.Version.CreatedAt.UTC(),
+			SizeBytes: detail.Size,
+		}
+		if version.Tags == nil {
+			version.Tags = []string{}
+		}
+		if scan := detail.Scan; scan != nil {
+			version.Scan = &pluginScan{Scanner: scan.Scanner, ScannedAt: scan.ScannedAt.UTC(), Counts: scan.Counts}
+		}
+		report.Versions = append(report.Versions, version)
+	}
+	if activity == nil {
+		return report
+	}
+
+	imaged := imagedReleases(details)
+	report.Releases = make([]pluginRelease, 0, len(activity.Releases))
+	for _, release := range activity.Releases {
+		if release.Draft {
+			continue
+		}
+		version, ok := parseSemver(release.TagName)
+		entry := pluginRelease{
+			Tag:        release.TagName,
+			Name:       release.Name,
+			URL:        release.HTMLURL,
+			Prerelease: release.Prerelease,
+			Published:  release.PublishedAt.UTC(),
+			Image:      ok && imaged[version.String()],
+			Runs:       []pluginRun{},
+		}
+		for _, run := range activity.Runs[release.TagName] {
+			entry.Runs = append(entry.Runs, pluginRun(run))
+		}
+		report.Releases = append(report.Releases, entry)
+	}
+	return report
+}
+
+func newPluginsCommand() *Command {
+	list := newCommand("list", "", "List the format and notify plugins in plugin_dir and on PATH.")
+	list.Run = func(args []string) error {
+		if err := list.ExactArgs(args, 0); err != nil {
+			return err
+		}
+		plugins := discoverPlugins()
+		if len(plugins) == 0 {
+			fmt.Println("No plugins found; plugins are executables named strunzctl-format-<name> or strunzctl-notify-<name>")
+			return nil
+		}
+		enabled := splitList(config.Alerts.Plugins)
+		fmt.Printf("\n🔌 %d plugin(s)\n\n", len(plugins))
+		for _, plugin := range plugins {
+			use := "packages report --format " + plugin.Name
+			if plugin.Kind == pluginNotify {
+				use = "not in alerts.plugins"
+				if slices.Contains(enabled, plugin.Name) {
+					use = "alerts.plugins"
+				}
+			}
+			fmt.Printf("  %-7s %-16s %s  (%s)\n", plugin.Kind, plugin.Name, plugin.Path, use)
+		}
+		return nil
+	}
+	return newGroup("plugins", "Discover report format and alert sink plugins.", list)
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"errors"
+	"sync"
+)
+
+// forEachConcurrent calls fn for every item using at most concurrency
+// goroutines. All items are processed even if some fail; the failures are
+// returned joined, in item order.
+func forEachConcurrent[T any](items []T, concurrency int, fn func(i int, item T) error) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	errs := make([]error, len(items))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(concurrency, len(items)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				errs[i] = fn(i, items[i])
+			}
+		}()
+	}
+
+	for i := range items {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// progressLogInterval spaces the progress log lines written instead of a
+// redrawn line when stderr is not a terminal, as in CI
+const progressLogInterval = 30 * time.Second
+
+// progressFrames animate the spinner of operations without a known total
+var progressFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// progressTerminal guards the progress line on stderr, which log records
+// and progressPrintf clear before they write
+var progressTerminal struct {
+	sync.Mutex
+	drawn bool
+}
+
+// clearProgressLine erases a drawn progress line; the caller holds the lock
+func clearProgressLine() {
+	if progressTerminal.drawn {
+		fmt.Fprint(os.Stderr, "\r\033[K")
+		progressTerminal.drawn = false
+	}
+}
+
+// progressAwareWriter writes below the progress line instead of into it;
+// the next tick redraws the line
+type progressAwareWriter struct {
+	w io.Writer
+}
+
+func (w progressAwareWriter) Write(p []byte) (int, error) {
+	progressTerminal.Lock()
+	defer progressTerminal.Unlock()
+	clearProgressLine()
+	return w.w.Write(p)
+}
+
+// progressPrintf prints to stdout like fmt.Printf without garbling the
+// progress line on a shared terminal
+func progressPrintf(format string, args ...any) {
+	progressTerminal.Lock()
+	defer progressTerminal.Unlock()
+	clearProgressLine()
+	fmt.Printf(format, args...)
+}
+
+// progress reports a long operation: a spinner line with rate, ETA and
+// bytes transferred, redrawn on stderr when it is a terminal, or a log line
+// every progressLogInterval otherwise. Add and AddBytes are safe for
+// concurrent use; Finish must be called once the operation ends.
+type progress struct {
+	label string
+	// total is the number of items, 0 when unknown
+	total        int64
+	items, bytes atomic.Int64
+	start        time.Time
+	stop, done   chan struct{}
+}
+
+// startProgress starts reporting an operation over total items
+func startProgress(label string, total int) *progress {
+	p := &progress{label: label, total: int64(total), start: time.Now(), stop: make(chan struct{}), done: make(chan struct{})}
+	// Quiet runs and JSON logs get no redrawn line; log lines at info are
+	// dropped by --quiet as the handler's level
+	terminal := isTerminal(os.Stderr) && !globalOptions.quiet && globalOptions.logFormat == "text"
+	go p.run(terminal)
+	return p
+}
+
+// Add counts n finished items
+func (p *progress) Add(n int) {
+	p.items.Add(int64(n))
+}
+
+// AddBytes counts n transferred bytes
+func (p *progress) AddBytes(n int64) {
+	p.bytes.Add(n)
+}
+
+// Reader counts the bytes read from r
+func (p *progress) Reader(r io.Reader) io.Reader {
+	return progressReader{r: r, p: p}
+}
+
+// Finish stops reporting and erases the progress line
+func (p *progress) Finish() {
+	close(p.stop)
+	<-p.done
+}
+
+func (p *progress) run(terminal bool) {
+	defer close(p.done)
+	interval := progressLogInterval
+	if terminal {
+		interval = 100 * time.Millisecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for frame := 0; ; frame++ {
+		select {
+		case <-ticker.C:
+			if !terminal {
+				slog.Info(p.label, "progress", p.status())
+				continue
+			}
+			progressTerminal.Lock()
+			fmt.Fprintf(os.Stderr, "\r\033[K%s %s  %s", progressFrames[frame%len(progressFrames)], p.label, p.status())
+			progressTerminal.drawn = true
+			progressTerminal.Unlock()
+		case <-p.stop:
+			progressTerminal.Lock()
+			clearProgressLine()
+			progressTerminal.Unlock()
+			return
+		}
+	}
+}
+
+// status describes the progress so far, such as
+// "42/120 (35%), 3.2/s, ETA 24s" or "7 done, 0.5/s, 12.3 MiB at 1.1 MiB/s"
+func (p *progress) status() string {
+	elapsed := time.Since(p.start).Seconds()
+	items, bytes := p.items.Load(), p.bytes.Load()
+	var parts []string
+	if p.total > 0 {
+		parts = append(parts, fmt.Sprintf("%d/%d (%d%%)", items, p.total, items*100/p.total))
+	} else {
+		parts = append(parts, fmt.Sprintf("%d done", items))
+	}
+	if items > 0 && elapsed > 0 {
+		rate := float64(items) / elapsed
+		parts = append(parts, fmt.Sprintf("%.1f/s", rate))
+		if p.total > items {
+			eta := time.Duration(float64(p.total-items) / rate * float64(time.Second))
+			parts = append(parts, "ETA "+eta.Round(time.Second).String())
+		}
+	}
+	if bytes > 0 && elapsed > 0 {
+		parts = append(parts, fmt.Sprintf("%s at %s/s", formatBytes(bytes), formatBytes(int64(float64(bytes)/elapsed))))
+	}
+	return strings.Join(parts, ", ")
+}
+
+type progressReader struct {
+	r io.Reader
+	p *progress
+}
+
+func (r progressReader) Read(b []byte) (int, error) {
+	n, err := r.r.Read(b)
+	r.p.AddBytes(int64(n))
+	return n, err
+}
@@ -0,0 +1,72 @@
+package main
+
+import "fmt"
+
+func newImagePromoteCommand() *Command {
+	cmd := newCommand("promote", "<source-tag> <dest-tag>", "Point a tag at the image of another tag using only registry API calls.")
+	dryRun := cmd.Flags.Bool("dry-run", false, "show what would change without writing the tag")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 2); err != nil {
+			return err
+		}
+		source, dest := args[0], args[1]
+		registry := newRegistryClient(config.Registry, imageRepository())
+
+		fmt.Printf("\n⬆️  Promoting %s → %s\n", registry.Reference(source), registry.Reference(dest))
+		previous, digest, err := promoteTag(registry, source, dest, *dryRun)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case previous == digest:
+			fmt.Printf("✅ %s already points to %s\n", dest, digest)
+		case *dryRun:
+			fmt.Printf("Would move %s from %s to %s (dry run)\n", dest, orNone(previous), digest)
+		default:
+			fmt.Printf("✅ %s moved from %s to %s\n", dest, orNone(previous), digest)
+			return recordAudit(AuditEntry{Action: auditPromote, Tag: source, Digest: digest, Target: dest})
+		}
+		return nil
+	}
+	return cmd
+}
+
+// promoteTag copies the manifest of source to dest. It returns the digest
+// dest pointed to before (empty if it did not exist) and the new digest.
+func promoteTag(registry *RegistryClient, source, dest string, dryRun bool) (previous, digest string, err error) {
+	manifest, err := registry.GetRawManifest(source)
+	if err != nil {
+		return "", "", err
+	}
+
+	if current, err := registry.GetRawManifest(dest); err == nil {
+		previous = current.Digest
+	} else if !isNotFound(err) {
+		return "", "", err
+	}
+
+	if previous == manifest.Digest || dryRun {
+		return previous, manifest.Digest, nil
+	}
+
+	digest, err = registry.PutManifest(dest, manifest)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to tag %s: %w", dest, err)
+	}
+	if digest == "" {
+		digest = manifest.Digest
+	}
+	if digest != manifest.Digest {
+		return "", "", fmt.Errorf("registry stored %s as %s, expected %s", dest, digest, manifest.Digest)
+	}
+	return previous, digest, nil
+}
+
+func orNone(value string) string {
+	if value == "" {
+		return "(none)"
+	}
+	return value
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// SLSA provenance predicate types written by BuildKit
+const (
+	predicateSLSA02 = "https://slsa.dev/provenance/v0.2"
+	predicateSLSA1  = "https://slsa.dev/provenance/v1"
+)
+
+// actionsBuilderID matches the builder ID of a GitHub Actions build
+var actionsBuilderID = regexp.MustCompile(`^https://github\.com/([^/]+/[^/]+)/actions/runs/(\d+)`)
+
+// Provenance holds the fields of a SLSA v0.2 or v1 predicate that identify
+// where an image was built
+type Provenance struct {
+	BuilderID string
+	SourceURI string
+	Revision  string
+}
+
+// parseProvenance reads the builder and source from either predicate
+// version
+func parseProvenance(predicate []byte, predicateType string) (*Provenance, error) {
+	type configSource struct {
+		URI    string            `json:"uri"`
+		Digest map[string]string `json:"digest"`
+	}
+	var source configSource
+	provenance := &Provenance{}
+
+	switch predicateType {
+	case predicateSLSA02:
+		var doc struct {
+			Builder    struct{ ID string } `json:"builder"`
+			Invocation struct {
+				ConfigSource configSource `json:"configSource"`
+			} `json:"invocation"`
+		}
+		if err := json.Unmarshal(predicate, &doc); err != nil {
+			return nil, fmt.Errorf("failed to parse provenance: %w", err)
+		}
+		provenance.BuilderID, source = doc.Builder.ID, doc.Invocation.ConfigSource
+	case predicateSLSA1:
+		var doc struct {
+			BuildDefinition struct {
+				ExternalParameters struct {
+					ConfigSource configSource `json:"configSource"`
+				} `json:"externalParameters"`
+			} `json:"buildDefinition"`
+			RunDetails struct {
+				Builder struct{ ID string } `json:"builder"`
+			} `json:"runDetails"`
+		}
+		if err := json.Unmarshal(predicate, &doc); err != nil {
+			return nil, fmt.Errorf("failed to parse provenance: %w", err)
+		}
+		provenance.BuilderID, source = doc.RunDetails.Builder.ID, doc.BuildDefinition.ExternalParameters.ConfigSource
+	default:
+		return nil, fmt.Errorf("unsupported provenance type %s", predicateType)
+	}
+	provenance.SourceURI = source.URI
+	provenance.Revision = source.Digest["sha1"]
+	return provenance, nil
+}
+
+func newImageVerifyProvenanceCommand() *Command {
+	cmd := newCommand("verify-provenance", "<tag>", "Check that an image was built by the expected GitHub Actions workflow, repository and ref.")
+	platform := cmd.Flags.String("platform", defaultPlatform, "platform image whose provenance is checked")
+	workflow := cmd.Flags.String("workflow", defaultWorkflow, "workflow file that must have built the image")
+	ref := cmd.Flags.String("ref", "", "branch or tag the build must come from (default: v<tag> for release tags, else main)")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 1); err != nil {
+			return err
+		}
+		tag := args[0]
+		expectedRef := *ref
+		if expectedRef == "" {
+			expectedRef = "main"
+			if version, ok := parseSemver(tag); ok {
+				expectedRef = "v" + version.String()
+			}
+		}
+		registry := newRegistryClient(config.Registry, imageRepository())
+
+		index, err := registry.GetManifest(tag)
+		if err != nil {
+			return err
+		}
+		image, err := selectPlatform(registry, index, *platform)
+		if err != nil {
+			return err
+		}
+		predicate, predicateType, err := attestedPredicate(registry, index, image.Digest, predicateSLSA1, predicateSLSA02)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("\n🔏 Provenance of %s (%s)\n", registry.Reference(tag), *platform)
+		if predicate == nil {
+			fmt.Println("❌ no SLSA provenance attestation attached")
+			return fmt.Errorf("%w: %s has no provenance, it was not built by CI with attestations", errPolicy, tag)
+		}
+		provenance, err := parseProvenance(predicate, predicateType)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Type:    %s\n", predicateType)
+		fmt.Printf("Builder: %s\n", provenance.BuilderID)
+		fmt.Printf("Source:  %s\n", provenance.SourceURI)
+
+		problems := 0
+		check := func(ok bool, success, failure string) {
+			if ok {
+				fmt.Printf("✅ %s\n", success)
+			} else {
+				problems++
+				fmt.Printf("❌ %s\n", failure)
+			}
+		}
+
+		match := actionsBuilderID.FindStringSubmatch(provenance.BuilderID)
+		check(match != nil && strings.EqualFold(match[1], config.Repo), "built by GitHub Actions in "+config.Repo,
+			fmt.Sprintf("builder %q is not a GitHub Actions run of %s", provenance.BuilderID, config.Repo))
+		sourceRepo, sourceRef := splitSourceURI(provenance.SourceURI)
+		check(strings.EqualFold(sourceRepo, config.Repo), "source repository is "+config.Repo,
+			fmt.Sprintf("source %q is not %s", provenance.SourceURI, config.Repo))
+
+		if match != nil {
+			github, err := newGitHubClient()
+			if err != nil {
+				return err
+			}
+			runID, _ := strconv.ParseInt(match[2], 10, 64)
+			run, err := getWorkflowRun(github, match[1], runID)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Run:     %s (%s on %s)\n", run.HTMLURL, run.Event, run.HeadBranch)
+			check(path.Base(run.Path) == *workflow, "built by workflow "+*workflow,
+				fmt.Sprintf("built by workflow %s, expected %s", path.Base(run.Path), *workflow))
+			check(run.HeadBranch == expectedRef, "built from "+expectedRef,
+				fmt.Sprintf("built from %s, expected %s", run.HeadBranch, expectedRef))
+			if provenance.Revision != "" {
+				check(provenance.Revision == run.HeadSHA, "revision "+provenance.Revision+" matches the run",
+					fmt.Sprintf("revision %s differs from the run's commit %s", provenance.Revision, run.HeadSHA))
+			}
+		} else if sourceRef != "" {
+			sourceRef = strings.TrimPrefix(strings.TrimPrefix(sourceRef, "refs/tags/"), "refs/heads/")
+			check(sourceRef == expectedRef, "built from "+expectedRef,
+				fmt.Sprintf("built from %s, expected %s", sourceRef, expectedRef))
+		}
+
+		if problems > 0 {
+			return fmt.Errorf("%w: provenance of %s failed %d check(s)", errPolicy, tag, problems)
+		}
+		fmt.Println("\n✅ Provenance verified")
+		return nil
+	}
+	return cmd
+}
+
+// getWorkflowRun fetches a single workflow run
+func getWorkflowRun(github *GitHubClient, repo string, id int64) (*WorkflowRun, error) {
+	var run WorkflowRun
+	if err := github.Get(fmt.Sprintf("/repos/%s/actions/runs/%d", repo, id), &run); err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// splitSourceURI turns https://github.com/org/repo.git#refs/tags/v1.0.0
+// into the repository and the ref
+func splitSourceURI(uri string) (repo, ref string) {
+	uri, ref, _ = strings.Cut(uri, "#")
+	uri = strings.TrimPrefix(strings.TrimPrefix(uri, "git+"), "https://github.com/")
+	return strings.TrimSuffix(uri, ".git"), ref
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// imageArchive writes an image into a tarball as it is pulled. Blobs are
+// stored content addressed under blobs/sha256, which both `docker load`
+// and OCI tools read.
+type imageArchive struct {
+	tar      *tar.Writer
+	registry *RegistryClient
+	written  map[string]bool
+	bytes    int64
+	created  time.Time
+	// progress counts the blobs written and the bytes downloaded
+	progress *progress
+}
+
+func (a *imageArchive) writeFile(name string, content []byte) error {
+	header := &tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), ModTime: a.created, Typeflag: tar.TypeReg}
+	if err := a.tar.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := a.tar.Write(content)
+	return err
+}
+
+// blobPath is where a blob is stored in the archive
+func blobPath(digest string) string {
+	return "blobs/sha256/" + strings.TrimPrefix(digest, "sha256:")
+}
+
+// writeManifest adds a manifest exactly as the registry serves it and
+// returns its descriptor
+func (a *imageArchive) writeManifest(reference string) (*RawManifest, Descriptor, error) {
+	raw, err := a.registry.GetRawManifest(reference)
+	if err != nil {
+		return nil, Descriptor{}, err
+	}
+	sum := sha256.Sum256(raw.Body)
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+	if strings.HasPrefix(reference, "sha256:") && digest != reference {
+		return nil, Descriptor{}, fmt.Errorf("manifest %s has digest %s", reference, digest)
+	}
+	if !a.written[digest] {
+		if err := a.writeFile(blobPath(digest), raw.Body); err != nil {
+			return nil, Descriptor{}, err
+		}
+		a.written[digest] = true
+	}
+	return raw, Descriptor{MediaType: raw.MediaType, Digest: digest, Size: int64(len(raw.Body))}, nil
+}
+
+// writeBlob streams a blob into the archive, checking its digest
+func (a *imageArchive) writeBlob(blob Descriptor) error {
+	if a.written[blob.Digest] {
+		return nil
+	}
+	reader, _, err := a.registry.OpenBlob(blob.Digest)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	header := &tar.Header{Name: blobPath(blob.Digest), Mode: 0o644, Size: blob.Size, ModTime: a.created, Typeflag: tar.TypeReg}
+	if err := a.tar.WriteHeader(header); err != nil {
+		return err
+	}
+	hash := sha256.New()
+	written, err := io.Copy(a.tar, io.TeeReader(io.LimitReader(a.progress.Reader(reader), blob.Size), hash))
+	if err != nil {
+		return fmt.Errorf("failed to download blob %s: %w", blob.Digest, err)
+	}
+	if got := "sha256:" + hex.EncodeToString(hash.Sum(nil)); written != blob.Size || got != blob.Digest {
+		return fmt.Errorf("blob %s does not match its descriptor (%d bytes, %s)", blob.Digest, written, got)
+	}
+	a.written[blob.Digest] = true
+	a.bytes += blob.Size
+	a.progress.Add(1)
+	return nil
+}
+
+// writeImage adds a platform image manifest with its config and layers
+func (a *imageArchive) writeImage(reference string) (*Manifest, Descriptor, error) {
+	raw, desc, err := a.writeManifest(reference)
+	if err != nil {
+		return nil, Descriptor{}, err
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(raw.Body, &manifest); err != nil {
+		return nil, Descriptor{}, fmt.Errorf("failed to parse manifest %s: %w", reference, err)
+	}
+	manifest.Digest = desc.Digest
+	for i, blob := range append([]Descriptor{manifest.Config}, manifest.Layers...) {
+		if i > 0 {
+			progressPrintf("  ⬇️  %s (%s)\n", shortDigest(blob.Digest), formatBytes(blob.Size))
+		}
+		if err := a.writeBlob(blob); err != nil {
+			return nil, Descriptor{}, err
+		}
+	}
+	return &manifest, desc, nil
+}
+
+// pullImage writes tag into the archive, as a docker-save tarball for
+// `docker load` or as an OCI image layout. With allPlatforms the OCI
+// layout keeps the whole manifest list.
+func pullImage(archive *imageArchive, tag, platform, format string, allPlatforms bool) (string, error) {
+	reference := archive.registry.Reference(tag)
+	annotations := map[string]string{
+		"org.opencontainers.image.ref.name": tag,
+		"io.containerd.image.name":          reference,
+	}
+
+	var root Descriptor
+	var image *Manifest
+	if allPlatforms {
+		raw, desc, err := archive.writeManifest(tag)
+		if err != nil {
+			return "", err
+		}
+		var index Manifest
+		if err := json.Unmarshal(raw.Body, &index); err != nil {
+			return "", fmt.Errorf("failed to parse manifest %s: %w", tag, err)
+		}
+		if !index.IsIndex() {
+			return "", fmt.Errorf("%w: %s is a single-platform image, drop --all-platforms", errUsage, tag)
+		}
+		for _, child := range index.Manifests {
+			label := "attestation"
+			if child.Platform != nil && !child.IsAttestation() {
+				label = child.Platform.String()
+			}
+			progressPrintf("📦 %s %s\n", label, shortDigest(child.Digest))
+			if _, _, err := archive.writeImage(child.Digest); err != nil {
+				return "", err
+			}
+		}
+		root = desc
+	} else {
+		selected, err := resolvePlatformManifest(archive.registry, tag, platform)
+		if err != nil {
+			return "", err
+		}
+		progressPrintf("📦 %s %s\n", platform, shortDigest(selected.Digest))
+		if image, root, err = archive.writeImage(selected.Digest); err != nil {
+			return "", err
+		}
+	}
+	root.Annotations = annotations
+
+	switch format {
+	case "docker":
+		layers := make([]string, len(image.Layers))
+		for i, layer := range image.Layers {
+			layers[i] = blobPath(layer.Digest)
+		}
+		manifest, _ := json.Marshal([]map[string]any{{
+			"Config":   blobPath(image.Config.Digest),
+			"RepoTags": []string{reference},
+			"Layers":   layers,
+		}})
+		if err := archive.writeFile("manifest.json", manifest); err != nil {
+			return "", err
+		}
+	case "oci":
+		index, _ := json.Marshal(map[string]any{
+			"schemaVersion": 2,
+			"mediaType":     mediaTypeOCIIndex,
+			"manifests":     []Descriptor{root},
+		})
+		if err := archive.writeFile("index.json", index); err != nil {
+			return "", err
+		}
+		if err := archive.writeFile("oci-layout", []byte(`{"imageLayoutVersion":"1.0.0"}`)); err != nil {
+			return "", err
+		}
+	}
+	return root.Digest, nil
+}
+
+func newImagePullCommand() *Command {
+	cmd := newCommand("pull", "<tag>", "Pull an image through the registry API into a docker-save tarball or OCI layout, without a Docker daemon.")
+	output := cmd.Flags.String("output", "", "tarball to write (default <package>_<tag>.tar)")
+	format := cmd.Flags.String("format", "docker", "archive format: docker (for docker load and trivy --input) or oci (an OCI image layout)")
+	platform := cmd.Flags.String("platform", defaultPlatform, "platform image to pull")
+	allPlatforms := cmd.Flags.Bool("all-platforms", false, "keep every platform of the manifest list (oci format only)")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 1); err != nil {
+			return err
+		}
+		tag := args[0]
+		if *format != "docker" && *format != "oci" {
+			return fmt.Errorf("%w: --format must be docker or oci", errUsage)
+		}
+		if *allPlatforms && *format != "oci" {
+			return fmt.Errorf("%w: --all-platforms needs --format oci; a docker-save tarball holds one platform", errUsage)
+		}
+		if *output == "" {
+			*output = fmt.Sprintf("%s_%s.tar", config.Package, strings.NewReplacer(":", "-", "/", "-").Replace(tag))
+		}
+
+		registry := newRegistryClient(config.Registry, imageRepository())
+		fmt.Printf("\n⬇️  Pulling %s → %s (%s)\n", registry.Reference(tag), *output, *format)
+
+		// Written next to the output and renamed, so a failed pull leaves
+		// no truncated tarball behind
+		file, err := os.CreateTemp(filepath.Dir(*output), ".strunzctl-pull-*")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(file.Name())
+		defer file.Close()
+		archive := &imageArchive{tar: tar.NewWriter(file), registry: registry, written: make(map[string]bool), created: time.Now().UTC(),
+			progress: startProgress("Pulling "+tag, 0)}
+		digest, err := pullImage(archive, tag, *platform, *format, *allPlatforms)
+		archive.progress.Finish()
+		if err != nil {
+			return err
+		}
+		if err := archive.tar.Close(); err != nil {
+			return err
+		}
+		if err := file.Close(); err != nil {
+			return err
+		}
+		if err := os.Chmod(file.Name(), 0o644); err != nil {
+			return err
+		}
+		if err := os.Rename(file.Name(), *output); err != nil {
+			return err
+		}
+		fmt.Printf("\n✅ Wrote %s (%s, %s of blobs)\n", *output, digest, formatBytes(archive.bytes))
+		return nil
+	}
+	return cmd
+}
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	railwayapi "github.com/longevitycoach/StrunzKnowledge/src/scripts/strunzctl/pkg/railway"
+)
+
+// railwayAPI is the Railway public GraphQL endpoint
+const railwayAPI = railwayapi.API
+
+// The Railway client lives in pkg/railway so other tools can import it;
+// the aliases keep the names the commands use
+type (
+	RailwayClient       = railwayapi.Client
+	RailwayDeployment   = railwayapi.Deployment
+	RailwayLog          = railwayapi.Log
+	RailwayProjectToken = railwayapi.ProjectToken
+	RailwayCustomDomain = railwayapi.CustomDomain
+	// railwayTarget identifies the service instance commands operate on
+	railwayTarget = railwayapi.Target
+)
+
+// newRailwayClient uses railway.token (an account or team token), then
+// RAILWAY_API_TOKEN, then the project token in RAILWAY_TOKEN that CI uses,
+// then the token stored by `login`
+func newRailwayClient() (*RailwayClient, error) {
+	var client *RailwayClient
+	switch {
+	case config.Railway.Token != "":
+		client = railwayapi.New(config.Railway.Token, false)
+	case os.Getenv("RAILWAY_API_TOKEN") != "":
+		client = railwayapi.New(os.Getenv("RAILWAY_API_TOKEN"), false)
+	case os.Getenv("RAILWAY_TOKEN") != "":
+		client = railwayapi.New(os.Getenv("RAILWAY_TOKEN"), true)
+	case loadCredential(railwayCredentialServer) != "":
+		client = railwayapi.New(loadCredential(railwayCredentialServer), false)
+	default:
+		return nil, fmt.Errorf("%w: no Railway token available (set railway.token, RAILWAY_API_TOKEN or RAILWAY_TOKEN, or run `strunzctl login railway`)", errAuth)
+	}
+	client.Retry = doWithRetry
+	return client, nil
+}
+
+// resolveRailwayTarget resolves the configured project and environment
+// and a service, railway.service unless one is given
+func resolveRailwayTarget(railway *RailwayClient, service string) (railwayTarget, error) {
+	if service == "" {
+		service = config.Railway.Service
+	}
+	if config.Railway.Project == "" && !railway.ProjectToken {
+		return railwayTarget{}, fmt.Errorf("%w: set railway.project or use a project token in RAILWAY_TOKEN", errUsage)
+	}
+	target, err := railway.ResolveTarget(config.Railway.Project, config.Railway.Environment, service)
+	if errors.Is(err, railwayapi.ErrServiceRequired) {
+		return target, fmt.Errorf("%w: %w; set railway.service or --service", errUsage, err)
+	}
+	return target, err
+}
+
+// deployRailwayImage points the service at an image and starts a
+// deployment, returning its ID. Deployments are recorded in the audit log.
+func deployRailwayImage(railway *RailwayClient, target railwayTarget, image string) (string, error) {
+	if err := railway.SetImage(target, image); err != nil {
+		return "", err
+	}
+	id, err := railway.Deploy(target)
+	if err != nil {
+		return "", err
+	}
+	if err := recordAudit(AuditEntry{Action: auditDeploy, Tag: image, Target: target.ServiceName}); err != nil {
+		return id, err
+	}
+	return id, nil
+}
+
+// waitForDeployment polls a deployment until Railway finishes it
+func waitForDeployment(railway *RailwayClient, id string, timeout, interval time.Duration) (*RailwayDeployment, error) {
+	deadline := time.Now().Add(timeout)
+	status := ""
+	for {
+		deployment, err := railway.Deployment(id)
+		if err != nil {
+			return nil, err
+		}
+		if deployment.Status != status {
+			status = deployment.Status
+			fmt.Printf("  %s  %s\n", time.Now().Format(time.TimeOnly), status)
+		}
+		if deployment.Finished() {
+			if deployment.Status != "SUCCESS" {
+				return deployment, fmt.Errorf("deployment %s finished with status %s", id, deployment.Status)
+			}
+			return deployment, nil
+		}
+		if time.Now().Add(interval).After(deadline) {
+			return deployment, fmt.Errorf("deployment %s did not finish within %s (status %s)", id, timeout, deployment.Status)
+		}
+		if err := sleepContext(interval); err != nil {
+			return deployment, err
+		}
+	}
+}
+
+func newDeployStatusCommand() *Command {
+	cmd := newCommand("status", "", "Show the latest Railway deployments of the service.")
+	service := cmd.Flags.String("service", "", "Railway service name or ID (default: railway.service)")
+	limit := cmd.Flags.Int("limit", 5, "number of deployments to show")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 0); err != nil {
+			return err
+		}
+		railway, err := newRailwayClient()
+		if err != nil {
+			return err
+		}
+		target, err := resolveRailwayTarget(railway, *service)
+		if err != nil {
+			return err
+		}
+		deployments, err := railway.Deployments(target, *limit)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("\n🚂 Deployments of %s\n", target.ServiceName)
+		if len(deployments) == 0 {
+			fmt.Println("  (none)")
+			return nil
+		}
+		for _, deployment := range deployments {
+			fmt.Printf("  %s  %-10s %s  %s\n", deployment.ID, deployment.Status,
+				deployment.CreatedAt.Local().Format("2006-01-02 15:04"), deployment.Source())
+		}
+		return nil
+	}
+	return cmd
+}
+
+func newDeployLogsCommand() *Command {
+	cmd := newCommand("logs", "[deployment-id]", "Print the log of a Railway deployment (default: the latest one).")
+	service := cmd.Flags.String("service", "", "Railway service name or ID (default: railway.service)")
+	limit := cmd.Flags.Int("limit", 100, "number of log lines")
+
+	cmd.Run = func(args []string) error {
+		if len(args) > 1 {
+			return cmd.ExactArgs(args, 1)
+		}
+		railway, err := newRailwayClient()
+		if err != nil {
+			return err
+		}
+
+		var id string
+		if len(args) == 1 {
+			id = args[0]
+		} else {
+			target, err := resolveRailwayTarget(railway, *service)
+			if err != nil {
+				return err
+			}
+			deployments, err := railway.Deployments(target, 1)
+			if err != nil {
+				return err
+			}
+			if len(deployments) == 0 {
+				return fmt.Errorf("service %s has no deployments", target.ServiceName)
+			}
+			id = deployments[0].ID
+		}
+
+		logs, err := railway.DeploymentLogs(id, *limit)
+		if err != nil {
+			return err
+		}
+		for _, line := range logs {
+			fmt.Printf("%s %-5s %s\n", line.Timestamp.Local().Format(time.DateTime), strings.ToUpper(line.Severity), line.Message)
+		}
+		return nil
+	}
+	return cmd
+}
+
+func newDeployRedeployCommand() *Command {
+	cmd := newCommand("redeploy", "<tag>", "Deploy a GHCR image tag to the Railway service.")
+	service := cmd.Flags.String("service", "", "Railway service name or ID (default: railway.service)")
+	wait := cmd.Flags.Bool("wait", true, "wait until the deployment finished")
+	timeout := cmd.Flags.Duration("timeout", 15*time.Minute, "how long to wait for the deployment")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 1); err != nil {
+			return err
+		}
+		registry := newRegistryClient(config.Registry, imageRepository())
+		image := registry.Reference(args[0])
+		if _, err := registry.GetRawManifest(args[0]); err != nil {
+			return err
+		}
+
+		railway, err := newRailwayClient()
+		if err != nil {
+			return err
+		}
+		target, err := resolveRailwayTarget(railway, *service)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("\n🚂 Deploying %s to %s\n", image, target.ServiceName)
+		id, err := deployRailwayImage(railway, target, image)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Deployment: %s\n", id)
+		if !*wait {
+			return nil
+		}
+		if _, err := waitForDeployment(railway, id, *timeout, 10*time.Second); err != nil {
+			return err
+		}
+		fmt.Printf("\n✅ %s is live\n", image)
+		return nil
+	}
+	return cmd
+}
+
+func newDeployRollbackCommand() *Command {
+	cmd := newCommand("rollback", "", "Point the production tag back at the last promoted image and redeploy it.")
+	service := cmd.Flags.String("service", "", "Railway service name or ID (default: railway.service)")
+	productionTag := cmd.Flags.String("production-tag", "latest", "GHCR tag that marks the production image")
+	to := cmd.Flags.String("to", "", "tag to roll back to (default: the previously promoted image)")
+	yes := cmd.Flags.Bool("yes", false, "do not ask for confirmation")
+	wait := cmd.Flags.Bool("wait", true, "wait until the deployment finished")
+	timeout := cmd.Flags.Duration("timeout", 15*time.Minute, "how long to wait for the deployment")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 0); err != nil {
+			return err
+		}
+		registry := newRegistryClient(config.Registry, imageRepository())
+		current, err := registry.GetRawManifest(*productionTag)
+		if err != nil {
+			return err
+		}
+
+		var target AuditEntry
+		if *to != "" {
+			manifest, err := registry.GetRawManifest(*to)
+			if err != nil {
+				return err
+			}
+			target = AuditEntry{Tag: *to, Digest: manifest.Digest}
+		} else {
+			entries, err := loadAudit()
+			if err != nil {
+				return err
+			}
+			previous := lastKnownGood(entries, *productionTag, current.Digest)
+			if previous == nil {
+				return fmt.Errorf("no earlier promotion of %s is recorded, pass --to <tag>", *productionTag)
+			}
+			target = *previous
+		}
+		if target.Digest == current.Digest {
+			fmt.Printf("✅ %s already points to %s\n", *productionTag, target.Digest)
+			return nil
+		}
+
+		// Deploy by digest if the tag has been moved since it was promoted
+		image := registry.Reference(target.Tag)
+		if manifest, err := registry.GetRawManifest(target.Tag); err != nil || manifest.Digest != target.Digest {
+			image = registry.Reference(target.Digest)
+		}
+
+		railway, err := newRailwayClient()
+		if err != nil {
+			return err
+		}
+		railwayTarget, err := resolveRailwayTarget(railway, *service)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("\n⏪ Rollback of %s\n", railwayTarget.ServiceName)
+		fmt.Printf("Current:  %s → %s\n", registry.Reference(*productionTag), current.Digest)
+		fmt.Printf("Rollback: %s (%s)\n", image, target.Digest)
+		if !target.At.IsZero() {
+			fmt.Printf("Promoted: %s by %s\n", target.At.Local().Format("2006-01-02 15:04"), target.By)
+		}
+		ok, err := confirm(fmt.Sprintf("Move %s back to %s and redeploy %s?", *productionTag, target.Tag, railwayTarget.ServiceName), *yes)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("rollback cancelled")
+		}
+
+		if _, _, err := promoteTag(registry, target.Digest, *productionTag, false); err != nil {
+			return err
+		}
+		fmt.Printf("✅ %s moved back to %s\n", *productionTag, target.Digest)
+		err = recordAudit(AuditEntry{Action: auditRollback, Tag: target.Tag, Digest: target.Digest, Target: *productionTag, Replaced: current.Digest})
+		if err != nil {
+			return err
+		}
+
+		id, err := deployRailwayImage(railway, railwayTarget, image)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Deployment: %s\n", id)
+		if !*wait {
+			return nil
+		}
+		if _, err := waitForDeployment(railway, id, *timeout, 10*time.Second); err != nil {
+			return err
+		}
+		fmt.Printf("\n✅ Rolled back to %s\n", target.Tag)
+		return nil
+	}
+	return cmd
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"cmp"
+	"flag"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// retentionDecision is what a retention policy does with a version and why
+type retentionDecision struct {
+	Version PackageVersion
+	Keep    bool
+	Reason  string
+}
+
+// applyRetention decides which versions a policy keeps. A version is kept
+// when any rule protects it; old, untagged and stale versions go.
+func applyRetention(versions []PackageVersion, policy RetentionPolicy, now time.Time) []retentionDecision {
+	newest := slices.Clone(versions)
+	slices.SortStableFunc(newest, func(a, b PackageVersion) int { return b.CreatedAt.Compare(a.CreatedAt) })
+	recent := make(map[int64]bool)
+	for _, version := range newest[:min(max(policy.KeepLast, 0), len(newest))] {
+		recent[version.ID] = true
+	}
+
+	staleTags := make(map[string]bool)
+	if policy.DeleteStalePrereleases {
+		series, _ := groupReleases(versions)
+		for _, s := range series {
+			for _, tag := range s.Tags {
+				if tag.Stale {
+					staleTags[tag.Original] = true
+				}
+			}
+		}
+	}
+
+	decisions := make([]retentionDecision, len(versions))
+	for i, version := range versions {
+		decision := retentionDecision{Version: version, Keep: true}
+		tags := version.Metadata.Container.Tags
+		// A version is stale when every one of its tags is a stale prerelease
+		stale := len(tags) > 0 && !slices.ContainsFunc(tags, func(tag string) bool { return !staleTags[tag] })
+		_, semver := highestSemver(version)
+		age := now.Sub(version.CreatedAt)
+		switch {
+		case stale:
+			decision.Keep, decision.Reason = false, message("stale prerelease")
+		case recent[version.ID]:
+			decision.Reason = fmt.Sprintf(message("one of the last %d"), policy.KeepLast)
+		case policy.KeepSemver && semver:
+			decision.Reason = message("semver tag")
+		case policy.KeepTagged && len(tags) > 0:
+			decision.Reason = message("tagged")
+		case policy.MaxAgeDays > 0 && age < time.Duration(policy.MaxAgeDays)*24*time.Hour:
+			decision.Reason = fmt.Sprintf(message("younger than %d days"), policy.MaxAgeDays)
+		default:
+			decision.Keep = false
+			if len(tags) == 0 {
+				decision.Reason = message("untagged")
+			} else {
+				decision.Reason = message("not protected")
+			}
+			if policy.MaxAgeDays > 0 {
+				decision.Reason += fmt.Sprintf(message(", %d days old"), int(age.Hours()/24))
+			}
+		}
+		decisions[i] = decision
+	}
+	return decisions
+}
+
+// versionBlobs are the blobs and child manifests a version references
+type versionBlobs struct {
+	Blobs    map[string]int64
+	Children []string
+}
+
+// resolveVersionBlobs collects the config and layer blobs of a version,
+// descending into manifest lists
+func resolveVersionBlobs(registry *RegistryClient, digest string) (*versionBlobs, error) {
+	manifest, err := registry.GetManifest(digest)
+	if err != nil {
+		return nil, err
+	}
+	result := &versionBlobs{Blobs: make(map[string]int64)}
+	add := func(manifest *Manifest) {
+		for _, desc := range append([]Descriptor{manifest.Config}, manifest.Layers...) {
+			if desc.Digest != "" {
+				result.Blobs[desc.Digest] = desc.Size
+			}
+		}
+	}
+	if !manifest.IsIndex() {
+		add(manifest)
+		return result, nil
+	}
+	for _, desc := range manifest.Manifests {
+		result.Children = append(result.Children, desc.Digest)
+		child, err := registry.GetManifest(desc.Digest)
+		if err != nil {
+			return nil, err
+		}
+		add(child)
+	}
+	return result, nil
+}
+
+// resolveAllVersionBlobs resolves the blobs of every version by ID
+func resolveAllVersionBlobs(registry *RegistryClient, versions []PackageVersion) (map[int64]*versionBlobs, error) {
+	resolved := make([]*versionBlobs, len(versions))
+	progress := startProgress("Resolving manifests", len(versions))
+	defer progress.Finish()
+	err := forEachConcurrent(versions, globalOptions.concurrency, func(i int, version PackageVersion) error {
+		defer progress.Add(1)
+		blobs, err := resolveVersionBlobs(registry, version.Name)
+		if err != nil {
+			return fmt.Errorf("%s: %w", version.Tag(), err)
+		}
+		resolved[i] = blobs
+		return nil
+	})
+	if err != nil {
+		// Without every version's layers, shared ones could be counted as freed
+		return nil, fmt.Errorf("cannot estimate without the layers of every version: %w", err)
+	}
+	blobs := make(map[int64]*versionBlobs, len(versions))
+	for i, version := range versions {
+		blobs[version.ID] = resolved[i]
+	}
+	return blobs, nil
+}
+
+// reclaimEstimate is the storage a retention plan frees
+type reclaimEstimate struct {
+	Decisions []retentionDecision
+	// Exclusive is what deleting a version alone frees, Freeable what it
+	// frees together with the other deleted versions, by version ID
+	Exclusive, Freeable map[int64]int64
+	// Freed counts blobs no kept version references, Stored all blobs once
+	Freed, Stored int64
+}
+
+// estimateReclaim counts each blob once. Deleting versions frees the blobs
+// no kept version references; a version sharing its layers with kept
+// versions frees nothing.
+func estimateReclaim(decisions []retentionDecision, blobs map[int64]*versionBlobs) *reclaimEstimate {
+	// Platform images of a kept manifest list must stay with it
+	byDigest := make(map[string]int)
+	for i, decision := range decisions {
+		byDigest[decision.Version.Name] = i
+	}
+	for i := range decisions {
+		if !decisions[i].Keep {
+			continue
+		}
+		for _, child := range blobs[decisions[i].Version.ID].Children {
+			if j, ok := byDigest[child]; ok && !decisions[j].Keep {
+				decisions[j].Keep, decisions[j].Reason = true, fmt.Sprintf(message("platform image of %s"), decisions[i].Version.Tag())
+			}
+		}
+	}
+
+	kept := make(map[string]bool)
+	users := make(map[string]int)
+	sizes := make(map[string]int64)
+	for _, decision := range decisions {
+		for digest, size := range blobs[decision.Version.ID].Blobs {
+			sizes[digest] = size
+			if decision.Keep {
+				kept[digest] = true
+			} else {
+				users[digest]++
+			}
+		}
+	}
+	estimate := &reclaimEstimate{Decisions: decisions, Exclusive: make(map[int64]int64), Freeable: make(map[int64]int64)}
+	for digest, size := range sizes {
+		estimate.Stored += size
+		if !kept[digest] {
+			estimate.Freed += size
+		}
+	}
+	for _, decision := range decisions {
+		if decision.Keep {
+			continue
+		}
+		for digest, size := range blobs[decision.Version.ID].Blobs {
+			if kept[digest] {
+				continue
+			}
+			estimate.Freeable[decision.Version.ID] += size
+			if users[digest] == 1 {
+				estimate.Exclusive[decision.Version.ID] += size
+			}
+		}
+	}
+	return estimate
+}
+
+func describeRetention(policy RetentionPolicy) string {
+	parts := []string{fmt.Sprintf(message("keep the last %d"), policy.KeepLast)}
+	if policy.MaxAgeDays > 0 {
+		parts = append(parts, fmt.Sprintf(message("the last %d days"), policy.MaxAgeDays))
+	}
+	if policy.KeepSemver {
+		parts = append(parts, message("semver tags"))
+	}
+	if policy.KeepTagged {
+		parts = append(parts, message("tagged versions"))
+	}
+	description := strings.Join(parts, ", ")
+	if policy.DeleteStalePrereleases {
+		description += message("; delete stale prereleases")
+	}
+	return description
+}
+
+// retentionFlags adds flags overriding each rule of the configured
+// retention policy and returns the policy they make
+func retentionFlags(cmd *Command) func() RetentionPolicy {
+	defaults := defaultConfig().Retention
+	keepLast := cmd.Flags.Int("keep-last", defaults.KeepLast, "keep this many newest versions; overrides retention.keep_last")
+	maxAge := cmd.Flags.Int("max-age-days", defaults.MaxAgeDays, "keep versions younger than this, 0 for no age limit; overrides retention.max_age_days")
+	keepSemver := cmd.Flags.Bool("keep-semver", defaults.KeepSemver, "keep versions with a semver tag; overrides retention.keep_semver")
+	keepTagged := cmd.Flags.Bool("keep-tagged", defaults.KeepTagged, "keep versions with any tag; overrides retention.keep_tagged")
+	deleteStale := cmd.Flags.Bool("delete-stale-prereleases", defaults.DeleteStalePrereleases, "delete prereleases whose final release exists; overrides retention.delete_stale_prereleases")
+	return func() RetentionPolicy {
+		// Flags given on the command line override the configured policy
+		policy := config.Retention
+		cmd.Flags.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "keep-last":
+				policy.KeepLast = *keepLast
+			case "max-age-days":
+				policy.MaxAgeDays = *maxAge
+			case "keep-semver":
+				policy.KeepSemver = *keepSemver
+			case "keep-tagged":
+				policy.KeepTagged = *keepTagged
+			case "delete-stale-prereleases":
+				policy.DeleteStalePrereleases = *deleteStale
+			}
+		})
+		return policy
+	}
+}
+
+func newPackagesReclaimCommand() *Command {
+	cmd := newCommand("reclaim", "", "Estimate the storage a retention plan would free, counting layers shared between versions once, before deleting anything.")
+	retention := retentionFlags(cmd)
+	all := cmd.Flags.Bool("all", false, "list kept versions too")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 0); err != nil {
+			return err
+		}
+		policy := retention()
+
+		github, err := newGitHubClient()
+		if err != nil {
+			return err
+		}
+		versions, err := listPackageVersions(github)
+		if err != nil {
+			return err
+		}
+		blobs, err := resolveAllVersionBlobs(newRegistryClient(config.Registry, imageRepository()), versions)
+		if err != nil {
+			return err
+		}
+
+		estimate := estimateReclaim(applyRetention(versions, policy, time.Now()), blobs)
+		var deleted []retentionDecision
+		for _, decision := range estimate.Decisions {
+			if !decision.Keep {
+				deleted = append(deleted, decision)
+			}
+		}
+		slices.SortStableFunc(deleted, func(a, b retentionDecision) int {
+			return cmp.Compare(estimate.Exclusive[b.Version.ID], estimate.Exclusive[a.Version.ID])
+		})
+
+		fmt.Printf("\n🧮 Retention plan: %s\n", describeRetention(policy))
+		fmt.Printf("%d of %d versions would be deleted\n", len(deleted), len(versions))
+		if *all {
+			fmt.Println("\nKept:")
+			for _, decision := range estimate.Decisions {
+				if decision.Keep {
+					fmt.Printf("  ✅ %-24s %s  %s\n", truncate(decision.Version.Tag(), 24), decision.Version.CreatedAt.Format(time.DateOnly), decision.Reason)
+				}
+			}
+		}
+		if len(deleted) > 0 {
+			fmt.Println("\nDeleted:")
+		}
+		nothing := 0
+		for _, decision := range deleted {
+			id := decision.Version.ID
+			frees := "frees " + formatBytes(estimate.Exclusive[id])
+			switch {
+			case estimate.Freeable[id] == 0:
+				frees = "frees nothing"
+				nothing++
+			case estimate.Exclusive[id] < estimate.Freeable[id]:
+				frees += fmt.Sprintf(" (+%s shared)", formatBytes(estimate.Freeable[id]-estimate.Exclusive[id]))
+			}
+			fmt.Printf("  🗑️  %-24s %s  %-32s %s (ID: %d)\n", truncate(decision.Version.Tag(), 24), decision.Version.CreatedAt.Format(time.DateOnly),
+				frees, decision.Reason, decision.Version.ID)
+		}
+
+		fmt.Printf("\nStored: %s in unique blobs\n", formatBytes(estimate.Stored))
+		fmt.Printf("Reclaimed: %s (%.1f%%)\n", formatBytes(estimate.Freed), share(estimate.Freed, estimate.Stored))
+		var exclusive int64
+		for _, size := range estimate.Exclusive {
+			exclusive += size
+		}
+		if shared := estimate.Freed - exclusive; shared > 0 {
+			fmt.Printf("  %s of it is shared between deleted versions and only freed when all of them go\n", formatBytes(shared))
+		}
+		if nothing > 0 {
+			fmt.Printf("⚠️  %d version(s) free nothing: every layer is also in a kept version\n", nothing)
+		}
+		return nil
+	}
+	return cmd
+}
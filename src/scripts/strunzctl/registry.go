@@ -0,0 +1,122 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/longevitycoach/StrunzKnowledge/src/scripts/strunzctl/pkg/ghcr"
+)
+
+// The registry client lives in pkg/ghcr so other tools can import it; the
+// aliases keep the names the commands use
+type (
+	RegistryClient = ghcr.Client
+	RegistryError  = ghcr.Error
+	Platform       = ghcr.Platform
+	Descriptor     = ghcr.Descriptor
+	Manifest       = ghcr.Manifest
+	RawManifest    = ghcr.RawManifest
+	ImageConfig    = ghcr.ImageConfig
+)
+
+// Manifest media types accepted from the registry
+const (
+	mediaTypeOCIIndex    = ghcr.MediaTypeOCIIndex
+	mediaTypeOCIManifest = ghcr.MediaTypeOCIManifest
+)
+
+// isNotFound reports whether err is a registry or GitHub API 404
+func isNotFound(err error) bool {
+	var registryErr *RegistryError
+	var githubErr *GitHubError
+	return errors.As(err, &registryErr) && registryErr.StatusCode == http.StatusNotFound ||
+		errors.As(err, &githubErr) && githubErr.StatusCode == http.StatusNotFound
+}
+
+// newRegistryClient returns a client with the credentials for the host,
+// the retry policy of --max-retries and the response cache
+func newRegistryClient(host, repository string) *RegistryClient {
+	client := ghcr.New(host, repository)
+	client.Credentials = githubRegistryCredentials
+	client.Retry = doWithRetry
+	client.Cache = registryCache{}
+	client.Offline = globalOptions.offline
+	if host == "docker.io" {
+		client.Credentials = dockerHubCredentials
+	}
+	if oidcApplies(host) {
+		client.Credentials = oidcRegistryCredentials(client.Credentials)
+	}
+	return client
+}
+
+// registryCache keeps registry responses in the response cache. They are
+// keyed without a credential since tokens only gate access to them.
+type registryCache struct{}
+
+func (registryCache) Load(url string) ([]byte, http.Header, bool) {
+	cached := loadCachedResponse(url, "")
+	if cached == nil {
+		return nil, nil, false
+	}
+	return cached.Body, cached.HTTPHeader(), true
+}
+
+func (registryCache) Store(url string, header http.Header, body []byte) {
+	storeCachedResponse(url, "", header, body)
+}
+
+// parseImageReference splits "registry/org/repo[:tag]" into its parts
+func parseImageReference(ref string) (host, repository, tag string, err error) {
+	host, repository, tag, err = ghcr.ParseReference(ref)
+	if err != nil {
+		return "", "", "", fmt.Errorf("%w: %w", errUsage, err)
+	}
+	return host, repository, tag, nil
+}
+
+// githubRegistryCredentials authenticates against GHCR with a GitHub token
+func githubRegistryCredentials() (string, string, error) {
+	token, err := githubToken()
+	return "token", token, err
+}
+
+// dockerHubCredentials reads Docker Hub access tokens from the environment
+func dockerHubCredentials() (string, string, error) {
+	username, token := os.Getenv("DOCKERHUB_USERNAME"), os.Getenv("DOCKERHUB_TOKEN")
+	if username == "" || token == "" {
+		return "", "", errors.New("DOCKERHUB_USERNAME and DOCKERHUB_TOKEN are not set")
+	}
+	return username, token, nil
+}
+
+// githubToken returns the configured token, then an installation token of
+// the configured GitHub App, then GITHUB_TOKEN, then the token stored by
+// `login`, falling back to the gh CLI's stored credentials
+func githubToken() (string, error) {
+	if config.Token != "" {
+		return config.Token, nil
+	}
+	if githubAppConfigured() {
+		return appTokens.Token()
+	}
+	// As a gh extension, use the account gh is logged in with; gh auth
+	// token still honors GH_TOKEN and GITHUB_TOKEN
+	if !runningAsGHExtension() {
+		if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+			return token, nil
+		}
+		if token := loadCredential(githubCredentialServer); token != "" {
+			return token, nil
+		}
+	}
+
+	output, err := newProcess("gh", "auth", "token").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get token from gh CLI: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// GitHubRelease is the subset of the releases API used here
+type GitHubRelease struct {
+	ID         int64  `json:"id"`
+	TagName    string `json:"tag_name"`
+	Name       string `json:"name"`
+	Body       string `json:"body"`
+	HTMLURL    string `json:"html_url"`
+	Draft      bool   `json:"draft"`
+	Prerelease bool   `json:"prerelease"`
+	UploadURL  string `json:"upload_url"`
+	// PublishedAt is zero for drafts
+	PublishedAt time.Time `json:"published_at"`
+	Assets      []struct {
+		ID   int64  `json:"id"`
+		Name string `json:"name"`
+		// URL downloads the asset with Accept: application/octet-stream
+		URL string `json:"url"`
+	} `json:"assets"`
+}
+
+func newReleaseCommand() *Command {
+	return newGroup("release", "Tag, publish and verify releases.",
+		newReleaseCreateCommand(),
+		newReleaseChangelogCommand(),
+		newReleaseBumpCommand(),
+		newReleaseVerifyCommand(),
+		newReleasePublishNotesCommand(),
+		newReleaseGateCommand(),
+		newReleaseCloseIssuesCommand(),
+	)
+}
+
+func newReleaseCreateCommand() *Command {
+	cmd := newCommand("create", "<vX.Y.Z>", "Tag HEAD, create the GitHub release and wait for the published image.")
+	notesFile := cmd.Flags.String("notes-file", "", "Markdown release notes (default: notes generated by GitHub)")
+	changelog := cmd.Flags.Bool("changelog", false, "use notes generated from conventional commits since the previous tag")
+	remote := cmd.Flags.String("remote", "origin", "git remote to push the tag to")
+	workflow := cmd.Flags.String("workflow", defaultWorkflow, "image build workflow to follow before checking the registry (empty: skip)")
+	timeout := cmd.Flags.Duration("timeout", 30*time.Minute, "how long to wait for the image to be published")
+	interval := cmd.Flags.Duration("interval", 20*time.Second, "time between registry checks while waiting")
+	dryRun := cmd.Flags.Bool("dry-run", false, "run the preflight checks and show the plan without changing anything")
+	notify := cmd.Flags.Bool("notify-webhook", false, "post the outcome to webhooks.notify and the alerts sinks")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 1); err != nil {
+			return err
+		}
+		version, ok := parseSemver(args[0])
+		if !ok {
+			return fmt.Errorf("%w: %q is not a semantic version", errUsage, args[0])
+		}
+		tag := "v" + version.String()
+		// docker/metadata-action's {{version}} pattern drops the "v" prefix
+		imageTag := version.String()
+
+		var notes string
+		var err error
+		if *notesFile != "" {
+			data, err := os.ReadFile(*notesFile)
+			if err != nil {
+				return fmt.Errorf("failed to read release notes: %w", err)
+			}
+			notes = string(data)
+		} else if *changelog {
+			if notes, err = generateChangelog("", "HEAD", tag); err != nil {
+				return err
+			}
+		}
+		notifier, err := newNotifier(*notify)
+		if err != nil {
+			return err
+		}
+		registry := newRegistryClient(config.Registry, imageRepository())
+
+		fmt.Printf("\n🚀 Releasing %s of %s\n", tag, config.Repo)
+		commit, tagged, err := releasePreflight(tag)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Commit: %s\n", commit)
+		fmt.Printf("Image: %s\n", registry.Reference(imageTag))
+		if *dryRun {
+			fmt.Println("\nPlan (dry run, nothing changed):")
+			fmt.Printf("  1. git tag -a %s && git push %s %s\n", tag, *remote, tag)
+			fmt.Printf("  2. create GitHub release %s (prerelease: %t)\n", tag, version.Prerelease != "")
+			if *workflow != "" {
+				fmt.Printf("  3. wait up to %s for %s and %s\n", *timeout, *workflow, registry.Reference(imageTag))
+			} else {
+				fmt.Printf("  3. wait up to %s for %s\n", *timeout, registry.Reference(imageTag))
+			}
+			fmt.Printf("  4. verify platforms %s\n", defaultPlatforms)
+			return nil
+		}
+
+		github, err := newGitHubClient()
+		if err != nil {
+			return err
+		}
+		err = createRelease(github, registry, releasePlan{
+			tag: tag, imageTag: imageTag, remote: *remote, notes: notes, workflow: *workflow,
+			prerelease: version.Prerelease != "", tagged: tagged,
+			timeout: *timeout, interval: *interval,
+		})
+		if err != nil {
+			notifier.alert(severityCritical, "release "+tag, "Release failed", fmt.Sprintf("`%s`: %v", tag, err))
+			return err
+		}
+		notifier.resolve("release "+tag, "Release published", fmt.Sprintf("`%s` is available as `%s`", tag, registry.Reference(imageTag)))
+		return nil
+	}
+	return cmd
+}
+
+// releasePlan holds the inputs of a release run
+type releasePlan struct {
+	tag, imageTag, remote, notes string
+	// workflow is followed so a failed build ends the wait early
+	workflow   string
+	prerelease bool
+	// tagged is set when the tag already exists at HEAD from an earlier run
+	tagged            bool
+	timeout, interval time.Duration
+}
+
+// releasePreflight checks the working tree and returns the HEAD commit and
+// whether the tag already points to it, so an interrupted release resumes
+func releasePreflight(tag string) (commit string, tagged bool, err error) {
+	status, err := runGit("status", "--porcelain", "--untracked-files=no")
+	if err != nil {
+		return "", false, err
+	}
+	if status != "" {
+		return "", false, fmt.Errorf("working tree has uncommitted changes:\n%s", status)
+	}
+	commit, err = runGit("rev-parse", "HEAD")
+	if err != nil {
+		return "", false, err
+	}
+
+	if !gitTagExists(tag) {
+		return commit, false, nil
+	}
+	tagCommit, err := runGit("rev-list", "-n", "1", tag)
+	if err != nil {
+		return "", false, err
+	}
+	if tagCommit != commit {
+		return "", false, fmt.Errorf("tag %s already exists at %s, not HEAD", tag, tagCommit)
+	}
+	return commit, true, nil
+}
+
+// createRelease runs the release steps. Each step is skipped when an
+// earlier run already completed it.
+func createRelease(github *GitHubClient, registry *RegistryClient, plan releasePlan) error {
+	fmt.Println("\n🏷️  Tagging")
+	if plan.tagged {
+		fmt.Printf("Tag %s already exists at HEAD\n", plan.tag)
+	} else if _, err := runGit("tag", "-a", plan.tag, "-m", "Release "+plan.tag); err != nil {
+		return err
+	}
+	if _, err := runGit("push", plan.remote, "refs/tags/"+plan.tag); err != nil {
+		return err
+	}
+	fmt.Printf("✅ Pushed %s to %s\n", plan.tag, plan.remote)
+
+	fmt.Println("\n📝 GitHub release")
+	release, err := findRelease(github, plan.tag)
+	if err != nil {
+		return err
+	}
+	if release != nil {
+		fmt.Printf("Release already exists: %s\n", release.HTMLURL)
+	} else {
+		request := map[string]any{
+			"tag_name":               plan.tag,
+			"name":                   plan.tag,
+			"body":                   plan.notes,
+			"prerelease":             plan.prerelease,
+			"generate_release_notes": plan.notes == "",
+		}
+		release = &GitHubRelease{}
+		if err := github.Send(http.MethodPost, fmt.Sprintf("/repos/%s/releases", config.Repo), request, release); err != nil {
+			return fmt.Errorf("failed to create release: %w", err)
+		}
+		fmt.Printf("✅ Created %s\n", release.HTMLURL)
+	}
+
+	if plan.workflow != "" {
+		if _, err := waitForWorkflow(github, plan.workflow, plan.tag, plan.timeout, plan.interval); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("\n⏳ Waiting for %s\n", registry.Reference(plan.imageTag))
+	digest, err := waitForImage(registry, plan.imageTag, plan.timeout, plan.interval)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("✅ Published %s\n", digest)
+
+	fmt.Println("\n🔎 Verifying image")
+	manifest, err := registry.GetManifest(plan.imageTag)
+	if err != nil {
+		return err
+	}
+	images, err := resolvePlatformImages(registry, manifest)
+	if err != nil {
+		return err
+	}
+	if missing := missingPlatforms(images, splitList(defaultPlatforms)); len(missing) > 0 {
+		return fmt.Errorf("%w: %s is missing platform(s) %s", errPolicy, plan.imageTag, strings.Join(missing, ", "))
+	}
+	fmt.Printf("✅ %s provides %s\n", plan.imageTag, defaultPlatforms)
+	fmt.Printf("\n🎉 Released %s\n", plan.tag)
+	return nil
+}
+
+// findRelease returns the release for a tag, or nil if there is none
+func findRelease(github *GitHubClient, tag string) (*GitHubRelease, error) {
+	var release GitHubRelease
+	err := github.Get(fmt.Sprintf("/repos/%s/releases/tags/%s", config.Repo, tag), &release)
+	if isNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &release, nil
+}
+
+// waitForImage polls the registry until the tag exists and returns its digest
+func waitForImage(registry *RegistryClient, tag string, timeout, interval time.Duration) (string, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		manifest, err := registry.GetRawManifest(tag)
+		if err == nil {
+			return manifest.Digest, nil
+		}
+		if !isNotFound(err) {
+			return "", err
+		}
+		if time.Now().Add(interval).After(deadline) {
+			return "", fmt.Errorf("%s was not published within %s", registry.Reference(tag), timeout)
+		}
+		fmt.Printf("  not published yet, checking again in %s\n", interval)
+		if err := sleepContext(interval); err != nil {
+			return "", err
+		}
+	}
+}
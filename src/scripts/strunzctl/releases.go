@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ReleaseTag is a semver tag and the package version it points to
+type ReleaseTag struct {
+	Semver
+	Version PackageVersion
+	// Stale marks a prerelease whose final release exists
+	Stale bool
+}
+
+// ReleaseSeries groups the tags of one minor release line, newest first
+type ReleaseSeries struct {
+	Major, Minor int
+	Tags         []ReleaseTag
+}
+
+func (s ReleaseSeries) String() string {
+	return fmt.Sprintf("%d.%d.x", s.Major, s.Minor)
+}
+
+func newPackagesReleasesCommand() *Command {
+	cmd := newCommand("releases", "", "Group semver tags by minor release and flag stale prereleases.")
+	staleOnly := cmd.Flags.Bool("stale", false, "only list prereleases whose final release exists")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 0); err != nil {
+			return err
+		}
+		github, err := newGitHubClient()
+		if err != nil {
+			return err
+		}
+		versions, err := listPackageVersions(github)
+		if err != nil {
+			return err
+		}
+
+		series, other := groupReleases(versions)
+		if !*staleOnly {
+			printReleaseSeries(series, other)
+		}
+		printStalePrereleases(series)
+		return nil
+	}
+	return cmd
+}
+
+// groupReleases collects semver tags into minor release series, newest
+// first, and returns the number of non-semver tags
+func groupReleases(versions []PackageVersion) ([]ReleaseSeries, int) {
+	var tags []ReleaseTag
+	released := make(map[[3]int]bool)
+	other := 0
+	for _, version := range versions {
+		for _, tag := range version.Metadata.Container.Tags {
+			semver, ok := parseSemver(tag)
+			if !ok {
+				other++
+				continue
+			}
+			tags = append(tags, ReleaseTag{Semver: semver, Version: version})
+			if semver.Prerelease == "" {
+				released[[3]int{semver.Major, semver.Minor, semver.Patch}] = true
+			}
+		}
+	}
+
+	slices.SortFunc(tags, func(a, b ReleaseTag) int { return b.Compare(a.Semver) })
+	var series []ReleaseSeries
+	for _, tag := range tags {
+		tag.Stale = tag.Prerelease != "" && released[[3]int{tag.Major, tag.Minor, tag.Patch}]
+		if n := len(series); n == 0 || series[n-1].Major != tag.Major || series[n-1].Minor != tag.Minor {
+			series = append(series, ReleaseSeries{Major: tag.Major, Minor: tag.Minor})
+		}
+		series[len(series)-1].Tags = append(series[len(series)-1].Tags, tag)
+	}
+	return series, other
+}
+
+func printReleaseSeries(series []ReleaseSeries, other int) {
+	printf("\n📦 Release Series:\n")
+	if len(series) == 0 {
+		printf("No semver tags found\n")
+	}
+	for _, s := range series {
+		releases := 0
+		for _, tag := range s.Tags {
+			if tag.Prerelease == "" {
+				releases++
+			}
+		}
+		printf("\n%s  (%d releases, %d prereleases)\n", s, releases, len(s.Tags)-releases)
+		for _, tag := range s.Tags {
+			note := ""
+			if tag.Stale {
+				note = message("  ⚠️  stale")
+			}
+			fmt.Printf("  %-16s %s  %s%s\n", tag.Original, shortDigest(tag.Version.Name), tag.Version.CreatedAt.Format(time.DateOnly), note)
+		}
+	}
+	if other > 0 {
+		printf("\n(%d non-semver tags such as latest or sha-* not shown)\n", other)
+	}
+}
+
+// printStalePrereleases lists cleanup candidates. A version is only safe to
+// delete when every one of its tags is a stale prerelease; otherwise the
+// prerelease tag shares the image with a tag that must be kept.
+func printStalePrereleases(series []ReleaseSeries) {
+	var stale []ReleaseTag
+	staleTags := make(map[string]bool)
+	for _, s := range series {
+		for _, tag := range s.Tags {
+			if tag.Stale {
+				stale = append(stale, tag)
+				staleTags[tag.Original] = true
+			}
+		}
+	}
+
+	printf("\n🧹 Stale Prereleases:\n")
+	if len(stale) == 0 {
+		printf("✅ No prerelease tags with an existing final release\n")
+		return
+	}
+	for _, tag := range stale {
+		final := fmt.Sprintf("%d.%d.%d", tag.Major, tag.Minor, tag.Patch)
+		var shared []string
+		for _, other := range tag.Version.Metadata.Container.Tags {
+			if !staleTags[other] {
+				shared = append(shared, other)
+			}
+		}
+		if len(shared) > 0 {
+			printf("  - %s (ID: %d) %s released; image also tagged %s, untag only\n",
+				tag.Original, tag.Version.ID, final, strings.Join(shared, ", "))
+			continue
+		}
+		printf("  - %s (ID: %d) %s released; version can be deleted\n", tag.Original, tag.Version.ID, final)
+	}
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// gitRef is a branch or tag with the commit it points at
+type gitRef struct {
+	Name   string `json:"name"`
+	Commit struct {
+		SHA string `json:"sha"`
+	} `json:"commit"`
+	Protected bool `json:"protected"`
+}
+
+// staleRef is a branch or tag clean-branches would delete
+type staleRef struct {
+	Kind   string // "heads" or "tags"
+	Name   string
+	SHA    string
+	Age    time.Duration
+	Reason string
+}
+
+// protectedRef reports whether a name matches one of the glob patterns
+func protectedRef(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func listRefs(github *GitHubClient, kind string) ([]gitRef, error) {
+	var refs []gitRef
+	err := github.GetPages(fmt.Sprintf("/repos/%s/%s", config.Repo, kind), func(body []byte) error {
+		var page []gitRef
+		if err := json.Unmarshal(body, &page); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", kind, err)
+		}
+		refs = append(refs, page...)
+		return nil
+	})
+	return refs, err
+}
+
+// commitAge is how long ago a commit was made
+func commitAge(github *GitHubClient, sha string) (time.Duration, error) {
+	var commit struct {
+		Commit struct {
+			Committer struct {
+				Date time.Time `json:"date"`
+			} `json:"committer"`
+		} `json:"commit"`
+	}
+	if err := github.Get(fmt.Sprintf("/repos/%s/commits/%s", config.Repo, sha), &commit); err != nil {
+		return 0, err
+	}
+	return time.Since(commit.Commit.Committer.Date), nil
+}
+
+// branchState tells whether a branch is merged into base, directly or by
+// a squash or rebase merge of its pull request, or still has an open one
+func branchState(github *GitHubClient, base string, branch gitRef) (merged bool, openPR int, err error) {
+	var compare struct {
+		AheadBy int `json:"ahead_by"`
+	}
+	target := fmt.Sprintf("/repos/%s/compare/%s...%s", config.Repo, url.PathEscape(base), url.PathEscape(branch.Name))
+	if err := github.Get(target, &compare); err != nil {
+		return false, 0, err
+	}
+	owner, _, _ := strings.Cut(config.Repo, "/")
+	query := url.Values{"state": {"all"}, "head": {owner + ":" + branch.Name}}
+	var pulls []struct {
+		Number   int        `json:"number"`
+		State    string     `json:"state"`
+		MergedAt *time.Time `json:"merged_at"`
+		Head     struct {
+			SHA string `json:"sha"`
+		} `json:"head"`
+	}
+	if err := github.Get(fmt.Sprintf("/repos/%s/pulls?%s", config.Repo, query.Encode()), &pulls); err != nil {
+		return false, 0, err
+	}
+	merged = compare.AheadBy == 0
+	for _, pull := range pulls {
+		if pull.State == "open" {
+			openPR = pull.Number
+		}
+		// Commits pushed after the merge make the branch live again
+		if pull.MergedAt != nil && pull.Head.SHA == branch.Commit.SHA {
+			merged = true
+		}
+	}
+	return merged, openPR, nil
+}
+
+func newRepoCommand() *Command {
+	return newGroup("repo", "Keep the GitHub repository tidy.",
+		newRepoCleanBranchesCommand(),
+	)
+}
+
+func newRepoCleanBranchesCommand() *Command {
+	cmd := newCommand("clean-branches", "", "Delete merged or abandoned branches and orphaned prerelease tags through the GitHub API.")
+	merged := cmd.Flags.Bool("merged", false, "only delete branches merged into the default branch, not abandoned ones")
+	olderThan := cmd.Flags.String("older-than", "90d", "minimum age of the last commit (e.g. 90d, 12w)")
+	protect := cmd.Flags.String("protect", "main,release/*", "comma-separated branch and tag globs never deleted, besides the default and protected branches")
+	tags := cmd.Flags.Bool("tags", true, "also delete prerelease tags without a GitHub release whose final version is tagged or that are older than --older-than")
+	dryRun := cmd.Flags.Bool("dry-run", false, "list what would be deleted without deleting")
+	yes := cmd.Flags.Bool("yes", false, "do not ask for confirmation")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 0); err != nil {
+			return err
+		}
+		age, err := parseAge(*olderThan)
+		if err != nil {
+			return fmt.Errorf("%w: --older-than: %v", errUsage, err)
+		}
+		patterns := splitList(*protect)
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("%w: --protect %q: %v", errUsage, pattern, err)
+			}
+		}
+		github, err := newGitHubClient()
+		if err != nil {
+			return err
+		}
+		var repository struct {
+			DefaultBranch string `json:"default_branch"`
+		}
+		if err := github.Get("/repos/"+config.Repo, &repository); err != nil {
+			return err
+		}
+
+		branches, err := listRefs(github, "branches")
+		if err != nil {
+			return err
+		}
+		var candidates []gitRef
+		for _, branch := range branches {
+			if branch.Name != repository.DefaultBranch && !branch.Protected && !protectedRef(branch.Name, patterns) {
+				candidates = append(candidates, branch)
+			}
+		}
+		found := make([]*staleRef, len(candidates))
+		err = forEachConcurrent(candidates, globalOptions.concurrency, func(i int, branch gitRef) error {
+			branchAge, err := commitAge(github, branch.Commit.SHA)
+			if err != nil || branchAge < age {
+				return err
+			}
+			isMerged, openPR, err := branchState(github, repository.DefaultBranch, branch)
+			if err != nil {
+				return fmt.Errorf("failed to check branch %s: %w", branch.Name, err)
+			}
+			switch {
+			case openPR != 0:
+				slog.Debug("Branch has an open pull request", "branch", branch.Name, "pull", openPR)
+			case isMerged:
+				found[i] = &staleRef{Kind: "heads", Name: branch.Name, SHA: branch.Commit.SHA, Age: branchAge, Reason: "merged"}
+			case !*merged:
+				found[i] = &staleRef{Kind: "heads", Name: branch.Name, SHA: branch.Commit.SHA, Age: branchAge, Reason: "abandoned"}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if *tags {
+			orphans, err := orphanedPrereleaseTags(github, patterns, age)
+			if err != nil {
+				return err
+			}
+			found = append(found, orphans...)
+		}
+		var stale []*staleRef
+		for _, ref := range found {
+			if ref != nil {
+				stale = append(stale, ref)
+			}
+		}
+		slices.SortFunc(stale, func(a, b *staleRef) int {
+			return cmp.Or(strings.Compare(a.Kind, b.Kind), strings.Compare(a.Name, b.Name))
+		})
+
+		kept := patterns
+		if !slices.Contains(kept, repository.DefaultBranch) {
+			kept = append([]string{repository.DefaultBranch}, kept...)
+		}
+		fmt.Printf("\n🌿 Refs of %s to clean (last commit older than %s, protected: %s): %d\n",
+			config.Repo, *olderThan, strings.Join(kept, ", "), len(stale))
+		for _, ref := range stale {
+			kind := "branch"
+			if ref.Kind == "tags" {
+				kind = "tag"
+			}
+			fmt.Printf("  %-6s %-40s %-22s %6s  %s\n", kind, ref.Name, ref.Reason, formatAge(ref.Age), ref.SHA[:min(7, len(ref.SHA))])
+		}
+		if len(stale) == 0 {
+			fmt.Println("\n✅ Nothing to delete")
+			return nil
+		}
+		if *dryRun {
+			fmt.Println("\nDry run: nothing deleted")
+			return nil
+		}
+		ok, err := confirm(fmt.Sprintf("Delete %d ref(s) from %s?", len(stale), config.Repo), *yes)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("clean-up cancelled")
+		}
+
+		for _, ref := range stale {
+			if err := github.Delete(fmt.Sprintf("/repos/%s/git/refs/%s/%s", config.Repo, ref.Kind, ref.Name)); err != nil {
+				return fmt.Errorf("failed to delete %s: %w", ref.Name, err)
+			}
+			// The commit stays reachable for a while; this brings the ref back
+			fmt.Printf("🗑️  %s (restore: git push origin %s:refs/%s/%s)\n", ref.Name, ref.SHA, ref.Kind, ref.Name)
+		}
+		fmt.Printf("\n✅ Deleted %d ref(s)\n", len(stale))
+		return nil
+	}
+	return cmd
+}
+
+// orphanedPrereleaseTags finds prerelease tags without a published GitHub
+// release whose final version is tagged, or that are older than age
+func orphanedPrereleaseTags(github *GitHubClient, patterns []string, age time.Duration) ([]*staleRef, error) {
+	tags, err := listRefs(github, "tags")
+	if err != nil {
+		return nil, err
+	}
+	released := make(map[string]bool)
+	err = github.GetPages(fmt.Sprintf("/repos/%s/releases", config.Repo), func(body []byte) error {
+		var page []GitHubRelease
+		if err := json.Unmarshal(body, &page); err != nil {
+			return fmt.Errorf("failed to parse releases: %w", err)
+		}
+		for _, release := range page {
+			released[release.TagName] = released[release.TagName] || !release.Draft
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	finals := make(map[string]bool)
+	for _, tag := range tags {
+		if version, ok := parseSemver(tag.Name); ok && version.Prerelease == "" {
+			finals[version.String()] = true
+		}
+	}
+
+	var orphans []*staleRef
+	for _, tag := range tags {
+		version, ok := parseSemver(tag.Name)
+		if !ok || version.Prerelease == "" || released[tag.Name] || protectedRef(tag.Name, patterns) {
+			continue
+		}
+		tagAge, err := commitAge(github, tag.Commit.SHA)
+		if err != nil {
+			return nil, err
+		}
+		final := version
+		final.Prerelease = ""
+		switch {
+		case finals[final.String()]:
+			orphans = append(orphans, &staleRef{Kind: "tags", Name: tag.Name, SHA: tag.Commit.SHA, Age: tagAge, Reason: "superseded by v" + final.String()})
+		case tagAge >= age:
+			orphans = append(orphans, &staleRef{Kind: "tags", Name: tag.Name, SHA: tag.Commit.SHA, Age: tagAge, Reason: "prerelease, no release"})
+		}
+	}
+	return orphans, nil
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// reportReleases is how many of the most recent releases the report lists
+const reportReleases = 20
+
+func newPackagesReportCommand() *Command {
+	cmd := newCommand("report", "", "Export an audit report of all versions as Markdown, CSV or a plugin format.")
+	format := cmd.Flags.String("format", "markdown", "report format: markdown, csv or the name of a strunzctl-format-<name> plugin")
+	output := cmd.Flags.String("output", "", "write the report to a file instead of stdout")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 0); err != nil {
+			return err
+		}
+		var write func(io.Writer, []VersionDetails, *releaseActivity) error
+		switch *format {
+		case "markdown", "md":
+			write = writeMarkdownReport
+		case "csv":
+			write = func(w io.Writer, details []VersionDetails, _ *releaseActivity) error {
+				return writeCSVReport(w, details)
+			}
+		default:
+			path, err := findPlugin(pluginFormat, *format)
+			if err != nil {
+				return fmt.Errorf("%w: unsupported report format %q: %v", errUsage, *format, err)
+			}
+			write = pluginReportWriter(path)
+		}
+
+		github, err := newGitHubClient()
+		if err != nil {
+			return err
+		}
+		versions, err := listPackageVersions(github)
+		if err != nil {
+			return err
+		}
+		slog.Info("Collecting version details", "versions", len(versions))
+		details, detailErr := fetchVersionDetails(newRegistryClient(config.Registry, imageRepository()), versions, "")
+		// CSV rows are versions; Markdown and plugins also get the releases
+		var activity *releaseActivity
+		if *format != "csv" {
+			fetched, err := fetchReleaseActivity(github, reportReleases)
+			if err != nil {
+				detailErr = errors.Join(detailErr, fmt.Errorf("failed to fetch releases: %w", err))
+			} else {
+				activity = &fetched
+			}
+		}
+
+		out := os.Stdout
+		if *output != "" {
+			file, err := os.Create(*output)
+			if err != nil {
+				return fmt.Errorf("failed to create report: %w", err)
+			}
+			defer file.Close()
+			out = file
+		}
+		if err := write(out, details, activity); err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
+		}
+		if *output != "" {
+			slog.Info("Report written", "path", *output, "format", *format)
+		}
+
+		if detailErr != nil {
+			return fmt.Errorf("incomplete report: %w", detailErr)
+		}
+		return nil
+	}
+	return cmd
+}
+
+// reportRow flattens a version for tabular output
+type reportRow struct {
+	Tags, Digest, Created, Size, Scan, Scanned string
+}
+
+func newReportRow(detail VersionDetails) reportRow {
+	version := detail.Version
+	row := reportRow{
+		Tags:    strings.Join(version.Metadata.Container.Tags, ", "),
+		Digest:  version.Name,
+		Created: version.CreatedAt.UTC().Format(time.RFC3339),
+		Size:    "unknown",
+		Scan:    "not scanned",
+	}
+	if row.Tags == "" {
+		row.Tags = "untagged"
+	}
+	if detail.Size > 0 {
+		row.Size = formatBytes(detail.Size)
+	}
+	if detail.Scan != nil {
+		row.Scan = fmt.Sprintf("%s (%s)", detail.Scan.Summary(), detail.Scan.Scanner)
+		row.Scanned = detail.Scan.ScannedAt.UTC().Format(time.RFC3339)
+	}
+	return row
+}
+
+// writeMarkdownReport writes the summary, the versions and, unless
+// activity is nil, the recent releases with their image and workflow runs
+func writeMarkdownReport(w io.Writer, details []VersionDetails, activity *releaseActivity) error {
+	var totalSize int64
+	tagged, scanned := 0, 0
+	for _, detail := range details {
+		totalSize += detail.Size
+		if len(detail.Version.Metadata.Container.Tags) > 0 {
+			tagged++
+		}
+		if detail.Scan != nil {
+			scanned++
+		}
+	}
+
+	var b strings.Builder
+	fprintf(&b, "# Container Image Audit: %s/%s\n\n", config.Registry, imageRepository())
+	fprintf(&b, "Generated: %s\n\n", time.Now().UTC().Format(time.RFC3339))
+	if oldest, newest := offlineSnapshotRange(); !oldest.IsZero() {
+		// An --offline report says how old its data is
+		fprintf(&b, "Offline snapshot: cached %s to %s\n\n", oldest.UTC().Format(time.RFC3339), newest.UTC().Format(time.RFC3339))
+	}
+	fprintf(&b, "## Summary\n\n")
+	fprintf(&b, "- Versions: %d (%d tagged, %d untagged)\n", len(details), tagged, len(details)-tagged)
+	fprintf(&b, "- Scanned: %d of %d\n", scanned, len(details))
+	fprintf(&b, "- Total size: %s (shared layers counted once per version)\n\n", formatBytes(totalSize))
+	fprintf(&b, "## Versions\n\n")
+	fprintf(&b, "| Tags | Digest | Created | Size | Vulnerabilities | Scanned |\n|------|--------|---------|------|-----------------|---------|\n")
+	for _, detail := range details {
+		row := newReportRow(detail)
+		scannedAt := row.Scanned
+		if scannedAt == "" {
+			scannedAt = "-"
+		}
+		fmt.Fprintf(&b, "| %s | `%s` | %s | %s | %s | %s |\n",
+			markdownCell(message(row.Tags)), row.Digest, row.Created, message(row.Size), message(row.Scan), scannedAt)
+	}
+	if activity != nil {
+		writeMarkdownReleases(&b, details, *activity)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeMarkdownReleases lists the published releases, whether an image is
+// tagged with their version and how the workflows of their tag ended
+func writeMarkdownReleases(b *strings.Builder, details []VersionDetails, activity releaseActivity) {
+	imaged := imagedReleases(details)
+	fprintf(b, "\n## Releases\n\n")
+	if len(activity.Releases) == 0 {
+		fmt.Fprintln(b, message("(none)"))
+		return
+	}
+	fprintf(b, "| Release | Published | Image | Workflow runs |\n|---------|-----------|-------|---------------|\n")
+	for _, release := range activity.Releases {
+		if release.Draft {
+			continue
+		}
+		name := release.TagName
+		if release.Prerelease {
+			name += " " + message("(prerelease)")
+		}
+		image := "missing"
+		if version, ok := parseSemver(release.TagName); ok && imaged[version.String()] {
+			image = "tagged"
+		}
+		fmt.Fprintf(b, "| [%s](%s) | %s | %s | %s |\n", markdownCell(name), release.HTMLURL,
+			release.PublishedAt.UTC().Format(time.RFC3339), message(image), markdownCell(summarizeRuns(activity.Runs[release.TagName])))
+	}
+}
+
+// imagedReleases are the semantic versions tagged on an image, matched
+// by version, so a v1.2.0 release matches an image tagged 1.2.0
+func imagedReleases(details []VersionDetails) map[string]bool {
+	imaged := make(map[string]bool)
+	for _, detail := range details {
+		for _, tag := range detail.Version.Metadata.Container.Tags {
+			if version, ok := parseSemver(tag); ok {
+				imaged[version.String()] = true
+			}
+		}
+	}
+	return imaged
+}
+
+// markdownCell escapes pipes so a value cannot break the table layout
+func markdownCell(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
+
+func writeCSVReport(w io.Writer, details []VersionDetails) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"id", "tags", "digest", "created", "size_bytes", "size", "critical", "high", "medium", "low", "unknown", "scanner", "scanned_at"})
+	for _, detail := range details {
+		row := newReportRow(detail)
+		record := []string{
+			strconv.FormatInt(detail.Version.ID, 10),
+			strings.Join(detail.Version.Metadata.Container.Tags, " "),
+			row.Digest,
+			row.Created,
+			strconv.FormatInt(detail.Size, 10),
+			row.Size,
+		}
+		if scan := detail.Scan; scan != nil {
+			for _, severity := range severities {
+				record = append(record, strconv.Itoa(scan.Counts[severity]))
+			}
+			record = append(record, scan.Scanner, row.Scanned)
+		} else {
+			record = append(record, make([]string, len(severities)+2)...)
+		}
+		out.Write(record)
+	}
+	out.Flush()
+	return out.Error()
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Backoff bounds for retried requests
+const (
+	retryBaseDelay = time.Second
+	retryMaxDelay  = 2 * time.Minute
+)
+
+// doWithRetry sends a request built by newRequest, retrying rate limits,
+// and network errors and 5xx gateway errors of idempotent methods. GitHub
+// may have completed a POST or PATCH before the proxy gave up on it, so
+// those are not resent; a rate limit is refused before anything is done.
+// Rate limits honor Retry-After and X-RateLimit-Reset; everything else
+// backs off exponentially with jitter.
+// newRequest is called once per attempt so request bodies can be replayed.
+// An interrupt or --timeout ends the wait for the next attempt.
+func doWithRetry(client *http.Client, newRequest func() (*http.Request, error)) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := newRequest()
+		if err != nil {
+			return nil, err
+		}
+
+		start := time.Now()
+		resp, err := client.Do(req)
+		if err == nil {
+			slog.Debug("HTTP request", "method", req.Method, "target", requestTarget(req), "status", resp.StatusCode, "duration", time.Since(start).Round(time.Millisecond))
+		}
+		if attempt >= globalOptions.maxRetries || commandContext.Err() != nil || errors.Is(err, errOffline) {
+			return resp, err
+		}
+
+		var delay time.Duration
+		switch {
+		case err != nil && !idempotent(req.Method):
+			return resp, err
+		case err != nil:
+			delay = backoffDelay(attempt)
+			slog.Warn("Request failed, retrying", "host", req.URL.Host, "error", err, "attempt", attempt+1, "delay", delay.Round(time.Second))
+		case isRateLimited(resp):
+			delay = rateLimitDelay(resp, attempt)
+			slog.Warn("Rate limited, retrying", "host", req.URL.Host, "status", resp.StatusCode, "attempt", attempt+1, "delay", delay.Round(time.Second))
+		case idempotent(req.Method) && (resp.StatusCode == http.StatusBadGateway || resp.StatusCode == http.StatusServiceUnavailable || resp.StatusCode == http.StatusGatewayTimeout):
+			delay = backoffDelay(attempt)
+			slog.Warn("Gateway error, retrying", "host", req.URL.Host, "status", resp.StatusCode, "attempt", attempt+1, "delay", delay.Round(time.Second))
+		default:
+			return resp, nil
+		}
+
+		if resp != nil {
+			resp.Body.Close()
+		}
+		if err := sleepContext(delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// idempotent reports whether sending a request of method twice has the
+// effect of sending it once
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// isRateLimited detects primary (429 or exhausted quota) and secondary
+// (403 with Retry-After) GitHub rate limits
+func isRateLimited(resp *http.Response) bool {
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusForbidden:
+		return resp.Header.Get("Retry-After") != "" || resp.Header.Get("X-RateLimit-Remaining") == "0"
+	}
+	return false
+}
+
+func rateLimitDelay(resp *http.Response, attempt int) time.Duration {
+	if value := resp.Header.Get("Retry-After"); value != "" {
+		if seconds, err := strconv.Atoi(value); err == nil {
+			return time.Duration(seconds) * time.Second
+		}
+		if at, err := http.ParseTime(value); err == nil {
+			return time.Until(at)
+		}
+	}
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			// Add a little slack for clock skew
+			return time.Until(time.Unix(reset, 0)) + time.Second
+		}
+	}
+	return backoffDelay(attempt)
+}
+
+// backoffDelay returns an exponential delay with full jitter
+func backoffDelay(attempt int) time.Duration {
+	limit := retryBaseDelay << attempt
+	if limit <= 0 || limit > retryMaxDelay {
+		limit = retryMaxDelay
+	}
+	return retryBaseDelay/2 + rand.N(limit)
+}
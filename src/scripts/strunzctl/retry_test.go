@@ -0,0 +1,74 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRetryMethods(t *testing.T) {
+	globalOptions.maxRetries = 2
+	tests := []struct {
+		name     string
+		method   string
+		status   int
+		header   http.Header
+		requests int32
+	}{
+		{"POST is not resent after a gateway error", http.MethodPost, http.StatusBadGateway, nil, 1},
+		{"PATCH is not resent after a gateway error", http.MethodPatch, http.StatusServiceUnavailable, nil, 1},
+		{"GET is retried after a gateway error", http.MethodGet, http.StatusGatewayTimeout, nil, 3},
+		{"POST is retried when rate limited", http.MethodPost, http.StatusTooManyRequests, http.Header{"Retry-After": {"0"}}, 3},
+		{"POST is retried after a secondary rate limit", http.MethodPost, http.StatusForbidden, http.Header{"Retry-After": {"0"}}, 3},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var requests atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests.Add(1)
+				for key, values := range test.header {
+					w.Header()[key] = values
+				}
+				w.WriteHeader(test.status)
+			}))
+			defer server.Close()
+
+			resp, err := doWithRetry(server.Client(), func() (*http.Request, error) {
+				return http.NewRequest(test.method, server.URL, nil)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != test.status {
+				t.Errorf("status %d, want %d", resp.StatusCode, test.status)
+			}
+			if got := requests.Load(); got != test.requests {
+				t.Errorf("sent %d times, want %d", got, test.requests)
+			}
+		})
+	}
+}
+
+func TestRetryTransportErrors(t *testing.T) {
+	globalOptions.maxRetries = 1
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		// Drop the connection as if it were lost after the write was done
+		conn, _, _ := http.NewResponseController(w).Hijack()
+		conn.Close()
+	}))
+	defer server.Close()
+
+	_, err := doWithRetry(server.Client(), func() (*http.Request, error) {
+		return http.NewRequest(http.MethodPost, server.URL, nil)
+	})
+	if err == nil {
+		t.Fatal("want the transport error")
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("POST sent %d times, want 1", got)
+	}
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// railwayTokenSecret is the GitHub Actions secret the workflows deploy with
+const railwayTokenSecret = "RAILWAY_TOKEN"
+
+// rotatedTokenPrefix names the Railway project tokens created by rotation,
+// so the next rotation knows which ones it may revoke
+const rotatedTokenPrefix = "strunzctl-rotate-"
+
+// variableName matches environment variable names
+var variableName = regexp.MustCompile(`^[A-Z_][A-Z0-9_]*$`)
+
+// generateSecret returns 32 random bytes URL-safe encoded, like Python's
+// secrets.token_urlsafe(32) the server falls back to
+func generateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate a secret: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// actionsSecret is a GitHub Actions repository secret without its value
+type actionsSecret struct {
+	Name      string    `json:"name"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func listActionsSecrets(github *GitHubClient) (map[string]actionsSecret, error) {
+	secrets := make(map[string]actionsSecret)
+	err := github.GetPages(fmt.Sprintf("/repos/%s/actions/secrets", config.Repo), func(body []byte) error {
+		var page struct {
+			Secrets []actionsSecret `json:"secrets"`
+		}
+		if err := json.Unmarshal(body, &page); err != nil {
+			return fmt.Errorf("failed to parse the Actions secrets: %w", err)
+		}
+		for _, secret := range page.Secrets {
+			secrets[secret.Name] = secret
+		}
+		return nil
+	})
+	return secrets, err
+}
+
+// setActionsSecret stores a repository secret with gh, which encrypts it
+// with the repository's public key as the secrets API requires
+func setActionsSecret(github *GitHubClient, name, value string) error {
+	token, err := github.currentToken()
+	if err != nil {
+		return err
+	}
+	cmd := newProcess("gh", "secret", "set", name, "--repo", config.Repo)
+	cmd.Stdin = strings.NewReader(value)
+	cmd.Env = append(os.Environ(), "GH_TOKEN="+token)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("gh secret set %s failed: %w\n%s", name, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+func newSecretsCommand() *Command {
+	return newGroup("secrets", "Manage the server's secrets across Railway and GitHub Actions.",
+		newSecretsRotateCommand(),
+	)
+}
+
+func newSecretsRotateCommand() *Command {
+	cmd := newCommand("rotate", "[variable...]", "Generate new secrets, set them on the Railway service and in GitHub Actions, redeploy and verify the server runs with them.")
+	service := cmd.Flags.String("service", "", "Railway service name or ID (default: railway.service)")
+	githubNames := cmd.Flags.String("github", "", "comma-separated variables to store as GitHub Actions secrets too (default: those the repository already has)")
+	railwayToken := cmd.Flags.Bool("railway-token", false, "also replace the "+railwayTokenSecret+" project token of the workflows and revoke the previously rotated one")
+	serverURL := cmd.Flags.String("url", "", "base URL of the server to check afterwards (default: railway.domain, else "+defaultServerURL+")")
+	yes := cmd.Flags.Bool("yes", false, "do not ask for confirmation")
+	timeout := cmd.Flags.Duration("timeout", 15*time.Minute, "how long to wait for the deployment")
+
+	cmd.Run = func(args []string) error {
+		names := args
+		if len(names) == 0 {
+			names = splitList(config.Secrets.Rotate)
+		}
+		if len(names) == 0 && !*railwayToken {
+			return fmt.Errorf("%w: name the variables to rotate or set secrets.rotate", errUsage)
+		}
+		for _, name := range names {
+			if !variableName.MatchString(name) {
+				return fmt.Errorf("%w: %q is not an environment variable name", errUsage, name)
+			}
+			if name == railwayTokenSecret {
+				return fmt.Errorf("%w: %s is a Railway project token, rotate it with --railway-token", errUsage, name)
+			}
+		}
+		url := *serverURL
+		if url == "" {
+			url = defaultServerURL
+			if config.Railway.Domain != "" {
+				url = "https://" + config.Railway.Domain
+			}
+		}
+
+		railway, err := newRailwayClient()
+		if err != nil {
+			return err
+		}
+		if *railwayToken && railway.ProjectToken {
+			return fmt.Errorf("%w: creating project tokens needs an account or team token in railway.token or RAILWAY_API_TOKEN", errUsage)
+		}
+		target, err := resolveRailwayTarget(railway, *service)
+		if err != nil {
+			return err
+		}
+		github, err := newGitHubClient()
+		if err != nil {
+			return err
+		}
+		existing, err := listActionsSecrets(github)
+		if err != nil {
+			return fmt.Errorf("cannot list the GitHub Actions secrets of %s: %w", config.Repo, err)
+		}
+		toGitHub := make(map[string]bool)
+		if *githubNames != "" {
+			for _, name := range splitList(*githubNames) {
+				if !slices.Contains(names, name) {
+					return fmt.Errorf("%w: --github names %s, which is not rotated", errUsage, name)
+				}
+				toGitHub[name] = true
+			}
+		} else {
+			for _, name := range names {
+				_, toGitHub[name] = existing[name]
+			}
+		}
+		needsGH := *railwayToken
+		for _, ok := range toGitHub {
+			needsGH = needsGH || ok
+		}
+		if _, err := exec.LookPath("gh"); err != nil && needsGH {
+			return fmt.Errorf("%w: gh is not installed; it encrypts GitHub Actions secrets (https://cli.github.com)", errUsage)
+		}
+		current, err := railway.Variables(target)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("\n🔄 Rotating secrets of %s\n", target.ServiceName)
+		for _, name := range names {
+			action := "replace"
+			if _, ok := current[name]; !ok {
+				action = "create"
+			}
+			where := "Railway"
+			if toGitHub[name] {
+				where += " and GitHub Actions"
+			}
+			fmt.Printf("  %-24s %s in %s\n", name, action, where)
+		}
+		if *railwayToken {
+			fmt.Printf("  %-24s new project token in GitHub Actions, earlier rotated tokens revoked\n", railwayTokenSecret)
+		}
+		ok, err := confirm(fmt.Sprintf("Rotate and redeploy %s? Clients holding tokens signed with the old secrets have to sign in again.", target.ServiceName), *yes)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("rotation cancelled")
+		}
+
+		started := time.Now()
+		values := make(map[string]string, len(names))
+		for _, name := range names {
+			if values[name], err = generateSecret(); err != nil {
+				return err
+			}
+		}
+		for _, name := range names {
+			if err := railway.SetVariable(target, name, values[name]); err != nil {
+				return err
+			}
+			fmt.Printf("✅ %s set on %s\n", name, target.ServiceName)
+		}
+
+		var previousTokens []RailwayProjectToken
+		if *railwayToken {
+			tokens, err := railway.ProjectTokens(target)
+			if err != nil {
+				return err
+			}
+			for _, token := range tokens {
+				if strings.HasPrefix(token.Name, rotatedTokenPrefix) && token.EnvironmentID == target.Environment {
+					previousTokens = append(previousTokens, token)
+				}
+			}
+			token, err := railway.CreateProjectToken(target, rotatedTokenPrefix+started.UTC().Format("20060102-150405"))
+			if err != nil {
+				return err
+			}
+			values[railwayTokenSecret] = token
+			toGitHub[railwayTokenSecret] = true
+		}
+		for _, name := range append(slices.Clone(names), railwayTokenSecret) {
+			if !toGitHub[name] {
+				continue
+			}
+			if err := setActionsSecret(github, name, values[name]); err != nil {
+				return fmt.Errorf("%w; the Railway variables are already replaced, rotate again once gh works", err)
+			}
+			fmt.Printf("✅ %s set in GitHub Actions\n", name)
+		}
+
+		// The variables were set without deploying; one deployment picks up all
+		fmt.Printf("\n🚂 Redeploying %s\n", target.ServiceName)
+		id, err := railway.Deploy(target)
+		if err != nil {
+			return err
+		}
+		if _, err := waitForDeployment(railway, id, *timeout, 10*time.Second); err != nil {
+			return err
+		}
+
+		fmt.Println("\n🔍 Verifying")
+		failed := 0
+		deployed, err := railway.Variables(target)
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			if deployed[name] != values[name] {
+				fmt.Printf("❌ %s on %s is not the rotated value\n", name, target.ServiceName)
+				failed++
+			}
+		}
+		if failed == 0 && len(names) > 0 {
+			fmt.Printf("✅ deployment %s runs with the rotated variables\n", id)
+		}
+		if health, err := fetchHealth(url); err != nil {
+			fmt.Printf("❌ %s: %v\n", url, err)
+			failed++
+		} else {
+			fmt.Printf("✅ %s answers /health (%s)\n", url, health.Status)
+		}
+		if needsGH {
+			updated, err := listActionsSecrets(github)
+			if err != nil {
+				return err
+			}
+			for name, ok := range toGitHub {
+				if !ok {
+					continue
+				}
+				// updated_at has second precision
+				if secret, found := updated[name]; !found || secret.UpdatedAt.Before(started.Truncate(time.Second)) {
+					fmt.Printf("❌ GitHub Actions secret %s was not updated\n", name)
+					failed++
+				}
+			}
+		}
+		if *railwayToken {
+			check := &RailwayClient{Token: values[railwayTokenSecret], ProjectToken: true, URL: railway.URL, HTTPClient: railway.HTTPClient, Retry: doWithRetry}
+			if resolved, err := resolveRailwayTarget(check, target.Service); err != nil || resolved.Environment != target.Environment {
+				fmt.Printf("❌ the new %s does not reach %s: %v\n", railwayTokenSecret, target.ServiceName, err)
+				failed++
+			} else {
+				fmt.Printf("✅ the new %s reaches %s\n", railwayTokenSecret, target.ServiceName)
+			}
+		}
+		if failed > 0 {
+			// Keep the old project tokens so the workflows still deploy
+			return fmt.Errorf("%w: %d check(s) failed after rotating", errPolicy, failed)
+		}
+
+		for _, token := range previousTokens {
+			if err := railway.DeleteProjectToken(token.ID); err != nil {
+				return err
+			}
+			fmt.Printf("🗑️  revoked project token %s from %s\n", token.Name, token.CreatedAt.Local().Format(time.DateOnly))
+		}
+		if *railwayToken && len(previousTokens) == 0 {
+			fmt.Printf("ℹ️  revoke the %s the workflows used before in the Railway project settings; it was not created by rotation\n", railwayTokenSecret)
+		}
+
+		rotated := slices.Clone(names)
+		if *railwayToken {
+			rotated = append(rotated, railwayTokenSecret)
+		}
+		if err := recordAudit(AuditEntry{Action: auditRotate, Tag: strings.Join(rotated, ","), Target: target.ServiceName}); err != nil {
+			return err
+		}
+		fmt.Printf("\n✅ Rotated %s\n", strings.Join(rotated, ", "))
+		return nil
+	}
+	return cmd
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+)
+
+// SBOM predicate types used in in-toto attestations
+const (
+	predicateSPDX      = "https://spdx.dev/Document"
+	predicateCycloneDX = "https://cyclonedx.org/bom"
+)
+
+// sbomFormats maps our --format names to predicate types and syft outputs
+var sbomFormats = map[string]struct{ predicate, syftOutput string }{
+	"spdx":      {predicateSPDX, "spdx-json"},
+	"cyclonedx": {predicateCycloneDX, "cyclonedx-json"},
+}
+
+func newImageSBOMCommand() *Command {
+	cmd := newCommand("sbom", "<tag>", "Download the SBOM attached to an image, or generate one with syft.")
+	format := cmd.Flags.String("format", "spdx", "SBOM format: spdx or cyclonedx")
+	output := cmd.Flags.String("output", "", "write the SBOM to a file instead of stdout")
+	platform := cmd.Flags.String("platform", defaultPlatform, "platform image to read from a manifest list")
+	generate := cmd.Flags.Bool("generate", false, "always generate with syft, ignoring attached SBOMs")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 1); err != nil {
+			return err
+		}
+		if _, ok := sbomFormats[*format]; !ok {
+			return fmt.Errorf("%w: unsupported SBOM format %q", errUsage, *format)
+		}
+		registry := newRegistryClient(config.Registry, imageRepository())
+
+		sbom, source, err := fetchSBOM(registry, args[0], *platform, *format, *generate)
+		if err != nil {
+			return err
+		}
+		slog.Info("SBOM found", "reference", registry.Reference(args[0]), "format", *format, "source", source)
+
+		if *output == "" {
+			_, err = os.Stdout.Write(sbom)
+			return err
+		}
+		if err := os.WriteFile(*output, sbom, 0o644); err != nil {
+			return fmt.Errorf("failed to write SBOM: %w", err)
+		}
+		slog.Info("SBOM written", "path", *output)
+		return nil
+	}
+	return cmd
+}
+
+// fetchSBOM returns the SBOM of a platform image and where it came from:
+// a BuildKit attestation, a cosign-attached SBOM, or syft generation
+func fetchSBOM(registry *RegistryClient, tag, platform, format string, generate bool) ([]byte, string, error) {
+	index, err := registry.GetManifest(tag)
+	if err != nil {
+		return nil, "", err
+	}
+	image, err := selectPlatform(registry, index, platform)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if !generate {
+		if sbom, _, err := attestedPredicate(registry, index, image.Digest, sbomFormats[format].predicate); err != nil {
+			return nil, "", err
+		} else if sbom != nil {
+			return sbom, "attestation", nil
+		}
+		if sbom, err := cosignSBOM(registry, image.Digest); err != nil {
+			return nil, "", err
+		} else if sbom != nil && sbomMatchesFormat(sbom, format) {
+			return sbom, "cosign attachment", nil
+		}
+	}
+
+	sbom, err := generateSBOM(registry.Reference(image.Digest), sbomFormats[format].syftOutput)
+	if err != nil {
+		return nil, "", err
+	}
+	return sbom, "generated by syft", nil
+}
+
+// attestedPredicate looks for a BuildKit attestation manifest referring to
+// the image digest and extracts the first predicate of one of the given
+// types, returning it with its type
+func attestedPredicate(registry *RegistryClient, index *Manifest, imageDigest string, predicateTypes ...string) ([]byte, string, error) {
+	if !index.IsIndex() {
+		return nil, "", nil
+	}
+
+	for _, desc := range index.Manifests {
+		if !desc.IsAttestation() || desc.Annotations["vnd.docker.reference.digest"] != imageDigest {
+			continue
+		}
+		attestation, err := registry.GetManifest(desc.Digest)
+		if err != nil {
+			return nil, "", err
+		}
+
+		for _, layer := range attestation.Layers {
+			predicateType := layer.Annotations["in-toto.io/predicate-type"]
+			if !slices.Contains(predicateTypes, predicateType) {
+				continue
+			}
+			statement, err := registry.GetBlob(layer.Digest)
+			if err != nil {
+				return nil, "", err
+			}
+			var envelope struct {
+				Predicate json.RawMessage `json:"predicate"`
+			}
+			if err := json.Unmarshal(statement, &envelope); err != nil {
+				return nil, "", fmt.Errorf("failed to parse attestation %s: %w", layer.Digest, err)
+			}
+			return envelope.Predicate, predicateType, nil
+		}
+	}
+	return nil, "", nil
+}
+
+// cosignSBOM fetches an SBOM attached with `cosign attach sbom`, which is
+// stored under the tag sha256-<hex>.sbom
+func cosignSBOM(registry *RegistryClient, imageDigest string) ([]byte, error) {
+	manifest, err := registry.GetManifest(cosignTag(imageDigest, "sbom"))
+	if isNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(manifest.Layers) == 0 {
+		return nil, nil
+	}
+	return registry.GetBlob(manifest.Layers[0].Digest)
+}
+
+// cosignTag returns the tag cosign uses for artifacts attached to a digest
+func cosignTag(digest, suffix string) string {
+	return strings.Replace(digest, ":", "-", 1) + "." + suffix
+}
+
+func sbomMatchesFormat(sbom []byte, format string) bool {
+	var doc struct {
+		SPDXVersion string `json:"spdxVersion"`
+		BOMFormat   string `json:"bomFormat"`
+	}
+	if err := json.Unmarshal(sbom, &doc); err != nil {
+		return false
+	}
+	switch format {
+	case "spdx":
+		return doc.SPDXVersion != ""
+	case "cyclonedx":
+		return doc.BOMFormat == "CycloneDX"
+	}
+	return false
+}
+
+func generateSBOM(reference, output string) ([]byte, error) {
+	cmd := newProcess("syft", "registry:"+reference, "--output", output, "--quiet")
+	cmd.Env = append(os.Environ(), registryCredentialEnv(reference, "SYFT_REGISTRY_AUTH_USERNAME", "SYFT_REGISTRY_AUTH_PASSWORD")...)
+	cmd.Stderr = os.Stderr
+
+	sbom, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("syft failed to generate SBOM: %w", err)
+	}
+	return sbom, nil
+}
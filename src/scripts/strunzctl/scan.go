@@ -0,0 +1,319 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// severities in descending order of importance
+var severities = []string{"CRITICAL", "HIGH", "MEDIUM", "LOW", "UNKNOWN"}
+
+// Vulnerability is a single finding reported by a scanner
+type Vulnerability struct {
+	ID               string `json:"id"`
+	Severity         string `json:"severity"`
+	Package          string `json:"package"`
+	InstalledVersion string `json:"installed_version"`
+	FixedVersion     string `json:"fixed_version,omitempty"`
+	// OS is set for packages of the distribution rather than of a language
+	// ecosystem like pip
+	OS bool `json:"os,omitempty"`
+}
+
+// ScanResult is the normalized output of a vulnerability scan
+type ScanResult struct {
+	Reference       string          `json:"reference"`
+	Digest          string          `json:"digest"`
+	IndexDigest     string          `json:"index_digest,omitempty"`
+	Scanner         string          `json:"scanner"`
+	ScannedAt       time.Time       `json:"scanned_at"`
+	Counts          map[string]int  `json:"counts"`
+	Vulnerabilities []Vulnerability `json:"vulnerabilities"`
+}
+
+// Summary renders the severity counts, e.g. "C:0 H:3 M:12 L:40"
+func (s *ScanResult) Summary() string {
+	return fmt.Sprintf("C:%d H:%d M:%d L:%d",
+		s.Counts["CRITICAL"], s.Counts["HIGH"], s.Counts["MEDIUM"], s.Counts["LOW"])
+}
+
+func newScanCommand() *Command {
+	cmd := newCommand("scan", "<tag>", "Scan an image for vulnerabilities with trivy or grype.")
+	scanner := cmd.Flags.String("scanner", "trivy", "vulnerability scanner to run (trivy or grype)")
+	platform := cmd.Flags.String("platform", defaultPlatform, "platform image to scan from a manifest list")
+	notify := cmd.Flags.Bool("notify-webhook", false, "post scan failures to webhooks.notify and the alerts sinks")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 1); err != nil {
+			return err
+		}
+		tag := args[0]
+		notifier, err := newNotifier(*notify)
+		if err != nil {
+			return err
+		}
+		registry := newRegistryClient(config.Registry, imageRepository())
+
+		fmt.Printf("\n🛡️  Scanning %s (%s) with %s...\n", registry.Reference(tag), *platform, *scanner)
+		result, err := scanReference(registry, tag, *platform, *scanner)
+		if err != nil {
+			notifier.alert(severityWarning, "scan "+tag, "Scan failed", fmt.Sprintf("`%s` (%s, %s): %v", registry.Reference(tag), *platform, *scanner, err))
+			return err
+		}
+		printScanResult(result)
+		return nil
+	}
+	cmd.AddCommand(newScanGateCommand())
+	return cmd
+}
+
+func printScanResult(result *ScanResult) {
+	fmt.Println("\nSeverity counts:")
+	for _, severity := range severities {
+		fmt.Printf("  %-9s %d\n", severity, result.Counts[severity])
+	}
+
+	var serious []Vulnerability
+	for _, vuln := range result.Vulnerabilities {
+		if vuln.Severity == "CRITICAL" || vuln.Severity == "HIGH" {
+			serious = append(serious, vuln)
+		}
+	}
+	if len(serious) == 0 {
+		fmt.Println("\n✅ No critical or high vulnerabilities")
+		return
+	}
+
+	fmt.Println("\nCritical and high findings:")
+	for _, vuln := range serious {
+		fixed := vuln.FixedVersion
+		if fixed == "" {
+			fixed = "no fix"
+		}
+		fmt.Printf("  - %-8s %-20s %s %s (fixed: %s)\n", vuln.Severity, vuln.ID, vuln.Package, vuln.InstalledVersion, fixed)
+	}
+}
+
+// scanReference resolves a tag or digest to its platform image, scans it and
+// caches the result under both the platform and the manifest list digest
+func scanReference(registry *RegistryClient, reference, platform, scanner string) (*ScanResult, error) {
+	manifest, err := registry.GetManifest(reference)
+	if err != nil {
+		return nil, err
+	}
+	image, err := selectPlatform(registry, manifest, platform)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := scanImage(scanner, registry.Reference(image.Digest), image.Digest)
+	if err != nil {
+		return nil, err
+	}
+	if manifest.Digest != image.Digest {
+		result.IndexDigest = manifest.Digest
+	}
+
+	if err := saveScanResult(result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// scanImage runs the scanner against an image reference and normalizes the findings
+func scanImage(scanner, reference, digest string) (*ScanResult, error) {
+	var vulns []Vulnerability
+	var err error
+	switch scanner {
+	case "trivy":
+		vulns, err = runTrivy(reference)
+	case "grype":
+		vulns, err = runGrype(reference)
+	default:
+		return nil, fmt.Errorf("%w: unsupported scanner %q", errUsage, scanner)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ScanResult{
+		Reference:       reference,
+		Digest:          digest,
+		Scanner:         scanner,
+		ScannedAt:       time.Now().UTC(),
+		Counts:          make(map[string]int),
+		Vulnerabilities: vulns,
+	}
+	for _, vuln := range vulns {
+		result.Counts[vuln.Severity]++
+	}
+	return result, nil
+}
+
+func runTrivy(reference string) ([]Vulnerability, error) {
+	cmd := newProcess("trivy", "image", "--quiet", "--format", "json", reference)
+	cmd.Env = append(os.Environ(), registryCredentialEnv(reference, "TRIVY_USERNAME", "TRIVY_PASSWORD")...)
+	cmd.Stderr = os.Stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("trivy scan failed: %w", err)
+	}
+
+	var report struct {
+		Results []struct {
+			Class           string `json:"Class"`
+			Vulnerabilities []struct {
+				VulnerabilityID  string `json:"VulnerabilityID"`
+				PkgName          string `json:"PkgName"`
+				InstalledVersion string `json:"InstalledVersion"`
+				FixedVersion     string `json:"FixedVersion"`
+				Severity         string `json:"Severity"`
+			} `json:"Vulnerabilities"`
+		} `json:"Results"`
+	}
+	if err := json.Unmarshal(output, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse trivy report: %w", err)
+	}
+
+	var vulns []Vulnerability
+	for _, result := range report.Results {
+		for _, v := range result.Vulnerabilities {
+			vulns = append(vulns, Vulnerability{
+				ID:               v.VulnerabilityID,
+				Severity:         normalizeSeverity(v.Severity),
+				Package:          v.PkgName,
+				InstalledVersion: v.InstalledVersion,
+				FixedVersion:     v.FixedVersion,
+				OS:               result.Class == "os-pkgs",
+			})
+		}
+	}
+	return vulns, nil
+}
+
+// osPackageTypes are the grype artifact types of distribution packages
+var osPackageTypes = map[string]bool{"deb": true, "rpm": true, "apk": true}
+
+func runGrype(reference string) ([]Vulnerability, error) {
+	cmd := newProcess("grype", "registry:"+reference, "--output", "json", "--quiet")
+	cmd.Env = append(os.Environ(), registryCredentialEnv(reference, "GRYPE_REGISTRY_AUTH_USERNAME", "GRYPE_REGISTRY_AUTH_PASSWORD")...)
+	cmd.Stderr = os.Stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("grype scan failed: %w", err)
+	}
+
+	var report struct {
+		Matches []struct {
+			Vulnerability struct {
+				ID       string `json:"id"`
+				Severity string `json:"severity"`
+				Fix      struct {
+					Versions []string `json:"versions"`
+				} `json:"fix"`
+			} `json:"vulnerability"`
+			Artifact struct {
+				Name    string `json:"name"`
+				Version string `json:"version"`
+				Type    string `json:"type"`
+			} `json:"artifact"`
+		} `json:"matches"`
+	}
+	if err := json.Unmarshal(output, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse grype report: %w", err)
+	}
+
+	var vulns []Vulnerability
+	for _, m := range report.Matches {
+		vulns = append(vulns, Vulnerability{
+			ID:               m.Vulnerability.ID,
+			Severity:         normalizeSeverity(m.Vulnerability.Severity),
+			Package:          m.Artifact.Name,
+			InstalledVersion: m.Artifact.Version,
+			FixedVersion:     strings.Join(m.Vulnerability.Fix.Versions, ", "),
+			OS:               osPackageTypes[m.Artifact.Type],
+		})
+	}
+	return vulns, nil
+}
+
+func normalizeSeverity(severity string) string {
+	severity = strings.ToUpper(strings.TrimSpace(severity))
+	for _, known := range severities {
+		if severity == known {
+			return severity
+		}
+	}
+	return "UNKNOWN"
+}
+
+// registryCredentialEnv passes the GitHub token to scanners that pull from
+// GHCR themselves, using the given environment variable names. Images of
+// other registries, like base images, are pulled anonymously.
+func registryCredentialEnv(reference, userVar, passwordVar string) []string {
+	if !strings.HasPrefix(reference, config.Registry+"/") {
+		return nil
+	}
+	token, err := githubToken()
+	if err != nil {
+		return nil
+	}
+	return []string{userVar + "=token", passwordVar + "=" + token}
+}
+
+func scanResultPath(digest string) (string, error) {
+	dir, err := cacheDir("scans")
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, strings.ReplaceAll(digest, ":", "-")+".json"), nil
+}
+
+func saveScanResult(result *ScanResult) error {
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	for _, digest := range []string{result.Digest, result.IndexDigest} {
+		if digest == "" {
+			continue
+		}
+		path, err := scanResultPath(digest)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			return fmt.Errorf("failed to cache scan result: %w", err)
+		}
+	}
+	return nil
+}
+
+// loadScanResult returns the cached scan of a digest, or nil if it was never scanned
+func loadScanResult(digest string) (*ScanResult, error) {
+	path, err := scanResultPath(digest)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var result ScanResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse cached scan %s: %w", path, err)
+	}
+	return &result, nil
+}
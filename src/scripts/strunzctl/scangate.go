@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// defaultCVEAllowlist is read from the repository root when present
+const defaultCVEAllowlist = ".cve-allowlist"
+
+// acceptedCVE is an allowlist entry: a finding that was reviewed and is
+// accepted until it expires
+type acceptedCVE struct {
+	ID string
+	// Package restricts the entry to one package when set
+	Package string
+	Expires time.Time
+	Reason  string
+	Line    int
+	matched int
+}
+
+// parseCVEAllowlist reads lines of the form
+//
+//	CVE-2024-12345 2026-12-31 [package] # reason
+//
+// Blank lines and lines starting with # are skipped.
+func parseCVEAllowlist(path string) ([]*acceptedCVE, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []*acceptedCVE
+	scanner := bufio.NewScanner(file)
+	for n := 1; scanner.Scan(); n++ {
+		line, reason, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 2 || len(fields) > 3 {
+			return nil, fmt.Errorf("%w: %s:%d: expected \"<id> <expires YYYY-MM-DD> [package] # reason\"", errUsage, path, n)
+		}
+		expires, err := time.Parse(time.DateOnly, fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s:%d: invalid expiry date %q", errUsage, path, n, fields[1])
+		}
+		entry := &acceptedCVE{ID: fields[0], Expires: expires, Reason: strings.TrimSpace(reason), Line: n}
+		if len(fields) == 3 {
+			entry.Package = fields[2]
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// acceptedBy finds the allowlist entry covering a finding. Entries are
+// valid through the day they expire on.
+func acceptedBy(v Vulnerability, entries []*acceptedCVE, now time.Time) (entry *acceptedCVE, expired bool) {
+	for _, e := range entries {
+		if !strings.EqualFold(e.ID, v.ID) || (e.Package != "" && e.Package != v.Package) {
+			continue
+		}
+		if now.After(e.Expires.AddDate(0, 0, 1)) {
+			entry, expired = e, true
+			continue
+		}
+		return e, false
+	}
+	return entry, expired
+}
+
+func newScanGateCommand() *Command {
+	cmd := newCommand("gate", "<tag>", "Fail when an image has more unaccepted vulnerabilities of a severity than allowed.")
+	scanner := cmd.Flags.String("scanner", "trivy", "vulnerability scanner to run (trivy or grype)")
+	platform := cmd.Flags.String("platform", defaultPlatform, "platform image to scan from a manifest list")
+	maxCritical := cmd.Flags.Int("max-critical", 0, "allowed critical findings")
+	maxHigh := cmd.Flags.Int("max-high", 0, "allowed high findings")
+	maxMedium := cmd.Flags.Int("max-medium", -1, "allowed medium findings, -1 for any number")
+	allowlist := cmd.Flags.String("allowlist", "", "file of accepted CVEs with expiry dates (default: "+defaultCVEAllowlist+" in the repository, if present)")
+	ignoreUnfixed := cmd.Flags.Bool("ignore-unfixed", false, "do not count findings without a fixed version")
+	maxAge := cmd.Flags.Duration("max-age", 0, "reuse a cached scan younger than this instead of scanning again")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 1); err != nil {
+			return err
+		}
+		tag := args[0]
+		path := *allowlist
+		if path == "" {
+			if root, err := repoPath(""); err == nil {
+				path = filepath.Join(root, defaultCVEAllowlist)
+				if _, err := os.Stat(path); err != nil {
+					path = ""
+				}
+			}
+		}
+		var entries []*acceptedCVE
+		if path != "" {
+			var err error
+			if entries, err = parseCVEAllowlist(path); err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					return fmt.Errorf("%w: %v", errUsage, err)
+				}
+				return err
+			}
+		}
+
+		registry := newRegistryClient(config.Registry, imageRepository())
+		image, err := resolvePlatformManifest(registry, tag, *platform)
+		if err != nil {
+			return err
+		}
+		var result *ScanResult
+		if *maxAge > 0 {
+			if cached, err := loadScanResult(image.Digest); err == nil && cached != nil && cached.Scanner == *scanner && time.Since(cached.ScannedAt) < *maxAge {
+				result = cached
+			}
+		}
+		if result == nil {
+			fmt.Printf("\n🛡️  Scanning %s (%s) with %s...\n", registry.Reference(tag), *platform, *scanner)
+			if result, err = scanReference(registry, tag, *platform, *scanner); err != nil {
+				return err
+			}
+		} else {
+			fmt.Printf("\n🛡️  Using the %s scan of %s from %s\n", result.Scanner, registry.Reference(tag), result.ScannedAt.Local().Format(time.DateTime))
+		}
+
+		now := time.Now()
+		limits := map[string]int{"CRITICAL": *maxCritical, "HIGH": *maxHigh, "MEDIUM": *maxMedium}
+		found, accepted, unfixed := make(map[string]int), make(map[string]int), make(map[string]int)
+		var counted []Vulnerability
+		for _, v := range result.Vulnerabilities {
+			found[v.Severity]++
+			switch entry, expired := acceptedBy(v, entries, now); {
+			case entry != nil && !expired:
+				entry.matched++
+				accepted[v.Severity]++
+			case *ignoreUnfixed && v.FixedVersion == "":
+				unfixed[v.Severity]++
+			default:
+				counted = append(counted, v)
+			}
+		}
+
+		fmt.Printf("\n  %-9s %6s %9s %9s %8s %6s\n", "SEVERITY", "FOUND", "ACCEPTED", "UNFIXED", "COUNTED", "LIMIT")
+		exceeded := make(map[string]bool)
+		for _, severity := range severities {
+			count := found[severity] - accepted[severity] - unfixed[severity]
+			limit, ok := limits[severity]
+			status, shown := "  ", "-"
+			if ok && limit >= 0 {
+				shown = fmt.Sprint(limit)
+				status = "✅"
+				if count > limit {
+					status = "❌"
+					exceeded[severity] = true
+				}
+			}
+			fmt.Printf("%s %-9s %6d %9d %9d %8d %6s\n", status, severity, found[severity], accepted[severity], unfixed[severity], count, shown)
+		}
+
+		var blocking []Vulnerability
+		for _, v := range counted {
+			if exceeded[v.Severity] {
+				blocking = append(blocking, v)
+			}
+		}
+		if len(blocking) > 0 {
+			fmt.Println("\nFindings over the limits; fix them or accept them in the allowlist:")
+			for _, v := range blocking {
+				fmt.Printf("  - %-8s %-20s %s %s (fixed: %s)\n", v.Severity, v.ID, v.Package, v.InstalledVersion, cmp.Or(v.FixedVersion, "no fix"))
+			}
+		}
+
+		for _, entry := range entries {
+			switch {
+			case now.After(entry.Expires.AddDate(0, 0, 1)):
+				fmt.Printf("⚠️  %s expired on %s (%s:%d) and counts again; fix it or renew the entry\n", entry.ID, entry.Expires.Format(time.DateOnly), path, entry.Line)
+			case entry.matched == 0:
+				fmt.Printf("ℹ️  %s (%s:%d) matches no finding any more; remove it\n", entry.ID, path, entry.Line)
+			case entry.Expires.Before(now.AddDate(0, 0, 14)):
+				fmt.Printf("⏳ %s is accepted only until %s (%s:%d)\n", entry.ID, entry.Expires.Format(time.DateOnly), path, entry.Line)
+			}
+			if entry.Reason == "" {
+				fmt.Printf("⚠️  %s (%s:%d) has no reason; add one after #\n", entry.ID, path, entry.Line)
+			}
+		}
+
+		if len(exceeded) > 0 {
+			return fmt.Errorf("%w: %s exceeds the vulnerability limits for %d severity level(s)", errPolicy, tag, len(exceeded))
+		}
+		fmt.Printf("\n✅ %s is within the vulnerability limits\n", tag)
+		return nil
+	}
+	return cmd
+}
@@ -0,0 +1,457 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxTaskLogSize is the size at which a task log is moved to .1 before
+// the next run, keeping at most twice this per task
+const maxTaskLogSize = 10 << 20
+
+// scheduledTask is one entry of tasks: in a schedule file
+type scheduledTask struct {
+	name     string
+	spec     string
+	schedule cronSchedule
+	// args are the strunzctl arguments of the command
+	args    []string
+	timeout time.Duration
+	// restart reruns an @reboot task this long after it exits, so commands
+	// such as monitor run keep running; 0 runs it once
+	restart  time.Duration
+	severity string
+}
+
+// scheduleFile is a parsed schedule file
+type scheduleFile struct {
+	location *time.Location
+	logDir   string
+	// tasks are sorted by name
+	tasks []*scheduledTask
+}
+
+// parseScheduleFile reads a schedule file: an optional timezone the cron
+// expressions are in (default UTC), an optional log_dir and a tasks:
+// section with one section per task
+func parseScheduleFile(path string) (*scheduleFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %v", errUsage, err)
+		}
+		return nil, err
+	}
+	values, err := parseYAML(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", errUsage, path, err)
+	}
+	invalid := func(format string, args ...any) error {
+		return fmt.Errorf("%w: %s: %s", errUsage, path, fmt.Sprintf(format, args...))
+	}
+
+	file := &scheduleFile{location: time.UTC}
+	for key, value := range values {
+		text, _ := value.(string)
+		switch key {
+		case "timezone":
+			if file.location, err = time.LoadLocation(text); err != nil {
+				return nil, invalid("timezone %q: %v", text, err)
+			}
+		case "log_dir":
+			file.logDir = text
+		case "tasks":
+		default:
+			return nil, invalid("unknown setting %s", key)
+		}
+	}
+	tasks, ok := values["tasks"].(map[string]any)
+	if !ok || len(tasks) == 0 {
+		return nil, invalid("expected a tasks: section with at least one task")
+	}
+
+	for name, value := range tasks {
+		settings, ok := value.(map[string]any)
+		if !ok {
+			return nil, invalid("tasks.%s must be a section", name)
+		}
+		task := &scheduledTask{name: name, severity: severityWarning}
+		for key, value := range settings {
+			text, ok := value.(string)
+			if !ok {
+				return nil, invalid("tasks.%s.%s must be a value", name, key)
+			}
+			switch key {
+			case "cron":
+				task.spec = text
+				if task.schedule, err = parseCron(text); err != nil {
+					return nil, invalid("tasks.%s.cron: %v", name, err)
+				}
+			case "command":
+				if task.args, err = splitCommandLine(strings.TrimPrefix(strings.TrimSpace(text), "strunzctl ")); err != nil {
+					return nil, invalid("tasks.%s.command: %v", name, err)
+				}
+			case "timeout", "restart":
+				d, err := time.ParseDuration(text)
+				if err != nil || d <= 0 {
+					return nil, invalid("tasks.%s.%s must be a duration like 30m, got %q", name, key, text)
+				}
+				if key == "timeout" {
+					task.timeout = d
+				} else {
+					task.restart = d
+				}
+			case "severity":
+				if !slices.Contains(alertSeverities, text) {
+					return nil, invalid("tasks.%s.severity must be one of %s", name, strings.Join(alertSeverities, ", "))
+				}
+				task.severity = text
+			default:
+				return nil, invalid("unknown setting tasks.%s.%s", name, key)
+			}
+		}
+		switch {
+		case task.spec == "":
+			return nil, invalid("tasks.%s needs a cron expression", name)
+		case len(task.args) == 0:
+			return nil, invalid("tasks.%s needs a command", name)
+		case task.args[0] == "schedule":
+			return nil, invalid("tasks.%s cannot run another scheduler", name)
+		case task.restart > 0 && !task.schedule.reboot:
+			return nil, invalid("tasks.%s: restart only applies to @reboot tasks", name)
+		}
+		file.tasks = append(file.tasks, task)
+	}
+	slices.SortFunc(file.tasks, func(a, b *scheduledTask) int { return strings.Compare(a.name, b.name) })
+	return file, nil
+}
+
+// splitCommandLine splits a command into arguments at unquoted spaces.
+// Single quotes keep everything literally, double quotes and backslashes
+// as in a POSIX shell; nothing is expanded.
+func splitCommandLine(line string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	inArg, escaped := false, false
+	var quote rune
+	for _, r := range line {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\\':
+			escaped, inArg = true, true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inArg = r, true
+		case r == ' ' || r == '\t':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, fmt.Errorf("unterminated quote or escape in %q", line)
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+func newScheduleCommand() *Command {
+	cmd := newCommand("schedule", "", "Run the commands of a schedule file on cron expressions in one long-lived process, with a log per task and alerts on failures.")
+	path := cmd.Flags.String("config", "schedule.yaml", "schedule file of the tasks to run")
+	notify := cmd.Flags.Bool("notify-webhook", false, "alert webhooks.notify and the alerts sinks when a task fails, and resolve the alert once it succeeds again")
+	check := cmd.Flags.Bool("check", false, "validate the file and print the next runs of each task instead of running them")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 0); err != nil {
+			return err
+		}
+		file, err := parseScheduleFile(*path)
+		if err != nil {
+			return err
+		}
+		if *check {
+			printSchedule(file, time.Now())
+			return nil
+		}
+		notifier, err := newNotifier(*notify)
+		if err != nil {
+			return err
+		}
+		runner, err := newScheduleRunner(file, notifier)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("\n⏰ Running %d task(s) from %s, logs in %s (Ctrl-C to stop)\n\n", len(file.tasks), *path, runner.logDir)
+		var wg sync.WaitGroup
+		for _, task := range file.tasks {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				runner.loop(task)
+			}()
+		}
+		wg.Wait()
+		if err := context.Cause(commandContext); err != nil {
+			return err
+		}
+		fmt.Println("\n✅ Every task has finished and none is scheduled again")
+		return nil
+	}
+	return cmd
+}
+
+// printSchedule lists each task with its next runs
+func printSchedule(file *scheduleFile, now time.Time) {
+	fmt.Printf("\n⏰ %d task(s), times in %s\n", len(file.tasks), file.location)
+	for _, task := range file.tasks {
+		fmt.Printf("\n%s: strunzctl %s\n", task.name, strings.Join(task.args, " "))
+		switch {
+		case task.schedule.reboot && task.restart > 0:
+			fmt.Printf("  at start, restarted %s after it exits\n", task.restart)
+		case task.schedule.reboot:
+			fmt.Println("  once at start")
+		default:
+			at := now.In(file.location)
+			for range 3 {
+				if at = task.schedule.next(at); at.IsZero() {
+					fmt.Printf("  ⚠️  %q never matches\n", task.spec)
+					break
+				}
+				fmt.Printf("  %s\n", at.Format("Mon 2006-01-02 15:04 MST"))
+			}
+		}
+		if task.timeout > 0 {
+			fmt.Printf("  timeout %s\n", task.timeout)
+		}
+	}
+}
+
+// scheduleRunner runs the tasks of a schedule file as subprocesses of the
+// same binary, so every run starts with fresh options and its own exit code
+type scheduleRunner struct {
+	file       *scheduleFile
+	executable string
+	// global are the root options the tasks inherit
+	global   []string
+	logDir   string
+	notifier *Notifier
+	// mu serializes alerts, whose dedup history is one file, and guards
+	// failing
+	mu      sync.Mutex
+	failing map[string]bool
+}
+
+func newScheduleRunner(file *scheduleFile, notifier *Notifier) (*scheduleRunner, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("cannot locate the strunzctl binary: %w", err)
+	}
+	logDir := file.logDir
+	if logDir == "" {
+		if logDir, err = cacheDir("schedule"); err != nil {
+			return nil, err
+		}
+	} else if err := os.MkdirAll(logDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	return &scheduleRunner{file: file, executable: executable, global: inheritedOptions(), logDir: logDir, notifier: notifier, failing: make(map[string]bool)}, nil
+}
+
+// inheritedOptions are the root options a strunzctl subprocess gets from
+// the process that starts it: the config file, trust and output settings
+func inheritedOptions() []string {
+	var options []string
+	for _, option := range []struct{ name, value string }{
+		{"--config", globalOptions.configPath},
+		{"--ca-bundle", globalOptions.caBundle},
+		{"--lang", globalOptions.lang},
+		{"--log-format", globalOptions.logFormat},
+	} {
+		if option.value != "" {
+			options = append(options, option.name, option.value)
+		}
+	}
+	if globalOptions.verbose {
+		options = append(options, "--verbose")
+	}
+	if globalOptions.quiet {
+		options = append(options, "--quiet")
+	}
+	return options
+}
+
+// loop runs a task whenever its schedule fires until commandContext ends.
+// A run that overlaps the next firing time delays it rather than running
+// twice at once.
+func (r *scheduleRunner) loop(task *scheduledTask) {
+	if task.schedule.reboot {
+		for {
+			r.run(task)
+			if task.restart == 0 || sleepContext(task.restart) != nil {
+				return
+			}
+		}
+	}
+	for {
+		next := task.schedule.next(time.Now().In(r.file.location))
+		if next.IsZero() {
+			slog.Warn("Task never runs", "task", task.name, "cron", task.spec)
+			return
+		}
+		slog.Info("Task scheduled", "task", task.name, "next", next.Format(time.RFC3339))
+		if sleepContext(time.Until(next)) != nil {
+			return
+		}
+		r.run(task)
+	}
+}
+
+// run executes a task once, appending its output to the task's log and
+// echoing it prefixed with the task name
+func (r *scheduleRunner) run(task *scheduledTask) {
+	logPath := filepath.Join(r.logDir, task.name+".log")
+	if info, err := os.Stat(logPath); err == nil && info.Size() > maxTaskLogSize {
+		os.Rename(logPath, logPath+".1")
+	}
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		slog.Error("Cannot open the task log, skipping the run", "task", task.name, "path", logPath, "error", err)
+		return
+	}
+	defer logFile.Close()
+
+	args := slices.Clone(r.global)
+	if task.timeout > 0 {
+		args = append(args, "--timeout", task.timeout.String())
+	}
+	args = append(args, task.args...)
+	command := "strunzctl " + strings.Join(task.args, " ")
+	start := time.Now()
+	fmt.Fprintf(logFile, "=== %s %s\n", start.UTC().Format(time.RFC3339), command)
+	slog.Info("Task started", "task", task.name, "command", command)
+
+	output := &taskOutput{task: task.name, log: logFile}
+	process := newProcess(r.executable, args...)
+	process.Stdin = nil
+	process.Stdout, process.Stderr = output, output
+	err = process.Run()
+	output.flush()
+	duration := time.Since(start).Round(time.Second)
+
+	code := exitOK
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		code = exitErr.ExitCode()
+	} else if err != nil {
+		code = exitFailure
+		fmt.Fprintf(logFile, "%v\n", err)
+	}
+	fmt.Fprintf(logFile, "=== exit %d after %s\n", code, duration)
+
+	if commandContext.Err() != nil {
+		// Stopping the scheduler stops its tasks; that is no failure
+		slog.Info("Task stopped", "task", task.name, "duration", duration)
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key, wasFailing := "schedule "+task.name, r.failing[task.name]
+	if code == exitOK {
+		slog.Info("Task finished", "task", task.name, "duration", duration)
+		if wasFailing {
+			r.notifier.resolve(key, "Scheduled task "+task.name+" recovered", fmt.Sprintf("`%s` succeeded after %s", command, duration))
+		}
+		delete(r.failing, task.name)
+		return
+	}
+	slog.Error("Task failed", "task", task.name, "exit_code", code, "duration", duration, "log", logPath)
+	r.failing[task.name] = true
+	message := fmt.Sprintf("`%s` exited %d (%s) after %s; log: %s", command, code, orNone(errorKinds[code]), duration, logPath)
+	if tail := output.tail(); tail != "" {
+		message += "\n\n" + tail
+	}
+	r.notifier.alert(task.severity, key, "Scheduled task "+task.name+" failed", message)
+}
+
+// taskOutputTail is how many of the last output lines an alert quotes
+const taskOutputTail = 10
+
+// taskOutput writes the output of a run line by line to the task log and,
+// prefixed with the task name, to stdout, keeping the last lines for the
+// alert. os/exec gives stdout and stderr one writer, so Write is never
+// called concurrently for one run.
+type taskOutput struct {
+	task    string
+	log     *os.File
+	partial []byte
+	last    []string
+}
+
+func (o *taskOutput) Write(p []byte) (int, error) {
+	o.partial = append(o.partial, p...)
+	for {
+		i := slices.Index(o.partial, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		o.line(string(o.partial[:i]))
+		o.partial = o.partial[i+1:]
+	}
+}
+
+// flush writes a last line without a newline
+func (o *taskOutput) flush() {
+	if len(o.partial) > 0 {
+		o.line(string(o.partial))
+		o.partial = nil
+	}
+}
+
+func (o *taskOutput) line(line string) {
+	line = strings.TrimRight(line, "\r")
+	fmt.Fprintln(o.log, line)
+	fmt.Printf("[%s] %s\n", o.task, line)
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+	o.last = append(o.last, line)
+	if len(o.last) > taskOutputTail {
+		o.last = o.last[1:]
+	}
+}
+
+// tail is the last lines of output
+func (o *taskOutput) tail() string {
+	return strings.Join(o.last, "\n")
+}
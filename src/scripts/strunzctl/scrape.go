@@ -0,0 +1,222 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// scrapeUserAgent identifies the scraper to the site and to robots.txt
+const scrapeUserAgent = "strunzctl-scraper/1.0 (+https://github.com/longevitycoach/StrunzKnowledge)"
+
+var (
+	errDisallowed  = errors.New("disallowed by robots.txt")
+	errNotModified = errors.New("not modified")
+)
+
+func newScrapeCommand() *Command {
+	return newGroup("scrape", "Scrape strunz.com politely into the layout the Python processing pipeline reads.",
+		newScrapeNewsCommand(),
+	)
+}
+
+// politeFetcher spaces all requests to a site by a minimum delay, honors
+// robots.txt and retries failures with backoff
+type politeFetcher struct {
+	client *http.Client
+	delay  time.Duration
+	robots *robotsRules
+
+	mu   sync.Mutex
+	next time.Time
+
+	requests int
+}
+
+// newPoliteFetcher reads robots.txt of the site; a missing robots.txt
+// allows everything, as the convention says
+func newPoliteFetcher(site *url.URL, delay, timeout time.Duration) (*politeFetcher, error) {
+	f := &politeFetcher{client: &http.Client{Timeout: timeout}, delay: delay, robots: &robotsRules{}}
+	body, status, _, err := f.get(site.ResolveReference(&url.URL{Path: "/robots.txt"}).String(), pageVersion{})
+	switch {
+	case err != nil:
+		return nil, fmt.Errorf("failed to fetch robots.txt: %w", err)
+	case status == http.StatusOK:
+		f.robots = parseRobots(string(body), "strunzctl-scraper")
+	case status >= 500:
+		return nil, fmt.Errorf("robots.txt returned %d; not scraping a site that may disallow it", status)
+	}
+	if f.robots.crawlDelay > f.delay {
+		slog.Info("Using the crawl delay of robots.txt", "delay", f.robots.crawlDelay)
+		f.delay = f.robots.crawlDelay
+	}
+	return f, nil
+}
+
+// wait blocks until the next request slot
+func (f *politeFetcher) wait() {
+	f.mu.Lock()
+	now := time.Now()
+	at := now
+	if f.next.After(now) {
+		at = f.next
+	}
+	f.next = at.Add(f.delay)
+	f.requests++
+	f.mu.Unlock()
+	time.Sleep(at.Sub(now))
+}
+
+// Fetch returns the body and validators of a page, or errDisallowed
+// without requesting it. Given the validators of an earlier fetch it
+// returns errNotModified when the server says the page is unchanged.
+func (f *politeFetcher) Fetch(page string, since pageVersion) ([]byte, pageVersion, error) {
+	target, err := url.Parse(page)
+	if err != nil {
+		return nil, pageVersion{}, err
+	}
+	if !f.robots.allowed(target.RequestURI()) {
+		return nil, pageVersion{}, errDisallowed
+	}
+	body, status, version, err := f.get(page, since)
+	switch {
+	case err != nil:
+	case status == http.StatusNotModified:
+		err = errNotModified
+	case status != http.StatusOK:
+		err = fmt.Errorf("%s returned %d %s", page, status, http.StatusText(status))
+	}
+	return body, version, err
+}
+
+func (f *politeFetcher) get(page string, since pageVersion) ([]byte, int, pageVersion, error) {
+	f.wait()
+	resp, err := doWithRetry(f.client, func() (*http.Request, error) {
+		req, err := http.NewRequest(http.MethodGet, page, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("User-Agent", scrapeUserAgent)
+		req.Header.Set("Accept", "text/html,application/xhtml+xml;q=0.9,*/*;q=0.8")
+		req.Header.Set("Accept-Language", "de,en;q=0.9")
+		if since.ETag != "" {
+			req.Header.Set("If-None-Match", since.ETag)
+		}
+		if since.LastModified != "" {
+			req.Header.Set("If-Modified-Since", since.LastModified)
+		}
+		return req, nil
+	})
+	if err != nil {
+		return nil, 0, pageVersion{}, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 16<<20))
+	version := pageVersion{ETag: resp.Header.Get("ETag"), LastModified: resp.Header.Get("Last-Modified")}
+	return body, resp.StatusCode, version, err
+}
+
+// robotsRules are the Allow and Disallow lines of the robots.txt group
+// that applies to the scraper (RFC 9309)
+type robotsRules struct {
+	rules      []robotsRule
+	crawlDelay time.Duration
+}
+
+type robotsRule struct {
+	allow   bool
+	pattern *regexp.Regexp
+	length  int
+}
+
+// parseRobots keeps the groups naming agent, or the * groups when none do
+func parseRobots(text, agent string) *robotsRules {
+	type group struct {
+		agents []string
+		rules  []robotsRule
+		delay  time.Duration
+	}
+	var groups []*group
+	var current *group
+	inAgents := false
+	for _, line := range strings.Split(text, "\n") {
+		line, _, _ = strings.Cut(line, "#")
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+		switch key {
+		case "user-agent":
+			// Consecutive user-agent lines share one group
+			if !inAgents {
+				current = &group{}
+				groups = append(groups, current)
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+			inAgents = true
+			continue
+		case "allow", "disallow":
+			if current != nil && value != "" {
+				current.rules = append(current.rules, robotsRule{allow: key == "allow", pattern: robotsPattern(value), length: len(value)})
+			}
+		case "crawl-delay":
+			if seconds, err := strconv.ParseFloat(value, 64); err == nil && current != nil {
+				current.delay = time.Duration(seconds * float64(time.Second))
+			}
+		}
+		inAgents = false
+	}
+
+	rules := &robotsRules{}
+	for _, wildcard := range []bool{false, true} {
+		matched := false
+		for _, g := range groups {
+			for _, name := range g.agents {
+				if (wildcard && name == "*") || (!wildcard && name != "*" && strings.Contains(strings.ToLower(agent), name)) {
+					rules.rules = append(rules.rules, g.rules...)
+					rules.crawlDelay = max(rules.crawlDelay, g.delay)
+					matched = true
+					break
+				}
+			}
+		}
+		if matched {
+			break
+		}
+	}
+	return rules
+}
+
+// robotsPattern turns a path pattern with * and a trailing $ into a regexp
+func robotsPattern(pattern string) *regexp.Regexp {
+	anchored := strings.HasSuffix(pattern, "$")
+	parts := strings.Split(strings.TrimSuffix(pattern, "$"), "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	expr := "^" + strings.Join(parts, ".*")
+	if anchored {
+		expr += "$"
+	}
+	return regexp.MustCompile(expr)
+}
+
+// allowed applies the longest matching rule; Allow wins ties
+func (r *robotsRules) allowed(path string) bool {
+	best, allow := -1, true
+	for _, rule := range r.rules {
+		if rule.pattern.MatchString(path) && (rule.length > best || (rule.length == best && rule.allow)) {
+			best, allow = rule.length, rule.allow
+		}
+	}
+	return allow
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// contentManifest remembers every page a scrape saved, keyed by URL, so
+// later runs fetch only new pages and re-embed only changed ones. Unlike
+// the run state it persists between runs.
+type contentManifest struct {
+	Updated time.Time                 `json:"updated"`
+	Pages   map[string]*manifestEntry `json:"pages"`
+
+	path string
+	mu   sync.Mutex
+}
+
+type manifestEntry struct {
+	Title          string    `json:"title"`
+	SHA256         string    `json:"sha256"` // of the extracted content, not the page
+	PreviousSHA256 string    `json:"previous_sha256,omitempty"`
+	ETag           string    `json:"etag,omitempty"`
+	LastModified   string    `json:"last_modified,omitempty"`
+	FirstSeen      time.Time `json:"first_seen"`
+	Checked        time.Time `json:"checked"`
+	Changed        time.Time `json:"changed"`
+}
+
+// pageVersion holds the validators of a response for conditional requests
+type pageVersion struct {
+	ETag         string
+	LastModified string
+}
+
+func loadContentManifest(path string) (*contentManifest, error) {
+	manifest := &contentManifest{Pages: make(map[string]*manifestEntry), path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return manifest, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if manifest.Pages == nil {
+		manifest.Pages = make(map[string]*manifestEntry)
+	}
+	return manifest, nil
+}
+
+func (m *contentManifest) save() error {
+	m.mu.Lock()
+	m.Updated = time.Now()
+	data, err := json.MarshalIndent(m, "", "  ")
+	m.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(m.path, data)
+}
+
+// get returns a copy of the entry of a page, or nil for a new page
+func (m *contentManifest) get(page string) *manifestEntry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if entry, ok := m.Pages[page]; ok {
+		copied := *entry
+		return &copied
+	}
+	return nil
+}
+
+// checked notes that a page was found unchanged
+func (m *contentManifest) checked(page string, version pageVersion) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if entry, ok := m.Pages[page]; ok {
+		entry.Checked = time.Now()
+		entry.setVersion(version)
+	}
+}
+
+// update records the content of a page and reports how it compares with
+// what the manifest knew
+func (m *contentManifest) update(page, title, sum string, version pageVersion) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	entry, ok := m.Pages[page]
+	if !ok {
+		m.Pages[page] = &manifestEntry{Title: title, SHA256: sum, ETag: version.ETag, LastModified: version.LastModified,
+			FirstSeen: now, Checked: now, Changed: now}
+		return articleAdded
+	}
+	entry.Checked = now
+	entry.setVersion(version)
+	if entry.SHA256 == sum {
+		return articleUnchanged
+	}
+	entry.Title, entry.PreviousSHA256, entry.SHA256, entry.Changed = title, entry.SHA256, sum, now
+	return articleChanged
+}
+
+// remove forgets the pages and returns their last entries
+func (m *contentManifest) remove(pages []string) map[string]*manifestEntry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	removed := make(map[string]*manifestEntry)
+	for _, page := range pages {
+		if entry, ok := m.Pages[page]; ok {
+			removed[page] = entry
+			delete(m.Pages, page)
+		}
+	}
+	return removed
+}
+
+func (e *manifestEntry) version() pageVersion {
+	return pageVersion{ETag: e.ETag, LastModified: e.LastModified}
+}
+
+// setVersion keeps the old validators when a 304 response repeats none
+func (e *manifestEntry) setVersion(version pageVersion) {
+	if version.ETag != "" || version.LastModified != "" {
+		e.ETag, e.LastModified = version.ETag, version.LastModified
+	}
+}
+
+// contentHash covers what gets embedded, so layout changes around an
+// article do not count as changes
+func (a *newsArticle) contentHash() string {
+	date := ""
+	if a.Date != nil {
+		date = *a.Date
+	}
+	sum := sha256.Sum256([]byte(a.Title + "\x00" + a.Content + "\x00" + date + "\x00" + a.Author))
+	return hex.EncodeToString(sum[:])
+}
+
+// scrapeDelta is the news_delta_<timestamp>.json report of what changed
+// since the previous run: added and changed documents need embedding,
+// removed ones need dropping from the index
+type scrapeDelta struct {
+	Site      string       `json:"site"`
+	Generated string       `json:"generated"`
+	Results   string       `json:"results"`
+	Added     []deltaEntry `json:"added"`
+	Changed   []deltaEntry `json:"changed"`
+	Removed   []deltaEntry `json:"removed"`
+	Unchanged int          `json:"unchanged"`
+	Reembed   []string     `json:"reembed"`
+}
+
+type deltaEntry struct {
+	URL            string `json:"url"`
+	Title          string `json:"title"`
+	SHA256         string `json:"sha256"`
+	PreviousSHA256 string `json:"previous_sha256,omitempty"`
+}
+
+// newScrapeDelta sorts the outcomes of a run into a delta report; removed
+// holds the manifest entries of pages no longer listed or kept
+func newScrapeDelta(state *scrapeState, manifest *contentManifest, removed map[string]*manifestEntry) *scrapeDelta {
+	delta := &scrapeDelta{Site: state.Site, Generated: time.Now().Format(time.RFC3339),
+		Added: []deltaEntry{}, Changed: []deltaEntry{}, Removed: []deltaEntry{}, Reembed: []string{}}
+	for _, page := range slices.Sorted(maps.Keys(state.Visited)) {
+		entry := manifest.get(page)
+		switch state.Visited[page] {
+		case articleAdded:
+			delta.Added = append(delta.Added, deltaEntry{URL: page, Title: entry.Title, SHA256: entry.SHA256})
+			delta.Reembed = append(delta.Reembed, page)
+		case articleChanged:
+			delta.Changed = append(delta.Changed, deltaEntry{URL: page, Title: entry.Title, SHA256: entry.SHA256, PreviousSHA256: entry.PreviousSHA256})
+			delta.Reembed = append(delta.Reembed, page)
+		case articleUnchanged:
+			delta.Unchanged++
+		}
+	}
+	for _, page := range slices.Sorted(maps.Keys(removed)) {
+		delta.Removed = append(delta.Removed, deltaEntry{URL: page, Title: removed[page].Title, SHA256: removed[page].SHA256})
+	}
+	return delta
+}
+
+// writeFileAtomic writes then renames so an interrupted run never leaves
+// half a file
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.WriteFile(path+".tmp", data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
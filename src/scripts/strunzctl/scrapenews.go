@@ -0,0 +1,577 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// newsArticle is an article as src/scraper/production_scraper.py writes
+// it; the field order matches so the two outputs diff cleanly
+type newsArticle struct {
+	Title     string  `json:"title"`
+	Content   string  `json:"content"`
+	Date      *string `json:"date"`
+	Author    string  `json:"author"`
+	SourceURL string  `json:"source_url"`
+	Type      string  `json:"type"`
+	Category  string  `json:"category"`
+	ScrapedAt string  `json:"scraped_at"`
+}
+
+// scrapeResults is the layout of data/scraped/*_scraping_*.json
+type scrapeResults struct {
+	News   []newsArticle  `json:"news"`
+	Forums map[string]any `json:"forums"`
+}
+
+// Final outcomes of an article; failed articles are retried on resume
+const (
+	articleAdded      = "added"
+	articleChanged    = "changed"
+	articleUnchanged  = "unchanged"
+	articleSkipped    = "skipped"
+	articleDisallowed = "disallowed"
+)
+
+// scrapeState is what an interrupted run needs to continue where it stopped
+type scrapeState struct {
+	Site     string            `json:"site"`
+	Started  time.Time         `json:"started"`
+	Listings []string          `json:"listings"` // listing pages done
+	Pending  []string          `json:"pending"`  // listing pages still to visit
+	Visited  map[string]string `json:"visited"`  // article URL → outcome
+	Articles []newsArticle     `json:"articles"`
+
+	path string
+	mu   sync.Mutex
+}
+
+func loadScrapeState(path, site string) (*scrapeState, bool, error) {
+	state := &scrapeState{path: path}
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		state.Site, state.Started = site, time.Now()
+		state.Pending = []string{strings.TrimRight(site, "/") + "/news.html"}
+		state.Visited = make(map[string]string)
+		return state, false, nil
+	case err != nil:
+		return nil, false, err
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, false, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if state.Site != site {
+		return nil, false, fmt.Errorf("%w: %s is a run against %s; pass --fresh to start over", errUsage, path, state.Site)
+	}
+	return state, true, nil
+}
+
+func (s *scrapeState) save() error {
+	s.mu.Lock()
+	data, err := json.MarshalIndent(s, "", "  ")
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path, data)
+}
+
+// done reports whether an article needs no further attempt
+func (s *scrapeState) done(article string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Contains([]string{articleAdded, articleChanged, articleUnchanged, articleSkipped, articleDisallowed}, s.Visited[article])
+}
+
+func (s *scrapeState) record(article, outcome string, saved *newsArticle) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Visited[article] = outcome
+	if saved != nil {
+		s.Articles = append(s.Articles, *saved)
+	}
+}
+
+func newScrapeNewsCommand() *Command {
+	cmd := newCommand("news", "", "Scrape the strunz.com news archive with rate limiting, robots.txt, retries and resumable state.")
+	site := cmd.Flags.String("site", "https://www.strunz.com", "site to scrape")
+	outputDir := cmd.Flags.String("output-dir", "", "directory for news_scraping_<timestamp>.json (default: data/scraped in the repository)")
+	htmlDir := cmd.Flags.String("html-dir", "", "directory for the raw article HTML that src/rag/news_processor.py reads (default: data/raw/news in the repository, - to skip)")
+	statePath := cmd.Flags.String("state", "", "state file for resuming an interrupted run (default: news_state.json in --output-dir)")
+	manifestPath := cmd.Flags.String("manifest", "", "manifest of the articles scraped so far, URL → content hash (default: news_manifest.json in --output-dir)")
+	fresh := cmd.Flags.Bool("fresh", false, "ignore the state of an interrupted run and start over")
+	full := cmd.Flags.Bool("full", false, "fetch every article unconditionally and write all of them, not just new and changed ones")
+	recheckAfter := cmd.Flags.Duration("recheck-after", 7*24*time.Hour, "revalidate known articles last checked longer ago than this; newer ones are not requested (0: revalidate all)")
+	delay := cmd.Flags.Duration("delay", time.Second, "minimum time between requests; a longer robots.txt Crawl-delay wins")
+	concurrency := cmd.Flags.Int("concurrency", 4, "articles fetched at once, all sharing --delay")
+	maxPages := cmd.Flags.Int("max-pages", 0, "stop after this many listing pages (0: all)")
+	minScore := cmd.Flags.Float64("min-score", 0.4, "minimum quality score of an article, as in the Python scraper")
+	timeout := cmd.Flags.Duration("timeout", 30*time.Second, "timeout for each request")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 0); err != nil {
+			return err
+		}
+		siteURL, err := url.Parse(*site)
+		if err != nil || siteURL.Host == "" {
+			return fmt.Errorf("%w: invalid site URL %q", errUsage, *site)
+		}
+		if *concurrency < 1 {
+			return fmt.Errorf("%w: --concurrency must be at least 1", errUsage)
+		}
+		if *outputDir == "" {
+			if *outputDir, err = repoPath("data/scraped"); err != nil {
+				return err
+			}
+		}
+		if *htmlDir == "" {
+			if *htmlDir, err = repoPath("data/raw/news"); err != nil {
+				return err
+			}
+		}
+		if *statePath == "" {
+			*statePath = filepath.Join(*outputDir, "news_state.json")
+		}
+		if *manifestPath == "" {
+			*manifestPath = filepath.Join(*outputDir, "news_manifest.json")
+		}
+		if err := os.MkdirAll(*outputDir, 0o755); err != nil {
+			return err
+		}
+		if *fresh {
+			os.Remove(*statePath)
+		}
+		state, resumed, err := loadScrapeState(*statePath, *site)
+		if err != nil {
+			return err
+		}
+		manifest, err := loadContentManifest(*manifestPath)
+		if err != nil {
+			return err
+		}
+		fetcher, err := newPoliteFetcher(siteURL, *delay, *timeout)
+		if err != nil {
+			return err
+		}
+		save := func() error {
+			if err := manifest.save(); err != nil {
+				return err
+			}
+			return state.save()
+		}
+
+		fmt.Printf("\n🕷️  Scraping news from %s, one request per %s, %d article(s) at once\n", *site, fetcher.delay, *concurrency)
+		if resumed {
+			fmt.Printf("Resuming the run started %s: %d listing page(s) done, %d article(s) saved\n",
+				state.Started.Local().Format(time.DateTime), len(state.Listings), len(state.Articles))
+		}
+		if len(manifest.Pages) > 0 && !*full {
+			fmt.Printf("Fetching only new and changed articles; %d known from %s\n", len(manifest.Pages), *manifestPath)
+		}
+		ctx, stop := context.WithCancel(commandContext)
+		defer stop()
+
+		scraper := &newsScraper{fetcher: fetcher, state: state, manifest: manifest, htmlDir: *htmlDir, minScore: *minScore, full: *full}
+		if *htmlDir == "-" {
+			scraper.htmlDir = ""
+		} else if err := os.MkdirAll(*htmlDir, 0o755); err != nil {
+			return err
+		}
+		scrape := func(articles []string) {
+			progress := startProgress("Scraping articles", len(articles))
+			defer progress.Finish()
+			work := make(chan string)
+			var wg sync.WaitGroup
+			for range *concurrency {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for article := range work {
+						scraper.scrape(article)
+						progress.Add(1)
+					}
+				}()
+			}
+		dispatch:
+			for _, article := range articles {
+				select {
+				case work <- article:
+				case <-ctx.Done():
+					break dispatch
+				}
+			}
+			close(work)
+			wg.Wait()
+		}
+
+		// Articles that failed last time are linked from listings already done
+		var retry []string
+		for article := range state.Visited {
+			if !state.done(article) {
+				retry = append(retry, article)
+			}
+		}
+		if len(retry) > 0 {
+			slices.Sort(retry)
+			fmt.Printf("  🔁 retrying %d article(s) that failed last time\n", len(retry))
+			scrape(retry)
+			if err := save(); err != nil {
+				return err
+			}
+		}
+
+		for len(state.Pending) > 0 && ctx.Err() == nil && (*maxPages == 0 || len(state.Listings) < *maxPages) {
+			listing := state.Pending[0]
+			body, _, err := fetcher.Fetch(listing, pageVersion{})
+			if err != nil {
+				// Without the listing there is nothing to resume from
+				save()
+				return fmt.Errorf("listing %s: %w", listing, err)
+			}
+			doc := parseHTML(string(body))
+			for _, page := range newsPaginationLinks(doc, listing) {
+				if !slices.Contains(state.Listings, page) && !slices.Contains(state.Pending, page) {
+					state.Pending = append(state.Pending, page)
+				}
+			}
+			var todo []string
+			for _, article := range newsArticleLinks(doc, siteURL) {
+				switch known := manifest.get(article); {
+				case state.done(article):
+				case known != nil && !*full && *recheckAfter > 0 && time.Since(known.Checked) < *recheckAfter:
+					state.record(article, articleUnchanged, nil)
+				default:
+					todo = append(todo, article)
+				}
+			}
+			fmt.Printf("  📄 %s: %d article(s) to fetch\n", listing, len(todo))
+			scrape(todo)
+			if ctx.Err() == nil {
+				state.Listings = append(state.Listings, listing)
+				state.Pending = state.Pending[1:]
+			}
+			if err := save(); err != nil {
+				return err
+			}
+		}
+
+		outcomes := make(map[string]int)
+		for _, outcome := range state.Visited {
+			outcomes[outcome]++
+		}
+		failed := len(state.Visited) - outcomes[articleAdded] - outcomes[articleChanged] - outcomes[articleUnchanged] -
+			outcomes[articleSkipped] - outcomes[articleDisallowed]
+		fmt.Printf("\n📊 %d request(s), %d new, %d changed, %d unchanged, %d below --min-score, %d disallowed, %d failed\n",
+			fetcher.requests, outcomes[articleAdded], outcomes[articleChanged], outcomes[articleUnchanged],
+			outcomes[articleSkipped], outcomes[articleDisallowed], failed)
+		if ctx.Err() != nil {
+			fmt.Printf("\n⏸️  Interrupted; run the same command again to resume from %s\n", *statePath)
+			return nil
+		}
+
+		// Only a run over every listing knows which pages are gone
+		var gone []string
+		if len(state.Pending) == 0 {
+			for page := range manifest.Pages {
+				if outcome, ok := state.Visited[page]; !ok || outcome == articleSkipped || outcome == articleDisallowed {
+					gone = append(gone, page)
+				}
+			}
+		}
+		removed := manifest.remove(gone)
+		if err := manifest.save(); err != nil {
+			return err
+		}
+
+		stamp := time.Now().Format("20060102_150405")
+		output := filepath.Join(*outputDir, fmt.Sprintf("news_scraping_%s.json", stamp))
+		data, err := json.MarshalIndent(scrapeResults{News: state.Articles, Forums: map[string]any{}}, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(output, data, 0o644); err != nil {
+			return err
+		}
+		delta := newScrapeDelta(state, manifest, removed)
+		delta.Results = filepath.Base(output)
+		deltaPath := filepath.Join(*outputDir, fmt.Sprintf("news_delta_%s.json", stamp))
+		if data, err = json.MarshalIndent(delta, "", "  "); err != nil {
+			return err
+		}
+		if err := os.WriteFile(deltaPath, data, 0o644); err != nil {
+			return err
+		}
+		// Failed articles stay in the state so the next run retries them
+		if failed == 0 {
+			os.Remove(*statePath)
+		}
+		fmt.Printf("\n✅ %d article(s) written to %s\n", len(state.Articles), output)
+		fmt.Printf("🔁 %d new, %d changed, %d removed: %d document(s) to re-embed, listed in %s\n",
+			len(delta.Added), len(delta.Changed), len(delta.Removed), len(delta.Reembed), deltaPath)
+		if failed > 0 {
+			return fmt.Errorf("%d article(s) failed; run again to retry them from %s", failed, *statePath)
+		}
+		return nil
+	}
+	return cmd
+}
+
+// newsScraper fetches, extracts and records articles for one run
+type newsScraper struct {
+	fetcher  *politeFetcher
+	state    *scrapeState
+	manifest *contentManifest
+	htmlDir  string // "" keeps no raw HTML
+	minScore float64
+	full     bool
+}
+
+func (s *newsScraper) scrape(article string) {
+	var since pageVersion
+	if known := s.manifest.get(article); known != nil && !s.full {
+		since = known.version()
+	}
+	body, version, err := s.fetcher.Fetch(article, since)
+	switch {
+	case errors.Is(err, errDisallowed):
+		s.state.record(article, articleDisallowed, nil)
+		return
+	case errors.Is(err, errNotModified):
+		s.manifest.checked(article, version)
+		s.state.record(article, articleUnchanged, nil)
+		return
+	case err != nil:
+		progressPrintf("  ❌ %s: %v\n", article, err)
+		s.state.record(article, "failed: "+err.Error(), nil)
+		return
+	}
+	extracted := extractNewsArticle(parseHTML(string(body)), article)
+	if extracted == nil || newsQualityScore(extracted.Content) < s.minScore {
+		s.state.record(article, articleSkipped, nil)
+		return
+	}
+	if s.htmlDir != "" {
+		if err := os.WriteFile(filepath.Join(s.htmlDir, path.Base(article)), body, 0o644); err != nil {
+			s.state.record(article, "failed: "+err.Error(), nil)
+			return
+		}
+	}
+	outcome := s.manifest.update(article, extracted.Title, extracted.contentHash(), version)
+	if outcome == articleUnchanged && !s.full {
+		extracted = nil
+	}
+	s.state.record(article, outcome, extracted)
+}
+
+// newsArticleLinks returns the article URLs of a listing page, in order
+func newsArticleLinks(doc *htmlNode, site *url.URL) []string {
+	var links []string
+	for _, a := range doc.FindAll(byTag("a")) {
+		link, err := site.Parse(a.Attrs["href"])
+		if err != nil || link.Host != site.Host || !strings.Contains(link.Path, "/news/") || !strings.HasSuffix(link.Path, ".html") {
+			continue
+		}
+		link.Fragment = ""
+		if !slices.Contains(links, link.String()) {
+			links = append(links, link.String())
+		}
+	}
+	return links
+}
+
+// newsPaginationLinks returns the further listing pages (?p=N) linked
+// from a listing page
+func newsPaginationLinks(doc *htmlNode, listing string) []string {
+	base, err := url.Parse(listing)
+	if err != nil {
+		return nil
+	}
+	var pages []string
+	for _, a := range doc.FindAll(byTag("a")) {
+		link, err := base.Parse(a.Attrs["href"])
+		if err != nil || link.Host != base.Host || link.Path != base.Path {
+			continue
+		}
+		if n, err := strconv.Atoi(link.Query().Get("p")); err == nil && n > 1 && !slices.Contains(pages, link.String()) {
+			pages = append(pages, link.String())
+		}
+	}
+	return pages
+}
+
+// Selectors of the Python scraper, in the order it tries them
+var (
+	newsTitleMatchers   = []func(*htmlNode) bool{byTag("h1"), byTag("title"), byClass("article-title"), byClass("news-title"), byClass("post-title")}
+	newsContentMatchers = []func(*htmlNode) bool{
+		byClass("post-content"), byClass("article-content"), byClass("news-content"), byClass("content"),
+		byClass("post-text"), byClass("entry-content"),
+		func(n *htmlNode) bool { return n.Tag == "article" && n.Parent != nil && n.Parent.Tag == "main" },
+		byClass("main-content"),
+	}
+	newsUnwantedMatchers = []func(*htmlNode) bool{byTag("nav"), byClass("ads"), byClass("advertisement"), byClass("social-share")}
+	newsDateMatchers     = []func(*htmlNode) bool{byClass("date"), byClass("post-date"), byClass("timestamp"), byTag("time")}
+	newsAuthorMatchers   = []func(*htmlNode) bool{byClass("author"), byClass("post-author"), byClass("by-author"),
+		func(n *htmlNode) bool { return n.Tag == "span" && strings.Contains(n.Attrs["class"], "author") }}
+)
+
+// extractNewsArticle picks title, content, date and author the way the
+// Python scraper does; nil when the page has no title or content
+func extractNewsArticle(doc *htmlNode, source string) *newsArticle {
+	article := &newsArticle{SourceURL: source, Type: "news_article", Category: "news", ScrapedAt: time.Now().Format("2006-01-02T15:04:05.000000")}
+	for _, match := range newsTitleMatchers {
+		if node := doc.Find(match); node != nil {
+			if title := strings.TrimSpace(node.InnerText()); len([]rune(title)) > 5 {
+				article.Title = cleanScrapedText(title)
+				break
+			}
+		}
+	}
+	for _, match := range newsContentMatchers {
+		node := doc.Find(match)
+		if node == nil {
+			continue
+		}
+		for _, unwanted := range newsUnwantedMatchers {
+			for _, child := range node.FindAll(unwanted) {
+				child.Remove()
+			}
+		}
+		if content := cleanScrapedText(node.InnerText()); len(content) > len(article.Content) {
+			article.Content = content
+		}
+	}
+	for _, match := range newsDateMatchers {
+		if node := doc.Find(match); node != nil {
+			text := strings.TrimSpace(node.InnerText())
+			if text == "" {
+				text = node.Attrs["datetime"]
+			}
+			if date, ok := parseGermanDate(text); ok {
+				article.Date = &date
+				break
+			}
+		}
+	}
+	for _, match := range newsAuthorMatchers {
+		if node := doc.Find(match); node != nil {
+			if author := strings.TrimSpace(node.InnerText()); author != "" {
+				article.Author = cleanScrapedText(author)
+				break
+			}
+		}
+	}
+	if article.Title == "" || article.Content == "" {
+		return nil
+	}
+	return article
+}
+
+var (
+	scrapedBoilerplate = regexp.MustCompile(`(?i)Cookies?\s+akzeptieren|Datenschutz[erklärung]*|Impressum|Newsletter\s+abonnieren|Jetzt\s+registrieren|Anmelden|© \d{4}|Alle Rechte vorbehalten|Weiterlesen\s*[>»]*|Kommentare?\s*\(\d+\)`)
+	// Double-encoded UTF-8 umlauts
+	scrapedMojibake = strings.NewReplacer("Ã¤", "ä", "Ã¶", "ö", "Ã¼", "ü", "Ã„", "Ä", "Ã–", "Ö", "Ãœ", "Ü", "ÃŸ", "ß")
+)
+
+// cleanScrapedText normalizes text like the Python scraper's _clean_text
+func cleanScrapedText(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	text = scrapedBoilerplate.ReplaceAllString(text, "")
+	return strings.TrimSpace(scrapedMojibake.Replace(text))
+}
+
+var (
+	germanNumericDate = regexp.MustCompile(`(\d{1,2})\.(\d{1,2})\.(\d{4})`)
+	germanISODate     = regexp.MustCompile(`(\d{4})-(\d{1,2})-(\d{1,2})`)
+	germanNamedDate   = regexp.MustCompile(`(\d{1,2})\.?\s+(\pL+)\s+(\d{4})`)
+	germanMonths      = map[string]time.Month{
+		"januar": 1, "februar": 2, "märz": 3, "april": 4, "mai": 5, "juni": 6, "juli": 7, "august": 8,
+		"september": 9, "oktober": 10, "november": 11, "dezember": 12,
+		"jan": 1, "feb": 2, "mär": 3, "apr": 4, "jun": 6, "jul": 7, "aug": 8, "sep": 9, "okt": 10, "nov": 11, "dez": 12,
+	}
+)
+
+// parseGermanDate reads DD.MM.YYYY, YYYY-MM-DD and "12. März 2024" into
+// the ISO format Python's datetime.isoformat() gives a date
+func parseGermanDate(text string) (string, bool) {
+	var year, month, day int
+	if m := germanNumericDate.FindStringSubmatch(text); m != nil {
+		day, month, year = atoiOrZero(m[1]), atoiOrZero(m[2]), atoiOrZero(m[3])
+	} else if m := germanISODate.FindStringSubmatch(text); m != nil {
+		year, month, day = atoiOrZero(m[1]), atoiOrZero(m[2]), atoiOrZero(m[3])
+	} else if m := germanNamedDate.FindStringSubmatch(strings.ToLower(text)); m != nil && germanMonths[m[2]] != 0 {
+		day, month, year = atoiOrZero(m[1]), int(germanMonths[m[2]]), atoiOrZero(m[3])
+	} else {
+		return "", false
+	}
+	date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	// time.Date normalizes 31.02. into March
+	if date.Day() != day || int(date.Month()) != month {
+		return "", false
+	}
+	return date.Format("2006-01-02T15:04:05"), true
+}
+
+func atoiOrZero(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// newsHealthKeywords mark content as relevant in the quality score
+var newsHealthKeywords = []string{
+	"gesundheit", "ernährung", "fitness", "vitamin", "mineral", "protein",
+	"training", "körper", "bewegung", "prävention", "therapie", "studien",
+	"forschung", "wissenschaft", "medizin", "arzt", "patient", "behandlung",
+	"omega", "fettsäuren", "entzündung", "immunsystem", "blut", "stoffwechsel",
+	"hormone", "zellen", "mitochondrien", "antioxidantien", "aminosäure",
+	"krankheit", "heilung", "regeneration", "leistung", "energie", "muskeln",
+}
+
+// newsQualityScore is the Python scraper's _meets_quality_threshold score:
+// length, health relevance and structure, between 0 and 0.8
+func newsQualityScore(content string) float64 {
+	words := len(strings.Fields(content))
+	if words < 20 || len([]rune(content)) < 100 {
+		return 0
+	}
+	score := 0.0
+	switch {
+	case words >= 100:
+		score += 0.3
+	case words >= 50:
+		score += 0.2
+	case words >= 30:
+		score += 0.1
+	}
+	lower := strings.ToLower(content)
+	keywords := 0
+	for _, keyword := range newsHealthKeywords {
+		if strings.Contains(lower, keyword) {
+			keywords++
+		}
+	}
+	switch {
+	case keywords >= 3:
+		score += 0.4
+	case keywords >= 2:
+		score += 0.3
+	case keywords >= 1:
+		score += 0.2
+	}
+	if strings.ContainsAny(lower, ":?!") || strings.Contains(lower, "http") || strings.Contains(lower, "www.") {
+		score += 0.1
+	}
+	return score
+}
@@ -0,0 +1,368 @@
+package main
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// secretRule is a known credential format. The first group, when the
+// pattern has one, is the secret itself.
+type secretRule struct {
+	Name    string
+	Pattern *regexp.Regexp
+}
+
+var secretRules = []secretRule{
+	{"github-token", regexp.MustCompile(`\b(gh[pousr]_[A-Za-z0-9]{36,255})\b`)},
+	{"github-token", regexp.MustCompile(`\b(github_pat_[A-Za-z0-9_]{50,255})\b`)},
+	{"railway-token", regexp.MustCompile(`(?i)railway_?(?:api_?|project_?)?token["']?\s*[:=]\s*["']?([0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12})`)},
+	{"oauth-client-secret", regexp.MustCompile(`(?i)client_?secret["']?\s*[:=]\s*["']([^"'\s]{16,})["']`)},
+	{"oauth-client-secret", regexp.MustCompile(`\b(GOCSPX-[A-Za-z0-9_-]{28})`)},
+	{"google-api-key", regexp.MustCompile(`\b(AIza[0-9A-Za-z_-]{35})`)},
+	{"anthropic-api-key", regexp.MustCompile(`\b(sk-ant-[A-Za-z0-9_-]{32,})`)},
+	{"aws-access-key", regexp.MustCompile(`\b((?:AKIA|ASIA)[0-9A-Z]{16})\b`)},
+	{"private-key", regexp.MustCompile(`-----BEGIN (?:[A-Z]+ )?PRIVATE KEY-----`)},
+}
+
+// secretAssignment finds quoted values assigned to names that suggest a
+// credential; they count as secrets when random enough
+var secretAssignment = regexp.MustCompile(`(?i)[\w.-]*(?:secret|token|passw(?:or)?d|api_?key|credential)[\w.-]*["']?\s*[:=]\s*["']([^"'\s]{20,})["']`)
+
+// placeholderMarkers are parts of example values, not secrets
+var placeholderMarkers = []string{"example", "your", "placeholder", "changeme", "xxxx", "dummy", "redacted", "****", "${", "{{", "<"}
+
+// secretFinding is a likely secret in a file of an image layer
+type secretFinding struct {
+	Layer int
+	Path  string
+	// Line is 0 for findings about the whole file
+	Line   int
+	Rule   string
+	Secret string
+	// Removed is set when a later layer deletes the file, which leaves it
+	// in the image for anyone who pulls the layer
+	Removed bool
+}
+
+type secretScanOptions struct {
+	MaxFileSize int64
+	Entropy     float64
+	// EntropyPaths limit the entropy check to application files; installed
+	// packages are full of random-looking constants
+	EntropyPaths []string
+	Allow        []string
+}
+
+func defaultSecretScanOptions() secretScanOptions {
+	return secretScanOptions{
+		MaxFileSize:  5 << 20,
+		Entropy:      4.0,
+		EntropyPaths: []string{"app/", "root/", "home/"},
+		Allow:        splitList(config.Secrets.Allow),
+	}
+}
+
+type secretScanResult struct {
+	Image    *analyzedImage
+	Findings []secretFinding
+	Files    int
+	// Unreadable are layers in a compression the scanner cannot read
+	Unreadable []int
+}
+
+// shannonEntropy is the entropy of a string in bits per character
+func shannonEntropy(s string) float64 {
+	counts := make(map[rune]int)
+	total := 0
+	for _, r := range s {
+		counts[r]++
+		total++
+	}
+	entropy := 0.0
+	for _, count := range counts {
+		p := float64(count) / float64(total)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+func isPlaceholder(value string) bool {
+	lower := strings.ToLower(value)
+	for _, marker := range placeholderMarkers {
+		if strings.Contains(lower, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// isEnvFile matches dotenv files; templates meant for shipping are fine
+func isEnvFile(name string) bool {
+	if name != ".env" && !strings.HasPrefix(name, ".env.") {
+		return false
+	}
+	for _, suffix := range []string{".example", ".sample", ".template", ".dist"} {
+		if strings.HasSuffix(name, suffix) {
+			return false
+		}
+	}
+	return true
+}
+
+// secretAllowed matches a file against allow entries: globs of the full
+// path or the file name, or directories ending in /
+func secretAllowed(file string, allow []string) bool {
+	for _, pattern := range allow {
+		pattern = strings.TrimPrefix(pattern, "/")
+		if strings.HasSuffix(pattern, "/") && strings.HasPrefix(file, pattern) {
+			return true
+		}
+		if ok, _ := path.Match(pattern, file); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, path.Base(file)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// redactSecret keeps enough of a secret to find it again
+func redactSecret(secret string) string {
+	if len(secret) <= 8 {
+		return "****"
+	}
+	return fmt.Sprintf("%s… (%d chars)", secret[:4], len(secret))
+}
+
+// scanTextForSecrets applies the rules to each line of a text file
+func scanTextForSecrets(file string, content []byte, options secretScanOptions) []secretFinding {
+	entropy := false
+	for _, prefix := range options.EntropyPaths {
+		if prefix == "*" || strings.HasPrefix(file, prefix) {
+			entropy = true
+		}
+	}
+	var findings []secretFinding
+	for n, line := range strings.Split(string(content), "\n") {
+		matched := false
+		for _, rule := range secretRules {
+			match := rule.Pattern.FindStringSubmatch(line)
+			if match == nil {
+				continue
+			}
+			// Without a group the match is only a marker, like a PEM header
+			secret := match[0]
+			if len(match) > 1 {
+				if isPlaceholder(match[1]) {
+					continue
+				}
+				secret = redactSecret(match[1])
+			}
+			findings = append(findings, secretFinding{Path: file, Line: n + 1, Rule: rule.Name, Secret: secret})
+			matched = true
+		}
+		if matched || !entropy {
+			continue
+		}
+		for _, match := range secretAssignment.FindAllStringSubmatch(line, -1) {
+			if value := match[1]; !isPlaceholder(value) && shannonEntropy(value) >= options.Entropy {
+				findings = append(findings, secretFinding{Path: file, Line: n + 1, Rule: "high-entropy-value", Secret: redactSecret(value)})
+			}
+		}
+	}
+	return findings
+}
+
+// errUnreadableLayer marks layers compressed with something other than gzip
+var errUnreadableLayer = errors.New("unsupported layer compression")
+
+// scanLayerForSecrets reads a layer tarball. It returns the findings, the
+// number of scanned files and what the layer deletes from lower layers:
+// paths, and directories ending in / for opaque whiteouts.
+func scanLayerForSecrets(layer io.Reader, options secretScanOptions) ([]secretFinding, int, []string, error) {
+	buffered := bufio.NewReader(layer)
+	magic, _ := buffered.Peek(4)
+	var reader io.Reader = buffered
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		gz, err := gzip.NewReader(buffered)
+		if err != nil {
+			return nil, 0, nil, err
+		}
+		defer gz.Close()
+		reader = gz
+	case bytes.Equal(magic, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		return nil, 0, nil, fmt.Errorf("%w: zstd", errUnreadableLayer)
+	}
+
+	var findings []secretFinding
+	var deleted []string
+	files := 0
+	archive := tar.NewReader(reader)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, 0, nil, fmt.Errorf("failed to read layer: %w", err)
+		}
+		file := strings.TrimPrefix(path.Clean("/"+header.Name), "/")
+		dir, name := path.Split(file)
+		if name == ".wh..wh..opq" {
+			deleted = append(deleted, dir)
+			continue
+		}
+		if removed, ok := strings.CutPrefix(name, ".wh."); ok {
+			deleted = append(deleted, dir+removed)
+			continue
+		}
+		if header.Typeflag != tar.TypeReg || secretAllowed(file, options.Allow) {
+			continue
+		}
+		files++
+		if isEnvFile(name) && header.Size > 0 {
+			findings = append(findings, secretFinding{Path: file, Rule: "env-file", Secret: fmt.Sprintf("%d bytes", header.Size)})
+		}
+		if header.Size > options.MaxFileSize {
+			continue
+		}
+		content, err := io.ReadAll(archive)
+		if err != nil {
+			return nil, 0, nil, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		// Binaries would only produce noise
+		if bytes.IndexByte(content[:min(len(content), 8000)], 0) >= 0 {
+			continue
+		}
+		findings = append(findings, scanTextForSecrets(file, content, options)...)
+	}
+	return findings, files, deleted, nil
+}
+
+// scanImageSecrets streams every layer of a platform image through the
+// secret scanner. Files deleted by later layers are still scanned: deleting
+// does not remove them from the layer that added them.
+func scanImageSecrets(registry *RegistryClient, tag, platform string, options secretScanOptions) (*secretScanResult, error) {
+	image, err := analyzeImage(registry, tag, platform)
+	if err != nil {
+		return nil, err
+	}
+	type layerResult struct {
+		findings []secretFinding
+		files    int
+		deleted  []string
+		err      error
+	}
+	results := make([]layerResult, len(image.Layers))
+	err = forEachConcurrent(image.Layers, globalOptions.concurrency, func(i int, layer imageLayer) error {
+		blob, _, err := registry.OpenBlob(layer.Digest)
+		if err != nil {
+			return err
+		}
+		defer blob.Close()
+		findings, files, deleted, err := scanLayerForSecrets(blob, options)
+		if errors.Is(err, errUnreadableLayer) {
+			results[i].err = err
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("layer %d (%s): %w", i+1, shortDigest(layer.Digest), err)
+		}
+		results[i] = layerResult{findings: findings, files: files, deleted: deleted}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &secretScanResult{Image: image}
+	for i, layer := range results {
+		if layer.err != nil {
+			slog.Warn("Layer not scanned", "layer", i+1, "digest", image.Layers[i].Digest, "error", layer.err)
+			result.Unreadable = append(result.Unreadable, i+1)
+			continue
+		}
+		result.Files += layer.files
+		for _, finding := range layer.findings {
+			finding.Layer = i + 1
+			for _, later := range results[i+1:] {
+				for _, deleted := range later.deleted {
+					if finding.Path == deleted || strings.HasPrefix(finding.Path, strings.TrimSuffix(deleted, "/")+"/") {
+						finding.Removed = true
+					}
+				}
+			}
+			result.Findings = append(result.Findings, finding)
+		}
+	}
+	return result, nil
+}
+
+func printSecretFindings(result *secretScanResult) {
+	fmt.Printf("Scanned %d files in %d layers\n", result.Files, len(result.Image.Layers)-len(result.Unreadable))
+	if len(result.Unreadable) > 0 {
+		fmt.Printf("⚠️  Layers %s use a compression the scanner cannot read\n", strings.Trim(fmt.Sprint(result.Unreadable), "[]"))
+	}
+	layer := 0
+	for _, finding := range result.Findings {
+		if finding.Layer != layer {
+			layer = finding.Layer
+			fmt.Printf("\n  Layer %d %s  %s\n", layer, shortDigest(result.Image.Layers[layer-1].Digest), truncate(result.Image.Layers[layer-1].Instruction, 60))
+		}
+		location := finding.Path
+		if finding.Line > 0 {
+			location += fmt.Sprintf(":%d", finding.Line)
+		}
+		removed := ""
+		if finding.Removed {
+			removed = "  (deleted by a later layer, still in the image)"
+		}
+		fmt.Printf("    ❌ %-20s %s  %s%s\n", finding.Rule, location, finding.Secret, removed)
+	}
+}
+
+func newImageScanSecretsCommand() *Command {
+	cmd := newCommand("scan-secrets", "<tag>", "Scan the files of every image layer for credentials, high-entropy values and .env files.")
+	defaults := defaultSecretScanOptions()
+	platform := cmd.Flags.String("platform", defaultPlatform, "platform image to scan")
+	maxFileSize := cmd.Flags.Int64("max-file-size", defaults.MaxFileSize, "skip the contents of larger files")
+	entropy := cmd.Flags.Float64("entropy", defaults.Entropy, "bits per character from which an assigned value counts as a secret")
+	entropyPaths := cmd.Flags.String("entropy-paths", strings.Join(defaults.EntropyPaths, ","), "path prefixes the entropy check covers (* for all)")
+	allow := cmd.Flags.String("allow", "", "additional comma-separated paths or globs to skip; secrets.allow in the config")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 1); err != nil {
+			return err
+		}
+		options := defaultSecretScanOptions()
+		options.MaxFileSize, options.Entropy = *maxFileSize, *entropy
+		options.EntropyPaths = splitList(*entropyPaths)
+		options.Allow = append(options.Allow, splitList(*allow)...)
+
+		registry := newRegistryClient(config.Registry, imageRepository())
+		fmt.Printf("\n🔐 Scanning %s (%s) for secrets\n", registry.Reference(args[0]), *platform)
+		result, err := scanImageSecrets(registry, args[0], *platform, options)
+		if err != nil {
+			return err
+		}
+		printSecretFindings(result)
+		if len(result.Findings) > 0 {
+			return fmt.Errorf("%w: %d likely secret(s) in %s; rotate them and rebuild, or add false positives to secrets.allow", errPolicy, len(result.Findings), args[0])
+		}
+		fmt.Printf("\n✅ No secrets found in %s\n", args[0])
+		return nil
+	}
+	return cmd
+}
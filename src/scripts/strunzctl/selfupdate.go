@@ -0,0 +1,334 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// checksumsAsset lists the sha256 of every binary of a release. Its keyless
+// cosign signature is attached as the .sig and .pem assets.
+const checksumsAsset = "strunzctl_SHA256SUMS"
+
+// binaryAssetName is the release asset built for a platform
+func binaryAssetName(goos, goarch string) string {
+	name := fmt.Sprintf("strunzctl_%s_%s", goos, goarch)
+	if goos == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+// releaseAsset finds an asset of a release by name
+func releaseAsset(release *GitHubRelease, name string) (url string, ok bool) {
+	for _, asset := range release.Assets {
+		if asset.Name == name {
+			return asset.URL, true
+		}
+	}
+	return "", false
+}
+
+// latestBinaryRelease is the highest release with a binary for this
+// platform and its checksums
+func latestBinaryRelease(github *GitHubClient, prerelease bool) (*GitHubRelease, Semver, error) {
+	var latest *GitHubRelease
+	var latestVersion Semver
+	err := github.GetPages(fmt.Sprintf("/repos/%s/releases", config.Repo), func(body []byte) error {
+		var page []GitHubRelease
+		if err := json.Unmarshal(body, &page); err != nil {
+			return fmt.Errorf("failed to parse releases: %w", err)
+		}
+		for i := range page {
+			release := &page[i]
+			version, ok := parseSemver(release.TagName)
+			if !ok || release.Draft || (release.Prerelease && !prerelease) {
+				continue
+			}
+			_, hasBinary := releaseAsset(release, binaryAssetName(runtime.GOOS, runtime.GOARCH))
+			_, hasChecksums := releaseAsset(release, checksumsAsset)
+			if hasBinary && hasChecksums && (latest == nil || version.Compare(latestVersion) > 0) {
+				latest, latestVersion = release, version
+			}
+		}
+		return nil
+	})
+	return latest, latestVersion, err
+}
+
+// downloadAsset fetches the content of a release asset through the API,
+// which also works for private repositories
+func downloadAsset(github *GitHubClient, url string) ([]byte, error) {
+	client := &http.Client{Timeout: 10 * time.Minute}
+	resp, err := doWithRetry(client, func() (*http.Request, error) {
+		req, err := github.newRequest(http.MethodGet, url)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/octet-stream")
+		return req, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("download failed: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("download failed: %w", err)
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, newGitHubError(resp, body)
+	}
+	return body, nil
+}
+
+// parseChecksums reads `sha256sum` output into name → hex digest
+func parseChecksums(data []byte) map[string]string {
+	sums := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 {
+			sums[strings.TrimPrefix(fields[1], "*")] = strings.ToLower(fields[0])
+		}
+	}
+	return sums
+}
+
+// verifyChecksumsSignature checks the keyless cosign signature of the
+// checksums, made by our release workflow
+func verifyChecksumsSignature(github *GitHubClient, release *GitHubRelease, checksums []byte) error {
+	sigURL, hasSig := releaseAsset(release, checksumsAsset+".sig")
+	certURL, hasCert := releaseAsset(release, checksumsAsset+".pem")
+	if !hasSig || !hasCert {
+		return fmt.Errorf("%w: %s has no signature for its checksums, pass --skip-signature to trust them anyway", errPolicy, release.TagName)
+	}
+	if _, err := exec.LookPath("cosign"); err != nil {
+		return fmt.Errorf("%w: cosign is not installed (https://docs.sigstore.dev/cosign/system_config/installation/), or pass --skip-signature", errUsage)
+	}
+	dir, err := os.MkdirTemp("", "strunzctl-update-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{checksumsAsset: "", checksumsAsset + ".sig": sigURL, checksumsAsset + ".pem": certURL}
+	for name, url := range files {
+		data := checksums
+		if url != "" {
+			if data, err = downloadAsset(github, url); err != nil {
+				return fmt.Errorf("failed to download %s: %w", name, err)
+			}
+		}
+		if name == checksumsAsset+".pem" {
+			if err := checkSigningCertificate(data, releaseSigningIdentity, githubOIDCIssuer); err != nil {
+				return fmt.Errorf("%w: %s: %w", errPolicy, name, err)
+			}
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o600); err != nil {
+			return err
+		}
+	}
+	cmd := newProcess("cosign", "verify-blob",
+		"--signature", filepath.Join(dir, checksumsAsset+".sig"),
+		"--certificate", filepath.Join(dir, checksumsAsset+".pem"),
+		"--certificate-identity-regexp", releaseSigningIdentity,
+		"--certificate-oidc-issuer", githubOIDCIssuer,
+		filepath.Join(dir, checksumsAsset))
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%w: signature of %s does not verify: %s", errPolicy, checksumsAsset, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// Fulcio certificate extensions holding the OIDC issuer of the signer: the
+// original raw string and its DER-encoded successor
+var (
+	fulcioIssuerV1 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 1}
+	fulcioIssuerV2 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 8}
+)
+
+// checkSigningCertificate checks that a keyless signing certificate, PEM or
+// base64-encoded PEM as sign-blob writes it, was issued to a workflow
+// matching identity for a token of issuer. cosign checks the same; doing it
+// first names what is wrong with a foreign certificate.
+func checkSigningCertificate(data []byte, identity, issuer string) error {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil {
+			return fmt.Errorf("not a PEM certificate")
+		}
+		if block, _ = pem.Decode(decoded); block == nil {
+			return fmt.Errorf("not a PEM certificate")
+		}
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return err
+	}
+	certIssuer := ""
+	for _, ext := range cert.Extensions {
+		switch {
+		case ext.Id.Equal(fulcioIssuerV2):
+			if _, err := asn1.Unmarshal(ext.Value, &certIssuer); err != nil {
+				return fmt.Errorf("invalid OIDC issuer extension: %w", err)
+			}
+		case ext.Id.Equal(fulcioIssuerV1) && certIssuer == "":
+			certIssuer = string(ext.Value)
+		}
+	}
+	if certIssuer != issuer {
+		return fmt.Errorf("signed with a token of %q, not of %s", orNone(certIssuer), issuer)
+	}
+	pattern := regexp.MustCompile(identity)
+	for _, uri := range cert.URIs {
+		if pattern.MatchString(uri.String()) {
+			return nil
+		}
+	}
+	var signers []string
+	for _, uri := range cert.URIs {
+		signers = append(signers, uri.String())
+	}
+	return fmt.Errorf("signed by %s, not by the release workflow on a version tag", orNone(strings.Join(signers, ", ")))
+}
+
+// replaceExecutable swaps the running binary for data. The new file is
+// written next to it first, so the swap is a rename on the same file system.
+func replaceExecutable(data []byte) (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(exe), ".strunzctl-update-*")
+	if err != nil {
+		return "", fmt.Errorf("cannot write next to %s, rerun with the rights to replace it: %w", exe, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Chmod(tmp.Name(), 0o755); err != nil {
+		return "", err
+	}
+	// Windows cannot overwrite a running executable but can rename it
+	if runtime.GOOS == "windows" {
+		old := exe + ".old"
+		os.Remove(old)
+		if err := os.Rename(exe, old); err != nil {
+			return "", err
+		}
+	}
+	if err := os.Rename(tmp.Name(), exe); err != nil {
+		return "", fmt.Errorf("failed to replace %s: %w", exe, err)
+	}
+	return exe, nil
+}
+
+func newSelfUpdateCommand() *Command {
+	cmd := newCommand("self-update", "", "Replace this binary with the newest release built for this OS and architecture, after verifying its checksum and signature.")
+	check := cmd.Flags.Bool("check", false, "only report whether an update is available (exit 4 when it is)")
+	prerelease := cmd.Flags.Bool("prerelease", false, "also consider prereleases")
+	force := cmd.Flags.Bool("force", false, "install the latest release even if it is not newer, e.g. over a development build")
+	skipSignature := cmd.Flags.Bool("skip-signature", false, "trust the checksums without verifying their cosign signature")
+	yes := cmd.Flags.Bool("yes", false, "do not ask for confirmation")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 0); err != nil {
+			return err
+		}
+		github, err := newGitHubClient()
+		if err != nil {
+			return err
+		}
+		platform := runtime.GOOS + "/" + runtime.GOARCH
+		release, latest, err := latestBinaryRelease(github, *prerelease)
+		if err != nil {
+			return err
+		}
+		if release == nil {
+			return fmt.Errorf("no release of %s has a strunzctl binary for %s", config.Repo, platform)
+		}
+
+		current, known := parseSemver(buildVersion)
+		fmt.Printf("\n⬆️  strunzctl %s (%s), latest release %s\n", buildVersion, platform, release.TagName)
+		switch {
+		case !known && (*check || !*force):
+			fmt.Printf("⚠️  This is a development build; pass --force to replace it with %s\n", release.TagName)
+			return nil
+		case known && latest.Compare(current) <= 0 && (*check || !*force):
+			fmt.Println("✅ Up to date")
+			return nil
+		case *check:
+			fmt.Printf("⚠️  %s is available: %s\n", release.TagName, release.HTMLURL)
+			return fmt.Errorf("%w: strunzctl %s is out of date", errPolicy, buildVersion)
+		}
+
+		name := binaryAssetName(runtime.GOOS, runtime.GOARCH)
+		binaryURL, _ := releaseAsset(release, name)
+		checksumsURL, _ := releaseAsset(release, checksumsAsset)
+		checksums, err := downloadAsset(github, checksumsURL)
+		if err != nil {
+			return fmt.Errorf("failed to download %s: %w", checksumsAsset, err)
+		}
+		if *skipSignature {
+			fmt.Println("⚠️  Signature not verified (--skip-signature)")
+		} else {
+			if err := verifyChecksumsSignature(github, release, checksums); err != nil {
+				return err
+			}
+			fmt.Println("✅ Checksums signed by the release workflow")
+		}
+		want, ok := parseChecksums(checksums)[name]
+		if !ok {
+			return fmt.Errorf("%w: %s lists no checksum for %s", errPolicy, checksumsAsset, name)
+		}
+
+		ok, err = confirm(fmt.Sprintf("Replace strunzctl %s with %s?", buildVersion, release.TagName), *yes)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("update cancelled")
+		}
+		binary, err := downloadAsset(github, binaryURL)
+		if err != nil {
+			return fmt.Errorf("failed to download %s: %w", name, err)
+		}
+		sum := sha256.Sum256(binary)
+		if got := hex.EncodeToString(sum[:]); got != want {
+			return fmt.Errorf("%w: checksum of %s is %s, expected %s", errPolicy, name, got, want)
+		}
+		fmt.Println("✅ Checksum matches")
+		path, err := replaceExecutable(binary)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("\n✅ Updated %s to %s\n", path, release.TagName)
+		return nil
+	}
+	return cmd
+}
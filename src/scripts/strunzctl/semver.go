@@ -0,0 +1,108 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Semver is a parsed semantic version tag such as v2.3.0 or 1.4.0-rc.1
+type Semver struct {
+	Major, Minor, Patch int
+	Prerelease          string
+	Original            string
+}
+
+// parseSemver parses a tag with an optional "v" prefix. Build metadata is
+// ignored as it does not affect precedence.
+func parseSemver(tag string) (Semver, bool) {
+	version := Semver{Original: tag}
+	s := strings.TrimPrefix(tag, "v")
+	s, _, _ = strings.Cut(s, "+")
+	s, version.Prerelease, _ = strings.Cut(s, "-")
+
+	parts := strings.Split(s, ".")
+	if len(parts) != 3 {
+		return Semver{}, false
+	}
+	numbers := make([]int, 3)
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 || (len(part) > 1 && part[0] == '0') {
+			return Semver{}, false
+		}
+		numbers[i] = n
+	}
+	version.Major, version.Minor, version.Patch = numbers[0], numbers[1], numbers[2]
+	return version, true
+}
+
+// Compare orders versions by semver precedence
+func (v Semver) Compare(other Semver) int {
+	if c := cmp.Compare(v.Major, other.Major); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(v.Minor, other.Minor); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(v.Patch, other.Patch); c != 0 {
+		return c
+	}
+	return comparePrerelease(v.Prerelease, other.Prerelease)
+}
+
+func (v Semver) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Prerelease != "" {
+		s += "-" + v.Prerelease
+	}
+	return s
+}
+
+// comparePrerelease implements semver rule 11: a release sorts after its
+// prereleases, numeric identifiers compare numerically and sort before
+// alphanumeric ones
+func comparePrerelease(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		return 1
+	case b == "":
+		return -1
+	}
+
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < min(len(as), len(bs)); i++ {
+		an, aErr := strconv.Atoi(as[i])
+		bn, bErr := strconv.Atoi(bs[i])
+		var c int
+		switch {
+		case aErr == nil && bErr == nil:
+			c = cmp.Compare(an, bn)
+		case aErr == nil:
+			c = -1
+		case bErr == nil:
+			c = 1
+		default:
+			c = strings.Compare(as[i], bs[i])
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return cmp.Compare(len(as), len(bs))
+}
+
+// highestSemver returns the highest semver tag of a version
+func highestSemver(version PackageVersion) (Semver, bool) {
+	var best Semver
+	found := false
+	for _, tag := range version.Metadata.Container.Tags {
+		if v, ok := parseSemver(tag); ok && (!found || v.Compare(best) > 0) {
+			best, found = v, true
+		}
+	}
+	return best, found
+}
@@ -0,0 +1,533 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The tokens of `strunzctl serve` are read from the environment only, so
+// they stay out of shell history and process listings. The read token may
+// only list versions and jobs.
+const (
+	serveTokenEnv     = "STRUNZCTL_SERVE_TOKEN"
+	serveReadTokenEnv = "STRUNZCTL_SERVE_READ_TOKEN"
+)
+
+const (
+	// serveJobOutput is how much of the end of a job's output is kept
+	serveJobOutput = 64 << 10
+	// serveJobHistory is how many jobs are kept for GET /v1/jobs
+	serveJobHistory = 100
+	// serveMaxBody limits request bodies, which are small JSON objects
+	serveMaxBody = 64 << 10
+)
+
+// ociTag is the grammar of registry tags; it also keeps request values from
+// being read as flags of the job's command
+var ociTag = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9._-]{0,127}$`)
+
+// serveJob is an operation run as a strunzctl subprocess. Field values are
+// only changed under adminServer.mu; handlers return copies.
+type serveJob struct {
+	ID          string     `json:"id"`
+	Operation   string     `json:"operation"`
+	Command     string     `json:"command"`
+	RequestedBy string     `json:"requested_by,omitempty"`
+	Status      string     `json:"status"` // queued, running, succeeded or failed
+	ExitCode    *int       `json:"exit_code,omitempty"`
+	Kind        string     `json:"kind,omitempty"` // the exit code's kind, as --error-format json names it
+	Created     time.Time  `json:"created"`
+	Started     *time.Time `json:"started,omitempty"`
+	Finished    *time.Time `json:"finished,omitempty"`
+	Output      string     `json:"output,omitempty"`
+
+	args   []string
+	output *tailBuffer
+	done   chan struct{}
+}
+
+// tailBuffer keeps the last bytes written to it
+type tailBuffer struct {
+	mu   sync.Mutex
+	data []byte
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.data = append(b.data, p...)
+	if over := len(b.data) - serveJobOutput; over > 0 {
+		b.data = b.data[over:]
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return string(b.data)
+}
+
+// adminServer is the HTTP API of `strunzctl serve`
+type adminServer struct {
+	token, readToken [32]byte
+	hasReadToken     bool
+	github           *GitHubClient
+	executable       string
+	global           []string
+	// slot lets one job run at a time: promotions, rollbacks and rebuilds
+	// of one package must not interleave
+	slot chan struct{}
+	jobs sync.WaitGroup
+
+	mu     sync.Mutex
+	byID   map[string]*serveJob
+	order  []string
+	nextID int
+	mux    *http.ServeMux
+}
+
+func newServeCommand() *Command {
+	cmd := newCommand("serve", "", "Serve an authenticated HTTP/JSON API to list versions, promote, roll back, smoke test and rebuild the knowledge base, for dashboards and chat bots.")
+	listen := cmd.Flags.String("listen", "127.0.0.1:8090", "address to listen on")
+	tlsCert := cmd.Flags.String("tls-cert", "", "PEM certificate to serve HTTPS with (with --tls-key)")
+	tlsKey := cmd.Flags.String("tls-key", "", "PEM private key of --tls-cert")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 0); err != nil {
+			return err
+		}
+		if (*tlsCert == "") != (*tlsKey == "") {
+			return fmt.Errorf("%w: --tls-cert and --tls-key go together", errUsage)
+		}
+		token := os.Getenv(serveTokenEnv)
+		if len(token) < 16 {
+			return fmt.Errorf("%w: set %s to a random token of at least 16 characters, e.g. `openssl rand -hex 32`", errUsage, serveTokenEnv)
+		}
+		github, err := newGitHubClient()
+		if err != nil {
+			return err
+		}
+		executable, err := os.Executable()
+		if err != nil {
+			return fmt.Errorf("cannot locate the strunzctl binary: %w", err)
+		}
+
+		admin := &adminServer{
+			token:      sha256.Sum256([]byte(token)),
+			github:     github,
+			executable: executable,
+			global:     inheritedOptions(),
+			slot:       make(chan struct{}, 1),
+			byID:       make(map[string]*serveJob),
+		}
+		if readToken := os.Getenv(serveReadTokenEnv); readToken != "" {
+			admin.readToken, admin.hasReadToken = sha256.Sum256([]byte(readToken)), true
+		}
+		admin.routes()
+
+		listener, err := net.Listen("tcp", *listen)
+		if err != nil {
+			return fmt.Errorf("failed to listen: %w", err)
+		}
+		// No write timeout: ?wait=true holds the response until the job ends
+		server := &http.Server{
+			Handler:           admin,
+			ReadHeaderTimeout: 10 * time.Second,
+			IdleTimeout:       2 * time.Minute,
+			MaxHeaderBytes:    64 << 10,
+		}
+		ctx, stop := context.WithCancel(commandContext)
+		defer stop()
+		go func() {
+			<-ctx.Done()
+			server.Close()
+		}()
+
+		scheme := "http"
+		if *tlsCert != "" {
+			scheme = "https"
+		}
+		fmt.Printf("\n🛠️  Admin API %s://%s for %s (Ctrl-C to stop)\n", scheme, listener.Addr(), imageRepository())
+		fmt.Println("  GET /v1/versions, POST /v1/promote, /v1/rollback, /v1/smoke, /v1/kb/rebuild, GET /v1/jobs[/{id}]")
+		if *tlsCert != "" {
+			err = server.ServeTLS(listener, *tlsCert, *tlsKey)
+		} else {
+			err = server.Serve(listener)
+		}
+		if !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		// Running jobs got SIGINT from commandContext; let them clean up
+		admin.jobs.Wait()
+		return nil
+	}
+	return cmd
+}
+
+func (s *adminServer) routes() {
+	s.mux = http.NewServeMux()
+	s.mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		serveJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	s.mux.HandleFunc("GET /v1/versions", s.listVersions)
+	s.mux.HandleFunc("GET /v1/jobs", s.listJobs)
+	s.mux.HandleFunc("GET /v1/jobs/{id}", s.getJob)
+	s.mux.HandleFunc("POST /v1/promote", s.operation("promote", promoteJob))
+	s.mux.HandleFunc("POST /v1/rollback", s.operation("rollback", rollbackJob))
+	s.mux.HandleFunc("POST /v1/smoke", s.operation("smoke", smokeJob))
+	s.mux.HandleFunc("POST /v1/kb/rebuild", s.operation("kb rebuild", kbRebuildJob))
+}
+
+// ServeHTTP authenticates every request but the health check: the write
+// token may do everything, the read token only GET
+func (s *adminServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/healthz" {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		sum := sha256.Sum256([]byte(token))
+		write := ok && subtle.ConstantTimeCompare(sum[:], s.token[:]) == 1
+		read := ok && s.hasReadToken && subtle.ConstantTimeCompare(sum[:], s.readToken[:]) == 1
+		switch {
+		case !write && !read:
+			w.Header().Set("WWW-Authenticate", `Bearer realm="strunzctl"`)
+			serveError(w, http.StatusUnauthorized, "missing or invalid bearer token")
+			return
+		case !write && r.Method != http.MethodGet:
+			serveError(w, http.StatusForbidden, "the read token cannot run operations")
+			return
+		}
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+func serveJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func serveError(w http.ResponseWriter, status int, message string) {
+	serveJSON(w, status, map[string]string{"error": message})
+}
+
+// listVersions returns the package versions, newest first; ?limit=N keeps
+// the first N
+func (s *adminServer) listVersions(w http.ResponseWriter, r *http.Request) {
+	limit := 0
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			serveError(w, http.StatusBadRequest, "limit must be a number of versions")
+			return
+		}
+		limit = n
+	}
+	versions, err := listPackageVersions(s.github)
+	if err != nil {
+		slog.Warn("Listing versions failed", "error", err)
+		serveError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	if limit > 0 && len(versions) > limit {
+		versions = versions[:limit]
+	}
+	type version struct {
+		ID      int64     `json:"id"`
+		Digest  string    `json:"digest"`
+		Tags    []string  `json:"tags"`
+		Created time.Time `json:"created"`
+	}
+	result := make([]version, len(versions))
+	for i, v := range versions {
+		result[i] = version{ID: v.ID, Digest: v.Name, Tags: v.Metadata.Container.Tags, Created: v.CreatedAt}
+		if result[i].Tags == nil {
+			result[i].Tags = []string{}
+		}
+	}
+	serveJSON(w, http.StatusOK, map[string]any{"package": imageRepository(), "versions": result})
+}
+
+// Job builders validate a request body and return the strunzctl arguments
+// to run
+
+func promoteJob(body map[string]any) ([]string, error) {
+	var request struct {
+		Source string `json:"source"`
+		Dest   string `json:"dest"`
+		DryRun bool   `json:"dry_run"`
+	}
+	if err := decodeJobRequest(body, &request); err != nil {
+		return nil, err
+	}
+	if !ociTag.MatchString(request.Source) || !ociTag.MatchString(request.Dest) {
+		return nil, errors.New("source and dest must be tags")
+	}
+	args := []string{"image", "promote", request.Source, request.Dest}
+	if request.DryRun {
+		args = append(args, "--dry-run")
+	}
+	return args, nil
+}
+
+func rollbackJob(body map[string]any) ([]string, error) {
+	var request struct {
+		To            string `json:"to"`
+		Service       string `json:"service"`
+		ProductionTag string `json:"production_tag"`
+	}
+	if err := decodeJobRequest(body, &request); err != nil {
+		return nil, err
+	}
+	args := []string{"deploy", "rollback", "--yes"}
+	for _, option := range []struct{ flag, value string }{
+		{"--to", request.To},
+		{"--service", request.Service},
+		{"--production-tag", request.ProductionTag},
+	} {
+		if option.value == "" {
+			continue
+		}
+		if !ociTag.MatchString(option.value) {
+			return nil, fmt.Errorf("%s is not a valid name", strings.TrimPrefix(option.flag, "--"))
+		}
+		args = append(args, option.flag+"="+option.value)
+	}
+	return args, nil
+}
+
+// smokeJob smoke tests an image tag locally or a running server by URL
+func smokeJob(body map[string]any) ([]string, error) {
+	var request struct {
+		Tag string `json:"tag"`
+		URL string `json:"url"`
+	}
+	if err := decodeJobRequest(body, &request); err != nil {
+		return nil, err
+	}
+	switch {
+	case (request.Tag == "") == (request.URL == ""):
+		return nil, errors.New("pass either tag or url")
+	case request.Tag != "":
+		if !ociTag.MatchString(request.Tag) {
+			return nil, errors.New("tag is not a valid tag")
+		}
+		return []string{"image", "smoke", request.Tag}, nil
+	}
+	target, err := url.Parse(request.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return nil, errors.New("url must be an http or https URL")
+	}
+	return []string{"mcp", "smoke", target.String()}, nil
+}
+
+func kbRebuildJob(body map[string]any) ([]string, error) {
+	var request struct {
+		Upload string `json:"upload"`
+		DryRun bool   `json:"dry_run"`
+	}
+	if err := decodeJobRequest(body, &request); err != nil {
+		return nil, err
+	}
+	args := []string{"kb", "rebuild"}
+	if request.DryRun {
+		args = append(args, "--dry-run")
+	}
+	if request.Upload != "" {
+		if !ociTag.MatchString(request.Upload) {
+			return nil, errors.New("upload must be a release tag")
+		}
+		args = append(args, "--upload="+request.Upload)
+	}
+	return args, nil
+}
+
+// decodeJobRequest decodes the operation's fields of a request body;
+// requested_by is common to all operations
+func decodeJobRequest(body map[string]any, v any) error {
+	delete(body, "requested_by")
+	data, _ := json.Marshal(body)
+	decoder := json.NewDecoder(strings.NewReader(string(data)))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+// operation starts a job from a JSON request. It answers 202 with the job
+// at once, or with ?wait=true 200 once the job has finished.
+func (s *adminServer) operation(name string, build func(map[string]any) ([]string, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body := map[string]any{}
+		if r.ContentLength != 0 {
+			decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, serveMaxBody))
+			if err := decoder.Decode(&body); err != nil {
+				serveError(w, http.StatusBadRequest, "body must be a JSON object: "+err.Error())
+				return
+			}
+		}
+		requestedBy, _ := body["requested_by"].(string)
+		if len(requestedBy) > 100 || strings.ContainsFunc(requestedBy, func(r rune) bool { return r < ' ' }) {
+			serveError(w, http.StatusBadRequest, "requested_by must be a short name")
+			return
+		}
+		args, err := build(body)
+		if err != nil {
+			serveError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		job := s.start(name, args, requestedBy)
+		slog.Info("Job queued", "job", job.ID, "operation", name, "requested_by", orNone(requestedBy), "remote", r.RemoteAddr)
+		w.Header().Set("Location", "/v1/jobs/"+job.ID)
+		if wait, _ := strconv.ParseBool(r.URL.Query().Get("wait")); wait {
+			select {
+			case <-job.done:
+			case <-r.Context().Done():
+				return
+			}
+			serveJSON(w, http.StatusOK, s.snapshot(job))
+			return
+		}
+		serveJSON(w, http.StatusAccepted, s.snapshot(job))
+	}
+}
+
+// start queues a job and runs it once the slot is free
+func (s *adminServer) start(operation string, args []string, requestedBy string) *serveJob {
+	s.mu.Lock()
+	s.nextID++
+	job := &serveJob{
+		ID:          strconv.Itoa(s.nextID),
+		Operation:   operation,
+		Command:     "strunzctl " + strings.Join(args, " "),
+		RequestedBy: requestedBy,
+		Status:      "queued",
+		Created:     time.Now().UTC(),
+		args:        args,
+		output:      &tailBuffer{},
+		done:        make(chan struct{}),
+	}
+	s.byID[job.ID] = job
+	s.order = append(s.order, job.ID)
+	s.prune()
+	s.mu.Unlock()
+
+	s.jobs.Add(1)
+	go s.run(job)
+	return job
+}
+
+// prune forgets the oldest finished jobs beyond serveJobHistory; the
+// caller holds mu
+func (s *adminServer) prune() {
+	for i := 0; len(s.order) > serveJobHistory && i < len(s.order); {
+		if job := s.byID[s.order[i]]; job.Finished != nil {
+			delete(s.byID, job.ID)
+			s.order = slices.Delete(s.order, i, i+1)
+			continue
+		}
+		i++
+	}
+}
+
+func (s *adminServer) run(job *serveJob) {
+	defer s.jobs.Done()
+	defer close(job.done)
+	finish := func(code int) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		now := time.Now().UTC()
+		job.Finished, job.ExitCode, job.Kind = &now, &code, errorKinds[code]
+		job.Status = "succeeded"
+		if code != exitOK {
+			job.Status = "failed"
+		}
+	}
+
+	select {
+	case s.slot <- struct{}{}:
+		defer func() { <-s.slot }()
+	case <-commandContext.Done():
+		finish(exitInterrupted)
+		return
+	}
+	s.mu.Lock()
+	now := time.Now().UTC()
+	job.Started, job.Status = &now, "running"
+	s.mu.Unlock()
+
+	who := "strunzctl serve"
+	if job.RequestedBy != "" {
+		who = job.RequestedBy + " via strunzctl serve"
+	}
+	slog.Info("Job started", "job", job.ID, "command", job.Command)
+	process := newProcess(s.executable, append(slices.Clone(s.global), job.args...)...)
+	// The jobs need no API tokens of their own server
+	process.Env = slices.DeleteFunc(os.Environ(), func(variable string) bool {
+		return strings.HasPrefix(variable, serveTokenEnv+"=") || strings.HasPrefix(variable, serveReadTokenEnv+"=")
+	})
+	process.Env = append(process.Env, operatorEnv+"="+who)
+	process.Stdin = nil
+	process.Stdout, process.Stderr = job.output, job.output
+	err := process.Run()
+
+	code := exitOK
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		code = exitErr.ExitCode()
+	} else if err != nil {
+		code = exitFailure
+		fmt.Fprintf(job.output, "%v\n", err)
+	}
+	finish(code)
+	if code == exitOK {
+		slog.Info("Job succeeded", "job", job.ID, "operation", job.Operation)
+	} else {
+		slog.Warn("Job failed", "job", job.ID, "operation", job.Operation, "exit_code", code)
+	}
+}
+
+// snapshot copies a job with its output so far
+func (s *adminServer) snapshot(job *serveJob) serveJob {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	copied := *job
+	copied.Output = job.output.String()
+	return copied
+}
+
+// listJobs returns the kept jobs, newest first, without their output
+func (s *adminServer) listJobs(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	jobs := make([]serveJob, 0, len(s.order))
+	for i := len(s.order) - 1; i >= 0; i-- {
+		jobs = append(jobs, *s.byID[s.order[i]])
+	}
+	s.mu.Unlock()
+	serveJSON(w, http.StatusOK, map[string]any{"jobs": jobs})
+}
+
+func (s *adminServer) getJob(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	job, ok := s.byID[r.PathValue("id")]
+	s.mu.Unlock()
+	if !ok {
+		serveError(w, http.StatusNotFound, "no such job")
+		return
+	}
+	serveJSON(w, http.StatusOK, s.snapshot(job))
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Keyless signing identities of our GitHub Actions workflows: the images
+// are signed by docker-publish.yml on main and on tags, the checksums of
+// the strunzctl binaries only by its release job on version tags
+const (
+	githubOIDCIssuer       = "https://token.actions.githubusercontent.com"
+	defaultSigningIdentity = `^https://github\.com/longevitycoach/StrunzKnowledge/\.github/workflows/docker-publish\.yml@refs/(heads/main|tags/v.+)$`
+	releaseSigningIdentity = `^https://github\.com/longevitycoach/StrunzKnowledge/\.github/workflows/docker-publish\.yml@refs/tags/v`
+)
+
+// cosignVerification is one entry of `cosign verify --output json`
+type cosignVerification struct {
+	Critical struct {
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+	} `json:"critical"`
+	Optional map[string]any `json:"optional"`
+}
+
+func newImageVerifySignatureCommand() *Command {
+	cmd := newCommand("verify-signature", "<tag>", "Verify cosign signatures and attestations of a published image.")
+	key := cmd.Flags.String("key", "", "cosign public key file (default: keyless GitHub OIDC verification)")
+	identity := cmd.Flags.String("identity", defaultSigningIdentity, "certificate identity regexp for keyless verification")
+	issuer := cmd.Flags.String("issuer", githubOIDCIssuer, "OIDC issuer for keyless verification")
+	attestationType := cmd.Flags.String("attestation-type", "", "also verify an attestation of this type (e.g. spdxjson, slsaprovenance)")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 1); err != nil {
+			return err
+		}
+		tag := args[0]
+		registry := newRegistryClient(config.Registry, imageRepository())
+
+		// Verify the digest the tag points to now, so a retag during
+		// verification cannot slip an unsigned image through
+		manifest, err := registry.GetManifest(tag)
+		if err != nil {
+			return err
+		}
+		reference := registry.Reference(manifest.Digest)
+
+		fmt.Printf("\n🔏 Verifying signature of %s\n", registry.Reference(tag))
+		fmt.Printf("Digest: %s\n", manifest.Digest)
+
+		if _, err := registry.GetManifest(cosignTag(manifest.Digest, "sig")); isNotFound(err) {
+			fmt.Println("\n❌ No cosign signature found for this digest")
+			return fmt.Errorf("%w: image %s is not signed", errPolicy, tag)
+		} else if err != nil {
+			return err
+		}
+
+		verifyArgs := cosignVerifyArgs(*key, *identity, *issuer)
+		signatures, err := runCosignVerify("verify", append(verifyArgs, reference)...)
+		if err != nil {
+			fmt.Println("\n❌ Signature verification failed")
+			return fmt.Errorf("%w: %w", errPolicy, err)
+		}
+		fmt.Printf("\n✅ %d valid signature(s)\n", len(signatures))
+		for _, sig := range signatures {
+			fmt.Printf("  - digest %s", shortDigest(sig.Critical.Image.DockerManifestDigest))
+			if subject, ok := sig.Optional["Subject"]; ok {
+				fmt.Printf(", subject %v", subject)
+			}
+			if sigIssuer, ok := sig.Optional["Issuer"]; ok {
+				fmt.Printf(", issuer %v", sigIssuer)
+			}
+			fmt.Println()
+		}
+
+		if *attestationType != "" {
+			attestArgs := append([]string{"--type", *attestationType}, verifyArgs...)
+			if _, err := runCosignVerify("verify-attestation", append(attestArgs, reference)...); err != nil {
+				fmt.Printf("\n❌ %s attestation verification failed\n", *attestationType)
+				return fmt.Errorf("%w: %w", errPolicy, err)
+			}
+			fmt.Printf("✅ %s attestation verified\n", *attestationType)
+		}
+		return nil
+	}
+	return cmd
+}
+
+// cosignVerifyArgs selects key-based or keyless verification
+func cosignVerifyArgs(key, identity, issuer string) []string {
+	if key != "" {
+		return []string{"--key", key}
+	}
+	return []string{"--certificate-identity-regexp", identity, "--certificate-oidc-issuer", issuer}
+}
+
+// runCosignVerify runs `cosign verify` or `cosign verify-attestation` and
+// parses the verified payloads. Registry credentials come from the Docker
+// config, as with every cosign invocation.
+func runCosignVerify(subcommand string, args ...string) ([]cosignVerification, error) {
+	cmd := newProcess("cosign", append([]string{subcommand, "--output", "json"}, args...)...)
+	cmd.Stderr = os.Stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("cosign %s failed: %w", subcommand, err)
+	}
+
+	// verify prints a JSON array, verify-attestation one JSON object per line
+	var verifications []cosignVerification
+	if json.Unmarshal(output, &verifications) == nil {
+		return verifications, nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(output))
+	for decoder.More() {
+		var v cosignVerification
+		if err := decoder.Decode(&v); err != nil {
+			break
+		}
+		verifications = append(verifications, v)
+	}
+	return verifications, nil
+}
+
+// inGitHubActions reports whether the workflow can request an OIDC token
+// for keyless signing, which needs `permissions: id-token: write`
+func inGitHubActions() (bool, error) {
+	if os.Getenv("GITHUB_ACTIONS") != "true" {
+		return false, nil
+	}
+	if !actionsOIDCAvailable() {
+		return false, fmt.Errorf("%w: keyless signing in GitHub Actions needs `permissions: id-token: write` on the job", errAuth)
+	}
+	return true, nil
+}
+
+func newImageSignCommand() *Command {
+	cmd := newCommand("sign", "<tag>", "Sign a published image with cosign, keyless with the workflow identity in GitHub Actions or with a key file locally.")
+	key := cmd.Flags.String("key", "", "cosign private key file; COSIGN_PASSWORD holds its password (default in GitHub Actions: keyless)")
+	recursive := cmd.Flags.Bool("recursive", true, "also sign every platform image of a manifest list")
+	noVerify := cmd.Flags.Bool("no-verify", false, "skip verifying the signature after signing")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 1); err != nil {
+			return err
+		}
+		tag := args[0]
+		keyless, err := inGitHubActions()
+		if err != nil {
+			return err
+		}
+		if *key != "" {
+			keyless = false
+		} else if !keyless {
+			return fmt.Errorf("%w: outside GitHub Actions pass --key (create one with `cosign generate-key-pair`)", errUsage)
+		}
+		if _, err := exec.LookPath("cosign"); err != nil {
+			return fmt.Errorf("cosign is not installed; see https://docs.sigstore.dev/cosign/system_config/installation/")
+		}
+
+		// Sign the digest, not the tag, so the signature covers exactly the
+		// image that was checked
+		registry := newRegistryClient(config.Registry, imageRepository())
+		manifest, err := registry.GetManifest(tag)
+		if err != nil {
+			return err
+		}
+		reference := registry.Reference(manifest.Digest)
+		signArgs := []string{"sign", "--yes", "--annotations", "tag=" + tag}
+		if *recursive && manifest.IsIndex() {
+			signArgs = append(signArgs, "--recursive")
+		}
+		mode := "keyless with the GitHub Actions OIDC identity"
+		if !keyless {
+			signArgs = append(signArgs, "--key", *key)
+			mode = "with " + *key
+		}
+		fmt.Printf("\n🔏 Signing %s (%s) %s\n", registry.Reference(tag), shortDigest(manifest.Digest), mode)
+		sign := newProcess("cosign", append(signArgs, reference)...)
+		sign.Stdout, sign.Stderr = os.Stderr, os.Stderr
+		if err := sign.Run(); err != nil {
+			return fmt.Errorf("cosign sign failed: %w", err)
+		}
+		fmt.Printf("✅ Signature pushed to %s\n", registry.Reference(cosignTag(manifest.Digest, "sig")))
+		if *noVerify {
+			return nil
+		}
+
+		// The same check verify-signature runs, against the public key next
+		// to the private one
+		publicKey := ""
+		if !keyless {
+			publicKey = strings.TrimSuffix(*key, ".key") + ".pub"
+			if _, err := os.Stat(publicKey); err != nil {
+				slog.Warn("No public key next to the private key, not verifying", "expected", publicKey)
+				return nil
+			}
+		}
+		signatures, err := runCosignVerify("verify", append(cosignVerifyArgs(publicKey, defaultSigningIdentity, githubOIDCIssuer), reference)...)
+		if err != nil {
+			return fmt.Errorf("%w: the new signature does not verify: %w", errPolicy, err)
+		}
+		fmt.Printf("✅ %d valid signature(s) for %s\n", len(signatures), shortDigest(manifest.Digest))
+		return nil
+	}
+	return cmd
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// smokeTool must be advertised and answer a search; its failure mode is
+// the "Connected then Disabled" state in Claude.ai
+const smokeTool = "search_knowledge"
+
+// toolCall is a tool invocation a smoke test expects to succeed
+type toolCall struct {
+	Tool      string
+	Arguments map[string]any
+}
+
+func (c toolCall) String() string {
+	if query, ok := c.Arguments["query"]; ok {
+		return fmt.Sprintf("%s %q", c.Tool, query)
+	}
+	return c.Tool
+}
+
+// smokeToolCalls are the calls made by default
+var smokeToolCalls = []toolCall{
+	{smokeTool, map[string]any{"query": "Vitamin D", "limit": 1}},
+}
+
+// smokeCheck is the outcome of one smoke test
+type smokeCheck struct {
+	Name     string
+	Err      error
+	Duration time.Duration
+}
+
+// runSmokeTests probes a server the way Claude.ai uses it: health, the
+// start-auth path, the MCP handshake and the given tool calls. version is
+// compared with /health unless empty.
+func runSmokeTests(serverURL, version string, calls []toolCall) []smokeCheck {
+	var checks []smokeCheck
+	run := func(name string, check func() error) bool {
+		start := time.Now()
+		err := check()
+		checks = append(checks, smokeCheck{Name: name, Err: err, Duration: time.Since(start)})
+		return err == nil
+	}
+
+	run("health", func() error {
+		health, err := fetchHealth(serverURL)
+		if err != nil {
+			return err
+		}
+		if health.Status != "ok" && health.Status != "healthy" {
+			return fmt.Errorf("status is %q", health.Status)
+		}
+		if version != "" && normalizeVersion(health.Version) != normalizeVersion(version) {
+			return fmt.Errorf("version is %s, expected %s", health.Version, normalizeVersion(version))
+		}
+		return nil
+	})
+
+	run("claude.ai start-auth", func() error {
+		client := &http.Client{
+			Timeout:       30 * time.Second,
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		}
+		resp, err := client.Get(strings.TrimRight(serverURL, "/") + "/api/organizations/smoke-test/mcp/start-auth/smoke-test")
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusFound {
+			return fmt.Errorf("returned %s", resp.Status)
+		}
+		return nil
+	})
+
+	var mcp *MCPClient
+	connected := run("mcp initialize", func() error {
+		var err error
+		if mcp, err = connectMCP(serverURL, 30*time.Second); err != nil {
+			return err
+		}
+		_, err = mcp.Initialize(commandContext)
+		return err
+	})
+	if mcp != nil {
+		defer mcp.Close()
+	}
+	if !connected {
+		return checks
+	}
+
+	run("mcp tools/list", func() error {
+		tools, err := mcp.ListTools(commandContext)
+		if err != nil {
+			return err
+		}
+		if !slices.ContainsFunc(tools, func(tool MCPTool) bool { return tool.Name == smokeTool }) {
+			return fmt.Errorf("%s is not advertised (%d tools)", smokeTool, len(tools))
+		}
+		return nil
+	})
+
+	for _, call := range calls {
+		run("mcp tools/call "+call.String(), func() error {
+			result, err := mcp.CallTool(commandContext, call.Tool, call.Arguments)
+			if err != nil {
+				return err
+			}
+			// The server reports search exceptions as text, not isError
+			if text := result.Text(); result.IsError || strings.HasPrefix(text, "Search failed") {
+				return fmt.Errorf("tool returned an error: %s", text)
+			}
+			return nil
+		})
+	}
+	return checks
+}
+
+// printSmokeChecks prints the checks and returns how many failed
+func printSmokeChecks(checks []smokeCheck) int {
+	failed := 0
+	for _, check := range checks {
+		if check.Err != nil {
+			failed++
+			fmt.Printf("  ❌ %-44s %v\n", check.Name, check.Err)
+		} else {
+			fmt.Printf("  ✅ %-44s %s\n", check.Name, check.Duration.Round(time.Millisecond))
+		}
+	}
+	return failed
+}
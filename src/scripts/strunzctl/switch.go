@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// blueGreen is the state of the service pair: which one serves the domain
+type blueGreen struct {
+	active, idle railwayTarget
+	// activeDomain is nil before the first switch
+	activeDomain *RailwayCustomDomain
+}
+
+// resolveBlueGreen finds which of the two services holds the domain
+func resolveBlueGreen(railway *RailwayClient, blue, green, domain string) (*blueGreen, error) {
+	var pair [2]railwayTarget
+	for i, service := range []string{blue, green} {
+		target, err := resolveRailwayTarget(railway, service)
+		if err != nil {
+			return nil, err
+		}
+		pair[i] = target
+	}
+	if pair[0].Service == pair[1].Service {
+		return nil, fmt.Errorf("%w: blue and green must be different services", errUsage)
+	}
+
+	state := &blueGreen{active: pair[0], idle: pair[1]}
+	found := false
+	for i, target := range pair {
+		domains, err := railway.CustomDomains(target)
+		if err != nil {
+			return nil, err
+		}
+		for j := range domains {
+			if domains[j].Domain != domain {
+				continue
+			}
+			if found {
+				return nil, fmt.Errorf("%s is attached to both %s and %s", domain, pair[0].ServiceName, pair[1].ServiceName)
+			}
+			state.active, state.idle, state.activeDomain, found = target, pair[1-i], &domains[j], true
+		}
+	}
+	if !found {
+		// Nothing is live yet, so the first switch deploys to blue
+		state.active, state.idle = pair[1], pair[0]
+	}
+	return state, nil
+}
+
+func newDeploySwitchCommand() *Command {
+	cmd := newCommand("switch", "<tag>", "Deploy a tag to the idle blue/green service, check it and move the domain over, moving back if it fails there.")
+	blue := cmd.Flags.String("blue", "", "blue Railway service name or ID (default: railway.blue)")
+	green := cmd.Flags.String("green", "", "green Railway service name or ID (default: railway.green)")
+	domain := cmd.Flags.String("domain", "", "custom domain that serves production (default: railway.domain)")
+	settle := cmd.Flags.Duration("settle", 2*time.Minute, "how long the domain may take to serve the new service after the switch")
+	timeout := cmd.Flags.Duration("timeout", 15*time.Minute, "how long to wait for the deployment")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 1); err != nil {
+			return err
+		}
+		tag := args[0]
+		for _, option := range []struct {
+			value    *string
+			fallback string
+			name     string
+		}{{blue, config.Railway.Blue, "blue"}, {green, config.Railway.Green, "green"}, {domain, config.Railway.Domain, "domain"}} {
+			if *option.value == "" {
+				*option.value = option.fallback
+			}
+			if *option.value == "" {
+				return fmt.Errorf("%w: set railway.%s or --%s", errUsage, option.name, option.name)
+			}
+		}
+
+		registry := newRegistryClient(config.Registry, imageRepository())
+		image := registry.Reference(tag)
+		if _, err := registry.GetRawManifest(tag); err != nil {
+			return err
+		}
+		railway, err := newRailwayClient()
+		if err != nil {
+			return err
+		}
+		state, err := resolveBlueGreen(railway, *blue, *green, *domain)
+		if err != nil {
+			return err
+		}
+		if state.activeDomain != nil {
+			fmt.Printf("\n🔵 %s serves %s, %s is idle\n", state.active.ServiceName, *domain, state.idle.ServiceName)
+		} else {
+			fmt.Printf("\n🔵 %s is not attached yet, starting with %s\n", *domain, state.idle.ServiceName)
+		}
+
+		fmt.Printf("\n🚂 Deploying %s to %s\n", image, state.idle.ServiceName)
+		id, err := deployRailwayImage(railway, state.idle, image)
+		if err != nil {
+			return err
+		}
+		deployment, err := waitForDeployment(railway, id, *timeout, 10*time.Second)
+		if err != nil {
+			return err
+		}
+		if deployment.StaticURL == "" {
+			return fmt.Errorf("%w: %s has no Railway domain to check before the switch", errUsage, state.idle.ServiceName)
+		}
+
+		version := ""
+		if _, ok := parseSemver(tag); ok {
+			version = tag
+		}
+		idleURL := "https://" + deployment.StaticURL
+		fmt.Printf("\n🧪 Checking %s\n", idleURL)
+		if failed := printSmokeChecks(runSmokeTests(idleURL, version, smokeToolCalls)); failed > 0 {
+			return fmt.Errorf("%w: %s failed %d check(s), %s was not switched", errPolicy, state.idle.ServiceName, failed, *domain)
+		}
+
+		fmt.Printf("\n🔀 Moving %s to %s\n", *domain, state.idle.ServiceName)
+		attached, err := railway.MoveCustomDomain(*domain, state.activeDomain, state.idle)
+		if err != nil {
+			return err
+		}
+		for _, record := range attached.Status.DNSRecords {
+			if record.CurrentValue != record.RequiredValue {
+				fmt.Printf("⚠️  DNS %s %s must point to %s (currently %s)\n", record.RecordType, record.Hostlabel, record.RequiredValue, orNone(record.CurrentValue))
+			}
+		}
+		if err := recordAudit(AuditEntry{Action: auditSwitch, Tag: tag, Target: *domain + " → " + state.idle.ServiceName}); err != nil {
+			return err
+		}
+
+		publicURL := "https://" + *domain
+		fmt.Printf("\n🧪 Checking %s after the switch\n", publicURL)
+		checks := waitForSwitch(publicURL, version, *settle)
+		if failed := printSmokeChecks(checks); failed == 0 {
+			fmt.Printf("\n🎉 %s now serves %s from %s\n", *domain, tag, state.idle.ServiceName)
+			return nil
+		}
+		if state.activeDomain == nil {
+			return fmt.Errorf("%w: %s fails its checks and there is no previous service to fall back to", errPolicy, *domain)
+		}
+
+		fmt.Printf("\n↩️  Checks failed, moving %s back to %s\n", *domain, state.active.ServiceName)
+		if _, err := railway.MoveCustomDomain(*domain, attached, state.active); err != nil {
+			return fmt.Errorf("fallback failed, %s may be down: %w", *domain, err)
+		}
+		if err := recordAudit(AuditEntry{Action: auditSwitch, Target: *domain + " → " + state.active.ServiceName}); err != nil {
+			return err
+		}
+		return fmt.Errorf("%w: %s failed on %s and was switched back to %s", errPolicy, tag, *domain, state.active.ServiceName)
+	}
+	return cmd
+}
+
+// waitForSwitch repeats the smoke tests against the public domain until
+// they pass or the settle time is up, since DNS and the edge take a while
+// to route to the new service
+func waitForSwitch(url, version string, settle time.Duration) []smokeCheck {
+	deadline := time.Now().Add(settle)
+	for {
+		checks := runSmokeTests(url, version, smokeToolCalls)
+		failed := false
+		for _, check := range checks {
+			failed = failed || check.Err != nil
+		}
+		if !failed || time.Now().After(deadline) || sleepContext(10*time.Second) != nil {
+			return checks
+		}
+	}
+}
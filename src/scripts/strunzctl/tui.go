@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// browserPageSize is the number of versions shown per page
+const browserPageSize = 15
+
+func newTUICommand() *Command {
+	cmd := newCommand("tui", "", "Browse package versions interactively and promote or delete them.")
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 0); err != nil {
+			return err
+		}
+		github, err := newGitHubClient()
+		if err != nil {
+			return err
+		}
+
+		b := &versionBrowser{
+			github:   github,
+			registry: newRegistryClient(config.Registry, imageRepository()),
+			in:       bufio.NewScanner(os.Stdin),
+		}
+		if err := b.refresh(); err != nil {
+			return err
+		}
+		return b.run()
+	}
+	return cmd
+}
+
+// versionBrowser is a line-driven pager over the package versions
+type versionBrowser struct {
+	github   *GitHubClient
+	registry *RegistryClient
+	in       *bufio.Scanner
+
+	all     []PackageVersion
+	visible []PackageVersion
+	filter  string
+	page    int
+}
+
+func (b *versionBrowser) run() error {
+	b.render()
+	for {
+		line, ok := b.prompt("> ")
+		if !ok {
+			return nil
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			b.render()
+			continue
+		}
+
+		var err error
+		switch command := fields[0]; {
+		case command == "q" || command == "quit":
+			return nil
+		case command == "n":
+			b.turnPage(1)
+		case command == "p":
+			b.turnPage(-1)
+		case command == "r":
+			err = b.refresh()
+			b.render()
+		case strings.HasPrefix(command, "/"):
+			b.filter = strings.TrimSpace(strings.TrimPrefix(line, "/"))
+			b.applyFilter()
+			b.render()
+		case command == "promote" && len(fields) == 3:
+			err = b.withVersion(fields[1], func(v PackageVersion) error { return b.promote(v, fields[2]) })
+		case command == "delete" && len(fields) == 2:
+			err = b.withVersion(fields[1], b.delete)
+		case command == "?" || command == "help":
+			b.help()
+		default:
+			if _, convErr := strconv.Atoi(command); convErr == nil && len(fields) == 1 {
+				err = b.withVersion(command, b.expand)
+			} else {
+				fmt.Println("Unknown command, type ? for help")
+			}
+		}
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+		}
+	}
+}
+
+func (b *versionBrowser) help() {
+	fmt.Println(`
+Commands:
+  <number>                show tags, labels and size of a version
+  n / p                   next / previous page
+  /<text>                 show only versions with a tag containing text (/ clears)
+  promote <number> <tag>  point tag at the version's image
+  delete <number>         delete the version (asks for confirmation)
+  r                       reload versions from GitHub
+  q                       quit`)
+}
+
+func (b *versionBrowser) refresh() error {
+	fmt.Println("Loading versions...")
+	versions, err := listPackageVersions(b.github)
+	if err != nil {
+		return err
+	}
+	b.all = versions
+	b.applyFilter()
+	return nil
+}
+
+func (b *versionBrowser) applyFilter() {
+	b.visible = b.all
+	if b.filter != "" {
+		b.visible = nil
+		for _, version := range b.all {
+			for _, tag := range version.Metadata.Container.Tags {
+				if strings.Contains(tag, b.filter) {
+					b.visible = append(b.visible, version)
+					break
+				}
+			}
+		}
+	}
+	b.page = min(b.page, b.pages()-1)
+}
+
+func (b *versionBrowser) pages() int {
+	return max(1, (len(b.visible)+browserPageSize-1)/browserPageSize)
+}
+
+func (b *versionBrowser) turnPage(delta int) {
+	b.page = min(max(b.page+delta, 0), b.pages()-1)
+	b.render()
+}
+
+func (b *versionBrowser) render() {
+	if isTerminal(os.Stdout) {
+		fmt.Print("\033[H\033[2J")
+	}
+	title := fmt.Sprintf("📋 %s/%s", config.Org, config.Package)
+	if b.filter != "" {
+		title += fmt.Sprintf(" (filter: %q)", b.filter)
+	}
+	fmt.Printf("%s — %d versions, page %d/%d\n\n", title, len(b.visible), b.page+1, b.pages())
+
+	start := b.page * browserPageSize
+	for i := start; i < min(start+browserPageSize, len(b.visible)); i++ {
+		version := b.visible[i]
+		tags := strings.Join(version.Metadata.Container.Tags, ", ")
+		if tags == "" {
+			tags = "untagged"
+		}
+		fmt.Printf("%4d  %-19s %21s  %s\n", i+1, shortDigest(version.Name), version.CreatedAt.Format(time.DateTime), tags)
+	}
+	fmt.Println("\nType a number to expand, n/p to page, ? for help")
+}
+
+// withVersion resolves a 1-based index into the visible versions
+func (b *versionBrowser) withVersion(index string, fn func(PackageVersion) error) error {
+	i, err := strconv.Atoi(index)
+	if err != nil || i < 1 || i > len(b.visible) {
+		return fmt.Errorf("no version %s", index)
+	}
+	return fn(b.visible[i-1])
+}
+
+func (b *versionBrowser) expand(version PackageVersion) error {
+	fmt.Printf("\n🔎 Version %d\n", version.ID)
+	fmt.Printf("Digest: %s\n", version.Name)
+	fmt.Printf("Created: %s\n", version.CreatedAt.Format(time.RFC3339))
+	fmt.Printf("Tags: %s\n", orNone(strings.Join(version.Metadata.Container.Tags, ", ")))
+
+	size, err := b.registry.ImageSize(version.Name)
+	if err != nil {
+		return fmt.Errorf("failed to get size: %w", err)
+	}
+	fmt.Printf("Size: %s\n", formatBytes(size))
+
+	manifest, err := resolvePlatformManifest(b.registry, version.Name, defaultPlatform)
+	if err != nil {
+		return err
+	}
+	config, err := b.registry.GetImageConfig(manifest)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\nLabels (%s):\n", defaultPlatform)
+	printLabels(config.Config.Labels, false)
+	return nil
+}
+
+func (b *versionBrowser) promote(version PackageVersion, tag string) error {
+	if !b.confirm(fmt.Sprintf("Point %s at %s?", b.registry.Reference(tag), shortDigest(version.Name))) {
+		return nil
+	}
+	previous, digest, err := promoteTag(b.registry, version.Name, tag, false)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("✅ %s moved from %s to %s\n", tag, orNone(previous), digest)
+	if err := recordAudit(AuditEntry{Action: auditPromote, Tag: version.Name, Digest: digest, Target: tag}); err != nil {
+		return err
+	}
+	return b.refresh()
+}
+
+func (b *versionBrowser) delete(version PackageVersion) error {
+	tags := strings.Join(version.Metadata.Container.Tags, ", ")
+	if !b.confirm(fmt.Sprintf("Delete version %d (%s, tags: %s)? This cannot be undone.", version.ID, shortDigest(version.Name), orNone(tags))) {
+		return nil
+	}
+	path := fmt.Sprintf("/orgs/%s/packages/container/%s/versions/%d", config.Org, config.Package, version.ID)
+	if err := b.github.Delete(path); err != nil {
+		return fmt.Errorf("failed to delete version %d: %w", version.ID, err)
+	}
+	fmt.Printf("✅ Deleted version %d\n", version.ID)
+	err := recordAudit(AuditEntry{Action: auditDelete, Tag: tags, Digest: version.Name, Target: fmt.Sprintf("version %d", version.ID)})
+	if err != nil {
+		return err
+	}
+	return b.refresh()
+}
+
+func (b *versionBrowser) confirm(question string) bool {
+	answer, ok := b.prompt(question + " [y/N] ")
+	return ok && strings.EqualFold(strings.TrimSpace(answer), "y")
+}
+
+// prompt reads a line from stdin; ok is false at end of input
+func (b *versionBrowser) prompt(text string) (string, bool) {
+	fmt.Print(text)
+	if !b.in.Scan() {
+		fmt.Println()
+		return "", false
+	}
+	return b.in.Text(), true
+}
+
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	// The null device is a character device too, and what cron, systemd
+	// and CI runners connect stdin to
+	null, err := os.Stat(os.DevNull)
+	return err != nil || !os.SameFile(info, null)
+}
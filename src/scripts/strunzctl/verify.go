@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+func newReleaseVerifyCommand() *Command {
+	cmd := newCommand("verify", "<vX.Y.Z>", "Check that the git tag, the GHCR image and the deployed server agree on a release.")
+	serverURL := cmd.Flags.String("url", defaultServerURL, "base URL of the deployed server")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 1); err != nil {
+			return err
+		}
+		version, ok := parseSemver(args[0])
+		if !ok {
+			return fmt.Errorf("%w: %q is not a semantic version", errUsage, args[0])
+		}
+		tag := "v" + version.String()
+		imageTag := version.String()
+		registry := newRegistryClient(config.Registry, imageRepository())
+
+		fmt.Printf("\n🔎 Verifying release %s\n", tag)
+		var problems []string
+		fail := func(format string, args ...any) {
+			problem := fmt.Sprintf(format, args...)
+			problems = append(problems, problem)
+			fmt.Printf("❌ %s\n", problem)
+		}
+
+		commit := ""
+		if !gitTagExists(tag) {
+			fail("git tag %s does not exist locally (run `git fetch --tags`)", tag)
+		} else {
+			var err error
+			if commit, err = runGit("rev-list", "-n", "1", tag); err != nil {
+				return err
+			}
+			fmt.Printf("✅ git tag %s → %s\n", tag, commit)
+		}
+
+		revision, err := imageRevision(registry, imageTag)
+		switch {
+		case isNotFound(err):
+			fail("image %s does not exist", registry.Reference(imageTag))
+		case err != nil:
+			return err
+		case revision == "":
+			fail("image %s has no %srevision label", registry.Reference(imageTag), ociLabelPrefix)
+		case commit != "" && !sameCommit(revision, commit):
+			fail("image %s was built from %s, tag points to %s", registry.Reference(imageTag), revision, commit)
+		default:
+			fmt.Printf("✅ image %s built from %s\n", registry.Reference(imageTag), revision)
+		}
+
+		health, err := fetchHealth(*serverURL)
+		if err != nil {
+			return err
+		}
+		if normalizeVersion(health.Version) != imageTag {
+			fail("%s reports version %s", *serverURL, health.Version)
+		} else {
+			fmt.Printf("✅ %s reports version %s\n", *serverURL, health.Version)
+		}
+
+		if len(problems) > 0 {
+			return fmt.Errorf("%w: release %s is inconsistent (%d problem(s))", errPolicy, tag, len(problems))
+		}
+		fmt.Printf("\n✅ Release %s is consistent\n", tag)
+		return nil
+	}
+	return cmd
+}
+
+// imageRevision returns the commit an image tag was built from
+func imageRevision(registry *RegistryClient, tag string) (string, error) {
+	manifest, err := resolvePlatformManifest(registry, tag, defaultPlatform)
+	if err != nil {
+		return "", err
+	}
+	config, err := registry.GetImageConfig(manifest)
+	if err != nil {
+		return "", err
+	}
+	return config.Config.Labels[ociLabelPrefix+"revision"], nil
+}
+
+// sameCommit compares commit SHAs, allowing either to be abbreviated
+func sameCommit(a, b string) bool {
+	if len(a) < 7 || len(b) < 7 {
+		return false
+	}
+	return strings.HasPrefix(a, b) || strings.HasPrefix(b, a)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+// Build metadata, set by the release build with
+// -ldflags "-X main.buildVersion=vX.Y.Z -X main.buildCommit=<sha> -X main.buildDate=<RFC 3339>"
+var (
+	buildVersion = "dev"
+	buildCommit  = ""
+	buildDate    = ""
+)
+
+// buildInfo describes the running binary
+type buildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	Date      string `json:"date"`
+	GoVersion string `json:"go_version"`
+	Platform  string `json:"platform"`
+}
+
+// currentBuild returns the ldflags metadata, filling gaps from what the Go
+// toolchain records when building from a checkout
+func currentBuild() buildInfo {
+	info := buildInfo{Version: buildVersion, Commit: buildCommit, Date: buildDate,
+		GoVersion: runtime.Version(), Platform: runtime.GOOS + "/" + runtime.GOARCH}
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range build.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = setting.Value
+			case setting.Key == "vcs.time" && info.Date == "":
+				info.Date = setting.Value
+			}
+		}
+	}
+	return info
+}
+
+func newVersionCommand() *Command {
+	cmd := newCommand("version", "", "Print the version, commit, build date and Go version of this binary.")
+	output := cmd.Flags.String("output", "text", "output format: text or json")
+	cmd.Flags.StringVar(output, "format", "text", "deprecated alias of --output")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 0); err != nil {
+			return err
+		}
+		info := currentBuild()
+		switch *output {
+		case "json":
+			data, err := json.MarshalIndent(info, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+		case "text":
+			fmt.Printf("strunzctl %s\n", info.Version)
+			fmt.Printf("  commit:   %s\n", orNone(info.Commit))
+			fmt.Printf("  built:    %s\n", orNone(info.Date))
+			fmt.Printf("  go:       %s\n", info.GoVersion)
+			fmt.Printf("  platform: %s\n", info.Platform)
+		default:
+			return fmt.Errorf("%w: --output must be text or json", errUsage)
+		}
+		return nil
+	}
+	return cmd
+}
@@ -0,0 +1,346 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// watchState is the tag → digest mapping seen by the last poll, and every
+// digest each tag has pointed to since watching began
+type watchState struct {
+	Tags      map[string]string      `json:"tags"`
+	History   map[string][]tagDigest `json:"history,omitempty"`
+	CheckedAt time.Time              `json:"checked_at"`
+}
+
+// tagDigest is a digest a tag pointed to and when that was observed
+type tagDigest struct {
+	Digest    string    `json:"digest"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// TagEvent describes a tag that appeared or moved to a new digest
+type TagEvent struct {
+	Tag       string
+	Digest    string
+	Previous  string
+	CreatedAt time.Time
+	// Explained is the promotion or rollback in the audit log that moved
+	// the tag; a move without one is a silent retag
+	Explained *AuditEntry
+}
+
+// Immutable reports whether the tag is a release version. Releases must
+// never move, unlike latest, branch and major.minor tags.
+func (e TagEvent) Immutable() bool {
+	_, ok := parseSemver(e.Tag)
+	return ok
+}
+
+// SilentRetag reports whether a release tag moved without a logged
+// promotion: an unlogged rebuild or a supply-chain red flag
+func (e TagEvent) SilentRetag() bool {
+	return e.Previous != "" && e.Immutable() && e.Explained == nil
+}
+
+func newPackagesWatchCommand() *Command {
+	cmd := newCommand("watch", "", "Poll for newly published tags and report them as they appear.")
+	interval := cmd.Flags.Duration("interval", 5*time.Minute, "time between polls")
+	notify := cmd.Flags.Bool("notify-webhook", false, "post new tags to webhooks.notify and the alerts sinks")
+	once := cmd.Flags.Bool("once", false, "poll a single time, e.g. from cron; exit 4 when a release tag was silently retagged")
+
+	cmd.Run = func(args []string) error {
+		if err := cmd.ExactArgs(args, 0); err != nil {
+			return err
+		}
+		if *interval < 10*time.Second {
+			return fmt.Errorf("%w: --interval must be at least 10s", errUsage)
+		}
+		notifier, err := newNotifier(*notify)
+		if err != nil {
+			return err
+		}
+		github, err := newGitHubClient()
+		if err != nil {
+			return err
+		}
+
+		state, err := loadWatchState()
+		if err != nil {
+			return err
+		}
+		if state.Tags == nil {
+			fmt.Println("No previous state, the first poll records the current tags as the baseline.")
+		} else {
+			fmt.Printf("Last checked %s (%d tags known)\n", state.CheckedAt.Format(time.RFC3339), len(state.Tags))
+		}
+
+		if *once {
+			events, err := pollVersions(github, state, notifier)
+			if err != nil {
+				return err
+			}
+			retagged := 0
+			for _, event := range events {
+				if event.SilentRetag() {
+					retagged++
+				}
+			}
+			if retagged > 0 {
+				return fmt.Errorf("%w: %d release tag(s) point to a different digest without a logged promotion; investigate before deploying them", errPolicy, retagged)
+			}
+			return nil
+		}
+
+		fmt.Printf("\n👀 Watching %s/%s every %s (Ctrl-C to stop)\n", config.Org, config.Package, *interval)
+		for {
+			if _, err := pollVersions(github, state, notifier); err != nil {
+				// Keep watching through transient API failures
+				slog.Warn("Poll failed", "error", err)
+			}
+			if err := sleepContext(*interval); err != nil {
+				return err
+			}
+		}
+	}
+	return cmd
+}
+
+func newPackagesTagHistoryCommand() *Command {
+	cmd := newCommand("tag-history", "[tag]", "Show every digest the tags have pointed to since `packages watch` started recording them.")
+	all := cmd.Flags.Bool("all", false, "also list tags that never moved")
+
+	cmd.Run = func(args []string) error {
+		if len(args) > 1 {
+			return fmt.Errorf("%w: tag-history takes at most one tag", errUsage)
+		}
+		state, err := loadWatchState()
+		if err != nil {
+			return err
+		}
+		if len(state.History) == 0 {
+			return fmt.Errorf("no tag history recorded yet, run `strunzctl packages watch --once` first")
+		}
+		var tags []string
+		for tag, history := range state.History {
+			if len(args) == 1 && tag != args[0] || len(args) == 0 && !*all && len(history) < 2 {
+				continue
+			}
+			tags = append(tags, tag)
+		}
+		if len(args) == 1 && len(tags) == 0 {
+			return fmt.Errorf("tag %s was never seen", args[0])
+		}
+		sort.Strings(tags)
+
+		fmt.Printf("\n📜 Digest history of %s/%s (last checked %s)\n", config.Org, config.Package, state.CheckedAt.Format(time.RFC3339))
+		if len(tags) == 0 {
+			fmt.Println("  No tag has moved")
+		}
+		for _, tag := range tags {
+			history := state.History[tag]
+			marker := ""
+			if (TagEvent{Tag: tag}).Immutable() && len(history) > 1 {
+				marker = "  ⚠️  release tag moved"
+			}
+			fmt.Printf("\n  %s%s\n", tag, marker)
+			for _, seen := range history {
+				fmt.Printf("    %s  %s → %s\n", shortDigest(seen.Digest), seen.FirstSeen.Format(time.DateTime), seen.LastSeen.Format(time.DateTime))
+			}
+		}
+		return nil
+	}
+	return cmd
+}
+
+// pollVersions fetches the current versions, reports changes against state
+// and persists the new state
+func pollVersions(github *GitHubClient, state *watchState, notifier *Notifier) ([]TagEvent, error) {
+	versions, err := listPackageVersions(github)
+	if err != nil {
+		return nil, err
+	}
+
+	baseline := state.Tags == nil
+	current := make(map[string]string)
+	created := make(map[string]time.Time)
+	for _, version := range versions {
+		for _, tag := range version.Metadata.Container.Tags {
+			current[tag] = version.Name
+			created[tag] = version.CreatedAt
+		}
+	}
+
+	events := diffTags(state.Tags, current, created)
+	if err := explainTagEvents(events); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	switch {
+	case baseline:
+		fmt.Printf("[%s] Baseline: %d tags across %d versions\n", now.Format(time.TimeOnly), len(current), len(versions))
+	case len(events) == 0:
+		fmt.Printf("[%s] No new tags\n", now.Format(time.TimeOnly))
+	default:
+		for _, event := range events {
+			printTagEvent(now, event)
+			severity := severityInfo
+			if event.SilentRetag() {
+				severity = severityCritical
+			}
+			title, message := tagEventMessage(event)
+			notifier.alert(severity, "tag "+event.Tag+" "+event.Digest, title, message)
+		}
+	}
+
+	state.Tags = current
+	state.CheckedAt = now.UTC()
+	recordTagHistory(state, now.UTC())
+	return events, saveWatchState(state)
+}
+
+// recordTagHistory adds the current digest of every tag to its history
+func recordTagHistory(state *watchState, now time.Time) {
+	if state.History == nil {
+		state.History = make(map[string][]tagDigest)
+	}
+	for tag, digest := range state.Tags {
+		history := state.History[tag]
+		if n := len(history); n > 0 && history[n-1].Digest == digest {
+			history[n-1].LastSeen = now
+			continue
+		}
+		state.History[tag] = append(history, tagDigest{Digest: digest, FirstSeen: now, LastSeen: now})
+	}
+}
+
+// explainTagEvents pairs moved tags with the audit entry that moved them:
+// the newest promotion or rollback to that tag and digest
+func explainTagEvents(events []TagEvent) error {
+	moved := false
+	for _, event := range events {
+		moved = moved || event.Previous != ""
+	}
+	if !moved {
+		return nil
+	}
+	entries, err := loadAudit()
+	if err != nil {
+		return err
+	}
+	for i := range events {
+		for j := len(entries) - 1; j >= 0; j-- {
+			entry := entries[j]
+			if (entry.Action == auditPromote || entry.Action == auditRollback) && entry.Target == events[i].Tag && entry.Digest == events[i].Digest {
+				events[i].Explained = &entries[j]
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// diffTags returns tags that are new or point to a different digest,
+// oldest first. A nil previous map yields no events.
+func diffTags(previous, current map[string]string, created map[string]time.Time) []TagEvent {
+	if previous == nil {
+		return nil
+	}
+
+	var events []TagEvent
+	for tag, digest := range current {
+		if previous[tag] != digest {
+			events = append(events, TagEvent{Tag: tag, Digest: digest, Previous: previous[tag], CreatedAt: created[tag]})
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if !events[i].CreatedAt.Equal(events[j].CreatedAt) {
+			return events[i].CreatedAt.Before(events[j].CreatedAt)
+		}
+		return events[i].Tag < events[j].Tag
+	})
+	return events
+}
+
+func printTagEvent(now time.Time, event TagEvent) {
+	if event.Previous == "" {
+		fmt.Printf("[%s] 🆕 New tag %s → %s (created %s)\n",
+			now.Format(time.TimeOnly), event.Tag, shortDigest(event.Digest), event.CreatedAt.Format(time.RFC3339))
+		return
+	}
+	fmt.Printf("[%s] 🔁 Tag %s moved %s → %s (created %s)\n",
+		now.Format(time.TimeOnly), event.Tag, shortDigest(event.Previous), shortDigest(event.Digest), event.CreatedAt.Format(time.RFC3339))
+	switch {
+	case event.Explained != nil:
+		fmt.Printf("           %s by %s at %s\n", event.Explained.Action, event.Explained.By, event.Explained.At.Format(time.RFC3339))
+	case event.SilentRetag():
+		fmt.Printf("           🚨 release tag retagged without a logged promotion\n")
+	}
+}
+
+// tagEventMessage formats an event as a notification title and body
+func tagEventMessage(event TagEvent) (string, string) {
+	reference := fmt.Sprintf("%s/%s:%s", config.Registry, imageRepository(), event.Tag)
+	if event.Previous == "" {
+		return "New tag pushed", fmt.Sprintf("`%s` → `%s`", reference, event.Digest)
+	}
+	message := fmt.Sprintf("`%s` now points to `%s` (was `%s`)", reference, event.Digest, event.Previous)
+	switch {
+	case event.Explained != nil:
+		return "Tag moved", fmt.Sprintf("%s; %s by %s", message, event.Explained.Action, event.Explained.By)
+	case event.SilentRetag():
+		return "Release tag retagged", message + "; no promotion was logged, so this is an unlogged rebuild or a tampered tag. Investigate before deploying it."
+	}
+	return "Tag moved", message
+}
+
+func watchStatePath() (string, error) {
+	dir, err := cacheDir("watch")
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, config.Package+".json"), nil
+}
+
+// loadWatchState returns the saved state, or an empty state on first run
+func loadWatchState() (*watchState, error) {
+	path, err := watchStatePath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &watchState{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read watch state: %w", err)
+	}
+
+	var state watchState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse watch state %s: %w", path, err)
+	}
+	return &state, nil
+}
+
+func saveWatchState(state *watchState) error {
+	path, err := watchStatePath()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode watch state: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to save watch state: %w", err)
+	}
+	return nil
+}